@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	stdlog "log"
 	"os"
@@ -17,6 +18,7 @@ import (
 	"github.com/compresr/context-gateway/internal/config"
 	"github.com/compresr/context-gateway/internal/dashboard"
 	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/launcher"
 	"github.com/compresr/context-gateway/internal/plugins"
 	"github.com/compresr/context-gateway/internal/postsession"
 	"github.com/compresr/context-gateway/internal/preemptive"
@@ -47,8 +49,11 @@ func runAgentCommand(args []string) {
 		passthroughArgs []string
 		daemonFlag      bool
 		stopFlag        bool
+		detachFlag      bool
+		reuseBusyFlag   bool
 		sessionDirFlag  string
 		sessionNameFlag string
+		envFileFlags    []string
 	)
 
 	portFlag = "" // Empty = auto-find available port
@@ -70,6 +75,12 @@ parseLoop:
 		case "--daemon":
 			daemonFlag = true
 			i++
+		case "--detach":
+			detachFlag = true
+			i++
+		case "--reuse-gateway":
+			reuseBusyFlag = true
+			i++
 		case "--session":
 			if i+1 < len(args) {
 				sessionDirFlag = args[i+1]
@@ -77,12 +88,20 @@ parseLoop:
 			} else {
 				i++
 			}
-		case "-n", "--name":
+		case "-n", "--name", "--session-name":
 			if i+1 < len(args) {
 				sessionNameFlag = args[i+1]
 				i += 2
 			} else {
-				fmt.Fprintln(os.Stderr, "Error: --name requires a value")
+				fmt.Fprintf(os.Stderr, "Error: %s requires a value\n", args[i])
+				os.Exit(1)
+			}
+		case "--env-file":
+			if i+1 < len(args) {
+				envFileFlags = append(envFileFlags, args[i+1])
+				i += 2
+			} else {
+				fmt.Fprintln(os.Stderr, "Error: --env-file requires a value")
 				os.Exit(1)
 			}
 		case "-c", "--config":
@@ -137,6 +156,11 @@ parseLoop:
 		}
 	}
 
+	if err := launcher.LoadEnvFiles(envFileFlags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Handle --stop flag - stop a running background gateway
 	if stopFlag {
 		pidFile := filepath.Join(os.TempDir(), "context-gateway.pid")
@@ -162,7 +186,7 @@ parseLoop:
 		}
 
 		// Check if process is actually alive (FindProcess always succeeds on Unix)
-		if !isProcessRunning(process) {
+		if !launcher.IsProcessRunning(process) {
 			fmt.Println("Gateway process not running (stale PID file).")
 			_ = os.Remove(pidFile)
 			_ = os.Remove(portFile)
@@ -177,7 +201,7 @@ parseLoop:
 		fmt.Println("Waiting for plugins to restore configs...")
 		time.Sleep(2 * time.Second)
 
-		if err := terminateProcess(process); err != nil {
+		if err := launcher.TerminateProcess(process); err != nil {
 			fmt.Printf("Failed to stop gateway: %v\n", err)
 			// Only remove PID file if it still contains the same PID we read
 			if currentBytes, readErr := os.ReadFile(filepath.Clean(pidFile)); readErr == nil {
@@ -192,7 +216,7 @@ parseLoop:
 		exited := false
 		for i := 0; i < 150; i++ { // 150 * 100ms = 15s max
 			time.Sleep(100 * time.Millisecond)
-			if !isProcessRunning(process) {
+			if !launcher.IsProcessRunning(process) {
 				exited = true
 				break
 			}
@@ -228,27 +252,29 @@ parseLoop:
 	basePort := config.DefaultGatewayBasePort
 	maxPorts := config.MaxGatewayPorts
 
-	var gatewayPort int
-
+	portReq := launcher.PortRequest{Detach: detachFlag, ReuseBusy: reuseBusyFlag, CanReuse: !daemonFlag && proxyMode != "skip"}
 	if portFlag != "" {
 		// User explicitly specified a port
 		var err error
-		gatewayPort, err = strconv.Atoi(portFlag)
-		if err != nil || gatewayPort <= 0 || gatewayPort > 65535 {
+		portReq.Port, err = strconv.Atoi(portFlag)
+		if err != nil || portReq.Port <= 0 || portReq.Port > 65535 {
 			_, _ = os.Stderr.WriteString("Error: invalid port " + strconv.Quote(portFlag) + "\n")
 			os.Exit(1)
 		}
-	} else {
-		// Find first available port
-		port, found := findAvailablePort(basePort, maxPorts)
-		if !found {
-			fmt.Fprintf(os.Stderr, "Error: no available ports in range %d-%d\n", basePort, basePort+maxPorts-1)
-			fmt.Fprintln(os.Stderr, "Close some terminal sessions to free up ports.")
-			os.Exit(1)
-		}
-		gatewayPort = port
 	}
 
+	// A detached gateway left running on an explicit port is reused instead of
+	// failing the port-in-use check below; with --reuse-gateway, so is a healthy
+	// gateway holding a port when the whole range is busy.
+	probe := launcher.PortProbe{InUse: isPortInUse, Healthy: checkGatewayRunning}
+	portChoice, portErr := probe.ChoosePort(portReq, basePort, maxPorts)
+	var noPort *launcher.NoPortError
+	if errors.As(portErr, &noPort) {
+		printNoAvailablePort(noPort)
+		os.Exit(1)
+	}
+	gatewayPort, reuseGateway := portChoice.Port, portChoice.Reuse
+
 	// Set GATEWAY_PORT env for variable expansion in configs/agents
 	_ = os.Setenv("GATEWAY_PORT", strconv.Itoa(gatewayPort))
 
@@ -294,7 +320,7 @@ parseLoop:
 	// or when proxy is disabled.
 	var previewGW *gateway.Gateway
 	previewBrowserOpened := false
-	if proxyMode != "skip" && !showConfigMenu && !daemonFlag {
+	if proxyMode != "skip" && !showConfigMenu && !daemonFlag && !reuseGateway {
 		previewGW, previewBrowserOpened = startPreviewGateway(gatewayPort, debugFlag)
 	}
 
@@ -377,6 +403,10 @@ mainSelectionLoop:
 			printSuccess(fmt.Sprintf("Installed %s plugin", ac.Agent.Name))
 		}
 
+		// Agent-declared default config: launch straight to the gateway unless the
+		// user asked for the config menu (bare -c) or passed -c NAME.
+		configFlag = launcher.LaunchConfig(configFlag, ac.Agent.DefaultConfig, showConfigMenu, proxyMode == "skip")
+
 		// First-run experience: offer to configure or use defaults
 		if firstRun && configFlag == "" && proxyMode != "skip" {
 			firstRunItems := []tui.MenuItem{
@@ -491,6 +521,24 @@ mainSelectionLoop:
 			fmt.Fprintf(os.Stderr, "Error: %v\n", configErr)
 			os.Exit(1)
 		}
+		// Catch a missing provider key now, while the user can still fix it,
+		// instead of on the first summarization call. The daemon child inherits
+		// whatever the parent fixed.
+		if !daemonFlag && !doctorBeforeLaunch(configData, configSource, ac.Agent.Name) {
+			os.Exit(0)
+		}
+		// A shared gateway keeps its own config; refuse to silently run the
+		// agent against different settings than the ones selected.
+		if reuseGateway {
+			var mismatch *launcher.ConfigMismatchError
+			if err := launcher.CheckReuse(gatewayPort, configSource); errors.As(err, &mismatch) {
+				printError(fmt.Sprintf("Cannot reuse the gateway: %v", err))
+				fmt.Fprintf(os.Stderr, "Launch with -c %s to share it, or stop it first (a detached one: context-gateway serve stop --port %d)\n", mismatch.Running, gatewayPort)
+				os.Exit(1)
+			} else if err != nil {
+				printWarn(err.Error())
+			}
+		}
 	}
 
 	// Export agent environment variables
@@ -501,12 +549,16 @@ mainSelectionLoop:
 	// This prevents duplicate logs when both parent and daemon initialize
 	isBackgroundParent := ac.Agent.IsBackgroundMode() && !daemonFlag
 
+	// Detach mode: the gateway runs in a daemon subprocess that outlives the agent,
+	// so the parent only spawns (or reuses) it. Background agents already detach.
+	isDetachParent := detachFlag && !daemonFlag && !ac.Agent.IsBackgroundMode()
+
 	// Start gateway as goroutine (not background process)
 	// Each agent invocation gets its own session directory for logs
 	var gw *gateway.Gateway
 	var sessionDir string
 	var statusBar *tui.StatusBar
-	if proxyMode != "skip" && configData != nil && !isBackgroundParent {
+	if proxyMode != "skip" && configData != nil && !isBackgroundParent && !isDetachParent && !reuseGateway {
 
 		// Parse config early to check telemetry_enabled before setting env vars
 		earlyConfig, earlyErr := config.LoadFromBytes(configData)
@@ -546,7 +598,7 @@ mainSelectionLoop:
 			// Daemon mode: reuse session directory from parent process
 			sessionDir = sessionDirFlag
 		} else {
-			sessionDir = prepareSessionPath("logs", agentArg, sessionNameFlag)
+			sessionDir = launcher.PrepareSessionPath("logs", agentArg, sessionNameFlag, time.Now())
 		}
 
 		// Export session log paths for this agent (paths may not exist yet - lazy creation)
@@ -562,6 +614,7 @@ mainSelectionLoop:
 			_ = os.Setenv("SESSION_TOOLS_LOG", filepath.Join(sessionDir, "session_tools.json"))
 			_ = os.Setenv("SESSION_STATS_LOG", filepath.Join(sessionDir, "session_stats.json"))
 			_ = os.Setenv("SESSION_EXPAND_CALLS_LOG", filepath.Join(sessionDir, "expand_context_calls.jsonl"))
+			_ = os.Setenv("SESSION_REQUESTS_LOG", filepath.Join(sessionDir, "requests.jsonl"))
 		}
 		_ = os.Setenv("SESSION_GATEWAY_LOG", filepath.Join(sessionDir, "gateway.log"))
 
@@ -724,6 +777,8 @@ mainSelectionLoop:
 		)
 	} else if proxyMode == "skip" {
 		printInfo("Skipping gateway (--proxy skip)")
+	} else if reuseGateway && !isDetachParent {
+		printSuccess(fmt.Sprintf("All gateway ports busy; reusing the gateway on port %d", gatewayPort))
 	}
 
 	displayName := ac.Agent.DisplayName
@@ -743,7 +798,8 @@ mainSelectionLoop:
 	}
 
 	// Run pre-run command if specified (e.g., start OpenClaw gateway)
-	if len(ac.Agent.Command.PreRunCmd) > 0 {
+	var preRunProc *exec.Cmd // background pre-run process we own; stopped on exit
+	if len(ac.Agent.Command.PreRunCmd) > 0 && !(daemonFlag && detachFlag) {
 		// Check if gateway is already running
 		if checkGatewayRunning(18789) {
 			printSuccess(fmt.Sprintf("%s internal gateway already running", displayName))
@@ -758,6 +814,7 @@ mainSelectionLoop:
 				if err := preRunCmd.Start(); err != nil {
 					printWarn(fmt.Sprintf("Pre-run command failed: %v", err))
 				} else {
+					preRunProc = preRunCmd
 					// Wait for gateway to be ready (poll with timeout)
 					// OpenClaw gateway runs on port 18789 by default
 					gatewayReady := waitForGateway(18789, 10*time.Second)
@@ -877,10 +934,9 @@ mainSelectionLoop:
 
 		// Now safe to shutdown gateway
 		if gw != nil {
-			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-			defer cancel()
-			_ = gw.Shutdown(ctx)
+			shutdownGateway(gw)
 		}
+		stopPreRunProcess(preRunProc, displayName)
 
 		// Only remove PID file if it still contains our PID (avoid race with new daemon)
 		myPid := os.Getpid()
@@ -895,6 +951,36 @@ mainSelectionLoop:
 		return
 	}
 
+	// Detached daemon: serve until `context-gateway serve stop` signals us
+	if daemonFlag && detachFlag {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, getShutdownSignals()...)
+		<-sigCh
+
+		if gw != nil {
+			runPostSessionUpdate(gw)
+			shutdownGateway(gw)
+		}
+
+		// Only remove PID file if it still contains our PID (avoid race with a new daemon)
+		pidFile := launcher.DetachedPidFile(gatewayPort)
+		if pidBytes, err := os.ReadFile(filepath.Clean(pidFile)); err == nil {
+			if pidInFile, err := strconv.Atoi(strings.TrimSpace(string(pidBytes))); err == nil && pidInFile == os.Getpid() {
+				_ = os.Remove(pidFile)
+			}
+		}
+		return
+	}
+
+	if isDetachParent && proxyMode != "skip" && configData != nil {
+		if reuseGateway {
+			printSuccess(fmt.Sprintf("Reusing detached gateway on port %d", gatewayPort))
+		} else {
+			startDetachedGateway(previewGW, agentArg, configFlag, sessionNameFlag, gatewayPort, debugFlag)
+			previewGW = nil
+		}
+	}
+
 	// Interactive mode: launch agent as child process with env vars set for routing
 	printStep(fmt.Sprintf("Launching %s...", displayName))
 	fmt.Println()
@@ -938,10 +1024,9 @@ mainSelectionLoop:
 	}
 
 	if gw != nil {
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-		_ = gw.Shutdown(ctx)
+		shutdownGateway(gw)
 	}
+	stopPreRunProcess(preRunProc, displayName)
 
 	// Reset terminal title
 	tui.ClearTerminalTitle()
@@ -956,10 +1041,58 @@ mainSelectionLoop:
 		cost := gw.CostTracker().GetGlobalCost()
 		printInfo(fmt.Sprintf("Session spend: $%.4f", cost))
 	}
+	if gw != nil {
+		printSessionTotals(gw)
+	}
 
 	if sessionDir != "" {
 		fmt.Printf("\033[0;36mSession logs: %s\033[0m\n\n", sessionDir)
 	}
+	if isDetachParent && proxyMode != "skip" {
+		printInfo(fmt.Sprintf("Gateway still running on port %d (stop with: context-gateway serve stop --port %d)", gatewayPort, gatewayPort))
+	}
+}
+
+// startDetachedGateway spawns a daemon subprocess that serves the gateway on
+// gatewayPort until `context-gateway serve stop` and waits for it to be healthy.
+// The preview gateway holds the same port, so it is shut down first.
+func startDetachedGateway(previewGW *gateway.Gateway, agentArg, configFlag, sessionName string, gatewayPort int, debugFlag bool) {
+	if previewGW != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		_ = previewGW.Shutdown(ctx)
+		cancel()
+	}
+
+	exe, _ := os.Executable()
+	daemonArgs := []string{"--daemon", "--detach", "-a", agentArg, "-p", strconv.Itoa(gatewayPort),
+		"--session", launcher.PrepareSessionPath("logs", agentArg, sessionName, time.Now())}
+	if configFlag != "" {
+		daemonArgs = append(daemonArgs, "-c", configFlag)
+	}
+	if debugFlag {
+		daemonArgs = append(daemonArgs, "-d")
+	}
+
+	daemonCmd := exec.Command(exe, daemonArgs...) // #nosec G204,G702 -- exe is our own binary path
+	daemonCmd.Stdout = nil
+	daemonCmd.Stderr = nil
+	daemonCmd.Stdin = nil
+	// Detach from parent process group so the gateway survives the terminal
+	daemonCmd.SysProcAttr = getSysProcAttr()
+
+	if err := daemonCmd.Start(); err != nil {
+		printError(fmt.Sprintf("Failed to start detached gateway: %v", err))
+		os.Exit(1)
+	}
+
+	// Save PID file immediately (so `serve stop` works even if the gateway fails)
+	_ = os.WriteFile(launcher.DetachedPidFile(gatewayPort), []byte(strconv.Itoa(daemonCmd.Process.Pid)), 0600) // #nosec G703 -- temp dir path
+
+	if !waitForGateway(gatewayPort, 30*time.Second) {
+		printWarn("Detached gateway may not be ready (timeout)")
+		return
+	}
+	printSuccess(fmt.Sprintf("Context Gateway detached on port %d (PID: %d)", gatewayPort, daemonCmd.Process.Pid))
 }
 
 // startPreviewGateway starts a minimal gateway with the fast_setup config so the
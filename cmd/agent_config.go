@@ -25,6 +25,7 @@ type AgentSpec struct {
 	RoutingMethod   string        `yaml:"routing_method"` // "env_var" (default) or "config_override"
 	Models          []AgentModel  `yaml:"models"`
 	DefaultModel    string        `yaml:"default_model"`
+	DefaultConfig   string        `yaml:"default_config"` // gateway config used when -c is not given (skips the config menu)
 	Environment     []AgentEnvVar `yaml:"environment"`
 	Unset           []string      `yaml:"unset"`              // env vars to unset (for OAuth auth)
 	SkipAPIKeySetup bool          `yaml:"skip_api_key_setup"` // skip gateway API key prompt (agent handles own config)
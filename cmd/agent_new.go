@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/compresr/context-gateway/internal/tui"
+)
+
+// agentNamePattern restricts agent names to safe file names.
+var agentNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// runAgentNewCommand interactively creates a starter agent YAML in the user agents directory.
+func runAgentNewCommand(args []string) {
+	for _, a := range args {
+		if a == "-h" || a == "--help" {
+			printAgentNewUsage()
+			return
+		}
+	}
+
+	printHeader("New Agent")
+
+	spec, err := promptAgentSpec()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	content := generateAgentYAML(spec)
+	// Validate with the same parser used at load time
+	if _, err := parseAgentConfig([]byte(content)); err != nil {
+		printError(fmt.Sprintf("Generated agent config is invalid: %v", err))
+		os.Exit(1)
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil || homeDir == "" {
+		printError("Failed to resolve user home directory")
+		os.Exit(1)
+	}
+	agentsDir := filepath.Join(homeDir, ".config", "context-gateway", "agents")
+	// #nosec G301 -- agent directory permissions
+	if err := os.MkdirAll(agentsDir, 0750); err != nil {
+		printError(fmt.Sprintf("Failed to create agents directory: %v", err))
+		os.Exit(1)
+	}
+
+	agentPath := filepath.Join(agentsDir, spec.Name+".yaml")
+	if _, err := os.Stat(agentPath); err == nil {
+		if !tui.PromptYesNo(fmt.Sprintf("%s already exists. Overwrite?", agentPath), false) {
+			printInfo("Cancelled")
+			return
+		}
+	}
+	// #nosec G306 -- agent file permissions
+	if err := os.WriteFile(agentPath, []byte(content), 0600); err != nil {
+		printError(fmt.Sprintf("Failed to write agent config: %v", err))
+		os.Exit(1)
+	}
+
+	if _, ok := discoverAgents()[spec.Name]; !ok {
+		printWarn(fmt.Sprintf("Agent saved but not discovered: %s", agentPath))
+		return
+	}
+	printSuccess(fmt.Sprintf("Agent saved: %s", agentPath))
+	printInfo(fmt.Sprintf("Launch it with: context-gateway -a %s", spec.Name))
+}
+
+// printAgentNewUsage prints usage for the agent new subcommand.
+func printAgentNewUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  context-gateway agent new")
+	fmt.Println()
+	fmt.Println("Prompts for the agent's name, commands and environment, then writes")
+	fmt.Println("~/.config/context-gateway/agents/<name>.yaml.")
+}
+
+// promptAgentSpec asks for the fields of a new agent.
+func promptAgentSpec() (AgentSpec, error) {
+	var spec AgentSpec
+
+	for {
+		spec.Name = strings.TrimSpace(tui.PromptInput("Name (e.g. my_agent): "))
+		if spec.Name == "" {
+			return spec, fmt.Errorf("agent name is required")
+		}
+		if agentNamePattern.MatchString(spec.Name) {
+			break
+		}
+		printWarn("Use lowercase letters, digits, '_' or '-'")
+	}
+
+	spec.DisplayName = tui.PromptInput(fmt.Sprintf("Display name [%s]: ", spec.Name))
+	if spec.DisplayName == "" {
+		spec.DisplayName = spec.Name
+	}
+	spec.Description = tui.PromptInput("Description: ")
+
+	for {
+		run := strings.Fields(tui.PromptInput("Run command (e.g. my-agent --flag): "))
+		if len(run) == 0 {
+			return spec, fmt.Errorf("run command is required")
+		}
+		cmd, err := normalizeCommandSpec(AgentCommand{Run: run[0], Args: run[1:]})
+		if err == nil {
+			spec.Command = cmd
+			break
+		}
+		printWarn(err.Error())
+	}
+
+	defaultCheck := "which " + spec.Command.Run
+	check := tui.PromptInput(fmt.Sprintf("Check command [%s]: ", defaultCheck))
+	if check == "" {
+		check = defaultCheck
+	}
+	checkCmd, err := parseLegacyCommand(check)
+	if err != nil {
+		return spec, fmt.Errorf("check command: %w", err)
+	}
+	spec.Command.CheckCmd = checkCmd
+
+	installCmd, err := parseLegacyCommand(tui.PromptInput("Install command (optional): "))
+	if err != nil {
+		return spec, fmt.Errorf("install command: %w", err)
+	}
+	spec.Command.InstallCmd = installCmd
+
+	printInfo("Environment variables as NAME=value, empty line to finish")
+	printInfo("e.g. ANTHROPIC_BASE_URL=http://localhost:${GATEWAY_PORT}")
+	for {
+		line := tui.PromptInput("  env: ")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			printWarn("Expected NAME=value")
+			continue
+		}
+		spec.Environment = append(spec.Environment, AgentEnvVar{Name: name, Value: value})
+	}
+
+	return spec, nil
+}
+
+// generateAgentYAML renders a starter agent config for spec.
+func generateAgentYAML(spec AgentSpec) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s Agent Configuration\n", spec.DisplayName)
+	b.WriteString("# Generated by `context-gateway agent new`\n\n")
+	b.WriteString("agent:\n")
+	fmt.Fprintf(&b, "  name: %s\n", strconv.Quote(spec.Name))
+	fmt.Fprintf(&b, "  display_name: %s\n", strconv.Quote(spec.DisplayName))
+	fmt.Fprintf(&b, "  description: %s\n", strconv.Quote(spec.Description))
+	b.WriteString("  run_mode: \"interactive\"\n")
+	b.WriteString("  routing_method: \"env_var\"\n")
+
+	if len(spec.Environment) > 0 {
+		b.WriteString("\n  environment:\n")
+		for _, env := range spec.Environment {
+			fmt.Fprintf(&b, "    - name: %s\n", strconv.Quote(env.Name))
+			fmt.Fprintf(&b, "      value: %s\n", strconv.Quote(env.Value))
+		}
+	}
+
+	b.WriteString("\n  command:\n")
+	if len(spec.Command.CheckCmd) > 0 {
+		fmt.Fprintf(&b, "    check_cmd: %s\n", yamlFlowList(spec.Command.CheckCmd))
+	}
+	fmt.Fprintf(&b, "    run: %s\n", strconv.Quote(spec.Command.Run))
+	fmt.Fprintf(&b, "    args: %s\n", yamlFlowList(spec.Command.Args))
+	if len(spec.Command.InstallCmd) > 0 {
+		fmt.Fprintf(&b, "    install_cmd: %s\n", yamlFlowList(spec.Command.InstallCmd))
+	}
+	fmt.Fprintf(&b, "    fallback_message: %s\n", strconv.Quote(spec.Command.Run+" not found."))
+
+	return b.String()
+}
+
+// yamlFlowList renders items as a YAML flow sequence of double-quoted strings.
+func yamlFlowList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = strconv.Quote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+const (
+	// gatewayShutdownTimeout bounds draining in-flight requests on exit.
+	gatewayShutdownTimeout = 10 * time.Second
+	// subprocessStopTimeout is how long a background subprocess gets to exit
+	// after a graceful stop signal before it is force-killed.
+	subprocessStopTimeout = 5 * time.Second
+)
+
+// stopPreRunProcess stops the background pre-run process started by this
+// session (e.g. the OpenClaw internal gateway) and reports the outcome.
+func stopPreRunProcess(cmd *exec.Cmd, displayName string) {
+	if cmd == nil {
+		return
+	}
+	forced, err := launcher.StopSubprocess(cmd, subprocessStopTimeout)
+	switch {
+	case err != nil:
+		log.Warn().Err(err).Msg("failed to stop pre-run process")
+		printWarn(fmt.Sprintf("%s internal gateway did not stop: %v", displayName, err))
+	case forced:
+		log.Warn().Dur("timeout", subprocessStopTimeout).Msg("pre-run process force-killed")
+		printWarn(fmt.Sprintf("%s internal gateway force-killed after %s", displayName, subprocessStopTimeout))
+	default:
+		printSuccess(fmt.Sprintf("%s internal gateway stopped", displayName))
+	}
+}
+
+// shutdownGateway drains and stops gw, reporting instead of swallowing errors.
+func shutdownGateway(gw *gateway.Gateway) {
+	ctx, cancel := context.WithTimeout(context.Background(), gatewayShutdownTimeout)
+	defer cancel()
+
+	if err := gw.Shutdown(ctx); err != nil {
+		log.Warn().Err(err).Msg("gateway shutdown incomplete")
+		printWarn(fmt.Sprintf("Gateway shutdown incomplete: %v", err))
+		return
+	}
+	log.Info().Msg("gateway stopped")
+	printSuccess("Gateway stopped")
+}
+
+// printSessionTotals prints request and savings totals from gateway telemetry.
+func printSessionTotals(gw *gateway.Gateway) {
+	stats := gw.Stats()
+	printInfo(fmt.Sprintf("Requests handled: %d (%d successful)",
+		stats.Gateway.TotalRequests, stats.Gateway.SuccessfulRequests))
+	if stats.Gateway.Compressions > 0 {
+		printInfo(fmt.Sprintf("Tool outputs compressed: %d (%d from cache), %s in → %s upstream",
+			stats.Gateway.Compressions, stats.Gateway.CacheHits,
+			formatBytes(stats.Gateway.BytesIn), formatBytes(stats.Gateway.BytesOut)))
+	}
+	if stats.Savings.TokensSaved > 0 {
+		printInfo(fmt.Sprintf("Tokens saved: %d (%.1f%%)", stats.Savings.TokensSaved, stats.Savings.TokenSavedPct))
+	}
+	if stats.Savings.CostSavedUSD > 0 {
+		printInfo(fmt.Sprintf("Cost saved: $%.4f", stats.Savings.CostSavedUSD))
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/compresr/context-gateway/internal/launcher"
 	"github.com/compresr/context-gateway/internal/tui"
 
 	"gopkg.in/yaml.v3"
@@ -44,16 +45,30 @@ func checkGatewayRunning(port int) bool {
 	return resp.StatusCode == http.StatusOK
 }
 
-// findAvailablePort finds the first available port in the given range.
-// Returns the port number and true if found, or 0 and false if no port available.
-func findAvailablePort(basePort, maxPorts int) (int, bool) {
-	for i := 0; i < maxPorts; i++ {
-		port := basePort + i
-		if !isPortInUse(port) {
-			return port, true
-		}
+// printNoAvailablePort explains why no gateway port is free and how to get one.
+func printNoAvailablePort(e *launcher.NoPortError) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", e)
+	if len(e.Gateways) > 0 {
+		fmt.Fprintf(os.Stderr, "  Held by running gateways: %s\n", joinPorts(e.Gateways))
+	}
+	if len(e.Others) > 0 {
+		fmt.Fprintf(os.Stderr, "  Held by other processes:  %s\n", joinPorts(e.Others))
+	}
+	if len(e.Gateways) > 0 {
+		fmt.Fprintf(os.Stderr, "Stop a detached gateway with: context-gateway serve stop --port %d\n", e.Gateways[0])
+		fmt.Fprintln(os.Stderr, "Or share a running gateway: rerun with --reuse-gateway")
+	} else {
+		fmt.Fprintln(os.Stderr, "Close some terminal sessions to free up ports.")
 	}
-	return 0, false
+}
+
+// joinPorts renders ports as a comma-separated list.
+func joinPorts(ports []int) string {
+	parts := make([]string, len(ports))
+	for i, p := range ports {
+		parts[i] = strconv.Itoa(p)
+	}
+	return strings.Join(parts, ", ")
 }
 
 // isPortInUse checks if a TCP port is in use.
@@ -514,58 +529,6 @@ func listAvailableConfigsPrint() {
 	}
 }
 
-// sanitizeName replaces characters that are not filesystem-safe with underscores.
-func sanitizeName(s string) string {
-	return strings.Map(func(r rune) rune {
-		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
-			return r
-		}
-		return '_'
-	}, s)
-}
-
-// prepareSessionPath computes a session directory path without creating it.
-// The actual directory is created lazily on first LLM request via ensureSessionDir().
-// This prevents empty session folders when the gateway starts but receives no traffic.
-//
-// Auto-generated format: <agent>_<N>_<YYYYMMDD_HHMMSS>  (e.g. claude_code_3_20240315_143022)
-// If customName is non-empty it is used as-is (sanitized) without an agent prefix.
-func prepareSessionPath(baseDir string, agentName string, customName string) string {
-	_ = os.MkdirAll(baseDir, 0750)
-
-	if customName != "" {
-		return filepath.Join(baseDir, sanitizeName(customName))
-	}
-
-	prefix := sanitizeName(agentName)
-	if prefix == "" {
-		prefix = "session"
-	}
-	now := time.Now().Format("20060102_150405")
-
-	// Find the highest session number already used for this agent prefix.
-	sessionNum := 1
-	entries, err := os.ReadDir(baseDir)
-	if err == nil {
-		needle := prefix + "_"
-		for _, e := range entries {
-			if !e.IsDir() || !strings.HasPrefix(e.Name(), needle) {
-				continue
-			}
-			// Name is "<prefix>_<N>_<date>" — parse the part right after the prefix.
-			rest := e.Name()[len(needle):]
-			parts := strings.SplitN(rest, "_", 2)
-			if len(parts) >= 1 {
-				if n, err := strconv.Atoi(parts[0]); err == nil && n >= sessionNum {
-					sessionNum = n + 1
-				}
-			}
-		}
-	}
-
-	return filepath.Join(baseDir, fmt.Sprintf("%s_%d_%s", prefix, sessionNum, now))
-}
-
 // exportAgentEnv sets environment variables defined in the agent config.
 func exportAgentEnv(ac *AgentConfig) {
 	// First, unset any specified variables (for OAuth-based auth)
@@ -614,7 +577,12 @@ func listAvailableAgents() {
 }
 
 // Print helper functions for consistent output formatting.
+// Header, success, info and step lines are decorative and dropped under --quiet;
+// warnings and errors always print.
 func printHeader(title string) {
+	if quietMode {
+		return
+	}
 	fmt.Printf("\033[1m\033[0;36m========================================\033[0m\n")
 	fmt.Printf("\033[1m\033[0;36m       %s\033[0m\n", title)
 	fmt.Printf("\033[1m\033[0;36m========================================\033[0m\n")
@@ -622,10 +590,16 @@ func printHeader(title string) {
 }
 
 func printSuccess(msg string) {
+	if quietMode {
+		return
+	}
 	fmt.Printf("\r\033[0;32m[OK]\033[0m %s\n", msg)
 }
 
 func printInfo(msg string) {
+	if quietMode {
+		return
+	}
 	fmt.Printf("  \033[2m·\033[0m %s\n", msg)
 }
 
@@ -638,6 +612,9 @@ func printError(msg string) {
 }
 
 func printStep(msg string) {
+	if quietMode {
+		return
+	}
 	fmt.Printf("\033[0;36m>>>\033[0m %s\n", msg)
 }
 
@@ -649,10 +626,17 @@ func printAgentHelp() {
 	fmt.Println("Options:")
 	fmt.Println("  -a, --agent AGENT    Select agent directly (claude_code, openclaw, codex, etc.)")
 	fmt.Println("  -c, --config [NAME]  Config menu if NAME omitted, uses NAME directly if provided")
+	fmt.Println("                       (without -c: the agent's default_config, else fast_setup)")
 	fmt.Println("  --config list        List available configs")
 	fmt.Println("  -p, --port PORT      Gateway port (default: 18081)")
 	fmt.Println("  -d, --debug          Enable debug logging")
+	fmt.Println("  --quiet              Suppress banner and decorative output (or CG_QUIET=1)")
 	fmt.Println("  --proxy MODE         auto (default), start, skip")
+	fmt.Println("  --detach             Keep the gateway running after the agent exits;")
+	fmt.Println("                       later launches with the same -p reuse it")
+	fmt.Println("  --reuse-gateway      When every gateway port is busy, share a running")
+	fmt.Println("                       gateway instead of exiting")
+	fmt.Println("  --env-file PATH      Load an extra .env file (repeatable; later files win)")
 	fmt.Println("  --reset-api-key      Reset Compresr API key and re-run setup")
 	fmt.Println("  -l, --list           List available agents")
 	fmt.Println("  -h, --help           Show this help")
@@ -671,6 +655,8 @@ func printAgentHelp() {
 	fmt.Println("  context-gateway --config list                    List configs")
 	fmt.Println("  context-gateway -l                               List agents")
 	fmt.Println("  context-gateway claude_code -- -p \"fix the bug\"  Pass -p to Claude Code")
+	fmt.Println("  context-gateway claude_code -p 18085 --detach    Leave the gateway up for reuse")
+	fmt.Println("  context-gateway serve stop --port 18085          Stop a detached gateway")
 }
 
 // sortedKeys returns the sorted keys of a map.
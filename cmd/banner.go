@@ -103,5 +103,8 @@ func colorLines(art string, termW int) string {
 
 // printBanner prints the banner sized to the current terminal width.
 func printBanner() {
+	if quietMode {
+		return
+	}
 	fmt.Print(buildBanner(terminalWidth()))
 }
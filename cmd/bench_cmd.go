@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/compresr/context-gateway/internal/bench"
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// runBenchCommand handles "context-gateway bench <target>".
+func runBenchCommand(args []string) {
+	if len(args) == 0 || args[0] != "proxy" {
+		fmt.Println("Usage: context-gateway bench proxy [--requests N] [--warmup N] [--config FILE]")
+		os.Exit(1)
+	}
+	runBenchProxy(args[1:])
+}
+
+// runBenchProxy handles "context-gateway bench proxy".
+// Measures the latency an in-process gateway adds over calling a local mock
+// upstream directly. Never contacts a real API.
+func runBenchProxy(args []string) {
+	fs := flag.NewFlagSet("bench proxy", flag.ExitOnError)
+	requests := fs.Int("requests", 200, "timed requests per path (direct and proxied)")
+	warmup := fs.Int("warmup", 20, "untimed requests per path sent first")
+	configName := fs.String("config", "fast_setup", "config name or path")
+	_ = fs.Parse(args)
+
+	if *requests <= 0 || *warmup < 0 {
+		printError("--requests must be positive and --warmup non-negative")
+		os.Exit(1)
+	}
+
+	data, source, err := resolveConfig(*configName)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	cfg, err := config.LoadFromBytes(data)
+	if err != nil {
+		printError(fmt.Sprintf("Failed to load config from %s: %v", source, err))
+		os.Exit(1)
+	}
+
+	printInfo(fmt.Sprintf("Sending %d requests directly and through the gateway (%s)", *requests, source))
+	res, err := bench.Proxy(cfg, bench.ProxyOptions{Requests: *requests, Warmup: *warmup})
+	if err != nil {
+		printError(fmt.Sprintf("Benchmark failed: %v", err))
+		os.Exit(1)
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PATH\tP50\tP95\tP99")
+	for _, row := range []struct {
+		name string
+		l    bench.Latencies
+	}{
+		{"direct", res.Direct},
+		{"proxied", res.Proxied},
+		{"added", res.Added},
+	} {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", row.name,
+			formatLatency(row.l.P50), formatLatency(row.l.P95), formatLatency(row.l.P99))
+	}
+	_ = w.Flush()
+	fmt.Println()
+	fmt.Println("ADDED = per-request proxied minus direct latency against the same local mock upstream.")
+}
+
+// formatLatency renders a duration with microsecond precision.
+func formatLatency(d time.Duration) string {
+	return d.Round(time.Microsecond).String()
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// runExportCommand handles "context-gateway export".
+// Bundles user configs and agents (and .env with --include-secrets) into a tar.gz.
+func runExportCommand(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("out", "context-gateway-setup.tar.gz", "output archive path")
+	includeSecrets := fs.Bool("include-secrets", false, "include ~/.config/context-gateway/.env (API keys)")
+	_ = fs.Parse(args)
+
+	configDir := getConfigDir()
+	if configDir == "" {
+		printError("Cannot determine home directory")
+		os.Exit(1)
+	}
+
+	// #nosec G304 -- user-specified output path
+	f, err := os.OpenFile(*out, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		printError(fmt.Sprintf("Cannot create %s: %v", *out, err))
+		os.Exit(1)
+	}
+
+	manifest, err := config.ExportBundle(configDir, f, *includeSecrets, Version)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(*out)
+		printError(fmt.Sprintf("Export failed: %v", err))
+		os.Exit(1)
+	}
+
+	for _, rel := range manifest.Files {
+		fmt.Printf("  %s\n", rel)
+	}
+	secretNames := make([]string, 0, len(manifest.Secrets))
+	for name := range manifest.Secrets {
+		secretNames = append(secretNames, name)
+	}
+	sort.Strings(secretNames)
+	for _, name := range secretNames {
+		fmt.Printf("  %s=%s\n", name, manifest.Secrets[name])
+	}
+	printSuccess(fmt.Sprintf("Exported %d files to %s", len(manifest.Files), *out))
+	if *includeSecrets {
+		printWarn("Archive contains secrets — store and transfer it carefully")
+	}
+}
+
+// runImportCommand handles "context-gateway import <archive>".
+// Unpacks a bundle into ~/.config/context-gateway, prompting before overwriting changed files.
+func runImportCommand(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	yes := fs.Bool("yes", false, "overwrite conflicting files without prompting")
+	_ = fs.Parse(args)
+
+	// Allow flags after the archive path: import setup.tar.gz --yes
+	archive := fs.Arg(0)
+	if fs.NArg() > 1 {
+		_ = fs.Parse(fs.Args()[1:])
+	}
+	if archive == "" {
+		fmt.Println("Usage: context-gateway import <archive.tar.gz> [--yes]")
+		os.Exit(1)
+	}
+
+	configDir := getConfigDir()
+	if configDir == "" {
+		printError("Cannot determine home directory")
+		os.Exit(1)
+	}
+
+	// #nosec G304 -- user-specified archive path
+	f, err := os.Open(archive)
+	if err != nil {
+		printError(fmt.Sprintf("Cannot open %s: %v", archive, err))
+		os.Exit(1)
+	}
+	defer func() { _ = f.Close() }()
+
+	reader := bufio.NewReader(os.Stdin)
+	overwrite := func(rel string) bool {
+		if *yes {
+			return true
+		}
+		fmt.Printf("%s already exists and differs. Overwrite? [y/N] ", rel)
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(strings.ToLower(input))
+		return input == "y" || input == "yes"
+	}
+
+	written, err := config.ImportBundle(f, configDir, overwrite)
+	for _, rel := range written {
+		fmt.Printf("  %s\n", rel)
+	}
+	if err != nil {
+		printError(fmt.Sprintf("Import failed: %v", err))
+		os.Exit(1)
+	}
+	printSuccess(fmt.Sprintf("Imported %d files into %s", len(written), configDir))
+
+	if names := listUserConfigs(); len(names) > 0 {
+		printInfo("Configs: " + strings.Join(names, ", "))
+	}
+}
@@ -28,6 +28,18 @@ func runConfigCommand(args []string) {
 		runConfigMigrate(args[1:])
 		return
 	}
+	if len(args) > 0 && args[0] == "diff" {
+		runConfigDiff(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "validate" {
+		runConfigValidate(args[1:])
+		return
+	}
+	if len(args) > 0 && args[0] == "doctor" {
+		runConfigDoctor(args[1:])
+		return
+	}
 
 	fs := flag.NewFlagSet("config", flag.ExitOnError)
 	browserMode := fs.Bool("browser", false, "open settings in browser")
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// runConfigDiff handles "context-gateway config diff A B".
+// Both configs are resolved like everywhere else (name or path), loaded with env
+// expansion and defaults, and compared setting by setting — so formatting and
+// comments never show up, only values that change behavior.
+func runConfigDiff(args []string) {
+	if len(args) != 2 {
+		fmt.Println("Usage: context-gateway config diff <A> <B>")
+		os.Exit(1)
+	}
+
+	loadEnvFiles()
+
+	cfgs := make([]*config.Config, 0, 2)
+	for _, name := range args {
+		data, source, err := resolveConfig(name)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		cfg, err := config.LoadFromBytes(data)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to load config from %s: %v", source, err))
+			os.Exit(1)
+		}
+		cfgs = append(cfgs, cfg)
+	}
+
+	diffs := config.Diff(cfgs[0], cfgs[1])
+	if len(diffs) == 0 {
+		printInfo(fmt.Sprintf("%s and %s have identical effective settings", args[0], args[1]))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "SETTING\t%s\t%s\n", args[0], args[1])
+	for _, d := range diffs {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", d.Path, d.A, d.B)
+	}
+	_ = w.Flush()
+	fmt.Printf("\n%d setting(s) differ\n", len(diffs))
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/launcher"
+	"github.com/compresr/context-gateway/internal/tui"
+)
+
+// runConfigDoctor handles "context-gateway config doctor [--ping] [--agent NAME] <config>".
+// It loads the config like serve would and reports provider problems that would
+// otherwise only surface on the first summarization or compression call.
+func runConfigDoctor(args []string) {
+	fs := flag.NewFlagSet("config doctor", flag.ExitOnError)
+	ping := fs.Bool("ping", false, "also check that each used provider endpoint answers")
+	agent := fs.String("agent", "", "agent the config is used with (its own credentials are captured, e.g. claude_code)")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: context-gateway config doctor [--ping] [--agent NAME] <config>")
+		os.Exit(1)
+	}
+
+	loadEnvFiles()
+
+	data, source, err := resolveConfig(fs.Arg(0))
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	cfg, err := config.LoadFromBytes(data)
+	if err != nil {
+		printError(fmt.Sprintf("%s: %v", source, err))
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	issues := cfg.Doctor(ctx, config.DoctorOptions{CapturedProvider: launcher.CapturedProvider(*agent), Ping: *ping})
+	if len(issues) == 0 {
+		printSuccess(fmt.Sprintf("%s: no problems found", source))
+		return
+	}
+	for _, issue := range issues {
+		printWarn(issue.Message)
+	}
+	os.Exit(1)
+}
+
+// doctorBeforeLaunch checks the selected config before the gateway starts and
+// lets the user fix a missing provider key in-flow (see launcher.CheckKeys).
+// Returns false when the user chose to exit.
+func doctorBeforeLaunch(configData []byte, configSource, agentName string) bool {
+	warn := func(issue config.DoctorIssue) {
+		printWarn(fmt.Sprintf("%s: %s", configSource, issue.Message))
+	}
+	return launcher.CheckKeys(configData, launcher.CapturedProvider(agentName), func(missing config.DoctorIssue) launcher.KeyAction {
+		provider := providerInfo(missing.Provider)
+		items := []tui.MenuItem{
+			{Label: "Enter API key", Description: provider.EnvVar, Value: "enter"},
+			{Label: "Continue anyway", Description: "calls to " + missing.Provider + " will fail", Value: "continue"},
+			{Label: "✗ Exit", Value: "exit"},
+		}
+		idx, err := tui.SelectMenu(fmt.Sprintf("%s is not set (needed by %s)", provider.EnvVar, configSource), items)
+		if err != nil || items[idx].Value == "exit" {
+			return launcher.KeyExit
+		}
+		if items[idx].Value == "continue" {
+			return launcher.KeyContinue
+		}
+		promptAndSetAPIKey(&ConfigState{Provider: provider})
+		if os.Getenv(provider.EnvVar) == "" {
+			return launcher.KeyContinue // prompt skipped; don't ask again
+		}
+		return launcher.KeyEntered
+	}, warn)
+}
+
+// providerInfo looks up a provider's display info and key variable, falling back
+// to NAME_API_KEY for providers the wizard does not know. Vertex takes an OAuth
+// access token, not an API key.
+func providerInfo(name string) tui.ProviderInfo {
+	for _, p := range tui.SupportedProviders {
+		if p.Name == name {
+			return p
+		}
+	}
+	if name == config.ProviderVertex {
+		return tui.ProviderInfo{Name: name, DisplayName: "Google Vertex AI", EnvVar: config.VertexTokenEnv}
+	}
+	return tui.ProviderInfo{Name: name, DisplayName: name, EnvVar: strings.ToUpper(name) + "_API_KEY"}
+}
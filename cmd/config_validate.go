@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// runConfigValidate handles "context-gateway config validate [--strict] <config>".
+// The config is resolved by name or path and loaded exactly as serve would load
+// it; --strict additionally rejects keys that map to no setting.
+func runConfigValidate(args []string) {
+	fs := flag.NewFlagSet("config validate", flag.ExitOnError)
+	strict := fs.Bool("strict", false, "reject unknown config keys instead of ignoring them")
+	_ = fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fmt.Println("Usage: context-gateway config validate [--strict] <config>")
+		os.Exit(1)
+	}
+
+	loadEnvFiles()
+
+	data, source, err := resolveConfig(fs.Arg(0))
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	loadConfig := config.LoadFromBytes
+	if *strict {
+		loadConfig = config.LoadFromBytesStrict
+	}
+	if _, err := loadConfig(data); err != nil {
+		printError(fmt.Sprintf("%s: %v", source, err))
+		os.Exit(1)
+	}
+	printSuccess(fmt.Sprintf("%s is valid", source))
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// runEmbeddedCommand handles the "context-gateway embedded" subcommand.
+// Inspects the configs and agents compiled into the binary.
+func runEmbeddedCommand(args []string) {
+	if len(args) == 0 {
+		printEmbeddedUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "list":
+		runEmbeddedList(args[1:])
+	case "show":
+		runEmbeddedShow(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown embedded command: %s\n\n", args[0])
+		printEmbeddedUsage()
+		os.Exit(1)
+	}
+}
+
+// printEmbeddedUsage prints usage for the embedded subcommand.
+func printEmbeddedUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  context-gateway embedded list [--validate]")
+	fmt.Println("  context-gateway embedded show <name>")
+	fmt.Println()
+	fmt.Println("--validate loads every embedded config and agent and exits 1 if any fails.")
+}
+
+// runEmbeddedList prints embedded config and agent names. With --validate each
+// config is loaded through config.LoadFromBytes and each agent parsed as the
+// launcher would, so a broken shipped default fails here instead of at startup.
+func runEmbeddedList(args []string) {
+	fs := flag.NewFlagSet("embedded list", flag.ExitOnError)
+	validate := fs.Bool("validate", false, "load each embedded config and agent and report errors")
+	_ = fs.Parse(args)
+
+	configs, err := listEmbeddedConfigs()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	agents, err := listEmbeddedAgents()
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	if *validate {
+		loadEnvFiles()
+	}
+
+	failed := 0
+	fmt.Println("Configs:")
+	for _, name := range configs {
+		failed += printEmbeddedEntry(name, *validate, func() error {
+			data, err := getEmbeddedConfig(name)
+			if err != nil {
+				return err
+			}
+			_, err = config.LoadFromBytes(data)
+			return err
+		})
+	}
+	fmt.Println()
+	fmt.Println("Agents:")
+	for _, name := range agents {
+		failed += printEmbeddedEntry(name, *validate, func() error {
+			data, err := getEmbeddedAgent(name)
+			if err != nil {
+				return err
+			}
+			_, err = parseAgentConfig(data)
+			return err
+		})
+	}
+
+	if failed > 0 {
+		fmt.Println()
+		printError(fmt.Sprintf("%d embedded file(s) failed validation", failed))
+		os.Exit(1)
+	}
+}
+
+// printEmbeddedEntry prints one list line, running check when validating.
+// Returns 1 if the check failed.
+func printEmbeddedEntry(name string, validate bool, check func() error) int {
+	if !validate {
+		fmt.Printf("  %s\n", name)
+		return 0
+	}
+	if err := check(); err != nil {
+		fmt.Printf("  %-20s \033[0;31minvalid\033[0m: %v\n", name, err)
+		return 1
+	}
+	fmt.Printf("  %-20s \033[0;32mok\033[0m\n", name)
+	return 0
+}
+
+// runEmbeddedShow dumps the raw YAML of an embedded config or agent.
+// Configs are searched first when a name exists in both.
+func runEmbeddedShow(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: context-gateway embedded show <name>")
+		os.Exit(1)
+	}
+
+	data, err := getEmbeddedConfig(args[0])
+	if err != nil {
+		data, err = getEmbeddedAgent(args[0])
+	}
+	if err != nil {
+		printError(fmt.Sprintf("no embedded config or agent named %q (see: context-gateway embedded list)", args[0]))
+		os.Exit(1)
+	}
+	_, _ = os.Stdout.Write(data)
+}
@@ -3,11 +3,15 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -18,6 +22,8 @@ import (
 	"github.com/compresr/context-gateway/internal/compresr"
 	"github.com/compresr/context-gateway/internal/config"
 	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/launcher"
+	"github.com/compresr/context-gateway/internal/monitoring"
 	"github.com/compresr/context-gateway/internal/tui"
 )
 
@@ -37,11 +43,23 @@ func loadEnvFiles() {
 	}
 }
 
+// quietMode suppresses the banner and decorative output (--quiet or CG_QUIET).
+// Results, warnings and errors are still printed.
+var quietMode bool
+
 func main() {
+	var args []string
+	args, quietMode = launcher.StripGlobalFlags(os.Args[1:], os.Getenv(launcher.QuietEnv))
+	os.Args = append(os.Args[:1], args...)
+
 	// Handle subcommands first (before flags)
 	if len(os.Args) > 1 {
 		switch os.Args[1] {
 		case "agent":
+			if len(os.Args) > 2 && os.Args[2] == "new" {
+				runAgentNewCommand(os.Args[3:])
+				return
+			}
 			// Launch agent with interactive selection
 			runAgentCommand(os.Args[2:])
 			return
@@ -53,9 +71,30 @@ func main() {
 			printBanner()
 			runConfigCommand(os.Args[2:])
 			return
+		case "store":
+			runStoreCommand(os.Args[2:])
+			return
+		case "embedded":
+			runEmbeddedCommand(os.Args[2:])
+			return
+		case "export":
+			runExportCommand(os.Args[2:])
+			return
+		case "import":
+			runImportCommand(os.Args[2:])
+			return
+		case "replay-session":
+			runReplaySessionCommand(os.Args[2:])
+			return
+		case "bench":
+			runBenchCommand(os.Args[2:])
+			return
+		case "stats":
+			runStatsCommand(os.Args[2:])
+			return
 		case "update":
 			printBanner()
-			if err := DoUpdate(); err != nil {
+			if err := runUpdateCommand(os.Args[2:]); err != nil {
 				fmt.Fprintf(os.Stderr, "Update failed: %v\n", err)
 				os.Exit(1)
 			}
@@ -136,18 +175,63 @@ func resolveServeConfig(userConfig string) ([]byte, string, error) {
 	return nil, "", fmt.Errorf("no config file found. Specify --config path")
 }
 
+// remoteConfigPollInterval is how often --watch revalidates a remote config.
+const remoteConfigPollInterval = 30 * time.Second
+
+// serveReadyTimeout bounds how long serve waits for /health before giving up on
+// the readiness signal.
+const serveReadyTimeout = 30 * time.Second
+
+// fetchRemoteConfig fetches and validates a config from an http(s) URL. The last
+// good copy is cached under ~/.config/context-gateway/cache for offline starts.
+func fetchRemoteConfig(rawURL string) (*config.HTTPSource, []byte, error) {
+	var cacheDir string
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		cacheDir = filepath.Join(homeDir, ".config", "context-gateway", "cache")
+	}
+	src := config.NewHTTPSource(rawURL, cacheDir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	data, _, err := src.Fetch(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return src, data, nil
+}
+
 // runGatewayServer starts the gateway proxy server
 func runGatewayServer(args []string) {
+	if len(args) > 0 && args[0] == "stop" {
+		runServeStop(args[1:])
+		return
+	}
+
 	// Load .env files from standard locations
 	loadEnvFiles()
 
 	// Parse flags
 	fs := flag.NewFlagSet("serve", flag.ExitOnError)
-	configPath := fs.String("config", "", "path to config file")
+	configPath := fs.String("config", "", "path or http(s) URL of config file")
+	watch := fs.Bool("watch", false, "poll a remote --config URL for changes (local files are always watched)")
 	debug := fs.Bool("debug", false, "enable debug logging")
-	noBanner := fs.Bool("no-banner", false, "suppress startup banner")
+	noBanner := fs.Bool("no-banner", false, "suppress startup banner (see also global --quiet)")
+	profile := fs.Bool("profile", false, "expose pprof endpoints on localhost")
+	profilePort := fs.Int("profile-port", config.DefaultProfilePort, "port for pprof endpoints (with --profile)")
+	unixSocket := fs.String("unix-socket", "", "listen on this Unix domain socket instead of the TCP port")
+	pidFile := fs.String("pid-file", "", "write the process ID here once /health responds (removed on exit)")
+	strict := fs.Bool("strict", false, "reject unknown config keys instead of ignoring them")
+	sampleRate := fs.Float64("compression-log-sample-rate", -1, "fraction (0-1) of compression events written to the compression log (overrides monitoring.compression_log_sample_rate)")
+	maxTokensGuard := fs.String("max-tokens-guard", "", "compact, truncate or error: handle requests still over the context window after compression (overrides preemptive.overflow_action)")
+	var envFiles stringList
+	fs.Var(&envFiles, "env-file", "additional .env file, loaded after the defaults (repeatable; later files win)")
 	_ = fs.Parse(args) // ExitOnError handles errors
 
+	if err := launcher.LoadEnvFiles(envFiles); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Print banner unless suppressed
 	if !*noBanner {
 		printBanner()
@@ -159,10 +243,22 @@ func runGatewayServer(args []string) {
 	// Setup logging
 	setupLogging(*debug)
 
-	// Resolve config from filesystem
-	configData, configSource, err := resolveServeConfig(*configPath)
-	if err != nil {
-		log.Fatal().Err(err).Msg("No config file found. Specify --config path")
+	// Resolve config from a remote URL or the filesystem
+	var remoteSource *config.HTTPSource
+	var configData []byte
+	var configSource string
+	var err error
+	if config.IsRemoteSource(*configPath) {
+		remoteSource, configData, err = fetchRemoteConfig(*configPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to fetch remote config")
+		}
+		configSource = remoteSource.Name()
+	} else {
+		configData, configSource, err = resolveServeConfig(*configPath)
+		if err != nil {
+			log.Fatal().Err(err).Msg("No config file found. Specify --config path")
+		}
 	}
 
 	log.Info().
@@ -171,17 +267,18 @@ func runGatewayServer(args []string) {
 		Str("dashboard", fmt.Sprintf("http://localhost:%d/dashboard/", config.DefaultDashboardPort)).
 		Msg("Context Gateway starting")
 
-	// Load configuration from bytes
-	cfg, err := config.LoadFromBytes(configData)
+	// Load configuration from bytes. Reloads go through the same loader so they
+	// keep --strict and the CLI overrides.
+	if *sampleRate > 1 {
+		log.Fatal().Float64("compression_log_sample_rate", *sampleRate).Msg("--compression-log-sample-rate must be between 0 and 1")
+	}
+	loadConfig := serveConfigLoader(*strict, *sampleRate, *maxTokensGuard)
+	cfg, err := loadConfig(configData)
 	if err != nil {
 		log.Fatal().Err(err).Str("config", configSource).Msg("failed to load configuration")
 	}
 
-	log.Info().
-		Int("port", cfg.Server.Port).
-		Bool("tool_output_pipe", cfg.Pipes.ToolOutput.Enabled).
-		Bool("tool_discovery_pipe", cfg.Pipes.ToolDiscovery.Enabled).
-		Msg("configuration loaded")
+	log.Info().EmbedObject(cfg.EffectiveSummary()).Msg("effective configuration")
 
 	// Warn if any API keys are stored as literal values instead of env var references.
 	// Literal keys don't update automatically when credentials rotate.
@@ -190,9 +287,19 @@ func runGatewayServer(args []string) {
 		log.Warn().Msg("config: " + warning)
 	}
 
-	// Create gateway (pass config source for hot-reload support)
-	gw := gateway.New(cfg, configSource)
+	// Create gateway (pass config file path for hot-reload support).
+	// Remote configs have no file to persist to; they are polled only with --watch.
+	var gw *gateway.Gateway
+	if remoteSource != nil {
+		gw = gateway.New(cfg)
+		if *watch {
+			gw.WatchConfigSource(remoteSource, remoteConfigPollInterval)
+		}
+	} else {
+		gw = gateway.New(cfg, configSource)
+	}
 	gw.SetVersion(Version)
+	gw.SetConfigLoader(loadConfig)
 
 	// Attach embedded React dashboard SPA
 	if dashFS, err := getDashboardFS(); err == nil {
@@ -205,6 +312,15 @@ func runGatewayServer(args []string) {
 		statusBar.SetDashboardPort(cfg.Server.Port)
 	}
 
+	// Start pprof on its own localhost-only listener (only when requested)
+	var profileServer *http.Server
+	if *profile {
+		if *profilePort <= 0 || *profilePort > 65535 || *profilePort == cfg.Server.Port {
+			log.Fatal().Int("profile_port", *profilePort).Msg("--profile-port must be a valid port distinct from server.port")
+		}
+		profileServer = startProfileServer(*profilePort)
+	}
+
 	// Handle graceful shutdown
 	go func() {
 		sigChan := make(chan os.Signal, 1)
@@ -216,21 +332,170 @@ func runGatewayServer(args []string) {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		if profileServer != nil {
+			_ = profileServer.Shutdown(ctx)
+		}
 		if err := gw.Shutdown(ctx); err != nil {
 			log.Error().Err(err).Msg("gateway shutdown error")
 		}
 	}()
 
+	// Reload config on SIGHUP without dropping connections
+	go handleReloadSignals(gw, serveReloadSource(remoteSource, configSource), configSource)
+
 	// Start gateway
-	if err := gw.Start(); err != nil {
+	start := gw.Start
+	if *unixSocket != "" {
+		start = func() error { return gw.StartUnix(*unixSocket) }
+	}
+	go announceReady(cfg.Server.Port, *unixSocket, *pidFile)
+	if err := start(); err != nil {
 		if err.Error() != "http: Server closed" {
 			log.Fatal().Err(err).Msg("gateway error")
 		}
 	}
 
+	if *pidFile != "" {
+		_ = os.Remove(*pidFile)
+	}
 	log.Info().Msg("Context Gateway stopped")
 }
 
+// serveConfigLoader returns the loader serve uses at startup and on every
+// reload: strict parsing when requested, then the CLI overrides
+// (sampleRate < 0 and an empty maxTokensGuard leave the config value).
+func serveConfigLoader(strict bool, sampleRate float64, maxTokensGuard string) func([]byte) (*config.Config, error) {
+	return func(data []byte) (*config.Config, error) {
+		load := config.LoadFromBytes
+		if strict {
+			load = config.LoadFromBytesStrict
+		}
+		cfg, err := load(data)
+		if err != nil {
+			return nil, err
+		}
+		if sampleRate >= 0 {
+			rate := sampleRate
+			cfg.Monitoring.CompressionLogSampleRate = &rate
+		}
+		if maxTokensGuard != "" {
+			cfg.Preemptive.OverflowAction = maxTokensGuard
+			if err := cfg.Preemptive.Validate(); err != nil {
+				return nil, fmt.Errorf("invalid --max-tokens-guard: %w", err)
+			}
+		}
+		return cfg, nil
+	}
+}
+
+// serveReloadSource returns the source a reload signal re-reads, or nil when
+// serve runs on the embedded default config.
+func serveReloadSource(remote *config.HTTPSource, configSource string) config.Source {
+	if remote != nil {
+		return remote
+	}
+	if _, err := os.Stat(configSource); err != nil {
+		return nil
+	}
+	return config.NewFileSource(configSource)
+}
+
+// handleReloadSignals reloads the config from src on every reload signal
+// (SIGHUP on Unix). A config that fails validation is logged and ignored.
+// A nil src (embedded default config) cannot be reloaded; signals are logged.
+func handleReloadSignals(gw *gateway.Gateway, src config.Source, configSource string) {
+	sigs := getReloadSignals()
+	if len(sigs) == 0 {
+		return
+	}
+	if src == nil {
+		log.Warn().Str("config", configSource).Msg("config reload unsupported: config is not a file or URL")
+	}
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, sigs...)
+	for sig := range sigChan {
+		if src == nil {
+			log.Warn().Str("signal", sig.String()).Str("config", configSource).Msg("config reload unsupported: config is not a file or URL, ignoring signal")
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		err := gw.ReloadConfig(ctx, src)
+		cancel()
+		if err != nil {
+			log.Error().Err(err).Str("signal", sig.String()).Str("config", src.Name()).Msg("config reload failed, keeping current config")
+			continue
+		}
+		log.Info().Str("signal", sig.String()).Str("config", src.Name()).Msg("config reloaded")
+	}
+}
+
+// announceReady polls /health until the listener accepts connections, then logs
+// "ready" and writes pidFile (if set) so systemd or container healthchecks can
+// gate on it. On a TCP port the reported pid must be ours, so another process
+// answering on the port before our listener binds is not taken for it.
+// Nothing is written if the gateway never becomes healthy.
+func announceReady(port int, unixSocket, pidFile string) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	healthURL := fmt.Sprintf("http://localhost:%d/health", port)
+	if unixSocket != "" {
+		client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", unixSocket)
+			},
+		}
+		healthURL = "http://unix/health"
+	}
+
+	deadline := time.Now().Add(serveReadyTimeout)
+	for time.Now().Before(deadline) {
+		// A unix socket is ours by construction: StartUnix refuses one in use.
+		if pid, ok := healthyPID(client, healthURL); ok && (unixSocket != "" || pid == os.Getpid()) {
+			if pidFile != "" {
+				if err := os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0600); err != nil {
+					log.Error().Err(err).Str("pid_file", pidFile).Msg("failed to write pid file")
+				}
+			}
+			log.Info().Int("pid", os.Getpid()).Msg("ready")
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	log.Warn().Dur("timeout", serveReadyTimeout).Msg("gateway did not report healthy; not marking ready")
+}
+
+// healthyPID reports whether healthURL answered healthy, with the pid the
+// gateway reported (0 if none).
+func healthyPID(client *http.Client, healthURL string) (int, bool) {
+	// #nosec G107,G704 -- health check against our own listener
+	resp, err := client.Get(healthURL)
+	if err != nil {
+		return 0, false
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return 0, false
+	}
+	var health struct {
+		PID int `json:"pid"`
+	}
+	_ = json.NewDecoder(resp.Body).Decode(&health)
+	return health.PID, true
+}
+
+// runServeStop stops a gateway left running by `agent --detach`.
+func runServeStop(args []string) {
+	fs := flag.NewFlagSet("serve stop", flag.ExitOnError)
+	port := fs.Int("port", config.DefaultGatewayBasePort, "port of the detached gateway")
+	_ = fs.Parse(args) // ExitOnError handles errors
+
+	// The gateway drains in-flight requests for up to 10s before exiting.
+	if err := launcher.StopDetached(launcher.DetachedPidFile(*port), *port, 15*time.Second); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	printSuccess(fmt.Sprintf("Gateway on port %d stopped.", *port))
+}
+
 // setupLogging configures zerolog.
 // If logFile is non-nil, logs are written there instead of stdout.
 func setupLogging(debug bool, logFile ...*os.File) {
@@ -241,9 +506,9 @@ func setupLogging(debug bool, logFile ...*os.File) {
 		out = os.Stdout
 	}
 
-	// Pretty console output
+	// Pretty console output, with keys and URL credentials masked
 	log.Logger = log.Output(zerolog.ConsoleWriter{
-		Out:        out,
+		Out:        monitoring.NewSanitizingWriter(out),
 		TimeFormat: time.RFC3339,
 	})
 
@@ -268,6 +533,14 @@ func printHelp() {
 	fmt.Println("  (none)       Launch Claude Code with gateway proxy (default)")
 	fmt.Println("  config       Configure gateway (TUI or browser)")
 	fmt.Println("  serve        Start the gateway proxy server only")
+	fmt.Println("  agent new    Create a starter agent YAML interactively")
+	fmt.Println("  store        Inspect or clear shadow store of a running gateway (store dump, store clear)")
+	fmt.Println("  embedded     List, show or validate the configs and agents built into the binary")
+	fmt.Println("  export       Bundle user configs and agents into a tar.gz")
+	fmt.Println("  import       Restore configs and agents from an export bundle")
+	fmt.Println("  replay-session  Compare configs on a captured session (offline)")
+	fmt.Println("  bench proxy  Measure latency the gateway adds over a local mock (offline)")
+	fmt.Println("  stats        Query telemetry history in SQLite (stats --sql, stats --schema)")
 	fmt.Println("  update       Update to the latest version")
 	fmt.Println("  uninstall    Remove context-gateway")
 	fmt.Println("  version      Print version information")
@@ -276,20 +549,57 @@ func printHelp() {
 	fmt.Println("Options:")
 	fmt.Println("  -c, --config FILE    Gateway config (shows menu if not specified)")
 	fmt.Println("  -p, --port PORT      Gateway port (default: 18081)")
-	fmt.Println("  -n, --name NAME      Session name (default: auto-generated)")
+	fmt.Println("  -n, --session-name N Label the session log directory and dashboard entry")
+	fmt.Println("  --env-file PATH      Load an extra .env file (repeatable; later files win)")
 	fmt.Println("  -d, --debug          Enable debug logging")
+	fmt.Println("  --quiet              Suppress banner and decorative output (or CG_QUIET=1)")
 	fmt.Println("  --proxy MODE         auto (default), start, skip")
+	fmt.Println("  --detach             Keep the gateway running after the agent exits")
+	fmt.Println("  --reuse-gateway      Share a running gateway when every port is busy")
 	fmt.Println("  --reset-api-key      Reset Compresr API key and re-run setup")
 	fmt.Println("  -l, --list           List available agents")
 	fmt.Println()
 	fmt.Println("Server Options:")
-	fmt.Println("  context-gateway serve [--config FILE] [--debug] [--no-banner]")
+	fmt.Println("  context-gateway serve [--config FILE] [--env-file PATH] [--pid-file PATH] [--debug] [--no-banner]")
+	fmt.Println("                        [--profile] [--profile-port PORT] [--unix-socket PATH]")
+	fmt.Println("                        [--compression-log-sample-rate RATE] [--strict]")
+	fmt.Println("                        [--max-tokens-guard compact|truncate|error]")
+	fmt.Println("  --profile enables pprof on 127.0.0.1:6060/debug/pprof/ (off by default)")
+	fmt.Println("  --unix-socket serves on a Unix domain socket instead of the TCP port")
+	fmt.Println("  --pid-file writes the PID once /health responds (a readiness signal for systemd/Docker)")
+	fmt.Println("  --strict fails startup on unknown config keys (typos) instead of ignoring them")
+	fmt.Println("  --compression-log-sample-rate logs only that fraction of compression events (totals in /stats stay complete)")
+	fmt.Println("  --max-tokens-guard compacts, truncates or rejects a request still over the model's context window after compression")
+	fmt.Println("  SIGHUP re-reads --config and applies it to new requests (an invalid config is rejected)")
+	fmt.Println("  context-gateway serve stop [--port PORT]")
+	fmt.Println("                        Stop a gateway left running by --detach")
 	fmt.Println()
 	fmt.Println("Examples:")
 	fmt.Println("  context-gateway                    Launch Claude Code (default)")
 	fmt.Println("  context-gateway -d                 Launch with debug logging")
 	fmt.Println("  context-gateway serve              Start gateway server only")
 	fmt.Println("  context-gateway update             Update to latest version")
+	fmt.Println("  context-gateway export --out setup.tar.gz [--include-secrets]")
+	fmt.Println("                                     Bundle configs/agents (.env only if flagged)")
+	fmt.Println("  context-gateway import setup.tar.gz")
+	fmt.Println("                                     Unpack a bundle, prompting on conflicts")
+	fmt.Println("  context-gateway replay-session --session logs/session_x --config fast_setup --config mine")
+	fmt.Println("                                     A/B compression configs on recorded traffic")
+	fmt.Println("  context-gateway bench proxy --requests 500")
+	fmt.Println("                                     p50/p95/p99 proxy overhead for capacity planning")
+	fmt.Println("  context-gateway stats --sql \"SELECT tool_name, SUM(bytes_saved) FROM compressions GROUP BY tool_name\"")
+	fmt.Println("                                     Query telemetry_sqlite_path history")
+	fmt.Println("  context-gateway config diff fast_setup ./my.yaml")
+	fmt.Println("                                     Show effective settings that differ between two configs")
+	fmt.Println("  context-gateway embedded list --validate")
+	fmt.Println("                                     Check every config and agent shipped in the binary")
+	fmt.Println("  context-gateway config validate --strict ./my.yaml")
+	fmt.Println("                                     Check a config, failing on unknown or misspelled keys")
+	fmt.Println("  context-gateway config doctor --ping --agent claude_code ./my.yaml")
+	fmt.Println("                                     Check provider keys, models and endpoints a config needs")
+	fmt.Println("  context-gateway update --version v0.5.2")
+	fmt.Println("                                     Install and pin a specific release")
+	fmt.Println("  context-gateway update --rollback  Restore the binary replaced by the last update")
 	fmt.Println("  context-gateway claude_code -- -p \"fix the bug\"")
 	fmt.Println("                                     Pass -p flag through to Claude Code")
 	fmt.Println()
@@ -320,6 +630,8 @@ func displayGatewayStatus() *tui.StatusBar {
 		return statusBar
 	}
 
-	statusBar.RenderBox()
+	if !quietMode {
+		statusBar.RenderBox()
+	}
 	return statusBar
 }
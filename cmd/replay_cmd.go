@@ -0,0 +1,92 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/replay"
+)
+
+// stringList collects a repeatable string flag (e.g. --config A --config B).
+type stringList []string
+
+func (c *stringList) String() string     { return strings.Join(*c, ",") }
+func (c *stringList) Set(v string) error { *c = append(*c, v); return nil }
+
+// runReplaySessionCommand handles "context-gateway replay-session".
+// Replays a captured session through each config against a mock upstream and
+// prints a side-by-side comparison. Never contacts a real API.
+func runReplaySessionCommand(args []string) {
+	fs := flag.NewFlagSet("replay-session", flag.ExitOnError)
+	session := fs.String("session", "", "session directory (or requests.jsonl) captured with monitoring.capture_requests")
+	var configs stringList
+	fs.Var(&configs, "config", "config name or path (repeat to compare, e.g. --config A --config B)")
+	expand := fs.Bool("expand", true, "mock model expands every newly compressed tool output")
+	_ = fs.Parse(args)
+
+	if *session == "" || len(configs) < 2 {
+		fmt.Println("Usage: context-gateway replay-session --session DIR --config A --config B [--expand=false]")
+		os.Exit(1)
+	}
+
+	reqs, err := replay.LoadSession(*session)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+	printInfo(fmt.Sprintf("Replaying %d requests from %s", len(reqs), *session))
+
+	results := make([]*replay.Result, 0, len(configs))
+	for _, name := range configs {
+		data, source, err := resolveConfig(name)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		cfg, err := config.LoadFromBytes(data)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to load config from %s: %v", source, err))
+			os.Exit(1)
+		}
+		for _, note := range replay.OfflineNotes(cfg) {
+			printWarn(fmt.Sprintf("%s: %s", name, note))
+		}
+
+		res, err := replay.Run(name, cfg, reqs, replay.Options{Expand: *expand})
+		if err != nil {
+			printError(fmt.Sprintf("Replay with %s failed: %v", name, err))
+			os.Exit(1)
+		}
+		results = append(results, res)
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CONFIG\tREQUESTS\tORIGINAL\tSENT\tRATIO\tUPSTREAM CALLS\tEXPAND CALLS\tFAILED")
+	for _, r := range results {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%.2f\t%d\t%d\t%d\n",
+			r.Name, r.Requests, formatBytes(r.OriginalBytes), formatBytes(r.SentBytes),
+			r.Ratio(), r.UpstreamCalls, r.ExpandCalls, r.Failed)
+	}
+	_ = w.Flush()
+	fmt.Println()
+	fmt.Println("RATIO = bytes sent upstream / bytes sent by the client (lower is better).")
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/monitoring"
+)
+
+// runStatsCommand handles "context-gateway stats".
+// Runs a read-only SQL query against the telemetry database written when
+// monitoring.telemetry_sqlite_path is set, or prints its schema.
+func runStatsCommand(args []string) {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	query := fs.String("sql", "", "read-only SQL query to run against the telemetry database")
+	schema := fs.Bool("schema", false, "print the telemetry database schema")
+	dbPath := fs.String("db", "", "telemetry database path (default: monitoring.telemetry_sqlite_path of --config)")
+	configName := fs.String("config", "fast_setup", "config name or path used to locate the database")
+	_ = fs.Parse(args)
+
+	if *schema {
+		fmt.Print(monitoring.TelemetrySQLiteSchema)
+		return
+	}
+	if *query == "" {
+		fmt.Println("Usage: context-gateway stats --sql \"SELECT ...\" [--db PATH | --config FILE]")
+		fmt.Println("       context-gateway stats --schema")
+		os.Exit(1)
+	}
+
+	path := *dbPath
+	if path == "" {
+		data, source, err := resolveConfig(*configName)
+		if err != nil {
+			printError(err.Error())
+			os.Exit(1)
+		}
+		cfg, err := config.LoadFromBytes(data)
+		if err != nil {
+			printError(fmt.Sprintf("Failed to load config from %s: %v", source, err))
+			os.Exit(1)
+		}
+		if path = cfg.Monitoring.TelemetrySQLitePath; path == "" {
+			printError(fmt.Sprintf("%s has no monitoring.telemetry_sqlite_path; pass --db PATH", source))
+			os.Exit(1)
+		}
+	}
+
+	columns, rows, err := monitoring.QueryTelemetrySQLite(context.Background(), path, *query)
+	if err != nil {
+		printError(err.Error())
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, strings.ToUpper(strings.Join(columns, "\t")))
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// runStoreCommand handles the "context-gateway store" subcommand.
+// Inspects or clears the shadow store of a running gateway via its debug endpoints.
+func runStoreCommand(args []string) {
+	if len(args) == 0 {
+		printStoreUsage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "dump":
+		runStoreDump(args[1:])
+	case "clear":
+		runStoreClear(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown store command: %s\n\n", args[0])
+		printStoreUsage()
+		os.Exit(1)
+	}
+}
+
+// printStoreUsage prints usage for the store subcommand.
+func printStoreUsage() {
+	fmt.Println("Usage:")
+	fmt.Println("  context-gateway store dump [--port PORT]")
+	fmt.Println("  context-gateway store clear [--port PORT]   Drop all shadow entries and cached compressions")
+	fmt.Println()
+	fmt.Println("Requires server.debug_endpoints: true in the gateway config.")
+}
+
+// runStoreDump fetches GET /debug/store and prints a table of entries.
+func runStoreDump(args []string) {
+	fs := flag.NewFlagSet("store dump", flag.ExitOnError)
+	port := fs.Int("port", 0, "gateway port (default: auto-detect)")
+	_ = fs.Parse(args)
+
+	if *port == 0 {
+		*port = findRunningGatewayPort()
+		if *port == 0 {
+			fmt.Fprintln(os.Stderr, "No running gateway found. Specify --port.")
+			os.Exit(1)
+		}
+	}
+
+	resp, err := fetchStoreDump(*port)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Store dump failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ID\tORIGINAL\tORIG TTL\tCOMPRESSED\tCOMP TTL\tEXPANDED")
+	for _, e := range resp.Entries {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%v\n",
+			e.ID,
+			formatStoreSize(e.HasOriginal, e.OriginalBytes),
+			formatStoreTTL(e.HasOriginal, e.OriginalTTLSecs),
+			formatStoreSize(e.HasCompressed, e.CompressedBytes),
+			formatStoreTTL(e.HasCompressed, e.CompressedTTLSecs),
+			e.HasExpansion,
+		)
+	}
+	_ = w.Flush()
+	fmt.Printf("\n%d entries\n", resp.Count)
+}
+
+// runStoreClear calls DELETE /debug/store and reports how much was dropped.
+func runStoreClear(args []string) {
+	fs := flag.NewFlagSet("store clear", flag.ExitOnError)
+	port := fs.Int("port", 0, "gateway port (default: auto-detect)")
+	_ = fs.Parse(args)
+
+	if *port == 0 {
+		*port = findRunningGatewayPort()
+		if *port == 0 {
+			fmt.Fprintln(os.Stderr, "No running gateway found. Specify --port.")
+			os.Exit(1)
+		}
+	}
+
+	resp, err := clearStore(*port)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Store clear failed: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Cleared %d store entries and %d cached responses\n", resp.Cleared, resp.CachedResponses)
+}
+
+// clearStore calls the debug store endpoint with DELETE on a local gateway.
+func clearStore(port int) (*gateway.DebugStoreClearResponse, error) {
+	req, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("http://localhost:%d/debug/store", port), nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gateway not reachable on port %d: %w", port, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("debug endpoints disabled (set server.debug_endpoints: true)")
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var out gateway.DebugStoreClearResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &out, nil
+}
+
+// fetchStoreDump calls the debug store endpoint on a local gateway.
+func fetchStoreDump(port int) (*gateway.DebugStoreResponse, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/debug/store", port))
+	if err != nil {
+		return nil, fmt.Errorf("gateway not reachable on port %d: %w", port, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, fmt.Errorf("debug endpoints disabled (set server.debug_endpoints: true)")
+	default:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var out gateway.DebugStoreResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return &out, nil
+}
+
+// formatStoreSize renders a byte count, or "-" when the entry is absent.
+func formatStoreSize(present bool, n int) string {
+	if !present {
+		return "-"
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// formatStoreTTL renders remaining TTL, or "-" when the entry is absent.
+func formatStoreTTL(present bool, secs int64) string {
+	if !present {
+		return "-"
+	}
+	return (time.Duration(secs) * time.Second).String()
+}
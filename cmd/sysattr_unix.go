@@ -20,12 +20,7 @@ func getShutdownSignals() []os.Signal {
 	return []os.Signal{syscall.SIGINT, syscall.SIGTERM}
 }
 
-// terminateProcess sends SIGTERM to gracefully stop a process.
-func terminateProcess(p *os.Process) error {
-	return p.Signal(syscall.SIGTERM)
-}
-
-// isProcessRunning checks if a process is still alive.
-func isProcessRunning(p *os.Process) bool {
-	return p.Signal(syscall.Signal(0)) == nil
+// getReloadSignals returns signals that make serve reload its config.
+func getReloadSignals() []os.Signal {
+	return []os.Signal{syscall.SIGHUP}
 }
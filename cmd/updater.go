@@ -5,6 +5,7 @@ import (
 	"bufio"
 	_ "embed"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,11 +14,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/updater"
 )
 
 // Version is read from cmd/VERSION file (single source of truth).
@@ -84,6 +85,30 @@ func getVersionFile() string {
 	return filepath.Join(getConfigDir(), ".version")
 }
 
+// currentInstall returns the update file locations for execPath
+// (may be "" when only the version files are needed).
+func currentInstall(execPath string) updater.Install {
+	return updater.Install{ConfigDir: getConfigDir(), ExecPath: execPath}
+}
+
+// getPinnedVersion returns the pinned version, or "" when not pinned.
+func getPinnedVersion() string {
+	return currentInstall("").PinnedVersion()
+}
+
+// resolveExecPath returns the real path of the running binary.
+func resolveExecPath() (string, error) {
+	execPath, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("failed to get executable path: %w", err)
+	}
+	execPath, err = filepath.EvalSymlinks(execPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve executable path: %w", err)
+	}
+	return execPath, nil
+}
+
 // getCurrentVersion returns the current installed version
 func getCurrentVersion() string {
 	return Version
@@ -117,6 +142,9 @@ func getLatestVersion() (string, error) {
 
 // printUpdateNotification prints the update notification box.
 func printUpdateNotification(current, latest string) {
+	if quietMode {
+		return
+	}
 	fmt.Printf("\n")
 	fmt.Printf("%s%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colorYellow, colorBold, colorReset)
 	fmt.Printf("%s%s  🔄 UPDATE AVAILABLE: %s → %s%s\n", colorYellow, colorBold, current, latest, colorReset)
@@ -128,7 +156,12 @@ func printUpdateNotification(current, latest string) {
 
 // CheckForUpdates checks if a newer version is available.
 // Called on gateway startup (serve mode).
+// Skipped when the user pinned a version via "update --version" or "update --rollback".
 func CheckForUpdates() {
+	if getPinnedVersion() != "" {
+		return
+	}
+
 	current := getCurrentVersion()
 
 	latest, err := getLatestVersion()
@@ -137,55 +170,13 @@ func CheckForUpdates() {
 		return
 	}
 
-	if !isNewerVersion(current, latest) {
+	if !updater.IsNewerVersion(current, latest) {
 		return
 	}
 
 	printUpdateNotification(current, latest)
 }
 
-// isNewerVersion returns true if latest is newer than current.
-// Handles dev versions: v0.5.2-dev is considered newer than v0.5.1.
-func isNewerVersion(current, latest string) bool {
-	// Strip "v" prefix and "-dev"/"-private" suffixes for comparison
-	cleanVersion := func(v string) string {
-		v = strings.TrimPrefix(v, "v")
-		if idx := strings.IndexByte(v, '-'); idx >= 0 {
-			v = v[:idx]
-		}
-		return v
-	}
-	currentClean := cleanVersion(current)
-	latestClean := cleanVersion(latest)
-
-	// Parse major.minor.patch
-	parseParts := func(v string) (int, int, int) {
-		parts := strings.Split(v, ".")
-		major, minor, patch := 0, 0, 0
-		if len(parts) >= 1 {
-			major, _ = strconv.Atoi(parts[0])
-		}
-		if len(parts) >= 2 {
-			minor, _ = strconv.Atoi(parts[1])
-		}
-		if len(parts) >= 3 {
-			patch, _ = strconv.Atoi(parts[2])
-		}
-		return major, minor, patch
-	}
-
-	cMaj, cMin, cPatch := parseParts(currentClean)
-	lMaj, lMin, lPatch := parseParts(latestClean)
-
-	if lMaj != cMaj {
-		return lMaj > cMaj
-	}
-	if lMin != cMin {
-		return lMin > cMin
-	}
-	return lPatch > cPatch
-}
-
 // updateCheckResult holds the result of an async update check.
 type updateCheckResult struct {
 	current string
@@ -198,6 +189,10 @@ type updateCheckResult struct {
 // available update notification. This lets the check run in parallel with
 // other startup work so it never blocks the user.
 func CheckForUpdatesAsync() func() {
+	if getPinnedVersion() != "" {
+		return func() {}
+	}
+
 	ch := make(chan updateCheckResult, 1)
 	go func() {
 		current := getCurrentVersion()
@@ -208,7 +203,7 @@ func CheckForUpdatesAsync() func() {
 	return func() {
 		select {
 		case r := <-ch:
-			if r.err != nil || !isNewerVersion(r.current, r.latest) {
+			if r.err != nil || !updater.IsNewerVersion(r.current, r.latest) {
 				return
 			}
 			printUpdateNotification(r.current, r.latest)
@@ -218,36 +213,60 @@ func CheckForUpdatesAsync() func() {
 	}
 }
 
-// DoUpdate downloads and installs the latest version
-func DoUpdate() error {
+// runUpdateCommand handles the "context-gateway update" subcommand.
+//
+//	update                  install the latest release (clears any pin)
+//	update --version vX.Y.Z install a specific release and pin it
+//	update --rollback       restore the binary backed up by the last update
+func runUpdateCommand(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	version := fs.String("version", "", "install a specific release (e.g. v0.5.2) and pin it")
+	rollback := fs.Bool("rollback", false, "restore the previously installed binary")
+	_ = fs.Parse(args)
+
+	if *rollback && *version != "" {
+		return fmt.Errorf("--rollback and --version cannot be used together")
+	}
+	if *rollback {
+		return DoRollback()
+	}
+	return DoUpdate(*version)
+}
+
+// DoUpdate downloads and installs a release. An empty targetVersion installs
+// the latest release and clears any pin; otherwise the given version is pinned.
+// The replaced binary is kept as a backup for "update --rollback".
+func DoUpdate(targetVersion string) error {
 	current := getCurrentVersion()
+	targetVersion = updater.NormalizeVersionTag(targetVersion)
 
-	fmt.Printf("\n%s%s  Checking for updates...%s\n\n", colorGreen, colorBold, colorReset)
+	target := targetVersion
+	if target == "" {
+		fmt.Printf("\n%s%s  Checking for updates...%s\n\n", colorGreen, colorBold, colorReset)
 
-	latest, err := getLatestVersion()
-	if err != nil {
-		return fmt.Errorf("failed to check for updates: %w", err)
+		latest, err := getLatestVersion()
+		if err != nil {
+			return fmt.Errorf("failed to check for updates: %w", err)
+		}
+		target = latest
 	}
 
-	if current == latest {
-		fmt.Printf("%s[✓]%s Already on latest version: %s\n", colorGreen, colorReset, current)
+	if current == target {
+		currentInstall("").SetPinnedVersion(targetVersion)
+		fmt.Printf("%s[✓]%s Already on version: %s\n", colorGreen, colorReset, current)
 		return nil
 	}
 
-	fmt.Printf("  Updating: %s%s%s → %s%s%s\n\n", colorYellow, current, colorReset, colorGreen, latest, colorReset)
+	fmt.Printf("  Updating: %s%s%s → %s%s%s\n\n", colorYellow, current, colorReset, colorGreen, target, colorReset)
 
 	// Stop any running gateway processes before replacing the binary
 	// This prevents "zsh: killed" errors on macOS when replacing a running executable
 	stopRunningGateways()
 
 	// Get binary path
-	execPath, err := os.Executable()
+	execPath, err := resolveExecPath()
 	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
-	}
-	execPath, err = filepath.EvalSymlinks(execPath)
-	if err != nil {
-		return fmt.Errorf("failed to resolve executable path: %w", err)
+		return err
 	}
 
 	// Construct download URL
@@ -259,7 +278,7 @@ func DoUpdate() error {
 	}
 
 	repo := getRepo()
-	downloadURL := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, latest, filename)
+	downloadURL := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, target, filename)
 
 	fmt.Printf("  Downloading from: %s\n", downloadURL)
 
@@ -271,6 +290,9 @@ func DoUpdate() error {
 	}
 	defer func() { _ = resp.Body.Close() }()
 
+	if resp.StatusCode == http.StatusNotFound && targetVersion != "" {
+		return fmt.Errorf("release %s not found for %s/%s", targetVersion, osName, arch)
+	}
 	if resp.StatusCode != 200 {
 		return fmt.Errorf("download failed with status %d", resp.StatusCode)
 	}
@@ -302,27 +324,12 @@ func DoUpdate() error {
 		return fmt.Errorf("failed to chmod: %w", err)
 	}
 
-	// Replace old binary
-	oldFile := execPath + ".old"
-	_ = os.Remove(oldFile)
-
-	if err := os.Rename(execPath, oldFile); err != nil {
-		// #nosec G703 -- tmpFile comes from os.CreateTemp
-		_ = os.Remove(tmpFile)
-		return fmt.Errorf("failed to backup old binary: %w", err)
-	}
-
-	// #nosec G703 -- tmpFile comes from os.CreateTemp in the executable directory
-	if err := os.Rename(tmpFile, execPath); err != nil {
-		// Try to restore old binary
-		if restoreErr := os.Rename(oldFile, execPath); restoreErr != nil {
-			return fmt.Errorf("failed to install new binary: %w (failed to restore old binary: %v)", err, restoreErr)
-		}
-		return fmt.Errorf("failed to install new binary: %w", err)
+	// Replace old binary, keeping it as the rollback backup
+	install := currentInstall(execPath)
+	if err := install.Replace(tmpFile, current); err != nil {
+		return err
 	}
-
-	// Remove old binary
-	_ = os.Remove(oldFile)
+	install.SetPinnedVersion(targetVersion)
 
 	// Print success
 	fmt.Printf("\n")
@@ -330,14 +337,49 @@ func DoUpdate() error {
 	fmt.Printf("%s%s  ✅ UPDATE COMPLETE!%s\n", colorGreen, colorBold, colorReset)
 	fmt.Printf("%s%s━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━━%s\n", colorGreen, colorBold, colorReset)
 	fmt.Printf("\n")
-	fmt.Printf("  Version: %s%s%s\n", colorGreen, latest, colorReset)
+	fmt.Printf("  Version: %s%s%s\n", colorGreen, target, colorReset)
+	if targetVersion != "" {
+		fmt.Printf("  Pinned:  update notifications disabled (run %scontext-gateway update%s to unpin)\n", colorCyan, colorReset)
+	}
 	fmt.Printf("\n")
 	fmt.Printf("  Run: %scontext-gateway%s to start\n", colorCyan, colorReset)
+	fmt.Printf("  Undo: %scontext-gateway update --rollback%s\n", colorCyan, colorReset)
 	fmt.Printf("\n")
 
 	return nil
 }
 
+// DoRollback restores the binary backed up by the last update and pins its version
+// so startup does not immediately suggest updating again.
+func DoRollback() error {
+	execPath, err := resolveExecPath()
+	if err != nil {
+		return err
+	}
+
+	install := currentInstall(execPath)
+	if err := install.CheckBackup(); err != nil {
+		return err
+	}
+
+	label := install.PreviousVersion()
+	if label == "" {
+		label = "previous version"
+	}
+
+	fmt.Printf("\n  Rolling back: %s%s%s → %s%s%s\n\n", colorYellow, getCurrentVersion(), colorReset, colorGreen, label, colorReset)
+
+	stopRunningGateways()
+
+	if _, err := install.Rollback(); err != nil {
+		return err
+	}
+
+	fmt.Printf("%s[✓]%s Restored %s\n", colorGreen, colorReset, label)
+	fmt.Printf("  Update notifications are paused. Run %scontext-gateway update%s to return to latest.\n\n", colorCyan, colorReset)
+	return nil
+}
+
 // DoUninstall removes the gateway binary and optionally configs
 func DoUninstall() error {
 	fmt.Printf("\n%s%s⚠️  UNINSTALL CONTEXT-GATEWAY%s\n\n", colorYellow, colorBold, colorReset)
@@ -378,8 +420,12 @@ func DoUninstall() error {
 		fmt.Printf("%s[✓]%s Removed %s\n", colorGreen, colorReset, compresr)
 	}
 
-	// Remove version file
+	// Remove version files and rollback backup
+	install := currentInstall(execPath)
 	_ = os.Remove(getVersionFile())
+	_ = os.Remove(install.PinnedVersionFile())
+	_ = os.Remove(install.PreviousVersionFile())
+	_ = os.Remove(install.BackupPath())
 
 	// Remove binary (self-delete)
 	// On Unix, we can delete ourselves while running
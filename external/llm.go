@@ -23,6 +23,7 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 
@@ -54,9 +55,18 @@ var defaultHTTPClient = &http.Client{
 	},
 }
 
+// VertexTokenEnv holds a Google Cloud OAuth access token for Vertex AI
+// (e.g. GOOGLE_OAUTH_ACCESS_TOKEN=$(gcloud auth print-access-token)).
+const VertexTokenEnv = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+// VertexTokenFromEnv, given as the vertex ProviderKey, reads VertexTokenEnv at
+// call time instead of once at startup: access tokens expire after ~1h and
+// the variable may be refreshed while the gateway runs.
+const VertexTokenFromEnv = "env:" + VertexTokenEnv
+
 // CallLLMParams contains parameters for calling an LLM provider.
 type CallLLMParams struct {
-	// Provider overrides auto-detection. One of: "anthropic", "openai", "gemini", "bedrock".
+	// Provider overrides auto-detection. One of: "anthropic", "openai", "gemini", "vertex", "bedrock".
 	// If empty, provider is detected from the Endpoint URL.
 	Provider string
 
@@ -118,6 +128,7 @@ type CallLLMResult struct {
 // Provider detection (when params.Provider is empty):
 //   - "anthropic" in URL → Anthropic Messages API
 //   - "generativelanguage.googleapis.com" in URL → Gemini generateContent API
+//   - "aiplatform.googleapis.com" in URL → Gemini via Vertex AI (OAuth bearer)
 //   - otherwise → OpenAI Chat Completions API
 //
 // For proxy/custom endpoints where URL doesn't identify the provider,
@@ -190,7 +201,7 @@ func CallLLM(ctx context.Context, params CallLLMParams) (*CallLLMResult, error)
 			if len(errBody) > maxErrorBodyLen {
 				errBody = errBody[:maxErrorBodyLen] + "... (truncated)"
 			}
-			lastErr = fmt.Errorf("%s API returned status %d: %s", provider, resp.StatusCode, errBody)
+			lastErr = &retry.StatusError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s API returned status %d: %s", provider, resp.StatusCode, errBody)}
 			continue
 		}
 		if resp.StatusCode != http.StatusOK {
@@ -198,7 +209,7 @@ func CallLLM(ctx context.Context, params CallLLMParams) (*CallLLMResult, error)
 			if len(errBody) > maxErrorBodyLen {
 				errBody = errBody[:maxErrorBodyLen] + "... (truncated)"
 			}
-			return nil, fmt.Errorf("%s API returned status %d: %s", provider, resp.StatusCode, errBody)
+			return nil, &retry.StatusError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("%s API returned status %d: %s", provider, resp.StatusCode, errBody)}
 		}
 
 		return parseResponse(provider, respBody)
@@ -217,6 +228,8 @@ func DetectProvider(endpoint string) string {
 		return "anthropic"
 	case strings.Contains(endpoint, "generativelanguage.googleapis.com"):
 		return "gemini"
+	case strings.Contains(endpoint, "aiplatform.googleapis.com"):
+		return "vertex"
 	case strings.Contains(endpoint, ".openai.azure.com") || strings.Contains(endpoint, "azure"):
 		return "azure"
 	default:
@@ -251,6 +264,20 @@ func setAuthHeaders(req *http.Request, provider, apiKey, bearerToken string) {
 			log.Debug().Str("provider", provider).Int("key_len", len(key)).Str("key_prefix", safePrefix(key, 10)).Msg("Setting Gemini auth header")
 			req.Header.Set("x-goog-api-key", key)
 		}
+	case "vertex":
+		// Vertex AI takes no API keys: the configured token is a Google Cloud
+		// OAuth access token sent as Authorization: Bearer. VertexTokenFromEnv
+		// re-reads VertexTokenEnv on every call since access tokens expire.
+		token := apiKey
+		if token == VertexTokenFromEnv {
+			token = os.Getenv(VertexTokenEnv)
+		}
+		if token == "" {
+			token = bearerToken
+		}
+		if token != "" {
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		}
 	case "azure":
 		// Azure OpenAI uses different headers than OpenAI:
 		// 1. API key → api-key header (NOT Authorization)
@@ -299,7 +326,8 @@ func buildRequestBody(provider string, params CallLLMParams) ([]byte, error) {
 			req.AnthropicVersion = "bedrock-2023-05-31"
 		}
 		return json.Marshal(req)
-	case "gemini":
+	case "gemini", "vertex":
+		// Vertex serves Gemini models with the same generateContent format
 		return json.Marshal(&GeminiRequest{
 			SystemInstruction: &GeminiContent{
 				Parts: []GeminiPart{{Text: params.SystemPrompt}},
@@ -342,7 +370,7 @@ func parseResponse(provider string, body []byte) (*CallLLMResult, error) {
 		result.InputTokens = resp.Usage.InputTokens
 		result.OutputTokens = resp.Usage.OutputTokens
 
-	case "gemini":
+	case "gemini", "vertex":
 		var resp GeminiResponse
 		if err := json.Unmarshal(body, &resp); err != nil {
 			return nil, fmt.Errorf("failed to parse %s response: %w", provider, err)
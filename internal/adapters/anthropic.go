@@ -99,6 +99,7 @@ func (a *AnthropicAdapter) extractToolOutputsFromMessages(messages []any) []Extr
 			toolUseID, _ := blockMap["tool_use_id"].(string)
 			content := a.extractBlockContent(blockMap)
 			if content != "" {
+				isErr, _ := blockMap["is_error"].(bool)
 				extracted = append(extracted, ExtractedContent{
 					ID:           toolUseID,
 					Content:      content,
@@ -107,6 +108,7 @@ func (a *AnthropicAdapter) extractToolOutputsFromMessages(messages []any) []Extr
 					ToolName:     toolNames[toolUseID],
 					MessageIndex: msgIdx,
 					BlockIndex:   blockIdx,
+					IsError:      isErr,
 				})
 			}
 		}
@@ -128,7 +130,11 @@ func (a *AnthropicAdapter) ApplyToolOutput(body []byte, results []CompressedResu
 		// Anthropic: messages[N].content[M].content where M is the tool_result block
 		path := fmt.Sprintf("messages.%d.content.%d.content", r.MessageIndex, r.BlockIndex)
 		var err error
-		modified, err = sjson.SetBytes(modified, path, r.Compressed)
+		if existing := gjson.GetBytes(modified, path); existing.IsArray() {
+			modified, err = setBlockArrayText(modified, path, existing, r.Compressed)
+		} else {
+			modified, err = sjson.SetBytes(modified, path, r.Compressed)
+		}
 		if err != nil {
 			log.Warn().Err(err).Str("path", path).Str("id", r.ID).
 				Msg("sjson set failed for tool output, skipping")
@@ -138,6 +144,160 @@ func (a *AnthropicAdapter) ApplyToolOutput(body []byte, results []CompressedResu
 	return modified, nil
 }
 
+// setBlockArrayText rewrites an array-form tool_result content in place so the
+// request keeps its shape: the first text block carries text (other keys such as
+// cache_control kept), later text blocks are dropped since extractBlockContent
+// concatenated them, and non-text blocks (images) stay where they were.
+// An array with no text block gets one appended.
+func setBlockArrayText(body []byte, path string, blocks gjson.Result, text string) ([]byte, error) {
+	var parts []string
+	placed := false
+	for _, block := range blocks.Array() {
+		if block.Get("type").String() != "text" {
+			parts = append(parts, block.Raw)
+			continue
+		}
+		if placed {
+			continue
+		}
+		raw, err := sjson.Set(block.Raw, "text", text)
+		if err != nil {
+			return body, err
+		}
+		parts = append(parts, raw)
+		placed = true
+	}
+	if !placed {
+		raw, err := sjson.Set(`{"type":"text"}`, "text", text)
+		if err != nil {
+			return body, err
+		}
+		parts = append(parts, raw)
+	}
+	return sjson.SetRawBytes(body, path, []byte("["+strings.Join(parts, ",")+"]"))
+}
+
+// ExtractEmptyToolOutputs returns tool_result blocks with empty or whitespace-only
+// content. Error results (is_error: true) are left alone.
+func (a *AnthropicAdapter) ExtractEmptyToolOutputs(body []byte) ([]ExtractedContent, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	messages, _ := req["messages"].([]any)
+
+	var empty []ExtractedContent
+	for msgIdx, msgAny := range messages {
+		msg, ok := msgAny.(map[string]any)
+		if !ok || getString(msg, "role") != "user" {
+			continue
+		}
+		contentArr, ok := msg["content"].([]any)
+		if !ok {
+			continue
+		}
+		for blockIdx, block := range contentArr {
+			blockMap, ok := block.(map[string]any)
+			if !ok || getString(blockMap, "type") != "tool_result" {
+				continue
+			}
+			if isErr, _ := blockMap["is_error"].(bool); isErr {
+				continue
+			}
+			if isBlankContent(blockMap["content"]) {
+				empty = append(empty, ExtractedContent{
+					ID:           getString(blockMap, "tool_use_id"),
+					ContentType:  "tool_result",
+					MessageIndex: msgIdx,
+					BlockIndex:   blockIdx,
+				})
+			}
+		}
+	}
+	return empty, nil
+}
+
+// DropDuplicateToolResults keeps only the last tool_result block per tool_use_id
+// within each user message.
+func (a *AnthropicAdapter) DropDuplicateToolResults(body []byte) ([]byte, []string, error) {
+	if !gjson.ValidBytes(body) {
+		return body, nil, fmt.Errorf("failed to parse request: invalid JSON")
+	}
+
+	var paths, dupIDs []string
+	for msgIdx, msg := range gjson.GetBytes(body, "messages").Array() {
+		if msg.Get("role").String() != "user" {
+			continue
+		}
+		blocks := msg.Get("content").Array()
+		last := make(map[string]int)
+		for blockIdx, block := range blocks {
+			if block.Get("type").String() == "tool_result" {
+				last[block.Get("tool_use_id").String()] = blockIdx
+			}
+		}
+		// Descending order, so deleting one block keeps earlier indices valid.
+		for blockIdx := len(blocks) - 1; blockIdx >= 0; blockIdx-- {
+			block := blocks[blockIdx]
+			if block.Get("type").String() != "tool_result" {
+				continue
+			}
+			if id := block.Get("tool_use_id").String(); last[id] != blockIdx {
+				paths = append(paths, fmt.Sprintf("messages.%d.content.%d", msgIdx, blockIdx))
+				dupIDs = append(dupIDs, id)
+			}
+		}
+	}
+	return deletePaths(body, paths, dupIDs)
+}
+
+// ExtractToolInputs returns the string arguments of tool_use blocks in assistant
+// messages before the last one, which holds the call being executed.
+func (a *AnthropicAdapter) ExtractToolInputs(body []byte) ([]ExtractedContent, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("failed to parse request: invalid JSON")
+	}
+	messages := gjson.GetBytes(body, "messages").Array()
+	last := -1
+	for i, msg := range messages {
+		if msg.Get("role").String() == "assistant" {
+			last = i
+		}
+	}
+
+	var inputs []ExtractedContent
+	for msgIdx := 0; msgIdx < last; msgIdx++ {
+		if messages[msgIdx].Get("role").String() != "assistant" {
+			continue
+		}
+		for blockIdx, block := range messages[msgIdx].Get("content").Array() {
+			if block.Get("type").String() != "tool_use" {
+				continue
+			}
+			inputs = append(inputs, toolInputArgs(block.Get("input"), block.Get("id").String(),
+				block.Get("name").String(), msgIdx, blockIdx)...)
+		}
+	}
+	return inputs, nil
+}
+
+// ApplyToolInputs replaces tool_use input arguments at messages[N].content[M].input.<field>.
+func (a *AnthropicAdapter) ApplyToolInputs(body []byte, results []CompressedResult) ([]byte, error) {
+	modified := body
+	for i := len(results) - 1; i >= 0; i-- {
+		r := results[i]
+		path := fmt.Sprintf("messages.%d.content.%d.input.%s", r.MessageIndex, r.BlockIndex, gjson.Escape(r.Field))
+		updated, err := sjson.SetBytes(modified, path, r.Compressed)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Str("id", r.ID).
+				Msg("sjson set failed for tool input, skipping")
+			continue
+		}
+		modified = updated
+	}
+	return modified, nil
+}
+
 // TOOL DISCOVERY - Extract/Apply
 
 // ExtractToolDiscovery extracts tool definitions for filtering.
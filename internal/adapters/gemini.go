@@ -82,6 +82,8 @@ func (a *GeminiAdapter) ExtractToolOutput(body []byte) ([]ExtractedContent, erro
 			respContent := a.extractResponseContent(fnResp["response"])
 
 			if respContent != "" {
+				respMap, _ := fnResp["response"].(map[string]any)
+				_, isErr := respMap["error"]
 				extracted = append(extracted, ExtractedContent{
 					ID:           fmt.Sprintf("%d_%d", msgIdx, partIdx),
 					Content:      respContent,
@@ -89,6 +91,7 @@ func (a *GeminiAdapter) ExtractToolOutput(body []byte) ([]ExtractedContent, erro
 					ToolName:     name,
 					MessageIndex: msgIdx,
 					BlockIndex:   partIdx,
+					IsError:      isErr,
 				})
 			}
 		}
@@ -123,6 +126,62 @@ func (a *GeminiAdapter) ApplyToolOutput(body []byte, results []CompressedResult)
 	return modified, nil
 }
 
+// ExtractEmptyToolOutputs returns functionResponse parts whose response is
+// missing, an empty object, or a single-key wrapper around a blank string.
+func (a *GeminiAdapter) ExtractEmptyToolOutputs(body []byte) ([]ExtractedContent, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	contents, _ := req["contents"].([]any)
+
+	var empty []ExtractedContent
+	for msgIdx, contentAny := range contents {
+		content, ok := contentAny.(map[string]any)
+		if !ok {
+			continue
+		}
+		parts, _ := content["parts"].([]any)
+		for partIdx, partAny := range parts {
+			part, ok := partAny.(map[string]any)
+			if !ok {
+				continue
+			}
+			fnResp, ok := part["functionResponse"].(map[string]any)
+			if !ok || !a.isBlankResponse(fnResp["response"]) {
+				continue
+			}
+			empty = append(empty, ExtractedContent{
+				ID:           fmt.Sprintf("%d_%d", msgIdx, partIdx),
+				ContentType:  "tool_result",
+				ToolName:     getString(fnResp, "name"),
+				MessageIndex: msgIdx,
+				BlockIndex:   partIdx,
+			})
+		}
+	}
+	return empty, nil
+}
+
+// isBlankResponse reports whether a functionResponse.response carries no content.
+func (a *GeminiAdapter) isBlankResponse(v any) bool {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return isBlankContent(v)
+	}
+	if len(m) == 0 {
+		return true
+	}
+	if len(m) == 1 {
+		for _, val := range m {
+			if s, ok := val.(string); ok {
+				return strings.TrimSpace(s) == ""
+			}
+		}
+	}
+	return false
+}
+
 // TOOL DISCOVERY - Extract/Apply (stub)
 
 // ExtractToolDiscovery extracts tool definitions for filtering.
@@ -1,7 +1,12 @@
 // helpers.go contains small shared utilities used across multiple adapters.
 package adapters
 
-import "strings"
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
 
 // getString safely extracts a string value from a map by key.
 // Returns "" if the key is missing or the value is not a string.
@@ -44,3 +49,86 @@ func extractStringContent(v any) string {
 	}
 	return b.String()
 }
+
+// isBlankContent reports whether tool result content carries no text and no other
+// parts: nil, a whitespace-only string, or an array of whitespace-only text blocks.
+// Arrays holding images or other non-text parts are not blank.
+func isBlankContent(v any) bool {
+	switch c := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(c) == ""
+	case []any:
+		for _, it := range c {
+			m, ok := it.(map[string]any)
+			if !ok {
+				return false
+			}
+			text, ok := m["text"].(string)
+			if !ok || strings.TrimSpace(text) != "" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// looksLikeToolError applies the error convention for formats without an error
+// flag (OpenAI): output starting with "Error:" (any case), or Codex-style JSON
+// output whose metadata.exit_code is non-zero.
+func looksLikeToolError(content string) bool {
+	trimmed := strings.TrimSpace(content)
+	if len(trimmed) >= 6 && strings.EqualFold(trimmed[:6], "error:") {
+		return true
+	}
+	if strings.HasPrefix(trimmed, "{") {
+		if code := gjson.Get(trimmed, "metadata.exit_code"); code.Exists() && code.Int() != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// deletePaths removes the given JSON paths from body in order; callers list them
+// so that each deletion leaves the indices of the remaining paths valid.
+// dupIDs is passed through for the DropDuplicateToolResults return value.
+func deletePaths(body []byte, paths, dupIDs []string) ([]byte, []string, error) {
+	if len(paths) == 0 {
+		return body, nil, nil
+	}
+	modified := body
+	for _, path := range paths {
+		var err error
+		if modified, err = sjson.DeleteBytes(modified, path); err != nil {
+			return body, nil, err
+		}
+	}
+	return modified, dupIDs, nil
+}
+
+// toolInputArgs returns the non-empty top-level string arguments of a tool call
+// input object as tool_input extractions. Nested and non-string values are skipped.
+func toolInputArgs(input gjson.Result, id, name string, msgIdx, blockIdx int) []ExtractedContent {
+	if !input.IsObject() {
+		return nil
+	}
+	var args []ExtractedContent
+	input.ForEach(func(key, value gjson.Result) bool {
+		if value.Type == gjson.String && value.Str != "" {
+			args = append(args, ExtractedContent{
+				ID:           id,
+				Content:      value.Str,
+				ContentType:  "tool_input",
+				Format:       DetectContentFormat(value.Str),
+				ToolName:     name,
+				MessageIndex: msgIdx,
+				BlockIndex:   blockIdx,
+				Field:        key.String(),
+			})
+		}
+		return true
+	})
+	return args
+}
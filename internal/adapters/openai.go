@@ -83,6 +83,7 @@ func (a *OpenAIAdapter) extractResponsesAPIItems(items []any) []ExtractedContent
 					Format:       DetectContentFormat(content),
 					ToolName:     toolNames[callID],
 					MessageIndex: i,
+					IsError:      looksLikeToolError(content),
 				})
 			}
 		}
@@ -140,6 +141,7 @@ func (a *OpenAIAdapter) extractChatCompletionsMessages(messages []any) []Extract
 				Format:       DetectContentFormat(content),
 				ToolName:     toolNames[callID],
 				MessageIndex: i,
+				IsError:      looksLikeToolError(content),
 			})
 		}
 	}
@@ -170,7 +172,12 @@ func (a *OpenAIAdapter) ApplyToolOutput(body []byte, results []CompressedResult)
 			path = fmt.Sprintf("messages.%d.content", r.MessageIndex)
 		}
 		var err error
-		modified, err = sjson.SetBytes(modified, path, r.Compressed)
+		if existing := gjson.GetBytes(modified, path); existing.IsArray() {
+			// Content parts: compressed text replaces the text parts, other parts are kept
+			modified, err = sjson.SetRawBytes(modified, path, replaceTextParts(existing, r.Compressed))
+		} else {
+			modified, err = sjson.SetBytes(modified, path, r.Compressed)
+		}
 		if err != nil {
 			log.Warn().Err(err).Str("path", path).Str("id", r.ID).
 				Msg("sjson set failed for tool output, skipping")
@@ -180,6 +187,192 @@ func (a *OpenAIAdapter) ApplyToolOutput(body []byte, results []CompressedResult)
 	return modified, nil
 }
 
+// ExtractEmptyToolOutputs returns tool results with empty or whitespace-only content.
+// Supports both Responses API (function_call_output) and Chat Completions (role=tool).
+func (a *OpenAIAdapter) ExtractEmptyToolOutputs(body []byte) ([]ExtractedContent, error) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+
+	var empty []ExtractedContent
+	if input, ok := req["input"]; ok && input != nil {
+		if _, hasMessages := req["messages"]; !hasMessages {
+			items, _ := input.([]any)
+			for i, item := range items {
+				m, ok := item.(map[string]any)
+				if !ok || getString(m, "type") != "function_call_output" {
+					continue
+				}
+				if callID := getString(m, "call_id"); callID != "" && isBlankContent(m["output"]) {
+					empty = append(empty, ExtractedContent{ID: callID, ContentType: "tool_result", MessageIndex: i})
+				}
+			}
+			return empty, nil
+		}
+	}
+	messages, _ := req["messages"].([]any)
+	for i, msgAny := range messages {
+		msg, ok := msgAny.(map[string]any)
+		if !ok || getString(msg, "role") != "tool" {
+			continue
+		}
+		if callID := getString(msg, "tool_call_id"); callID != "" && isBlankContent(msg["content"]) {
+			empty = append(empty, ExtractedContent{ID: callID, ContentType: "tool_result", MessageIndex: i})
+		}
+	}
+	return empty, nil
+}
+
+// DropDuplicateToolResults keeps only the last tool result per call ID within
+// each run of consecutive results (role=tool messages for Chat Completions,
+// function_call_output items for the Responses API).
+func (a *OpenAIAdapter) DropDuplicateToolResults(body []byte) ([]byte, []string, error) {
+	if !gjson.ValidBytes(body) {
+		return body, nil, fmt.Errorf("failed to parse request: invalid JSON")
+	}
+
+	arrayPath, idField := "messages", "tool_call_id"
+	isResult := func(item gjson.Result) bool { return item.Get("role").String() == "tool" }
+	if isResponsesAPIBody(body) {
+		arrayPath, idField = "input", "call_id"
+		isResult = func(item gjson.Result) bool { return item.Get("type").String() == "function_call_output" }
+	}
+
+	items := gjson.GetBytes(body, arrayPath).Array()
+	var paths, dupIDs []string
+	// Walk backwards so the first occurrence seen in a run is the last one sent,
+	// and deletions keep earlier indices valid.
+	seen := make(map[string]bool)
+	for i := len(items) - 1; i >= 0; i-- {
+		if !isResult(items[i]) {
+			seen = make(map[string]bool)
+			continue
+		}
+		id := items[i].Get(idField).String()
+		if seen[id] {
+			paths = append(paths, fmt.Sprintf("%s.%d", arrayPath, i))
+			dupIDs = append(dupIDs, id)
+		}
+		seen[id] = true
+	}
+	return deletePaths(body, paths, dupIDs)
+}
+
+// ExtractToolInputs returns the string arguments of earlier tool calls.
+// Chat Completions: tool_calls of assistant messages before the last one.
+// Responses API: function_call items before the trailing run of function_call
+// (and reasoning) items, which is the action being executed.
+func (a *OpenAIAdapter) ExtractToolInputs(body []byte) ([]ExtractedContent, error) {
+	if !gjson.ValidBytes(body) {
+		return nil, fmt.Errorf("failed to parse request: invalid JSON")
+	}
+
+	var inputs []ExtractedContent
+	if isResponsesAPIBody(body) {
+		items := gjson.GetBytes(body, "input").Array()
+		start := -1
+		for i := len(items) - 1; i >= 0; i-- {
+			itemType := items[i].Get("type").String()
+			if itemType == "function_call" {
+				start = i
+			} else if start >= 0 && itemType != "reasoning" {
+				break
+			}
+		}
+		for i := 0; i < start; i++ {
+			if items[i].Get("type").String() != "function_call" {
+				continue
+			}
+			args := gjson.Parse(items[i].Get("arguments").String())
+			inputs = append(inputs, toolInputArgs(args, items[i].Get("call_id").String(),
+				items[i].Get("name").String(), i, 0)...)
+		}
+		return inputs, nil
+	}
+
+	messages := gjson.GetBytes(body, "messages").Array()
+	last := -1
+	for i, msg := range messages {
+		if msg.Get("role").String() == "assistant" {
+			last = i
+		}
+	}
+	for msgIdx := 0; msgIdx < last; msgIdx++ {
+		if messages[msgIdx].Get("role").String() != "assistant" {
+			continue
+		}
+		for callIdx, call := range messages[msgIdx].Get("tool_calls").Array() {
+			args := gjson.Parse(call.Get("function.arguments").String())
+			inputs = append(inputs, toolInputArgs(args, call.Get("id").String(),
+				call.Get("function.name").String(), msgIdx, callIdx)...)
+		}
+	}
+	return inputs, nil
+}
+
+// ApplyToolInputs replaces arguments inside the JSON-encoded arguments string.
+// Chat Completions: messages[N].tool_calls[M].function.arguments; Responses API:
+// input[N].arguments.
+func (a *OpenAIAdapter) ApplyToolInputs(body []byte, results []CompressedResult) ([]byte, error) {
+	isResponsesAPI := isResponsesAPIBody(body)
+
+	modified := body
+	for i := len(results) - 1; i >= 0; i-- {
+		r := results[i]
+		path := fmt.Sprintf("messages.%d.tool_calls.%d.function.arguments", r.MessageIndex, r.BlockIndex)
+		if isResponsesAPI {
+			path = fmt.Sprintf("input.%d.arguments", r.MessageIndex)
+		}
+		args, err := sjson.Set(gjson.GetBytes(modified, path).String(), gjson.Escape(r.Field), r.Compressed)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Str("id", r.ID).
+				Msg("sjson set failed for tool input, skipping")
+			continue
+		}
+		updated, err := sjson.SetBytes(modified, path, args)
+		if err != nil {
+			log.Warn().Err(err).Str("path", path).Str("id", r.ID).
+				Msg("sjson set failed for tool input, skipping")
+			continue
+		}
+		modified = updated
+	}
+	return modified, nil
+}
+
+// isResponsesAPIBody reports whether body is a Responses API request ("input"
+// without "messages").
+func isResponsesAPIBody(body []byte) bool {
+	return gjson.GetBytes(body, "input").Exists() && !gjson.GetBytes(body, "messages").Exists()
+}
+
+// replaceTextParts rewrites a content-part array so the first text part carries
+// text and later text parts are dropped (extraction joined them into one string).
+// Non-text parts (images, files) keep their original bytes and positions.
+func replaceTextParts(parts gjson.Result, text string) []byte {
+	out := []byte("[]")
+	replaced := false
+	parts.ForEach(func(_, part gjson.Result) bool {
+		raw := []byte(part.Raw)
+		if part.Get("text").Type == gjson.String {
+			if replaced {
+				return true
+			}
+			replaced = true
+			if updated, err := sjson.SetBytes(raw, "text", text); err == nil {
+				raw = updated
+			}
+		}
+		out, _ = sjson.SetRawBytes(out, "-1", raw)
+		return true
+	})
+	if !replaced {
+		out, _ = sjson.SetBytes(out, "-1", map[string]any{"type": "text", "text": text})
+	}
+	return out
+}
+
 // TOOL DISCOVERY - Extract/Apply
 
 // ExtractToolDiscovery extracts tool definitions for filtering.
@@ -41,7 +41,7 @@ func detectProvider(path string, headers http.Header) Provider {
 			return ProviderAnthropic
 		case "openai":
 			return ProviderOpenAI
-		case "gemini":
+		case "gemini", "vertex":
 			return ProviderGemini
 		case "bedrock":
 			return ProviderBedrock
@@ -95,8 +95,9 @@ func detectProvider(path string, headers http.Header) Provider {
 		return ProviderOpenAI
 	}
 
-	// 7. Check Gemini
+	// 7. Check Gemini (public API or Vertex AI publisher models)
 	if strings.Contains(path, "generativelanguage.googleapis.com") ||
+		strings.Contains(path, "/publishers/google/models/") ||
 		headers.Get("x-goog-api-key") != "" {
 		return ProviderGemini
 	}
@@ -0,0 +1,170 @@
+// Package adapters - tool_pairing.go checks that tool calls and tool results stay paired.
+package adapters
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ToolPairingError lists tool calls and results in a request that lost their partner.
+// Providers reject such requests outright, so a rewrite that produces one must not be sent.
+type ToolPairingError struct {
+	OrphanResults   []string // tool_result / tool / function_call_output IDs with no earlier call
+	UnansweredCalls []string // tool_use / tool_calls / function_call IDs never answered by a result
+}
+
+// Error implements error.
+func (e *ToolPairingError) Error() string {
+	var parts []string
+	if len(e.OrphanResults) > 0 {
+		parts = append(parts, fmt.Sprintf("tool results without a tool call: %s", strings.Join(e.OrphanResults, ", ")))
+	}
+	if len(e.UnansweredCalls) > 0 {
+		parts = append(parts, fmt.Sprintf("tool calls without a tool result: %s", strings.Join(e.UnansweredCalls, ", ")))
+	}
+	return "tool pairing broken: " + strings.Join(parts, "; ")
+}
+
+// ValidateToolPairing verifies that every tool result references a tool call made
+// earlier in the conversation, and that every tool call is answered before the next
+// assistant turn. Returns a *ToolPairingError describing the mismatches, or nil.
+//
+//	Anthropic:            assistant content[type=tool_use].id ↔ user content[type=tool_result].tool_use_id
+//	OpenAI Chat:          assistant tool_calls[].id ↔ role:"tool" tool_call_id
+//	OpenAI Responses API: input[type=function_call].call_id ↔ input[type=function_call_output].call_id
+//
+// A trailing assistant message is not checked for answers (nothing follows it yet).
+// Results without an ID (Ollama) answer the oldest pending call by position.
+// Formats without call IDs at all (Gemini) always pass.
+func ValidateToolPairing(body []byte) error {
+	if input := gjson.GetBytes(body, "input"); input.IsArray() && !gjson.GetBytes(body, "messages").Exists() {
+		return validateInputItemPairing(input)
+	}
+
+	messages := gjson.GetBytes(body, "messages").Array()
+	seen := make(map[string]bool)
+	var pending []string // calls of the latest assistant message still awaiting a result
+	answered := make(map[string]bool)
+	pairErr := &ToolPairingError{}
+
+	flushPending := func() {
+		for _, id := range pending {
+			if !answered[id] {
+				pairErr.UnansweredCalls = append(pairErr.UnansweredCalls, id)
+			}
+		}
+		pending = nil
+	}
+
+	for i, msg := range messages {
+		switch msg.Get("role").String() {
+		case "assistant":
+			flushPending()
+			var calls []string
+			msg.Get("content").ForEach(func(_, block gjson.Result) bool {
+				if block.Get("type").String() == "tool_use" {
+					calls = append(calls, block.Get("id").String())
+				}
+				return true
+			})
+			msg.Get("tool_calls").ForEach(func(_, tc gjson.Result) bool {
+				calls = append(calls, tc.Get("id").String())
+				return true
+			})
+			calls = withIDs(calls)
+			for _, id := range calls {
+				seen[id] = true
+			}
+			if i < len(messages)-1 {
+				pending = calls
+			}
+		case "user":
+			msg.Get("content").ForEach(func(_, block gjson.Result) bool {
+				if block.Get("type").String() == "tool_result" {
+					pairErr.recordResult(block.Get("tool_use_id").String(), seen, answered)
+				}
+				return true
+			})
+		case "tool":
+			id := msg.Get("tool_call_id").String()
+			if id == "" {
+				id = firstUnanswered(pending, answered)
+			}
+			pairErr.recordResult(id, seen, answered)
+		}
+	}
+	flushPending()
+
+	if len(pairErr.OrphanResults) == 0 && len(pairErr.UnansweredCalls) == 0 {
+		return nil
+	}
+	return pairErr
+}
+
+// validateInputItemPairing checks Responses API input[] items, where outputs may
+// appear anywhere after their function_call.
+func validateInputItemPairing(input gjson.Result) error {
+	seen := make(map[string]bool)
+	answered := make(map[string]bool)
+	var calls []string
+	pairErr := &ToolPairingError{}
+
+	input.ForEach(func(_, item gjson.Result) bool {
+		switch item.Get("type").String() {
+		case "function_call":
+			if id := item.Get("call_id").String(); id != "" {
+				seen[id] = true
+				calls = append(calls, id)
+			}
+		case "function_call_output":
+			pairErr.recordResult(item.Get("call_id").String(), seen, answered)
+		}
+		return true
+	})
+	for _, id := range calls {
+		if !answered[id] {
+			pairErr.UnansweredCalls = append(pairErr.UnansweredCalls, id)
+		}
+	}
+
+	if len(pairErr.OrphanResults) == 0 && len(pairErr.UnansweredCalls) == 0 {
+		return nil
+	}
+	return pairErr
+}
+
+// recordResult marks a result as answering its call, or records it as orphaned.
+// Results without an ID are skipped.
+func (e *ToolPairingError) recordResult(id string, seen, answered map[string]bool) {
+	if id == "" {
+		return
+	}
+	if !seen[id] {
+		e.OrphanResults = append(e.OrphanResults, id)
+		return
+	}
+	answered[id] = true
+}
+
+// firstUnanswered returns the oldest pending call without a result, or "".
+func firstUnanswered(pending []string, answered map[string]bool) string {
+	for _, id := range pending {
+		if !answered[id] {
+			return id
+		}
+	}
+	return ""
+}
+
+// withIDs drops empty call IDs.
+func withIDs(ids []string) []string {
+	out := ids[:0]
+	for _, id := range ids {
+		if id != "" {
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -38,6 +38,14 @@ type ExtractedContent struct {
 	// BlockIndex is the position within content blocks (Anthropic format)
 	BlockIndex int
 
+	// Field is the tool call argument name (tool_input only)
+	Field string
+
+	// IsError marks a tool result the client flagged as failed: Anthropic
+	// is_error, a Gemini response with an "error" key, or for OpenAI (which has
+	// no flag) output following the looksLikeToolError convention.
+	IsError bool
+
 	// Metadata holds provider-specific data needed for Apply
 	Metadata map[string]any
 }
@@ -67,6 +75,9 @@ type CompressedResult struct {
 	// BlockIndex is the position within content blocks (Anthropic format).
 	// Used together with MessageIndex for precise sjson path targeting.
 	BlockIndex int
+
+	// Field is the tool call argument name (from ExtractedContent, tool_input only).
+	Field string
 }
 
 // EXTRACT OPTIONS - Configuration for extraction
@@ -235,3 +246,34 @@ type ParsedRequestAdapter interface {
 	// ApplyToolDiscoveryToParsed filters tools and returns modified body.
 	ApplyToolDiscoveryToParsed(parsed *ParsedRequest, results []CompressedResult) ([]byte, error)
 }
+
+// EmptyToolOutputAdapter is an optional interface for adapters that can locate
+// tool results with empty or whitespace-only content. ExtractToolOutput skips
+// those, so they are reported separately; ApplyToolOutput patches them back.
+type EmptyToolOutputAdapter interface {
+	// ExtractEmptyToolOutputs returns tool results whose content is empty or
+	// whitespace-only. Content is left unset.
+	ExtractEmptyToolOutputs(body []byte) ([]ExtractedContent, error)
+}
+
+// DuplicateToolResultAdapter is an optional interface for adapters that can drop
+// repeated tool results for the same call ID. Providers reject a request that
+// answers one tool call twice, so only the last result per ID is kept.
+type DuplicateToolResultAdapter interface {
+	// DropDuplicateToolResults removes every result but the last for each call ID
+	// within one turn and returns the modified body with the IDs that repeated.
+	// The body is returned unchanged when there are no duplicates.
+	DropDuplicateToolResults(body []byte) ([]byte, []string, error)
+}
+
+// ToolInputAdapter is an optional interface for adapters that can locate string
+// arguments of tool calls in the conversation history. The latest assistant
+// turn is excluded: its tool calls are the action being executed.
+type ToolInputAdapter interface {
+	// ExtractToolInputs returns the top-level string arguments of earlier tool
+	// calls. ID is the tool call ID and Field the argument name.
+	ExtractToolInputs(body []byte) ([]ExtractedContent, error)
+
+	// ApplyToolInputs replaces the argument values named by Field.
+	ApplyToolInputs(body []byte, results []CompressedResult) ([]byte, error)
+}
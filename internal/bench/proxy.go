@@ -0,0 +1,176 @@
+// Package bench measures gateway overhead against an in-process mock upstream.
+//
+// Everything runs locally: the upstream is an httptest server and the gateway
+// config is rewritten with replay.PrepareOffline, so no request leaves the machine.
+package bench
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"time"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/replay"
+)
+
+// maxRateLimitRetries bounds retries when the gateway's per-IP limiter rejects a bench request.
+const maxRateLimitRetries = 3
+
+// proxyRequestBody is the small Anthropic request every iteration sends.
+var proxyRequestBody = []byte(`{"model":"claude-sonnet-4-5","max_tokens":64,"messages":[{"role":"user","content":"ping"}]}`)
+
+// proxyResponseBody is what the mock upstream answers with.
+var proxyResponseBody = []byte(`{"id":"msg_bench","type":"message","role":"assistant","model":"claude-sonnet-4-5",` +
+	`"content":[{"type":"text","text":"pong"}],"stop_reason":"end_turn","usage":{"input_tokens":8,"output_tokens":1}}`)
+
+// ProxyOptions controls the proxy benchmark.
+type ProxyOptions struct {
+	Requests int // timed request pairs (direct + proxied)
+	Warmup   int // untimed pairs sent first to open connections and fill caches
+}
+
+// Latencies holds percentiles of a latency sample.
+type Latencies struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// ProxyResult summarizes a proxy benchmark.
+type ProxyResult struct {
+	Requests int       // timed request pairs
+	Direct   Latencies // client -> mock upstream
+	Proxied  Latencies // client -> gateway -> mock upstream
+	Added    Latencies // per-pair proxied minus direct
+}
+
+// Proxy sends opts.Requests small requests to a mock upstream both directly and
+// through a gateway built from cfg, and reports the latency the gateway adds.
+// Direct and proxied requests alternate over the same keep-alive client so both
+// paths see the same machine load. Proxy takes ownership of cfg.
+func Proxy(cfg *config.Config, opts ProxyOptions) (*ProxyResult, error) {
+	if opts.Requests <= 0 {
+		return nil, fmt.Errorf("requests must be positive, got %d", opts.Requests)
+	}
+
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(proxyResponseBody)
+	}))
+	defer mock.Close()
+
+	replay.PrepareOffline(cfg, mock.URL)
+	gateway.EnableLocalHostsForReplay()
+	gw := gateway.New(cfg)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = gw.Shutdown(ctx)
+	}()
+	proxy := httptest.NewServer(gw.Handler())
+	defer proxy.Close()
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	directURL := mock.URL + "/v1/messages"
+	proxyURL := proxy.URL + "/v1/messages"
+
+	direct := make([]time.Duration, 0, opts.Requests)
+	proxied := make([]time.Duration, 0, opts.Requests)
+	added := make([]time.Duration, 0, opts.Requests)
+	for i := 0; i < opts.Warmup+opts.Requests; i++ {
+		// Alternate which path goes first so neither always runs on a warmer cache.
+		var d, p time.Duration
+		var err error
+		if i%2 == 0 {
+			if d, err = timeRequest(client, directURL, ""); err == nil {
+				p, err = timeRequest(client, proxyURL, directURL)
+			}
+		} else {
+			if p, err = timeRequest(client, proxyURL, directURL); err == nil {
+				d, err = timeRequest(client, directURL, "")
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i+1, err)
+		}
+		if i < opts.Warmup {
+			continue
+		}
+		direct = append(direct, d)
+		proxied = append(proxied, p)
+		added = append(added, p-d)
+	}
+
+	return &ProxyResult{
+		Requests: opts.Requests,
+		Direct:   percentiles(direct),
+		Proxied:  percentiles(proxied),
+		Added:    percentiles(added),
+	}, nil
+}
+
+// timeRequest POSTs the bench body to url and returns how long the full
+// response took. A non-empty targetURL is sent as X-Target-URL (gateway path).
+// Rate-limited attempts are retried after the limiter window and not timed.
+func timeRequest(client *http.Client, url, targetURL string) (time.Duration, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(proxyRequestBody))
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Anthropic-Version", "2023-06-01")
+		req.Header.Set("x-api-key", "sk-ant-bench")
+		if targetURL != "" {
+			req.Header.Set(gateway.HeaderTargetURL, targetURL)
+		}
+
+		start := time.Now()
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		body, err := io.ReadAll(resp.Body)
+		elapsed := time.Since(start)
+		_ = resp.Body.Close()
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < maxRateLimitRetries:
+			time.Sleep(time.Second) // gateway rate limiter: Retry-After: 1
+		case resp.StatusCode < 200 || resp.StatusCode >= 300:
+			return 0, fmt.Errorf("%s returned HTTP %d: %s", url, resp.StatusCode, bytes.TrimSpace(body))
+		default:
+			return elapsed, nil
+		}
+	}
+}
+
+// percentiles returns nearest-rank p50/p95/p99 of samples. samples is sorted in place.
+func percentiles(samples []time.Duration) Latencies {
+	if len(samples) == 0 {
+		return Latencies{}
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	rank := func(p float64) time.Duration {
+		idx := int(math.Ceil(p*float64(len(samples)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(samples) {
+			idx = len(samples) - 1
+		}
+		return samples[idx]
+	}
+	return Latencies{P50: rank(0.50), P95: rank(0.95), P99: rank(0.99)}
+}
@@ -3,6 +3,7 @@ package compresr
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -32,6 +33,9 @@ type Client struct {
 	apiKey     string
 	httpClient *http.Client
 
+	// debugDumpDir receives request/response dumps (see WithDebugDumpDir)
+	debugDumpDir string
+
 	// Cached gateway status to avoid slow external calls on every dashboard refresh
 	statusMu    sync.RWMutex
 	statusCache *GatewayStatus
@@ -339,6 +343,12 @@ func (c *Client) CompressHistory(params CompressHistoryParams) (*CompressHistory
 
 // CompressToolOutput calls the Compresr API to compress tool output.
 func (c *Client) CompressToolOutput(params CompressToolOutputParams) (*CompressToolOutputResponse, error) {
+	return c.CompressToolOutputContext(context.Background(), params)
+}
+
+// CompressToolOutputContext is CompressToolOutput bound to ctx: cancelling ctx
+// aborts the in-flight HTTP call and any pending retries.
+func (c *Client) CompressToolOutputContext(ctx context.Context, params CompressToolOutputParams) (*CompressToolOutputResponse, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("no API key configured")
 	}
@@ -374,7 +384,7 @@ func (c *Client) CompressToolOutput(params CompressToolOutputParams) (*CompressT
 	}
 
 	var resp APIResponse[CompressToolOutputResponse]
-	if err := c.post("/api/compress/tool-output/", payload, &resp); err != nil {
+	if err := c.postContext(ctx, "/api/compress/tool-output/", payload, &resp); err != nil {
 		return nil, err
 	}
 
@@ -391,6 +401,11 @@ func (c *Client) CompressToolOutput(params CompressToolOutputParams) (*CompressT
 
 // FilterTools calls the Compresr API to select relevant tools.
 func (c *Client) FilterTools(params FilterToolsParams) (*FilterToolsResponse, error) {
+	return c.FilterToolsContext(context.Background(), params)
+}
+
+// FilterToolsContext is FilterTools bound to ctx.
+func (c *Client) FilterToolsContext(ctx context.Context, params FilterToolsParams) (*FilterToolsResponse, error) {
 	if c.apiKey == "" {
 		return nil, fmt.Errorf("no API key configured")
 	}
@@ -432,7 +447,7 @@ func (c *Client) FilterTools(params FilterToolsParams) (*FilterToolsResponse, er
 	}
 
 	var resp APIResponse[FilterToolsResponse]
-	if err := c.post("/api/compress/tool-discovery/", payload, &resp); err != nil {
+	if err := c.postContext(ctx, "/api/compress/tool-discovery/", payload, &resp); err != nil {
 		return nil, err
 	}
 
@@ -487,14 +502,14 @@ func (c *Client) get(path string, result any) error {
 		}
 
 		if resp.StatusCode == http.StatusUnauthorized {
-			return fmt.Errorf("invalid API key")
+			return &retry.StatusError{StatusCode: resp.StatusCode, Message: "invalid API key"}
 		}
 		if retry.IsTransientStatus(resp.StatusCode) {
-			lastErr = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+			lastErr = &retry.StatusError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, string(body))}
 			continue
 		}
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+			return &retry.StatusError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, string(body))}
 		}
 
 		if unmarshalErr := json.Unmarshal(body, result); unmarshalErr != nil {
@@ -507,6 +522,12 @@ func (c *Client) get(path string, result any) error {
 }
 
 func (c *Client) post(path string, payload any, result any) error {
+	return c.postContext(context.Background(), path, payload, result)
+}
+
+// postContext is post bound to ctx: the request and the backoff between retries
+// stop as soon as ctx is done.
+func (c *Client) postContext(ctx context.Context, path string, payload any, result any) error {
 	reqURL := c.baseURL + path
 
 	parsedURL, err := url.Parse(reqURL)
@@ -526,10 +547,14 @@ func (c *Client) post(path string, payload any, result any) error {
 	var lastErr error
 	for attempt := 0; attempt < retry.MaxAttempts; attempt++ {
 		if attempt > 0 {
-			time.Sleep(retry.Backoff(attempt - 1))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retry.Backoff(attempt - 1)):
+			}
 		}
 
-		req, reqErr := http.NewRequest(http.MethodPost, validatedURL, bytes.NewReader(body)) //#nosec G704 -- scheme validated above
+		req, reqErr := http.NewRequestWithContext(ctx, http.MethodPost, validatedURL, bytes.NewReader(body)) //#nosec G704 -- scheme validated above
 		if reqErr != nil {
 			return fmt.Errorf("creating request: %w", reqErr)
 		}
@@ -540,8 +565,13 @@ func (c *Client) post(path string, payload any, result any) error {
 		req.Header.Set("Accept", "application/json")
 		req.Header.Set("User-Agent", "compresr-gateway/1.0")
 
+		dump := c.dumpRequest(req, body)
 		resp, doErr := c.httpClient.Do(req)
 		if doErr != nil {
+			dump.dumpResponse(nil, nil, doErr)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
 			if !retry.IsTransientErr(doErr) {
 				return fmt.Errorf("request failed: %w", doErr)
 			}
@@ -551,19 +581,20 @@ func (c *Client) post(path string, payload any, result any) error {
 
 		respBody, readErr := io.ReadAll(resp.Body)
 		_ = resp.Body.Close()
+		dump.dumpResponse(resp, respBody, readErr)
 		if readErr != nil {
 			return fmt.Errorf("reading response: %w", readErr)
 		}
 
 		if resp.StatusCode == http.StatusUnauthorized {
-			return fmt.Errorf("invalid API key")
+			return &retry.StatusError{StatusCode: resp.StatusCode, Message: "invalid API key"}
 		}
 		if retry.IsTransientStatus(resp.StatusCode) {
-			lastErr = fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+			lastErr = &retry.StatusError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, string(respBody))}
 			continue
 		}
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(respBody))
+			return &retry.StatusError{StatusCode: resp.StatusCode, Message: fmt.Sprintf("unexpected status %d: %s", resp.StatusCode, string(respBody))}
 		}
 
 		if unmarshalErr := json.Unmarshal(respBody, result); unmarshalErr != nil {
@@ -0,0 +1,114 @@
+package compresr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/monitoring"
+	"github.com/compresr/context-gateway/internal/utils"
+)
+
+// WithDebugDumpDir writes every API request and the raw response received to
+// dir (compresr.debug_dump_dir), one <id>.request.json / <id>.response.json
+// pair per attempt, for diagnosing unexpected backend responses. Credentials
+// are scrubbed. Empty dir disables dumping.
+func WithDebugDumpDir(dir string) ClientOption {
+	return func(client *Client) {
+		client.debugDumpDir = dir
+	}
+}
+
+// dumpSeq orders dump pairs written within the same millisecond.
+var dumpSeq atomic.Uint64
+
+// debugDump is one request/response pair being recorded.
+type debugDump struct {
+	dir    string
+	prefix string
+	apiKey string
+}
+
+// dumpRequest records an outgoing request and returns the dump to complete
+// with dumpResponse. Returns nil when dumping is off or the file could not be
+// written.
+func (c *Client) dumpRequest(req *http.Request, body []byte) *debugDump {
+	if c.debugDumpDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(c.debugDumpDir, 0o700); err != nil {
+		log.Warn().Err(err).Str("dir", c.debugDumpDir).Msg("compresr: cannot create debug dump dir")
+		return nil
+	}
+	d := &debugDump{
+		dir: c.debugDumpDir,
+		prefix: fmt.Sprintf("%s-%06d-%s", time.Now().UTC().Format("20060102T150405.000"), dumpSeq.Add(1),
+			strings.Trim(strings.ReplaceAll(req.URL.Path, "/", "-"), "-")),
+		apiKey: c.apiKey,
+	}
+	d.write("request", map[string]any{
+		"method":  req.Method,
+		"url":     req.URL.String(),
+		"headers": dumpHeaders(req.Header),
+		"body":    json.RawMessage(body),
+	})
+	return d
+}
+
+// dumpResponse completes d with the raw response body, or the transport error
+// when no response arrived.
+func (d *debugDump) dumpResponse(resp *http.Response, body []byte, err error) {
+	if d == nil {
+		return
+	}
+	record := map[string]any{}
+	if err != nil {
+		record["error"] = err.Error()
+	}
+	if resp != nil {
+		record["status"] = resp.StatusCode
+		record["headers"] = dumpHeaders(resp.Header)
+		record["body"] = string(body) // raw: may be HTML or truncated JSON
+	}
+	d.write("response", record)
+}
+
+func (d *debugDump) write(kind string, record map[string]any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep HTML error pages readable
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(record); err != nil {
+		return
+	}
+	text := monitoring.SanitizeLogLine(buf.String())
+	if len(d.apiKey) >= 8 { // shorter values would mask unrelated text
+		text = strings.ReplaceAll(text, d.apiKey, utils.MaskKeyShort(d.apiKey))
+	}
+	path := filepath.Join(d.dir, d.prefix+"."+kind+".json")
+	if err := os.WriteFile(path, []byte(text), 0o600); err != nil {
+		log.Warn().Err(err).Str("path", path).Msg("compresr: cannot write debug dump")
+	}
+}
+
+// dumpHeaders flattens headers for a dump, masking credential headers.
+func dumpHeaders(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name := range h {
+		value := h.Get(name)
+		switch strings.ToLower(name) {
+		case "x-api-key", "authorization", "cookie", "set-cookie":
+			value = utils.MaskKeyShort(value)
+		}
+		out[name] = value
+	}
+	return out
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/compresr/context-gateway/internal/utils"
+)
+
+// Setup bundles move a user's ~/.config/context-gateway between machines.
+// Only configs/*.yaml, agents/*.yaml and (on request) .env are included.
+const (
+	BundleManifestName = "manifest.json"
+	bundleEnvFile      = ".env"
+	maxBundleFileSize  = 4 << 20 // 4MB per file
+)
+
+// bundleDirs are the subdirectories of the config dir copied into a bundle.
+var bundleDirs = []string{"configs", "agents"}
+
+// BundleManifest describes the contents of a setup bundle.
+// Secret values are masked; only the .env file itself carries them.
+type BundleManifest struct {
+	CreatedAt      time.Time         `json:"created_at"`
+	Version        string            `json:"version,omitempty"`
+	Files          []string          `json:"files"`
+	IncludeSecrets bool              `json:"include_secrets"`
+	Secrets        map[string]string `json:"secrets,omitempty"` // env var → masked value
+}
+
+// ExportBundle writes a gzipped tar of the setup under baseDir to w.
+// The .env file is only included when includeSecrets is true.
+func ExportBundle(baseDir string, w io.Writer, includeSecrets bool, version string) (*BundleManifest, error) {
+	files, err := collectBundleFiles(baseDir, includeSecrets)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &BundleManifest{
+		CreatedAt:      time.Now().UTC(),
+		Version:        version,
+		Files:          files,
+		IncludeSecrets: includeSecrets,
+	}
+
+	if includeSecrets {
+		if data, ok := readBundleFile(baseDir, bundleEnvFile); ok {
+			env, err := godotenv.Parse(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("parse %s: %w", bundleEnvFile, err)
+			}
+			manifest.Secrets = make(map[string]string, len(env))
+			for k, v := range env {
+				manifest.Secrets[k] = utils.MaskKey(v)
+			}
+		}
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTarFile(tw, BundleManifestName, manifestData, 0644); err != nil {
+		return nil, err
+	}
+	for _, rel := range files {
+		data, ok := readBundleFile(baseDir, rel)
+		if !ok {
+			return nil, fmt.Errorf("read %s", rel)
+		}
+		mode := int64(0644)
+		if rel == bundleEnvFile {
+			mode = 0600
+		}
+		if err := writeTarFile(tw, rel, data, mode); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// ImportBundle unpacks a bundle produced by ExportBundle into baseDir.
+// When a destination file exists with different content, overwrite is asked;
+// returning false keeps the existing file. Returns the relative paths written.
+func ImportBundle(r io.Reader, baseDir string, overwrite func(rel string) bool) ([]string, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a gzip archive: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	tr := tar.NewReader(gz)
+	var written []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return written, fmt.Errorf("read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Name == BundleManifestName {
+			continue
+		}
+		if !isBundlePath(hdr.Name) {
+			return written, fmt.Errorf("unexpected file in bundle: %q", hdr.Name)
+		}
+		if hdr.Size > maxBundleFileSize {
+			return written, fmt.Errorf("file too large in bundle: %s", hdr.Name)
+		}
+
+		data, err := io.ReadAll(io.LimitReader(tr, maxBundleFileSize))
+		if err != nil {
+			return written, fmt.Errorf("read %s: %w", hdr.Name, err)
+		}
+
+		dest := filepath.Join(baseDir, filepath.FromSlash(hdr.Name))
+		// #nosec G304 -- dest is confined to baseDir by isBundlePath
+		if existing, err := os.ReadFile(dest); err == nil {
+			if bytes.Equal(existing, data) {
+				continue
+			}
+			if overwrite != nil && !overwrite(hdr.Name) {
+				continue
+			}
+		}
+
+		perm := os.FileMode(0644)
+		if hdr.Name == bundleEnvFile {
+			perm = 0600
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0750); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(dest, data, perm); err != nil {
+			return written, fmt.Errorf("write %s: %w", hdr.Name, err)
+		}
+		written = append(written, hdr.Name)
+	}
+	return written, nil
+}
+
+// collectBundleFiles lists bundle-relative paths (slash-separated) under baseDir.
+func collectBundleFiles(baseDir string, includeSecrets bool) ([]string, error) {
+	var files []string
+	for _, dir := range bundleDirs {
+		entries, err := os.ReadDir(filepath.Join(baseDir, dir))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || !strings.HasSuffix(e.Name(), ".yaml") {
+				continue
+			}
+			files = append(files, path.Join(dir, e.Name()))
+		}
+	}
+	if includeSecrets {
+		if _, err := os.Stat(filepath.Join(baseDir, bundleEnvFile)); err == nil {
+			files = append(files, bundleEnvFile)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isBundlePath reports whether name is a file a bundle may contain.
+// Rejects anything outside configs/, agents/ and .env (e.g. "../" traversal).
+func isBundlePath(name string) bool {
+	if name == bundleEnvFile {
+		return true
+	}
+	dir, file := path.Split(name)
+	if file == "" || !strings.HasSuffix(file, ".yaml") || path.Clean(name) != name {
+		return false
+	}
+	for _, d := range bundleDirs {
+		if dir == d+"/" {
+			return true
+		}
+	}
+	return false
+}
+
+func readBundleFile(baseDir, rel string) ([]byte, bool) {
+	// #nosec G304 -- rel comes from collectBundleFiles under baseDir
+	data, err := os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(rel)))
+	return data, err == nil
+}
+
+func writeTarFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    mode,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
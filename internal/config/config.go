@@ -2,7 +2,10 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -24,6 +27,7 @@ type CostControlConfig = costcontrol.CostControlConfig
 // Config is the root configuration for the Context Gateway.
 // All fields are required - no defaults are applied.
 type Config struct {
+	Metadata      MetadataConfig      `yaml:"metadata"`      // Descriptive only (name shown in config pickers)
 	Server        ServerConfig        `yaml:"server"`        // HTTP server settings
 	URLs          URLsConfig          `yaml:"urls"`          // Upstream URLs
 	Providers     ProvidersConfig     `yaml:"providers"`     // LLM provider configurations
@@ -42,6 +46,14 @@ type Config struct {
 	AgentFlags *AgentFlags `yaml:"-"` // Agent CLI flags, set at runtime by cmd/agent.go
 }
 
+// MetadataConfig describes a config file. The gateway never acts on it; it is
+// declared so strict loading accepts the section every bundled config carries.
+type MetadataConfig struct {
+	Name        string `yaml:"name,omitempty"`
+	Description string `yaml:"description,omitempty"`
+	Strategy    string `yaml:"strategy,omitempty"`
+}
+
 // AgentFlags stores passthrough args from the gateway CLI.
 // These are flags intended for the agent (Claude Code, Codex, etc.) that the
 // gateway also needs to be aware of for behavior adjustments.
@@ -121,6 +133,67 @@ type ServerConfig struct {
 	Port         int           `yaml:"port"`          // Port to listen on
 	ReadTimeout  time.Duration `yaml:"read_timeout"`  // Max time to read request
 	WriteTimeout time.Duration `yaml:"write_timeout"` // Max time to write response
+
+	// UpstreamTimeouts bound calls to the LLM provider. Unset phases fall back to
+	// write_timeout (overall, first_byte); connect 0 keeps a 30s dial and a 10s
+	// TLS handshake.
+	UpstreamTimeouts TimeoutsConfig `yaml:"upstream_timeouts,omitempty"`
+
+	// Upstream host policy for X-Target-URL (SSRF protection).
+	// Allowed hosts extend the built-in provider allowlist; denied hosts always win.
+	// Entries are hostnames, IPs, or CIDR ranges.
+	AllowedUpstreamHosts []string `yaml:"allowed_upstream_hosts,omitempty"`
+	DeniedUpstreamHosts  []string `yaml:"denied_upstream_hosts,omitempty"`
+
+	// DefaultUpstream is the base URL (e.g. "https://api.anthropic.com") used
+	// when a request carries no X-Target-URL header; the request path is
+	// appended. Bedrock and ChatGPT subscription requests keep their own
+	// upstream. Unset, the upstream is inferred from headers and path.
+	DefaultUpstream string `yaml:"default_upstream,omitempty"`
+
+	// CORSAllowedOrigins lists browser origins allowed to call the gateway, in
+	// addition to localhost. Entries are exact origins ("https://app.example.com")
+	// or "*" for any origin.
+	CORSAllowedOrigins []string `yaml:"cors_allowed_origins,omitempty"`
+
+	// ResponseCacheTTL replays the upstream response for an exact repeat of a
+	// non-streaming request (same body, target and credentials) within the TTL.
+	// Only successful responses are cached. 0 disables the cache.
+	ResponseCacheTTL time.Duration `yaml:"response_cache_ttl,omitempty"`
+
+	// RequestTimeout bounds the whole proxied request: compression, tool
+	// discovery and the upstream call share one deadline and are cancelled
+	// together when it expires or the client disconnects. 0 disables it.
+	RequestTimeout time.Duration `yaml:"request_timeout,omitempty"`
+
+	// PipelineCompression opens the upstream connection while a large request
+	// is still being compressed, so the forwarded request skips connection
+	// setup. The body itself is only sent once compression has finished.
+	PipelineCompression bool `yaml:"pipeline_compression,omitempty"`
+
+	// MaxRequestBytes caps a client request body, whether sent with
+	// Content-Length or chunked; larger bodies are rejected with 413 before any
+	// pipe runs. 0 = MaxRequestBodySize.
+	MaxRequestBytes int64 `yaml:"max_request_bytes,omitempty"`
+
+	// UpstreamHeaders are added to every proxied upstream request (e.g.
+	// OpenAI-Organization, proxy auth, tracing IDs). Values support ${VAR}
+	// expansion. Only header names are ever logged.
+	UpstreamHeaders map[string]string `yaml:"upstream_headers,omitempty"`
+
+	// OverrideUpstreamHeaders lets UpstreamHeaders replace headers the client
+	// already sent. By default client-provided values win.
+	OverrideUpstreamHeaders bool `yaml:"override_upstream_headers,omitempty"`
+
+	// DebugEndpoints exposes loopback-only diagnostics: GET /debug/store (metadata
+	// only) and GET /expand/{id}, which returns a stored original to the session
+	// that produced it.
+	DebugEndpoints bool `yaml:"debug_endpoints"`
+
+	// ModelAliases maps friendly model names to concrete ones (e.g. "fast" ->
+	// "claude-3-haiku-20240307"). A request's "model" field is rewritten before
+	// routing and context-window lookup; summarizer models resolve the same way.
+	ModelAliases map[string]string `yaml:"model_aliases,omitempty"`
 }
 
 // URLsConfig contains upstream URL configuration.
@@ -128,15 +201,71 @@ type URLsConfig struct {
 	Compresr string `yaml:"compresr"` // Compresr platform URL (e.g., "https://api.compresr.ai")
 }
 
+// EffectiveUpstreamTimeouts resolves upstream timeouts against the legacy write_timeout.
+func (s ServerConfig) EffectiveUpstreamTimeouts() TimeoutsConfig {
+	t := s.UpstreamTimeouts
+	if t.Overall <= 0 {
+		t.Overall = s.WriteTimeout
+	}
+	if t.FirstByte <= 0 {
+		t.FirstByte = t.Overall // 0 = no header timeout (safe for extended thinking)
+	}
+	return t
+}
+
+// EffectiveMaxRequestBytes returns the request body limit, defaulting to
+// MaxRequestBodySize.
+func (s ServerConfig) EffectiveMaxRequestBytes() int64 {
+	if s.MaxRequestBytes > 0 {
+		return s.MaxRequestBytes
+	}
+	return MaxRequestBodySize
+}
+
+// ResolveModelAlias returns the concrete model for an alias in ModelAliases,
+// or model unchanged when it is not an alias.
+func (s ServerConfig) ResolveModelAlias(model string) string {
+	if concrete, ok := s.ModelAliases[model]; ok {
+		return concrete
+	}
+	return model
+}
+
 // NotificationsConfig controls notification integrations.
+// Gateway events fan out to every enabled integration (see internal/notifications).
 type NotificationsConfig struct {
-	Slack SlackConfig `yaml:"slack"` // Slack notification settings
+	Slack   SlackConfig   `yaml:"slack"`             // Slack notification settings
+	Discord DiscordConfig `yaml:"discord,omitempty"` // Discord channel webhook
+	Webhook WebhookConfig `yaml:"webhook,omitempty"` // Generic JSON webhook (PagerDuty, Teams, custom)
 }
 
-// SlackConfig controls Slack notifications via Claude Code hooks.
+// SlackConfig controls Slack notifications: Claude Code hook events and, through
+// the notifications fan-out, gateway events, both sent to the same webhook.
 type SlackConfig struct {
 	Enabled    bool   `yaml:"enabled"`               // Whether Slack notifications are enabled
-	WebhookURL string `yaml:"webhook_url,omitempty"` // Slack incoming webhook URL
+	WebhookURL string `yaml:"webhook_url,omitempty"` // Slack incoming webhook URL (default: $SLACK_WEBHOOK_URL)
+}
+
+// EffectiveWebhookURL returns webhook_url, or SLACK_WEBHOOK_URL (set by the
+// setup wizard and read by the hook script) when unset.
+func (s SlackConfig) EffectiveWebhookURL() string {
+	if s.WebhookURL != "" {
+		return s.WebhookURL
+	}
+	return os.Getenv("SLACK_WEBHOOK_URL")
+}
+
+// DiscordConfig controls Discord notifications.
+type DiscordConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	WebhookURL string `yaml:"webhook_url,omitempty"` // Discord channel webhook URL
+}
+
+// WebhookConfig controls the generic webhook notifier, which POSTs each event as JSON.
+type WebhookConfig struct {
+	Enabled bool              `yaml:"enabled"`
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"` // Extra request headers (e.g. Authorization)
 }
 
 // DashboardConfig controls the embedded dashboard UI.
@@ -193,11 +322,24 @@ func Load(path string) (*Config, error) {
 // LoadFromBytes parses configuration from raw YAML bytes.
 // Supports ${VAR:-default} env var expansion, env overrides, and validation.
 func LoadFromBytes(data []byte) (*Config, error) {
+	return loadFromBytes(data, false)
+}
+
+// LoadFromBytesStrict is LoadFromBytes but rejects keys that map to no config
+// field, so a misspelled setting fails instead of silently keeping its default.
+// Errors name the key and its line, e.g. "line 12: field trigger_treshold not found".
+func LoadFromBytesStrict(data []byte) (*Config, error) {
+	return loadFromBytes(data, true)
+}
+
+func loadFromBytes(data []byte, strict bool) (*Config, error) {
 	// Expand environment variables (supports ${VAR:-default} syntax)
 	expanded := expandEnvWithDefaults(string(data))
 
 	var cfg Config
-	if err := yaml.Unmarshal([]byte(expanded), &cfg); err != nil {
+	dec := yaml.NewDecoder(strings.NewReader(expanded))
+	dec.KnownFields(strict)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
@@ -342,6 +484,17 @@ func (c *Config) ApplySessionEnvOverrides() {
 		c.Monitoring.ExpandContextCallsPath = envPath
 	}
 
+	// SESSION_REQUESTS_LOG overrides the requests.jsonl capture path
+	if envPath := os.Getenv("SESSION_REQUESTS_LOG"); envPath != "" {
+		c.Monitoring.RequestCapturePath = envPath
+	}
+
+	// Auto-derive RequestCapturePath next to the other session logs when capture is on.
+	if c.Monitoring.CaptureRequests && c.Monitoring.RequestCapturePath == "" && c.Monitoring.CompressionLogPath != "" {
+		dir := filepath.Dir(c.Monitoring.CompressionLogPath)
+		c.Monitoring.RequestCapturePath = filepath.Join(dir, "requests.jsonl")
+	}
+
 	// Auto-derive ExpandContextCallsPath from CompressionLogPath when missing.
 	// Handles stale configs that predate expand_context_calls_path.
 	if c.Monitoring.ExpandContextCallsPath == "" && c.Monitoring.CompressionLogPath != "" {
@@ -380,6 +533,44 @@ func (c *Config) Validate() error {
 	if c.Server.WriteTimeout <= 0 {
 		return fmt.Errorf("server.write_timeout must be positive")
 	}
+	if err := c.Server.UpstreamTimeouts.Validate("server.upstream_timeouts"); err != nil {
+		return err
+	}
+	if err := validateHostEntries("server.allowed_upstream_hosts", c.Server.AllowedUpstreamHosts); err != nil {
+		return err
+	}
+	if err := validateCORSOrigins("server.cors_allowed_origins", c.Server.CORSAllowedOrigins); err != nil {
+		return err
+	}
+	if err := validateHostEntries("server.denied_upstream_hosts", c.Server.DeniedUpstreamHosts); err != nil {
+		return err
+	}
+	if u := c.Server.DefaultUpstream; u != "" {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("server.default_upstream: invalid URL %q (expected http(s)://host[/path])", u)
+		}
+	}
+	if c.Server.ResponseCacheTTL < 0 {
+		return fmt.Errorf("server.response_cache_ttl must not be negative")
+	}
+	if c.Server.RequestTimeout < 0 {
+		return fmt.Errorf("server.request_timeout must not be negative")
+	}
+	if c.Server.MaxRequestBytes < 0 {
+		return fmt.Errorf("server.max_request_bytes must not be negative")
+	}
+	for name := range c.Server.UpstreamHeaders {
+		if name == "" || strings.ContainsAny(name, " \t\r\n:") {
+			return fmt.Errorf("server.upstream_headers: invalid header name %q", name)
+		}
+	}
+
+	for alias, model := range c.Server.ModelAliases {
+		if alias == "" || model == "" {
+			return fmt.Errorf("server.model_aliases: alias %q must map to a non-empty model", alias)
+		}
+	}
 
 	// Store validation
 	if c.Store.Type == "" {
@@ -411,6 +602,15 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	if r := c.Monitoring.CompressionLogSampleRate; r != nil && (*r < 0 || *r > 1) {
+		return fmt.Errorf("monitoring.compression_log_sample_rate must be between 0 and 1, got %g", *r)
+	}
+
+	if c.Notifications.Webhook.Enabled && !strings.HasPrefix(c.Notifications.Webhook.URL, "http://") &&
+		!strings.HasPrefix(c.Notifications.Webhook.URL, "https://") {
+		return fmt.Errorf("notifications.webhook.url must be an http(s) URL when enabled")
+	}
+
 	// Validate provider references
 	if err := c.ValidateUsedProviders(); err != nil {
 		return err
@@ -0,0 +1,56 @@
+// Package config - cors.go validates and matches CORS origin entries.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CORSAnyOrigin is the cors_allowed_origins entry that allows every origin.
+const CORSAnyOrigin = "*"
+
+// NormalizeOrigin returns origin as lowercase scheme://host[:port], or "" if it
+// is not a valid http(s) origin (paths, queries and credentials are rejected).
+func NormalizeOrigin(origin string) string {
+	u, err := url.Parse(strings.TrimSpace(origin))
+	if err != nil || u.Host == "" || u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return ""
+	}
+	if u.Path != "" && u.Path != "/" {
+		return ""
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return ""
+	}
+	return scheme + "://" + strings.ToLower(u.Host)
+}
+
+// OriginAllowed reports whether origin matches one of the configured CORS entries.
+func OriginAllowed(origin string, entries []string) bool {
+	normalized := NormalizeOrigin(origin)
+	if normalized == "" {
+		return false
+	}
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if e == CORSAnyOrigin || NormalizeOrigin(e) == normalized {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCORSOrigins checks every entry of a CORS origin list config field.
+func validateCORSOrigins(field string, entries []string) error {
+	for _, e := range entries {
+		if strings.TrimSpace(e) == CORSAnyOrigin {
+			continue
+		}
+		if NormalizeOrigin(e) == "" {
+			return fmt.Errorf("%s: invalid origin %q (expected scheme://host[:port] or \"*\")", field, e)
+		}
+	}
+	return nil
+}
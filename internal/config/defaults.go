@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/compresr/context-gateway/internal/compresr"
+	"github.com/compresr/context-gateway/internal/pipes"
 )
 
 // STORE DEFAULTS
@@ -38,8 +39,9 @@ const MaxRateLimitBuckets = 10000
 // DefaultBufferSize is the standard I/O buffer size.
 const DefaultBufferSize = 4096
 
-// DefaultDialTimeout is the TCP dial timeout.
-const DefaultDialTimeout = 30 * time.Second
+// DefaultDialTimeout is the TCP dial timeout when no connect timeout is
+// configured. Re-exported from pipes.
+const DefaultDialTimeout = pipes.DefaultDialTimeout
 
 // MaxRequestBodySize is the maximum allowed request body (50MB).
 const MaxRequestBodySize = 50 * 1024 * 1024
@@ -69,6 +71,13 @@ const DefaultMinTokens = 512
 // Larger outputs skip compression (too expensive to process).
 const DefaultMaxTokens = 50000
 
+// DefaultCompressionConcurrency is the max parallel compression API calls per pipe.
+const DefaultCompressionConcurrency = 10
+
+// DefaultCompressionQueueDepth is how many compression calls may wait for a free
+// slot. Calls beyond this apply the fallback strategy instead of blocking.
+const DefaultCompressionQueueDepth = 128
+
 // GATEWAY PORT RANGE
 
 // DefaultDashboardPort is the fixed port for the centralized dashboard.
@@ -85,6 +94,9 @@ const DefaultGatewayBasePort = 18081
 // MaxGatewayPorts is the maximum concurrent gateway instances.
 const MaxGatewayPorts = 10
 
+// DefaultProfilePort is the localhost port for pprof endpoints (serve --profile).
+const DefaultProfilePort = 6060
+
 // COMPRESR PLATFORM URLS
 
 // DefaultCompresrAPIBaseURL is the production Compresr API base URL.
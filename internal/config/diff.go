@@ -0,0 +1,148 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/compresr/context-gateway/internal/utils"
+)
+
+// FieldDiff is one effective setting that differs between two configs.
+// A and B are display values; secrets are masked.
+type FieldDiff struct {
+	Path string // YAML path, e.g. "preemptive.trigger_threshold"
+	A    string
+	B    string
+}
+
+// secretFields are YAML keys whose values (or, for maps, entry values) are
+// credentials and must not be printed.
+var secretFields = map[string]bool{
+	"api_key":          true,
+	"webhook":          true,
+	"webhook_url":      true,
+	"upstream_headers": true,
+}
+
+// Diff compares the effective settings of two loaded configs field by field and
+// returns the differences in declaration order (map keys sorted). Paths use YAML
+// names, so formatting, comments and env-var indirection never show up — only
+// values that change behavior. Runtime-only fields (yaml:"-") and metadata are ignored.
+func Diff(a, b *Config) []FieldDiff {
+	var diffs []FieldDiff
+	diffValue(reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem(), "", false, &diffs)
+	return diffs
+}
+
+func diffValue(a, b reflect.Value, path string, secret bool, diffs *[]FieldDiff) {
+	switch a.Kind() {
+	case reflect.Struct:
+		t := a.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name, inline := yamlFieldName(field)
+			if name == "-" {
+				continue
+			}
+			fieldPath := path
+			if !inline {
+				fieldPath = joinPath(path, name)
+			}
+			if fieldPath == "metadata" {
+				continue // descriptive only, never changes behavior
+			}
+			diffValue(a.Field(i), b.Field(i), fieldPath, secret || secretFields[name], diffs)
+		}
+		return
+	case reflect.Ptr:
+		if !a.IsNil() && !b.IsNil() {
+			diffValue(a.Elem(), b.Elem(), path, secret, diffs)
+			return
+		}
+		// A struct section set on one side only (e.g. fallback_summarizer) is
+		// compared field by field against its zero value, like a map entry.
+		if elem := a.Type().Elem(); elem.Kind() == reflect.Struct && a.IsNil() != b.IsNil() {
+			av, bv := reflect.Zero(elem), reflect.Zero(elem)
+			if !a.IsNil() {
+				av = a.Elem()
+			} else {
+				bv = b.Elem()
+			}
+			diffValue(av, bv, path, secret, diffs)
+			return
+		}
+	case reflect.Map:
+		keys := map[string]reflect.Value{}
+		for _, k := range append(a.MapKeys(), b.MapKeys()...) {
+			keys[fmt.Sprint(k.Interface())] = k
+		}
+		names := make([]string, 0, len(keys))
+		for name := range keys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		// An entry missing on one side is compared against its zero value, so
+		// e.g. an added provider lists the fields it sets.
+		for _, name := range names {
+			av, bv := a.MapIndex(keys[name]), b.MapIndex(keys[name])
+			if !av.IsValid() {
+				av = reflect.Zero(a.Type().Elem())
+			}
+			if !bv.IsValid() {
+				bv = reflect.Zero(b.Type().Elem())
+			}
+			diffValue(av, bv, joinPath(path, name), secret, diffs)
+		}
+		return
+	}
+
+	if reflect.DeepEqual(a.Interface(), b.Interface()) {
+		return
+	}
+	*diffs = append(*diffs, FieldDiff{Path: path, A: display(a, secret), B: display(b, secret)})
+}
+
+// yamlFieldName returns the YAML key for field and whether it is inlined.
+func yamlFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("yaml")
+	name, opts, _ := strings.Cut(tag, ",")
+	if strings.Contains(opts, "inline") {
+		return "", true
+	}
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// display formats a leaf value for diff output.
+func display(v reflect.Value, secret bool) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "(unset)"
+		}
+		v = v.Elem()
+	}
+	if secret {
+		if v.Kind() == reflect.String {
+			return utils.MaskKey(v.String())
+		}
+		return "(redacted)"
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("%q", v.String())
+	}
+	return fmt.Sprintf("%v", v.Interface())
+}
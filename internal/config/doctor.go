@@ -0,0 +1,106 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Doctor issue kinds.
+const (
+	IssueMissingKey    = "missing_key"
+	IssueModelMismatch = "model_mismatch"
+	IssueUnreachable   = "unreachable"
+)
+
+// DoctorIssue is one problem that would make a launch with this config fail
+// later (on the first summarization or compression call) rather than at start.
+type DoctorIssue struct {
+	Provider string
+	Kind     string
+	Message  string
+}
+
+// DoctorOptions tunes Doctor.
+type DoctorOptions struct {
+	// CapturedProvider is the provider whose credentials the agent itself sends
+	// (e.g. anthropic for Claude Code); the gateway captures them from incoming
+	// requests, so a missing key for it is not an issue.
+	CapturedProvider string
+	// Ping sends one request to each used provider's endpoint; any HTTP response
+	// counts as reachable.
+	Ping       bool
+	HTTPClient *http.Client
+}
+
+// Doctor checks the providers this config actually uses: a key is present, the
+// model belongs to the provider and, with Ping, the endpoint answers. Issues are
+// ordered by provider name.
+func (cfg *Config) Doctor(ctx context.Context, opts DoctorOptions) []DoctorIssue {
+	names := GetUsedProviderNames(cfg)
+	sort.Strings(names)
+
+	var issues []DoctorIssue
+	for _, name := range names {
+		p, ok := cfg.Providers[name]
+		if !ok {
+			continue // reported by ValidateUsedProviders
+		}
+		if p.GetProviderAuth(name) == "" && p.Auth != "oauth" && p.Auth != "bedrock" && name != opts.CapturedProvider {
+			msg := fmt.Sprintf("provider %q has no api_key (is its environment variable set?)", name)
+			if name == ProviderVertex {
+				msg = fmt.Sprintf("provider %q has no access token (set %s or api_key)", name, VertexTokenEnv)
+			}
+			issues = append(issues, DoctorIssue{
+				Provider: name,
+				Kind:     IssueMissingKey,
+				Message:  msg,
+			})
+		}
+		switch name {
+		case ProviderAnthropic, ProviderGemini, ProviderOpenAI:
+			model := cfg.Server.ResolveModelAlias(p.Model)
+			if family := inferProviderFromModel(model); family != name {
+				issues = append(issues, DoctorIssue{
+					Provider: name,
+					Kind:     IssueModelMismatch,
+					Message:  fmt.Sprintf("provider %q: model %q belongs to %s", name, model, family),
+				})
+			}
+		}
+		if opts.Ping {
+			if err := pingEndpoint(ctx, opts.HTTPClient, p.GetEndpoint(name)); err != nil {
+				issues = append(issues, DoctorIssue{
+					Provider: name,
+					Kind:     IssueUnreachable,
+					Message:  fmt.Sprintf("provider %q endpoint unreachable: %v", name, err),
+				})
+			}
+		}
+	}
+	return issues
+}
+
+// pingEndpoint reports whether endpoint answers at all; auth and method errors
+// still prove it is reachable.
+func pingEndpoint(ctx context.Context, client *http.Client, endpoint string) error {
+	if _, err := url.ParseRequestURI(endpoint); err != nil {
+		return err
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
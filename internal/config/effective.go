@@ -0,0 +1,122 @@
+package config
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/compresr/context-gateway/internal/preemptive"
+	"github.com/compresr/context-gateway/internal/utils"
+)
+
+// EffectiveSummary is what a loaded config actually runs with — after env-var
+// expansion and defaults — logged once at startup for support debugging.
+// API keys appear only masked.
+type EffectiveSummary struct {
+	Port     int
+	Upstream string // server.default_upstream, or "inferred" (X-Target-URL / headers / path)
+
+	ToolOutputEnabled  bool
+	ToolOutputStrategy string
+	MinTokens          int
+	MaxTokens          int
+	TargetRatio        float64
+	ExpandContext      bool
+
+	ToolDiscoveryStrategy string
+
+	StoreType string
+	StoreTTL  time.Duration
+
+	PreemptiveEnabled  bool
+	PreemptiveStrategy string
+	TriggerThreshold   float64
+	SummarizerModel    string
+
+	APIKeys map[string]string // "compresr" and provider names -> masked key
+}
+
+// EffectiveSummary resolves the settings worth knowing when asking "what was
+// actually running?". Zero-valued thresholds report the defaults the pipes use.
+func (c *Config) EffectiveSummary() EffectiveSummary {
+	to := c.Pipes.ToolOutput
+	s := EffectiveSummary{
+		Port:                  c.Server.Port,
+		Upstream:              c.Server.DefaultUpstream,
+		ToolOutputEnabled:     to.Enabled,
+		ToolOutputStrategy:    to.Strategy,
+		MinTokens:             to.MinTokens,
+		MaxTokens:             to.MaxTokens,
+		TargetRatio:           to.TargetCompressionRatio,
+		ExpandContext:         to.EnableExpandContext,
+		ToolDiscoveryStrategy: c.Pipes.ToolDiscovery.Strategy,
+		StoreType:             c.Store.Type,
+		StoreTTL:              c.Store.TTL,
+		PreemptiveEnabled:     c.Preemptive.Enabled,
+		PreemptiveStrategy:    c.Preemptive.Strategy,
+		TriggerThreshold:      c.Preemptive.TriggerThreshold,
+		SummarizerModel:       c.Server.ResolveModelAlias(c.Preemptive.Summarizer.Model),
+		APIKeys:               make(map[string]string),
+	}
+	if s.Upstream == "" {
+		s.Upstream = "inferred"
+	}
+	if s.MinTokens == 0 {
+		s.MinTokens = DefaultMinTokens
+	}
+	if s.MaxTokens == 0 {
+		s.MaxTokens = DefaultMaxTokens
+	}
+	if s.StoreType == "" {
+		s.StoreType = "memory"
+	}
+	if s.PreemptiveStrategy == preemptive.CompactionDefault {
+		s.PreemptiveStrategy = "default"
+	}
+	if c.Preemptive.Summarizer.Provider != "" {
+		if p, ok := c.Providers[c.Preemptive.Summarizer.Provider]; ok && p.Model != "" {
+			s.SummarizerModel = c.Server.ResolveModelAlias(p.Model)
+		}
+	}
+
+	if c.CompresrCreds.APIKey != "" {
+		s.APIKeys["compresr"] = utils.MaskKey(c.CompresrCreds.APIKey)
+	}
+	for name, p := range c.Providers {
+		if p.ProviderAuth != "" {
+			s.APIKeys[name] = utils.MaskKey(p.ProviderAuth)
+		}
+	}
+	return s
+}
+
+// MarshalZerologObject writes the summary as flat snake_case log fields.
+func (s EffectiveSummary) MarshalZerologObject(e *zerolog.Event) {
+	e.Int("port", s.Port).
+		Str("upstream", s.Upstream).
+		Bool("tool_output_enabled", s.ToolOutputEnabled).
+		Str("tool_output_strategy", s.ToolOutputStrategy).
+		Int("min_tokens", s.MinTokens).
+		Int("max_tokens", s.MaxTokens).
+		Float64("target_compression_ratio", s.TargetRatio).
+		Bool("expand_context", s.ExpandContext).
+		Str("tool_discovery_strategy", s.ToolDiscoveryStrategy).
+		Str("store_type", s.StoreType).
+		Dur("store_ttl", s.StoreTTL).
+		Bool("preemptive_enabled", s.PreemptiveEnabled).
+		Str("preemptive_strategy", s.PreemptiveStrategy).
+		Float64("trigger_threshold", s.TriggerThreshold).
+		Str("summarizer_model", s.SummarizerModel)
+
+	names := make([]string, 0, len(s.APIKeys))
+	for name := range s.APIKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	keys := zerolog.Dict()
+	for _, name := range names {
+		keys.Str(name, s.APIKeys[name])
+	}
+	e.Dict("api_keys", keys)
+}
@@ -0,0 +1,53 @@
+// Package config - hosts.go validates and matches upstream host entries.
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// validHostnameRE matches valid hostnames (e.g. api.openai.com) and bare labels (e.g. localhost).
+// Allows letters, digits, hyphens, and dots. Does not allow IP literals (handled by net.ParseCIDR).
+var validHostnameRE = regexp.MustCompile(`^[a-z0-9]([a-z0-9\.\-]*[a-z0-9])?$`)
+
+// IsValidHostEntry returns true if entry is a valid hostname, IP address, or CIDR range.
+// Entries are expected to be lowercase.
+func IsValidHostEntry(entry string) bool {
+	// Accept CIDR ranges (e.g. 10.0.0.0/8)
+	if _, _, err := net.ParseCIDR(entry); err == nil {
+		return true
+	}
+	// Accept plain IP addresses
+	if net.ParseIP(entry) != nil {
+		return true
+	}
+	// Accept valid hostnames (lowercase enforced by caller)
+	return validHostnameRE.MatchString(entry)
+}
+
+// HostMatchesEntry reports whether host (no port, lowercase) matches a host entry.
+// CIDR entries match IP literals inside the range; other entries match exactly.
+func HostMatchesEntry(host, entry string) bool {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	if host == entry {
+		return true
+	}
+	if _, cidr, err := net.ParseCIDR(entry); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			return cidr.Contains(ip)
+		}
+	}
+	return false
+}
+
+// validateHostEntries checks every entry of a host list config field.
+func validateHostEntries(field string, entries []string) error {
+	for _, e := range entries {
+		if !IsValidHostEntry(strings.ToLower(strings.TrimSpace(e))) {
+			return fmt.Errorf("%s: invalid host entry %q (expected hostname, IP, or CIDR)", field, e)
+		}
+	}
+	return nil
+}
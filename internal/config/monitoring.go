@@ -8,12 +8,19 @@ type MonitoringConfig struct {
 	LogFormat string `yaml:"log_format"` // json, console
 	LogOutput string `yaml:"log_output"` // stdout, stderr, or file path
 
+	// API keys and URL credentials are masked in log output unless this is set.
+	UnmaskedLogs bool `yaml:"unmasked_logs"`
+
 	// Telemetry settings
 	TelemetryEnabled bool   `yaml:"telemetry_enabled"` // Enable telemetry tracking
 	TelemetryPath    string `yaml:"telemetry_path"`    // Path to telemetry JSONL file
 	LogToStdout      bool   `yaml:"log_to_stdout"`     // Also log telemetry to stdout
 	VerbosePayloads  bool   `yaml:"verbose_payloads"`  // Log full request/response payloads
 
+	// SQLite mirror of telemetry (requests, compressions, expansions) that persists
+	// across sessions; requires telemetry_enabled. Query it with `context-gateway stats --sql`.
+	TelemetrySQLitePath string `yaml:"telemetry_sqlite_path,omitempty"`
+
 	// Additional log files
 	CompressionLogPath     string `yaml:"compression_log_path"`      // Log original vs compressed
 	ToolDiscoveryLogPath   string `yaml:"tool_discovery_log_path"`   // Log tool discovery filtering details
@@ -22,6 +29,15 @@ type MonitoringConfig struct {
 	SessionStatsPath       string `yaml:"session_stats_path"`        // Live session_stats.json snapshot (rewritten every ~3s)
 	ExpandContextCallsPath string `yaml:"expand_context_calls_path"` // JSONL log of expand_context calls (original + compressed content)
 
+	// Fraction (0.0-1.0) of compression events written to compression_log_path.
+	// Unset logs every event, 0 logs none. Totals in GET /stats always count all events.
+	CompressionLogSampleRate *float64 `yaml:"compression_log_sample_rate,omitempty"`
+
+	// Request capture for offline replay (`context-gateway replay-session`).
+	// Stores full request bodies — off by default.
+	CaptureRequests    bool   `yaml:"capture_requests"`     // Record raw client requests to requests.jsonl
+	RequestCapturePath string `yaml:"request_capture_path"` // Defaults to requests.jsonl in the session directory
+
 	// Trajectory logging (ATIF format)
 	TrajectoryEnabled bool   `yaml:"trajectory_enabled"` // Enable trajectory logging
 	TrajectoryPath    string `yaml:"trajectory_path"`    // Path to trajectory.json file
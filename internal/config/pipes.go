@@ -23,6 +23,7 @@ const (
 	StrategyCompresr = pipes.StrategyCompresr
 	StrategySimple   = pipes.StrategySimple
 	StrategyTrimming = pipes.StrategyTrimming
+	StrategyLocal    = pipes.StrategyLocal
 )
 
 // TYPE ALIASES FOR YAML UNMARSHALING
@@ -33,8 +34,14 @@ type PipesConfig = pipes.Config
 // ToolOutputPipeConfig is an alias for pipes.ToolOutputConfig.
 type ToolOutputPipeConfig = pipes.ToolOutputConfig
 
+// HierarchicalConfig is an alias for pipes.HierarchicalConfig.
+type HierarchicalConfig = pipes.HierarchicalConfig
+
 // ToolDiscoveryPipeConfig is an alias for pipes.ToolDiscoveryConfig.
 type ToolDiscoveryPipeConfig = pipes.ToolDiscoveryConfig
 
 // CompresrConfig is an alias for pipes.CompresrConfig.
 type CompresrConfig = pipes.CompresrConfig
+
+// TimeoutsConfig is an alias for pipes.TimeoutsConfig.
+type TimeoutsConfig = pipes.TimeoutsConfig
@@ -3,9 +3,13 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"regexp"
 	"strings"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/external"
 )
 
 // ProviderConfig configures a single LLM provider.
@@ -14,6 +18,10 @@ type ProviderConfig struct {
 	Auth         string `yaml:"auth,omitempty"`     // Auth method: "api_key" (default), "oauth", or "bedrock" (SigV4)
 	Model        string `yaml:"model"`              // Model name (e.g., "claude-haiku-4-5", "gemini-2.0-flash")
 	Endpoint     string `yaml:"endpoint,omitempty"` // Optional: override auto-resolved endpoint
+
+	// Vertex AI only: Google Cloud project and region baked into the endpoint URL.
+	Project string `yaml:"project,omitempty"`
+	Region  string `yaml:"region,omitempty"`
 }
 
 // ProvidersConfig is a map of provider names to their configurations.
@@ -24,17 +32,46 @@ const (
 	ProviderAnthropic = "anthropic"
 	ProviderGemini    = "gemini"
 	ProviderOpenAI    = "openai"
+	ProviderVertex    = "vertex" // Gemini via Google Cloud Vertex AI (OAuth bearer, regional endpoint)
 )
 
+// VertexTokenEnv is read for the Vertex AI bearer token when api_key is empty
+// (e.g. GOOGLE_OAUTH_ACCESS_TOKEN=$(gcloud auth print-access-token)).
+const VertexTokenEnv = external.VertexTokenEnv
+
+// vertexRegionRE matches a Google Cloud region (us-central1, europe-west4).
+var vertexRegionRE = regexp.MustCompile(`^[a-z0-9-]+$`)
+
 // GetEndpoint returns the endpoint URL for a provider.
 // If Endpoint is set, returns it. Otherwise, auto-resolves from provider name + model.
 func (p ProviderConfig) GetEndpoint(providerName string) string {
 	if p.Endpoint != "" {
 		return p.Endpoint
 	}
+	if strings.EqualFold(providerName, ProviderVertex) {
+		return VertexEndpoint(p.Project, p.Region, p.Model)
+	}
 	return ResolveProviderEndpoint(providerName, p.Model)
 }
 
+// GetProviderAuth returns the configured credential. Vertex, which takes no API
+// keys, falls back to the OAuth access token in VertexTokenEnv when it is set:
+// the result is external.VertexTokenFromEnv, so the token is re-read on every
+// call rather than captured once.
+func (p ProviderConfig) GetProviderAuth(providerName string) string {
+	if p.ProviderAuth == "" && strings.EqualFold(providerName, ProviderVertex) && os.Getenv(VertexTokenEnv) != "" {
+		return external.VertexTokenFromEnv
+	}
+	return p.ProviderAuth
+}
+
+// VertexEndpoint returns the regional Vertex AI generateContent URL for a
+// Gemini model in the given Google Cloud project.
+func VertexEndpoint(project, region, model string) string {
+	return fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s/publishers/google/models/%s:generateContent",
+		region, project, region, model)
+}
+
 // ResolveProviderEndpoint returns the standard endpoint URL for a provider.
 // Falls back to treating unknown providers as OpenAI-compatible.
 func ResolveProviderEndpoint(provider, model string) string {
@@ -70,6 +107,13 @@ func (p ProvidersConfig) Validate() error {
 		if cfg.Auth == "bedrock" && cfg.ProviderAuth != "" {
 			return fmt.Errorf("provider %q: auth=bedrock but api_key is set (bedrock uses AWS SigV4)", name)
 		}
+		// Vertex builds its endpoint from project/region unless one is given
+		if strings.EqualFold(name, ProviderVertex) && cfg.Endpoint == "" && (cfg.Project == "" || cfg.Region == "") {
+			return fmt.Errorf("provider %q: project and region are required (or set endpoint)", name)
+		}
+		if strings.EqualFold(name, ProviderVertex) && cfg.Region != "" && !vertexRegionRE.MatchString(cfg.Region) {
+			return fmt.Errorf("provider %q: invalid region %q", name, cfg.Region)
+		}
 	}
 	return nil
 }
@@ -91,6 +135,9 @@ func GetUsedProviderNames(cfg *Config) []string {
 	if cfg.Preemptive.Summarizer.Provider != "" {
 		used[cfg.Preemptive.Summarizer.Provider] = true
 	}
+	if fb := cfg.Preemptive.FallbackSummarizer; fb != nil && fb.Provider != "" {
+		used[fb.Provider] = true
+	}
 
 	result := make([]string, 0, len(used))
 	for name := range used {
@@ -124,7 +171,7 @@ func (cfg *Config) ValidateUsedProviders() error {
 // ResolveProviderSettings returns the fully-resolved settings for a provider reference.
 // If providerName is empty, returns the legacy inline settings.
 type ResolvedProvider struct {
-	Provider     string // Provider name (anthropic, gemini, openai)
+	Provider     string // Provider name (anthropic, gemini, openai, vertex)
 	Endpoint     string
 	ProviderAuth string // Resolved credential field
 	Auth         string // Auth method: "api_key", "oauth", or "bedrock"
@@ -152,7 +199,7 @@ func (cfg *Config) ResolveProvider(providerName string) (*ResolvedProvider, erro
 	return &ResolvedProvider{
 		Provider:     providerName,
 		Endpoint:     provider.GetEndpoint(providerName),
-		ProviderAuth: provider.ProviderAuth,
+		ProviderAuth: provider.GetProviderAuth(providerName),
 		Auth:         auth,
 		Model:        provider.Model,
 	}, nil
@@ -160,48 +207,65 @@ func (cfg *Config) ResolveProvider(providerName string) (*ResolvedProvider, erro
 
 // ResolvePreemptiveProvider resolves provider settings for preemptive summarizer.
 // If Provider reference is set, looks up and populates Model/APIKey/Endpoint.
+// The fallback summarizer, if any, is resolved the same way.
 // Returns a copy of PreemptiveConfig with resolved settings.
 func (cfg *Config) ResolvePreemptiveProvider() PreemptiveConfig {
 	resolved := cfg.Preemptive
+	resolved.Summarizer = cfg.resolveSummarizerProvider(resolved.Summarizer)
+	if resolved.FallbackSummarizer != nil {
+		fallback := cfg.resolveSummarizerProvider(*resolved.FallbackSummarizer)
+		resolved.FallbackSummarizer = &fallback
+	}
+	return resolved
+}
 
+// resolveSummarizerProvider merges a summarizer's provider reference into a copy of sc.
+func (cfg *Config) resolveSummarizerProvider(sc SummarizerConfig) SummarizerConfig {
 	// Always inject Compresr base URL for API strategy
-	resolved.Summarizer.CompresrBaseURL = cfg.URLs.Compresr
+	sc.CompresrBaseURL = cfg.URLs.Compresr
+	sc.Model = cfg.Server.ResolveModelAlias(sc.Model)
 
-	if resolved.Summarizer.Provider == "" {
-		return resolved // No provider reference, use inline settings
+	if sc.Provider == "" {
+		return sc // No provider reference, use inline settings
 	}
 
-	provider, ok := cfg.Providers[resolved.Summarizer.Provider]
+	provider, ok := cfg.Providers[sc.Provider]
 	if !ok {
-		return resolved // Provider not found, use inline settings (validation will catch this)
+		return sc // Provider not found, use inline settings (validation will catch this)
 	}
 
 	// Merge provider settings into summarizer config
 	// Inline settings take precedence (for partial overrides)
-	if resolved.Summarizer.Model == "" {
-		resolved.Summarizer.Model = provider.Model
+	if sc.Model == "" {
+		sc.Model = cfg.Server.ResolveModelAlias(provider.Model)
 	}
-	if resolved.Summarizer.ProviderKey == "" {
-		resolved.Summarizer.ProviderKey = provider.ProviderAuth
+	if sc.ProviderKey == "" {
+		sc.ProviderKey = provider.GetProviderAuth(sc.Provider)
 		// Debug: log resolved API key length
-		if provider.ProviderAuth != "" {
+		if sc.ProviderKey != "" {
 			log.Debug().
-				Str("provider_name", resolved.Summarizer.Provider).
-				Int("provider_key_len", len(provider.ProviderAuth)).
+				Str("provider_name", sc.Provider).
+				Int("provider_key_len", len(sc.ProviderKey)).
 				Msg("Resolved API key from provider config")
 		} else {
 			log.Warn().
-				Str("provider_name", resolved.Summarizer.Provider).
+				Str("provider_name", sc.Provider).
 				Msg("Provider API key is empty!")
 		}
 	}
-	if resolved.Summarizer.Endpoint == "" {
+	if sc.Endpoint == "" && strings.EqualFold(sc.Provider, ProviderVertex) {
+		// Vertex bakes project, region and the summarizer's model into the URL
+		sc.Endpoint = provider.Endpoint
+		if sc.Endpoint == "" {
+			sc.Endpoint = VertexEndpoint(provider.Project, provider.Region, sc.Model)
+		}
+	} else if sc.Endpoint == "" {
 		// Infer actual provider type from model name to resolve correct endpoint
 		actualProvider := inferProviderFromModel(provider.Model)
-		resolved.Summarizer.Endpoint = ResolveProviderEndpoint(actualProvider, provider.Model)
+		sc.Endpoint = ResolveProviderEndpoint(actualProvider, provider.Model)
 	}
 
-	return resolved
+	return sc
 }
 
 // ResolvePreemptiveProviderWithLogging resolves provider settings and sets logging flag.
@@ -109,6 +109,7 @@ type Reloader struct {
 	config           *Config     // effective = base + session overrides (cached)
 	filePath         string
 	subscribers      []func(*Config)
+	loader           func([]byte) (*Config, error) // parses reloaded data; nil = LoadFromBytes
 }
 
 // NewReloader creates a Reloader with the given initial config and file path.
@@ -127,6 +128,20 @@ func NewReloader(cfg *Config, filePath string) *Reloader {
 	}
 }
 
+// FilePath returns the absolute path of the config file, or "" when the
+// config did not come from a file.
+func (r *Reloader) FilePath() string {
+	return r.filePath
+}
+
+// SetLoader sets the function that parses reloaded config data, so reloads
+// apply the same strictness and overrides as startup. nil restores LoadFromBytes.
+func (r *Reloader) SetLoader(fn func([]byte) (*Config, error)) {
+	r.mu.Lock()
+	r.loader = fn
+	r.mu.Unlock()
+}
+
 // Current returns the effective config: base + session overrides (thread-safe).
 func (r *Reloader) Current() *Config {
 	r.mu.RLock()
@@ -534,6 +549,16 @@ func (r *Reloader) WatchFile(ctx context.Context, interval time.Duration) {
 	if r.filePath == "" {
 		return
 	}
+	src := NewFileSource(r.filePath)
+	_, _, _ = src.Fetch(ctx) // prime: the current file is already loaded
+	r.WatchSource(ctx, src, interval)
+}
+
+// WatchSource polls src and reloads whenever it reports a change.
+// Blocks until ctx is cancelled — call in a goroutine.
+// interval is the polling frequency; 0 defaults to 3 seconds.
+// Changes are applied to the base config only; nothing is written back to src.
+func (r *Reloader) WatchSource(ctx context.Context, src Source, interval time.Duration) {
 	if interval <= 0 {
 		interval = 3 * time.Second
 	}
@@ -541,43 +566,54 @@ func (r *Reloader) WatchFile(ctx context.Context, interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
-	lastMod := r.fileMod()
+	var lastErr string
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			mod := r.fileMod()
-			if mod.IsZero() || mod.Equal(lastMod) {
+			data, changed, err := src.Fetch(ctx)
+			if err != nil {
+				// Log each distinct failure once rather than every tick.
+				if msg := err.Error(); msg != lastErr {
+					lastErr = msg
+					log.Warn().Err(err).Str("source", src.Name()).Msg("config watch: fetch failed")
+				}
+				continue
+			}
+			lastErr = ""
+			if !changed {
 				continue
 			}
-			lastMod = mod
-			if err := r.reloadFromFile(); err != nil {
-				log.Warn().Err(err).Str("path", r.filePath).Msg("config watch: reload failed")
+			if err := r.reload(data); err != nil {
+				log.Warn().Err(err).Str("source", src.Name()).Msg("config watch: reload failed")
 			} else {
-				log.Info().Str("path", r.filePath).Msg("config reloaded from file")
+				log.Info().Str("source", src.Name()).Msg("config reloaded")
 			}
 		}
 	}
 }
 
-// fileMod returns the modification time of the config file, or zero on error.
-func (r *Reloader) fileMod() time.Time {
-	info, err := os.Stat(r.filePath)
+// ReloadSource fetches src once and reloads from it whether or not it changed,
+// e.g. on SIGHUP. An invalid config is rejected and the current one kept.
+func (r *Reloader) ReloadSource(ctx context.Context, src Source) error {
+	data, _, err := src.Fetch(ctx)
 	if err != nil {
-		return time.Time{}
+		return err
 	}
-	return info.ModTime()
+	return r.reload(data)
 }
 
-// reloadFromFile reads the config file, updates baseConfig, recomputes effective
-// config (preserving session overrides), and notifies subscribers.
-func (r *Reloader) reloadFromFile() error {
-	data, err := os.ReadFile(r.filePath) //#nosec G304 -- filePath is set at startup from a trusted CLI arg, cleaned via filepath.Abs in NewReloader
-	if err != nil {
-		return fmt.Errorf("read file: %w", err)
+// reload parses data, updates baseConfig, recomputes effective config
+// (preserving session overrides), and notifies subscribers.
+func (r *Reloader) reload(data []byte) error {
+	r.mu.RLock()
+	load := r.loader
+	r.mu.RUnlock()
+	if load == nil {
+		load = LoadFromBytes
 	}
-	newCfg, err := LoadFromBytes(data)
+	newCfg, err := load(data)
 	if err != nil {
 		return fmt.Errorf("parse config: %w", err)
 	}
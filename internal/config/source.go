@@ -0,0 +1,256 @@
+// Package config - pluggable sources for raw config YAML.
+//
+// A Source produces the config bytes; parsing and validation always go through
+// LoadFromBytes, so a config fetched over HTTP is checked exactly like a local
+// file. Sources are also what the Reloader polls for live changes.
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// maxRemoteConfigSize caps the body accepted from a remote config source.
+const maxRemoteConfigSize = 10 * 1024 * 1024
+
+// Source produces raw config YAML.
+type Source interface {
+	// Fetch returns the current config bytes. changed is false when the data
+	// is the same as the previous successful Fetch.
+	Fetch(ctx context.Context) (data []byte, changed bool, err error)
+	// Name identifies the source in logs (never includes credentials).
+	Name() string
+}
+
+// IsRemoteSource reports whether location is an http(s) URL rather than a path.
+func IsRemoteSource(location string) bool {
+	lower := strings.ToLower(location)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// =============================================================================
+// FILE SOURCE
+// =============================================================================
+
+// FileSource reads config from a local file, reporting a change when its
+// modification time moves.
+type FileSource struct {
+	path    string
+	lastMod time.Time
+	data    []byte
+}
+
+// NewFileSource creates a Source for a local config file.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{path: path}
+}
+
+// Name returns the file path.
+func (s *FileSource) Name() string { return s.path }
+
+// Fetch re-reads the file if its modification time changed.
+func (s *FileSource) Fetch(_ context.Context) ([]byte, bool, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return s.data, false, fmt.Errorf("stat %s: %w", s.path, err)
+	}
+	if s.data != nil && info.ModTime().Equal(s.lastMod) {
+		return s.data, false, nil
+	}
+	data, err := os.ReadFile(s.path) // #nosec G304 -- path is a trusted CLI arg
+	if err != nil {
+		return s.data, false, fmt.Errorf("read %s: %w", s.path, err)
+	}
+	s.lastMod = info.ModTime()
+	s.data = data
+	return data, true, nil
+}
+
+// =============================================================================
+// BYTES SOURCE
+// =============================================================================
+
+// BytesSource serves fixed config bytes, e.g. an embedded default config.
+type BytesSource struct {
+	name   string
+	data   []byte
+	served bool
+}
+
+// NewBytesSource creates a Source for in-memory config bytes.
+func NewBytesSource(name string, data []byte) *BytesSource {
+	return &BytesSource{name: name, data: data}
+}
+
+// Name returns the label given at construction.
+func (s *BytesSource) Name() string { return s.name }
+
+// Fetch returns the bytes; changed is true only on the first call.
+func (s *BytesSource) Fetch(_ context.Context) ([]byte, bool, error) {
+	changed := !s.served
+	s.served = true
+	return s.data, changed, nil
+}
+
+// =============================================================================
+// HTTP SOURCE
+// =============================================================================
+
+// HTTPSource fetches config from an http(s) URL. Responses carrying an ETag are
+// revalidated with If-None-Match, and the last valid copy is cached on disk so
+// the gateway can still start when the server is unreachable.
+type HTTPSource struct {
+	url      string
+	cacheDir string
+	client   *http.Client
+
+	mu   sync.Mutex
+	etag string
+	data []byte
+}
+
+// NewHTTPSource creates a Source for a remote config URL. cacheDir holds the
+// last fetched copy; empty disables the disk cache.
+func NewHTTPSource(rawURL, cacheDir string) *HTTPSource {
+	s := &HTTPSource{
+		url:      rawURL,
+		cacheDir: cacheDir,
+		client:   &http.Client{Timeout: 30 * time.Second},
+	}
+	s.loadCache()
+	return s
+}
+
+// Name returns the URL without userinfo or query (which may hold credentials).
+func (s *HTTPSource) Name() string {
+	u, err := url.Parse(s.url)
+	if err != nil {
+		return "(remote config)"
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String()
+}
+
+// Fetch downloads the config. A 304 or an unchanged body reports changed=false.
+// The body must pass LoadFromBytes before it replaces the cached copy. On
+// network or server errors the cached copy is returned if there is one.
+func (s *HTTPSource) Fetch(ctx context.Context) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, etag, notModified, err := s.get(ctx)
+	if err != nil {
+		if s.data != nil {
+			log.Warn().Err(err).Str("source", s.Name()).Msg("remote config unavailable, using cached copy")
+			return s.data, false, nil
+		}
+		return nil, false, fmt.Errorf("fetch config from %s: %w (no cached copy available)", s.Name(), err)
+	}
+	if notModified {
+		return s.data, false, nil
+	}
+	if _, err := LoadFromBytes(data); err != nil {
+		return nil, false, fmt.Errorf("invalid config from %s: %w", s.Name(), err)
+	}
+
+	changed := string(data) != string(s.data)
+	s.data = data
+	s.etag = etag
+	if changed || etag != "" {
+		s.saveCache()
+	}
+	return data, changed, nil
+}
+
+// get performs the conditional GET. notModified is true on 304 with a cached copy.
+func (s *HTTPSource) get(ctx context.Context) (data []byte, etag string, notModified bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, "", false, err
+	}
+	req.Header.Set("Accept", "application/yaml, text/yaml, text/plain, */*")
+	if s.etag != "" && s.data != nil {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, "", false, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotModified && s.data != nil {
+		return nil, "", true, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteConfigSize+1))
+	if err != nil {
+		return nil, "", false, fmt.Errorf("read body: %w", err)
+	}
+	if len(body) > maxRemoteConfigSize {
+		return nil, "", false, fmt.Errorf("config exceeds %d bytes", maxRemoteConfigSize)
+	}
+	return body, resp.Header.Get("ETag"), false, nil
+}
+
+// cachePath returns the cache file for this URL. The name is derived from a
+// hash so credentials in the URL never reach the filesystem.
+func (s *HTTPSource) cachePath() string {
+	sum := sha256.Sum256([]byte(s.url))
+	return filepath.Join(s.cacheDir, "remote-"+hex.EncodeToString(sum[:8])+".yaml")
+}
+
+// loadCache restores the last fetched copy and its ETag from disk.
+func (s *HTTPSource) loadCache() {
+	if s.cacheDir == "" {
+		return
+	}
+	data, err := os.ReadFile(s.cachePath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Debug().Err(err).Msg("remote config cache unreadable")
+		}
+		return
+	}
+	s.data = data
+	if etag, err := os.ReadFile(s.cachePath() + ".etag"); err == nil {
+		s.etag = strings.TrimSpace(string(etag))
+	}
+}
+
+// saveCache writes the current copy and ETag to disk. Failures are logged only.
+func (s *HTTPSource) saveCache() {
+	if s.cacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(s.cacheDir, 0700); err != nil {
+		log.Debug().Err(err).Msg("remote config cache dir not writable")
+		return
+	}
+	// Configs may contain literal keys: keep the cache private.
+	if err := os.WriteFile(s.cachePath(), s.data, 0600); err != nil {
+		log.Debug().Err(err).Msg("failed to write remote config cache")
+		return
+	}
+	if err := os.WriteFile(s.cachePath()+".etag", []byte(s.etag), 0600); err != nil {
+		log.Debug().Err(err).Msg("failed to write remote config etag")
+	}
+}
@@ -0,0 +1,118 @@
+package formats
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// Binary detection thresholds.
+const (
+	// binarySampleSize is how many leading bytes are inspected.
+	binarySampleSize = 8 * 1024
+	// binaryMaxNonPrintable is the fraction of non-printable runes above which
+	// content is treated as binary.
+	binaryMaxNonPrintable = 0.10
+	// base64MinLen is the minimum length for a whitespace-free blob to count as base64.
+	base64MinLen = 256
+)
+
+// ReplacementChar substitutes invalid UTF-8 sequences (U+FFFD), matching what
+// encoding/json produces when decoding them.
+const ReplacementChar = "\uFFFD"
+
+// binaryMagic lists leading signatures of common compressed or media formats.
+var binaryMagic = []string{
+	"\x1f\x8b",          // gzip
+	"\x89PNG\r\n\x1a\n", // PNG
+	"\xff\xd8\xff",      // JPEG
+	"PK\x03\x04",        // zip
+	"\x28\xb5\x2f\xfd",  // zstd
+}
+
+// IsBinary reports whether content is binary or an encoded blob that text
+// compression cannot meaningfully shrink: a known compressed/media signature,
+// a high ratio of non-printable bytes, or a large base64 payload.
+func IsBinary(content string) bool {
+	if content == "" {
+		return false
+	}
+
+	sample := content
+	if len(sample) > binarySampleSize {
+		sample = sample[:binarySampleSize]
+	}
+	if nonPrintableRatio(sample) > binaryMaxNonPrintable {
+		return true
+	}
+	for _, magic := range binaryMagic {
+		if strings.HasPrefix(content, magic) {
+			return true
+		}
+	}
+	return isBase64Blob(strings.TrimSpace(content))
+}
+
+// ToValidUTF8 replaces each run of invalid UTF-8 bytes in data with
+// ReplacementChar. Valid input is returned as-is without copying. Invalid bytes
+// can only appear inside JSON strings, so a valid JSON document stays valid.
+func ToValidUTF8(data []byte) []byte {
+	if utf8.Valid(data) {
+		return data
+	}
+	return bytes.ToValidUTF8(data, []byte(ReplacementChar))
+}
+
+// nonPrintableRatio returns the fraction of runes in s that are control
+// characters (other than common whitespace), invalid UTF-8, or U+FFFD
+// (invalid bytes already replaced during JSON decoding).
+func nonPrintableRatio(s string) float64 {
+	total, bad := 0, 0
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		total++
+		switch {
+		case r == utf8.RuneError:
+			bad++
+		case r == '\n' || r == '\r' || r == '\t' || r == '\f':
+		case r < 0x20 || r == 0x7f:
+			bad++
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(bad) / float64(total)
+}
+
+// isBase64Blob reports whether s is a large base64 payload, optionally wrapped
+// in a data: URI or split across lines.
+func isBase64Blob(s string) bool {
+	if strings.HasPrefix(s, "data:") {
+		if idx := strings.Index(s, ";base64,"); idx > 0 && idx < 128 {
+			s = s[idx+len(";base64,"):]
+		}
+	}
+	if len(s) < base64MinLen {
+		return false
+	}
+
+	var upper, lower, digit bool
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			upper = true
+		case c >= 'a' && c <= 'z':
+			lower = true
+		case c >= '0' && c <= '9':
+			digit = true
+		case c == '+' || c == '/' || c == '-' || c == '_' || c == '=' || c == '\n' || c == '\r':
+		default:
+			return false
+		}
+	}
+	// Real payloads mix all three classes; long identifiers or hex rarely do
+	return upper && lower && digit
+}
@@ -2,7 +2,7 @@
 package gateway
 
 import (
-	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,12 +18,27 @@ import (
 // ExpandContextToolName is the name of the expand_context phantom tool.
 const ExpandContextToolName = "expand_context"
 
+// DefaultExpandNotFoundMessage is the tool_result returned when expand_context asks
+// for an ID the store does not hold (hallucinated, never compressed, or expired).
+// {id} is replaced with the requested ID.
+const DefaultExpandNotFoundMessage = "[expand_context: '{id}' is not available for expansion. " +
+	"Only IDs from [REF:...] markers on compressed tool outputs can be expanded, and stored originals expire. " +
+	"Do not retry this ID — continue with the content already in your context.]"
+
+// ExpandCompressedFallbackNote prefixes the compressed version returned when the
+// original has expired (tool_output.expand_use_compressed_on_missing_original).
+// {id} is replaced with the requested ID.
+const ExpandCompressedFallbackNote = "[expand_context: the full content of '{id}' has expired; " +
+	"below is the compressed version. Do not retry this ID.]\n"
+
 // ExpandContextHandler implements PhantomToolHandler for expand_context.
 type ExpandContextHandler struct {
 	store            store.Store
 	expandLog        *monitoring.ExpandLog
 	expandCallsLog   *monitoring.ExpandCallsLogger          // writes expand_context_calls.jsonl
 	compressionIndex map[string]pipes.ToolOutputCompression // shadow_id → compression metadata
+	notFoundMessage  string                                 // tool_output.expand_not_found_message; {id} placeholder
+	useCompressed    bool                                   // tool_output.expand_use_compressed_on_missing_original
 	requestID        string
 	sessionID        string
 	mu               sync.Mutex      // Protects expandedIDs from concurrent access
@@ -33,9 +48,35 @@ type ExpandContextHandler struct {
 // NewExpandContextHandler creates a new expand context handler.
 func NewExpandContextHandler(st store.Store) *ExpandContextHandler {
 	return &ExpandContextHandler{
-		store:       st,
-		expandedIDs: make(map[string]bool),
+		store:           st,
+		notFoundMessage: DefaultExpandNotFoundMessage,
+		expandedIDs:     make(map[string]bool),
+	}
+}
+
+// WithNotFoundMessage sets the tool_result text for IDs missing from the store.
+// Empty keeps DefaultExpandNotFoundMessage.
+func (h *ExpandContextHandler) WithNotFoundMessage(msg string) *ExpandContextHandler {
+	if msg != "" {
+		h.mu.Lock()
+		h.notFoundMessage = msg
+		h.mu.Unlock()
 	}
+	return h
+}
+
+// WithCompressedFallback makes expand_context return the cached compressed
+// version, with ExpandCompressedFallbackNote, when only the original is missing.
+func (h *ExpandContextHandler) WithCompressedFallback(enabled bool) *ExpandContextHandler {
+	h.mu.Lock()
+	h.useCompressed = enabled
+	h.mu.Unlock()
+	return h
+}
+
+// notFoundText renders the not-found message for refID.
+func (h *ExpandContextHandler) notFoundText(refID string) string {
+	return strings.ReplaceAll(h.notFoundMessage, "{id}", refID)
 }
 
 // WithExpandLog sets the expand log for recording expand_context calls.
@@ -138,7 +179,7 @@ func (h *ExpandContextHandler) HandleCalls(calls []PhantomToolCall, adapter adap
 					Msg("expand_context: retrieved field ref")
 			} else {
 				found = false
-				resultText = fmt.Sprintf("[The full content for field reference '%s' is no longer available. The compressed summary is already present in your context — please continue working with that.]", refID)
+				resultText = h.notFoundText(refID)
 				log.Warn().
 					Str("field_ref", refID).
 					Str("request_id", h.requestID).
@@ -153,8 +194,15 @@ func (h *ExpandContextHandler) HandleCalls(calls []PhantomToolCall, adapter adap
 					Str("shadow_id", refID).
 					Int("content_len", len(content)).
 					Msg("expand_context: retrieved content")
+			} else if compressed, ok := h.compressedFallback(refID); ok {
+				resultText = strings.ReplaceAll(ExpandCompressedFallbackNote, "{id}", refID) + compressed
+				log.Warn().
+					Str("shadow_id", refID).
+					Str("request_id", h.requestID).
+					Str("reason", "original_expired").
+					Msg("expand_context: original missing, returned compressed version")
 			} else {
-				resultText = fmt.Sprintf("[The full content for shadow reference '%s' is no longer available (gateway was restarted between sessions). The compressed summary is already present in your context — please continue working with that.]", refID)
+				resultText = h.notFoundText(refID)
 				log.Error().
 					Str("shadow_id", refID).
 					Str("request_id", h.requestID).
@@ -177,6 +225,18 @@ func (h *ExpandContextHandler) HandleCalls(calls []PhantomToolCall, adapter adap
 	return result
 }
 
+// compressedFallback returns the cached compressed version of refID when
+// WithCompressedFallback is on.
+func (h *ExpandContextHandler) compressedFallback(refID string) (string, bool) {
+	h.mu.Lock()
+	enabled := h.useCompressed
+	h.mu.Unlock()
+	if !enabled {
+		return "", false
+	}
+	return h.store.GetCompressed(refID)
+}
+
 // isFieldRef checks if the ref ID is a field-level reference.
 func isFieldRef(refID string) bool {
 	return len(refID) > 6 && refID[:6] == "field_"
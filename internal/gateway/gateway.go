@@ -6,11 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/fs"
-	"net"
 	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -24,6 +23,8 @@ import (
 	"github.com/compresr/context-gateway/internal/costcontrol"
 	"github.com/compresr/context-gateway/internal/dashboard"
 	"github.com/compresr/context-gateway/internal/monitoring"
+	"github.com/compresr/context-gateway/internal/notifications"
+	"github.com/compresr/context-gateway/internal/pipes"
 	"github.com/compresr/context-gateway/internal/postsession"
 	"github.com/compresr/context-gateway/internal/preemptive"
 	"github.com/compresr/context-gateway/internal/prompthistory"
@@ -33,10 +34,24 @@ import (
 // Header constants for gateway requests.
 const (
 	HeaderRequestID = "X-Request-ID"
-	HeaderTargetURL = "X-Target-URL"
+	HeaderTargetURL = "X-Target-URL" // Upstream: a base URL gets the request path joined on; a URL ending with the path or a known endpoint is used as-is
 	HeaderProvider  = "X-Provider"
 )
 
+// Response headers summarizing tool_output compression (tool_output.add_response_headers).
+const (
+	HeaderCompressedBlocks = "X-CG-Compressed-Blocks" // tool outputs rewritten (compressed, cached, deduplicated)
+	HeaderOriginalBytes    = "X-CG-Original-Bytes"    // request body size as sent by the client
+	HeaderSentBytes        = "X-CG-Sent-Bytes"        // request body size forwarded upstream
+	HeaderExpandAvailable  = "X-CG-Expand-Available"  // "true" when expand_context can restore originals
+)
+
+// Per-block compression detail for debugging, requested per call.
+const (
+	HeaderExplain       = "X-CG-Explain"        // request: "true" asks for HeaderExplainBlocks; never forwarded
+	HeaderExplainBlocks = "X-CG-Explain-Blocks" // response: JSON array of ExplainBlock
+)
+
 // Re-export centralized defaults for backward compatibility within this package.
 const (
 	MaxRequestBodySize     = config.MaxRequestBodySize
@@ -105,7 +120,7 @@ func init() {
 				continue
 			}
 			// Validate entry is a proper hostname or CIDR to prevent accidental SSRF expansion.
-			if !isValidHostEntry(host) {
+			if !config.IsValidHostEntry(host) {
 				log.Warn().Str("entry", host).Msg("GATEWAY_ALLOWED_HOSTS: skipping invalid hostname/CIDR entry")
 				continue
 			}
@@ -120,29 +135,15 @@ func init() {
 	}
 }
 
-// validHostnameRE matches valid hostnames (e.g. api.openai.com) and bare labels (e.g. localhost).
-// Allows letters, digits, hyphens, and dots. Does not allow IP literals (handled by net.ParseCIDR).
-var validHostnameRE = regexp.MustCompile(`^[a-z0-9]([a-z0-9\.\-]*[a-z0-9])?$`)
-
-// isValidHostEntry returns true if entry is a valid hostname or CIDR range.
-// Invalid entries in GATEWAY_ALLOWED_HOSTS are logged and skipped to prevent
-// accidental SSRF allowlist expansion via typos or injection.
-func isValidHostEntry(entry string) bool {
-	// Accept CIDR ranges (e.g. 10.0.0.0/8)
-	if _, _, err := net.ParseCIDR(entry); err == nil {
-		return true
-	}
-	// Accept plain IP addresses
-	if net.ParseIP(entry) != nil {
-		return true
-	}
-	// Accept valid hostnames (lowercase enforced by caller)
-	return validHostnameRE.MatchString(entry)
-}
-
 // EnableLocalHostsForTesting adds localhost to the SSRF allowlist.
 // This should only be called from test setup (TestMain).
 func EnableLocalHostsForTesting() {
+	EnableLocalHostsForReplay()
+}
+
+// EnableLocalHostsForReplay adds localhost to the SSRF allowlist so an in-process
+// mock upstream is reachable. Only for offline replay-session; never for serve.
+func EnableLocalHostsForReplay() {
 	allowedHosts["localhost"] = true
 	allowedHosts["127.0.0.1"] = true
 }
@@ -172,6 +173,7 @@ type Gateway struct {
 	aggregator        *monitoring.LogAggregator  // New: Background log aggregator (single source of truth)
 	trajectory        *monitoring.TrajectoryStore
 	httpClient        *http.Client
+	upstreamLastUsed  sync.Map     // host → time a kept-alive upstream connection was last used (prewarm skips warm hosts)
 	peerHTTPClient    *http.Client // Short-timeout client for peer dashboard calls (loopback)
 	monitorHTTPClient *http.Client // Short-timeout client for monitor/sessions calls (loopback)
 	server            *http.Server
@@ -181,11 +183,15 @@ type Gateway struct {
 
 	// Config hot-reload
 	configReloader *config.Reloader
-	watchCancel    context.CancelFunc // cancels the file-watcher goroutine
+	watchCtx       context.Context    // lifetime of config watcher goroutines
+	watchCancel    context.CancelFunc // cancels the config watcher goroutines
 
 	// Cost control
 	costTracker *costcontrol.Tracker
 
+	// Outbound event notifications (Slack, Discord, generic webhook)
+	notifier *notifications.Dispatcher
+
 	// Preemptive summarization
 	preemptive *preemptive.Manager
 
@@ -193,6 +199,16 @@ type Gateway struct {
 	toolSessions *ToolSessionStore
 	authMode     *authFallbackStore
 
+	// Replayed responses for exact request repeats (server.response_cache_ttl).
+	responseCache *responseCache
+
+	// Session that produced each shadow ID, checked by GET /expand/{id}.
+	shadowOwners *shadowOwners
+
+	// Embedder hooks registered before New (hooks.go).
+	requestHooks  []RequestHook
+	responseHooks []ResponseHook
+
 	// Provider-specific auth handlers (subscription/fallback)
 	authRegistry *auth.Registry
 
@@ -334,6 +350,7 @@ type StatusReporter interface {
 func New(cfg *config.Config, configFilePath ...string) *Gateway {
 	st := store.NewMemoryStoreWithDualTTL(store.DefaultOriginalTTL, store.DefaultCompressedTTL)
 	registry := adapters.NewRegistry()
+	requestHooks, responseHooks := registeredHooks()
 	r := NewRouter(cfg, st)
 
 	// Initialize logging
@@ -341,6 +358,8 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 		Level:  cfg.Monitoring.LogLevel,
 		Format: cfg.Monitoring.LogFormat,
 		Output: cfg.Monitoring.LogOutput,
+
+		DisableMasking: cfg.Monitoring.UnmaskedLogs,
 	}
 	logger := monitoring.New(loggerCfg)
 	monitoring.Global(loggerCfg)
@@ -353,6 +372,10 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 	})
 
 	// Initialize telemetry
+	requestCapturePath := ""
+	if cfg.Monitoring.CaptureRequests {
+		requestCapturePath = cfg.Monitoring.RequestCapturePath
+	}
 	tracker, err := monitoring.NewTracker(monitoring.TelemetryConfig{
 		Enabled:                cfg.Monitoring.TelemetryEnabled,
 		LogPath:                cfg.Monitoring.TelemetryPath,
@@ -364,6 +387,10 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 		SessionToolsPath:       cfg.Monitoring.SessionToolsPath,
 		SessionStatsPath:       cfg.Monitoring.SessionStatsPath,
 		ExpandContextCallsPath: cfg.Monitoring.ExpandContextCallsPath,
+		RequestCapturePath:     requestCapturePath,
+		SQLitePath:             cfg.Monitoring.TelemetrySQLitePath,
+
+		CompressionLogSampleRate: cfg.Monitoring.CompressionLogSampleRate,
 	})
 	if err != nil {
 		log.Error().Err(err).Msg("failed to initialize telemetry")
@@ -384,29 +411,20 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 		AgentName: cfg.Monitoring.AgentName,
 	})
 
-	// Use config write_timeout for upstream requests
-	// If 0, no timeout (recommended for LLM proxies to avoid client retries on timeout)
-	clientTimeout := cfg.Server.WriteTimeout
-	headerTimeout := cfg.Server.WriteTimeout
-	if clientTimeout == 0 {
-		headerTimeout = 0 // No response header timeout if no client timeout
-	}
+	// Upstream timeouts: connect/first_byte/overall, defaulting to write_timeout.
+	// Overall 0 = no timeout (recommended for LLM proxies to avoid client retries on timeout)
+	upstreamTimeouts := cfg.Server.EffectiveUpstreamTimeouts()
 
 	transport := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
+		Proxy:                 http.ProxyFromEnvironment,
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
 		MaxIdleConnsPerHost:   20,
 		MaxConnsPerHost:       100,
 		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
 		ExpectContinueTimeout: 1 * time.Second,
-		ResponseHeaderTimeout: headerTimeout, // 0 = no timeout (safe for LLM with extended thinking)
 	}
+	upstreamTimeouts.ApplyTo(transport) // dial, TLS handshake, response header (0 = safe for extended thinking)
 
 	// Initialize AWS Bedrock signer only when explicitly enabled
 	var bedrockSigner *BedrockSigner
@@ -471,14 +489,19 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 		savings:           monitoring.NewSavingsTracker(),
 		aggregator:        aggregator,
 		trajectory:        trajectoryStore,
-		httpClient:        &http.Client{Timeout: clientTimeout, Transport: transport},
+		httpClient:        &http.Client{Timeout: upstreamTimeouts.Overall, Transport: transport},
 		peerHTTPClient:    &http.Client{Timeout: 2 * time.Second},
 		monitorHTTPClient: &http.Client{Timeout: 3 * time.Second},
 		rateLimiter:       newRateLimiter(DefaultRateLimit),
 		costTracker:       costcontrol.NewTracker(cfg.CostControl),
+		notifier:          notifications.NewDispatcher(cfg.Notifications),
 		preemptive:        preemptive.NewManager(cfg.ResolvePreemptiveProviderWithLogging(cfg.Monitoring.TelemetryEnabled)),
 		toolSessions:      toolSessions,
 		authMode:          newAuthFallbackStore(time.Hour),
+		responseCache:     newResponseCache(),
+		shadowOwners:      newShadowOwners(),
+		requestHooks:      requestHooks,
+		responseHooks:     responseHooks,
 		authRegistry:      authRegistry,
 		bedrockSigner:     bedrockSigner,
 		expandLog:         monitoring.NewExpandLog(),
@@ -504,10 +527,9 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 
 	// Start file watcher so changes to the YAML config file are picked up live.
 	// The watcher goroutine is stopped in Shutdown() via watchCancel.
-	watchCtx, watchCancel := context.WithCancel(context.Background())
-	g.watchCancel = watchCancel
+	g.watchCtx, g.watchCancel = context.WithCancel(context.Background())
 	if cfgPath != "" {
-		go g.configReloader.WatchFile(watchCtx, 3*time.Second)
+		go g.configReloader.WatchFile(g.watchCtx, 3*time.Second)
 	}
 
 	// Subscribe subsystems to config changes
@@ -515,6 +537,9 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 		if g.costTracker != nil {
 			g.costTracker.UpdateConfig(newCfg.CostControl)
 		}
+		if g.notifier != nil {
+			g.notifier.UpdateConfig(newCfg.Notifications)
+		}
 		if g.router != nil {
 			g.router.UpdateConfig(newCfg)
 		}
@@ -560,7 +585,7 @@ func New(cfg *config.Config, configFilePath ...string) *Gateway {
 			defer ticker.Stop()
 			for {
 				select {
-				case <-watchCtx.Done():
+				case <-g.watchCtx.Done():
 					return
 				case <-ticker.C:
 					if !g.dashboardStarted {
@@ -585,6 +610,24 @@ func (g *Gateway) ConfigReloader() *config.Reloader {
 	return g.configReloader
 }
 
+// WatchConfigSource polls src for config changes until Shutdown.
+// Used for sources other than the local config file, which is watched by default.
+func (g *Gateway) WatchConfigSource(src config.Source, interval time.Duration) {
+	go g.configReloader.WatchSource(g.watchCtx, src, interval)
+}
+
+// ReloadConfig re-reads src and applies it to new requests; in-flight requests
+// and open connections are unaffected. On error the current config stays live.
+func (g *Gateway) ReloadConfig(ctx context.Context, src config.Source) error {
+	return g.configReloader.ReloadSource(ctx, src)
+}
+
+// SetConfigLoader sets how reloaded config data is parsed (file watch, --watch
+// and reload signals); see config.Reloader.SetLoader.
+func (g *Gateway) SetConfigLoader(fn func([]byte) (*config.Config, error)) {
+	g.configReloader.SetLoader(fn)
+}
+
 // CostTracker returns the gateway's cost tracker (for CLI status display).
 func (g *Gateway) CostTracker() *costcontrol.Tracker {
 	return g.costTracker
@@ -724,6 +767,9 @@ func (g *Gateway) resetForNewSession() {
 	if g.metrics != nil {
 		g.metrics.Reset()
 	}
+	if g.tracker != nil {
+		g.tracker.ResetCompressionTotals()
+	}
 
 	// Reset shadow context store (cached compressed content from previous sessions)
 	if ms, ok := g.store.(*store.MemoryStore); ok {
@@ -740,6 +786,13 @@ func (g *Gateway) resetForNewSession() {
 		g.authMode.Reset()
 	}
 
+	if g.responseCache != nil {
+		g.responseCache.Reset()
+	}
+	if g.shadowOwners != nil {
+		g.shadowOwners.reset()
+	}
+
 	log.Debug().Msg("all session variables reset to 0")
 }
 
@@ -748,6 +801,8 @@ func (g *Gateway) resetForNewSession() {
 func (g *Gateway) setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/health", g.handleHealth)
 	mux.HandleFunc("/expand", g.handleExpand)
+	mux.HandleFunc("/count-tokens", g.handleCountTokens)
+	mux.HandleFunc("/v1/messages/count_tokens", g.handleMessagesCountTokens)
 	// API endpoints still available on proxy port for internal use (e.g., /savings slash command)
 	mux.HandleFunc("/api/dashboard", g.handleDashboardAPI)
 	mux.HandleFunc("/api/savings", g.handleSavingsAPI)
@@ -760,6 +815,8 @@ func (g *Gateway) setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/api/compress/", g.handleCompressAPINotFound)
 	mux.HandleFunc("/stats", g.handleStats)
 	mux.HandleFunc("/v1/models", g.handleModels)
+	mux.HandleFunc("/debug/store", g.handleDebugStore)
+	mux.HandleFunc("/expand/", g.handleExpandByID)
 
 	// Session monitoring dashboard
 	monitorHandlers := dashboard.NewHandlers(g.monitorStore, g.monitorHub)
@@ -814,6 +871,15 @@ func (g *Gateway) handleCompressAPINotFound(w http.ResponseWriter, r *http.Reque
 // Start starts the gateway.
 func (g *Gateway) Start() error {
 	log.Info().Int("port", g.config.Server.Port).Msg("Context Gateway starting")
+	if headers := g.config.Server.UpstreamHeaders; len(headers) > 0 {
+		names := make([]string, 0, len(headers))
+		for name := range headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		// Names only: values often carry credentials
+		log.Info().Strs("headers", names).Msg("injecting upstream headers")
+	}
 	if g.dashboardStarted {
 		log.Info().
 			Int("port", config.DefaultDashboardPort).
@@ -833,6 +899,25 @@ func (g *Gateway) IsAllowedHostForTest(host string) bool {
 	return g.isAllowedHost(host)
 }
 
+// AutoDetectTargetURLForTest exposes autoDetectTargetURL for unit testing
+// upstream selection without X-Target-URL.
+func (g *Gateway) AutoDetectTargetURLForTest(r *http.Request) string {
+	return g.autoDetectTargetURL(r)
+}
+
+// ShadowStoreForTest returns the shadow store, e.g. to seed originals.
+func (g *Gateway) ShadowStoreForTest() store.Store {
+	return g.store
+}
+
+// SetToolOutputPipeForTest replaces the tool_output workers with pipes built by
+// factory, e.g. to inject a failing pipe. Call before serving requests.
+func (g *Gateway) SetToolOutputPipeForTest(factory func() pipes.Pipe) {
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+	g.router.toolOutputPool = newPool(g.router.poolSize, factory)
+}
+
 // Shutdown gracefully shuts down the gateway.
 func (g *Gateway) Shutdown(ctx context.Context) error {
 	log.Info().Msg("gateway shutting down")
@@ -849,6 +934,9 @@ func (g *Gateway) Shutdown(ctx context.Context) error {
 	if g.authMode != nil {
 		g.authMode.Stop()
 	}
+	if g.responseCache != nil {
+		g.responseCache.Stop()
+	}
 	if g.authRegistry != nil {
 		g.authRegistry.Stop()
 	}
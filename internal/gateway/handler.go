@@ -5,10 +5,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,6 +26,7 @@ import (
 	"github.com/compresr/context-gateway/internal/dashboard"
 	"github.com/compresr/context-gateway/internal/monitoring"
 	phantom_tools "github.com/compresr/context-gateway/internal/phantom_tools"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
 	"github.com/compresr/context-gateway/internal/preemptive"
 	"github.com/compresr/context-gateway/internal/prompthistory"
 	"github.com/compresr/context-gateway/internal/tokenizer"
@@ -339,6 +343,16 @@ func (g *Gateway) writeError(w http.ResponseWriter, msg string, status int) {
 	}
 }
 
+// writeUpstreamError reports a failed upstream call: 504 when the request
+// deadline (server.request_timeout) expired, 502 otherwise.
+func (g *Gateway) writeUpstreamError(w http.ResponseWriter, r *http.Request) {
+	if errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		g.writeError(w, "request timeout", http.StatusGatewayTimeout)
+		return
+	}
+	g.writeError(w, "upstream request failed", http.StatusBadGateway)
+}
+
 // handleHealth returns gateway health status.
 func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
 	health := map[string]any{
@@ -346,6 +360,15 @@ func (g *Gateway) handleHealth(w http.ResponseWriter, r *http.Request) {
 		"time":    time.Now().Format(time.RFC3339),
 		"version": g.version,
 	}
+	// Local callers only. serve matches the pid to confirm it reached its own
+	// listener; the launcher compares the config before sharing a running
+	// gateway (--detach, --reuse-gateway).
+	if isLoopback(r.RemoteAddr) {
+		health["pid"] = os.Getpid()
+		if path := g.configReloader.FilePath(); path != "" {
+			health["config"] = path
+		}
+	}
 
 	if err := g.store.Set("_health_", "ok"); err != nil {
 		health["status"] = "degraded"
@@ -449,13 +472,42 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject disallowed X-Target-URL hosts before doing any work (SSRF protection)
+	if !g.isAllowedTargetURL(r.Header.Get(HeaderTargetURL)) {
+		log.Warn().
+			Str("target_url", r.Header.Get(HeaderTargetURL)).
+			Str("client_ip", r.RemoteAddr).
+			Msg("rejected request: target host not allowed")
+		g.writeError(w, "target host not allowed", http.StatusForbidden)
+		return
+	}
+
+	// Without X-Target-URL the upstream comes from server.default_upstream or is
+	// inferred; fail fast with a clear message when neither works.
+	if r.Header.Get(HeaderTargetURL) == "" && g.autoDetectTargetURL(r) == "" {
+		log.Warn().Str("path", r.URL.Path).Msg("rejected request: upstream could not be determined")
+		g.writeError(w, errUnknownUpstream.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// X-CG-Explain is for the gateway only; drop it so no path forwards it.
+	explain, _ := strconv.ParseBool(r.Header.Get(HeaderExplain))
+	r.Header.Del(HeaderExplain)
+
+	// One deadline for the whole request. Pipes and the upstream call all derive
+	// from r.Context(), so expiry or a client disconnect cancels them together.
+	if timeout := g.cfg().Server.RequestTimeout; timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
 	// Non-LLM endpoints (telemetry, analytics, event_logging) forward to upstream unchanged
 	// These SDK requests pass through transparently - client unaware of proxy
 	if g.isNonLLMEndpoint(r.URL.Path) {
-		r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
-		body, err := io.ReadAll(r.Body)
+		body, err := g.readRequestBody(w, r)
 		if err != nil {
-			g.writeError(w, "failed to read request", http.StatusBadRequest)
+			g.writeBodyReadError(w, err)
 			return
 		}
 
@@ -463,7 +515,7 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 		resp, _, err := g.forwardPassthrough(r.Context(), r, body)
 		if err != nil {
 			log.Debug().Err(err).Str("path", r.URL.Path).Msg("passthrough failed")
-			g.writeError(w, "upstream request failed", http.StatusBadGateway)
+			g.writeUpstreamError(w, r)
 			return
 		}
 		defer func() { _ = resp.Body.Close() }()
@@ -480,11 +532,10 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 	g.EnsureSession()
 
 	// Read and validate body
-	r.Body = http.MaxBytesReader(w, r.Body, MaxRequestBodySize)
-	body, err := io.ReadAll(r.Body)
+	body, err := g.readRequestBody(w, r)
 	if err != nil {
 		g.alerts.FlagInvalidRequest(requestID, "failed to read body", nil)
-		g.writeError(w, "failed to read request", http.StatusBadRequest)
+		g.writeBodyReadError(w, err)
 		return
 	}
 
@@ -496,19 +547,46 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Record the untouched request for offline replay (monitoring.capture_requests)
+	g.tracker.CaptureRequest(monitoring.NewCapturedRequest(r, requestID, body))
+
+	// Resolve server.model_aliases before anything reads the model.
+	body = g.applyModelAlias(body, requestID)
+
+	// Exact repeat of a recent non-streaming request: replay the cached response
+	// without running pipes or calling upstream (server.response_cache_ttl).
+	var cacheKey string
+	if g.responseCache != nil && g.cfg().Server.ResponseCacheTTL > 0 && !g.isStreamingRequest(body) {
+		cacheKey = responseCacheKey(r, body)
+		if cached, ok := g.responseCache.Get(cacheKey); ok {
+			log.Info().
+				Str("request_id", requestID).
+				Int("response_size", len(cached.body)).
+				Msg("Returning cached response for repeated request")
+			copyHeaders(w, cached.header)
+			w.Header().Set(HeaderResponseCache, "hit")
+			w.WriteHeader(cached.statusCode)
+			_, _ = w.Write(cached.body) //nolint:gosec // G705: replays headers set when the response was first written
+			return
+		}
+	}
+
 	// Build pipeline context (no universal parsing needed)
 	pipeCtx := NewPipelineContext(provider, adapter, body, r.URL.Path)
 	pipeCtx.RequestCtx = r.Context()
 	pipeCtx.RequestID = requestID
+	pipeCtx.ResponseCacheKey = cacheKey
+	// Use canonical session ID from preemptive package (hash of first user message).
+	// The clean first-user-message hash keeps the session ID stable across turns
+	// even when phantom tools are injected (injected XML changes full-body hash).
+	// Used for tool discovery caching and per-session compression budgets.
+	sessionID := preemptive.ComputeSessionIDFromClean(pipeCtx.Classification.FirstUserCleanContent)
+	pipeCtx.SessionID = sessionID
+
 	// Initialize tool session for hybrid tool discovery
-	// Use canonical session ID from preemptive package (hash of first user message)
 	if g.toolSessions != nil && g.cfg().Pipes.ToolDiscovery.Enabled {
-		// Use clean first-user-message hash so session ID is stable across turns
-		// even when phantom tools are injected (injected XML changes full-body hash).
-		sessionID := preemptive.ComputeSessionIDFromClean(pipeCtx.Classification.FirstUserCleanContent)
 		if sessionID != "" {
 			pipeCtx.ToolSessionID = sessionID
-			pipeCtx.SessionID = sessionID // Also set for tool discovery pipe caching
 			// BUG-027: Cache isMainAgent per session so turn 2+ doesn't mis-classify
 			// when the tools array has shrunk (filtered tools removed from context).
 			if cached, ok := g.toolSessions.GetIsMainAgent(sessionID); ok {
@@ -539,6 +617,9 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 	// Capture auth headers from incoming request using centralized helper
 	// Used by pipes (tool output compression), preemptive summarizer, and session collector
 	capturedAuth := authtypes.CaptureFromHeaders(r.Header)
+	// Consumers use Endpoint as the full upstream URL, so resolve it the same
+	// way the proxied request is (X-Target-URL joined with the path, or auto-detected).
+	capturedAuth.Endpoint, _ = g.resolveTargetURL(r)
 
 	// Pass full auth to pipes so they can handle both API key and OAuth users
 	pipeCtx.CapturedAuth = capturedAuth
@@ -548,11 +629,7 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 
 	// Capture auth for post-session updater using the same captured auth
 	if g.sessionCollector != nil && capturedAuth.HasAuth() {
-		sessionAuth := capturedAuth
-		if sessionAuth.Endpoint == "" {
-			sessionAuth.Endpoint = g.autoDetectTargetURL(r)
-		}
-		g.sessionCollector.CaptureAuth(sessionAuth)
+		g.sessionCollector.CaptureAuth(capturedAuth)
 	}
 
 	// Extract model for preemptive summarization and cost-based compression decisions
@@ -697,20 +774,14 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 	var isCompaction bool
 	var syntheticResponse []byte
 	if g.preemptive != nil {
-		// Resolve endpoint: X-Target-URL header > autoDetect
-		xTargetURL := r.Header.Get(HeaderTargetURL)
-		targetURL := xTargetURL
-		if targetURL == "" {
-			targetURL = g.autoDetectTargetURL(r)
-		}
-		// Pass full auth struct to summarizer — single call, single source of truth
+		// Pass full auth struct to summarizer — single call, single source of truth.
+		// Its Endpoint is the resolved upstream URL (see resolveTargetURL).
 		authForSummarizer := capturedAuth
-		authForSummarizer.Endpoint = targetURL
 		if authForSummarizer.HasAuth() || authForSummarizer.Endpoint != "" {
 			log.Debug().
 				Str("auth_type", map[bool]string{true: "x-api-key", false: "Authorization"}[capturedAuth.IsXAPIKey]).
 				Str("auth", utils.MaskKey(capturedAuth.Token)).
-				Str("endpoint", targetURL).
+				Str("endpoint", authForSummarizer.Endpoint).
 				Msg("Passing auth to summarizer")
 			g.preemptive.SetAuth(authForSummarizer)
 		}
@@ -789,6 +860,20 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 				pipeCtx.OriginalRequest = body
 			}
 		}
+
+		// Whole-history tool_result compaction (preemptive.tool_result_compaction)
+		if !isCompaction {
+			if compacted, ok := g.preemptive.CompactToolResults(body, pipeCtx.SessionID, g.archiveToolResult); ok {
+				if g.savings != nil && len(compacted) < len(body) {
+					origTok := tokenizer.CountBytes(body)
+					compactedTok := tokenizer.CountBytes(compacted)
+					g.savings.RecordPreemptiveSummarization(origTok, compactedTok, model, pipeCtx.CostSessionID, g.isMainConversation(pipeCtx.StableFingerprint))
+					g.tracker.RecordPreemptiveStats(origTok, compactedTok)
+				}
+				body = compacted
+				pipeCtx.OriginalRequest = body
+			}
+		}
 	}
 
 	// Capture pre-compaction body size BEFORE compression pipeline may further modify it.
@@ -803,7 +888,7 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Store preemptive headers in context for response
-	pipeCtx.PreemptiveHeaders = preemptiveHeaders
+	pipeCtx.ResponseHeaders = preemptiveHeaders
 	pipeCtx.IsCompaction = isCompaction
 
 	// Capture prompt to persistent history (non-blocking).
@@ -852,8 +937,44 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// server.pipeline_compression: connect upstream while compression runs.
+	if g.cfg().Server.PipelineCompression && len(body) >= pipelineCompressionMinBytes {
+		if targetURL, err := g.resolveTargetURL(r); err == nil {
+			go g.prewarmUpstream(r.Context(), targetURL, requestID)
+		}
+	}
+
 	// Process compression pipeline
 	forwardBody, pipeType, pipeStrategy, compressionUsed, compressLatency := g.processCompressionPipeline(body, pipeCtx, requestID)
+	var leaked, stripped int
+	if forwardBody, leaked, stripped = stripLeakedShadowRefs(forwardBody, g.isShadowID); leaked > 0 {
+		log.Warn().
+			Str("request_id", requestID).
+			Int("leaked_refs", leaked).
+			Int("stripped_refs", stripped).
+			Msg("[REF:id] shadow references found in system/assistant text; removed those the gateway emitted")
+		if g.metrics != nil {
+			g.metrics.RecordLeakedRefs(leaked)
+		}
+	}
+
+	// Last resort for requests still over the context window (preemptive.overflow_action).
+	if g.preemptive != nil {
+		guarded, changed, err := g.preemptive.GuardOverflow(r.Context(), r.Header, forwardBody, model)
+		if err != nil {
+			log.Warn().Err(err).Str("request_id", requestID).Msg("Rejecting request over the context window")
+			g.writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if changed {
+			forwardBody = guarded
+		}
+	}
+
+	g.shadowOwners.record(pipeCtx.ShadowRefs, pipeCtx.CostSessionID)
+	if g.cfg().Server.DebugEndpoints && len(pipeCtx.ShadowRefs) > 0 {
+		w.Header().Set(HeaderSessionID, pipeCtx.CostSessionID)
+	}
 
 	// Store deferred tools in session for hybrid search fallback
 	if g.toolSessions != nil && pipeCtx.ToolSessionID != "" && len(pipeCtx.DeferredTools) > 0 {
@@ -870,15 +991,32 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 	// regardless of which pipes are enabled. Config may change mid-session, and
 	// the LLM should consistently see both tools from turn one.
 	// Dedup in InjectPhantomTool prevents double-injection if a tool already exists.
+	// A client tool_choice of "none" or a forced non-phantom tool skips injection
+	// so the forwarded request keeps the client's tool_choice valid.
 	isStreaming := g.isStreamingRequest(body)
-	if injected, err := phantom_tools.InjectAll(forwardBody, provider); err == nil {
+	if injected, allowed, err := phantom_tools.InjectAllIfAllowed(forwardBody, provider); !allowed {
+		log.Debug().Str("request_id", requestID).Msg("tool_choice excludes phantom tools, skipping injection")
+	} else if err == nil {
 		forwardBody = injected
 		pipeCtx.PhantomToolsInjected = true
+		// Lossy mode keeps no originals, so expand_context would have nothing to return.
+		if g.cfg().Pipes.ToolOutput.LossyMode && !phantom_tools.HasToolByName(body, ExpandContextToolName) {
+			forwardBody = removeToolFromRequest(forwardBody, ExpandContextToolName)
+		}
 	}
+	forwardBody = g.runRequestHooks(forwardBody, pipeCtx, r.URL.Path)
+	g.metrics.RecordBytes(preCompactionBodySize, len(forwardBody))
 	// expandEnabled=true: phantom loop always handles calls to either tool.
 	// For streaming: needsExpandBuffer still checks compressionUsed + ShadowRefs.
 	expandEnabled := true
 
+	if g.cfg().Pipes.ToolOutput.AddResponseHeaders {
+		addCompressionHeaders(pipeCtx, preCompactionBodySize, len(forwardBody))
+	}
+	if explain {
+		addExplainHeader(pipeCtx)
+	}
+
 	// Route to streaming or non-streaming handler
 	if isStreaming {
 		g.handleStreamingWithExpand(w, r, forwardBody, pipeCtx, requestID, startTime, adapter,
@@ -889,6 +1027,25 @@ func (g *Gateway) handleProxy(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// archiveToolResult keeps a tool_result compacted by preemptive
+// tool_result_compaction in the shadow store so expand_context can restore it.
+func (g *Gateway) archiveToolResult(content string) string {
+	shadowID := tooloutput.HashShadowIDGenerator{}.ShadowID(content)
+	if _, ok := g.store.Get(shadowID); !ok {
+		if err := g.store.Set(shadowID, content); err != nil {
+			log.Warn().Err(err).Str("shadow_id", shadowID).Msg("failed to archive compacted tool result")
+		}
+	}
+	return shadowID
+}
+
+// isShadowID reports whether id is a shadow ID the gateway stored, i.e. a
+// [REF:id] it emitted itself.
+func (g *Gateway) isShadowID(id string) bool {
+	_, ok := g.store.Get(id)
+	return ok
+}
+
 // processCompressionPipeline routes and processes through ALL applicable compression pipes.
 // Now processes BOTH tool_output AND tool_discovery if both are present (no priority skipping).
 func (g *Gateway) processCompressionPipeline(body []byte, pipeCtx *PipelineContext, requestID string) ([]byte, PipeType, string, bool, time.Duration) {
@@ -951,11 +1108,24 @@ func (g *Gateway) processCompressionPipeline(body []byte, pipeCtx *PipelineConte
 			CacheHit:         tc.CacheHit, IsLastTool: tc.IsLastTool, MappingStatus: tc.MappingStatus,
 			Duration: compressLatency,
 		})
-		g.metrics.RecordCompression(tc.OriginalTokens, tc.CompressedTokens, true)
-		if tc.CacheHit {
-			g.metrics.RecordCacheHit()
-		} else {
+		// Only count outputs that were actually replaced; passthrough entries are
+		// neither compressions nor cache lookups.
+		switch tc.MappingStatus {
+		case "compressed":
+			g.metrics.RecordCompression(tc.OriginalTokens, tc.CompressedTokens, true)
 			g.metrics.RecordCacheMiss()
+		case "cache_hit":
+			g.metrics.RecordCompression(tc.OriginalTokens, tc.CompressedTokens, true)
+			g.metrics.RecordCacheHit()
+		case "deduplicated":
+			g.metrics.RecordCompression(tc.OriginalTokens, tc.CompressedTokens, true)
+		case "input_compressed":
+			g.metrics.RecordCompression(tc.OriginalTokens, tc.CompressedTokens, true)
+			if tc.CacheHit {
+				g.metrics.RecordCacheHit()
+			} else {
+				g.metrics.RecordCacheMiss()
+			}
 		}
 		// Record for post-session collector
 		if g.sessionCollector != nil {
@@ -966,20 +1136,43 @@ func (g *Gateway) processCompressionPipeline(body []byte, pipeCtx *PipelineConte
 	return forwardBody, pipeType, pipeStrategy, compressionUsed, compressLatency
 }
 
+// errUnknownUpstream is returned when a request has no X-Target-URL and no
+// upstream can be configured or inferred for it.
+var errUnknownUpstream = fmt.Errorf("cannot determine upstream for this request: send an %s header or set server.default_upstream", HeaderTargetURL)
+
+// isAllowedTargetURL reports whether an X-Target-URL value may be proxied to.
+// An empty value is allowed (the target is auto-detected from known providers).
+func (g *Gateway) isAllowedTargetURL(targetURL string) bool {
+	if targetURL == "" {
+		return true
+	}
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil || parsedURL.Host == "" {
+		return false
+	}
+	return g.isAllowedHost(parsedURL.Host)
+}
+
+// resolveTargetURL returns the upstream URL for r: X-Target-URL joined with
+// the request path (see joinTargetPath), or the auto-detected provider URL.
+// Every consumer of the upstream URL (proxying, prewarm, summarizer, session
+// collector) goes through it.
+func (g *Gateway) resolveTargetURL(r *http.Request) (string, error) {
+	if targetURL := r.Header.Get(HeaderTargetURL); targetURL != "" {
+		return targetURLForPath(targetURL, r.URL.Path), nil
+	}
+	if targetURL := g.autoDetectTargetURL(r); targetURL != "" {
+		return targetURL, nil
+	}
+	return "", errUnknownUpstream
+}
+
 // forwardPassthrough forwards the request body unchanged to upstream.
 func (g *Gateway) forwardPassthrough(ctx context.Context, r *http.Request, body []byte) (*http.Response, forwardAuthMeta, error) {
 	authMeta := forwardAuthMeta{InitialMode: "unknown", EffectiveMode: "unknown"}
-	targetURL := r.Header.Get(HeaderTargetURL)
-	if targetURL != "" {
-		// X-Target-URL provided - append request path if not already included
-		if !strings.HasSuffix(targetURL, r.URL.Path) {
-			targetURL = strings.TrimSuffix(targetURL, "/") + r.URL.Path
-		}
-	} else {
-		targetURL = g.autoDetectTargetURL(r)
-		if targetURL == "" {
-			return nil, authMeta, fmt.Errorf("missing %s header", HeaderTargetURL)
-		}
+	targetURL, err := g.resolveTargetURL(r)
+	if err != nil {
+		return nil, authMeta, err
 	}
 
 	// Detect if this is a Bedrock request
@@ -1049,6 +1242,14 @@ func (g *Gateway) forwardPassthrough(ctx context.Context, r *http.Request, body
 				}
 			}
 
+			// Configured server.upstream_headers; client values win unless overridden
+			serverCfg := g.cfg().Server
+			for k, v := range serverCfg.UpstreamHeaders {
+				if serverCfg.OverrideUpstreamHeaders || httpReq.Header.Get(k) == "" {
+					httpReq.Header.Set(k, v)
+				}
+			}
+
 			// Sticky/triggered fallback mode: apply fallback headers from auth handler
 			if useAPIKeyMode && fallbackHeaders != nil {
 				// Clear subscription auth headers based on provider
@@ -1070,6 +1271,7 @@ func (g *Gateway) forwardPassthrough(ctx context.Context, r *http.Request, body
 			log.Error().Err(doErr).Str("targetURL", targetURL).Msg("upstream request failed")
 			return nil, nil, doErr
 		}
+		g.noteUpstreamConn(httpReq.URL.Host, resp.Close)
 
 		// Read body for upstream errors so we can inspect and preserve it.
 		if resp.StatusCode >= 400 {
@@ -1191,12 +1393,36 @@ var protectedHeaders = map[string]bool{
 	"X-Xss-Protection":          true,
 }
 
+// hopByHopHeaders apply to a single connection (RFC 9110 §7.6.1) and must not be
+// forwarded from the upstream connection to the client's.
+var hopByHopHeaders = map[string]bool{
+	"Connection":          true,
+	"Keep-Alive":          true,
+	"Proxy-Connection":    true,
+	"Proxy-Authenticate":  true,
+	"Proxy-Authorization": true,
+	"Te":                  true,
+	"Trailer":             true,
+	"Transfer-Encoding":   true,
+	"Upgrade":             true,
+}
+
 // copyHeaders copies HTTP headers from source to destination.
 // Headers listed in protectedHeaders are never copied from upstream to prevent
-// upstream responses from weakening gateway-set security policies.
+// upstream responses from weakening gateway-set security policies. Hop-by-hop
+// headers (and any named in Connection) are dropped, and upstream CORS headers
+// are ignored so the gateway's own CORS policy is what the browser sees.
 func copyHeaders(w http.ResponseWriter, src http.Header) {
+	connectionScoped := make(map[string]bool)
+	for _, v := range src.Values("Connection") {
+		for _, name := range strings.Split(v, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				connectionScoped[http.CanonicalHeaderKey(name)] = true
+			}
+		}
+	}
 	for k, v := range src {
-		if protectedHeaders[k] {
+		if protectedHeaders[k] || hopByHopHeaders[k] || connectionScoped[k] || strings.HasPrefix(k, "Access-Control-") {
 			continue
 		}
 		w.Header()[k] = v
@@ -214,11 +214,7 @@ func (g *Gateway) handleDeleteConfig(w http.ResponseWriter, r *http.Request) {
 }
 
 func buildConfigResponse(cfg *config.Config) configResponse {
-	// Determine effective webhook URL from config or env
-	webhookURL := cfg.Notifications.Slack.WebhookURL
-	if webhookURL == "" {
-		webhookURL = os.Getenv("SLACK_WEBHOOK_URL")
-	}
+	webhookURL := cfg.Notifications.Slack.EffectiveWebhookURL()
 	slackConfigured := webhookURL != ""
 	maskedWebhook := ""
 	if slackConfigured {
@@ -0,0 +1,183 @@
+// Package gateway - handler_count_tokens.go previews token cost after compression.
+//
+// POST /count-tokens takes the same body (and provider headers) as a proxied
+// request, runs it through the pipes and phantom tool injection, and returns
+// estimated input tokens before and after. Nothing is sent upstream.
+//
+// POST /v1/messages/count_tokens is Anthropic's counting endpoint: the body is
+// compressed the same way, then counted by the upstream (or locally).
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	authtypes "github.com/compresr/context-gateway/internal/auth/types"
+	phantom_tools "github.com/compresr/context-gateway/internal/phantom_tools"
+	"github.com/compresr/context-gateway/internal/tokenizer"
+)
+
+// HeaderTokenCountSource tells whether /v1/messages/count_tokens was answered
+// by the upstream ("upstream") or estimated by the gateway ("local").
+const HeaderTokenCountSource = "X-CG-Token-Count-Source"
+
+// CountTokensResponse is the JSON response for POST /count-tokens.
+type CountTokensResponse struct {
+	Provider         string  `json:"provider"`
+	Model            string  `json:"model"`
+	InputTokens      int     `json:"input_tokens"`      // as sent by the client
+	CompressedTokens int     `json:"compressed_tokens"` // as the gateway would forward it
+	TokensSaved      int     `json:"tokens_saved"`      // may be negative (injected tools)
+	CompressionRatio float64 `json:"compression_ratio"` // fraction of tokens removed
+	Compressed       bool    `json:"compressed"`        // any pipe changed the request
+	CompressedBlocks int     `json:"compressed_blocks"` // tool outputs compressed or deduplicated
+}
+
+// handleCountTokens runs a request through the pipes without forwarding it.
+func (g *Gateway) handleCountTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		g.writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := g.readRequestBody(w, r)
+	if err != nil {
+		g.writeBodyReadError(w, err)
+		return
+	}
+	if len(body) == 0 {
+		g.writeError(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if !json.Valid(body) {
+		g.writeError(w, "request body is not valid JSON", http.StatusBadRequest)
+		return
+	}
+
+	pipeCtx, forwardBody := g.compressForCount(r, body)
+	compressed := pipeCtx.OutputCompressed || pipeCtx.ToolsFiltered
+	model := pipeCtx.Model
+
+	before := tokenizer.CountBytesForModel(body, model)
+	after := tokenizer.CountBytesForModel(forwardBody, model)
+	resp := CountTokensResponse{
+		Provider:         pipeCtx.Adapter.Name(),
+		Model:            model,
+		InputTokens:      before,
+		CompressedTokens: after,
+		TokensSaved:      before - after,
+		CompressionRatio: tokenizer.CompressionRatio(before, after),
+		Compressed:       compressed,
+		CompressedBlocks: countCompressedBlocks(pipeCtx),
+	}
+
+	log.Debug().
+		Str("request_id", pipeCtx.RequestID).
+		Int("input_tokens", before).
+		Int("compressed_tokens", after).
+		Msg("count-tokens preview")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Warn().Err(err).Msg("handleCountTokens: failed to encode JSON response")
+	}
+}
+
+// handleMessagesCountTokens serves Anthropic's POST /v1/messages/count_tokens.
+// The body is compressed first so the count reflects what would be sent, then
+// forwarded to the upstream count endpoint. Without an upstream (no
+// X-Target-URL and no credentials), or when it is unreachable, tokens are
+// estimated locally and returned as {"input_tokens": N}.
+func (g *Gateway) handleMessagesCountTokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		g.writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !g.isAllowedTargetURL(r.Header.Get(HeaderTargetURL)) {
+		g.writeError(w, "target host not allowed", http.StatusForbidden)
+		return
+	}
+	if timeout := g.cfg().Server.RequestTimeout; timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	body, err := g.readRequestBody(w, r)
+	if err != nil {
+		g.writeBodyReadError(w, err)
+		return
+	}
+	if len(body) == 0 {
+		g.writeError(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if !json.Valid(body) {
+		g.writeError(w, "request body is not valid JSON", http.StatusBadRequest)
+		return
+	}
+
+	pipeCtx, forwardBody := g.compressForCount(r, body)
+
+	if hasCountUpstream(r) {
+		resp, _, err := g.forwardPassthrough(r.Context(), r, forwardBody)
+		if err == nil {
+			defer func() { _ = resp.Body.Close() }()
+			responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, MaxResponseSize))
+			copyHeaders(w, resp.Header)
+			w.Header().Set(HeaderTokenCountSource, "upstream")
+			w.WriteHeader(resp.StatusCode)
+			_, _ = w.Write(responseBody)
+			return
+		}
+		if r.Context().Err() != nil {
+			g.writeUpstreamError(w, r)
+			return
+		}
+		log.Warn().Err(err).Str("request_id", pipeCtx.RequestID).Msg("count_tokens: upstream unavailable, counting locally")
+	}
+
+	tokens := tokenizer.CountBytesForModel(forwardBody, pipeCtx.Model)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(HeaderTokenCountSource, "local")
+	if err := json.NewEncoder(w).Encode(map[string]int{"input_tokens": tokens}); err != nil {
+		log.Warn().Err(err).Msg("handleMessagesCountTokens: failed to encode JSON response")
+	}
+}
+
+// compressForCount runs body through the pipes and phantom tool injection as
+// handleProxy would, minus telemetry and forwarding. Returns the pipeline
+// context and the body that would be sent upstream.
+func (g *Gateway) compressForCount(r *http.Request, body []byte) (*PipelineContext, []byte) {
+	provider, adapter := adapters.IdentifyAndGetAdapter(g.registry, r.URL.Path, r.Header)
+	requestID := g.getRequestID(r)
+	body = g.applyModelAlias(body, requestID)
+	model := adapter.ExtractModel(body)
+
+	pipeCtx := NewPipelineContext(provider, adapter, body, r.URL.Path)
+	pipeCtx.RequestCtx = r.Context()
+	pipeCtx.RequestID = requestID
+	pipeCtx.CapturedAuth = authtypes.CaptureFromHeaders(r.Header)
+	pipeCtx.ClientAgent = detectClientAgent(r.Header)
+	pipeCtx.Model = model
+	pipeCtx.TargetModel = model
+
+	forwardBody, _, _ := g.router.ProcessAll(pipeCtx)
+	if injected, err := phantom_tools.InjectAll(forwardBody, provider); err == nil {
+		forwardBody = injected
+	}
+	return pipeCtx, forwardBody
+}
+
+// hasCountUpstream reports whether a count request can be forwarded: an
+// explicit target or credentials the upstream would accept.
+func hasCountUpstream(r *http.Request) bool {
+	return r.Header.Get(HeaderTargetURL) != "" ||
+		r.Header.Get("x-api-key") != "" ||
+		r.Header.Get("Authorization") != ""
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/compresr/context-gateway/internal/costcontrol"
 	"github.com/compresr/context-gateway/internal/dashboard"
 	"github.com/compresr/context-gateway/internal/monitoring"
+	"github.com/compresr/context-gateway/internal/notifications"
 	"github.com/compresr/context-gateway/internal/prompthistory"
 )
 
@@ -579,6 +580,20 @@ func (g *Gateway) returnBudgetExceededResponse(w http.ResponseWriter, provider s
 			sessionID, budget.CurrentCost, budget.Cap, dashboardURL)
 	}
 
+	if g.notifier != nil {
+		g.notifier.Notify(notifications.Event{
+			Type:      notifications.EventBudgetExceeded,
+			SessionID: sessionID,
+			Message:   msg,
+			Details: map[string]any{
+				"session_cost": budget.CurrentCost,
+				"session_cap":  budget.Cap,
+				"global_cost":  budget.GlobalCost,
+				"global_cap":   budget.GlobalCap,
+			},
+		})
+	}
+
 	var resp []byte
 	if provider == "anthropic" {
 		resp, _ = json.Marshal(map[string]any{
@@ -0,0 +1,179 @@
+// Package gateway - handler_debug.go exposes store diagnostics.
+//
+// GET /debug/store lists shadow IDs with sizes and TTLs (never content);
+// DELETE /debug/store flushes the store and response cache without a restart.
+// GET /expand/{id} returns one stored original for client-side "expand" UIs;
+// proxied responses carry the conversation session in X-CG-Session-ID, and the
+// caller must send it back so only shadows that session produced are served.
+// Both are only served when server.debug_endpoints is enabled, and only to
+// loopback.
+package gateway
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// HeaderSessionID carries the conversation session ID: set on proxied responses
+// that produced shadows, and required on GET /expand/{id}.
+const HeaderSessionID = "X-CG-Session-ID"
+
+// DebugStoreResponse is the JSON response for GET /debug/store.
+type DebugStoreResponse struct {
+	Count   int               `json:"count"`
+	Entries []store.EntryInfo `json:"entries"`
+}
+
+// DebugStoreClearResponse is the JSON response for DELETE /debug/store.
+type DebugStoreClearResponse struct {
+	Cleared         int `json:"cleared"`          // Store entries removed (originals, compressed, expansions, field refs)
+	CachedResponses int `json:"cached_responses"` // Response cache entries removed
+}
+
+// handleDebugStore returns a content-free snapshot of the shadow store (GET)
+// or clears it (DELETE).
+func (g *Gateway) handleDebugStore(w http.ResponseWriter, r *http.Request) {
+	if !g.cfg().Server.DebugEndpoints {
+		g.writeError(w, "not found", http.StatusNotFound)
+		return
+	}
+	if !isLoopback(r.RemoteAddr) {
+		g.writeError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodDelete:
+		g.clearDebugStore(w)
+		return
+	default:
+		g.writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	resp := DebugStoreResponse{Entries: []store.EntryInfo{}}
+	if ms, ok := g.store.(*store.MemoryStore); ok {
+		if entries := ms.Snapshot(); entries != nil {
+			resp.Entries = entries
+		}
+	}
+	resp.Count = len(resp.Entries)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Warn().Err(err).Msg("handleDebugStore: failed to encode JSON response")
+	}
+}
+
+// clearDebugStore flushes every shadow entry and cached compression, the
+// response cache and shadow ownership, so previously issued shadow IDs no
+// longer expand.
+func (g *Gateway) clearDebugStore(w http.ResponseWriter) {
+	var resp DebugStoreClearResponse
+	if ms, ok := g.store.(*store.MemoryStore); ok {
+		resp.Cleared = ms.Reset()
+	}
+	if g.responseCache != nil {
+		resp.CachedResponses = g.responseCache.Reset()
+	}
+	g.shadowOwners.reset()
+	log.Info().Int("cleared", resp.Cleared).Int("cached_responses", resp.CachedResponses).Msg("debug: store cleared")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Warn().Err(err).Msg("clearDebugStore: failed to encode JSON response")
+	}
+}
+
+// maxShadowOwners bounds shadowOwners; the oldest entries are dropped first.
+const maxShadowOwners = 10000
+
+// shadowOwners maps shadow IDs to the conversation session that produced them.
+type shadowOwners struct {
+	mu    sync.Mutex
+	owner map[string]string
+	order []string
+}
+
+func newShadowOwners() *shadowOwners {
+	return &shadowOwners{owner: make(map[string]string)}
+}
+
+// record assigns every shadow in refs to sessionID. A shadow produced again by
+// another session (identical content) moves to that session.
+func (o *shadowOwners) record(refs map[string]string, sessionID string) {
+	if o == nil || len(refs) == 0 || sessionID == "" {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for id := range refs {
+		if _, ok := o.owner[id]; !ok {
+			o.order = append(o.order, id)
+		}
+		o.owner[id] = sessionID
+	}
+	for len(o.order) > maxShadowOwners {
+		delete(o.owner, o.order[0])
+		o.order = o.order[1:]
+	}
+}
+
+// owns reports whether sessionID produced shadowID.
+func (o *shadowOwners) owns(sessionID, shadowID string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	owner, ok := o.owner[shadowID]
+	return ok && owner == sessionID
+}
+
+func (o *shadowOwners) reset() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.owner = make(map[string]string)
+	o.order = nil
+}
+
+// handleExpandByID returns the original content stored for a shadow ID. IDs
+// that are unknown, expired or owned by another session all return 404.
+func (g *Gateway) handleExpandByID(w http.ResponseWriter, r *http.Request) {
+	if !g.cfg().Server.DebugEndpoints {
+		g.writeError(w, "not found", http.StatusNotFound)
+		return
+	}
+	if !isLoopback(r.RemoteAddr) {
+		g.writeError(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if r.Method != http.MethodGet {
+		g.writeError(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/expand/")
+	if id == "" || len(id) > 64 || strings.Contains(id, "/") {
+		g.writeError(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+
+	if !g.shadowOwners.owns(r.Header.Get(HeaderSessionID), id) {
+		g.writeError(w, "not found", http.StatusNotFound)
+		return
+	}
+	data, ok := g.store.Get(id)
+	if !ok {
+		g.writeError(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]string{"id": id, "content": data}); err != nil {
+		log.Warn().Err(err).Msg("handleExpandByID: failed to encode JSON response")
+	}
+}
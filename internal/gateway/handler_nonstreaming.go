@@ -135,7 +135,8 @@ func (g *Gateway) handleNonStreaming(w http.ResponseWriter, r *http.Request, for
 		}
 
 		if expandEnabled {
-			ecHandler := NewExpandContextHandler(g.store)
+			ecHandler := NewExpandContextHandler(g.store).WithNotFoundMessage(g.cfg().Pipes.ToolOutput.ExpandNotFoundMessage).
+				WithCompressedFallback(g.cfg().Pipes.ToolOutput.ExpandUseCompressedOnMissingOriginal)
 			if g.expandLog != nil {
 				ecHandler.WithExpandLog(g.expandLog, requestID, pipeCtx.CostSessionID)
 			}
@@ -212,7 +213,7 @@ func (g *Gateway) handleNonStreaming(w http.ResponseWriter, r *http.Request, for
 			authModeInitial: authMeta.InitialMode, authModeEffective: authMeta.EffectiveMode, authFallbackUsed: authMeta.FallbackUsed,
 			requestHeaders: r.Header, responseHeaders: nil, upstreamURL: "", fallbackReason: "",
 		})
-		g.writeError(w, "upstream request failed", http.StatusBadGateway)
+		g.writeUpstreamError(w, r)
 		return
 	}
 
@@ -291,9 +292,11 @@ func (g *Gateway) handleNonStreaming(w http.ResponseWriter, r *http.Request, for
 		g.ensureSessionToolsCatalog(pipeCtx, forwardBody)
 	}
 
+	responseBody = g.runResponseHooks(responseBody, pipeCtx, result.Response.StatusCode)
+
 	// Write response — explicitly set Content-Type to prevent browser MIME sniffing (XSS mitigation).
 	copyHeaders(w, result.Response.Header)
-	addPreemptiveHeaders(w, pipeCtx.PreemptiveHeaders)
+	addResponseHeaders(w, pipeCtx.ResponseHeaders)
 	if w.Header().Get("Content-Type") == "" {
 		w.Header().Set("Content-Type", "application/json")
 	}
@@ -301,6 +304,9 @@ func (g *Gateway) handleNonStreaming(w http.ResponseWriter, r *http.Request, for
 	// Always set Content-Length from actual body (phantom loop may rewrite the body,
 	// making the upstream Content-Length header stale).
 	w.Header().Set("Content-Length", strconv.Itoa(len(responseBody)))
+	if g.responseCache != nil && pipeCtx.ResponseCacheKey != "" {
+		g.responseCache.Put(pipeCtx.ResponseCacheKey, result.Response.StatusCode, w.Header(), responseBody, g.cfg().Server.ResponseCacheTTL)
+	}
 	w.WriteHeader(result.Response.StatusCode)
 	_, _ = w.Write(responseBody) //nolint:gosec // G705: Content-Type and X-Content-Type-Options: nosniff set above
 }
@@ -52,7 +52,7 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 			requestHeaders: r.Header, responseHeaders: nil, upstreamURL: "", fallbackReason: "",
 		})
 		log.Error().Err(err).Str("request_id", requestID).Msg("upstream streaming request failed")
-		g.writeError(w, "upstream request failed", http.StatusBadGateway)
+		g.writeUpstreamError(w, r)
 		return
 	}
 
@@ -68,7 +68,7 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 	// If no buffering needed, stream directly to client
 	if !needsExpandBuffer && !toolSearchActive && !pipeCtx.PhantomToolsInjected {
 		defer func() { _ = resp.Body.Close() }()
-		writeStreamingHeaders(w, resp.Header, pipeCtx.PreemptiveHeaders)
+		writeStreamingHeaders(w, resp.Header, pipeCtx.ResponseHeaders)
 		w.WriteHeader(resp.StatusCode)
 		sseUsage, sseStopReason := g.streamResponse(w, resp.Body)
 
@@ -204,7 +204,7 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 			sseBody = jsonToOpenAISSE(capture.body.Bytes())
 		}
 
-		writeStreamingHeaders(w, capture.header, pipeCtx.PreemptiveHeaders)
+		writeStreamingHeaders(w, capture.header, pipeCtx.ResponseHeaders)
 		w.Header().Set("Content-Type", "text/event-stream")
 		w.Header().Del("Content-Length") // SSE streams have no Content-Length
 		w.WriteHeader(capture.statusCode)
@@ -238,7 +238,8 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 		}
 
 		// Use ExpandContextHandler to build tool_results (same as non-streaming path)
-		ecHandler := NewExpandContextHandler(g.store)
+		ecHandler := NewExpandContextHandler(g.store).WithNotFoundMessage(g.cfg().Pipes.ToolOutput.ExpandNotFoundMessage).
+			WithCompressedFallback(g.cfg().Pipes.ToolOutput.ExpandUseCompressedOnMissingOriginal)
 		if g.expandLog != nil {
 			ecHandler.WithExpandLog(g.expandLog, requestID, pipeCtx.CostSessionID)
 		}
@@ -247,10 +248,10 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 
 		// Build append body: original forwardBody + assistant expand_context call + tool_results
 		// This preserves KV cache — all existing messages are unchanged, we only append at the end
-		appendBody, err := buildExpandAppendBody(forwardBody, expandCalls, phantomResult.ToolResults, adapter)
+		appendBody, err := buildExpandAppendBody(forwardBody, expandCalls, streamBuffer.GetThinkingBlocks(), phantomResult.ToolResults, adapter)
 		if err != nil {
 			log.Error().Err(err).Msg("streaming: failed to build expand append body")
-			g.flushBufferedResponse(w, resp.Header, pipeCtx.PreemptiveHeaders, bufferedChunks, resp.StatusCode)
+			g.flushBufferedResponse(w, resp.Header, pipeCtx.ResponseHeaders, bufferedChunks, resp.StatusCode)
 			return
 		}
 
@@ -262,7 +263,7 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 		retryResp, retryMeta, err := g.forwardPassthrough(r.Context(), r, appendBody)
 		if err != nil {
 			log.Error().Err(err).Msg("streaming: failed to re-send after expansion")
-			g.flushBufferedResponse(w, resp.Header, pipeCtx.PreemptiveHeaders, bufferedChunks, resp.StatusCode)
+			g.flushBufferedResponse(w, resp.Header, pipeCtx.ResponseHeaders, bufferedChunks, resp.StatusCode)
 			return
 		}
 		mergeForwardAuthMeta(&authMeta, retryMeta)
@@ -270,7 +271,7 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 
 		// Stream the retry response (filter expand_context if it calls again)
 		// Also parse usage from the retry stream so we can track the full cost.
-		writeStreamingHeaders(w, retryResp.Header, pipeCtx.PreemptiveHeaders)
+		writeStreamingHeaders(w, retryResp.Header, pipeCtx.ResponseHeaders)
 		w.WriteHeader(retryResp.StatusCode)
 
 		retryUsage, retryStopReason := g.streamResponseWithFilterAndUsage(w, retryResp.Body)
@@ -338,7 +339,7 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 		return
 	} else {
 		// No expand_context detected - flush buffered response
-		g.flushBufferedResponse(w, resp.Header, pipeCtx.PreemptiveHeaders, bufferedChunks, resp.StatusCode)
+		g.flushBufferedResponse(w, resp.Header, pipeCtx.ResponseHeaders, bufferedChunks, resp.StatusCode)
 
 		// If stream was truncated, inject an SSE error event so the client knows
 		if pipeCtx.StreamTruncated {
@@ -383,9 +384,9 @@ func (g *Gateway) handleStreamingWithExpand(w http.ResponseWriter, r *http.Reque
 }
 
 // writeStreamingHeaders sets common streaming response headers.
-func writeStreamingHeaders(w http.ResponseWriter, upstream http.Header, preemptiveHeaders map[string]string) {
+func writeStreamingHeaders(w http.ResponseWriter, upstream http.Header, responseHeaders map[string]string) {
 	copyHeaders(w, upstream)
-	addPreemptiveHeaders(w, preemptiveHeaders)
+	addResponseHeaders(w, responseHeaders)
 	if w.Header().Get("Content-Type") == "" {
 		w.Header().Set("Content-Type", "text/event-stream")
 	}
@@ -396,8 +397,8 @@ func writeStreamingHeaders(w http.ResponseWriter, upstream http.Header, preempti
 }
 
 // flushBufferedResponse writes buffered chunks to the response writer.
-func (g *Gateway) flushBufferedResponse(w http.ResponseWriter, headers http.Header, preemptiveHeaders map[string]string, chunks [][]byte, statusCode int) {
-	writeStreamingHeaders(w, headers, preemptiveHeaders)
+func (g *Gateway) flushBufferedResponse(w http.ResponseWriter, headers http.Header, responseHeaders map[string]string, chunks [][]byte, statusCode int) {
+	writeStreamingHeaders(w, headers, responseHeaders)
 	w.WriteHeader(statusCode)
 
 	flusher, ok := w.(http.Flusher)
@@ -819,8 +820,27 @@ func jsonToAnthropicSSE(jsonBody []byte) []byte {
 				"type": "content_block_stop", "index": i,
 			})
 
+		case "thinking":
+			thinking, _ := blockMap["thinking"].(string)
+			signature, _ := blockMap["signature"].(string)
+			writeSSEEvent(&b, "content_block_start", map[string]any{
+				"type": "content_block_start", "index": i,
+				"content_block": map[string]any{"type": "thinking", "thinking": ""},
+			})
+			writeSSEEvent(&b, "content_block_delta", map[string]any{
+				"type": "content_block_delta", "index": i,
+				"delta": map[string]any{"type": "thinking_delta", "thinking": thinking},
+			})
+			writeSSEEvent(&b, "content_block_delta", map[string]any{
+				"type": "content_block_delta", "index": i,
+				"delta": map[string]any{"type": "signature_delta", "signature": signature},
+			})
+			writeSSEEvent(&b, "content_block_stop", map[string]any{
+				"type": "content_block_stop", "index": i,
+			})
+
 		default:
-			// Unknown block type — emit as-is
+			// Unknown block type (incl. redacted_thinking) — emit as-is
 			writeSSEEvent(&b, "content_block_start", map[string]any{
 				"type": "content_block_start", "index": i,
 				"content_block": blockMap,
@@ -1067,12 +1087,16 @@ func getIntFromMap(m map[string]any, key string) int {
 // buildExpandAppendBody appends the assistant's expand_context tool call and the
 // tool results with expanded content to the request body. Uses sjson to append
 // messages at the end, preserving the entire KV-cache prefix.
-func buildExpandAppendBody(body []byte, expandCalls []tooloutput.ExpandContextCall, toolResults []map[string]any, adapter adapters.Adapter) ([]byte, error) {
+func buildExpandAppendBody(body []byte, expandCalls []tooloutput.ExpandContextCall, thinkingBlocks []map[string]any, toolResults []map[string]any, adapter adapters.Adapter) ([]byte, error) {
 	modified := body
 
 	if adapter.Provider() == adapters.ProviderAnthropic || adapter.Provider() == adapters.ProviderBedrock {
-		// Anthropic: append assistant message with expand_context tool_use blocks
-		contentBlocks := make([]any, 0, len(expandCalls))
+		// Anthropic: append assistant message with expand_context tool_use blocks.
+		// With extended thinking the turn must start with its thinking blocks (signatures intact).
+		contentBlocks := make([]any, 0, len(thinkingBlocks)+len(expandCalls))
+		for _, tb := range thinkingBlocks {
+			contentBlocks = append(contentBlocks, tb)
+		}
 		for _, ec := range expandCalls {
 			contentBlocks = append(contentBlocks, map[string]any{
 				"type": "tool_use",
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -110,6 +111,7 @@ func (g *Gateway) recordRequestTelemetry(params telemetryParams) {
 	// Build the RequestEvent with base fields
 	event := &monitoring.RequestEvent{
 		RequestID:                params.requestID,
+		SessionID:                params.pipeCtx.CostSessionID,
 		Timestamp:                params.startTime,
 		Method:                   params.method,
 		Path:                     params.path,
@@ -151,6 +153,7 @@ func (g *Gateway) recordRequestTelemetry(params telemetryParams) {
 		HistoryCompactionTriggered: params.pipeCtx.IsCompaction,
 		ExpandPenaltyTokens:        params.expandPenaltyTokens,
 		IsMainAgent:                g.isMainConversation(params.pipeCtx.StableFingerprint),
+		PipelinePanic:              params.pipeCtx.PipelinePanic,
 	}
 
 	// Calculate cost for this request (for debugging/transparency)
@@ -771,6 +774,7 @@ func (g *Gateway) logCompressionDetails(pipeCtx *PipelineContext, requestID, pip
 
 		comparison := monitoring.CompressionComparison{
 			RequestID:         requestID,
+			SessionID:         costSessionID,
 			ProviderModel:     pipeCtx.TargetModel,
 			IsMainAgent:       isMainAgent,
 			ToolName:          tc.ToolName,
@@ -787,6 +791,9 @@ func (g *Gateway) logCompressionDetails(pipeCtx *PipelineContext, requestID, pip
 			CompressionModel:  tc.Model,
 			Query:             tc.Query,
 			QueryAgnostic:     tc.QueryAgnostic,
+			Attempts:          tc.Attempts,
+			TargetMissed:      tc.TargetMissed,
+			BudgetExhausted:   tc.BudgetExhausted,
 			EventType:         monitoring.EventTypeToolOutput,
 		}
 
@@ -799,7 +806,9 @@ func (g *Gateway) logCompressionDetails(pipeCtx *PipelineContext, requestID, pip
 		if g.tracker.CompressionLogEnabled() && !isTaskOutputTool(tc.ToolName) {
 			shouldLog := status == "compressed" || status == "cache_hit" ||
 				status == "passthrough_large" || status == "ratio_exceeded" ||
-				status == "skipped_by_config"
+				status == "skipped_by_config" || status == "passthrough_not_top_k" ||
+				status == "binary_skipped" || status == "protected" || status == "injection_wrapped" ||
+				status == "error_result" || tc.BudgetExhausted
 			if shouldLog {
 				g.tracker.LogCompressionComparison(comparison)
 			}
@@ -1053,8 +1062,8 @@ func mergeCompactedWithOriginal(compactedMessages []byte, originalBody []byte) (
 	return sjson.SetRawBytes(originalBody, "messages", []byte(rawMessages))
 }
 
-// addPreemptiveHeaders adds preemptive summarization headers to the response.
-func addPreemptiveHeaders(w http.ResponseWriter, headers map[string]string) {
+// addResponseHeaders adds gateway-generated headers (see PipelineContext.ResponseHeaders) to the response.
+func addResponseHeaders(w http.ResponseWriter, headers map[string]string) {
 	if headers == nil {
 		return
 	}
@@ -1063,6 +1072,80 @@ func addPreemptiveHeaders(w http.ResponseWriter, headers map[string]string) {
 	}
 }
 
+// countCompressedBlocks counts tool outputs and inputs that tool_output replaced
+// (compressed, served from cache, deduplicated, or input_compressed).
+func countCompressedBlocks(pipeCtx *PipelineContext) int {
+	blocks := 0
+	for _, tc := range pipeCtx.ToolOutputCompressions {
+		switch tc.MappingStatus {
+		case "compressed", "cache_hit", "deduplicated", "input_compressed":
+			blocks++
+		}
+	}
+	return blocks
+}
+
+// addCompressionHeaders records what tool_output did for this request in
+// pipeCtx.ResponseHeaders, so every response path (streaming included) sends them.
+// originalBytes is the client's request body size, sentBytes the forwarded body size.
+func addCompressionHeaders(pipeCtx *PipelineContext, originalBytes, sentBytes int) {
+	blocks := countCompressedBlocks(pipeCtx)
+
+	headers := make(map[string]string, len(pipeCtx.ResponseHeaders)+4)
+	for k, v := range pipeCtx.ResponseHeaders {
+		headers[k] = v
+	}
+	headers[HeaderCompressedBlocks] = strconv.Itoa(blocks)
+	headers[HeaderOriginalBytes] = strconv.Itoa(originalBytes)
+	headers[HeaderSentBytes] = strconv.Itoa(sentBytes)
+	headers[HeaderExpandAvailable] = strconv.FormatBool(pipeCtx.PhantomToolsInjected && len(pipeCtx.ShadowRefs) > 0)
+	pipeCtx.ResponseHeaders = headers
+}
+
+// ExplainBlock describes one tool output rewritten by tool_output, as reported
+// in the X-CG-Explain-Blocks response header.
+type ExplainBlock struct {
+	ToolCallID       string `json:"tool_call_id"`
+	ToolName         string `json:"tool_name,omitempty"`
+	Status           string `json:"status"` // compressed, cache_hit, deduplicated, input_compressed
+	ShadowID         string `json:"shadow_id,omitempty"`
+	OriginalBytes    int    `json:"original_bytes"`
+	CompressedBytes  int    `json:"compressed_bytes"` // as sent upstream, including any shadow prefix
+	OriginalTokens   int    `json:"original_tokens"`
+	CompressedTokens int    `json:"compressed_tokens"`
+}
+
+// addExplainHeader records a per-block breakdown of what tool_output replaced
+// in pipeCtx.ResponseHeaders. Blocks sent unchanged are omitted.
+func addExplainHeader(pipeCtx *PipelineContext) {
+	blocks := make([]ExplainBlock, 0)
+	for _, tc := range pipeCtx.ToolOutputCompressions {
+		switch tc.MappingStatus {
+		case "compressed", "cache_hit", "deduplicated", "input_compressed":
+		default:
+			continue
+		}
+		blocks = append(blocks, ExplainBlock{
+			ToolCallID:       tc.ToolCallID,
+			ToolName:         tc.ToolName,
+			Status:           tc.MappingStatus,
+			ShadowID:         tc.ShadowID,
+			OriginalBytes:    len(tc.OriginalContent),
+			CompressedBytes:  len(tc.CompressedContent),
+			OriginalTokens:   tc.OriginalTokens,
+			CompressedTokens: tc.CompressedTokens,
+		})
+	}
+	data, _ := json.Marshal(blocks) // plain struct slice, cannot fail
+
+	headers := make(map[string]string, len(pipeCtx.ResponseHeaders)+1)
+	for k, v := range pipeCtx.ResponseHeaders {
+		headers[k] = v
+	}
+	headers[HeaderExplainBlocks] = string(data)
+	pipeCtx.ResponseHeaders = headers
+}
+
 // countMessages counts the number of messages in a request body.
 func countMessages(body []byte) int {
 	if len(body) == 0 {
@@ -0,0 +1,176 @@
+// Package gateway - hooks.go lets embedders transform requests and responses
+// without forking.
+//
+// Hooks are registered process-wide before New, which snapshots them. The
+// compression pipes are the built-in first stage; request hooks then run in
+// registration order on the final body, just before it goes upstream. Response
+// hooks run on non-streaming responses before they reach the client (a streamed
+// response cannot be rewritten as a whole body).
+//
+// Each hook gets a freshly parsed copy of the body. A hook that leaves it
+// unchanged forwards the original bytes, so the byte-stable prefix the KV cache
+// relies on survives; a changed body is re-encoded (keys sorted, HTML not
+// escaped). A hook that returns an error or panics is logged and skipped, and
+// any changes it made are discarded.
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+)
+
+// HookRequest is the upstream request as seen by a RequestHook.
+type HookRequest struct {
+	Provider string
+	Model    string
+	Path     string
+	// Body is the parsed JSON request; mutate it in place. Numbers are json.Number.
+	Body map[string]any
+}
+
+// HookResponse is a non-streaming upstream response as seen by a ResponseHook.
+type HookResponse struct {
+	Provider   string
+	Model      string
+	StatusCode int
+	// Body is the parsed JSON response; mutate it in place. Numbers are json.Number.
+	Body map[string]any
+}
+
+// RequestHook transforms a request before it is forwarded upstream.
+type RequestHook interface {
+	Name() string
+	OnRequest(req *HookRequest) error
+}
+
+// ResponseHook transforms a response before it is returned to the client.
+type ResponseHook interface {
+	Name() string
+	OnResponse(resp *HookResponse) error
+}
+
+var hookRegistry struct {
+	mu       sync.RWMutex
+	request  []RequestHook
+	response []ResponseHook
+}
+
+// RegisterRequestHook appends a request hook. Only gateways created by a later
+// New call run it.
+func RegisterRequestHook(h RequestHook) {
+	hookRegistry.mu.Lock()
+	defer hookRegistry.mu.Unlock()
+	hookRegistry.request = append(hookRegistry.request, h)
+}
+
+// RegisterResponseHook appends a response hook. Only gateways created by a later
+// New call run it.
+func RegisterResponseHook(h ResponseHook) {
+	hookRegistry.mu.Lock()
+	defer hookRegistry.mu.Unlock()
+	hookRegistry.response = append(hookRegistry.response, h)
+}
+
+// ClearHooks removes all registered hooks. Gateways already created keep theirs.
+func ClearHooks() {
+	hookRegistry.mu.Lock()
+	defer hookRegistry.mu.Unlock()
+	hookRegistry.request = nil
+	hookRegistry.response = nil
+}
+
+// registeredHooks snapshots the registry for a new gateway.
+func registeredHooks() ([]RequestHook, []ResponseHook) {
+	hookRegistry.mu.RLock()
+	defer hookRegistry.mu.RUnlock()
+	return append([]RequestHook(nil), hookRegistry.request...),
+		append([]ResponseHook(nil), hookRegistry.response...)
+}
+
+// runRequestHooks applies the gateway's request hooks to body in order.
+func (g *Gateway) runRequestHooks(body []byte, pipeCtx *PipelineContext, path string) []byte {
+	for _, h := range g.requestHooks {
+		body = runHook(body, h.Name(), "request", func(parsed map[string]any) error {
+			return h.OnRequest(&HookRequest{
+				Provider: string(pipeCtx.Provider),
+				Model:    pipeCtx.Model,
+				Path:     path,
+				Body:     parsed,
+			})
+		})
+	}
+	return body
+}
+
+// runResponseHooks applies the gateway's response hooks to body in order.
+func (g *Gateway) runResponseHooks(body []byte, pipeCtx *PipelineContext, statusCode int) []byte {
+	for _, h := range g.responseHooks {
+		body = runHook(body, h.Name(), "response", func(parsed map[string]any) error {
+			return h.OnResponse(&HookResponse{
+				Provider:   string(pipeCtx.Provider),
+				Model:      pipeCtx.Model,
+				StatusCode: statusCode,
+				Body:       parsed,
+			})
+		})
+	}
+	return body
+}
+
+// runHook parses body, calls fn on the parsed copy and re-encodes it if fn
+// changed it. On a parse error, hook error, panic or no change the input body is
+// returned unchanged.
+func runHook(body []byte, name, kind string, fn func(map[string]any) error) (out []byte) {
+	out = body
+	defer func() {
+		if rec := recover(); rec != nil {
+			log.Error().
+				Str("hook", name).
+				Str("kind", kind).
+				Interface("panic", rec).
+				Bytes("stack", debug.Stack()).
+				Msg("hook panicked, skipping it")
+			out = body
+		}
+	}()
+
+	parsed, err := parseHookBody(body)
+	if err != nil {
+		log.Debug().Err(err).Str("hook", name).Str("kind", kind).Msg("hook skipped: body is not a JSON object")
+		return body
+	}
+	if err := fn(parsed); err != nil {
+		log.Warn().Err(err).Str("hook", name).Str("kind", kind).Msg("hook failed, skipping it")
+		return body
+	}
+	// Compare against a second parse: re-encoding an unchanged body would sort
+	// keys and rewrite escapes, breaking the cached prefix.
+	if original, err := parseHookBody(body); err == nil && reflect.DeepEqual(original, parsed) {
+		return body
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(parsed); err != nil {
+		log.Warn().Err(fmt.Errorf("encode %s body: %w", kind, err)).Str("hook", name).Msg("hook failed, skipping it")
+		return body
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func parseHookBody(body []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var parsed map[string]any
+	if err := dec.Decode(&parsed); err != nil {
+		return nil, err
+	}
+	return parsed, nil
+}
@@ -15,7 +15,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/rs/zerolog/log"
 
+	"github.com/compresr/context-gateway/internal/config"
 	"github.com/compresr/context-gateway/internal/monitoring"
+	"github.com/compresr/context-gateway/internal/notifications"
 )
 
 // responseWriter wraps http.ResponseWriter to capture the status code.
@@ -219,6 +221,13 @@ func (g *Gateway) panicRecovery(next http.Handler) http.Handler {
 
 				// Alert on panic
 				g.alerts.FlagPanic(requestID, err, stack)
+				if g.notifier != nil {
+					g.notifier.Notify(notifications.Event{
+						Type:    notifications.EventPanic,
+						Message: fmt.Sprintf("recovered panic on %s: %v", r.URL.Path, err),
+						Details: map[string]any{"request_id": requestID},
+					})
+				}
 
 				g.writeError(w, "internal error", http.StatusInternalServerError)
 			}
@@ -284,7 +293,7 @@ func (g *Gateway) security(next http.Handler) http.Handler {
 		if origin != "" && g.isAllowedOrigin(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Target-URL, X-Provider, X-Request-ID, x-api-key")
+			w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
 			w.Header().Set("Access-Control-Max-Age", "86400")
 		}
 
@@ -296,9 +305,15 @@ func (g *Gateway) security(next http.Handler) http.Handler {
 	})
 }
 
+// corsAllowedHeaders are the request headers browsers may send cross-origin,
+// covering gateway routing headers and the Anthropic/OpenAI client headers.
+const corsAllowedHeaders = "Content-Type, Authorization, X-Target-URL, X-Provider, X-Request-ID, X-CG-Explain, x-api-key, " +
+	"anthropic-version, anthropic-beta, anthropic-dangerous-direct-browser-access, OpenAI-Organization, OpenAI-Project"
+
 // isAllowedOrigin checks if origin is permitted for CORS.
 // Uses URL parsing to prevent bypass via http://localhost.evil.com style origins.
-// Accepts both http:// and https:// schemes for localhost origins.
+// Accepts both http:// and https:// schemes for localhost origins, plus any
+// origin listed in server.cors_allowed_origins.
 func (g *Gateway) isAllowedOrigin(origin string) bool {
 	u, err := url.Parse(origin)
 	if err != nil {
@@ -308,7 +323,10 @@ func (g *Gateway) isAllowedOrigin(origin string) bool {
 		return false
 	}
 	host := u.Hostname() // strips port number
-	return host == "localhost" || host == "127.0.0.1"
+	if host == "localhost" || host == "127.0.0.1" {
+		return true
+	}
+	return config.OriginAllowed(origin, g.cfg().Server.CORSAllowedOrigins)
 }
 
 // getClientIP extracts the client IP address from the request.
@@ -376,11 +394,26 @@ func (g *Gateway) isAllowedHost(host string) bool {
 		return false
 	}
 
+	// Configured denylist takes precedence over every allow rule
+	serverCfg := g.cfg().Server
+	for _, entry := range serverCfg.DeniedUpstreamHosts {
+		if config.HostMatchesEntry(host, entry) {
+			return false
+		}
+	}
+
 	// Check static allowlist first
 	if allowedHosts[host] {
 		return true
 	}
 
+	// Then configured allowlist
+	for _, entry := range serverCfg.AllowedUpstreamHosts {
+		if config.HostMatchesEntry(host, entry) {
+			return true
+		}
+	}
+
 	// Check suffix patterns for cloud providers with regional subdomains.
 	// Vertex AI: us-central1-aiplatform.googleapis.com, europe-west1-aiplatform.googleapis.com
 	// AWS Bedrock: specific hosts registered via registerBedrockHosts()
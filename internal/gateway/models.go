@@ -7,10 +7,33 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
 
 	"github.com/compresr/context-gateway/internal/costcontrol"
 )
 
+// applyModelAlias rewrites the request's top-level "model" field when it names
+// an alias in server.model_aliases. Returns body unchanged otherwise.
+func (g *Gateway) applyModelAlias(body []byte, requestID string) []byte {
+	aliases := g.cfg().Server.ModelAliases
+	if len(aliases) == 0 {
+		return body
+	}
+	alias := gjson.GetBytes(body, "model").String()
+	concrete, ok := aliases[alias]
+	if !ok {
+		return body
+	}
+	rewritten, err := sjson.SetBytes(body, "model", concrete)
+	if err != nil {
+		log.Warn().Err(err).Str("request_id", requestID).Str("alias", alias).Msg("failed to rewrite model alias")
+		return body
+	}
+	log.Debug().Str("request_id", requestID).Str("alias", alias).Str("model", concrete).Msg("rewrote model alias")
+	return rewritten
+}
+
 // modelObject represents a single model in the OpenAI-compatible /v1/models response.
 type modelObject struct {
 	ID      string `json:"id"`
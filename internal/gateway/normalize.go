@@ -0,0 +1,115 @@
+// Upstream body checks.
+//
+// A compressed tool result carries a [REF:id] line; it only means something
+// inside that tool result. A client that replays one into a system prompt or
+// an assistant turn (e.g. a bad history rewrite) leaks it upstream, where the
+// model can do nothing with it. stripLeakedShadowRefs removes those the
+// gateway itself emitted — their ID is in the shadow store — and counts every
+// one found so the gateway can warn. Anything else in system and assistant
+// text belongs to the client and the model and is left alone (an assistant
+// turn may legitimately hold a text-mode <<<EXPAND:id>>> request).
+package gateway
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// leakedRefRE matches a [REF:id] shadow reference as tool_output emits it,
+// with the newline that ends its line.
+var leakedRefRE = regexp.MustCompile(`\[REF:([^\[\]\s]+)\]\n?`)
+
+// nonToolRoles are message roles whose text is written by the client or the
+// model, never by the gateway's compression.
+var nonToolRoles = map[string]bool{
+	"system":    true,
+	"developer": true,
+	"assistant": true,
+	"model":     true, // Gemini
+}
+
+// stripLeakedShadowRefs finds [REF:id] shadow references in system and
+// assistant text fields (Anthropic, OpenAI Chat/Responses and Gemini formats)
+// and removes those for which known reports true, with the rest of their line
+// break. Tool results are not inspected. It returns the body, the number of
+// references found and the number removed; on a rewrite failure the body is
+// returned unchanged with nothing removed.
+func stripLeakedShadowRefs(body []byte, known func(id string) bool) ([]byte, int, int) {
+	if !bytes.Contains(body, []byte("[REF:")) {
+		return body, 0, 0
+	}
+	out := body
+	found, stripped := 0, 0
+	for _, path := range nonToolTextPaths(body) {
+		text := gjson.GetBytes(body, path).String()
+		removed := 0
+		cleaned := leakedRefRE.ReplaceAllStringFunc(text, func(ref string) string {
+			found++
+			if !known(leakedRefRE.FindStringSubmatch(ref)[1]) {
+				return ref
+			}
+			removed++
+			return ""
+		})
+		if removed == 0 {
+			continue
+		}
+		var err error
+		if out, err = sjson.SetBytes(out, path, cleaned); err != nil {
+			return body, found, 0
+		}
+		stripped += removed
+	}
+	return out, found, stripped
+}
+
+// nonToolTextPaths lists the gjson paths of every string field holding system
+// or assistant text.
+func nonToolTextPaths(body []byte) []string {
+	var paths []string
+	addContent := func(prefix string, content gjson.Result) {
+		switch {
+		case content.Type == gjson.String:
+			paths = append(paths, prefix)
+		case content.IsArray():
+			for i, block := range content.Array() {
+				if block.Get("text").Type == gjson.String {
+					paths = append(paths, fmt.Sprintf("%s.%d.text", prefix, i))
+				}
+			}
+		}
+	}
+
+	// Anthropic top-level system; Responses API instructions
+	addContent("system", gjson.GetBytes(body, "system"))
+	addContent("instructions", gjson.GetBytes(body, "instructions"))
+
+	// Anthropic / OpenAI Chat messages and Responses API input items
+	for _, field := range []string{"messages", "input"} {
+		items := gjson.GetBytes(body, field)
+		if !items.IsArray() {
+			continue
+		}
+		for i, msg := range items.Array() {
+			if nonToolRoles[msg.Get("role").String()] {
+				addContent(fmt.Sprintf("%s.%d.content", field, i), msg.Get("content"))
+			}
+		}
+	}
+
+	// Gemini systemInstruction and model turns
+	addContent("systemInstruction.parts", gjson.GetBytes(body, "systemInstruction.parts"))
+	if contents := gjson.GetBytes(body, "contents"); contents.IsArray() {
+		for i, msg := range contents.Array() {
+			if nonToolRoles[msg.Get("role").String()] {
+				addContent(fmt.Sprintf("contents.%d.parts", i), msg.Get("parts"))
+			}
+		}
+	}
+
+	return paths
+}
@@ -223,12 +223,24 @@ func (p *PhantomLoop) Run(
 		}
 
 		// Append assistant response and tool results
+		bodyBeforeAppend := currentBody
 		currentBody, err = adapter.AppendMessages(currentBody, responseBody, allToolResults)
 		if err != nil {
 			log.Error().Err(err).Msg("phantom_loop: failed to append messages")
 			break
 		}
 
+		// Refuse a rewrite that breaks tool_use/tool_result pairing — the provider would
+		// reject it. Happens when the model mixes phantom and real tool calls (the real
+		// ones have no result yet) or expands via text patterns (no tool_use to answer).
+		// The client gets this response with phantom calls stripped instead.
+		if pairErr := adapters.ValidateToolPairing(currentBody); pairErr != nil && adapters.ValidateToolPairing(bodyBeforeAppend) == nil {
+			log.Warn().Err(pairErr).Int("loop", result.LoopCount).
+				Msg("phantom_loop: rewrite would break tool pairing, returning response to client")
+			result.ResponseBody = p.filterPhantomCalls(responseBody, adapter)
+			break
+		}
+
 		// Apply request modifiers (e.g., add tools to tools array)
 		// Save backup so we can revert if a modifier corrupts the body
 		bodyBeforeModifiers := currentBody
@@ -296,6 +308,19 @@ func (p *PhantomLoop) parsePhantomCalls(responseBody []byte, adapter adapters.Ad
 	return calls
 }
 
+// filterPhantomCalls strips every named phantom tool call from a response.
+func (p *PhantomLoop) filterPhantomCalls(responseBody []byte, adapter adapters.Adapter) []byte {
+	for _, h := range p.handlers {
+		if h.Name() == "" {
+			continue // catch-all handler: intercepts real tool names, nothing to strip
+		}
+		if filtered, ok := adapter.FilterToolCallFromResponse(responseBody, h.Name()); ok {
+			responseBody = filtered
+		}
+	}
+	return responseBody
+}
+
 // filterCallsByName filters calls for a specific tool name.
 func (p *PhantomLoop) filterCallsByName(calls []PhantomToolCall, name string) []PhantomToolCall {
 	var filtered []PhantomToolCall
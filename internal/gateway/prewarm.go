@@ -0,0 +1,74 @@
+// Package gateway - prewarm.go overlaps upstream connection setup with compression.
+//
+// With server.pipeline_compression, a large request's upstream connection
+// (TCP, TLS, HTTP/2 settings) is opened while its tool outputs are compressed.
+// The connection goes back to the transport's idle pool and the forwarded
+// request picks it up. Nothing from the request is sent early: the warm-up is a
+// bare HEAD to the host root carrying only server.upstream_headers (e.g. proxy
+// auth), without the body or client credentials.
+//
+// Hosts with a kept-alive connection used within prewarmWarmWindow are skipped:
+// the pool likely holds an idle connection already, and a HEAD could take it
+// away from the forwarded request.
+package gateway
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// pipelineCompressionMinBytes is the request size from which compression is
+// slow enough for connection setup to be worth overlapping.
+const pipelineCompressionMinBytes = 32 * 1024
+
+// prewarmWarmWindow is how long after a kept-alive request a host is assumed
+// to still have an idle pooled connection (below the 90s IdleConnTimeout).
+const prewarmWarmWindow = 30 * time.Second
+
+// prewarmUpstream opens a connection to targetURL's host. Failures are only
+// logged; the forwarded request then dials as usual.
+func (g *Gateway) prewarmUpstream(ctx context.Context, targetURL, requestID string) {
+	parsed, err := url.Parse(targetURL)
+	if err != nil || parsed.Host == "" || !g.isAllowedHost(parsed.Host) {
+		return
+	}
+	if last, ok := g.upstreamLastUsed.Load(parsed.Host); ok && time.Since(last.(time.Time)) < prewarmWarmWindow {
+		return
+	}
+	origin := &url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: "/"}
+
+	ctx, cancel := context.WithTimeout(ctx, g.cfg().Server.EffectiveUpstreamTimeouts().ConnectLimit())
+	defer cancel()
+	// #nosec G704 -- host passed isAllowedHost, same as the forwarded request
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, origin.String(), nil)
+	if err != nil {
+		return
+	}
+	for k, v := range g.cfg().Server.UpstreamHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := g.httpClient.Do(req)
+	if err != nil {
+		log.Debug().Err(err).Str("request_id", requestID).Str("host", parsed.Host).Msg("upstream prewarm failed")
+		return
+	}
+	// Drain so the connection is returned to the idle pool.
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	g.noteUpstreamConn(parsed.Host, resp.Close)
+}
+
+// noteUpstreamConn records that a request to host just completed. closed
+// (Connection: close) means no connection was kept, so the host is cold again.
+func (g *Gateway) noteUpstreamConn(host string, closed bool) {
+	if closed {
+		g.upstreamLastUsed.Delete(host)
+		return
+	}
+	g.upstreamLastUsed.Store(host, time.Now())
+}
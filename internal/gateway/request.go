@@ -2,11 +2,46 @@
 package gateway
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"regexp"
 	"slices"
 	"strings"
 )
 
+// readRequestBody buffers the whole client body so pipes always see complete
+// JSON, however it was framed (Content-Length or chunked). Bodies larger than
+// server.max_request_bytes fail with *http.MaxBytesError.
+func (g *Gateway) readRequestBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	limit := g.cfg().Server.EffectiveMaxRequestBytes()
+	if r.ContentLength > limit {
+		return nil, &http.MaxBytesError{Limit: limit}
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	var buf bytes.Buffer
+	if r.ContentLength > 0 {
+		buf.Grow(int(r.ContentLength))
+	}
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBodyReadError answers a readRequestBody failure: 413 past the size
+// limit, 400 for anything else (e.g. the client aborting mid-upload).
+func (g *Gateway) writeBodyReadError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		g.writeError(w, fmt.Sprintf("request body too large (max %d bytes)", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	g.writeError(w, "failed to read request", http.StatusBadRequest)
+}
+
 // normalizeOpenAIPath ensures paths are in /v1/... format for OpenAI API.
 // Handles cases where clients send /responses instead of /v1/responses.
 func normalizeOpenAIPath(path string) string {
@@ -45,58 +80,167 @@ func isChatGPTSubscription(r *http.Request) bool {
 	return !strings.HasPrefix(token, "sk-")
 }
 
+// knownEndpoints are upstream endpoint paths a configured URL may already end
+// with. Such a URL is used as-is, except that sub-resources of the endpoint
+// (/v1/messages/count_tokens) are appended to it.
+var knownEndpoints = []string{
+	"/v1/messages",
+	"/v1/chat/completions",
+	"/v1/completions",
+	"/v1/responses",
+	"/chat/completions",
+	"/responses",
+}
+
+// joinTargetPath resolves an upstream URL (X-Target-URL or
+// server.default_upstream) for a request path:
+//   - a bare host ("https://api.anthropic.com") gets the path appended;
+//   - a URL already ending with the path is used as-is;
+//   - a URL ending with a known endpoint is used as-is, with endpoint
+//     sub-resources (count_tokens) appended;
+//   - any other path is a prefix and is joined, without repeating a segment
+//     both share ("https://openrouter.ai/api/v1" + "/v1/messages" →
+//     "https://openrouter.ai/api/v1/messages").
+func joinTargetPath(base, path string) string {
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" {
+		if strings.HasSuffix(base, path) {
+			return base
+		}
+		return strings.TrimSuffix(base, "/") + path
+	}
+	u.Path = joinURLPath(u.Path, path)
+	u.RawPath = ""
+	return u.String()
+}
+
+// joinURLPath applies the joinTargetPath rules to the path components.
+func joinURLPath(basePath, path string) string {
+	basePath = strings.TrimSuffix(basePath, "/")
+	if basePath == "" {
+		return path
+	}
+	if strings.HasSuffix(basePath, path) {
+		return basePath
+	}
+	endpoint := false
+	for _, ep := range knownEndpoints {
+		if !strings.HasSuffix(basePath, ep) {
+			continue
+		}
+		endpoint = true
+		if rest, ok := strings.CutPrefix(path, ep); ok && strings.HasPrefix(rest, "/") {
+			return basePath + rest
+		}
+	}
+	if endpoint {
+		return basePath
+	}
+	// Drop the longest trailing run of base segments the path starts with.
+	for i := 0; i < len(basePath); i++ {
+		if basePath[i] == '/' && strings.HasPrefix(path, basePath[i:]+"/") {
+			return basePath[:i] + path
+		}
+	}
+	return basePath + path
+}
+
+// targetURLForPath resolves an X-Target-URL value for a request path; see
+// joinTargetPath.
+func targetURLForPath(target, path string) string {
+	return joinTargetPath(target, path)
+}
+
+// vertexRegionRE matches a Google Cloud region; anything else in the path must
+// not end up in the upstream host name.
+var vertexRegionRE = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// vertexBaseURL returns the Vertex AI host for the region in a request path
+// (/v1/projects/{project}/locations/{region}/...). The "global" location uses
+// the non-regional host; paths without a valid location default to us-central1.
+func vertexBaseURL(path string) string {
+	const marker = "/locations/"
+	region := "us-central1"
+	if i := strings.Index(path, marker); i >= 0 {
+		region = path[i+len(marker):]
+		if j := strings.IndexByte(region, '/'); j >= 0 {
+			region = region[:j]
+		}
+	}
+	switch {
+	case !vertexRegionRE.MatchString(region):
+		region = "us-central1"
+	case region == "global":
+		return "https://aiplatform.googleapis.com"
+	}
+	return "https://" + region + "-aiplatform.googleapis.com"
+}
+
 // autoDetectTargetURL determines the upstream URL based on request characteristics.
+// A configured server.default_upstream takes precedence over inference, except
+// for Bedrock and ChatGPT subscription requests: those need their own upstream
+// (SigV4 signing, chatgpt.com backend) whatever the default is.
 func (g *Gateway) autoDetectTargetURL(r *http.Request) string {
+	inferred, providerBound := g.inferTargetURL(r)
+	if base := g.cfg().Server.DefaultUpstream; base != "" && !providerBound {
+		return joinTargetPath(base, r.URL.Path)
+	}
+	return inferred
+}
+
+// inferTargetURL infers the upstream URL from headers and path. providerBound
+// reports a Bedrock or ChatGPT subscription upstream.
+func (g *Gateway) inferTargetURL(r *http.Request) (target string, providerBound bool) {
 	path := r.URL.Path
 
 	// 0. Bedrock: path-based detection (/model/xxx/invoke or /model/xxx/converse)
 	if g.isBedrockRequest(path) && g.bedrockSigner != nil && g.bedrockSigner.IsConfigured() {
-		return g.bedrockSigner.BuildTargetURL(path)
+		return g.bedrockSigner.BuildTargetURL(path), true
 	}
 
 	// 1. Anthropic: anthropic-version header is definitive
 	if r.Header.Get("anthropic-version") != "" {
-		return getProviderBaseURL("anthropic") + path
+		return getProviderBaseURL("anthropic") + path, false
 	}
 
 	// 2. Check x-api-key for Anthropic pattern (sk-ant-)
 	if strings.HasPrefix(r.Header.Get("x-api-key"), "sk-ant-") {
-		return getProviderBaseURL("anthropic") + path
+		return getProviderBaseURL("anthropic") + path, false
 	}
 
 	// 3. Gemini: x-goog-api-key header is definitive
 	if r.Header.Get("x-goog-api-key") != "" {
-		return getProviderBaseURL("gemini") + path
+		return getProviderBaseURL("gemini") + path, false
 	}
 
 	// 4. Vertex AI: path contains aiplatform.googleapis.com (uses OAuth, not API keys)
 	if strings.Contains(path, "aiplatform.googleapis.com") ||
 		strings.Contains(path, "/publishers/google/models/") {
 		// Vertex AI uses regional endpoints, extract from path or use default
-		return "https://us-central1-aiplatform.googleapis.com" + path
+		return vertexBaseURL(path) + path, false
 	}
 
 	// 5. Check Authorization header - distinguish providers by API key prefix
 	if auth := r.Header.Get("Authorization"); auth != "" {
 		// Anthropic: Bearer sk-ant-xxx
 		if strings.HasPrefix(auth, "Bearer sk-ant-") {
-			return getProviderBaseURL("anthropic") + path
+			return getProviderBaseURL("anthropic") + path, false
 		}
 		// OpenRouter: Bearer sk-or-xxx
 		if strings.HasPrefix(auth, "Bearer sk-or-") {
 			path = normalizeOpenAIPath(path)
-			return getProviderBaseURL("openrouter") + path
+			return getProviderBaseURL("openrouter") + path, false
 		}
 		// OpenAI: Bearer sk-xxx (but not sk-ant- or sk-or-)
 		// Always route API keys to api.openai.com regardless of OPENAI_PROVIDER_URL
 		if strings.HasPrefix(auth, "Bearer sk-") {
 			path = normalizeOpenAIPath(path)
-			return "https://api.openai.com" + path
+			return "https://api.openai.com" + path, false
 		}
 		// ChatGPT subscription: Bearer token without sk- prefix
 		// Always route subscription tokens to chatgpt.com regardless of OPENAI_PROVIDER_URL
 		if isChatGPTSubscription(r) {
-			return "https://chatgpt.com/backend-api" + normalizeChatGPTPath(path)
+			return "https://chatgpt.com/backend-api" + normalizeChatGPTPath(path), true
 		}
 	}
 
@@ -107,11 +251,11 @@ func (g *Gateway) autoDetectTargetURL(r *http.Request) string {
 			auth := r.Header.Get("Authorization")
 			// API key: route to api.openai.com
 			if strings.HasPrefix(auth, "Bearer sk-") {
-				return "https://api.openai.com" + normalizeOpenAIPath(path)
+				return "https://api.openai.com" + normalizeOpenAIPath(path), false
 			}
 			// Subscription token: route to chatgpt.com
 			if isChatGPTSubscription(r) {
-				return "https://chatgpt.com/backend-api" + normalizeChatGPTPath(path)
+				return "https://chatgpt.com/backend-api" + normalizeChatGPTPath(path), true
 			}
 		}
 		// Normalize path for OpenAI and OpenRouter (API key auth)
@@ -119,10 +263,10 @@ func (g *Gateway) autoDetectTargetURL(r *http.Request) string {
 		if provider.Name == "openai" || provider.Name == "openrouter" {
 			normalizedPath = normalizeOpenAIPath(path)
 		}
-		return provider.BaseURL + normalizedPath
+		return provider.BaseURL + normalizedPath, false
 	}
 
-	return ""
+	return "", false
 }
 
 // isNonLLMEndpoint returns true for paths that shouldn't be processed as LLM requests.
@@ -0,0 +1,149 @@
+// Response cache for exact repeats of non-streaming requests (server.response_cache_ttl).
+package gateway
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxResponseCacheEntries bounds memory; new responses are not cached while full.
+	maxResponseCacheEntries = 256
+
+	// HeaderResponseCache is set to "hit" on responses replayed from the cache.
+	HeaderResponseCache = "X-CG-Response-Cache"
+)
+
+// cachedResponse is a successful upstream response as written to the client.
+type cachedResponse struct {
+	statusCode int
+	header     http.Header
+	body       []byte
+	expiresAt  time.Time
+}
+
+// responseCache maps request keys to recent successful responses.
+type responseCache struct {
+	mu      sync.RWMutex
+	entries map[string]*cachedResponse
+	stopCh  chan struct{}
+}
+
+func newResponseCache() *responseCache {
+	c := &responseCache{
+		entries: make(map[string]*cachedResponse),
+		stopCh:  make(chan struct{}),
+	}
+	go c.cleanupLoop()
+	return c
+}
+
+// responseCacheKey hashes everything that selects the upstream response: path,
+// target URL, credentials and the whitespace-normalized body. Credentials are
+// part of the key so one client's response is never replayed to another.
+func responseCacheKey(r *http.Request, body []byte) string {
+	h := sha256.New()
+	for _, part := range []string{
+		r.URL.Path,
+		r.Header.Get(HeaderTargetURL),
+		r.Header.Get("x-api-key"),
+		r.Header.Get("Authorization"),
+		r.Header.Get("x-goog-api-key"),
+	} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err == nil {
+		h.Write(compact.Bytes())
+	} else {
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Get returns the cached response for key if it has not expired.
+func (c *responseCache) Get(key string) (*cachedResponse, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+		return nil, false
+	}
+	return entry, true
+}
+
+// Put caches a 2xx response for ttl. Other statuses are ignored.
+func (c *responseCache) Put(key string, statusCode int, header http.Header, body []byte, ttl time.Duration) {
+	if key == "" || ttl <= 0 || statusCode < 200 || statusCode >= 300 {
+		return
+	}
+	entry := &cachedResponse{
+		statusCode: statusCode,
+		header:     header.Clone(),
+		body:       append([]byte(nil), body...),
+		expiresAt:  time.Now().Add(ttl),
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxResponseCacheEntries {
+		c.removeExpiredLocked()
+		if len(c.entries) >= maxResponseCacheEntries {
+			return
+		}
+	}
+	c.entries[key] = entry
+}
+
+func (c *responseCache) cleanupLoop() {
+	ticker := time.NewTicker(DefaultCleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.stopCh:
+			return
+		case <-ticker.C:
+			c.mu.Lock()
+			c.removeExpiredLocked()
+			c.mu.Unlock()
+		}
+	}
+}
+
+func (c *responseCache) removeExpiredLocked() {
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Reset drops all cached responses and returns how many there were.
+func (c *responseCache) Reset() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.entries)
+	c.entries = make(map[string]*cachedResponse)
+	return n
+}
+
+// Stop stops the cleanup goroutine. Safe to call multiple times.
+func (c *responseCache) Stop() {
+	select {
+	case <-c.stopCh:
+	default:
+		close(c.stopCh)
+	}
+}
@@ -3,6 +3,8 @@ package gateway
 
 import (
 	"fmt"
+	"runtime/debug"
+	"strings"
 	"sync"
 
 	"github.com/rs/zerolog/log"
@@ -165,6 +167,15 @@ func (r *Router) RouteFlags(ctx *PipelineContext, cfg *config.Config) RouteResul
 	// Check for tool outputs.
 	result.ToolOutput = cfg.Pipes.ToolOutput.Enabled && len(toolOutputs) > 0
 
+	// Blank tool results are not extracted above; they still need the pipe when
+	// empty_output_placeholder is set.
+	if !result.ToolOutput && cfg.Pipes.ToolOutput.Enabled && cfg.Pipes.ToolOutput.EmptyOutputPlaceholder != "" {
+		if locator, ok := ctx.Adapter.(adapters.EmptyToolOutputAdapter); ok {
+			empty, _ := locator.ExtractEmptyToolOutputs(ctx.OriginalRequest)
+			result.ToolOutput = len(empty) > 0
+		}
+	}
+
 	// Check for tool discovery
 	if cfg.Pipes.ToolDiscovery.Enabled {
 		contents, err := ctx.Adapter.ExtractToolDiscovery(ctx.OriginalRequest, nil)
@@ -190,7 +201,58 @@ func (r *Router) RouteFlags(ctx *PipelineContext, cfg *config.Config) RouteResul
 //
 // tool_output (messages[]) and tool_discovery (tools[]) modify non-overlapping JSON
 // paths so they can run concurrently. Results are merged via sjson.
-func (r *Router) ProcessAll(ctx *PipelineContext) ([]byte, RouteResult, error) {
+//
+// Pipes only own the conversation and tool arrays; every other top-level field
+// (sampling params, stop sequences, metadata, unknown fields) is restored
+// byte-for-byte from the incoming body afterwards.
+//
+// A panic anywhere in the pipeline is recovered: the stack is logged, the pipe
+// is recorded in ctx.PipelinePanic for telemetry, and the original request is
+// forwarded unmodified so one bad input cannot take down the gateway.
+func (r *Router) ProcessAll(ctx *PipelineContext) (body []byte, flags RouteResult, err error) {
+	input := ctx.OriginalRequest
+	defer func() {
+		if rec := recover(); rec != nil {
+			recordPipePanic(ctx, "router", rec, debug.Stack())
+		}
+		if ctx.PipelinePanic != "" {
+			resetPipeState(ctx, input)
+			body, err = input, nil
+		}
+	}()
+	body, flags, err = r.processPipes(ctx)
+	return preserveTopLevelFields(input, body), flags, err
+}
+
+// recordPipePanic logs a recovered pipeline panic with its stack and marks the
+// request so telemetry reports it. The first panic wins.
+func recordPipePanic(ctx *PipelineContext, pipe string, rec any, stack []byte) {
+	log.Error().
+		Str("pipe", pipe).
+		Str("request_id", ctx.RequestID).
+		Interface("panic", rec).
+		Bytes("stack", stack).
+		Msg("pipe panicked, forwarding original request")
+	if ctx.PipelinePanic == "" {
+		ctx.PipelinePanic = fmt.Sprintf("%s: %v", pipe, rec)
+	}
+}
+
+// resetPipeState discards what pipes recorded on ctx before a panic. The
+// original request is forwarded, so nothing was compressed or filtered and no
+// shadow reference is expandable.
+func resetPipeState(ctx *PipelineContext, original []byte) {
+	ctx.OriginalRequest = original
+	ctx.ShadowRefs = make(map[string]string)
+	ctx.ToolOutputCompressions = nil
+	ctx.TaskOutputCompressions = nil
+	ctx.TaskOutputHandledIDs = nil
+	ctx.OutputCompressed = false
+	ctx.ToolsFiltered = false
+	ctx.DeferredTools = nil
+}
+
+func (r *Router) processPipes(ctx *PipelineContext) ([]byte, RouteResult, error) {
 	// Take a consistent snapshot so config changes mid-request don't produce torn reads.
 	cfg, taPool, toPool, tdPool := r.snapshot()
 
@@ -212,7 +274,13 @@ func (r *Router) ProcessAll(ctx *PipelineContext) ([]byte, RouteResult, error) {
 		body = r.runPipe(taPool, ctx, body, "task_output")
 	}
 
-	runTO := flags.ToolOutput && cfg.Pipes.ToolOutput.Strategy != config.StrategyPassthrough
+	// empty_output_placeholder, detect_injection and dedupe_tool_use_ids are local
+	// rewrites that apply even in passthrough.
+	runTO := flags.ToolOutput &&
+		(cfg.Pipes.ToolOutput.Strategy != config.StrategyPassthrough ||
+			cfg.Pipes.ToolOutput.EmptyOutputPlaceholder != "" ||
+			cfg.Pipes.ToolOutput.DedupeToolUseIDs ||
+			cfg.Pipes.ToolOutput.DetectInjection != "")
 	runTD := flags.ToolDiscovery && cfg.Pipes.ToolDiscovery.Strategy != config.StrategyPassthrough
 
 	// Fast path: only one pipe active — no parallelization overhead
@@ -230,9 +298,11 @@ func (r *Router) ProcessAll(ctx *PipelineContext) ([]byte, RouteResult, error) {
 	// They modify non-overlapping JSON paths (messages[] vs tools[])
 	// and non-overlapping PipeContext fields.
 	var (
-		toBody, tdBody []byte
-		toErr, tdErr   error
-		wg             sync.WaitGroup
+		toBody, tdBody   []byte
+		toErr, tdErr     error
+		toPanic, tdPanic any
+		toStack, tdStack []byte
+		wg               sync.WaitGroup
 	)
 
 	// Deep clone PipeContext for tool_discovery to prevent data races.
@@ -257,7 +327,7 @@ func (r *Router) ProcessAll(ctx *PipelineContext) ([]byte, RouteResult, error) {
 		defer func() {
 			if r := recover(); r != nil {
 				toErr = fmt.Errorf("tool_output panic: %v", r)
-				log.Error().Interface("panic", r).Msg("tool_output pipe panicked")
+				toPanic, toStack = r, debug.Stack()
 			}
 		}()
 		ctx.OriginalRequest = body
@@ -270,13 +340,21 @@ func (r *Router) ProcessAll(ctx *PipelineContext) ([]byte, RouteResult, error) {
 		defer func() {
 			if r := recover(); r != nil {
 				tdErr = fmt.Errorf("tool_discovery panic: %v", r)
-				log.Error().Interface("panic", r).Msg("tool_discovery pipe panicked")
+				tdPanic, tdStack = r, debug.Stack()
 			}
 		}()
 		tdBody, tdErr = worker.Process(&tdCtx)
 	}()
 	wg.Wait()
 
+	// Recorded after Wait: both goroutines share ctx.
+	if toPanic != nil {
+		recordPipePanic(ctx, "tool_output", toPanic, toStack)
+	}
+	if tdPanic != nil {
+		recordPipePanic(ctx, "tool_discovery", tdPanic, tdStack)
+	}
+
 	// Merge tool_discovery metrics back into main context
 	ctx.ToolsFiltered = tdCtx.ToolsFiltered
 	ctx.DeferredTools = tdCtx.DeferredTools
@@ -297,7 +375,7 @@ func (r *Router) runPipe(pool *Pool, ctx *PipelineContext, body []byte, name str
 	defer pool.release(worker) // Release even on panic
 	defer func() {
 		if r := recover(); r != nil {
-			log.Error().Interface("panic", r).Str("pipe", name).Msg("pipe panicked, using original body")
+			recordPipePanic(ctx, name, r, debug.Stack())
 			result = body
 		}
 	}()
@@ -348,3 +426,69 @@ func mergeParallelResults(original, toBody []byte, toErr error, tdBody []byte, t
 	}
 	return result
 }
+
+// pipeOwnedFields are the top-level request fields pipes may rewrite: the
+// conversation (messages, Responses API input, Gemini contents) and tools.
+var pipeOwnedFields = map[string]bool{
+	"messages": true,
+	"input":    true,
+	"contents": true,
+	"tools":    true,
+}
+
+// preserveTopLevelFields restores every top-level field of original that is not
+// pipe-owned and was changed or dropped in rewritten, and removes non-owned
+// fields a pipe added. This guards generation parameters (temperature, top_p,
+// stop_sequences, max_tokens, ...) and fields the gateway doesn't know about
+// against loss in an unmarshal/marshal round-trip.
+func preserveTopLevelFields(original, rewritten []byte) []byte {
+	if len(rewritten) == 0 || string(original) == string(rewritten) {
+		return rewritten
+	}
+	orig := gjson.ParseBytes(original)
+	if !orig.IsObject() || !gjson.ValidBytes(rewritten) {
+		return rewritten
+	}
+
+	result := rewritten
+	restore := func(key, raw string) {
+		if updated, err := sjson.SetRawBytes(result, escapeFieldPath(key), []byte(raw)); err == nil {
+			result = updated
+		}
+	}
+	orig.ForEach(func(key, value gjson.Result) bool {
+		name := key.String()
+		if pipeOwnedFields[name] {
+			return true
+		}
+		if got := gjson.GetBytes(result, escapeFieldPath(name)); !got.Exists() || got.Raw != value.Raw {
+			log.Warn().Str("field", name).Msg("pipeline altered top-level field, restoring original")
+			restore(name, value.Raw)
+		}
+		return true
+	})
+	gjson.ParseBytes(result).ForEach(func(key, _ gjson.Result) bool {
+		name := key.String()
+		if !pipeOwnedFields[name] && !orig.Get(escapeFieldPath(name)).Exists() {
+			log.Warn().Str("field", name).Msg("pipeline added top-level field, removing")
+			if updated, err := sjson.DeleteBytes(result, escapeFieldPath(name)); err == nil {
+				result = updated
+			}
+		}
+		return true
+	})
+	return result
+}
+
+// escapeFieldPath escapes gjson/sjson path syntax in a literal object key.
+func escapeFieldPath(key string) string {
+	var b strings.Builder
+	for _, c := range key {
+		switch c {
+		case '.', '*', '?', '|', '#', '@', '\\', '!', '=', '<', '>', '%', ':':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
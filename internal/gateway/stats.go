@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/monitoring"
 )
 
 // StatsResponse is the JSON response for GET /stats.
@@ -18,9 +20,13 @@ type StatsResponse struct {
 		TotalRequests      int64 `json:"total_requests"`
 		UserTurns          int64 `json:"user_turns"` // Human-initiated prompts only
 		SuccessfulRequests int64 `json:"successful_requests"`
+		Errors             int64 `json:"errors"` // Requests answered with status >= 400
+		BytesIn            int64 `json:"bytes_in"`
+		BytesOut           int64 `json:"bytes_out"`
 		Compressions       int64 `json:"compressions"`
 		CacheHits          int64 `json:"cache_hits"`
 		CacheMisses        int64 `json:"cache_misses"`
+		LeakedRefs         int64 `json:"leaked_refs"` // [REF:id] references seen in system/assistant text
 	} `json:"gateway"`
 
 	Savings struct {
@@ -36,6 +42,9 @@ type StatsResponse struct {
 		Found    int `json:"found"`
 		NotFound int `json:"not_found"`
 	} `json:"expand_context"`
+
+	// Counts every compression event, even those not written due to compression_log_sample_rate.
+	CompressionLog monitoring.CompressionTotals `json:"compression_log"`
 }
 
 var gatewayStartTime = time.Now()
@@ -47,6 +56,17 @@ func (g *Gateway) handleStats(w http.ResponseWriter, r *http.Request) {
 		g.writeError(w, "forbidden", http.StatusForbidden)
 		return
 	}
+	resp := g.Stats()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Warn().Err(err).Msg("handleStats: failed to encode JSON response")
+	}
+}
+
+// Stats returns the aggregated metrics served by GET /stats. Embedders can call
+// it directly for a session summary; counters are reset with the session.
+func (g *Gateway) Stats() StatsResponse {
 	var resp StatsResponse
 	resp.Uptime = time.Since(gatewayStartTime).Truncate(time.Second).String()
 
@@ -56,9 +76,13 @@ func (g *Gateway) handleStats(w http.ResponseWriter, r *http.Request) {
 		resp.Gateway.TotalRequests = stats["requests"]
 		resp.Gateway.UserTurns = stats["user_turns"]
 		resp.Gateway.SuccessfulRequests = stats["successes"]
+		resp.Gateway.Errors = stats["requests"] - stats["successes"]
+		resp.Gateway.BytesIn = stats["bytes_in"]
+		resp.Gateway.BytesOut = stats["bytes_out"]
 		resp.Gateway.Compressions = stats["compressions"]
 		resp.Gateway.CacheHits = stats["cache_hits"]
 		resp.Gateway.CacheMisses = stats["cache_misses"]
+		resp.Gateway.LeakedRefs = stats["leaked_refs"]
 	}
 
 	// Savings
@@ -79,8 +103,8 @@ func (g *Gateway) handleStats(w http.ResponseWriter, r *http.Request) {
 		resp.ExpandContext.NotFound = summary.NotFound
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.Warn().Err(err).Msg("handleStats: failed to encode JSON response")
+	if g.tracker != nil {
+		resp.CompressionLog = g.tracker.CompressionTotals()
 	}
+	return resp
 }
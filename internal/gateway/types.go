@@ -49,9 +49,17 @@ type PipelineContext struct {
 	// Used to distinguish the main conversation from subagent conversations for savings/prompt recording.
 	StableFingerprint string
 
-	// Preemptive summarization
-	PreemptiveHeaders map[string]string // Headers to add to response
-	IsCompaction      bool              // Whether this is a compaction request
+	// Headers added to the client response (preemptive summarization status,
+	// tool_output.add_response_headers compression summary)
+	ResponseHeaders map[string]string
+	IsCompaction    bool // Whether this is a compaction request
+
+	// ResponseCacheKey is set when a successful response may be cached (server.response_cache_ttl).
+	ResponseCacheKey string
+
+	// PipelinePanic is "pipe: value" when a pipe panicked and the original request
+	// was forwarded unmodified (empty otherwise).
+	PipelinePanic string
 
 	// Metrics
 	OriginalTokenCount   int
@@ -0,0 +1,81 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// unixSocketProbeTimeout bounds the dial used to tell a live socket from a stale one.
+const unixSocketProbeTimeout = 500 * time.Millisecond
+
+// StartUnix serves the gateway on a Unix domain socket at path instead of the
+// TCP port. A stale socket file left by a crashed process is replaced; a path
+// with a live listener or a non-socket file is refused. The socket file is
+// removed when Shutdown closes the listener.
+func (g *Gateway) StartUnix(path string) error {
+	ln, err := listenUnix(path)
+	if err != nil {
+		return err
+	}
+	log.Info().Str("socket", path).Msg("Context Gateway starting")
+	return g.server.Serve(ln)
+}
+
+// listenUnix opens a Unix socket listener at path, readable and writable by the
+// owner only.
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("unix socket %s: path exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, unixSocketProbeTimeout); err == nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("unix socket %s: already in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("unix socket %s: remove stale socket: %w", path, err)
+		}
+		log.Info().Str("socket", path).Msg("removed stale unix socket")
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unix socket %s: %w", path, err)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("unix socket %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		_ = ln.Close()
+		return nil, fmt.Errorf("unix socket %s: %w", path, err)
+	}
+	return loopbackListener{ln}, nil
+}
+
+// loopbackListener reports every peer as 127.0.0.1. A Unix socket is only
+// reachable through the filesystem, so its clients are local by construction;
+// without this the localhost-only endpoints (dashboard API, stats) would reject
+// them because a Unix peer address is not an IP.
+type loopbackListener struct {
+	net.Listener
+}
+
+func (l loopbackListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return loopbackConn{conn}, nil
+}
+
+type loopbackConn struct {
+	net.Conn
+}
+
+func (loopbackConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+}
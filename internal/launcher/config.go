@@ -0,0 +1,16 @@
+// Package launcher holds the decisions the agent launcher (context-gateway
+// [AGENT]) makes around starting the gateway and the agent: which config and
+// port to use, whether to share a running gateway, and how to stop the
+// processes it started. Probes and prompts are passed in, so the decisions
+// can be tested without a terminal or real ports.
+package launcher
+
+// LaunchConfig returns the gateway config to start with. An explicit -c NAME
+// wins; otherwise the agent's default_config is used unless the user asked for
+// the config menu (bare -c) or the proxy is skipped. "" means the user picks one.
+func LaunchConfig(configFlag, agentDefault string, showMenu, skipProxy bool) string {
+	if configFlag != "" || showMenu || skipProxy {
+		return configFlag
+	}
+	return agentDefault
+}
@@ -0,0 +1,23 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// LoadEnvFiles loads --env-file paths in order on top of the default .env.
+// Later files override earlier ones (and the defaults). Unlike the default
+// locations, an explicitly named file must exist.
+func LoadEnvFiles(paths []string) error {
+	for _, path := range paths {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return fmt.Errorf("env file not found: %s", path)
+		}
+		if err := godotenv.Overload(path); err != nil {
+			return fmt.Errorf("env file %s: %w", path, err)
+		}
+	}
+	return nil
+}
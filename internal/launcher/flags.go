@@ -0,0 +1,26 @@
+package launcher
+
+import "strconv"
+
+// QuietEnv turns on quiet mode like --quiet when set to a true value.
+const QuietEnv = "CG_QUIET"
+
+// StripGlobalFlags removes global flags (--quiet) from args so subcommand
+// parsers never see them. Arguments after "--" belong to the agent and are
+// kept as-is. quiet reports whether --quiet was given or quietEnv, the value
+// of CG_QUIET, is true.
+func StripGlobalFlags(args []string, quietEnv string) (rest []string, quiet bool) {
+	quiet, _ = strconv.ParseBool(quietEnv)
+	rest = make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			return append(rest, args[i:]...), quiet
+		}
+		if arg == "--quiet" {
+			quiet = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, quiet
+}
@@ -0,0 +1,69 @@
+package launcher
+
+import (
+	"context"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// KeyAction is the user's answer to a missing provider key.
+type KeyAction int
+
+const (
+	// KeyEntered means a key was set in the environment; the config is checked again.
+	KeyEntered KeyAction = iota
+	// KeyContinue launches anyway; calls to the provider will fail.
+	KeyContinue
+	// KeyExit aborts the launch.
+	KeyExit
+)
+
+// KeyPrompt asks the user what to do about a missing provider key.
+type KeyPrompt func(missing config.DoctorIssue) KeyAction
+
+// CheckKeys runs config doctor on the selected config before the gateway
+// starts, so a missing provider key can be fixed in-flow instead of failing on
+// the first summarization call. Other issues go to warn; the first missing key
+// goes to prompt. Keys entered there are set in the environment, which the
+// later config load expands. Returns false when the user chose to exit.
+func CheckKeys(configData []byte, capturedProvider string, prompt KeyPrompt, warn func(config.DoctorIssue)) bool {
+	for {
+		cfg, err := config.LoadFromBytes(configData)
+		if err != nil {
+			return true // reported with full context when the gateway loads it
+		}
+		issues := cfg.Doctor(context.Background(), config.DoctorOptions{CapturedProvider: capturedProvider})
+
+		var missing *config.DoctorIssue
+		for i := range issues {
+			if issues[i].Kind == config.IssueMissingKey && missing == nil {
+				missing = &issues[i]
+				continue
+			}
+			warn(issues[i])
+		}
+		if missing == nil {
+			return true
+		}
+
+		switch prompt(*missing) {
+		case KeyExit:
+			return false
+		case KeyContinue:
+			return true
+		}
+	}
+}
+
+// CapturedProvider returns the provider whose credentials agentName sends on
+// its own requests, which the gateway captures (see selectCompactAuth).
+func CapturedProvider(agentName string) string {
+	switch agentName {
+	case "claude_code":
+		return config.ProviderAnthropic
+	case "codex":
+		return config.ProviderOpenAI
+	default:
+		return ""
+	}
+}
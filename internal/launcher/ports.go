@@ -0,0 +1,140 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PortProbe inspects gateway ports.
+type PortProbe struct {
+	// InUse reports whether something listens on the port.
+	InUse func(port int) bool
+	// Healthy reports whether a gateway answers /health on the port.
+	Healthy func(port int) bool
+}
+
+// PortRequest is how the launcher was asked to pick the gateway port.
+type PortRequest struct {
+	// Port is the -p value; 0 picks the first free port in the range.
+	Port int
+	// Detach is --detach: a gateway already serving an explicit Port is reused.
+	Detach bool
+	// ReuseBusy is --reuse-gateway: when the whole range is busy, the first
+	// port held by a healthy gateway is reused.
+	ReuseBusy bool
+	// CanReuse is false when this process must start its own gateway
+	// (the daemon child) or runs none (--proxy skip).
+	CanReuse bool
+}
+
+// PortChoice is the port the launcher uses.
+type PortChoice struct {
+	Port int
+	// Reuse means a running gateway already serves Port; none is started.
+	Reuse bool
+}
+
+// NoPortError reports that every port in the range is taken, split by who
+// holds them: a healthy gateway or some other process.
+type NoPortError struct {
+	Base, Count      int
+	Gateways, Others []int
+}
+
+func (e *NoPortError) Error() string {
+	return fmt.Sprintf("no available ports in range %d-%d", e.Base, e.Base+e.Count-1)
+}
+
+// ChoosePort picks the gateway port for req within [base, base+count).
+// It returns a *NoPortError when no port is free and none can be reused.
+func (p PortProbe) ChoosePort(req PortRequest, base, count int) (PortChoice, error) {
+	if req.Port == 0 {
+		for port := base; port < base+count; port++ {
+			if !p.InUse(port) {
+				return PortChoice{Port: port}, nil
+			}
+		}
+		busy := p.busyPorts(base, count)
+		if req.ReuseBusy && req.CanReuse && len(busy.Gateways) > 0 {
+			return PortChoice{Port: busy.Gateways[0], Reuse: true}, nil
+		}
+		return PortChoice{}, busy
+	}
+
+	reuse := req.CanReuse && req.Detach && p.InUse(req.Port) && p.Healthy(req.Port)
+	return PortChoice{Port: req.Port, Reuse: reuse}, nil
+}
+
+// busyPorts splits the ports in the range by who holds them.
+func (p PortProbe) busyPorts(base, count int) *NoPortError {
+	e := &NoPortError{Base: base, Count: count}
+	for port := base; port < base+count; port++ {
+		if !p.InUse(port) {
+			continue
+		}
+		if p.Healthy(port) {
+			e.Gateways = append(e.Gateways, port)
+		} else {
+			e.Others = append(e.Others, port)
+		}
+	}
+	return e
+}
+
+// DetachedPidFile returns the PID file of the detached gateway serving port.
+// Unlike context-gateway.pid (one background gateway), there is one per port.
+func DetachedPidFile(port int) string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("context-gateway-%d.pid", port))
+}
+
+// StopDetached stops the detached gateway whose PID is in pidFile (started
+// with --detach) and waits up to wait for it to exit. The PID file is removed
+// once it is stale or the gateway it names has been stopped.
+func StopDetached(pidFile string, port int, wait time.Duration) error {
+	// #nosec G304 -- reading pid file from temp dir (trusted path)
+	pidBytes, err := os.ReadFile(filepath.Clean(pidFile))
+	if err != nil {
+		return fmt.Errorf("no detached gateway on port %d", port)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(pidBytes)))
+	if err != nil {
+		_ = os.Remove(pidFile)
+		return fmt.Errorf("invalid PID file %s", pidFile)
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil || !IsProcessRunning(process) {
+		_ = os.Remove(pidFile)
+		return fmt.Errorf("gateway on port %d is not running (stale PID file)", port)
+	}
+
+	if err := TerminateProcess(process); err != nil {
+		return fmt.Errorf("failed to stop gateway: %w", err)
+	}
+
+	// Wait for the gateway to drain in-flight requests and exit
+	exited := false
+	for deadline := time.Now().Add(wait); time.Now().Before(deadline); {
+		time.Sleep(100 * time.Millisecond)
+		if !IsProcessRunning(process) {
+			exited = true
+			break
+		}
+	}
+
+	// Only remove PID file if it still contains the PID we stopped
+	if currentBytes, readErr := os.ReadFile(filepath.Clean(pidFile)); readErr == nil {
+		if currentPid, parseErr := strconv.Atoi(strings.TrimSpace(string(currentBytes))); parseErr == nil && currentPid == pid {
+			_ = os.Remove(pidFile)
+		}
+	}
+	if !exited {
+		return fmt.Errorf("gateway on port %d may still be shutting down", port)
+	}
+	return nil
+}
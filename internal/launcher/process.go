@@ -0,0 +1,38 @@
+package launcher
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StopSubprocess asks a started subprocess to exit and waits for it.
+// If it is still running after timeout it is killed; forced reports that case.
+func StopSubprocess(cmd *exec.Cmd, timeout time.Duration) (forced bool, err error) {
+	if cmd == nil || cmd.Process == nil {
+		return false, nil
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	if err := TerminateProcess(cmd.Process); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		log.Debug().Err(err).Int("pid", cmd.Process.Pid).Msg("graceful stop signal failed, killing")
+		timeout = 0
+	}
+
+	select {
+	case <-done:
+		return false, nil
+	case <-time.After(timeout):
+	}
+
+	if err := cmd.Process.Kill(); err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return true, err
+	}
+	<-done
+	return true, nil
+}
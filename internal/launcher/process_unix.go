@@ -0,0 +1,18 @@
+//go:build !windows
+
+package launcher
+
+import (
+	"os"
+	"syscall"
+)
+
+// TerminateProcess sends SIGTERM to gracefully stop a process.
+func TerminateProcess(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
+
+// IsProcessRunning checks if a process is still alive.
+func IsProcessRunning(p *os.Process) bool {
+	return p.Signal(syscall.Signal(0)) == nil
+}
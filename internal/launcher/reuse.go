@@ -0,0 +1,65 @@
+package launcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+)
+
+// ConfigMismatchError reports that a running gateway the launcher would share
+// serves a different config file than the one selected for this launch.
+type ConfigMismatchError struct {
+	Port    int
+	Running string // config file of the running gateway
+	Wanted  string // config file selected for this launch
+}
+
+func (e *ConfigMismatchError) Error() string {
+	return fmt.Sprintf("gateway on port %d runs config %s, not %s", e.Port, e.Running, e.Wanted)
+}
+
+// RunningConfig returns the config file the gateway on port was started with,
+// as reported by its /health endpoint, or "" when it does not report one.
+func RunningConfig(port int) (string, error) {
+	client := &http.Client{Timeout: 2 * time.Second}
+	// #nosec G107 -- localhost-only health check, port from internal config
+	resp, err := client.Get(fmt.Sprintf("http://localhost:%d/health", port))
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var health struct {
+		Config string `json:"config"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+		return "", fmt.Errorf("invalid /health response: %w", err)
+	}
+	return health.Config, nil
+}
+
+// CheckReuse returns a *ConfigMismatchError when the gateway on port serves a
+// config file other than configSource. A gateway that does not report its
+// config (older versions, or not started from a file) passes.
+func CheckReuse(port int, configSource string) error {
+	running, err := RunningConfig(port)
+	if err != nil {
+		return fmt.Errorf("cannot read the config of the gateway on port %d: %w", port, err)
+	}
+	if running == "" || configSource == "" || sameFile(running, configSource) {
+		return nil
+	}
+	return &ConfigMismatchError{Port: port, Running: running, Wanted: configSource}
+}
+
+// sameFile compares config paths the way the gateway records them (absolute).
+func sameFile(a, b string) bool {
+	absA, errA := filepath.Abs(filepath.Clean(a))
+	absB, errB := filepath.Abs(filepath.Clean(b))
+	if errA != nil || errB != nil {
+		return a == b
+	}
+	return absA == absB
+}
@@ -0,0 +1,84 @@
+package launcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sanitizeName replaces characters that are not filesystem-safe with underscores.
+func sanitizeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// PrepareSessionPath computes a session directory path without creating it.
+// The gateway creates the directory lazily on the first LLM request.
+// This prevents empty session folders when the gateway starts but receives no traffic.
+//
+// Format: <agent>_<N>_<YYYYMMDD_HHMMSS>  (e.g. claude_code_3_20240315_143022).
+// A non-empty customName is inserted as a slug before the timestamp
+// (e.g. claude_code_4_fix-bug-123_20240315_150110); the directory name is also
+// the session's ID in telemetry and the dashboard session list.
+func PrepareSessionPath(baseDir, agentName, customName string, now time.Time) string {
+	_ = os.MkdirAll(baseDir, 0750)
+
+	prefix := sanitizeName(agentName)
+	if prefix == "" {
+		prefix = "session"
+	}
+	stamp := now.Format("20060102_150405")
+
+	// Find the highest session number already used for this agent prefix.
+	sessionNum := 1
+	entries, err := os.ReadDir(baseDir)
+	if err == nil {
+		needle := prefix + "_"
+		for _, e := range entries {
+			if !e.IsDir() || !strings.HasPrefix(e.Name(), needle) {
+				continue
+			}
+			// Name is "<prefix>_<N>_<date>" — parse the part right after the prefix.
+			rest := e.Name()[len(needle):]
+			parts := strings.SplitN(rest, "_", 2)
+			if len(parts) >= 1 {
+				if n, err := strconv.Atoi(parts[0]); err == nil && n >= sessionNum {
+					sessionNum = n + 1
+				}
+			}
+		}
+	}
+
+	if slug := SessionSlug(customName); slug != "" {
+		return filepath.Join(baseDir, fmt.Sprintf("%s_%d_%s_%s", prefix, sessionNum, slug, stamp))
+	}
+	return filepath.Join(baseDir, fmt.Sprintf("%s_%d_%s", prefix, sessionNum, stamp))
+}
+
+// maxSessionSlugLen caps the session name part of a session directory.
+const maxSessionSlugLen = 48
+
+// SessionSlug turns a user-given session name into a filesystem-safe slug:
+// unsafe characters become '-', runs collapse, and the result is trimmed and capped.
+func SessionSlug(name string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(name) {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else if s := b.String(); s != "" && !strings.HasSuffix(s, "-") {
+			b.WriteByte('-')
+		}
+	}
+	slug := b.String()
+	if len(slug) > maxSessionSlugLen {
+		slug = slug[:maxSessionSlugLen]
+	}
+	return strings.Trim(slug, "-_")
+}
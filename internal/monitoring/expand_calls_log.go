@@ -26,11 +26,14 @@ type ExpandContextCallEntry struct {
 	CompressedContent string    `json:"compressed_content"` // what the model saw before calling expand
 }
 
-// ExpandCallsLogger appends ExpandContextCallEntry records to a JSONL file.
+// ExpandCallsLogger appends ExpandContextCallEntry records to a JSONL file
+// and, when telemetry_sqlite_path is set, to the expansions table.
 // Thread-safe. Safe to call on a nil receiver (disabled).
 type ExpandCallsLogger struct {
 	mu   sync.Mutex
-	file *os.File
+	file *os.File // nil when only the SQLite mirror is enabled
+
+	sqlite *telemetrySQLite
 }
 
 // NewExpandCallsLogger opens (or creates) the JSONL file for append.
@@ -54,6 +57,10 @@ func (l *ExpandCallsLogger) Log(entry ExpandContextCallEntry) {
 	if l == nil {
 		return
 	}
+	l.sqlite.recordExpansion(expansionSourceTool, entry)
+	if l.file == nil {
+		return
+	}
 	data, err := json.Marshal(entry)
 	if err != nil {
 		log.Error().Err(err).Msg("expand_calls: marshal failed")
@@ -73,5 +80,7 @@ func (l *ExpandCallsLogger) Close() {
 	}
 	l.mu.Lock()
 	defer l.mu.Unlock()
-	_ = l.file.Close()
+	if l.file != nil {
+		_ = l.file.Close()
+	}
 }
@@ -59,6 +59,10 @@ func New(cfg LoggerConfig) *Logger {
 		}
 	}
 
+	if !cfg.DisableMasking {
+		writer = NewSanitizingWriter(writer)
+	}
+
 	if cfg.Format == "console" {
 		writer = zerolog.ConsoleWriter{Out: writer, TimeFormat: "15:04:05"}
 	}
@@ -14,6 +14,9 @@ type MetricsCollector struct {
 	compressions atomic.Int64
 	cacheHits    atomic.Int64
 	cacheMisses  atomic.Int64
+	bytesIn      atomic.Int64 // Client request bytes for forwarded requests
+	bytesOut     atomic.Int64 // Bytes actually sent upstream for those requests
+	leakedRefs   atomic.Int64 // [REF:id] references found in system/assistant text
 }
 
 // NewMetricsCollector creates a new metrics collector.
@@ -29,6 +32,12 @@ func (mc *MetricsCollector) RecordRequest(success bool, _ time.Duration) {
 	}
 }
 
+// RecordBytes records the client's request size and the size forwarded upstream.
+func (mc *MetricsCollector) RecordBytes(in, out int) {
+	mc.bytesIn.Add(int64(in))
+	mc.bytesOut.Add(int64(out))
+}
+
 // RecordUserTurn records a new human-initiated prompt (not a tool loop or subagent).
 func (mc *MetricsCollector) RecordUserTurn() { mc.userTurns.Add(1) }
 
@@ -43,6 +52,9 @@ func (mc *MetricsCollector) RecordCacheHit() { mc.cacheHits.Add(1) }
 // RecordCacheMiss records a cache miss.
 func (mc *MetricsCollector) RecordCacheMiss() { mc.cacheMisses.Add(1) }
 
+// RecordLeakedRefs records [REF:id] references found outside tool results.
+func (mc *MetricsCollector) RecordLeakedRefs(n int) { mc.leakedRefs.Add(int64(n)) }
+
 // Stats returns current metrics.
 func (mc *MetricsCollector) Stats() map[string]int64 {
 	return map[string]int64{
@@ -52,6 +64,9 @@ func (mc *MetricsCollector) Stats() map[string]int64 {
 		"compressions": mc.compressions.Load(),
 		"cache_hits":   mc.cacheHits.Load(),
 		"cache_misses": mc.cacheMisses.Load(),
+		"bytes_in":     mc.bytesIn.Load(),
+		"bytes_out":    mc.bytesOut.Load(),
+		"leaked_refs":  mc.leakedRefs.Load(),
 	}
 }
 
@@ -63,6 +78,9 @@ func (mc *MetricsCollector) Reset() {
 	mc.compressions.Store(0)
 	mc.cacheHits.Store(0)
 	mc.cacheMisses.Store(0)
+	mc.bytesIn.Store(0)
+	mc.bytesOut.Store(0)
+	mc.leakedRefs.Store(0)
 }
 
 // Stop is a no-op for compatibility.
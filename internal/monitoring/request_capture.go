@@ -0,0 +1,143 @@
+// Package monitoring - request_capture.go writes requests.jsonl for offline replay.
+package monitoring
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// RequestCaptureFile is the default file name for captured requests in a session directory.
+const RequestCaptureFile = "requests.jsonl"
+
+// maxCapturedRequestSize bounds a single requests.jsonl line when reading it back.
+const maxCapturedRequestSize = 64 << 20 // 64MB
+
+// captureHeaders are the request headers kept in a capture. They drive provider
+// detection and request format; auth headers are never written.
+var captureHeaders = []string{
+	"Content-Type",
+	"Anthropic-Version",
+	"Anthropic-Beta",
+	"X-Provider",
+	"User-Agent",
+}
+
+// CapturedRequest is one client request exactly as it reached the gateway,
+// before any pipe ran. Used by `context-gateway replay-session`.
+type CapturedRequest struct {
+	Timestamp time.Time         `json:"timestamp"`
+	RequestID string            `json:"request_id,omitempty"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Body      json.RawMessage   `json:"body"`
+}
+
+// NewCapturedRequest builds a capture entry from an incoming request and its raw body.
+func NewCapturedRequest(r *http.Request, requestID string, body []byte) CapturedRequest {
+	headers := make(map[string]string)
+	for _, h := range captureHeaders {
+		if v := r.Header.Get(h); v != "" {
+			headers[h] = v
+		}
+	}
+	return CapturedRequest{
+		Timestamp: time.Now().UTC(),
+		RequestID: requestID,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Headers:   headers,
+		Body:      json.RawMessage(body),
+	}
+}
+
+// RequestCaptureLogger appends CapturedRequest records to a JSONL file.
+// Thread-safe. Safe to call on a nil receiver (disabled).
+type RequestCaptureLogger struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewRequestCaptureLogger opens (or creates) the JSONL file for append.
+// Returns nil if path is empty (feature disabled).
+func NewRequestCaptureLogger(path string) (*RequestCaptureLogger, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600) // #nosec G304
+	if err != nil {
+		return nil, err
+	}
+	return &RequestCaptureLogger{file: f}, nil
+}
+
+// Log appends an entry to the JSONL file. Safe to call on nil.
+// Bodies that are not valid JSON are skipped (they cannot be replayed).
+func (l *RequestCaptureLogger) Log(entry CapturedRequest) {
+	if l == nil {
+		return
+	}
+	if !json.Valid(entry.Body) {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.Error().Err(err).Msg("request_capture: marshal failed")
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		log.Error().Err(err).Msg("request_capture: write failed")
+	}
+}
+
+// Close closes the file. Safe to call on nil.
+func (l *RequestCaptureLogger) Close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_ = l.file.Close()
+}
+
+// ReadCapturedRequests loads all entries from a requests.jsonl file in order.
+func ReadCapturedRequests(path string) ([]CapturedRequest, error) {
+	f, err := os.Open(path) // #nosec G304 -- user-specified session path
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var out []CapturedRequest
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 1<<20), maxCapturedRequestSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry CapturedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		out = append(out, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", path, err)
+	}
+	return out, nil
+}
@@ -5,10 +5,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math/rand/v2"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -37,6 +39,12 @@ type Tracker struct {
 	seenSessionTools     map[string]map[string]bool // sessionID → tool names already in session_tools.json
 	statsTracker         *SessionStatsTracker       // live session_stats.json writer
 	expandCallsLogger    *ExpandCallsLogger         // expand_context_calls.jsonl writer
+	requestCapture       *RequestCaptureLogger      // requests.jsonl writer (replay-session input)
+	sqlite               *telemetrySQLite           // telemetry_sqlite_path mirror of request/compression/expansion events
+	// In-memory compression totals over every event, logged or sampled out.
+	compressionEvents      atomic.Int64
+	compressionTokensSaved atomic.Int64
+	compressionBytesSaved  atomic.Int64
 	// Per-file mutexes allow concurrent writes to different log files (P7).
 	muRequest       sync.Mutex // guards requestLogFile
 	muCompression   sync.Mutex // guards compressionLogFile
@@ -52,6 +60,15 @@ func NewTracker(cfg TelemetryConfig) (*Tracker, error) {
 		seenSessionTools: make(map[string]map[string]bool),
 	}
 
+	// Request capture is its own opt-in (monitoring.capture_requests), independent of telemetry.
+	if cfg.RequestCapturePath != "" {
+		rc, err := NewRequestCaptureLogger(cfg.RequestCapturePath)
+		if err != nil {
+			return nil, fmt.Errorf("open request capture log: %w", err)
+		}
+		t.requestCapture = rc
+	}
+
 	if !cfg.Enabled {
 		return t, nil
 	}
@@ -127,6 +144,19 @@ func NewTracker(cfg TelemetryConfig) (*Tracker, error) {
 		t.expandCallsLogger = el
 	}
 
+	if cfg.SQLitePath != "" {
+		db, err := newTelemetrySQLite(cfg.SQLitePath)
+		if err != nil {
+			return nil, fmt.Errorf("open telemetry sqlite: %w", err)
+		}
+		t.sqlite = db
+		// expand_context calls reach the database through the expand calls logger.
+		if t.expandCallsLogger == nil {
+			t.expandCallsLogger = &ExpandCallsLogger{}
+		}
+		t.expandCallsLogger.sqlite = db
+	}
+
 	return t, nil
 }
 
@@ -178,6 +208,7 @@ func (t *Tracker) RecordRequest(event *RequestEvent) {
 			t.requestCount++
 		}
 	}
+	t.sqlite.recordRequest(event)
 }
 
 // RecordExpand records an expand_context call.
@@ -186,6 +217,10 @@ func (t *Tracker) RecordExpand(event *ExpandEvent) {
 		return
 	}
 
+	t.sqlite.recordExpansion(expansionSourceAPI, ExpandContextCallEntry{
+		Timestamp: event.Timestamp, RequestID: event.RequestID, ShadowID: event.ShadowRefID, Found: event.Found,
+	})
+
 	t.muRequest.Lock()
 	defer t.muRequest.Unlock()
 
@@ -199,9 +234,9 @@ func (t *Tracker) RecordExpand(event *ExpandEvent) {
 	}
 }
 
-// CompressionLogEnabled returns true if compression logging is enabled.
+// CompressionLogEnabled returns true if compression logging (JSONL or SQLite) is enabled.
 func (t *Tracker) CompressionLogEnabled() bool {
-	return t.config.Enabled && t.compressionLogPath != ""
+	return t.config.Enabled && (t.compressionLogPath != "" || t.sqlite != nil)
 }
 
 // ToolDiscoveryLogEnabled returns true if tool discovery logging is enabled.
@@ -212,11 +247,59 @@ func (t *Tracker) ToolDiscoveryLogEnabled() bool {
 // now returns the current UTC timestamp in RFC3339 format.
 func now() string { return time.Now().UTC().Format(time.RFC3339) }
 
+// CompressionTotals are in-memory totals over every compression event passed to
+// LogCompressionComparison, including events dropped by compression_log_sample_rate.
+type CompressionTotals struct {
+	Events      int64   `json:"events"`
+	Logged      int64   `json:"logged"` // events written to the compression JSONL log
+	TokensSaved int64   `json:"tokens_saved"`
+	BytesSaved  int64   `json:"bytes_saved"`
+	SampleRate  float64 `json:"sample_rate"`
+}
+
+// CompressionTotals returns the compression totals since start or the last reset.
+func (t *Tracker) CompressionTotals() CompressionTotals {
+	t.muCompression.Lock()
+	logged := t.compressionCount
+	t.muCompression.Unlock()
+	return CompressionTotals{
+		Events:      t.compressionEvents.Load(),
+		Logged:      int64(logged),
+		TokensSaved: t.compressionTokensSaved.Load(),
+		BytesSaved:  t.compressionBytesSaved.Load(),
+		SampleRate:  t.compressionSampleRate(),
+	}
+}
+
+// ResetCompressionTotals zeros the compression totals for a fresh session.
+func (t *Tracker) ResetCompressionTotals() {
+	t.compressionEvents.Store(0)
+	t.compressionTokensSaved.Store(0)
+	t.compressionBytesSaved.Store(0)
+	t.muCompression.Lock()
+	t.compressionCount = 0
+	t.muCompression.Unlock()
+}
+
+// compressionSampleRate returns the effective compression log sample rate
+// (unset = 1, every event).
+func (t *Tracker) compressionSampleRate() float64 {
+	r := t.config.CompressionLogSampleRate
+	if r == nil {
+		return 1
+	}
+	return min(max(*r, 0), 1)
+}
+
 // LogCompressionComparison logs a tool-output compression event to tool_output_compression.jsonl.
 // Converts the internal CompressionComparison to a typed ToolOutputEntry before writing.
+// Totals are updated for every event; the JSONL write honours compression_log_sample_rate.
 func (t *Tracker) LogCompressionComparison(c CompressionComparison) {
 	// Stats are independent of JSONL file config — update always.
 	t.statsTracker.RecordToolOutput(c.Status, c.OriginalTokens, c.CompressedTokens, c.CacheHit)
+	t.compressionEvents.Add(1)
+	t.compressionTokensSaved.Add(int64(c.OriginalTokens - c.CompressedTokens))
+	t.compressionBytesSaved.Add(int64(len(c.OriginalContent) - len(c.CompressedContent)))
 
 	if !t.CompressionLogEnabled() {
 		return
@@ -244,9 +327,17 @@ func (t *Tracker) LogCompressionComparison(c CompressionComparison) {
 		CompressionModel:  c.CompressionModel,
 		Query:             c.Query,
 		QueryAgnostic:     c.QueryAgnostic,
+		Attempts:          c.Attempts,
+		TargetMissed:      c.TargetMissed,
+		BudgetExhausted:   c.BudgetExhausted,
 		OriginalContent:   c.OriginalContent,
 		CompressedContent: c.CompressedContent,
 	}
+	t.sqlite.recordCompression(entry)
+
+	if rate := t.compressionSampleRate(); rate < 1 && rand.Float64() >= rate { // #nosec G404 -- log sampling, not security
+		return
+	}
 
 	t.muCompression.Lock()
 	defer t.muCompression.Unlock()
@@ -458,6 +549,8 @@ func (t *Tracker) Close() error {
 
 	t.statsTracker.Stop()
 	t.expandCallsLogger.Close()
+	t.requestCapture.Close()
+	t.sqlite.close()
 
 	for _, f := range []*os.File{t.requestLogFile, t.compressionLogFile, t.toolDiscoveryLogFile, t.taskOutputLogFile} {
 		if f != nil {
@@ -479,6 +572,14 @@ func (t *Tracker) LogExpandContextCall(entry ExpandContextCallEntry) {
 	t.expandCallsLogger.Log(entry)
 }
 
+// CaptureRequest appends a raw client request to requests.jsonl (no-op when disabled).
+func (t *Tracker) CaptureRequest(entry CapturedRequest) {
+	if t == nil {
+		return
+	}
+	t.requestCapture.Log(entry)
+}
+
 // ExpandCallsLogger returns the logger for expand_context_calls.jsonl.
 // Returns nil if the feature is disabled. Used to wire ExpandContextHandler.
 func (t *Tracker) ExpandCallsLogger() *ExpandCallsLogger {
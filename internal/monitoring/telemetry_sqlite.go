@@ -0,0 +1,293 @@
+// Package monitoring - telemetry_sqlite.go mirrors telemetry events into SQLite
+// (monitoring.telemetry_sqlite_path) so history can be queried across sessions.
+package monitoring
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	_ "modernc.org/sqlite" // Pure-Go SQLite driver, registered as "sqlite".
+)
+
+// TelemetrySQLiteSchema is the DDL of the telemetry database. Timestamps are
+// RFC3339 UTC text, so they sort and compare as strings.
+const TelemetrySQLiteSchema = `CREATE TABLE IF NOT EXISTS requests (
+	id                     INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp              TEXT    NOT NULL,
+	request_id             TEXT    NOT NULL DEFAULT '',
+	session_id             TEXT    NOT NULL DEFAULT '',
+	provider               TEXT    NOT NULL DEFAULT '',
+	model                  TEXT    NOT NULL DEFAULT '',
+	path                   TEXT    NOT NULL DEFAULT '',
+	status_code            INTEGER NOT NULL DEFAULT 0,
+	success                INTEGER NOT NULL DEFAULT 0,
+	pipe_type              TEXT    NOT NULL DEFAULT '',
+	pipe_strategy          TEXT    NOT NULL DEFAULT '',
+	is_main_agent          INTEGER NOT NULL DEFAULT 0,
+	request_body_size      INTEGER NOT NULL DEFAULT 0,
+	response_body_size     INTEGER NOT NULL DEFAULT 0,
+	original_tokens        INTEGER NOT NULL DEFAULT 0,
+	compressed_tokens      INTEGER NOT NULL DEFAULT 0,
+	tokens_saved           INTEGER NOT NULL DEFAULT 0,
+	compression_ratio      REAL    NOT NULL DEFAULT 0,
+	tool_output_count      INTEGER NOT NULL DEFAULT 0,
+	input_tokens           INTEGER NOT NULL DEFAULT 0,
+	output_tokens          INTEGER NOT NULL DEFAULT 0,
+	cache_read_tokens      INTEGER NOT NULL DEFAULT 0,
+	cost_usd               REAL    NOT NULL DEFAULT 0,
+	compression_latency_ms INTEGER NOT NULL DEFAULT 0,
+	forward_latency_ms     INTEGER NOT NULL DEFAULT 0,
+	total_latency_ms       INTEGER NOT NULL DEFAULT 0,
+	error                  TEXT    NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_requests_timestamp ON requests(timestamp);
+CREATE INDEX IF NOT EXISTS idx_requests_session   ON requests(session_id);
+
+CREATE TABLE IF NOT EXISTS compressions (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp         TEXT    NOT NULL,
+	request_id        TEXT    NOT NULL DEFAULT '',
+	session_id        TEXT    NOT NULL DEFAULT '',
+	event_type        TEXT    NOT NULL DEFAULT '',
+	tool_name         TEXT    NOT NULL DEFAULT '',
+	shadow_id         TEXT    NOT NULL DEFAULT '',
+	model             TEXT    NOT NULL DEFAULT '',
+	compression_model TEXT    NOT NULL DEFAULT '',
+	status            TEXT    NOT NULL DEFAULT '',
+	cache_hit         INTEGER NOT NULL DEFAULT 0,
+	original_tokens   INTEGER NOT NULL DEFAULT 0,
+	compressed_tokens INTEGER NOT NULL DEFAULT 0,
+	tokens_saved      INTEGER NOT NULL DEFAULT 0,
+	original_bytes    INTEGER NOT NULL DEFAULT 0,
+	compressed_bytes  INTEGER NOT NULL DEFAULT 0,
+	bytes_saved       INTEGER NOT NULL DEFAULT 0,
+	compression_ratio REAL    NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_compressions_timestamp ON compressions(timestamp);
+CREATE INDEX IF NOT EXISTS idx_compressions_tool      ON compressions(tool_name);
+
+CREATE TABLE IF NOT EXISTS expansions (
+	id                INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp         TEXT    NOT NULL,
+	source            TEXT    NOT NULL DEFAULT '', -- expand_context (model tool call) or api (POST /expand)
+	request_id        TEXT    NOT NULL DEFAULT '',
+	session_id        TEXT    NOT NULL DEFAULT '',
+	shadow_id         TEXT    NOT NULL DEFAULT '',
+	tool_name         TEXT    NOT NULL DEFAULT '',
+	found             INTEGER NOT NULL DEFAULT 0,
+	original_tokens   INTEGER NOT NULL DEFAULT 0,
+	compressed_tokens INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_expansions_timestamp ON expansions(timestamp);
+`
+
+// Expansion sources recorded in expansions.source.
+const (
+	expansionSourceTool = "expand_context"
+	expansionSourceAPI  = "api"
+)
+
+// telemetrySQLiteQueueSize bounds the rows waiting for the writer goroutine.
+// Rows arriving while it is full are dropped rather than delaying requests.
+const telemetrySQLiteQueueSize = 1024
+
+// telemetrySQLite writes telemetry events into the tables of TelemetrySQLiteSchema.
+// Inserts are queued and applied by a single background writer, so a slow disk
+// or a locked database never holds up the request path. Safe to call on a nil
+// receiver (disabled). Write errors are logged, never returned, so a broken
+// database cannot fail a request.
+type telemetrySQLite struct {
+	db      *sql.DB
+	rows    chan sqliteRow
+	done    chan struct{} // closed when the writer has drained rows
+	mu      sync.RWMutex  // guards closed against sends on a closed rows
+	closed  bool
+	dropped atomic.Int64
+}
+
+// sqliteRow is one queued INSERT.
+type sqliteRow struct {
+	table string
+	query string
+	args  []any
+}
+
+// newTelemetrySQLite opens (or creates) the telemetry database at path.
+// Returns nil if path is empty (feature disabled).
+func newTelemetrySQLite(path string) (*telemetrySQLite, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_busy_timeout=5000", path))
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range []string{"PRAGMA journal_mode=WAL", TelemetrySQLiteSchema} {
+		if _, err := db.Exec(stmt); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("init %s: %w", path, err)
+		}
+	}
+	s := &telemetrySQLite{
+		db:   db,
+		rows: make(chan sqliteRow, telemetrySQLiteQueueSize),
+		done: make(chan struct{}),
+	}
+	go s.write()
+	return s, nil
+}
+
+// exec queues an INSERT for the writer without blocking.
+func (s *telemetrySQLite) exec(table, query string, args ...any) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.rows <- sqliteRow{table: table, query: query, args: args}:
+	default:
+		if s.dropped.Add(1) == 1 {
+			log.Warn().Str("table", table).Msg("telemetry: sqlite writer is behind, dropping rows")
+		}
+	}
+}
+
+// write applies queued rows in order until rows is closed.
+func (s *telemetrySQLite) write() {
+	defer close(s.done)
+	for row := range s.rows {
+		if _, err := s.db.Exec(row.query, row.args...); err != nil {
+			log.Error().Err(err).Str("table", row.table).Msg("telemetry: failed to write sqlite row")
+		}
+	}
+}
+
+func (s *telemetrySQLite) recordRequest(e *RequestEvent) {
+	if s == nil {
+		return
+	}
+	s.exec("requests", `INSERT INTO requests (
+		timestamp, request_id, session_id, provider, model, path, status_code, success,
+		pipe_type, pipe_strategy, is_main_agent, request_body_size, response_body_size,
+		original_tokens, compressed_tokens, tokens_saved, compression_ratio, tool_output_count,
+		input_tokens, output_tokens, cache_read_tokens, cost_usd,
+		compression_latency_ms, forward_latency_ms, total_latency_ms, error
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sqliteTime(e.Timestamp), e.RequestID, e.SessionID, e.Provider, e.Model, e.Path, e.StatusCode, e.Success,
+		string(e.PipeType), e.PipeStrategy, e.IsMainAgent, e.RequestBodySize, e.ResponseBodySize,
+		e.OriginalTokens, e.CompressedTokens, e.TokensSaved, e.CompressionRatio, e.ToolOutputCount,
+		e.InputTokens, e.OutputTokens, e.CacheReadInputTokens, e.CostUSD,
+		e.CompressionLatencyMs, e.ForwardLatencyMs, e.TotalLatencyMs, e.Error)
+}
+
+func (s *telemetrySQLite) recordCompression(e ToolOutputEntry) {
+	if s == nil {
+		return
+	}
+	origBytes, compBytes := len(e.OriginalContent), len(e.CompressedContent)
+	s.exec("compressions", `INSERT INTO compressions (
+		timestamp, request_id, session_id, event_type, tool_name, shadow_id, model, compression_model,
+		status, cache_hit, original_tokens, compressed_tokens, tokens_saved,
+		original_bytes, compressed_bytes, bytes_saved, compression_ratio
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Timestamp, e.RequestID, e.SessionID, e.EventType, e.ToolName, e.ShadowID, e.ProviderModel, e.CompressionModel,
+		e.Status, e.CacheHit, e.OriginalTokens, e.CompressedTokens, e.OriginalTokens-e.CompressedTokens,
+		origBytes, compBytes, origBytes-compBytes, e.CompressionRatio)
+}
+
+func (s *telemetrySQLite) recordExpansion(source string, e ExpandContextCallEntry) {
+	if s == nil {
+		return
+	}
+	s.exec("expansions", `INSERT INTO expansions (
+		timestamp, source, request_id, session_id, shadow_id, tool_name, found, original_tokens, compressed_tokens
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		sqliteTime(e.Timestamp), source, e.RequestID, e.SessionID, e.ShadowID, e.ToolName, e.Found,
+		e.OriginalTokens, e.CompressedTokens)
+}
+
+func (s *telemetrySQLite) close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.rows)
+	s.mu.Unlock()
+
+	// Flush what is queued before closing the database.
+	<-s.done
+	if n := s.dropped.Load(); n > 0 {
+		log.Warn().Int64("rows", n).Msg("telemetry: sqlite rows dropped while the writer was behind")
+	}
+	_ = s.db.Close()
+}
+
+// sqliteTime formats t like the JSONL logs (RFC3339 UTC); zero means now.
+func sqliteTime(t time.Time) string {
+	if t.IsZero() {
+		return now()
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// QueryTelemetrySQLite runs a read-only query against the telemetry database
+// at path and returns the column names and rows rendered as strings.
+func QueryTelemetrySQLite(ctx context.Context, path, query string) ([]string, [][]string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, nil, fmt.Errorf("telemetry database %s: %w", path, err)
+	}
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?mode=ro&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer db.Close() //nolint:errcheck
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close() //nolint:errcheck
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var out [][]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, nil, err
+		}
+		row := make([]string, len(columns))
+		for i, v := range values {
+			if v.Valid {
+				row[i] = v.String
+			} else {
+				row[i] = "NULL"
+			}
+		}
+		out = append(out, row)
+	}
+	return columns, out, rows.Err()
+}
@@ -24,6 +24,7 @@ const (
 // RequestEvent captures a request through the gateway.
 type RequestEvent struct {
 	RequestID        string    `json:"request_id"`
+	SessionID        string    `json:"session_id,omitempty"`
 	Timestamp        time.Time `json:"timestamp"`
 	Method           string    `json:"method"`
 	Path             string    `json:"path"`
@@ -62,6 +63,10 @@ type RequestEvent struct {
 	Success bool   `json:"success"`
 	Error   string `json:"error,omitempty"`
 
+	// PipelinePanic is "pipe: value" when compression panicked and the request
+	// was forwarded unmodified.
+	PipelinePanic string `json:"pipeline_panic,omitempty"`
+
 	// Latency
 	CompressionLatencyMs int64 `json:"compression_latency_ms"`
 	ForwardLatencyMs     int64 `json:"forward_latency_ms"`
@@ -131,6 +136,9 @@ type CompressionComparison struct {
 	ToolCount        int     `json:"tool_count,omitempty"`    // total tools in original request
 	StubCount        int     `json:"stub_count,omitempty"`    // tools replaced with stubs (deferred)
 	PhantomCount     int     `json:"phantom_count,omitempty"` // phantom tools injected
+	Attempts         int     `json:"attempts,omitempty"`      // compression API calls (adaptive targeting)
+	TargetMissed     bool    `json:"target_missed,omitempty"` // result missed target_compression_ratio
+	BudgetExhausted  bool    `json:"budget_exhausted,omitempty"`
 	// Large/variable fields — used internally, not written to tool_discovery.jsonl
 	AllTools          []string `json:"-"`
 	SelectedTools     []string `json:"-"`
@@ -257,6 +265,9 @@ type ToolOutputEntry struct {
 	CompressionModel  string  `json:"compression_model,omitempty"`
 	Query             string  `json:"query,omitempty"`
 	QueryAgnostic     bool    `json:"query_agnostic,omitempty"`
+	Attempts          int     `json:"attempts,omitempty"`
+	TargetMissed      bool    `json:"target_missed,omitempty"`
+	BudgetExhausted   bool    `json:"budget_exhausted,omitempty"`
 	OriginalContent   string  `json:"original_content,omitempty"`
 	CompressedContent string  `json:"compressed_content,omitempty"`
 }
@@ -319,6 +330,15 @@ type TelemetryConfig struct {
 	// Each entry contains the original + compressed content that triggered the call —
 	// a training signal for compressions the model found too aggressive.
 	ExpandContextCallsPath string `yaml:"expand_context_calls_path"`
+	// RequestCapturePath is the JSONL log of raw client requests (requests.jsonl).
+	// Empty = disabled. Consumed offline by `context-gateway replay-session`.
+	RequestCapturePath string `yaml:"request_capture_path"`
+	// SQLitePath is a SQLite database that mirrors request, compression and
+	// expansion events (see TelemetrySQLiteSchema). Empty = disabled.
+	SQLitePath string `yaml:"sqlite_path"`
+	// CompressionLogSampleRate is the fraction (0.0-1.0) of compression events
+	// written to CompressionLogPath. nil = every event, 0 = none.
+	CompressionLogSampleRate *float64 `yaml:"compression_log_sample_rate"`
 }
 
 // LoggerConfig contains logging configuration.
@@ -326,6 +346,8 @@ type LoggerConfig struct {
 	Level  string `yaml:"level"`  // debug, info, warn, error
 	Format string `yaml:"format"` // json, console
 	Output string `yaml:"output"` // stdout, stderr, or file path
+
+	DisableMasking bool `yaml:"disable_masking"` // Log keys and URL credentials unmasked
 }
 
 // AlertConfig contains alert thresholds.
@@ -0,0 +1,22 @@
+package notifications
+
+import "net/http"
+
+// DiscordNotifier posts events to a Discord channel webhook.
+type DiscordNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewDiscordNotifier creates a Discord notifier for the given channel webhook URL.
+func NewDiscordNotifier(webhookURL string) *DiscordNotifier {
+	return &DiscordNotifier{webhookURL: webhookURL, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Name returns "discord".
+func (d *DiscordNotifier) Name() string { return "discord" }
+
+// Notify sends the event as a Discord message.
+func (d *DiscordNotifier) Notify(event Event) error {
+	return postJSON(d.client, d.webhookURL, map[string]string{"content": formatText(event)}, nil)
+}
@@ -0,0 +1,106 @@
+package notifications
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// repeatWindow suppresses identical (type, session) events so a session stuck
+// over budget does not page on every blocked request.
+const repeatWindow = 10 * time.Minute
+
+// maxTrackedEvents triggers pruning of expired repeatWindow entries.
+const maxTrackedEvents = 1000
+
+// Dispatcher fans events out to all enabled notifiers.
+// Delivery is asynchronous; failures are logged and never reach the caller.
+type Dispatcher struct {
+	mu        sync.RWMutex
+	notifiers []Notifier
+	lastSent  map[string]time.Time
+	wg        sync.WaitGroup
+}
+
+// NewDispatcher builds a dispatcher from the notifications config.
+func NewDispatcher(cfg config.NotificationsConfig) *Dispatcher {
+	d := &Dispatcher{lastSent: make(map[string]time.Time)}
+	d.notifiers = buildNotifiers(cfg)
+	return d
+}
+
+// buildNotifiers returns a notifier for each enabled integration with a URL.
+func buildNotifiers(cfg config.NotificationsConfig) []Notifier {
+	var out []Notifier
+	if url := cfg.Slack.EffectiveWebhookURL(); cfg.Slack.Enabled && url != "" {
+		out = append(out, NewSlackNotifier(url))
+	}
+	if cfg.Discord.Enabled && cfg.Discord.WebhookURL != "" {
+		out = append(out, NewDiscordNotifier(cfg.Discord.WebhookURL))
+	}
+	if cfg.Webhook.Enabled && cfg.Webhook.URL != "" {
+		out = append(out, NewWebhookNotifier(cfg.Webhook.URL, cfg.Webhook.Headers))
+	}
+	return out
+}
+
+// UpdateConfig swaps the notifier set (hot-reload).
+func (d *Dispatcher) UpdateConfig(cfg config.NotificationsConfig) {
+	notifiers := buildNotifiers(cfg)
+	d.mu.Lock()
+	d.notifiers = notifiers
+	d.mu.Unlock()
+}
+
+// Enabled reports whether any notifier is configured.
+func (d *Dispatcher) Enabled() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.notifiers) > 0
+}
+
+// Notify sends event to every notifier in the background.
+func (d *Dispatcher) Notify(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	key := event.Type + "|" + event.SessionID
+	d.mu.Lock()
+	if len(d.notifiers) == 0 {
+		d.mu.Unlock()
+		return
+	}
+	if last, ok := d.lastSent[key]; ok && event.Timestamp.Sub(last) < repeatWindow {
+		d.mu.Unlock()
+		return
+	}
+	d.lastSent[key] = event.Timestamp
+	if len(d.lastSent) > maxTrackedEvents {
+		for k, t := range d.lastSent {
+			if event.Timestamp.Sub(t) >= repeatWindow {
+				delete(d.lastSent, k)
+			}
+		}
+	}
+	notifiers := append([]Notifier(nil), d.notifiers...)
+	d.mu.Unlock()
+
+	for _, n := range notifiers {
+		d.wg.Add(1)
+		go func(n Notifier) {
+			defer d.wg.Done()
+			if err := n.Notify(event); err != nil {
+				log.Warn().Err(err).Str("notifier", n.Name()).Str("event", event.Type).Msg("notification failed")
+			}
+		}(n)
+	}
+}
+
+// Wait blocks until in-flight notifications finish (used on shutdown and in tests).
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
@@ -0,0 +1,79 @@
+// Package notifications delivers gateway events to external services.
+//
+// Each integration implements Notifier. The Dispatcher fans an event out to
+// every notifier enabled in config.NotificationsConfig (Slack, Discord, and a
+// generic JSON webhook for PagerDuty/Teams/custom receivers).
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Event types emitted by the gateway.
+const (
+	EventBudgetExceeded = "budget_exceeded"
+	EventPanic          = "panic"
+)
+
+// requestTimeout bounds each outbound notification.
+const requestTimeout = 10 * time.Second
+
+// Event is a gateway occurrence worth telling someone about.
+// It is also the JSON body sent by the generic webhook notifier.
+type Event struct {
+	Type      string         `json:"type"`
+	Timestamp time.Time      `json:"timestamp"`
+	SessionID string         `json:"session_id,omitempty"`
+	Message   string         `json:"message"`
+	Details   map[string]any `json:"details,omitempty"`
+}
+
+// Notifier sends events to a single destination.
+type Notifier interface {
+	// Name identifies the notifier in logs (e.g. "slack").
+	Name() string
+	// Notify delivers the event. It may block for up to requestTimeout.
+	Notify(event Event) error
+}
+
+// postJSON marshals body and POSTs it to url with optional extra headers.
+func postJSON(client *http.Client, url string, body any, headers map[string]string) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// formatText renders an event as a one-line chat message.
+func formatText(event Event) string {
+	text := fmt.Sprintf("[context-gateway] %s: %s", event.Type, event.Message)
+	if event.SessionID != "" {
+		text += fmt.Sprintf(" (session %s)", event.SessionID)
+	}
+	return text
+}
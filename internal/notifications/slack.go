@@ -0,0 +1,22 @@
+package notifications
+
+import "net/http"
+
+// SlackNotifier posts events to a Slack incoming webhook.
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a Slack notifier for the given incoming webhook URL.
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{webhookURL: webhookURL, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Name returns "slack".
+func (s *SlackNotifier) Name() string { return "slack" }
+
+// Notify sends the event as a Slack text message.
+func (s *SlackNotifier) Notify(event Event) error {
+	return postJSON(s.client, s.webhookURL, map[string]string{"text": formatText(event)}, nil)
+}
@@ -0,0 +1,24 @@
+package notifications
+
+import "net/http"
+
+// WebhookNotifier POSTs the raw Event as JSON to a user-supplied URL.
+// Headers are sent verbatim (e.g. Authorization, routing keys).
+type WebhookNotifier struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhookNotifier creates a generic JSON webhook notifier.
+func NewWebhookNotifier(url string, headers map[string]string) *WebhookNotifier {
+	return &WebhookNotifier{url: url, headers: headers, client: &http.Client{Timeout: requestTimeout}}
+}
+
+// Name returns "webhook".
+func (w *WebhookNotifier) Name() string { return "webhook" }
+
+// Notify sends the event as JSON.
+func (w *WebhookNotifier) Notify(event Event) error {
+	return postJSON(w.client, w.url, event, w.headers)
+}
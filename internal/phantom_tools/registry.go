@@ -80,7 +80,24 @@ func DetectFormat(body []byte, provider adapters.Provider) ProviderFormat {
 }
 
 // InjectAll injects all registered phantom tools into the request body.
+// Requests whose tool_choice is "none" or forces a non-phantom tool are returned
+// unchanged: the model could not call a phantom tool anyway (see AllowsPhantomTools).
 func InjectAll(body []byte, provider adapters.Provider) ([]byte, error) {
+	body, _, err := InjectAllIfAllowed(body, provider)
+	return body, err
+}
+
+// InjectAllIfAllowed is InjectAll that also reports whether the tool_choice
+// allowed injection (false when the body was returned unchanged for it).
+func InjectAllIfAllowed(body []byte, provider adapters.Provider) ([]byte, bool, error) {
+	if !AllowsPhantomTools(body) {
+		return body, false, nil
+	}
+	body, err := injectAll(body, provider)
+	return body, true, err
+}
+
+func injectAll(body []byte, provider adapters.Provider) ([]byte, error) {
 	registry.mu.RLock()
 	defer registry.mu.RUnlock()
 
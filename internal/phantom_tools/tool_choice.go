@@ -0,0 +1,80 @@
+package phantom_tools
+
+import (
+	"github.com/tidwall/gjson"
+)
+
+// ToolChoiceMode classifies a request's tool_choice for phantom tool injection.
+type ToolChoiceMode int
+
+const (
+	// ToolChoiceAuto covers a missing tool_choice, "auto", and "any"/"required":
+	// the model may pick any tool, including a phantom one.
+	ToolChoiceAuto ToolChoiceMode = iota
+
+	// ToolChoiceNone disables tool calls for this turn.
+	ToolChoiceNone
+
+	// ToolChoiceForced pins the model to a specific tool (or an allow-list).
+	ToolChoiceForced
+)
+
+// ToolChoice is the parsed tool_choice of a request.
+type ToolChoice struct {
+	Mode ToolChoiceMode
+	Name string // forced tool name (ToolChoiceForced only; empty for hosted tools)
+}
+
+// ParseToolChoice reads tool_choice in Anthropic, OpenAI Chat and OpenAI Responses shapes:
+//
+//	Anthropic:  {"type":"auto"|"any"|"none"} or {"type":"tool","name":"X"}
+//	OpenAI:     "auto"|"required"|"none" or {"type":"function","function":{"name":"X"}}
+//	Responses:  {"type":"function","name":"X"} or {"type":"allowed_tools","tools":[...]}
+func ParseToolChoice(body []byte) ToolChoice {
+	tc := gjson.GetBytes(body, "tool_choice")
+	if !tc.Exists() {
+		return ToolChoice{Mode: ToolChoiceAuto}
+	}
+
+	if tc.Type == gjson.String {
+		if tc.String() == "none" {
+			return ToolChoice{Mode: ToolChoiceNone}
+		}
+		return ToolChoice{Mode: ToolChoiceAuto}
+	}
+
+	switch tc.Get("type").String() {
+	case "", "auto", "any", "required":
+		return ToolChoice{Mode: ToolChoiceAuto}
+	case "none":
+		return ToolChoice{Mode: ToolChoiceNone}
+	case "tool": // Anthropic
+		return ToolChoice{Mode: ToolChoiceForced, Name: tc.Get("name").String()}
+	case "function": // OpenAI Chat nests the name, Responses does not
+		name := tc.Get("function.name").String()
+		if name == "" {
+			name = tc.Get("name").String()
+		}
+		return ToolChoice{Mode: ToolChoiceForced, Name: name}
+	case "allowed_tools": // Responses: restricted to a list
+		return ToolChoice{Mode: ToolChoiceForced}
+	default: // hosted tools (file_search, web_search_preview, ...)
+		return ToolChoice{Mode: ToolChoiceForced}
+	}
+}
+
+// AllowsPhantomTools reports whether the model could call a phantom tool under
+// the request's tool_choice. A forced choice of another tool, or "none", means
+// an injected phantom tool is unreachable, so injection is skipped and the
+// client's tool_choice is forwarded untouched.
+func AllowsPhantomTools(body []byte) bool {
+	choice := ParseToolChoice(body)
+	switch choice.Mode {
+	case ToolChoiceNone:
+		return false
+	case ToolChoiceForced:
+		return GetByName(choice.Name) != nil
+	default:
+		return true
+	}
+}
@@ -3,6 +3,7 @@ package pipes
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -25,6 +26,9 @@ const (
 	MaxTargetCompressionRatio = 0.9
 )
 
+// MaxCompressionRetriesLimit caps compresr.max_compression_retries; each retry is a paid API call.
+const MaxCompressionRetriesLimit = 3
+
 // STRATEGY CONSTANTS
 
 // Strategy constants for pipe execution.
@@ -39,6 +43,19 @@ const (
 	StrategyCompresr = "compresr" // Alias for StrategyAPI (backward compat)
 	StrategySimple   = "simple"   // Simple compression (first N words)
 	StrategyTrimming = "trimming" // Tail-keep compression: discard head, keep only tail based on target_compression_ratio
+	StrategyLocal    = "local"    // fallback_chain level: local heuristic compression (same as trimming)
+)
+
+// detect_injection modes (empty = off).
+const (
+	InjectionWarn = "warn" // Log a warning for tool outputs that look like prompt injection
+	InjectionWrap = "wrap" // Also wrap them in an untrusted-tool-output delimiter
+)
+
+// expand_hint_position values.
+const (
+	ExpandHintBefore = "before" // Hint line precedes the [REF:id] block
+	ExpandHintAfter  = "after"  // Hint line follows the compressed content
 )
 
 // IsAPIStrategy returns true if the strategy is API-based (tool output only).
@@ -77,6 +94,11 @@ type ToolOutputConfig struct {
 	Strategy         string `yaml:"strategy"`          // passthrough | compresr | external_provider
 	FallbackStrategy string `yaml:"fallback_strategy"` // Fallback when primary fails
 
+	// FallbackChain lists fallback levels tried in order when the primary strategy
+	// fails (e.g. [local, passthrough]). Levels: local, simple, trimming, passthrough.
+	// Overrides fallback_strategy when set.
+	FallbackChain []string `yaml:"fallback_chain,omitempty"`
+
 	// Provider reference (preferred over inline Compresr config)
 	// References a provider defined in the top-level "providers" section.
 	Provider string `yaml:"provider,omitempty"`
@@ -89,12 +111,101 @@ type ToolOutputConfig struct {
 	MinTokens              int     `yaml:"min_tokens"`               // Below this token count, no compression (default: 512)
 	MaxTokens              int     `yaml:"max_tokens"`               // Above this token count, skip compression (default: 50000)
 	TargetCompressionRatio float64 `yaml:"target_compression_ratio"` // Sent to API: 0.1 = least aggressive, 0.9 = most aggressive. 0 = API default.
-	RefusalThreshold       float64 `yaml:"refusal_threshold"`        // Reject compression if token savings < this ratio (default: 0.05 = must save at least 5%)
+	RefusalThreshold       float64 `yaml:"refusal_threshold"`        // Reject compression if token savings, net of the shadow marker, < this ratio (default: 0.05 = must save at least 5%)
 
 	// Expand context feature
 	EnableExpandContext bool `yaml:"enable_expand_context"` // Inject expand_context tool
 	IncludeExpandHint   bool `yaml:"include_expand_hint"`   // Add hint to compressed content
 
+	// ExpandHintTemplate replaces the built-in expand hint text. {{shadow_id}} and
+	// {{original_bytes}} are substituted per block. Empty = built-in hint.
+	// Must be a single line when placed before the [REF:] block.
+	ExpandHintTemplate string `yaml:"expand_hint_template,omitempty"`
+	// ExpandHintPosition places the hint "before" the [REF:] block or "after" the
+	// compressed content. Empty = before for the built-in hint, after for a template.
+	ExpandHintPosition string `yaml:"expand_hint_position,omitempty"`
+
+	// LossyMode compresses tool outputs in place and keeps nothing to expand them:
+	// originals are not stored, no [REF:id] markers or hints are added, and the
+	// expand_context tool is not injected. Unlike passthrough the content is still
+	// compressed; unlike the default mode the model cannot recover the original.
+	// Cannot be combined with enable_expand_context or include_expand_hint.
+	LossyMode bool `yaml:"lossy_mode,omitempty"`
+
+	// ExpandNotFoundMessage is the tool_result returned when expand_context asks for
+	// an ID that was never compressed or has expired; {id} is replaced with the ID.
+	// Empty = a built-in message telling the model not to retry.
+	ExpandNotFoundMessage string `yaml:"expand_not_found_message,omitempty"`
+
+	// ExpandUseCompressedOnMissingOriginal answers expand_context with the cached
+	// compressed version (prefixed with an expiry note) when the original has
+	// expired but the compressed copy is still stored, instead of not-found.
+	ExpandUseCompressedOnMissingOriginal bool `yaml:"expand_use_compressed_on_missing_original,omitempty"`
+
+	// DedupeIdentical replaces repeated identical tool outputs within one request
+	// with a back-reference to the first occurrence (local, no API call).
+	DedupeIdentical bool `yaml:"dedupe_identical"`
+
+	// DedupeToolUseIDs drops all but the last tool result for a tool_use_id that
+	// is answered more than once in the same turn, logging a warning. Providers
+	// reject such requests. Applied for every strategy, including passthrough.
+	DedupeToolUseIDs bool `yaml:"dedupe_tool_use_ids,omitempty"`
+
+	// CanonicalizeJSON hashes JSON tool outputs by their canonical form (sorted
+	// keys, no insignificant whitespace), so outputs differing only in key order
+	// or formatting share a shadow ID, compressed-cache entry and dedupe reference.
+	// Non-JSON content hashes as-is. The content sent and stored is unchanged.
+	CanonicalizeJSON bool `yaml:"canonicalize_json,omitempty"`
+
+	// CompressToolInputs also compresses large string arguments of earlier tool
+	// calls in the history (e.g. a write_file content argument), storing originals
+	// for expand_context. The latest assistant turn — the action being executed —
+	// is never touched. Same thresholds and strategy as tool outputs.
+	CompressToolInputs bool `yaml:"compress_tool_inputs,omitempty"`
+
+	// CompressTopK, when > 0, compresses only the K largest eligible outputs per request.
+	// Eligibility (skip_tools, content_formats, min/max_tokens) is applied first;
+	// the remaining outputs pass through verbatim. 0 = compress every eligible output.
+	CompressTopK int `yaml:"compress_top_k"`
+
+	// MaxAdvertisedShadows, when > 0, keeps the expand_context hint line on at most
+	// K compressed blocks per request (the largest originals, like compress_top_k).
+	// The rest keep only their [REF:id] prefix and stay expandable by ID.
+	// 0 = hint every block.
+	MaxAdvertisedShadows int `yaml:"max_advertised_shadows,omitempty"`
+
+	// MaxCompressionCallsPerSession and MaxCompressionBytesPerSession cap the
+	// blocks and original bytes a session may send to the compression API
+	// (strategies compresr and external_provider). Once either is reached, further
+	// eligible outputs go to the fallback chain. Sessions are identified by the
+	// hash of the first user message. 0 = unlimited.
+	MaxCompressionCallsPerSession int   `yaml:"max_compression_calls_per_session,omitempty"`
+	MaxCompressionBytesPerSession int64 `yaml:"max_compression_bytes_per_session,omitempty"`
+
+	// KeepTailBytes, when > 0, makes local truncation (simple, trimming and the
+	// local fallback levels) keep the last N bytes of the original verbatim, so
+	// final summary lines and last errors survive. 0 = strategy default.
+	KeepTailBytes int `yaml:"keep_tail_bytes,omitempty"`
+
+	// EmptyOutputPlaceholder, when set, replaces empty or whitespace-only tool results
+	// with this text (e.g. "(no results)") so the model does not see a blank output.
+	// Applied for every strategy, including passthrough. Empty = leave outputs as-is.
+	EmptyOutputPlaceholder string `yaml:"empty_output_placeholder,omitempty"`
+
+	// DetectInjection scans tool outputs for text addressed to the model (e.g.
+	// "ignore previous instructions"). "warn" logs flagged outputs; "wrap" also
+	// encloses them in an untrusted-tool-output delimiter and leaves them
+	// uncompressed. Applied for every strategy. Empty = off.
+	DetectInjection string `yaml:"detect_injection,omitempty"`
+
+	// AddResponseHeaders adds X-CG-* headers to the client response summarizing what
+	// was compressed (blocks, original/sent bytes, expand availability). Never sent upstream.
+	AddResponseHeaders bool `yaml:"add_response_headers"`
+
+	// PreservePatterns lists regexes (or presets "error_codes", "file_paths") whose
+	// matches in the original must survive compression; otherwise the original is sent.
+	PreservePatterns []string `yaml:"preserve_patterns,omitempty"`
+
 	// BypassCostCheck disables the automatic cost-based skip (useful for testing/benchmarking).
 	// When false (default), cheap models (e.g. gpt-4o-mini) are skipped automatically.
 	BypassCostCheck bool `yaml:"bypass_cost_check"`
@@ -102,9 +213,63 @@ type ToolOutputConfig struct {
 	// Skip compression for specific tool categories (e.g., browser — real-time content)
 	SkipTools SkipToolsConfig `yaml:"skip_tools,omitempty"`
 
+	// CompressErrors controls whether tool results flagged as errors (Anthropic
+	// is_error, Gemini response.error, OpenAI "Error:"-prefixed or non-zero
+	// exit_code output) may be compressed. false = they always pass through
+	// verbatim. nil = true (see CompressErrorsEnabled).
+	CompressErrors *bool `yaml:"compress_errors,omitempty"`
+
+	// NeverCompressTools lists exact tool names (e.g. "apply_patch") whose outputs
+	// always reach the model intact, regardless of size. Unlike skip_tools there is
+	// no category mapping, and dedupe_identical does not apply either.
+	NeverCompressTools []string `yaml:"never_compress_tools,omitempty"`
+
 	// ContentFormats controls which detected text formats are eligible for compression.
 	// Default: all text-based formats (text, json, markdown) are compressed.
 	ContentFormats ContentFormatsConfig `yaml:"content_formats,omitempty"`
+
+	// Hierarchical compresses outputs above max_tokens in chunks instead of
+	// passing them through (see HierarchicalConfig).
+	Hierarchical HierarchicalConfig `yaml:"hierarchical,omitempty"`
+}
+
+// DefaultHierarchicalMaxChunks caps the chunks one output is split into.
+const DefaultHierarchicalMaxChunks = 64
+
+// HierarchicalConfig configures chunked compression of very large tool outputs.
+// An output above max_tokens is split at line boundaries into chunks of at most
+// max_tokens, each chunk is compressed in order, and the summaries are joined
+// with part markers. The full original is stored for expand_context as usual.
+type HierarchicalConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// MaxChunks: outputs needing more chunks still pass through (default: 64).
+	MaxChunks int `yaml:"max_chunks,omitempty"`
+	// MergeSummaries compresses the joined chunk summaries once more when they
+	// are still above max_tokens.
+	MergeSummaries bool `yaml:"merge_summaries,omitempty"`
+}
+
+// EffectiveMaxChunks returns max_chunks, or the default when unset.
+func (h HierarchicalConfig) EffectiveMaxChunks() int {
+	if h.MaxChunks <= 0 {
+		return DefaultHierarchicalMaxChunks
+	}
+	return h.MaxChunks
+}
+
+// CompressErrorsEnabled reports whether error-flagged tool results may be
+// compressed (the default).
+func (t ToolOutputConfig) CompressErrorsEnabled() bool {
+	return t.CompressErrors == nil || *t.CompressErrors
+}
+
+// ExpandHintAppended reports whether the expand hint goes after the compressed
+// content rather than before its [REF:id] line.
+func (t ToolOutputConfig) ExpandHintAppended() bool {
+	if t.ExpandHintPosition == "" {
+		return t.ExpandHintTemplate != ""
+	}
+	return t.ExpandHintPosition == ExpandHintAfter
 }
 
 // ContentFormatsConfig narrows which text formats are eligible for compression.
@@ -130,6 +295,65 @@ func (t *ToolOutputConfig) Validate() error {
 		return fmt.Errorf("tool_output: target_compression_ratio must be between %.1f (least aggressive) and %.1f (most aggressive), got %.2f",
 			MinTargetCompressionRatio, MaxTargetCompressionRatio, t.TargetCompressionRatio)
 	}
+	if t.CompressTopK < 0 {
+		return fmt.Errorf("tool_output: compress_top_k must be >= 0, got %d", t.CompressTopK)
+	}
+	if t.MaxAdvertisedShadows < 0 {
+		return fmt.Errorf("tool_output: max_advertised_shadows must be >= 0, got %d", t.MaxAdvertisedShadows)
+	}
+	if t.MaxCompressionCallsPerSession < 0 {
+		return fmt.Errorf("tool_output: max_compression_calls_per_session must be >= 0, got %d", t.MaxCompressionCallsPerSession)
+	}
+	if t.MaxCompressionBytesPerSession < 0 {
+		return fmt.Errorf("tool_output: max_compression_bytes_per_session must be >= 0, got %d", t.MaxCompressionBytesPerSession)
+	}
+	if t.Compresr.MaxCompressionRetries < 0 || t.Compresr.MaxCompressionRetries > MaxCompressionRetriesLimit {
+		return fmt.Errorf("tool_output: compresr.max_compression_retries must be between 0 and %d, got %d",
+			MaxCompressionRetriesLimit, t.Compresr.MaxCompressionRetries)
+	}
+	switch t.DetectInjection {
+	case "", InjectionWarn, InjectionWrap:
+	default:
+		return fmt.Errorf("tool_output: unknown detect_injection mode %q, must be 'warn' or 'wrap'", t.DetectInjection)
+	}
+	if t.LossyMode && (t.EnableExpandContext || t.IncludeExpandHint) {
+		return fmt.Errorf("tool_output: lossy_mode cannot be combined with enable_expand_context or include_expand_hint (no originals are kept to expand)")
+	}
+	switch t.ExpandHintPosition {
+	case "", ExpandHintBefore, ExpandHintAfter:
+	default:
+		return fmt.Errorf("tool_output: unknown expand_hint_position %q, must be 'before' or 'after'", t.ExpandHintPosition)
+	}
+	// A replayed block is recognised by its [REF:id] on the first or second
+	// line, so a hint placed before it must be a single line.
+	if !t.ExpandHintAppended() && strings.Contains(t.ExpandHintTemplate, "\n") {
+		return fmt.Errorf("tool_output: expand_hint_template must be a single line when expand_hint_position is 'before'")
+	}
+	if t.KeepTailBytes < 0 {
+		return fmt.Errorf("tool_output: keep_tail_bytes must be >= 0, got %d", t.KeepTailBytes)
+	}
+	if t.Hierarchical.MaxChunks < 0 {
+		return fmt.Errorf("tool_output: hierarchical.max_chunks must be >= 0, got %d", t.Hierarchical.MaxChunks)
+	}
+	for _, level := range t.FallbackChain {
+		switch level {
+		case StrategyLocal, StrategySimple, StrategyTrimming, StrategyPassthrough:
+		default:
+			return fmt.Errorf("tool_output: unknown fallback_chain level %q, must be 'local', 'simple', 'trimming', or 'passthrough'", level)
+		}
+	}
+	if t.Compresr.MaxConcurrency < 0 {
+		return fmt.Errorf("tool_output: compresr.max_concurrency must be >= 0, got %d", t.Compresr.MaxConcurrency)
+	}
+	if t.Compresr.MaxQueueDepth < 0 {
+		return fmt.Errorf("tool_output: compresr.max_queue_depth must be >= 0, got %d", t.Compresr.MaxQueueDepth)
+	}
+	if err := t.Compresr.Timeouts.Validate("tool_output: compresr.timeouts"); err != nil {
+		return err
+	}
+	if _, err := CompilePreservePatterns(t.PreservePatterns); err != nil {
+		return fmt.Errorf("tool_output: %w", err)
+	}
 	if t.Strategy == "" || t.Strategy == StrategyPassthrough {
 		return nil
 	}
@@ -284,14 +508,51 @@ type SkipToolsConfig struct {
 	Categories []string `yaml:"categories,omitempty"`
 }
 
-// CompresrConfig contains settings for calling the Compresr compression API.
-// Not used in current release - tool output compression is disabled.
+// CompresrConfig contains settings for calling the compression API: endpoint,
+// credentials and timeouts, plus, for tool_output, how calls are scheduled
+// (max_concurrency, max_queue_depth) and retried (max_compression_retries).
 type CompresrConfig struct {
 	Endpoint      string        `yaml:"endpoint"`       // Compresr API endpoint URL
 	APIKey        string        `yaml:"api_key"`        // API authentication key
 	Model         string        `yaml:"model"`          // Compression model to use
-	Timeout       time.Duration `yaml:"timeout"`        // Request timeout
+	Timeout       time.Duration `yaml:"timeout"`        // Request timeout (legacy alias for timeouts.overall)
 	QueryAgnostic bool          `yaml:"query_agnostic"` // If true, compression is context-agnostic
+
+	// ModelByFormat picks the compression model by request format (adapter
+	// name: "anthropic", "openai", ...); formats not listed use Model.
+	// tool_output only.
+	ModelByFormat map[string]string `yaml:"model_by_format,omitempty"`
+
+	// Per-phase timeouts for the compression call. timeouts.overall falls back to timeout.
+	Timeouts TimeoutsConfig `yaml:"timeouts,omitempty"`
+
+	// Concurrency control for compression API calls (tool_output only)
+	MaxConcurrency int `yaml:"max_concurrency"` // Max parallel API calls (0 = default 10)
+	MaxQueueDepth  int `yaml:"max_queue_depth"` // Max calls waiting for a slot before falling back (0 = default 128)
+
+	// MaxCompressionRetries enables adaptive targeting (tool_output, compresr strategy):
+	// when a result misses target_compression_ratio, the call is retried up to this
+	// many times with a more aggressive target, keeping the smallest result. Each
+	// retry counts against max_compression_calls_per_session. 0 = off.
+	MaxCompressionRetries int `yaml:"max_compression_retries,omitempty"`
+
+	// DebugDumpDir, when set, receives every request sent to the compression API
+	// and the raw response, one file pair per call, with credentials scrubbed.
+	// For diagnosing backend issues; dumps hold tool output, so keep it off.
+	DebugDumpDir string `yaml:"debug_dump_dir,omitempty"`
+}
+
+// EffectiveTimeouts resolves per-phase timeouts, using the legacy timeout field
+// (or defaultOverall when unset) as the overall deadline.
+func (c CompresrConfig) EffectiveTimeouts(defaultOverall time.Duration) TimeoutsConfig {
+	t := c.Timeouts
+	if t.Overall <= 0 {
+		t.Overall = c.Timeout
+	}
+	if t.Overall <= 0 {
+		t.Overall = defaultOverall
+	}
+	return t
 }
 
 // TASK OUTPUT PIPE CONFIG
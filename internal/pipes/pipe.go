@@ -98,6 +98,14 @@ type ToolOutputCompression struct {
 	QueryAgnostic     bool   `json:"query_agnostic"` // Whether compression used empty query
 	OriginalContent   string `json:"original_content"`
 	CompressedContent string `json:"compressed_content"`
+
+	// Adaptive targeting (compresr.max_compression_retries)
+	Attempts     int  `json:"attempts,omitempty"`      // Compression API calls made
+	TargetMissed bool `json:"target_missed,omitempty"` // Best result still missed target_compression_ratio
+
+	// BudgetExhausted marks an output sent to the fallback chain because the
+	// session reached max_compression_calls/bytes_per_session.
+	BudgetExhausted bool `json:"budget_exhausted,omitempty"`
 }
 
 // NewPipeContext creates a new pipe context.
@@ -0,0 +1,32 @@
+package pipes
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// PreservePatternPresets are named shortcuts accepted in tool_output.preserve_patterns.
+// Any other entry is compiled as a Go regular expression.
+var PreservePatternPresets = map[string]string{
+	// ENOENT, ECONNREFUSED, TS2304, E0308, HTTP-502, DB_ERROR_TIMEOUT
+	"error_codes": `\b(?:E[A-Z]{3,}|[A-Z]+-?[0-9]{3,5}|[A-Z][A-Z0-9]*_ERR(?:OR)?[A-Z0-9_]*)\b`,
+	// src/main.go, ./cmd/app/config.yaml, /var/log/syslog.1
+	"file_paths": `(?:\.{1,2}/|/)?(?:[\w.-]+/)+[\w-]+\.[\w]+`,
+}
+
+// CompilePreservePatterns resolves presets and compiles the remaining entries.
+func CompilePreservePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		expr := p
+		if preset, ok := PreservePatternPresets[p]; ok {
+			expr = preset
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid preserve pattern %q: %w", p, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,61 @@
+package pipes
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Dial and TLS handshake limits when no connect timeout is set: those of
+// http.DefaultTransport, which outbound calls used before connect existed.
+const (
+	DefaultDialTimeout         = 30 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// TimeoutsConfig splits an outbound HTTP call's deadline into phases, so an
+// unreachable host fails on connect instead of consuming the overall budget.
+type TimeoutsConfig struct {
+	Connect   time.Duration `yaml:"connect,omitempty"`    // TCP dial + TLS handshake (0 = 30s dial, 10s handshake)
+	FirstByte time.Duration `yaml:"first_byte,omitempty"` // Request sent → response headers (0 = bounded by overall only)
+	Overall   time.Duration `yaml:"overall,omitempty"`    // Whole call including body (0 = no limit)
+}
+
+// Validate rejects negative durations. field prefixes the error (e.g. "server.upstream_timeouts").
+func (t TimeoutsConfig) Validate(field string) error {
+	if t.Connect < 0 || t.FirstByte < 0 || t.Overall < 0 {
+		return fmt.Errorf("%s: timeouts must be >= 0", field)
+	}
+	return nil
+}
+
+// ConnectLimit is the longest connection setup may take: Connect, or the
+// default dial plus TLS handshake when unset.
+func (t TimeoutsConfig) ConnectLimit() time.Duration {
+	if t.Connect > 0 {
+		return t.Connect
+	}
+	return DefaultDialTimeout + DefaultTLSHandshakeTimeout
+}
+
+// ApplyTo sets dial, TLS handshake, and response header timeouts on tr.
+func (t TimeoutsConfig) ApplyTo(tr *http.Transport) {
+	dial, handshake := t.Connect, t.Connect
+	if t.Connect <= 0 {
+		dial, handshake = DefaultDialTimeout, DefaultTLSHandshakeTimeout
+	}
+	tr.DialContext = (&net.Dialer{
+		Timeout:   dial,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	tr.TLSHandshakeTimeout = handshake
+	tr.ResponseHeaderTimeout = t.FirstByte
+}
+
+// NewHTTPClient returns a client with its own transport configured from t.
+func (t TimeoutsConfig) NewHTTPClient() *http.Client {
+	tr := http.DefaultTransport.(*http.Transport).Clone()
+	t.ApplyTo(tr)
+	return &http.Client{Transport: tr, Timeout: t.Overall}
+}
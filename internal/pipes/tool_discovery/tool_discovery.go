@@ -2,6 +2,7 @@
 package tooldiscovery
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -104,10 +105,8 @@ func New(cfg *config.Config) *Pipe {
 			compresrEndpoint = strings.TrimRight(cfg.URLs.Compresr, "/") + "/api/compress/tool-discovery/"
 		}
 	}
-	compresrTimeout := cfg.Pipes.ToolDiscovery.Compresr.Timeout
-	if compresrTimeout <= 0 {
-		compresrTimeout = 10 * time.Second
-	}
+	compresrTimeouts := cfg.Pipes.ToolDiscovery.Compresr.EffectiveTimeouts(10 * time.Second)
+	compresrTimeout := compresrTimeouts.Overall
 
 	// Initialize Compresr client for API-backed strategies (compresr + tool-search).
 	var compresrClient *compresr.Client
@@ -116,7 +115,9 @@ func New(cfg *config.Config) *Pipe {
 		baseURL := cfg.URLs.Compresr
 		compresrKey := cfg.Pipes.ToolDiscovery.Compresr.APIKey
 		if baseURL != "" || compresrKey != "" {
-			compresrClient = compresr.NewClient(baseURL, compresrKey, compresr.WithTimeout(compresrTimeout))
+			compresrClient = compresr.NewClient(baseURL, compresrKey,
+				compresr.WithHTTPClient(compresrTimeouts.NewHTTPClient()),
+				compresr.WithDebugDumpDir(cfg.Pipes.ToolDiscovery.Compresr.DebugDumpDir))
 			log.Info().Str("base_url", baseURL).Str("strategy", tdStrategy).Msg("tool_discovery: initialized Compresr client")
 		} else {
 			log.Debug().Str("strategy", tdStrategy).Msg("tool_discovery: API strategy without Compresr credentials, will use local fallback")
@@ -435,7 +436,11 @@ func (p *Pipe) filterViaCompresr(ctx *pipes.PipeContext) ([]byte, error) {
 		toolDefs = append(toolDefs, def)
 	}
 
-	filterResp, err := p.compresrClient.FilterTools(compresr.FilterToolsParams{
+	reqCtx := ctx.RequestCtx
+	if reqCtx == nil {
+		reqCtx = context.Background()
+	}
+	filterResp, err := p.compresrClient.FilterToolsContext(reqCtx, compresr.FilterToolsParams{
 		Query:      query,
 		AlwaysKeep: p.alwaysKeepList,
 		Tools:      toolDefs,
@@ -0,0 +1,104 @@
+package tooloutput
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/pipes"
+	"github.com/compresr/context-gateway/internal/tokenizer"
+)
+
+// compressAdaptive calls the Compresr API and, while the result removes less
+// than target_compression_ratio, retries with a more aggressive target up to
+// compresr.max_compression_retries times. The smallest result is returned;
+// targetMissed reports that even it fell short. A failed retry keeps the best
+// result so far — only a failure of the first call is returned as an error.
+// Each retry is charged to sessionID's budget and takes a rate-limiter token;
+// when either is refused the best result so far is kept.
+func (p *Pipe) compressAdaptive(reqCtx context.Context, sessionID, query, content, toolName, provider string) (best string, attempts int, targetMissed bool, err error) {
+	goal := p.targetCompressionRatio
+	if goal == 0 {
+		goal = pipes.DefaultTargetCompressionRatio
+	}
+	origTokens := tokenizer.CountTokens(content)
+
+	target := p.targetCompressionRatio
+	bestTokens := 0
+	for {
+		compressed, callErr := p.compressViaCompresr(reqCtx, query, content, toolName, provider, target)
+		attempts++
+		if callErr != nil {
+			if best == "" {
+				return "", attempts, false, callErr
+			}
+			log.Debug().Err(callErr).Str("tool", toolName).Msg("tool_output: adaptive retry failed, keeping best result")
+			break
+		}
+
+		tokens := tokenizer.CountTokens(compressed)
+		if best == "" || tokens < bestTokens {
+			best, bestTokens = compressed, tokens
+		}
+		if tokenizer.CompressionRatio(origTokens, bestTokens) >= goal ||
+			attempts > p.maxCompressionRetries || target >= pipes.MaxTargetCompressionRatio {
+			break
+		}
+
+		if !p.chargeRetry(sessionID, len(content)) {
+			log.Debug().Str("tool", toolName).Msg("tool_output: adaptive retry over budget or rate limited, keeping best result")
+			break
+		}
+		target = strongerTarget(target)
+		p.recordAdaptiveRetry()
+		log.Debug().
+			Str("tool", toolName).
+			Int("original_tokens", origTokens).
+			Int("compressed_tokens", tokens).
+			Float64("goal", goal).
+			Float64("next_target", target).
+			Msg("tool_output: compression missed target, retrying more aggressively")
+	}
+
+	targetMissed = tokenizer.CompressionRatio(origTokens, bestTokens) < goal
+	if targetMissed {
+		p.recordTargetMissed()
+	}
+	return best, attempts, targetMissed, nil
+}
+
+// chargeRetry reserves one session budget call and a rate-limiter token for an
+// adaptive retry. The retry runs in the concurrency slot the first attempt
+// still holds, so it takes no slot of its own — waiting for a second one could
+// block forever at max_concurrency 1.
+func (p *Pipe) chargeRetry(sessionID string, size int) bool {
+	if !p.budget.charge(sessionID, size) {
+		return false
+	}
+	if p.rateLimiter != nil && !p.rateLimiter.Acquire() {
+		p.recordRateLimited()
+		return false
+	}
+	return true
+}
+
+// strongerTarget moves target halfway towards removing everything, capped at
+// the most aggressive ratio the API accepts (0.5 → 0.75 → 0.875 → 0.9).
+func strongerTarget(target float64) float64 {
+	if target == 0 {
+		target = pipes.DefaultTargetCompressionRatio
+	}
+	return min(target+(1-target)/2, pipes.MaxTargetCompressionRatio)
+}
+
+func (p *Pipe) recordAdaptiveRetry() {
+	p.mu.Lock()
+	p.metrics.AdaptiveRetries++
+	p.mu.Unlock()
+}
+
+func (p *Pipe) recordTargetMissed() {
+	p.mu.Lock()
+	p.metrics.TargetMissed++
+	p.mu.Unlock()
+}
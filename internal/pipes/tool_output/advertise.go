@@ -0,0 +1,179 @@
+package tooloutput
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/pipes"
+)
+
+// limitAdvertised enforces max_advertised_shadows: only K blocks keep the
+// expand_context hint line. The others drop the hint but keep their [REF:id]
+// prefix and stored original, so expand_context still resolves them by ID.
+//
+// The decision is sticky per session and shadow ID: a block replayed from
+// history keeps the bytes it was first sent with, so the prompt cache prefix
+// stays stable. Only blocks seen for the first time are ranked, for the slots
+// the sticky hinted blocks leave free (largest originals first, ties go to
+// the later block).
+func (p *Pipe) limitAdvertised(ctx *pipes.PipeContext, results []adapters.CompressedResult) {
+	if p.maxAdvertisedShadows <= 0 {
+		return
+	}
+
+	// Keyed by shadow ID: a call's input and output share the tool call ID.
+	originalTokens := make(map[string]int, len(ctx.ToolOutputCompressions))
+	for _, tc := range ctx.ToolOutputCompressions {
+		if tc.ShadowID != "" {
+			originalTokens[tc.ShadowID] = tc.OriginalTokens
+		}
+	}
+
+	type hinted struct {
+		index  int
+		tokens int
+	}
+	var fresh []hinted
+	var drop []int
+	slots := p.maxAdvertisedShadows
+	for i, r := range results {
+		if r.ShadowRef == "" {
+			continue
+		}
+		if _, ok := p.stripExpandHint(r.Compressed, p.expandHint(r.ShadowRef, len(ctx.ShadowRefs[r.ShadowRef]))); !ok {
+			continue
+		}
+		keep, decided := p.advertised.decision(ctx.SessionID, r.ShadowRef)
+		switch {
+		case !decided:
+			fresh = append(fresh, hinted{index: i, tokens: originalTokens[r.ShadowRef]})
+		case keep:
+			slots--
+		default:
+			drop = append(drop, i)
+		}
+	}
+
+	sort.SliceStable(fresh, func(a, b int) bool {
+		if fresh[a].tokens != fresh[b].tokens {
+			return fresh[a].tokens > fresh[b].tokens
+		}
+		return fresh[a].index > fresh[b].index
+	})
+	slots = max(slots, 0)
+	for n, c := range fresh {
+		p.advertised.decide(ctx.SessionID, results[c.index].ShadowRef, n < slots)
+		if n >= slots {
+			drop = append(drop, c.index)
+		}
+	}
+	if len(drop) == 0 {
+		return
+	}
+
+	unhinted := make(map[string]string, len(drop))
+	for _, i := range drop {
+		r := &results[i]
+		r.Compressed, _ = p.stripExpandHint(r.Compressed, p.expandHint(r.ShadowRef, len(ctx.ShadowRefs[r.ShadowRef])))
+		unhinted[r.ID+"\x00"+r.ShadowRef] = r.Compressed
+	}
+	for i := range ctx.ToolOutputCompressions {
+		tc := &ctx.ToolOutputCompressions[i]
+		if content, ok := unhinted[tc.ToolCallID+"\x00"+tc.ShadowID]; ok {
+			tc.CompressedContent = content
+		}
+	}
+
+	log.Debug().
+		Int("max_advertised", p.maxAdvertisedShadows).
+		Int("unhinted", len(unhinted)).
+		Msg("tool_output: max_advertised_shadows limited expand_context hints")
+}
+
+// advertisedHints remembers, per session and shadow ID, whether a block was
+// sent with its expand_context hint. Entries idle for sessionBudgetIdleTTL are
+// swept at most once per sessionBudgetSweepInterval.
+type advertisedHints struct {
+	mu        sync.Mutex
+	hinted    map[string]*hintDecision
+	lastSweep time.Time
+}
+
+type hintDecision struct {
+	hinted   bool
+	lastSeen time.Time
+}
+
+func newAdvertisedHints() *advertisedHints {
+	return &advertisedHints{hinted: make(map[string]*hintDecision), lastSweep: time.Now()}
+}
+
+func advertisedKey(sessionID, shadowID string) string {
+	return sessionID + "\x00" + shadowID
+}
+
+// decision returns the recorded hint decision for shadowID in sessionID.
+func (a *advertisedHints) decision(sessionID, shadowID string) (hinted, ok bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	d := a.hinted[advertisedKey(sessionID, shadowID)]
+	if d == nil {
+		return false, false
+	}
+	d.lastSeen = time.Now()
+	return d.hinted, true
+}
+
+// decide records whether shadowID in sessionID keeps its hint.
+func (a *advertisedHints) decide(sessionID, shadowID string, hinted bool) {
+	now := time.Now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.Sub(a.lastSweep) >= sessionBudgetSweepInterval {
+		for key, d := range a.hinted {
+			if now.Sub(d.lastSeen) > sessionBudgetIdleTTL {
+				delete(a.hinted, key)
+			}
+		}
+		a.lastSweep = now
+	}
+	a.hinted[advertisedKey(sessionID, shadowID)] = &hintDecision{hinted: hinted, lastSeen: now}
+}
+
+// expandHint renders the hint line (without trailing newline) for one block:
+// expand_hint_template with {{shadow_id}} and {{original_bytes}} substituted,
+// or the built-in ExpandHintFormat line.
+func (p *Pipe) expandHint(shadowID string, originalBytes int) string {
+	if p.expandHintTemplate == "" {
+		return strings.TrimSuffix(fmt.Sprintf(ExpandHintFormat, shadowID), "\n")
+	}
+	return strings.NewReplacer(
+		"{{shadow_id}}", shadowID,
+		"{{original_bytes}}", strconv.Itoa(originalBytes),
+	).Replace(p.expandHintTemplate)
+}
+
+// withExpandHint places hint before the [REF:id] block or after the compressed
+// content, per expand_hint_position.
+func (p *Pipe) withExpandHint(ref, hint string) string {
+	if p.expandHintAfter {
+		return ref + "\n" + hint
+	}
+	return hint + "\n" + ref
+}
+
+// stripExpandHint removes hint from content as placed by withExpandHint,
+// reporting whether it was present.
+func (p *Pipe) stripExpandHint(content, hint string) (string, bool) {
+	if p.expandHintAfter {
+		return strings.CutSuffix(content, "\n"+hint)
+	}
+	return strings.CutPrefix(content, hint+"\n")
+}
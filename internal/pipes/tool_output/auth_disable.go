@@ -0,0 +1,97 @@
+package tooloutput
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/retry"
+)
+
+// errAPIDisabled is passed to the fallback chain for sessions whose compression
+// API credentials were rejected.
+var errAPIDisabled = errors.New("compression API disabled for session after auth failure")
+
+// authDisabledSessions records sessions that got a 401/403 from the compression
+// API. Their remaining outputs skip the API and go straight to the fallback
+// chain instead of repeating a call that cannot succeed. Entries expire after
+// sessionBudgetIdleTTL so a fixed key is eventually retried.
+type authDisabledSessions struct {
+	mu       sync.Mutex
+	disabled map[string]time.Time
+}
+
+func newAuthDisabledSessions() *authDisabledSessions {
+	return &authDisabledSessions{disabled: make(map[string]time.Time)}
+}
+
+// isDisabled reports whether sessionID has had the API disabled.
+func (a *authDisabledSessions) isDisabled(sessionID string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	at, ok := a.disabled[sessionID]
+	if ok && time.Since(at) > sessionBudgetIdleTTL {
+		delete(a.disabled, sessionID)
+		return false
+	}
+	return ok
+}
+
+// disable marks sessionID as disabled. Returns true the first time, so the
+// caller warns once per session rather than once per output.
+func (a *authDisabledSessions) disable(sessionID string) bool {
+	if a == nil {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, ok := a.disabled[sessionID]; ok {
+		return false
+	}
+	a.disabled[sessionID] = time.Now()
+	return true
+}
+
+// classifyFailure logs an API compression failure according to its retry class
+// and, for rejected credentials, disables the API for the task's session.
+// Transient failures were already retried by the client; none of the classes
+// retries here, every failure goes to the fallback chain.
+func (p *Pipe) classifyFailure(t compressionTask, err error) {
+	switch retry.Classify(err) {
+	case retry.ClassAuth:
+		p.recordAuthDisabled()
+		if p.authDisabled.disable(t.sessionID) {
+			log.Error().
+				Err(err).
+				Str("strategy", p.strategy).
+				Str("session_id", t.sessionID).
+				Str("tool", t.toolName).
+				Msg("tool_output: compression API rejected credentials (401/403) — check the compresr api_key; API compression disabled for this session, using fallback chain")
+		}
+	case retry.ClassBadRequest:
+		log.Warn().
+			Err(err).
+			Str("strategy", p.strategy).
+			Strs("fallback_chain", p.fallbackChain).
+			Str("tool", t.toolName).
+			Msg("tool_output: compression API rejected the request, not retrying; applying fallback")
+	default:
+		log.Warn().
+			Err(err).
+			Str("strategy", p.strategy).
+			Strs("fallback_chain", p.fallbackChain).
+			Str("tool", t.toolName).
+			Msg("tool_output: compression failed, applying fallback")
+	}
+}
+
+func (p *Pipe) recordAuthDisabled() {
+	p.mu.Lock()
+	p.metrics.AuthDisabled++
+	p.mu.Unlock()
+}
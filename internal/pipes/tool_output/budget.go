@@ -0,0 +1,104 @@
+package tooloutput
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// errBudgetExhausted is passed to the fallback chain when a session has used up
+// its compression budget.
+var errBudgetExhausted = errors.New("session compression budget exhausted")
+
+// Idle sessions are forgotten after sessionBudgetIdleTTL; the sweep runs at most
+// once per sessionBudgetSweepInterval.
+const (
+	sessionBudgetIdleTTL       = 6 * time.Hour
+	sessionBudgetSweepInterval = 10 * time.Minute
+)
+
+// sessionBudget tracks API compression usage per session against
+// max_compression_calls_per_session and max_compression_bytes_per_session.
+type sessionBudget struct {
+	maxCalls int
+	maxBytes int64
+
+	mu        sync.Mutex
+	usage     map[string]*sessionUsage
+	lastSweep time.Time
+}
+
+type sessionUsage struct {
+	calls    int
+	bytes    int64
+	lastSeen time.Time
+}
+
+func newSessionBudget(maxCalls int, maxBytes int64) *sessionBudget {
+	if maxCalls <= 0 && maxBytes <= 0 {
+		return nil
+	}
+	return &sessionBudget{
+		maxCalls:  maxCalls,
+		maxBytes:  maxBytes,
+		usage:     make(map[string]*sessionUsage),
+		lastSweep: time.Now(),
+	}
+}
+
+// charge reserves one API compression of size bytes for sessionID. It returns
+// false, charging nothing, when that would exceed either limit. Requests without
+// a session ID are not budgeted.
+func (b *sessionBudget) charge(sessionID string, size int) bool {
+	return b.chargeCalls(sessionID, 1, size)
+}
+
+// chargeCalls reserves calls API compressions totalling size bytes, all or
+// nothing, e.g. one call per chunk of a hierarchically compressed output.
+func (b *sessionBudget) chargeCalls(sessionID string, calls, size int) bool {
+	if b == nil || sessionID == "" {
+		return true
+	}
+	now := time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.Sub(b.lastSweep) >= sessionBudgetSweepInterval {
+		for id, u := range b.usage {
+			if now.Sub(u.lastSeen) > sessionBudgetIdleTTL {
+				delete(b.usage, id)
+			}
+		}
+		b.lastSweep = now
+	}
+
+	u := b.usage[sessionID]
+	if u == nil {
+		u = &sessionUsage{}
+		b.usage[sessionID] = u
+	}
+	u.lastSeen = now
+	if b.maxCalls > 0 && u.calls+calls > b.maxCalls {
+		return false
+	}
+	if b.maxBytes > 0 && u.bytes+int64(size) > b.maxBytes {
+		return false
+	}
+	u.calls += calls
+	u.bytes += int64(size)
+	return true
+}
+
+// usesAPI reports whether strategy calls a remote compression API, the only
+// cost the session budget limits.
+func usesAPI(strategy string) bool {
+	return strategy == config.StrategyCompresr || strategy == config.StrategyExternalProvider
+}
+
+func (p *Pipe) recordBudgetExhausted() {
+	p.mu.Lock()
+	p.metrics.BudgetExhausted++
+	p.mu.Unlock()
+}
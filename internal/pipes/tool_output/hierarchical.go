@@ -0,0 +1,168 @@
+package tooloutput
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog/log"
+
+	authtypes "github.com/compresr/context-gateway/internal/auth/types"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/tokenizer"
+)
+
+// chunkPartFormat labels each chunk summary so the model can tell the parts
+// of a hierarchically compressed output apart and keep them in order.
+const chunkPartFormat = "[part %d/%d]\n%s"
+
+// chunkOutput splits an output of tokens tokens above max_tokens into chunks
+// of about max_tokens each. Returns nil when hierarchical compression is off
+// or the output needs more than max_chunks chunks.
+func (p *Pipe) chunkOutput(content string, tokens int) []string {
+	if !p.hierarchical || p.maxTokens <= 0 || tokens <= 0 {
+		return nil
+	}
+	if (tokens+p.maxTokens-1)/p.maxTokens > p.maxChunks {
+		return nil
+	}
+	chunks := splitChunks(content, max(1, len(content)*p.maxTokens/tokens))
+	if len(chunks) > p.maxChunks {
+		return nil
+	}
+	return chunks
+}
+
+// compressChunked compresses an output above max_tokens hierarchically: each
+// of t.chunks is compressed with one call, and the summaries are joined in
+// order with part markers. With merge_summaries the joined text is compressed
+// once more when it is still above max_tokens and the session budget allows.
+// Every call takes its own rate-limiter token and concurrency slot, so chunks
+// run in parallel within max_concurrency; the session budget was charged one
+// call per chunk when the task was queued. A failed chunk fails the whole
+// output so the fallback chain handles it. The original is stored by the
+// caller as for any output.
+func (p *Pipe) compressChunked(reqCtx context.Context, query, provider string, auth authtypes.CapturedAuth, t compressionTask) (string, error) {
+	parts := make([]string, len(t.chunks))
+	errs := make([]error, len(t.chunks))
+	var wg sync.WaitGroup
+	for i, chunk := range t.chunks {
+		wg.Add(1)
+		go func(i int, chunk string) {
+			defer wg.Done()
+			if err := p.acquireCall(reqCtx); err != nil {
+				errs[i] = err
+				return
+			}
+			defer p.releaseCall()
+			parts[i], errs[i] = p.compressChunk(reqCtx, query, provider, auth, chunk, t.toolName)
+		}(i, chunk)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return "", fmt.Errorf("chunk %d/%d: %w", i+1, len(t.chunks), err)
+		}
+		parts[i] = fmt.Sprintf(chunkPartFormat, i+1, len(t.chunks), parts[i])
+	}
+	joined := strings.Join(parts, "\n\n")
+
+	if p.mergeChunkSummaries && len(t.chunks) > 1 && tokenizer.CountTokens(joined) > p.maxTokens {
+		if merged, err := p.mergeChunks(reqCtx, query, provider, auth, joined, t); err != nil {
+			// The chunk summaries are complete on their own; keep them.
+			log.Debug().Err(err).Str("tool", t.toolName).Msg("tool_output: merging chunk summaries failed, keeping parts")
+		} else {
+			joined = merged
+		}
+	}
+
+	p.recordChunkedOutput()
+	log.Debug().
+		Str("tool", t.toolName).
+		Int("chunks", len(t.chunks)).
+		Int("original_bytes", len(t.original)).
+		Int("compressed_bytes", len(joined)).
+		Msg("tool_output: compressed large output hierarchically")
+	return joined, nil
+}
+
+// mergeChunks compresses the joined chunk summaries, charging one more call
+// to the session budget.
+func (p *Pipe) mergeChunks(reqCtx context.Context, query, provider string, auth authtypes.CapturedAuth, joined string, t compressionTask) (string, error) {
+	if usesAPI(p.strategy) && !p.budget.charge(t.sessionID, len(joined)) {
+		return "", errBudgetExhausted
+	}
+	if err := p.acquireCall(reqCtx); err != nil {
+		return "", err
+	}
+	defer p.releaseCall()
+	return p.compressChunk(reqCtx, query, provider, auth, joined, t.toolName)
+}
+
+// compressChunk compresses one chunk with a single call. Adaptive retries are
+// skipped so a chunked output costs exactly the calls charged to the budget.
+func (p *Pipe) compressChunk(reqCtx context.Context, query, provider string, auth authtypes.CapturedAuth, chunk, toolName string) (string, error) {
+	if p.strategy == config.StrategyCompresr {
+		return p.compressViaCompresr(reqCtx, query, chunk, toolName, provider, p.targetCompressionRatio)
+	}
+	compressed, _, _, err := p.compressContent(reqCtx, "", query, provider, auth, chunk, toolName)
+	return compressed, err
+}
+
+// acquireCall takes a rate-limiter token and a concurrency slot for one
+// compression call; release the slot with releaseCall.
+func (p *Pipe) acquireCall(ctx context.Context) error {
+	if p.rateLimiter != nil && !p.rateLimiter.Acquire() {
+		p.recordRateLimited()
+		return fmt.Errorf("rate limited")
+	}
+	if p.semaphore == nil {
+		return nil
+	}
+	select {
+	case p.semaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipe) releaseCall() {
+	if p.semaphore != nil {
+		<-p.semaphore
+	}
+}
+
+// splitChunks splits content into chunks of at most size bytes, cutting after
+// the last newline in each window so lines stay whole. A line longer than size
+// is cut at a UTF-8 boundary. Concatenating the chunks yields content.
+func splitChunks(content string, size int) []string {
+	var chunks []string
+	for len(content) > size {
+		cut := strings.LastIndexByte(content[:size], '\n') + 1
+		if cut == 0 {
+			cut = size
+			for cut > 0 && !utf8.RuneStart(content[cut]) {
+				cut--
+			}
+			if cut == 0 { // size smaller than one rune
+				_, cut = utf8.DecodeRuneInString(content)
+			}
+		}
+		chunks = append(chunks, content[:cut])
+		content = content[cut:]
+	}
+	if content != "" {
+		chunks = append(chunks, content)
+	}
+	return chunks
+}
+
+func (p *Pipe) recordChunkedOutput() {
+	p.mu.Lock()
+	p.metrics.ChunkedOutputs++
+	p.mu.Unlock()
+}
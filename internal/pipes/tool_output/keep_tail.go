@@ -0,0 +1,40 @@
+package tooloutput
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// omittedMarker separates retained head content from the kept tail.
+const omittedMarker = "\n... omitted ...\n"
+
+// keepTail ensures a locally truncated output ends with the last keepTailBytes
+// of the original. If truncation already kept them, compressed is returned as-is;
+// otherwise the tail is appended after an omitted marker. Returns the original
+// when the result would not be smaller.
+func (p *Pipe) keepTail(original, compressed string) string {
+	n := p.keepTailBytes
+	if n <= 0 || compressed == original {
+		return compressed
+	}
+	if n >= len(original) {
+		return original
+	}
+
+	// Widen to a rune boundary so the tail is valid UTF-8
+	start := len(original) - n
+	for start > 0 && !utf8.RuneStart(original[start]) {
+		start--
+	}
+	tail := original[start:]
+	if strings.HasSuffix(compressed, tail) {
+		return compressed
+	}
+
+	// The marker replaces simple's trailing ellipsis
+	out := strings.TrimSuffix(compressed, "...") + omittedMarker + tail
+	if len(out) >= len(original) {
+		return original
+	}
+	return out
+}
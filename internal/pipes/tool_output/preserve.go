@@ -0,0 +1,16 @@
+package tooloutput
+
+import "strings"
+
+// missingPreserved returns the first preserve_patterns match in original that
+// does not appear verbatim in compressed, or "" when every match survived.
+func (p *Pipe) missingPreserved(original, compressed string) string {
+	for _, re := range p.preservePatterns {
+		for _, m := range re.FindAllString(original, -1) {
+			if !strings.Contains(compressed, m) {
+				return m
+			}
+		}
+	}
+	return ""
+}
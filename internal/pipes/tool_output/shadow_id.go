@@ -0,0 +1,61 @@
+package tooloutput
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// ShadowIDGenerator produces the shadow ID a tool output is stored under.
+// Identical content must map to the same ID: the compressed cache (KV-cache
+// preservation) and dedupe_identical both rely on it. The pipe serves concurrent
+// requests, so implementations must be safe for concurrent use.
+type ShadowIDGenerator interface {
+	ShadowID(content string) string
+}
+
+// HashShadowIDGenerator is the default generator: ShadowIDPrefix + the first
+// 16 bytes of SHA256(content), hex encoded.
+type HashShadowIDGenerator struct{}
+
+// ShadowID implements ShadowIDGenerator.
+// V2: SHA256(normalize(original)) for consistency (E22)
+func (HashShadowIDGenerator) ShadowID(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	// Use first 16 bytes (32 hex chars) - still 128 bits of entropy
+	return ShadowIDPrefix + hex.EncodeToString(hash[:16])
+}
+
+// canonicalJSON returns content re-encoded with sorted object keys and no
+// insignificant whitespace when it is a JSON object or array (canonicalize_json).
+// Numbers keep their literal text. Anything else is returned unchanged.
+func canonicalJSON(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if trimmed == "" || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return content
+	}
+	dec := json.NewDecoder(strings.NewReader(trimmed))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil || dec.More() {
+		return content
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return content
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// SetShadowIDGenerator replaces the shadow ID generator (nil restores the default).
+// Intended for tests that assert exact shadow markers; call before Process.
+func (p *Pipe) SetShadowIDGenerator(g ShadowIDGenerator) {
+	if g == nil {
+		g = HashShadowIDGenerator{}
+	}
+	p.shadowIDs = g
+}
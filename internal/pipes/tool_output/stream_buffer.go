@@ -20,6 +20,10 @@ type StreamBuffer struct {
 	currentToolID   string
 	// OpenAI streaming state: track suppress across chunks for the same tool call
 	openAIInToolUse bool
+	// Anthropic extended thinking: thinking/redacted_thinking blocks rebuilt from the
+	// stream so an expand retry can replay them ahead of the expand_context call.
+	thinkingBlocks  []map[string]any
+	currentThinking map[string]any
 }
 
 // NewStreamBuffer creates a new stream buffer.
@@ -76,6 +80,9 @@ func (sb *StreamBuffer) ProcessChunk(chunk []byte) ([]byte, error) {
 			}
 		}
 
+		// Record thinking blocks (Anthropic extended thinking); they are forwarded unchanged
+		sb.trackThinking(event)
+
 		// Check for tool_use in content_block_start (Anthropic streaming)
 		if eventType, _ := event["type"].(string); eventType == "content_block_start" {
 			if contentBlock, ok := event["content_block"].(map[string]any); ok {
@@ -188,6 +195,39 @@ func (sb *StreamBuffer) ProcessChunk(chunk []byte) ([]byte, error) {
 	return output.Bytes(), nil
 }
 
+// trackThinking rebuilds thinking and redacted_thinking blocks from Anthropic SSE events.
+func (sb *StreamBuffer) trackThinking(event map[string]any) {
+	switch event["type"] {
+	case "content_block_start":
+		block, ok := event["content_block"].(map[string]any)
+		if !ok || (block["type"] != "thinking" && block["type"] != "redacted_thinking") {
+			return
+		}
+		sb.currentThinking = make(map[string]any, len(block))
+		for k, v := range block {
+			sb.currentThinking[k] = v
+		}
+	case "content_block_delta":
+		if sb.currentThinking == nil {
+			return
+		}
+		delta, _ := event["delta"].(map[string]any)
+		switch delta["type"] {
+		case "thinking_delta":
+			text, _ := sb.currentThinking["thinking"].(string)
+			part, _ := delta["thinking"].(string)
+			sb.currentThinking["thinking"] = text + part
+		case "signature_delta":
+			sb.currentThinking["signature"] = delta["signature"]
+		}
+	case "content_block_stop":
+		if sb.currentThinking != nil {
+			sb.thinkingBlocks = append(sb.thinkingBlocks, sb.currentThinking)
+			sb.currentThinking = nil
+		}
+	}
+}
+
 // extractShadowID tries to extract the shadow ID from partial JSON input.
 func (sb *StreamBuffer) extractShadowID(partialJSON string) {
 	sb.buffer.WriteString(partialJSON)
@@ -290,6 +330,17 @@ func (sb *StreamBuffer) GetSuppressedCalls() []ExpandContextCall {
 	return result
 }
 
+// GetThinkingBlocks returns the thinking/redacted_thinking blocks seen in the stream,
+// in order. Anthropic requires them to lead the assistant turn when a tool_use from
+// the same turn is answered, so expand retries must send them back unchanged.
+func (sb *StreamBuffer) GetThinkingBlocks() []map[string]any {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	result := make([]map[string]any, len(sb.thinkingBlocks))
+	copy(result, sb.thinkingBlocks)
+	return result
+}
+
 // Reset clears the buffer state.
 func (sb *StreamBuffer) Reset() {
 	sb.mu.Lock()
@@ -300,6 +351,8 @@ func (sb *StreamBuffer) Reset() {
 	sb.openAIInToolUse = false
 	sb.currentToolName = ""
 	sb.currentToolID = ""
+	sb.thinkingBlocks = nil
+	sb.currentThinking = nil
 }
 
 // HasSuppressedCalls returns true if any expand_context calls were suppressed.
@@ -0,0 +1,41 @@
+package tooloutput
+
+import (
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/formats"
+	"github.com/compresr/context-gateway/internal/pipes"
+)
+
+// compressHistoryInputs compresses large string arguments of earlier tool calls
+// (compress_tool_inputs), e.g. the content of a write_file several turns back.
+// The adapter never returns the latest assistant turn, so the action being
+// executed reaches the LLM verbatim. Inputs go through the same pass as outputs,
+// so originals are stored for expand_context and compressed values are cached,
+// and each turn rewrites the history identically.
+func (p *Pipe) compressHistoryInputs(ctx *pipes.PipeContext, locator adapters.ToolInputAdapter) []adapters.CompressedResult {
+	extracted, err := locator.ExtractToolInputs(ctx.OriginalRequest)
+	if err != nil {
+		log.Warn().Err(err).Msg("tool_output: tool input extraction failed, skipping")
+		return nil
+	}
+	return p.compressExtracted(ctx, extracted, true)
+}
+
+// applyToolInputs writes compressed tool input arguments into body. On failure
+// the body is returned unchanged.
+func applyToolInputs(locator adapters.ToolInputAdapter, body []byte, results []adapters.CompressedResult) []byte {
+	for i := range results {
+		results[i].Compressed = strings.ToValidUTF8(results[i].Compressed, formats.ReplacementChar)
+	}
+	modified, err := locator.ApplyToolInputs(formats.ToValidUTF8(body), results)
+	if err != nil {
+		log.Warn().Err(err).Msg("tool_output: failed to apply compressed tool inputs")
+		return body
+	}
+	log.Info().Int("count", len(results)).Msg("tool_output: compressed tool inputs in history")
+	return modified
+}
@@ -3,9 +3,9 @@ package tooloutput
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 
@@ -16,6 +16,7 @@ import (
 	authtypes "github.com/compresr/context-gateway/internal/auth/types"
 	"github.com/compresr/context-gateway/internal/compresr"
 	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/formats"
 	"github.com/compresr/context-gateway/internal/pipes"
 	"github.com/compresr/context-gateway/internal/tokenizer"
 )
@@ -29,6 +30,19 @@ func (p *Pipe) Process(ctx *pipes.PipeContext) ([]byte, error) {
 		return ctx.OriginalRequest, nil
 	}
 
+	// Local rewrites, independent of strategy: a call answered twice keeps only
+	// its last result, and blank outputs get the placeholder text.
+	if p.dedupeToolUseIDs {
+		ctx.OriginalRequest = p.dropDuplicateResults(ctx)
+	}
+	if p.emptyPlaceholder != "" {
+		ctx.OriginalRequest = p.fillEmptyOutputs(ctx)
+	}
+	// Likewise independent of strategy: flag (and optionally wrap) prompt injection.
+	if p.detectInjection != "" {
+		ctx.OriginalRequest = p.flagInjections(ctx)
+	}
+
 	// Passthrough = do nothing
 	if p.strategy == config.StrategyPassthrough {
 		log.Debug().Msg("tool_output: passthrough mode, skipping")
@@ -49,7 +63,8 @@ func (p *Pipe) Process(ctx *pipes.PipeContext) ([]byte, error) {
 	return p.compressAllTools(ctx)
 }
 
-// compressAllTools compresses new tool outputs in the request.
+// compressAllTools compresses new tool outputs in the request and, with
+// compress_tool_inputs, large arguments of earlier tool calls.
 //
 // Only compress new (uncompressed) outputs — prior turns are already compressed.
 // Already-compressed outputs are detected by [REF:] prefix and skipped.
@@ -60,60 +75,68 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 		return ctx.OriginalRequest, nil
 	}
 
-	// Get provider name for API source tracking
-	provider := ctx.Adapter.Name()
-
 	// ALWAYS delegate extraction to adapter - pipes don't implement extraction logic
 	extracted, err := ctx.Adapter.ExtractToolOutput(ctx.OriginalRequest)
 	if err != nil {
 		log.Warn().Err(err).Msg("tool_output: adapter extraction failed, skipping compression")
 		return ctx.OriginalRequest, nil
 	}
+	results := p.compressExtracted(ctx, extracted, false)
 
-	if len(extracted) == 0 {
+	locator, inputsOK := ctx.Adapter.(adapters.ToolInputAdapter)
+	var inputs []adapters.CompressedResult
+	if p.compressToolInputs && inputsOK {
+		inputs = p.compressHistoryInputs(ctx, locator)
+	}
+
+	if len(results) == 0 && len(inputs) == 0 {
 		return ctx.OriginalRequest, nil
 	}
 
-	// Determine query for compression context:
-	// - Query-agnostic models (LLM/cmprsr): don't need user query, use empty string
-	// - Query-dependent models (reranker): need query for relevance scoring
-	//
-	// Query extraction strategy (in priority order):
-	// 1. Assistant intent (best: captures WHY the LLM called the tool)
-	// 2. Last user text message (good: captures the user's original request)
-	// 3. Tool name + input summary (fallback: captures what was asked of the tool)
-	// 4. Empty string for query-agnostic models (acceptable: model doesn't use it)
-	var query string
-	if p.IsQueryAgnostic() {
-		query = ""
-		log.Debug().
-			Str("model", p.compresrModel).
-			Bool("query_agnostic", true).
-			Msg("tool_output: query-agnostic model, using empty query")
-	} else {
-		// Priority 1: Assistant's reasoning for calling the tool
-		query = ctx.Adapter.ExtractAssistantIntent(ctx.OriginalRequest)
-		if query == "" {
-			// Priority 2: Last user text message (pre-computed, injected tags stripped)
-			query = ctx.UserQuery
+	// One max_advertised_shadows cap covers outputs and inputs.
+	all := append(results[:len(results):len(results)], inputs...)
+	p.limitAdvertised(ctx, all)
+	results, inputs = all[:len(results)], all[len(results):]
+
+	// Apply all compressed results back to the request body
+	body := ctx.OriginalRequest
+	if len(results) > 0 {
+		body, err = applyResults(ctx, results)
+		if err != nil {
+			log.Warn().Err(err).Msg("tool_output: failed to apply compressed results")
+			return ctx.OriginalRequest, nil
 		}
-		if query == "" {
-			// Priority 3: Build query from tool names being compressed
-			var toolNames []string
-			for _, ext := range extracted {
-				if ext.ToolName != "" {
-					toolNames = append(toolNames, ext.ToolName)
-				}
-			}
-			if len(toolNames) > 0 {
-				query = "tool output from: " + strings.Join(toolNames, ", ")
+	}
+	if len(inputs) > 0 {
+		body = applyToolInputs(locator, body, inputs)
+	}
+	return body, nil
+}
+
+// compressExtracted runs the compression pass shared by tool outputs and tool
+// inputs: eligibility, thresholds and chunking, the compressed cache, the batch
+// and its result checks. It records each item in ctx.ToolOutputCompressions and
+// returns the replacements to apply. For inputs, dedupe_identical and
+// compress_top_k do not apply and only rewritten values are recorded, as
+// "input_compressed".
+func (p *Pipe) compressExtracted(ctx *pipes.PipeContext, extracted []adapters.ExtractedContent, inputs bool) []adapters.CompressedResult {
+	if len(extracted) == 0 {
+		return nil
+	}
+
+	// Get provider name for API source tracking
+	provider := ctx.Adapter.Name()
+	query := p.compressionQuery(ctx, extracted)
+
+	start := len(ctx.ToolOutputCompressions)
+	record := func(rec pipes.ToolOutputCompression) {
+		if inputs {
+			if rec.MappingStatus != "compressed" && rec.MappingStatus != "cache_hit" {
+				return
 			}
+			rec.MappingStatus = "input_compressed"
 		}
-		log.Debug().
-			Str("model", p.compresrModel).
-			Bool("query_agnostic", false).
-			Int("query_len", len(query)).
-			Msg("tool_output: using query for relevance scoring")
+		ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, rec)
 	}
 
 	// Build compression tasks from extracted content
@@ -123,87 +146,62 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 	// Resolve skip_tools categories to provider-specific tool names
 	skipSet := BuildSkipSet(p.skipCategories, ctx.Provider)
 
-	for _, ext := range extracted {
-		// Skip items already claimed by the task_output pipe.
-		// task_output runs before tool_output and populates TaskOutputHandledIDs
-		// so subagent results are not double-processed.
-		if len(ctx.TaskOutputHandledIDs) > 0 {
-			if _, claimed := ctx.TaskOutputHandledIDs[ext.ID]; claimed {
-				log.Debug().
-					Str("tool", ext.ToolName).
-					Str("id", ext.ID).
-					Msg("tool_output: skipped (claimed by task_output pipe)")
-				continue
-			}
-		}
+	// shadow ID → tool call ID of the first occurrence (dedupe_identical)
+	firstSeen := make(map[string]string)
 
-		// Skip empty tool outputs — nothing to compress.
-		if ext.Content == "" {
-			continue
-		}
+	// Set once the store rejects a write; remaining outputs pass through.
+	storeDown := false
 
-		// Skip already-compressed outputs from prior turns.
-		// These arrive in conversation history with the [REF:] prefix
-		// that was added when they were first compressed.
-		if strings.HasPrefix(ext.Content, ShadowPrefixMarker) {
-			log.Debug().
-				Str("tool", ext.ToolName).
-				Msg("tool_output: already compressed from prior turn, skipping")
-			ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
-				ToolName:         ext.ToolName,
-				ToolCallID:       ext.ID,
-				OriginalTokens:   tokenizer.CountTokens(ext.Content),
-				CompressedTokens: tokenizer.CountTokens(ext.Content),
-				MappingStatus:    "already_compressed",
-				MinThreshold:     p.minTokens,
-				MaxThreshold:     p.maxTokens,
-				Model:            p.getEffectiveModel(),
-			})
-			continue
-		}
+	// compress_top_k: indexes of the K largest eligible outputs (nil = no limit)
+	var topK map[int]bool
+	var topKTokens map[int]int
+	if !inputs {
+		topK, topKTokens = p.selectTopK(ctx, extracted, skipSet)
+	}
 
-		// Skip tools configured in skip_tools (resolved by provider)
-		if skipSet[ext.ToolName] {
+	for i, ext := range extracted {
+		// Outputs that are never compressed (claimed by task_output, already
+		// compressed, protected, binary, ...) pass through with their status.
+		switch status := p.skipStatus(ctx, ext, skipSet); status {
+		case "":
+		case statusClaimed, statusEmpty:
+			continue
+		default:
+			if shadowID, ok := existingShadowRef(ext.Content); ok && status == "already_compressed" {
+				p.touchOriginal(shadowID) // keep it expandable for this turn
+			}
 			log.Debug().
 				Str("tool", ext.ToolName).
-				Str("provider", string(ctx.Provider)).
-				Msg("tool_output: skipped by skip_tools config")
-			ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+				Str("id", ext.ID).
+				Str("status", status).
+				Msg("tool_output: not eligible for compression, passthrough")
+			tokens := tokenizer.CountTokens(ext.Content)
+			record(pipes.ToolOutputCompression{
 				ToolName:         ext.ToolName,
 				ToolCallID:       ext.ID,
-				OriginalTokens:   tokenizer.CountTokens(ext.Content),
-				CompressedTokens: tokenizer.CountTokens(ext.Content),
-				MappingStatus:    "skipped_by_config",
+				OriginalTokens:   tokens,
+				CompressedTokens: tokens,
+				MappingStatus:    status,
 				MinThreshold:     p.minTokens,
 				MaxThreshold:     p.maxTokens,
-				Model:            p.getEffectiveModel(),
+				Model:            p.getEffectiveModel(provider),
 			})
 			continue
 		}
 
-		// Skip if content format is not in the effective compressible set.
-		// Format is detected by the adapter during extraction (DetectContentFormat).
-		// FormatUnknown (empty/unclassifiable content) always passthroughs.
-		if !adapters.IsCompressible(ext.Format, p.effectiveFormats) {
-			log.Debug().
-				Str("tool", ext.ToolName).
-				Str("format", string(ext.Format)).
-				Msg("tool_output: content format not compressible, passthrough")
-			ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
-				ToolName:         ext.ToolName,
-				ToolCallID:       ext.ID,
-				OriginalTokens:   tokenizer.CountTokens(ext.Content),
-				CompressedTokens: tokenizer.CountTokens(ext.Content),
-				MappingStatus:    "passthrough_format",
-				MinThreshold:     p.minTokens,
-				MaxThreshold:     p.maxTokens,
-				Model:            p.getEffectiveModel(),
-			})
-			continue
+		// Replace repeats of an earlier output in this request with a back-reference.
+		if p.dedupeIdentical && !inputs {
+			if result, ok := p.dedupeOutput(ctx, ext, firstSeen); ok {
+				results = append(results, result)
+				continue
+			}
 		}
 
-		// Count tokens using tiktoken (accurate, model-aware)
-		contentTokens := tokenizer.CountTokensForModel(ext.Content, ctx.TargetModel)
+		// Count tokens using tiktoken (accurate, model-aware); selectTopK may have already.
+		contentTokens, counted := topKTokens[i]
+		if !counted {
+			contentTokens = tokenizer.CountTokensForModel(ext.Content, ctx.TargetModel)
+		}
 
 		// Skip if below min token threshold - but record for tracking
 		if contentTokens <= p.minTokens {
@@ -213,7 +211,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 				Str("tool", ext.ToolName).
 				Msg("tool_output: below min threshold, passthrough")
 			// Record passthrough for trajectory tracking
-			ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+			record(pipes.ToolOutputCompression{
 				ToolName:         ext.ToolName,
 				ToolCallID:       ext.ID,
 				OriginalTokens:   contentTokens,
@@ -222,18 +220,24 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 				MappingStatus:    "passthrough_small",
 				MinThreshold:     p.minTokens,
 				MaxThreshold:     p.maxTokens,
-				Model:            p.getEffectiveModel(),
+				Model:            p.getEffectiveModel(provider),
 			})
 			continue
 		}
+		// Above max_tokens: compressed in chunks when hierarchical is on and the
+		// output fits in max_chunks, passed through otherwise.
+		var chunks []string
 		if contentTokens > p.maxTokens {
+			chunks = p.chunkOutput(ext.Content, contentTokens)
+		}
+		if contentTokens > p.maxTokens && chunks == nil {
 			log.Debug().
 				Int("tokens", contentTokens).
 				Int("max_tokens", p.maxTokens).
 				Str("tool", ext.ToolName).
 				Msg("tool_output: above max threshold, passthrough")
 			// Record passthrough for trajectory tracking
-			ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+			record(pipes.ToolOutputCompression{
 				ToolName:         ext.ToolName,
 				ToolCallID:       ext.ID,
 				OriginalTokens:   contentTokens,
@@ -241,11 +245,34 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 				MappingStatus:    "passthrough_large",
 				MinThreshold:     p.minTokens,
 				MaxThreshold:     p.maxTokens,
-				Model:            p.getEffectiveModel(),
+				Model:            p.getEffectiveModel(provider),
+			})
+			continue
+		}
+		if topK != nil && !topK[i] {
+			log.Debug().
+				Int("tokens", contentTokens).
+				Int("compress_top_k", p.compressTopK).
+				Str("tool", ext.ToolName).
+				Msg("tool_output: not among the largest outputs, passthrough")
+			record(pipes.ToolOutputCompression{
+				ToolName:         ext.ToolName,
+				ToolCallID:       ext.ID,
+				OriginalTokens:   contentTokens,
+				CompressedTokens: contentTokens,
+				MappingStatus:    "passthrough_not_top_k",
+				MinThreshold:     p.minTokens,
+				MaxThreshold:     p.maxTokens,
+				Model:            p.getEffectiveModel(provider),
 			})
 			continue
 		}
 
+		if storeDown {
+			record(p.storeUnavailableRecord(ext, contentTokens, provider))
+			continue
+		}
+
 		shadowID := p.contentHash(ext.Content)
 
 		// Check compressed cache first (V2: C1 KV-cache preservation)
@@ -262,11 +289,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 				var cachedShadowRef string
 				if p.enableExpandContext {
 					// Full expand_context mode: prefix with shadow ID for retrieval
-					if p.includeExpandHint {
-						cachedFinalContent = fmt.Sprintf(PrefixFormatWithHint, shadowID, shadowID, cachedCompressed)
-					} else {
-						cachedFinalContent = fmt.Sprintf(PrefixFormat, shadowID, cachedCompressed)
-					}
+					cachedFinalContent = p.formatCompressed(shadowID, cachedCompressed, len(ext.Content))
 					p.touchOriginal(shadowID)
 					ctx.ShadowRefs[shadowID] = ext.Content
 					cachedShadowRef = shadowID
@@ -276,7 +299,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 					cachedShadowRef = ""
 				}
 
-				ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+				record(pipes.ToolOutputCompression{
 					ToolName:          ext.ToolName,
 					ToolCallID:        ext.ID,
 					ShadowID:          cachedShadowRef,
@@ -288,7 +311,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 					MappingStatus:     "cache_hit",
 					MinThreshold:      p.minTokens,
 					MaxThreshold:      p.maxTokens,
-					Model:             p.getEffectiveModel(),
+					Model:             p.getEffectiveModel(provider),
 				})
 				results = append(results, adapters.CompressedResult{
 					ID:           ext.ID,
@@ -296,6 +319,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 					ShadowRef:    cachedShadowRef,
 					MessageIndex: ext.MessageIndex,
 					BlockIndex:   ext.BlockIndex,
+					Field:        ext.Field,
 				})
 				p.recordCacheHit()
 				ctx.OutputCompressed = true
@@ -313,21 +337,32 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 		// (rate limit, API error), and the token savings from successful compression
 		// outweigh the one-time KV-cache miss.
 		// Successfully compressed content is handled above via the compressed cache hit path.
-		if p.store != nil {
-			if _, seen := p.store.Get(shadowID); !seen {
-				_ = p.store.Set(shadowID, ext.Content)
-			}
+		//
+		// Without a stored original, expand_context could not resolve the shadow
+		// marker, so a failed write degrades this request to passthrough.
+		if err := p.storeOriginal(shadowID, ext.Content); err != nil && p.enableExpandContext {
+			log.Warn().
+				Err(err).
+				Str("tool", ext.ToolName).
+				Msg("tool_output: store unavailable, passing through uncompressed")
+			p.recordStoreUnavailable()
+			storeDown = true
+			record(p.storeUnavailableRecord(ext, contentTokens, provider))
+			continue
 		}
 
 		// Queue for compression — this is genuinely new content
 		tasks = append(tasks, compressionTask{
-			index:        ext.MessageIndex,
+			index:        i,
 			msg:          message{Content: ext.Content, ToolCallID: ext.ID},
 			toolName:     ext.ToolName,
 			shadowID:     shadowID,
 			original:     ext.Content,
 			messageIndex: ext.MessageIndex,
 			blockIndex:   ext.BlockIndex,
+			overBudget:   usesAPI(p.strategy) && !p.authDisabled.isDisabled(ctx.SessionID) && !p.budget.chargeCalls(ctx.SessionID, max(1, len(chunks)), len(ext.Content)),
+			sessionID:    ctx.SessionID,
+			chunks:       chunks,
 		})
 
 		log.Debug().
@@ -358,7 +393,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 					Str("tool_name", result.toolName).
 					Int("tokens", tokenizer.CountTokens(result.originalContent)).
 					Msg("tool_output: using original content (fallback)")
-				ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+				record(pipes.ToolOutputCompression{
 					ToolName:          result.toolName,
 					ToolCallID:        result.toolCallID,
 					ShadowID:          "", // no shadow reference was created; original content was sent as-is
@@ -368,7 +403,34 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 					CompressedTokens:  tokenizer.CountTokens(result.originalContent),
 					CacheHit:          false,
 					MappingStatus:     "passthrough",
-					Model:             p.getEffectiveModel(),
+					Model:             p.getEffectiveModel(provider),
+					BudgetExhausted:   result.budgetExhausted,
+				})
+				continue
+			}
+
+			// Reject summaries that dropped a token the user marked as critical.
+			if missing := p.missingPreserved(result.originalContent, result.compressedContent); missing != "" {
+				log.Warn().
+					Str("missing", missing).
+					Str("tool", result.toolName).
+					Msg("tool_output: compression dropped preserved pattern, using original")
+				p.recordPreserveMissed()
+				origTokens := tokenizer.CountTokens(result.originalContent)
+				record(pipes.ToolOutputCompression{
+					ToolName:          result.toolName,
+					ToolCallID:        result.toolCallID,
+					OriginalContent:   result.originalContent,
+					CompressedContent: result.originalContent,
+					OriginalTokens:    origTokens,
+					CompressedTokens:  origTokens,
+					MappingStatus:     "preserve_missed",
+					MinThreshold:      p.minTokens,
+					MaxThreshold:      p.maxTokens,
+					Model:             p.getEffectiveModel(provider),
+					Attempts:          result.attempts,
+					TargetMissed:      result.targetMissed,
+					BudgetExhausted:   result.budgetExhausted,
 				})
 				continue
 			}
@@ -377,21 +439,29 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 			// compressionRatio = fraction of tokens removed (higher = more aggressive).
 			// Reject when compressionRatio < p.refusalThreshold (configurable, default DefaultRefusalThreshold).
 			// This also rejects cases where compression expanded the content (compressionRatio == 0 after clamping).
+			// Savings are measured on what would be sent, so the shadow marker and
+			// expand hint count against them.
 			origTokens := tokenizer.CountTokens(result.originalContent)
 			compTokens := tokenizer.CountTokens(result.compressedContent)
-			compressionRatio := tokenizer.CompressionRatio(origTokens, compTokens)
+			sentTokens := compTokens
+			if p.enableExpandContext {
+				sentTokens = tokenizer.CountTokens(p.formatCompressed(result.shadowID, result.compressedContent, len(result.originalContent)))
+			}
+			compressionRatio := tokenizer.CompressionRatio(origTokens, sentTokens)
 			if compressionRatio < p.refusalThreshold {
 				log.Warn().
 					Float64("compression_ratio", compressionRatio).
 					Float64("min_ratio_required", p.refusalThreshold).
 					Int("original_tokens", origTokens).
 					Int("api_returned_tokens", compTokens).
+					Int("sent_tokens", sentTokens).
 					Str("tool", result.toolName).
 					Msg("tool_output: insufficient token savings, using original")
+				p.recordLowSavingsRejected()
 				// Record origTokens for CompressedTokens because the original content is what
 				// we actually send to the LLM — the API-returned content is discarded.
 				// ShadowID is "" because no shadow was created (original is sent as-is).
-				ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+				record(pipes.ToolOutputCompression{
 					ToolName:          result.toolName,
 					ToolCallID:        result.toolCallID,
 					ShadowID:          "",
@@ -403,7 +473,10 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 					MappingStatus:     "ratio_exceeded",
 					MinThreshold:      p.minTokens,
 					MaxThreshold:      p.maxTokens,
-					Model:             p.getEffectiveModel(),
+					Model:             p.getEffectiveModel(provider),
+					Attempts:          result.attempts,
+					TargetMissed:      result.targetMissed,
+					BudgetExhausted:   result.budgetExhausted,
 				})
 				continue
 			}
@@ -420,11 +493,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 			var shadowRef string
 			if p.enableExpandContext {
 				// Full expand_context mode: prefix with shadow ID for retrieval
-				if p.includeExpandHint {
-					finalContent = fmt.Sprintf(PrefixFormatWithHint, result.shadowID, result.shadowID, result.compressedContent)
-				} else {
-					finalContent = fmt.Sprintf(PrefixFormat, result.shadowID, result.compressedContent)
-				}
+				finalContent = p.formatCompressed(result.shadowID, result.compressedContent, len(result.originalContent))
 				ctx.ShadowRefs[result.shadowID] = result.originalContent
 				shadowRef = result.shadowID
 			} else {
@@ -434,7 +503,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 			}
 
 			tokensSaved := origTokens - compTokens
-			ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+			record(pipes.ToolOutputCompression{
 				ToolName:          result.toolName,
 				ToolCallID:        result.toolCallID,
 				ShadowID:          shadowRef,
@@ -446,7 +515,10 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 				MappingStatus:     "compressed",
 				MinThreshold:      p.minTokens,
 				MaxThreshold:      p.maxTokens,
-				Model:             p.getEffectiveModel(),
+				Model:             p.getEffectiveModel(provider),
+				Attempts:          result.attempts,
+				TargetMissed:      result.targetMissed,
+				BudgetExhausted:   result.budgetExhausted,
 			})
 
 			results = append(results, adapters.CompressedResult{
@@ -455,6 +527,7 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 				ShadowRef:    shadowRef,
 				MessageIndex: result.messageIndex,
 				BlockIndex:   result.blockIndex,
+				Field:        extracted[result.index].Field,
 			})
 
 			p.recordCompressionOK(int64(tokensSaved))
@@ -470,25 +543,58 @@ func (p *Pipe) compressAllTools(ctx *pipes.PipeContext) ([]byte, error) {
 				Msg("tool_output: compressed successfully")
 		}
 	}
-
-	// Annotate all compression records with the query used
+	// Annotate this pass's compression records with the query used
 	isQueryAgnostic := p.IsQueryAgnostic()
-	for i := range ctx.ToolOutputCompressions {
+	for i := start; i < len(ctx.ToolOutputCompressions); i++ {
 		ctx.ToolOutputCompressions[i].Query = query
 		ctx.ToolOutputCompressions[i].QueryAgnostic = isQueryAgnostic
 	}
 
-	// Apply all compressed results back to the request body
-	if len(results) > 0 {
-		modifiedBody, err := ctx.Adapter.ApplyToolOutput(ctx.OriginalRequest, results)
-		if err != nil {
-			log.Warn().Err(err).Msg("tool_output: failed to apply compressed results")
-			return ctx.OriginalRequest, nil
-		}
-		return modifiedBody, nil
+	return results
+}
+
+// compressionQuery determines the query for compression context:
+// - Query-agnostic models (LLM/cmprsr): don't need user query, use empty string
+// - Query-dependent models (reranker): need query for relevance scoring
+//
+// Query extraction strategy (in priority order):
+// 1. Assistant intent (best: captures WHY the LLM called the tool)
+// 2. Last user text message (good: captures the user's original request)
+// 3. Tool name + input summary (fallback: captures what was asked of the tool)
+// 4. Empty string for query-agnostic models (acceptable: model doesn't use it)
+func (p *Pipe) compressionQuery(ctx *pipes.PipeContext, extracted []adapters.ExtractedContent) string {
+	if p.IsQueryAgnostic() {
+		log.Debug().
+			Str("model", p.compresrModel).
+			Bool("query_agnostic", true).
+			Msg("tool_output: query-agnostic model, using empty query")
+		return ""
 	}
 
-	return ctx.OriginalRequest, nil
+	// Priority 1: Assistant's reasoning for calling the tool
+	query := ctx.Adapter.ExtractAssistantIntent(ctx.OriginalRequest)
+	if query == "" {
+		// Priority 2: Last user text message (pre-computed, injected tags stripped)
+		query = ctx.UserQuery
+	}
+	if query == "" {
+		// Priority 3: Build query from tool names being compressed
+		var toolNames []string
+		for _, ext := range extracted {
+			if ext.ToolName != "" {
+				toolNames = append(toolNames, ext.ToolName)
+			}
+		}
+		if len(toolNames) > 0 {
+			query = "tool output from: " + strings.Join(toolNames, ", ")
+		}
+	}
+	log.Debug().
+		Str("model", p.compresrModel).
+		Bool("query_agnostic", false).
+		Int("query_len", len(query)).
+		Msg("tool_output: using query for relevance scoring")
+	return query
 }
 
 // compressBatch processes compression tasks with rate limiting (V2: C11).
@@ -517,8 +623,28 @@ func (p *Pipe) compressBatch(reqCtx context.Context, query, provider string, aut
 			default:
 			}
 
-			// V2: Rate limit (C11)
-			if p.rateLimiter != nil {
+			// Credentials rejected earlier in this session: the API cannot succeed.
+			if usesAPI(p.strategy) && p.authDisabled.isDisabled(task.sessionID) {
+				p.recordAuthDisabled()
+				results <- p.applyFallback(task, errAPIDisabled)
+				continue
+			}
+
+			// Session budget spent: no API call, the fallback chain handles the output.
+			if task.overBudget {
+				p.recordBudgetExhausted()
+				log.Info().
+					Str("tool", task.toolName).
+					Strs("fallback_chain", p.fallbackChain).
+					Msg("tool_output: session compression budget exhausted, applying fallback")
+				result := p.applyFallback(task, errBudgetExhausted)
+				result.budgetExhausted = true
+				results <- result
+				continue
+			}
+
+			// V2: Rate limit (C11). Chunked outputs take a token per chunk call instead.
+			if p.rateLimiter != nil && task.chunks == nil {
 				if !p.rateLimiter.Acquire() {
 					p.recordRateLimited()
 					log.Warn().Str("tool", task.toolName).Msg("tool_output: rate limited")
@@ -537,34 +663,47 @@ func (p *Pipe) compressBatch(reqCtx context.Context, query, provider string, aut
 				}
 			}
 
-			// V2: Semaphore for concurrent limit (C11) — respect context cancellation.
-			if p.semaphore != nil {
-				select {
-				case p.semaphore <- struct{}{}:
-				case <-reqCtx.Done():
-					results <- compressionResult{
-						index:           task.index,
-						shadowID:        task.shadowID,
-						toolName:        task.toolName,
-						toolCallID:      task.msg.ToolCallID,
-						originalContent: task.original,
-						success:         false,
-						err:             reqCtx.Err(),
-						messageIndex:    task.messageIndex,
-						blockIndex:      task.blockIndex,
-					}
-					continue
-				}
+			// Admission control: at most maxConcurrent calls run and maxQueueDepth wait.
+			// Beyond that apply the fallback strategy rather than blocking indefinitely.
+			if p.semaphore != nil && !p.admit() {
+				p.recordQueueFull()
+				log.Warn().
+					Str("tool", task.toolName).
+					Int("max_concurrency", p.maxConcurrent).
+					Int("max_queue_depth", p.maxQueueDepth).
+					Msg("tool_output: compression queue full, applying fallback")
+				results <- p.applyFallback(task, fmt.Errorf("compression queue full"))
+				continue
 			}
 
 			wg.Add(1)
 			go func(t compressionTask) {
 				defer wg.Done()
-				defer func() {
-					if p.semaphore != nil {
-						<-p.semaphore
+
+				// V2: Semaphore for concurrent limit (C11) — respect context cancellation.
+				// Chunked outputs take a slot per chunk call instead.
+				if p.semaphore != nil {
+					defer p.pending.Add(-1)
+				}
+				if p.semaphore != nil && t.chunks == nil {
+					select {
+					case p.semaphore <- struct{}{}:
+						defer func() { <-p.semaphore }()
+					case <-reqCtx.Done():
+						results <- compressionResult{
+							index:           t.index,
+							shadowID:        t.shadowID,
+							toolName:        t.toolName,
+							toolCallID:      t.msg.ToolCallID,
+							originalContent: t.original,
+							success:         false,
+							err:             reqCtx.Err(),
+							messageIndex:    t.messageIndex,
+							blockIndex:      t.blockIndex,
+						}
+						return
 					}
-				}()
+				}
 
 				result := p.compressOne(reqCtx, query, provider, auth, t)
 				results <- result
@@ -583,34 +722,324 @@ func (p *Pipe) compressBatch(reqCtx context.Context, query, provider string, aut
 func (p *Pipe) compressOne(reqCtx context.Context, query, provider string, auth authtypes.CapturedAuth, t compressionTask) compressionResult {
 	var compressed string
 	var err error
+	var attempts int
+	var targetMissed bool
+
+	if t.chunks != nil {
+		compressed, err = p.compressChunked(reqCtx, query, provider, auth, t)
+	} else {
+		compressed, attempts, targetMissed, err = p.compressContent(reqCtx, t.sessionID, query, provider, auth, t.original, t.toolName)
+	}
+	if errors.Is(err, errUnknownStrategy) {
+		return compressionResult{index: t.index, success: false, err: err, messageIndex: t.messageIndex, blockIndex: t.blockIndex}
+	}
 
+	if err != nil {
+		p.classifyFailure(t, err)
+		return p.applyFallback(t, err)
+	}
+
+	// V2: Don't add expand hint here - prefix is added at send-time
+	return compressionResult{
+		index:             t.index,
+		shadowID:          t.shadowID,
+		toolName:          t.toolName,
+		toolCallID:        t.msg.ToolCallID,
+		originalContent:   t.original,
+		compressedContent: compressed,
+		success:           true,
+		attempts:          attempts,
+		targetMissed:      targetMissed,
+		messageIndex:      t.messageIndex,
+		blockIndex:        t.blockIndex,
+	}
+}
+
+// errUnknownStrategy is returned by compressContent for a strategy it cannot run.
+var errUnknownStrategy = errors.New("unknown strategy")
+
+// compressContent compresses content with the configured strategy.
+func (p *Pipe) compressContent(reqCtx context.Context, sessionID, query, provider string, auth authtypes.CapturedAuth, content, toolName string) (compressed string, attempts int, targetMissed bool, err error) {
 	switch p.strategy {
 	case config.StrategyCompresr:
-		compressed, err = p.compressViaCompresr(query, t.original, t.toolName, provider)
+		if p.maxCompressionRetries > 0 {
+			return p.compressAdaptive(reqCtx, sessionID, query, content, toolName, provider)
+		}
+		compressed, err = p.compressViaCompresr(reqCtx, query, content, toolName, provider, p.targetCompressionRatio)
 	case config.StrategyExternalProvider:
-		compressed, err = p.compressViaExternalProvider(reqCtx, query, t.original, t.toolName, auth)
+		compressed, err = p.compressViaExternalProvider(reqCtx, query, content, toolName, auth)
 	case config.StrategySimple:
 		// Simple first-words compression for testing expand_context
-		compressed = p.CompressSimpleContent(t.original)
-		err = nil
+		compressed = p.compressLocal(p.strategy, content)
 	case config.StrategyTrimming:
 		// Tail-keep compression: discard head, keep only tail based on target_compression_ratio
-		compressed = p.compressTrimming(t.original)
-		err = nil
+		compressed = p.compressLocal(p.strategy, content)
 	default:
-		return compressionResult{index: t.index, success: false, err: fmt.Errorf("unknown strategy: %s", p.strategy), messageIndex: t.messageIndex, blockIndex: t.blockIndex}
+		return "", 0, false, fmt.Errorf("%w: %s", errUnknownStrategy, p.strategy)
+	}
+	return compressed, 0, false, err
+}
+
+// Statuses from skipStatus for outputs that are skipped without a record.
+const (
+	statusClaimed = "claimed_by_task_output"
+	statusEmpty   = "empty"
+)
+
+// skipStatus returns the MappingStatus of an output that must not be
+// compressed, or "" when it is eligible. compressAllTools and selectTopK share
+// it so compress_top_k only ranks outputs the main loop would compress.
+func (p *Pipe) skipStatus(ctx *pipes.PipeContext, ext adapters.ExtractedContent, skipSet map[string]bool) string {
+	// task_output runs before tool_output and claims subagent results.
+	if _, claimed := ctx.TaskOutputHandledIDs[ext.ID]; claimed {
+		return statusClaimed
+	}
+	switch {
+	case ext.Content == "":
+		return statusEmpty
+	case isAlreadyCompressed(ext.Content):
+		// Compressed in a prior turn and replayed with its [REF:] line;
+		// compressing again would nest markers and orphan the shadow ID.
+		return "already_compressed"
+	case strings.HasPrefix(ext.Content, UntrustedOutputOpen):
+		// Wrapped by detect_injection; the delimiter must survive.
+		return "injection_wrapped"
+	case p.neverCompress[ext.ToolName]:
+		return "protected"
+	case ext.IsError && !p.compressErrors:
+		return "error_result"
+	case skipSet[ext.ToolName]:
+		return "skipped_by_config"
+	case formats.IsBinary(ext.Content):
+		// The compressor only handles text; binary wastes a call and can fail.
+		return "binary_skipped"
+	case !adapters.IsCompressible(ext.Format, p.effectiveFormats):
+		return "passthrough_format"
+	}
+	return ""
+}
+
+// selectTopK returns the indexes of the compressTopK largest outputs (by tokens)
+// that skipStatus allows and that fit within min/max tokens (or can be chunked),
+// plus the token counts it computed so compressAllTools can reuse them.
+// The selection is nil when compress_top_k is unset or every eligible output fits within K.
+func (p *Pipe) selectTopK(ctx *pipes.PipeContext, extracted []adapters.ExtractedContent, skipSet map[string]bool) (map[int]bool, map[int]int) {
+	if p.compressTopK <= 0 {
+		return nil, nil
+	}
+
+	type candidate struct {
+		index  int
+		tokens int
+	}
+	var candidates []candidate
+	counted := make(map[int]int)
+	for i, ext := range extracted {
+		if p.skipStatus(ctx, ext, skipSet) != "" {
+			continue
+		}
+		tokens := tokenizer.CountTokensForModel(ext.Content, ctx.TargetModel)
+		counted[i] = tokens
+		if tokens <= p.minTokens || (tokens > p.maxTokens && p.chunkOutput(ext.Content, tokens) == nil) {
+			continue
+		}
+		candidates = append(candidates, candidate{index: i, tokens: tokens})
+	}
+	if len(candidates) <= p.compressTopK {
+		return nil, counted
 	}
 
+	// Largest first; ties keep request order so selection is deterministic.
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].tokens > candidates[b].tokens
+	})
+	selected := make(map[int]bool, p.compressTopK)
+	for _, c := range candidates[:p.compressTopK] {
+		selected[c.index] = true
+	}
+	return selected, counted
+}
+
+// dropDuplicateResults removes repeated tool results for the same call ID,
+// keeping the last, so the request is not rejected upstream. Returns the
+// original body when nothing changes.
+func (p *Pipe) dropDuplicateResults(ctx *pipes.PipeContext) []byte {
+	deduper, ok := ctx.Adapter.(adapters.DuplicateToolResultAdapter)
+	if !ok || len(ctx.OriginalRequest) == 0 {
+		return ctx.OriginalRequest
+	}
+	modified, dupIDs, err := deduper.DropDuplicateToolResults(ctx.OriginalRequest)
 	if err != nil {
-		log.Warn().
-			Err(err).
-			Str("strategy", p.strategy).
-			Str("fallback", p.fallbackStrategy).
-			Str("tool", t.toolName).
-			Msg("tool_output: compression failed, applying fallback")
-
-		// Apply fallback strategy
-		if p.fallbackStrategy == config.StrategyPassthrough {
+		log.Warn().Err(err).Msg("tool_output: failed to drop duplicate tool results")
+		return ctx.OriginalRequest
+	}
+	if len(dupIDs) > 0 {
+		log.Warn().Strs("tool_use_ids", dupIDs).Int("dropped", len(dupIDs)).
+			Msg("tool_output: dropped duplicate tool results, keeping the last per ID")
+	}
+	return modified
+}
+
+// fillEmptyOutputs replaces empty or whitespace-only tool results with the
+// empty_output_placeholder text. Returns the original body when nothing changes.
+// Not recorded in ToolOutputCompressions: the same blank outputs recur in history
+// on every turn and the rewrite is deterministic, so the prefix stays cache-stable.
+func (p *Pipe) fillEmptyOutputs(ctx *pipes.PipeContext) []byte {
+	locator, ok := ctx.Adapter.(adapters.EmptyToolOutputAdapter)
+	if !ok || len(ctx.OriginalRequest) == 0 {
+		return ctx.OriginalRequest
+	}
+	empty, err := locator.ExtractEmptyToolOutputs(ctx.OriginalRequest)
+	if err != nil || len(empty) == 0 {
+		return ctx.OriginalRequest
+	}
+
+	results := make([]adapters.CompressedResult, 0, len(empty))
+	for _, ext := range empty {
+		results = append(results, adapters.CompressedResult{
+			ID:           ext.ID,
+			Compressed:   p.emptyPlaceholder,
+			MessageIndex: ext.MessageIndex,
+			BlockIndex:   ext.BlockIndex,
+		})
+	}
+
+	modified, err := applyResults(ctx, results)
+	if err != nil {
+		log.Warn().Err(err).Msg("tool_output: failed to apply empty output placeholder")
+		return ctx.OriginalRequest
+	}
+	log.Debug().Int("count", len(results)).Msg("tool_output: replaced empty tool outputs with placeholder")
+	return modified
+}
+
+// applyResults writes results into the request body. Blocks the pipe leaves
+// untouched keep their raw bytes, so a binary-ish tool result would otherwise be
+// forwarded as invalid UTF-8 next to re-encoded blocks; both the body and the
+// replacements are sanitized first so strict upstream parsers accept the request.
+func applyResults(ctx *pipes.PipeContext, results []adapters.CompressedResult) ([]byte, error) {
+	for i := range results {
+		results[i].Compressed = strings.ToValidUTF8(results[i].Compressed, formats.ReplacementChar)
+	}
+	return ctx.Adapter.ApplyToolOutput(formats.ToValidUTF8(ctx.OriginalRequest), results)
+}
+
+// dedupeOutput replaces ext with a back-reference when identical content already
+// appeared earlier in the request. The original is stored under the shared shadow ID
+// so expand_context resolves the reference. Returns false for first occurrences.
+func (p *Pipe) dedupeOutput(ctx *pipes.PipeContext, ext adapters.ExtractedContent, firstSeen map[string]string) (adapters.CompressedResult, bool) {
+	shadowID := p.contentHash(ext.Content)
+	firstID, seen := firstSeen[shadowID]
+	if !seen {
+		firstSeen[shadowID] = ext.ID
+		return adapters.CompressedResult{}, false
+	}
+
+	ref := fmt.Sprintf(DuplicateRefFormat, shadowID, firstID)
+	shadowRef := shadowID
+	if p.lossyMode {
+		ref, shadowRef = fmt.Sprintf(DuplicateNoteFormat, firstID), ""
+	}
+	if len(ref) >= len(ext.Content) {
+		return adapters.CompressedResult{}, false
+	}
+
+	if err := p.storeOriginal(shadowID, ext.Content); err != nil && p.enableExpandContext {
+		return adapters.CompressedResult{}, false
+	}
+	if p.enableExpandContext {
+		ctx.ShadowRefs[shadowID] = ext.Content
+	}
+	ctx.OutputCompressed = true
+
+	log.Debug().
+		Str("tool", ext.ToolName).
+		Str("id", ext.ID).
+		Str("same_as", firstID).
+		Msg("tool_output: identical to earlier output, replaced with back-reference")
+
+	ctx.ToolOutputCompressions = append(ctx.ToolOutputCompressions, pipes.ToolOutputCompression{
+		ToolName:          ext.ToolName,
+		ToolCallID:        ext.ID,
+		ShadowID:          shadowRef,
+		OriginalContent:   ext.Content,
+		CompressedContent: ref,
+		OriginalTokens:    tokenizer.CountTokens(ext.Content),
+		CompressedTokens:  tokenizer.CountTokens(ref),
+		MappingStatus:     "deduplicated",
+		MinThreshold:      p.minTokens,
+		MaxThreshold:      p.maxTokens,
+		Model:             p.getEffectiveModel(ctx.Adapter.Name()),
+	})
+
+	return adapters.CompressedResult{
+		ID:           ext.ID,
+		Compressed:   ref,
+		ShadowRef:    shadowRef,
+		MessageIndex: ext.MessageIndex,
+		BlockIndex:   ext.BlockIndex,
+	}, true
+}
+
+// storeOriginal saves content under shadowID unless it is already present.
+// Lossy mode keeps no originals. Returns the store error so callers can avoid
+// emitting unexpandable references.
+func (p *Pipe) storeOriginal(shadowID, content string) error {
+	if p.store == nil || p.lossyMode {
+		return nil
+	}
+	if _, ok := p.store.Get(shadowID); ok {
+		return nil
+	}
+	return p.store.Set(shadowID, content)
+}
+
+// storeUnavailableRecord describes an output forwarded as-is because the store failed.
+func (p *Pipe) storeUnavailableRecord(ext adapters.ExtractedContent, tokens int, provider string) pipes.ToolOutputCompression {
+	return pipes.ToolOutputCompression{
+		ToolName:         ext.ToolName,
+		ToolCallID:       ext.ID,
+		OriginalTokens:   tokens,
+		CompressedTokens: tokens,
+		MappingStatus:    "passthrough_store_unavailable",
+		MinThreshold:     p.minTokens,
+		MaxThreshold:     p.maxTokens,
+		Model:            p.getEffectiveModel(provider),
+	}
+}
+
+// applyFallback builds the result for a task whose compression failed or was not attempted.
+// Walks the fallback chain in order: local levels compress the output in-process,
+// passthrough forwards it verbatim. Levels that cannot run here are skipped; when
+// none handles the output the task fails and the original is kept.
+func (p *Pipe) applyFallback(t compressionTask, err error) compressionResult {
+	for _, level := range p.fallbackChain {
+		switch level {
+		case config.StrategyLocal, config.StrategySimple, config.StrategyTrimming:
+			compressed := p.compressLocal(level, t.original)
+			if compressed == "" || compressed == t.original {
+				continue
+			}
+			log.Info().
+				Str("tool", t.toolName).
+				Str("fallback_level", level).
+				Msg("tool_output: fallback compressed output")
+			return compressionResult{
+				index:             t.index,
+				shadowID:          t.shadowID,
+				toolName:          t.toolName,
+				toolCallID:        t.msg.ToolCallID,
+				originalContent:   t.original,
+				compressedContent: compressed,
+				success:           true,
+				messageIndex:      t.messageIndex,
+				blockIndex:        t.blockIndex,
+			}
+		case config.StrategyPassthrough:
+			log.Info().
+				Str("tool", t.toolName).
+				Str("fallback_level", level).
+				Msg("tool_output: fallback passed output through")
 			return compressionResult{
 				index:             t.index,
 				shadowID:          t.shadowID,
@@ -624,33 +1053,72 @@ func (p *Pipe) compressOne(reqCtx context.Context, query, provider string, auth
 				blockIndex:        t.blockIndex,
 			}
 		}
+	}
 
-		if p.store != nil {
-			_ = p.store.Delete(t.shadowID)
-		}
-		return compressionResult{index: t.index, success: false, err: err, messageIndex: t.messageIndex, blockIndex: t.blockIndex}
+	if p.store != nil {
+		_ = p.store.Delete(t.shadowID)
 	}
+	return compressionResult{index: t.index, success: false, err: err, messageIndex: t.messageIndex, blockIndex: t.blockIndex}
+}
 
-	// V2: Don't add expand hint here - prefix is added at send-time
-	return compressionResult{
-		index:             t.index,
-		shadowID:          t.shadowID,
-		toolName:          t.toolName,
-		toolCallID:        t.msg.ToolCallID,
-		originalContent:   t.original,
-		compressedContent: compressed,
-		success:           true,
-		messageIndex:      t.messageIndex,
-		blockIndex:        t.blockIndex,
+// compressLocal runs an in-process compression strategy (no network calls).
+// keep_tail_bytes is applied on top of whichever strategy runs.
+func (p *Pipe) compressLocal(strategy, content string) string {
+	var compressed string
+	if strategy == config.StrategySimple {
+		compressed = p.CompressSimpleContent(content)
+	} else {
+		compressed = p.compressTrimming(content) // local, trimming
+	}
+	return p.keepTail(content, compressed)
+}
+
+// admit reserves a running-or-queued slot. Returns false when the queue is full.
+func (p *Pipe) admit() bool {
+	if p.pending.Add(1) > int64(p.maxConcurrent+p.maxQueueDepth) {
+		p.pending.Add(-1)
+		return false
 	}
+	return true
 }
 
-// contentHash generates a deterministic shadow ID from content.
-// V2: SHA256(normalize(original)) for consistency (E22)
+// contentHash returns the shadow ID for content from the pipe's generator.
 func (p *Pipe) contentHash(content string) string {
-	hash := sha256.Sum256([]byte(content))
-	// Use first 16 bytes (32 hex chars) - still 128 bits of entropy
-	return ShadowIDPrefix + hex.EncodeToString(hash[:16])
+	if p.canonicalizeJSON {
+		content = canonicalJSON(content)
+	}
+	return p.shadowIDs.ShadowID(content)
+}
+
+// existingShadowRef returns the shadow ID of a block compressed in a prior turn
+// and replayed by the client: [REF:id] on the first line, or on the second
+// under an expand hint placed before it (Validate keeps such hints to one line).
+func existingShadowRef(content string) (string, bool) {
+	first, rest, _ := strings.Cut(content, "\n")
+	if id, ok := parseShadowRef(first); ok {
+		return id, true
+	}
+	second, _, _ := strings.Cut(rest, "\n")
+	return parseShadowRef(second)
+}
+
+// parseShadowRef extracts id from a "[REF:id]" line.
+func parseShadowRef(line string) (string, bool) {
+	id, ok := strings.CutPrefix(line, ShadowPrefixMarker)
+	if !ok {
+		return "", false
+	}
+	id, ok = strings.CutSuffix(id, "]")
+	if !ok || id == "" || strings.ContainsAny(id, "[] ") {
+		return "", false
+	}
+	return id, true
+}
+
+// isAlreadyCompressed reports whether content carries a prior turn's [REF:id].
+func isAlreadyCompressed(content string) bool {
+	_, ok := existingShadowRef(content)
+	return ok
 }
 
 // touchOriginal extends the TTL of original content before LLM call (V2)
@@ -692,8 +1160,47 @@ func (p *Pipe) recordRateLimited() {
 	p.mu.Unlock()
 }
 
-// getEffectiveModel returns the compression model name with fallback to default.
-func (p *Pipe) getEffectiveModel() string {
+func (p *Pipe) recordStoreUnavailable() {
+	p.mu.Lock()
+	p.metrics.StoreUnavailable++
+	p.mu.Unlock()
+}
+
+func (p *Pipe) recordPreserveMissed() {
+	p.mu.Lock()
+	p.metrics.PreserveMissed++
+	p.mu.Unlock()
+}
+
+func (p *Pipe) recordLowSavingsRejected() {
+	p.mu.Lock()
+	p.metrics.LowSavingsRejected++
+	p.mu.Unlock()
+}
+
+func (p *Pipe) recordQueueFull() {
+	p.mu.Lock()
+	p.metrics.QueueFull++
+	p.mu.Unlock()
+}
+
+// formatCompressed prefixes compressed content with its shadow ID (and adds the
+// expand hint when enabled) for expand_context retrieval. originalBytes feeds
+// the {{original_bytes}} placeholder of expand_hint_template.
+func (p *Pipe) formatCompressed(shadowID, compressed string, originalBytes int) string {
+	ref := fmt.Sprintf(PrefixFormat, shadowID, compressed)
+	if !p.includeExpandHint {
+		return ref
+	}
+	return p.withExpandHint(ref, p.expandHint(shadowID, originalBytes))
+}
+
+// getEffectiveModel returns the compression model for a request format
+// (adapter name): compresr.model_by_format, then compresr.model, then default.
+func (p *Pipe) getEffectiveModel(provider string) string {
+	if model := p.compresrModelByFormat[provider]; model != "" {
+		return model
+	}
 	if p.compresrModel != "" {
 		return p.compresrModel
 	}
@@ -705,7 +1212,7 @@ func (p *Pipe) getEffectiveModel() string {
 // compressViaCompresr calls the Compresr API via the centralized client.
 // When the circuit breaker is open (repeated failures), returns the fallback error immediately
 // without waiting for the full API timeout.
-func (p *Pipe) compressViaCompresr(query, content, toolName, provider string) (string, error) {
+func (p *Pipe) compressViaCompresr(reqCtx context.Context, query, content, toolName, provider string, targetRatio float64) (string, error) {
 	// Use the centralized Compresr client
 	if p.compresrClient == nil {
 		return "", fmt.Errorf("compresr client not initialized")
@@ -717,7 +1224,7 @@ func (p *Pipe) compressViaCompresr(query, content, toolName, provider string) (s
 	}
 
 	// Use configured model, fallback to default if not set
-	modelName := p.getEffectiveModel()
+	modelName := p.getEffectiveModel(provider)
 
 	// Build source string: gateway:anthropic or gateway:openai
 	source := "gateway:" + provider
@@ -728,11 +1235,15 @@ func (p *Pipe) compressViaCompresr(query, content, toolName, provider string) (s
 		ToolName:               toolName,
 		ModelName:              modelName,
 		Source:                 source,
-		TargetCompressionRatio: p.targetCompressionRatio,
+		TargetCompressionRatio: targetRatio,
 	}
 
-	result, err := p.compresrClient.CompressToolOutput(params)
+	result, err := p.compresrClient.CompressToolOutputContext(reqCtx, params)
 	if err != nil {
+		if reqCtx.Err() != nil {
+			// Cancelled by the client or request_timeout — not an API failure
+			return "", err
+		}
 		p.circuit.RecordFailure()
 		return "", fmt.Errorf("compresr API call failed: %w", err)
 	}
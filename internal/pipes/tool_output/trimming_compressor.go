@@ -10,6 +10,7 @@ package tooloutput
 
 import (
 	"fmt"
+	"unicode/utf8"
 
 	"github.com/compresr/context-gateway/internal/tokenizer"
 )
@@ -30,6 +31,15 @@ func (p *Pipe) compressTrimming(content string) string {
 	if keepLen <= 0 {
 		keepLen = 1
 	}
+	keepPct := int(keepRatio * 100)
+	if keepLen < p.keepTailBytes {
+		// keep_tail_bytes widens the retained tail (to a rune boundary, as keepTail does)
+		keepLen = p.keepTailBytes
+		for keepLen < len(content) && !utf8.RuneStart(content[len(content)-keepLen]) {
+			keepLen++
+		}
+		keepPct = keepLen * 100 / len(content)
+	}
 	if keepLen >= len(content) {
 		return content
 	}
@@ -39,6 +49,6 @@ func (p *Pipe) compressTrimming(content string) string {
 	tailTokens := tokenizer.CountTokens(tail)
 
 	header := fmt.Sprintf("[TRIMMED — showing last %d%% of content (%d/%d tokens). Call expand_context to see full output.]\n",
-		int(keepRatio*100), tailTokens, origTokens)
+		keepPct, tailTokens, origTokens)
 	return header + tail
 }
@@ -2,7 +2,9 @@
 package tooloutput
 
 import (
+	"regexp"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/compresr/context-gateway/internal/adapters"
@@ -21,8 +23,9 @@ const (
 	// MaxExpandLoops prevents infinite expansion cycles.
 	MaxExpandLoops = 5
 
-	// MaxConcurrentCompressions limits parallel compression API calls.
-	MaxConcurrentCompressions = 10
+	// MaxConcurrentCompressions limits parallel compression API calls
+	// when compresr.max_concurrency is not configured.
+	MaxConcurrentCompressions = config.DefaultCompressionConcurrency
 
 	// MaxCompressionsPerSecond is the rate limit for compression API calls.
 	MaxCompressionsPerSecond = 20
@@ -40,8 +43,16 @@ const (
 	// Uses [REF:id] format for brevity and readability.
 	PrefixFormat = "[REF:%s]\n%s"
 
-	// PrefixFormatWithHint includes expand_context usage hint before compressed content.
-	PrefixFormatWithHint = "[COMPRESSED — call expand_context(id=\"%s\") for full content]\n[REF:%s]\n%s"
+	// ExpandHintFormat is the line that advertises a block as expandable.
+	ExpandHintFormat = "[COMPRESSED — call expand_context(id=\"%s\") for full content]\n"
+
+	// DuplicateRefFormat replaces a tool output identical to an earlier one in the same request.
+	// Keeps the [REF:] prefix so expand_context resolves it and later turns skip it.
+	DuplicateRefFormat = "[REF:%s]\n[Identical to the tool result for %s above]"
+
+	// DuplicateNoteFormat is DuplicateRefFormat without the [REF:] prefix, used
+	// in lossy mode where nothing is stored to expand.
+	DuplicateNoteFormat = "[Identical to the tool result for %s above]"
 
 	// ShadowPrefixMarker is used to detect already-compressed content.
 	ShadowPrefixMarker = "[REF:"
@@ -60,14 +71,35 @@ const (
 type Pipe struct {
 	enabled                bool
 	strategy               string
-	fallbackStrategy       string
+	fallbackChain          []string
+	shadowIDs              ShadowIDGenerator
 	minTokens              int
 	maxTokens              int
 	targetCompressionRatio float64
+	maxCompressionRetries  int
 	refusalThreshold       float64
 	includeExpandHint      bool
+	expandHintTemplate     string
+	expandHintAfter        bool
 	enableExpandContext    bool
+	lossyMode              bool // No originals stored, no markers (lossy_mode)
 	bypassCostCheck        bool
+	dedupeIdentical        bool
+	dedupeToolUseIDs       bool
+	compressErrors         bool
+	canonicalizeJSON       bool
+	compressToolInputs     bool
+	compressTopK           int
+	keepTailBytes          int
+	hierarchical           bool // Compress outputs above max_tokens in chunks (hierarchical.enabled)
+	maxChunks              int
+	mergeChunkSummaries    bool
+	emptyPlaceholder       string
+	maxAdvertisedShadows   int
+	advertised             *advertisedHints // Sticky max_advertised_shadows decisions
+	detectInjection        string
+	flagged                *flaggedOutputs // Tool-call IDs already reported by detect_injection
+	preservePatterns       []*regexp.Regexp
 	store                  store.Store
 
 	compresrClient *compresr.Client
@@ -75,12 +107,15 @@ type Pipe struct {
 	compresrEndpoint      string
 	compresrKey           string
 	compresrModel         string
+	compresrModelByFormat map[string]string
 	compresrTimeout       time.Duration
 	compresrQueryAgnostic bool
 
 	maxConcurrent int
+	maxQueueDepth int
 	maxPerSecond  int
 	semaphore     chan struct{}
+	pending       atomic.Int64 // calls running or waiting for a semaphore slot
 	rateLimiter   *RateLimiter
 
 	mu      sync.RWMutex
@@ -88,6 +123,14 @@ type Pipe struct {
 
 	skipCategories []string
 
+	// neverCompress holds never_compress_tools names; their outputs always pass through.
+	neverCompress map[string]bool
+
+	// budget enforces max_compression_calls/bytes_per_session (nil = unlimited).
+	budget *sessionBudget
+	// authDisabled lists sessions whose API credentials were rejected (401/403).
+	authDisabled *authDisabledSessions
+
 	// effectiveFormats is the resolved set of content formats eligible for compression.
 	effectiveFormats map[adapters.ContentFormat]bool
 
@@ -96,14 +139,24 @@ type Pipe struct {
 
 // Metrics tracks compression statistics.
 type Metrics struct {
-	CacheHits       int64
-	CacheMisses     int64
-	CompressionOK   int64
-	CompressionFail int64
-	ExpandRequests  int64
-	ExpandCacheMiss int64
-	RateLimited     int64
-	TokensSaved     int64
+	CacheHits          int64
+	CacheMisses        int64
+	CompressionOK      int64
+	CompressionFail    int64
+	ExpandRequests     int64
+	ExpandCacheMiss    int64
+	RateLimited        int64
+	QueueFull          int64
+	PreserveMissed     int64 // Compressions rejected for dropping a preserve_patterns match
+	LowSavingsRejected int64 // Compressions rejected for saving less than refusal_threshold
+	StoreUnavailable   int64 // Requests degraded to passthrough after a store write failed
+	TargetMissed       int64 // Adaptive mode: outputs still above target after all retries
+	AdaptiveRetries    int64 // Adaptive mode: extra API calls made to hit the target
+	BudgetExhausted    int64 // Outputs sent to the fallback chain after the session budget ran out
+	AuthDisabled       int64 // Outputs sent to the fallback chain because the API rejected credentials
+	InjectionFlagged   int64 // Tool outputs flagged by detect_injection
+	ChunkedOutputs     int64 // Outputs above max_tokens compressed hierarchically
+	TokensSaved        int64
 }
 
 // RateLimiter implements token bucket rate limiting.
@@ -217,59 +270,110 @@ func New(cfg *config.Config, st store.Store) *Pipe {
 		refusalThreshold = DefaultRefusalThreshold
 	}
 
-	fallbackStrategy := cfg.Pipes.ToolOutput.FallbackStrategy
-	if fallbackStrategy == "" {
-		fallbackStrategy = config.StrategyPassthrough
+	// fallback_chain wins; otherwise the single fallback_strategy is a one-level chain.
+	fallbackChain := cfg.Pipes.ToolOutput.FallbackChain
+	if len(fallbackChain) == 0 {
+		fallbackStrategy := cfg.Pipes.ToolOutput.FallbackStrategy
+		if fallbackStrategy == "" {
+			fallbackStrategy = config.StrategyPassthrough
+		}
+		fallbackChain = []string{fallbackStrategy}
 	}
 
-	maxConcurrent := MaxConcurrentCompressions
+	maxConcurrent := cfg.Pipes.ToolOutput.Compresr.MaxConcurrency
+	if maxConcurrent <= 0 {
+		maxConcurrent = MaxConcurrentCompressions
+	}
+	maxQueueDepth := cfg.Pipes.ToolOutput.Compresr.MaxQueueDepth
+	if maxQueueDepth <= 0 {
+		maxQueueDepth = config.DefaultCompressionQueueDepth
+	}
 	maxPerSecond := MaxCompressionsPerSecond
 
 	skipCategories := cfg.Pipes.ToolOutput.SkipTools.Categories
 
+	neverCompress := make(map[string]bool, len(cfg.Pipes.ToolOutput.NeverCompressTools))
+	for _, name := range cfg.Pipes.ToolOutput.NeverCompressTools {
+		neverCompress[name] = true
+	}
+
+	budget := newSessionBudget(cfg.Pipes.ToolOutput.MaxCompressionCallsPerSession, cfg.Pipes.ToolOutput.MaxCompressionBytesPerSession)
+
 	effectiveFormats := adapters.BuildEffectiveFormats(
 		cfg.Pipes.ToolOutput.ContentFormats.Allowed,
 		cfg.Pipes.ToolOutput.ContentFormats.Forbidden,
 	)
 
-	compresrTimeout := cfg.Pipes.ToolOutput.Compresr.Timeout
-	if compresrTimeout == 0 {
-		compresrTimeout = 30 * time.Second
+	// Validated at config load; an invalid pattern here disables the check.
+	preservePatterns, err := pipes.CompilePreservePatterns(cfg.Pipes.ToolOutput.PreservePatterns)
+	if err != nil {
+		log.Error().Err(err).Msg("tool_output: ignoring preserve_patterns")
 	}
 
+	compresrTimeouts := cfg.Pipes.ToolOutput.Compresr.EffectiveTimeouts(30 * time.Second)
+	compresrTimeout := compresrTimeouts.Overall
+
 	p := &Pipe{
 		enabled:                cfg.Pipes.ToolOutput.Enabled,
 		strategy:               cfg.Pipes.ToolOutput.Strategy,
-		fallbackStrategy:       fallbackStrategy,
+		fallbackChain:          fallbackChain,
 		minTokens:              minTokens,
 		maxTokens:              maxTokens,
 		targetCompressionRatio: targetCompressionRatio,
+		maxCompressionRetries:  cfg.Pipes.ToolOutput.Compresr.MaxCompressionRetries,
 		refusalThreshold:       refusalThreshold,
-		includeExpandHint:      cfg.Pipes.ToolOutput.IncludeExpandHint || cfg.Pipes.ToolOutput.EnableExpandContext,
-		enableExpandContext:    cfg.Pipes.ToolOutput.EnableExpandContext,
+		includeExpandHint:      (cfg.Pipes.ToolOutput.IncludeExpandHint || cfg.Pipes.ToolOutput.EnableExpandContext) && !cfg.Pipes.ToolOutput.LossyMode,
+		expandHintTemplate:     cfg.Pipes.ToolOutput.ExpandHintTemplate,
+		expandHintAfter:        cfg.Pipes.ToolOutput.ExpandHintAppended(),
+		enableExpandContext:    cfg.Pipes.ToolOutput.EnableExpandContext && !cfg.Pipes.ToolOutput.LossyMode,
+		lossyMode:              cfg.Pipes.ToolOutput.LossyMode,
 		bypassCostCheck:        cfg.Pipes.ToolOutput.BypassCostCheck,
+		dedupeIdentical:        cfg.Pipes.ToolOutput.DedupeIdentical,
+		dedupeToolUseIDs:       cfg.Pipes.ToolOutput.DedupeToolUseIDs,
+		compressErrors:         cfg.Pipes.ToolOutput.CompressErrorsEnabled(),
+		canonicalizeJSON:       cfg.Pipes.ToolOutput.CanonicalizeJSON,
+		compressToolInputs:     cfg.Pipes.ToolOutput.CompressToolInputs,
+		compressTopK:           cfg.Pipes.ToolOutput.CompressTopK,
+		keepTailBytes:          cfg.Pipes.ToolOutput.KeepTailBytes,
+		hierarchical:           cfg.Pipes.ToolOutput.Hierarchical.Enabled,
+		maxChunks:              cfg.Pipes.ToolOutput.Hierarchical.EffectiveMaxChunks(),
+		mergeChunkSummaries:    cfg.Pipes.ToolOutput.Hierarchical.MergeSummaries,
+		emptyPlaceholder:       cfg.Pipes.ToolOutput.EmptyOutputPlaceholder,
+		maxAdvertisedShadows:   cfg.Pipes.ToolOutput.MaxAdvertisedShadows,
+		detectInjection:        cfg.Pipes.ToolOutput.DetectInjection,
+		preservePatterns:       preservePatterns,
 		store:                  st,
 
 		compresrEndpoint:      compresrEndpoint,
 		compresrKey:           compresrKey,
 		compresrModel:         compresrModel,
+		compresrModelByFormat: cfg.Pipes.ToolOutput.Compresr.ModelByFormat,
 		compresrTimeout:       compresrTimeout,
 		compresrQueryAgnostic: cfg.Pipes.ToolOutput.Compresr.QueryAgnostic,
 
 		maxConcurrent:    maxConcurrent,
+		maxQueueDepth:    maxQueueDepth,
 		maxPerSecond:     maxPerSecond,
 		semaphore:        make(chan struct{}, maxConcurrent),
 		rateLimiter:      NewRateLimiter(maxPerSecond),
 		metrics:          &Metrics{},
 		skipCategories:   skipCategories,
+		neverCompress:    neverCompress,
+		budget:           budget,
+		authDisabled:     newAuthDisabledSessions(),
+		advertised:       newAdvertisedHints(),
+		flagged:          newFlaggedOutputs(),
 		effectiveFormats: effectiveFormats,
 		circuit:          circuitbreaker.New(),
+		shadowIDs:        HashShadowIDGenerator{},
 	}
 
 	if cfg.Pipes.ToolOutput.Strategy == config.StrategyCompresr {
 		baseURL := cfg.URLs.Compresr
-		p.compresrClient = compresr.NewClient(baseURL, compresrKey, compresr.WithTimeout(compresrTimeout))
-		log.Info().Str("base_url", baseURL).Str("model", compresrModel).Dur("timeout", compresrTimeout).Msg("tool_output: initialized Compresr client for compresr strategy")
+		p.compresrClient = compresr.NewClient(baseURL, compresrKey,
+			compresr.WithHTTPClient(compresrTimeouts.NewHTTPClient()),
+			compresr.WithDebugDumpDir(cfg.Pipes.ToolOutput.Compresr.DebugDumpDir))
+		log.Info().Str("base_url", baseURL).Str("model", compresrModel).Dur("timeout", compresrTimeout).Dur("connect_timeout", compresrTimeouts.ConnectLimit()).Msg("tool_output: initialized Compresr client for compresr strategy")
 	}
 
 	if p.compresrKey == "" && cfg.Pipes.ToolOutput.Strategy == config.StrategyExternalProvider {
@@ -278,6 +382,9 @@ func New(cfg *config.Config, st store.Store) *Pipe {
 	if len(skipCategories) > 0 {
 		log.Info().Strs("categories", skipCategories).Msg("tool_output: skip_tools categories configured (resolved per-request by provider)")
 	}
+	if len(neverCompress) > 0 {
+		log.Info().Strs("tools", cfg.Pipes.ToolOutput.NeverCompressTools).Msg("tool_output: never_compress_tools configured")
+	}
 
 	return p
 }
@@ -327,6 +434,9 @@ type compressionTask struct {
 	original     string
 	messageIndex int
 	blockIndex   int
+	overBudget   bool // session compression budget exhausted; use the fallback chain
+	sessionID    string
+	chunks       []string // above max_tokens: compressed hierarchically, one call per chunk
 }
 
 // message is a minimal message struct for internal use
@@ -345,6 +455,9 @@ type compressionResult struct {
 	compressedContent string
 	success           bool
 	usedFallback      bool
+	attempts          int  // compression API calls made (adaptive mode)
+	targetMissed      bool // best result still missed target_compression_ratio
+	budgetExhausted   bool // produced by the fallback chain after the session budget ran out
 	err               error
 	messageIndex      int
 	blockIndex        int
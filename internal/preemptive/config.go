@@ -17,6 +17,12 @@ var DefaultOpenClawPromptPatterns = []string{
 	"preserve all opaque identifiers exactly as written",
 }
 
+// DefaultKeepRecentTurns is the number of turns kept verbatim by the recency_window strategy.
+const DefaultKeepRecentTurns = 4
+
+// DefaultToolResultTriggerTokens is the tool_result_compaction batch threshold.
+const DefaultToolResultTriggerTokens = 20000
+
 // DEFAULT PROMPT PATTERNS (per provider)
 
 // DefaultClaudeCodePromptPatterns are phrases that indicate a Claude Code /compact request.
@@ -58,7 +64,8 @@ Be specific. Be thorough. Capture what matters, not just what happened.`
 // MODEL CONTEXT WINDOWS
 
 // DefaultModelContextWindows contains known model context windows.
-// Key: model name, Value: context window configuration.
+// Key: model name (dated variants such as "claude-sonnet-4-20250514" match
+// their base key), Value: context window configuration.
 var DefaultModelContextWindows = map[string]ModelContextWindow{
 	// Test models
 	"test-model-small": {Model: "test-model-small", MaxTokens: 10000, OutputMax: 2000, EffectiveMax: 8000},
@@ -71,14 +78,35 @@ var DefaultModelContextWindows = map[string]ModelContextWindow{
 	"claude-haiku-4-5-20251001":  {Model: "claude-haiku-4-5-20251001", MaxTokens: 200000, OutputMax: 64000, EffectiveMax: 136000},
 	"claude-haiku-4-5":           {Model: "claude-haiku-4-5", MaxTokens: 200000, OutputMax: 64000, EffectiveMax: 136000},
 
+	"claude-opus-4-5":   {Model: "claude-opus-4-5", MaxTokens: 200000, OutputMax: 64000, EffectiveMax: 136000},
+	"claude-opus-4-1":   {Model: "claude-opus-4-1", MaxTokens: 200000, OutputMax: 32000, EffectiveMax: 168000},
+	"claude-opus-4":     {Model: "claude-opus-4", MaxTokens: 200000, OutputMax: 32000, EffectiveMax: 168000},
+	"claude-sonnet-4":   {Model: "claude-sonnet-4", MaxTokens: 200000, OutputMax: 64000, EffectiveMax: 136000},
+	"claude-3-7-sonnet": {Model: "claude-3-7-sonnet", MaxTokens: 200000, OutputMax: 64000, EffectiveMax: 136000},
+	"claude-3-5-sonnet": {Model: "claude-3-5-sonnet", MaxTokens: 200000, OutputMax: 8192, EffectiveMax: 191808},
+	"claude-3-5-haiku":  {Model: "claude-3-5-haiku", MaxTokens: 200000, OutputMax: 8192, EffectiveMax: 191808},
+	"claude-3-haiku":    {Model: "claude-3-haiku", MaxTokens: 200000, OutputMax: 4096, EffectiveMax: 195904},
+
 	// OpenAI models
 	"gpt-4-turbo":         {Model: "gpt-4-turbo", MaxTokens: 128000, OutputMax: 4096, EffectiveMax: 123904},
 	"gpt-4-turbo-preview": {Model: "gpt-4-turbo-preview", MaxTokens: 128000, OutputMax: 4096, EffectiveMax: 123904},
 	"gpt-4o":              {Model: "gpt-4o", MaxTokens: 128000, OutputMax: 16384, EffectiveMax: 111616},
 	"gpt-4o-mini":         {Model: "gpt-4o-mini", MaxTokens: 128000, OutputMax: 16384, EffectiveMax: 111616},
+	"gpt-4.1":             {Model: "gpt-4.1", MaxTokens: 1047576, OutputMax: 32768, EffectiveMax: 1014808},
+	"gpt-4.1-mini":        {Model: "gpt-4.1-mini", MaxTokens: 1047576, OutputMax: 32768, EffectiveMax: 1014808},
+	"gpt-5":               {Model: "gpt-5", MaxTokens: 400000, OutputMax: 128000, EffectiveMax: 272000},
+	"gpt-5-mini":          {Model: "gpt-5-mini", MaxTokens: 400000, OutputMax: 128000, EffectiveMax: 272000},
+	"o3":                  {Model: "o3", MaxTokens: 200000, OutputMax: 100000, EffectiveMax: 100000},
+	"o4-mini":             {Model: "o4-mini", MaxTokens: 200000, OutputMax: 100000, EffectiveMax: 100000},
 
 	// OpenAI Codex models (ChatGPT subscription)
 	"gpt-5.3-codex": {Model: "gpt-5.3-codex", MaxTokens: 64000, OutputMax: 16384, EffectiveMax: 47616},
+
+	// Google Gemini models
+	"gemini-2.5-pro":   {Model: "gemini-2.5-pro", MaxTokens: 1048576, OutputMax: 65536, EffectiveMax: 983040},
+	"gemini-2.5-flash": {Model: "gemini-2.5-flash", MaxTokens: 1048576, OutputMax: 65536, EffectiveMax: 983040},
+	"gemini-2.0-flash": {Model: "gemini-2.0-flash", MaxTokens: 1048576, OutputMax: 8192, EffectiveMax: 1040384},
+	"gemini-1.5-pro":   {Model: "gemini-1.5-pro", MaxTokens: 2097152, OutputMax: 8192, EffectiveMax: 2088960},
 }
 
 // DefaultUnknownModelContextWindow is the fallback for unknown models.
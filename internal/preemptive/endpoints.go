@@ -0,0 +1,69 @@
+// Summarizer endpoint pool for preemptive.summarizer.endpoints.
+package preemptive
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// endpointCooldown is how long a failed endpoint is tried last.
+const endpointCooldown = 30 * time.Second
+
+// endpointPool spreads summarizer calls across the configured endpoints.
+// order() gives the try order for one call: healthy endpoints first (by
+// round-robin position or in-flight count), endpoints that failed within
+// endpointCooldown last, so a call still succeeds if only those are left.
+type endpointPool struct {
+	endpoints   []SummarizerEndpoint
+	leastLoaded bool
+
+	next        atomic.Uint64
+	inFlight    []atomic.Int64
+	failedUntil []atomic.Int64 // unix nanos
+}
+
+func newEndpointPool(endpoints []SummarizerEndpoint, selection string) *endpointPool {
+	return &endpointPool{
+		endpoints:   endpoints,
+		leastLoaded: selection == EndpointSelectionLeastLoaded,
+		inFlight:    make([]atomic.Int64, len(endpoints)),
+		failedUntil: make([]atomic.Int64, len(endpoints)),
+	}
+}
+
+// order returns endpoint indexes in the order one call should try them.
+func (p *endpointPool) order() []int {
+	n := len(p.endpoints)
+	start := int((p.next.Add(1) - 1) % uint64(n)) // #nosec G115 -- n > 0, result < n
+	idx := make([]int, n)
+	for k := range idx {
+		idx[k] = (start + k) % n
+	}
+
+	now := time.Now().UnixNano()
+	sort.SliceStable(idx, func(a, b int) bool {
+		coolA, coolB := p.failedUntil[idx[a]].Load() > now, p.failedUntil[idx[b]].Load() > now
+		if coolA != coolB {
+			return !coolA
+		}
+		if p.leastLoaded {
+			return p.inFlight[idx[a]].Load() < p.inFlight[idx[b]].Load()
+		}
+		return false
+	})
+	return idx
+}
+
+// acquire marks a call to endpoint i as in flight.
+func (p *endpointPool) acquire(i int) { p.inFlight[i].Add(1) }
+
+// release ends a call to endpoint i; a failure puts it in cooldown.
+func (p *endpointPool) release(i int, err error) {
+	p.inFlight[i].Add(-1)
+	if err != nil {
+		p.failedUntil[i].Store(time.Now().Add(endpointCooldown).UnixNano())
+	} else {
+		p.failedUntil[i].Store(0)
+	}
+}
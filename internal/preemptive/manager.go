@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"strconv"
 	"sync"
 
 	"github.com/compresr/context-gateway/internal/adapters"
@@ -39,21 +40,28 @@ type Manager struct {
 	summary  *Summarizer
 	worker   *Worker
 	enabled  bool
+
+	// notes caches tool_result_compaction batches; kept across config reloads.
+	notes *toolResultNotes
+	// bgCtx bounds background tool_result summarization; cancelled by Stop.
+	bgCtx    context.Context
+	bgCancel context.CancelFunc
 }
 
 // NewManager creates a preemptive summarization manager.
 // If cfg.Enabled is false, returns a no-op manager that passes requests through unchanged.
 func NewManager(cfg Config) *Manager {
 	cfg = WithDefaults(cfg)
-	m := &Manager{config: cfg, enabled: cfg.Enabled}
+	m := &Manager{config: cfg, enabled: cfg.Enabled, notes: newToolResultNotes()}
+	m.bgCtx, m.bgCancel = context.WithCancel(context.Background())
 
 	if !cfg.Enabled {
 		return m
 	}
 
 	m.sessions = NewSessionManager(cfg.Session)
-	m.summary = NewSummarizer(cfg.Summarizer)
-	m.worker = NewWorker(m.summary, m.sessions, cfg.Summarizer, cfg.TriggerThreshold)
+	m.summary = newSummarizerChain(cfg)
+	m.worker = NewWorker(m.summary, m.sessions, cfg.Summarizer, cfg.TriggerThreshold, cfg.RecentTurnsToKeep())
 	m.worker.Start()
 
 	initLogger(cfg)
@@ -84,8 +92,8 @@ func (m *Manager) UpdateConfig(cfg Config) {
 		if existingSessions == nil {
 			existingSessions = NewSessionManager(cfg.Session)
 		}
-		newSummary := NewSummarizer(cfg.Summarizer)
-		newWorker = NewWorker(newSummary, existingSessions, cfg.Summarizer, cfg.TriggerThreshold)
+		newSummary := newSummarizerChain(cfg)
+		newWorker = NewWorker(newSummary, existingSessions, cfg.Summarizer, cfg.TriggerThreshold, cfg.RecentTurnsToKeep())
 		newWorker.Start()
 	}
 
@@ -117,6 +125,7 @@ func (m *Manager) Stop() {
 	if worker != nil {
 		worker.Stop()
 	}
+	m.bgCancel()
 }
 
 // SetAuth passes captured auth credentials to the summarizer.
@@ -272,22 +281,23 @@ func (m *Manager) parseRequest(headers http.Header, body []byte, model, provider
 	auth := authtypes.CaptureFromHeaders(headers)
 
 	return &request{
-		messages:  messages,
-		model:     model,
-		sessionID: sessionID,
-		provider:  provider,
-		detection: detection,
-		auth:      auth,
+		messages:   messages,
+		model:      model,
+		sessionID:  sessionID,
+		provider:   provider,
+		detection:  detection,
+		tokenCount: tokenizer.CountBytes(body),
+		auth:       auth,
 	}, nil
 }
 
 // handleNormalRequest processes a non-compaction request.
 func (m *Manager) handleNormalRequest(req *request, body []byte, cfg Config, sessions *SessionManager) ([]byte, bool, []byte, map[string]string, error) {
-	effectiveMax := getEffectiveMax(req.model, cfg)
+	effectiveMax := cfg.ContextWindow(req.model)
 	session := sessions.GetOrCreateSession(req.sessionID, req.model, effectiveMax)
 
 	// Update usage tracking
-	tokenCount := tokenizer.CountBytes(body)
+	tokenCount := req.tokenCount
 	usage := CalculateUsage(tokenCount, effectiveMax)
 	_ = sessions.Update(req.sessionID, func(s *Session) {
 		s.LastKnownTokens = tokenCount
@@ -313,24 +323,25 @@ func (m *Manager) handleCompaction(ctx context.Context, req *request, cfg Config
 	logCompactionDetected(req.sessionID, req.model, req.detection)
 
 	session := sessions.Get(req.sessionID)
+	headers := compactionHeaders(CalculateUsage(req.tokenCount, cfg.ContextWindow(req.model)), cfg)
 
 	// Try each strategy in order
 	if result := m.tryPrecomputed(session, req); result != nil {
-		body, isCompaction, synthetic, err := m.buildResponse(req, result, true, sessions)
-		return body, isCompaction, synthetic, nil, err
+		body, isCompaction, synthetic, err := m.buildResponse(req, cfg, result, true, sessions)
+		return body, isCompaction, synthetic, headers, err
 	}
 
 	if result := m.tryPending(session, req, cfg, sessions, worker); result != nil {
-		body, isCompaction, synthetic, err := m.buildResponse(req, result, true, sessions)
-		return body, isCompaction, synthetic, nil, err
+		body, isCompaction, synthetic, err := m.buildResponse(req, cfg, result, true, sessions)
+		return body, isCompaction, synthetic, headers, err
 	}
 
 	result, err := m.doSynchronous(ctx, req, cfg, sessions, summary)
 	if err != nil {
 		return nil, true, nil, nil, err
 	}
-	body, isCompaction, synthetic, err := m.buildResponse(req, result, false, sessions)
-	return body, isCompaction, synthetic, nil, err
+	body, isCompaction, synthetic, err := m.buildResponse(req, cfg, result, false, sessions)
+	return body, isCompaction, synthetic, headers, err
 }
 
 // tryPrecomputed returns cached summary if available.
@@ -398,6 +409,8 @@ func (m *Manager) doSynchronous(ctx context.Context, req *request, cfg Config, s
 		TriggerThreshold: cfg.TriggerThreshold,
 		KeepRecentTokens: cfg.Summarizer.KeepRecentTokens,
 		KeepRecentCount:  cfg.Summarizer.KeepRecentCount,
+		KeepRecentTurns:  cfg.RecentTurnsToKeep(),
+		ContextWindow:    cfg.ContextWindow(req.model),
 		Model:            req.model,
 		Auth:             req.auth,
 	})
@@ -406,6 +419,8 @@ func (m *Manager) doSynchronous(ctx context.Context, req *request, cfg Config, s
 		return nil, fmt.Errorf("summarization failed: %w", err)
 	}
 
+	log.Info().Str("session", req.sessionID).Str("summarized_by", result.SummarizedBy).Msg("Synchronous summarization completed")
+
 	// Cache for potential reuse
 	_ = sessions.SetSummaryReady(req.sessionID, result.Summary, result.SummaryTokens, result.LastSummarizedIndex, len(req.messages))
 
@@ -423,7 +438,7 @@ func (m *Manager) doSynchronous(ctx context.Context, req *request, cfg Config, s
 // NOTE: We keep the summary in StateReady after use, allowing multiple compaction
 // requests to reuse the same precomputed summary. The summary will be replaced
 // when a new preemptive trigger occurs after the conversation continues.
-func (m *Manager) buildResponse(req *request, result *summaryResult, wasPrecomputed bool, sessions *SessionManager) ([]byte, bool, []byte, error) {
+func (m *Manager) buildResponse(req *request, cfg Config, result *summaryResult, wasPrecomputed bool, sessions *SessionManager) ([]byte, bool, []byte, error) {
 	// Increment use counter but keep summary available (StateReady)
 	sessions.IncrementUseCount(req.sessionID)
 	logCompactionApplied(req.sessionID, req.model, wasPrecomputed, result)
@@ -433,18 +448,23 @@ func (m *Manager) buildResponse(req *request, result *summaryResult, wasPrecompu
 	excludeLastMessage := req.detection.DetectedBy == "claude_code_prompt" ||
 		req.detection.DetectedBy == "openai_prompt"
 
+	summaryText := result.summary
+	if cfg.SummaryNoteEnabled() {
+		summaryText = LabelSummary(summaryText)
+	}
+
 	switch req.provider {
 	case adapters.ProviderAnthropic:
 		// Summary + recent messages appended (excluding compaction prompt if applicable)
-		synthetic := BuildAnthropicResponse(result.summary, req.messages, result.lastIndex, req.model, excludeLastMessage)
+		synthetic := BuildAnthropicResponse(summaryText, req.messages, result.lastIndex, req.model, excludeLastMessage)
 		return nil, true, synthetic, nil
 
 	case adapters.ProviderOpenAI:
-		compacted := BuildOpenAICompactedRequest(req.messages, result.summary, result.lastIndex, excludeLastMessage)
+		compacted := BuildOpenAICompactedRequest(req.messages, summaryText, result.lastIndex, excludeLastMessage)
 		return compacted, true, nil, nil
 
 	default:
-		synthetic := BuildAnthropicResponse(result.summary, req.messages, result.lastIndex, req.model, excludeLastMessage)
+		synthetic := BuildAnthropicResponse(summaryText, req.messages, result.lastIndex, req.model, excludeLastMessage)
 		return nil, true, synthetic, nil
 	}
 }
@@ -482,21 +502,17 @@ func (m *Manager) triggerIfNeeded(session *Session, req *request, usage float64)
 	worker.Submit(req.sessionID, req.messages, req.model, req.auth)
 }
 
-func getEffectiveMax(model string, cfg Config) int {
-	if cfg.TestContextWindowOverride > 0 {
-		return cfg.TestContextWindowOverride
-	}
-	return GetModelContextWindow(model).EffectiveMax
-}
-
 func buildHeaders(session *Session, usage TokenUsage, cfg Config) map[string]string {
 	if !cfg.AddResponseHeaders {
 		return nil
 	}
 
 	headers := map[string]string{
-		"X-Context-Usage":  fmt.Sprintf("%.1f%%", usage.UsagePercent),
-		"X-Context-Tokens": fmt.Sprintf("%d/%d", usage.InputTokens, usage.MaxTokens),
+		"X-Context-Usage":            fmt.Sprintf("%.1f%%", usage.UsagePercent),
+		"X-Context-Tokens":           fmt.Sprintf("%d/%d", usage.InputTokens, usage.MaxTokens),
+		"X-CG-Context-Usage-Percent": fmt.Sprintf("%.1f", usage.UsagePercent),
+		// Usage has reached trigger_threshold: preemptive summarization is running or done.
+		"X-CG-Compaction-Triggered": strconv.FormatBool(cfg.TriggerThreshold > 0 && usage.UsagePercent >= cfg.TriggerThreshold),
 	}
 
 	if session != nil {
@@ -510,6 +526,18 @@ func buildHeaders(session *Session, usage TokenUsage, cfg Config) map[string]str
 	return headers
 }
 
+// compactionHeaders reports context usage on the response to a compaction
+// request and marks it as a compaction.
+func compactionHeaders(usage TokenUsage, cfg Config) map[string]string {
+	if !cfg.AddResponseHeaders {
+		return nil
+	}
+	return map[string]string{
+		"X-CG-Context-Usage-Percent": fmt.Sprintf("%.1f", usage.UsagePercent),
+		"X-CG-Compaction-Triggered":  "true",
+	}
+}
+
 func (m *Manager) Stats() map[string]any {
 	// snapshot fields under lock to avoid races with UpdateConfig
 	m.mu.RLock()
@@ -0,0 +1,233 @@
+// Context overflow guard.
+//
+// Preemptive summarization keeps most sessions under the context window, but a
+// single huge turn or a session that grew faster than the background summary
+// can still produce a request the model will reject. GuardOverflow runs on the
+// final body, after every pipe, and applies overflow_action so such a request
+// is never forwarded as-is:
+//
+//	compact:  summarize older turns now and send summary + recent turns
+//	truncate: drop the oldest turns locally until the request fits
+//	error:    reject the request with an OverflowError
+//
+// Because truncate and error destroy history or reject the request, the guard
+// counts text only: base64 images, documents and other binary payloads are
+// replaced by a fixed per-payload estimate instead of being counted as text.
+// A model missing from model_context_windows and the built-in table is checked
+// against default_context_window (see Config.ContextWindow).
+package preemptive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/sjson"
+
+	authtypes "github.com/compresr/context-gateway/internal/auth/types"
+	"github.com/compresr/context-gateway/internal/formats"
+	"github.com/compresr/context-gateway/internal/tokenizer"
+)
+
+// overflowPayloadTokens is charged per binary payload (image, PDF, ...) in
+// place of its encoded bytes; providers bill an image at most ~1600 tokens.
+const overflowPayloadTokens = 1600
+
+// truncationNoteFormat replaces the turns dropped by overflow_action: truncate.
+const truncationNoteFormat = "[%d earlier messages were dropped because the conversation exceeded the model's context window.]"
+
+// OverflowError reports a request that exceeds the model's context window and
+// could not be brought under it by overflow_action.
+type OverflowError struct {
+	Model  string
+	Tokens int
+	Window int
+	Action string
+	Reason string // Why the action could not fix the request (empty for "error")
+}
+
+func (e *OverflowError) Error() string {
+	msg := fmt.Sprintf("context overflow: request is ~%d tokens but %s has a %d-token context window, even after compression (preemptive.overflow_action: %s)",
+		e.Tokens, e.Model, e.Window, e.Action)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// GuardOverflow checks body against the model's context window and applies
+// overflow_action when it does not fit. Returns the body to forward and whether
+// it was rewritten; an *OverflowError means the request must not be forwarded.
+// A body within the window, or any body when overflow_action is unset, is
+// returned unchanged.
+func (m *Manager) GuardOverflow(ctx context.Context, headers http.Header, body []byte, model string) ([]byte, bool, error) {
+	m.mu.RLock()
+	cfg := m.config
+	summary := m.summary
+	m.mu.RUnlock()
+	if cfg.OverflowAction == OverflowActionNone {
+		return body, false, nil
+	}
+
+	window := cfg.ContextWindow(model)
+	tokens := overflowTokens(body)
+	if tokens <= window {
+		return body, false, nil
+	}
+
+	overflow := &OverflowError{Model: model, Tokens: tokens, Window: window, Action: cfg.OverflowAction}
+	log.Warn().
+		Str("model", model).
+		Int("tokens", tokens).
+		Int("context_window", window).
+		Str("overflow_action", cfg.OverflowAction).
+		Msg("preemptive: request exceeds context window after compression")
+
+	var out []byte
+	var err error
+	switch cfg.OverflowAction {
+	case OverflowActionCompact:
+		out, err = compactOverflow(ctx, headers, body, model, window, cfg, summary)
+	case OverflowActionTruncate:
+		out, err = truncateOverflow(body, window)
+	default:
+		return nil, false, overflow
+	}
+	if err != nil {
+		overflow.Reason = err.Error()
+		return nil, false, overflow
+	}
+
+	log.Info().
+		Str("model", model).
+		Str("overflow_action", cfg.OverflowAction).
+		Int("tokens_before", tokens).
+		Int("tokens_after", overflowTokens(out)).
+		Msg("preemptive: request brought under context window")
+	return out, true, nil
+}
+
+// compactOverflow summarizes the older turns of body synchronously and
+// replaces them with the summary. Fails if the result still does not fit.
+func compactOverflow(ctx context.Context, headers http.Header, body []byte, model string, window int, cfg Config, summary *Summarizer) ([]byte, error) {
+	if summary == nil {
+		return nil, fmt.Errorf("no summarizer configured")
+	}
+	messages, err := ParseMessages(body)
+	if err != nil || len(messages) == 0 {
+		return nil, fmt.Errorf("request has no messages to compact")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.SyncTimeout)
+	defer cancel()
+	result, err := summary.Summarize(ctx, SummarizeInput{
+		Messages:         messages,
+		TriggerThreshold: cfg.TriggerThreshold,
+		KeepRecentTokens: cfg.Summarizer.KeepRecentTokens,
+		KeepRecentCount:  cfg.Summarizer.KeepRecentCount,
+		KeepRecentTurns:  cfg.RecentTurnsToKeep(),
+		ContextWindow:    window,
+		Model:            model,
+		Auth:             authtypes.CaptureFromHeaders(headers),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("summarization failed: %w", err)
+	}
+
+	summaryText := result.Summary
+	if cfg.SummaryNoteEnabled() {
+		summaryText = LabelSummary(summaryText)
+	}
+	out, err := replaceMessages(body, BuildOpenAICompactedRequest(messages, summaryText, result.LastSummarizedIndex, false))
+	if err != nil {
+		return nil, err
+	}
+	if tokens := overflowTokens(out); tokens > window {
+		return nil, fmt.Errorf("still ~%d tokens after compaction", tokens)
+	}
+	return out, nil
+}
+
+// truncateOverflow drops the oldest turns of body, replacing them with a short
+// note, until it fits in window. Cuts only at turn starts that keep every tool
+// call with its result. Fails if even the last turn alone does not fit.
+func truncateOverflow(body []byte, window int) ([]byte, error) {
+	messages, err := ParseMessages(body)
+	if err != nil || len(messages) == 0 {
+		return nil, fmt.Errorf("request has no messages to truncate")
+	}
+
+	// Estimate each cut from per-message counts; only candidates that look
+	// like they fit are built and counted in full.
+	remaining := overflowTokens(body)
+	for i := 1; i < len(messages); i++ {
+		remaining -= overflowTokens(messages[i-1])
+		if !isTurnStart(messages[i]) || splitsToolPair(messages, i-1) || remaining > window {
+			continue
+		}
+		note := fmt.Sprintf(truncationNoteFormat, i)
+		out, err := replaceMessages(body, BuildOpenAICompactedRequest(messages, note, i-1, false))
+		if err != nil {
+			return nil, err
+		}
+		if overflowTokens(out) <= window {
+			log.Info().Int("messages_dropped", i).Msg("preemptive: truncated oldest turns to fit context window")
+			return out, nil
+		}
+	}
+	return nil, fmt.Errorf("the latest turn alone exceeds the context window")
+}
+
+// overflowTokens estimates the tokens of a JSON request (or part of one),
+// counting each binary or base64 string value (image data, data: URLs, ...) as
+// overflowPayloadTokens rather than by its encoded size.
+func overflowTokens(raw []byte) int {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return tokenizer.CountBytes(raw)
+	}
+	payloads := 0
+	v = stripBinaryPayloads(v, &payloads)
+	if payloads == 0 {
+		return tokenizer.CountBytes(raw)
+	}
+	stripped, err := json.Marshal(v)
+	if err != nil {
+		return tokenizer.CountBytes(raw)
+	}
+	return tokenizer.CountBytes(stripped) + payloads*overflowPayloadTokens
+}
+
+// stripBinaryPayloads empties every binary string value in v, counting them.
+func stripBinaryPayloads(v any, payloads *int) any {
+	switch t := v.(type) {
+	case string:
+		if formats.IsBinary(t) {
+			*payloads++
+			return ""
+		}
+	case []any:
+		for i := range t {
+			t[i] = stripBinaryPayloads(t[i], payloads)
+		}
+	case map[string]any:
+		for k := range t {
+			t[k] = stripBinaryPayloads(t[k], payloads)
+		}
+	}
+	return v
+}
+
+// replaceMessages swaps the messages array of body for the one in compacted,
+// keeping every other field (model, tools, system, ...).
+func replaceMessages(body, compacted []byte) ([]byte, error) {
+	var c struct {
+		Messages json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(compacted, &c); err != nil {
+		return nil, err
+	}
+	return sjson.SetRawBytes(body, "messages", c.Messages)
+}
@@ -31,6 +31,13 @@ type Summarizer struct {
 	// bedrockClient is the cached HTTP client with SigV4 signing for Bedrock.
 	// Initialized once in NewSummarizer to avoid per-call transport creation.
 	bedrockClient *http.Client
+
+	// endpoints rotates LLM calls over summarizer.endpoints. Nil = single endpoint.
+	endpoints *endpointPool
+
+	// fallback is tried when this summarizer fails (preemptive.fallback_summarizer).
+	// When it fails too, the oldest turns are truncated locally. Nil = no fallback.
+	fallback *Summarizer
 }
 
 // NewSummarizer creates a new summarizer.
@@ -41,6 +48,9 @@ func NewSummarizer(cfg SummarizerConfig) *Summarizer {
 	if cfg.Strategy == StrategyCompresr && cfg.Compresr != nil {
 		s.compresrClient = compresr.NewClient(cfg.CompresrBaseURL, cfg.Compresr.APIKey, compresr.WithTimeout(cfg.Compresr.Timeout))
 	}
+	if cfg.Strategy != StrategyCompresr && len(cfg.Endpoints) > 0 {
+		s.endpoints = newEndpointPool(cfg.Endpoints, cfg.EndpointSelection)
+	}
 	if cfg.Provider == "bedrock" {
 		if client, err := s.buildBedrockHTTPClient(); err == nil {
 			s.bedrockClient = client
@@ -50,12 +60,24 @@ func NewSummarizer(cfg SummarizerConfig) *Summarizer {
 	return s
 }
 
+// newSummarizerChain creates the primary summarizer with the configured fallback attached.
+func newSummarizerChain(cfg Config) *Summarizer {
+	s := NewSummarizer(cfg.Summarizer)
+	if cfg.FallbackSummarizer != nil {
+		s.fallback = NewSummarizer(*cfg.FallbackSummarizer)
+	}
+	return s
+}
+
 // SetAuth stores auth captured from an incoming request.
 // Used when no API key is configured (e.g., Max/Pro subscription users).
 func (s *Summarizer) SetAuth(auth authtypes.CapturedAuth) {
 	if !auth.HasAuth() {
 		return
 	}
+	if s.fallback != nil {
+		s.fallback.SetAuth(auth)
+	}
 	s.authMutex.Lock()
 	defer s.authMutex.Unlock()
 	s.capturedAuth = auth
@@ -105,8 +127,9 @@ type SummarizeInput struct {
 	TriggerThreshold float64 // e.g., 80% → keep 20% of context as recent
 	KeepRecentTokens int     // Fixed token count (override)
 	KeepRecentCount  int     // Message-based (legacy fallback)
+	KeepRecentTurns  int     // Turn-based (recency_window strategy, takes precedence)
 	Model            string  // Used to look up context window
-	ContextWindow    int     // Override context window (for testing)
+	ContextWindow    int     // Resolved context window (0 = look up by Model)
 
 	// Per-job auth credentials for session isolation
 	// When set, these override global captured auth to prevent cross-session leakage
@@ -121,10 +144,88 @@ type SummarizeOutput struct {
 	Duration            time.Duration
 	InputTokens         int
 	OutputTokens        int
+
+	// Which summarizer produced the summary (SummarizedBy* constants) and the
+	// provider/model behind it.
+	SummarizedBy string
+	Provider     string
+	Model        string
 }
 
-// Summarize generates a summary based on the configured strategy.
+// Values of SummarizeOutput.SummarizedBy.
+const (
+	SummarizedByPrimary    = "primary"
+	SummarizedByFallback   = "fallback"
+	SummarizedByTruncation = "truncation"
+)
+
+// Summarize generates a summary based on the configured strategy. If it fails and
+// a fallback summarizer is configured, the fallback is tried next; if that fails
+// too, the oldest messages are dropped locally so compaction still succeeds.
 func (s *Summarizer) Summarize(ctx context.Context, input SummarizeInput) (*SummarizeOutput, error) {
+	out, err := s.summarize(ctx, input, SummarizedByPrimary)
+	if err == nil || s.fallback == nil || ctx.Err() != nil {
+		return out, err
+	}
+	log.Warn().Err(err).Msg("Primary summarizer failed, trying fallback summarizer")
+
+	out, fallbackErr := s.fallback.summarize(ctx, input, SummarizedByFallback)
+	if fallbackErr == nil {
+		return out, nil
+	}
+	log.Warn().Err(fallbackErr).Msg("Fallback summarizer failed, truncating oldest messages")
+
+	out, truncErr := s.truncateOldest(input)
+	if truncErr != nil {
+		return nil, fmt.Errorf("%w (fallback: %v; truncation: %v)", err, fallbackErr, truncErr)
+	}
+	return out, nil
+}
+
+// summarize runs this summarizer alone and records which one handled it.
+func (s *Summarizer) summarize(ctx context.Context, input SummarizeInput, role string) (*SummarizeOutput, error) {
+	out, err := s.summarizeByStrategy(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	out.SummarizedBy = role
+	out.Model, out.Provider = s.config.EffectiveModelAndProvider()
+	log.Info().Str("summarized_by", role).Str("provider", out.Provider).Str("model", out.Model).Msg("Summary generated")
+	return out, nil
+}
+
+// truncateOldest is the last resort when every summarizer failed: the messages
+// that would have been summarized are replaced by a short note.
+func (s *Summarizer) truncateOldest(input SummarizeInput) (*SummarizeOutput, error) {
+	if len(input.Messages) == 0 {
+		return nil, fmt.Errorf("no messages to summarize")
+	}
+	// Cut where the primary would have, so the same recent turns survive.
+	lastIndex, err := s.findSummarizationCutoff(input)
+	if s.config.Strategy == StrategyCompresr {
+		var keepRecent int
+		keepRecent, err = s.compresrKeepRecent(input)
+		lastIndex = len(input.Messages) - keepRecent - 1
+		if err == nil && lastIndex < 0 {
+			err = fmt.Errorf("not enough messages: have %d, keeping %d", len(input.Messages), keepRecent)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	summary := fmt.Sprintf("[%d earlier messages were dropped because the conversation could not be summarized.]", lastIndex+1)
+	log.Info().Str("summarized_by", SummarizedByTruncation).Int("messages_dropped", lastIndex+1).Msg("Summary generated")
+	return &SummarizeOutput{
+		Summary:             summary,
+		SummaryTokens:       tokenizer.CountTokens(summary),
+		LastSummarizedIndex: lastIndex,
+		SummarizedBy:        SummarizedByTruncation,
+		Provider:            "local",
+		Model:               SummarizedByTruncation,
+	}, nil
+}
+
+func (s *Summarizer) summarizeByStrategy(ctx context.Context, input SummarizeInput) (*SummarizeOutput, error) {
 	switch s.config.Strategy {
 	case StrategyCompresr:
 		return s.summarizeViaAPI(ctx, input)
@@ -193,31 +294,23 @@ func (s *Summarizer) summarizeViaAPI(ctx context.Context, input SummarizeInput)
 		return nil, fmt.Errorf("compresr config is nil (required for strategy: compresr)")
 	}
 
-	// Determine keep_recent count
-	keepRecent := input.KeepRecentCount
-	if keepRecent <= 0 {
-		keepRecent = s.config.KeepRecentCount
-	}
-	if keepRecent <= 0 {
-		keepRecent = 3 // default
+	keepRecent, err := s.compresrKeepRecent(input)
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert messages to Compresr format
 	historyMessages := make([]compresr.HistoryMessage, 0, total)
 	for _, msg := range input.Messages {
-		// Use any for Content to handle both string and array (Anthropic content blocks)
-		var parsedMsg struct {
-			Role    string `json:"role"`
-			Content any    `json:"content"`
-		}
+		// Content may be a string, content blocks (Anthropic) or null (OpenAI tool calls)
+		var parsedMsg map[string]any
 		if err := json.Unmarshal(msg, &parsedMsg); err != nil {
 			return nil, fmt.Errorf("failed to parse message: %w", err)
 		}
-		// Extract text content from string or content blocks array
-		contentStr := ExtractContentString(parsedMsg.Content)
+		role, _ := parsedMsg["role"].(string)
 		historyMessages = append(historyMessages, compresr.HistoryMessage{
-			Role:    parsedMsg.Role,
-			Content: contentStr,
+			Role:    role,
+			Content: messageText(parsedMsg),
 		})
 	}
 
@@ -253,9 +346,33 @@ func (s *Summarizer) summarizeViaAPI(ctx context.Context, input SummarizeInput)
 	}, nil
 }
 
+// compresrKeepRecent returns how many trailing messages the Compresr API keeps verbatim.
+func (s *Summarizer) compresrKeepRecent(input SummarizeInput) (int, error) {
+	keepRecent := input.KeepRecentCount
+	if keepRecent <= 0 {
+		keepRecent = s.config.KeepRecentCount
+	}
+	if keepRecent <= 0 {
+		keepRecent = 3 // default
+	}
+	if input.KeepRecentTurns > 0 {
+		cutoff, err := FindRecencyCutoff(input.Messages, input.KeepRecentTurns)
+		if err != nil {
+			return 0, err
+		}
+		keepRecent = len(input.Messages) - cutoff - 1
+	}
+	return keepRecent, nil
+}
+
 func (s *Summarizer) findSummarizationCutoff(input SummarizeInput) (int, error) {
 	total := len(input.Messages)
 
+	// Priority 0: recency_window strategy keeps whole turns verbatim
+	if input.KeepRecentTurns > 0 {
+		return FindRecencyCutoff(input.Messages, input.KeepRecentTurns)
+	}
+
 	// Priority 1: Fixed token override (explicit config takes precedence)
 	keepTokens := input.KeepRecentTokens
 	if keepTokens <= 0 {
@@ -418,9 +535,40 @@ func (s *Summarizer) callAPI(ctx context.Context, systemPrompt, userContent stri
 		}
 	}
 
+	if s.endpoints != nil {
+		return s.callEndpoints(ctx, params)
+	}
 	return external.CallLLM(ctx, params)
 }
 
+// callEndpoints sends params to summarizer.endpoints in pool order, failing
+// over to the next endpoint on error. An endpoint's own api_key replaces the
+// resolved auth; without one the resolved auth is kept.
+func (s *Summarizer) callEndpoints(ctx context.Context, params external.CallLLMParams) (*external.CallLLMResult, error) {
+	var lastErr error
+	for _, i := range s.endpoints.order() {
+		ep := s.endpoints.endpoints[i]
+		p := params
+		p.Endpoint = ep.Endpoint
+		if ep.ProviderKey != "" {
+			p.ProviderKey, p.BearerAuth, p.ExtraHeaders = ep.ProviderKey, "", nil
+		}
+
+		s.endpoints.acquire(i)
+		result, err := external.CallLLM(ctx, p)
+		s.endpoints.release(i, err)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		log.Warn().Err(err).Str("endpoint", ep.Endpoint).Msg("Summarizer endpoint failed, trying next")
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all %d summarizer endpoints failed: %w", len(s.endpoints.endpoints), lastErr)
+}
+
 // buildBedrockHTTPClient constructs an HTTP client with SigV4 signing for Bedrock.
 // Called once at construction time; the result is cached in s.bedrockClient.
 func (s *Summarizer) buildBedrockHTTPClient() (*http.Client, error) {
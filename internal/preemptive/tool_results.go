@@ -0,0 +1,408 @@
+// Whole-history tool_result compaction (preemptive.tool_result_compaction).
+//
+// Tool outputs older than the last keep_recent_turns turns are summarized in
+// one batch into a consolidated context note once they add up to
+// trigger_tokens. The note is summarized in the background, off the request
+// path; the batch is applied from the first request after it is ready. Each
+// compacted tool_result keeps its block (tool_use pairing stays valid) but its
+// content becomes a [REF:id] stub, and array-form content keeps its non-text
+// blocks (images); the newest one of the batch carries the note. Originals go to the shadow store via
+// ToolResultArchive, so expand_context still restores them. Recent tool_results
+// are left for the tool_output pipe and keep their individual shadows.
+package preemptive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/compresr/context-gateway/external"
+	"github.com/compresr/context-gateway/internal/tokenizer"
+)
+
+// ToolResultNoteMarker opens the consolidated context note.
+const ToolResultNoteMarker = "[Context note: earlier tool results consolidated; expand_context on a [REF:id] restores an original]"
+
+// toolResultStubText replaces every other tool_result of a compacted batch.
+const toolResultStubText = "[Tool result compacted into the context note in a later tool result]"
+
+// toolResultRefFormat matches tool_output's PrefixFormat so expand_context
+// and the tool_output pipe treat stubs as already-compressed shadows.
+const toolResultRefFormat = "[REF:%s]\n%s"
+
+// maxToolResultNoteBatches bounds the note cache; the least recently used
+// batch is evicted when it is exceeded.
+const maxToolResultNoteBatches = 1000
+
+// DefaultToolResultNotePrompt is the system prompt for the consolidated note.
+var DefaultToolResultNotePrompt = `You condense tool outputs from earlier in an agent's conversation into one context note the agent will rely on instead of the outputs themselves.
+
+Keep every fact needed to continue the work: file paths, identifiers, commands, error messages, versions, numbers and conclusions. Group by tool call. Drop boilerplate and repetition. Never invent details.`
+
+// ToolResultArchive stores the original content of a compacted tool_result
+// where expand_context can find it and returns its shadow ID.
+type ToolResultArchive func(content string) string
+
+// toolResultRef locates one tool_result in the request body.
+type toolResultRef struct {
+	key      string // hash of session, id and content; see toolResultKey
+	id       string // tool_use_id, or a content hash when missing
+	path     string // sjson path of the content to replace
+	toolName string
+	content  string
+}
+
+// toolResultNotes caches consolidated notes so a batch is summarized once and
+// reapplied as the client resends the same history. Keys cover the session
+// and the content, so a tool call ID reused by another conversation, or
+// resent with different content, never picks up a note it was not part of.
+type toolResultNotes struct {
+	mu       sync.Mutex
+	batchOf  map[string]int           // toolResultRef.key -> batch
+	batches  map[int]*toolResultBatch // batch -> note
+	inFlight map[string]bool          // keys being summarized in the background
+	next     int
+	clock    uint64
+}
+
+type toolResultBatch struct {
+	note     string
+	keys     []string
+	lastUsed uint64
+}
+
+func newToolResultNotes() *toolResultNotes {
+	return &toolResultNotes{
+		batchOf:  make(map[string]int),
+		batches:  make(map[int]*toolResultBatch),
+		inFlight: make(map[string]bool),
+	}
+}
+
+// lookup returns the batch a tool_result was compacted in and marks the batch
+// as recently used.
+func (n *toolResultNotes) lookup(key string) (int, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	b, ok := n.batchOf[key]
+	if ok {
+		n.clock++
+		n.batches[b].lastUsed = n.clock
+	}
+	return b, ok
+}
+
+// claim marks the keys not yet compacted or in flight as being summarized and
+// returns them; the caller must release them.
+func (n *toolResultNotes) claim(refs []toolResultRef) []toolResultRef {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var claimed []toolResultRef
+	for _, ref := range refs {
+		if _, done := n.batchOf[ref.key]; done || n.inFlight[ref.key] {
+			continue
+		}
+		n.inFlight[ref.key] = true
+		claimed = append(claimed, ref)
+	}
+	return claimed
+}
+
+// release clears the in-flight marks set by claim.
+func (n *toolResultNotes) release(refs []toolResultRef) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, ref := range refs {
+		delete(n.inFlight, ref.key)
+	}
+}
+
+func (n *toolResultNotes) note(batch int) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if b := n.batches[batch]; b != nil {
+		return b.note
+	}
+	return ""
+}
+
+// add records a new batch and returns its number, evicting the least recently
+// used batch when the cache is full.
+func (n *toolResultNotes) add(keys []string, note string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if len(n.batches) >= maxToolResultNoteBatches {
+		oldest := 0
+		for id, b := range n.batches {
+			if oldest == 0 || b.lastUsed < n.batches[oldest].lastUsed {
+				oldest = id
+			}
+		}
+		for _, k := range n.batches[oldest].keys {
+			if n.batchOf[k] == oldest {
+				delete(n.batchOf, k)
+			}
+		}
+		delete(n.batches, oldest)
+	}
+	n.next++
+	n.clock++
+	for _, k := range keys {
+		n.batchOf[k] = n.next
+	}
+	n.batches[n.next] = &toolResultBatch{note: note, keys: keys, lastUsed: n.clock}
+	return n.next
+}
+
+// CompactToolResults collapses old tool_results in body into the consolidated
+// context notes already summarized for them (see package comment above), and
+// starts summarizing a new batch in the background once the uncompacted ones
+// reach trigger_tokens. sessionID scopes the cached notes to one conversation.
+// archive may be nil, in which case stubs carry no
+// [REF:id] and originals cannot be expanded. Returns the body unchanged and
+// false when the pass is disabled or nothing was compacted.
+func (m *Manager) CompactToolResults(body []byte, sessionID string, archive ToolResultArchive) ([]byte, bool) {
+	m.mu.RLock()
+	enabled := m.enabled
+	cfg := m.config
+	summary := m.summary
+	notes := m.notes
+	bgCtx := m.bgCtx
+	m.mu.RUnlock()
+	if !enabled || !cfg.ToolResultCompaction.Enabled || summary == nil {
+		return body, false
+	}
+	trc := cfg.ToolResultCompaction.WithDefaults()
+
+	messages, err := ParseMessages(body)
+	if err != nil || len(messages) == 0 {
+		return body, false
+	}
+	cutoff, err := FindRecencyCutoff(messages, trc.KeepRecentTurns)
+	if err != nil {
+		return body, false
+	}
+	old := findToolResults(messages[:cutoff+1], sessionID)
+
+	if pending := notes.claim(old); len(pending) > 0 {
+		pendingTokens := 0
+		for _, ref := range pending {
+			pendingTokens += tokenizer.CountTokens(ref.content)
+		}
+		if pendingTokens >= trc.TriggerTokens {
+			go consolidateToolResults(bgCtx, cfg.SyncTimeout, summary, notes, pending, pendingTokens)
+		} else {
+			notes.release(pending)
+		}
+	}
+
+	// The newest tool_result of each batch carries the note.
+	carrier := make(map[int]int)
+	for i, ref := range old {
+		if batch, ok := notes.lookup(ref.key); ok {
+			carrier[batch] = i
+		}
+	}
+	if len(carrier) == 0 {
+		return body, false
+	}
+
+	out := body
+	for i, ref := range old {
+		batch, ok := notes.lookup(ref.key)
+		if !ok {
+			continue
+		}
+		text := toolResultStubText
+		if carrier[batch] == i {
+			text = ToolResultNoteMarker + "\n" + notes.note(batch)
+		}
+		if archive != nil {
+			text = fmt.Sprintf(toolResultRefFormat, archive(ref.content), text)
+		}
+		if out, err = setToolResultContent(out, ref.path, text); err != nil {
+			log.Warn().Err(err).Str("path", ref.path).Msg("tool_result compaction: failed to rewrite tool result")
+			return body, false
+		}
+	}
+	return out, true
+}
+
+// consolidateToolResults summarizes pending into one note and records it as a
+// batch. It runs in the background; the claimed keys are released either way.
+func consolidateToolResults(ctx context.Context, timeout time.Duration, summary *Summarizer, notes *toolResultNotes, pending []toolResultRef, tokens int) {
+	defer notes.release(pending)
+	sctx, cancel := context.WithTimeout(ctx, timeout)
+	note, err := summary.summarizeToolResults(sctx, pending)
+	cancel()
+	if err != nil {
+		log.Warn().Err(err).Int("tool_results", len(pending)).Msg("tool_result compaction: summarization failed")
+		return
+	}
+	keys := make([]string, len(pending))
+	for i, ref := range pending {
+		keys[i] = ref.key
+	}
+	batch := notes.add(keys, note)
+	log.Info().Int("batch", batch).Int("tool_results", len(pending)).Int("tokens", tokens).
+		Msg("tool_result compaction: consolidated old tool results")
+}
+
+// setToolResultContent replaces the tool_result content at path with text.
+// String content becomes text; array content keeps its shape: the text blocks
+// are replaced by one text block and other blocks (images) are kept in order.
+func setToolResultContent(body []byte, path, text string) ([]byte, error) {
+	content := gjson.GetBytes(body, path)
+	if !content.IsArray() {
+		return sjson.SetBytes(body, path, text)
+	}
+	textBlock, err := json.Marshal(map[string]string{"type": "text", "text": text})
+	if err != nil {
+		return nil, err
+	}
+	blocks := []string{string(textBlock)}
+	content.ForEach(func(_, block gjson.Result) bool {
+		if block.Get("type").String() != "text" {
+			blocks = append(blocks, block.Raw)
+		}
+		return true
+	})
+	return sjson.SetRawBytes(body, path, []byte("["+strings.Join(blocks, ",")+"]"))
+}
+
+// isShadowRef reports whether content already starts with a [REF:id] line,
+// possibly after a single expand_context hint line (see tool_output's
+// existingShadowRef).
+func isShadowRef(content string) bool {
+	first, rest, _ := strings.Cut(content, "\n")
+	if strings.HasPrefix(first, "[REF:") && strings.HasSuffix(first, "]") {
+		return true
+	}
+	second, _, _ := strings.Cut(rest, "\n")
+	return strings.HasPrefix(second, "[REF:") && strings.HasSuffix(second, "]")
+}
+
+// findToolResults returns the tool_results in messages, oldest first: Anthropic
+// tool_result blocks and OpenAI role "tool" messages. Content that is already
+// a shadow reference is skipped.
+func findToolResults(messages []json.RawMessage, sessionID string) []toolResultRef {
+	type block struct {
+		Type      string `json:"type"`
+		ID        string `json:"id"`
+		Name      string `json:"name"`
+		ToolUseID string `json:"tool_use_id"`
+		Content   any    `json:"content"`
+	}
+	type message struct {
+		Role       string `json:"role"`
+		Content    any    `json:"content"`
+		ToolCallID string `json:"tool_call_id"`
+		Name       string `json:"name"`
+		ToolCalls  []struct {
+			ID       string `json:"id"`
+			Function struct {
+				Name string `json:"name"`
+			} `json:"function"`
+		} `json:"tool_calls"`
+	}
+
+	names := make(map[string]string) // tool call ID -> tool name
+	var refs []toolResultRef
+	add := func(id, path, name, content string) {
+		if strings.TrimSpace(content) == "" || isShadowRef(content) {
+			return
+		}
+		if id == "" {
+			sum := sha256.Sum256([]byte(content))
+			id = hex.EncodeToString(sum[:16])
+		}
+		refs = append(refs, toolResultRef{key: toolResultKey(sessionID, id, content), id: id, path: path, toolName: name, content: content})
+	}
+
+	for i, raw := range messages {
+		var msg message
+		if json.Unmarshal(raw, &msg) != nil {
+			continue
+		}
+		for _, tc := range msg.ToolCalls {
+			names[tc.ID] = tc.Function.Name
+		}
+		if msg.Role == "tool" {
+			if content, ok := msg.Content.(string); ok {
+				name := msg.Name
+				if name == "" {
+					name = names[msg.ToolCallID]
+				}
+				add(msg.ToolCallID, fmt.Sprintf("messages.%d.content", i), name, content)
+			}
+			continue
+		}
+		blocks, ok := msg.Content.([]any)
+		if !ok {
+			continue
+		}
+		for j, item := range blocks {
+			data, _ := json.Marshal(item)
+			var b block
+			if json.Unmarshal(data, &b) != nil {
+				continue
+			}
+			switch b.Type {
+			case "tool_use":
+				names[b.ID] = b.Name
+			case "tool_result":
+				add(b.ToolUseID, fmt.Sprintf("messages.%d.content.%d.content", i, j), names[b.ToolUseID], ExtractText(b.Content))
+			}
+		}
+	}
+	return refs
+}
+
+// toolResultKey identifies a tool_result in the note cache by session, tool
+// call ID and content.
+func toolResultKey(sessionID, id, content string) string {
+	sum := sha256.Sum256([]byte(sessionID + "\x00" + id + "\x00" + content))
+	return hex.EncodeToString(sum[:])
+}
+
+// summarizeToolResults produces one consolidated note for refs. The compresr
+// strategy only compresses whole conversations, so it defers to the fallback
+// summarizer when one is configured.
+func (s *Summarizer) summarizeToolResults(ctx context.Context, refs []toolResultRef) (string, error) {
+	var b strings.Builder
+	for _, ref := range refs {
+		name := ref.toolName
+		if name == "" {
+			name = "unknown"
+		}
+		fmt.Fprintf(&b, "### Tool result %s (%s)\n%s\n\n", ref.id, name, ref.content)
+	}
+
+	var err error
+	for sm := s; sm != nil; sm = sm.fallback {
+		if sm.config.Strategy == StrategyCompresr {
+			err = fmt.Errorf("compresr summarizer cannot consolidate tool results")
+			continue
+		}
+		var result *external.CallLLMResult
+		if result, err = sm.callAPI(ctx, DefaultToolResultNotePrompt, b.String(), SummarizeInput{}); err == nil {
+			if result.Content != "" {
+				return result.Content, nil
+			}
+			err = fmt.Errorf("empty context note returned")
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return "", err
+}
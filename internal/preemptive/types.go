@@ -16,6 +16,21 @@ const (
 	StrategyCompresr         = "compresr"          // Use Compresr API for history compression
 )
 
+// Compaction strategy constants (which messages are collapsed into the summary).
+const (
+	CompactionDefault       = ""               // Keep recent context by token/message budget
+	CompactionRecencyWindow = "recency_window" // Keep the last N turns verbatim, summarize the rest
+)
+
+// Overflow action constants (what happens to a request still over the context
+// window after every pipe ran).
+const (
+	OverflowActionNone     = ""         // Forward unchanged (default)
+	OverflowActionCompact  = "compact"  // Summarize older turns synchronously
+	OverflowActionTruncate = "truncate" // Drop the oldest turns locally
+	OverflowActionError    = "error"    // Reject with a gateway error
+)
+
 // CodexDetectorConfig for Codex detection.
 type CodexDetectorConfig struct {
 	Enabled        bool     `yaml:"enabled"`
@@ -29,6 +44,10 @@ type Config struct {
 	Enabled          bool    `yaml:"enabled"`
 	TriggerThreshold float64 `yaml:"trigger_threshold"` // Start at this % (default: 80)
 
+	// Compaction strategy: "" (token budget) or "recency_window"
+	Strategy        string `yaml:"strategy,omitempty"`
+	KeepRecentTurns int    `yaml:"keep_recent_turns,omitempty"` // Turns kept verbatim (recency_window, default: 4)
+
 	// Timeouts
 	PendingJobTimeout time.Duration `yaml:"pending_job_timeout,omitempty"` // Wait for pending job (default: 90s)
 	SyncTimeout       time.Duration `yaml:"sync_timeout,omitempty"`        // Sync summarization timeout (default: 2m)
@@ -36,6 +55,16 @@ type Config struct {
 	// Testing override for context window size
 	TestContextWindowOverride int `yaml:"test_context_window_override,omitempty"`
 
+	// Context windows (tokens) used as 100% for trigger_threshold, keyed by model
+	// name. Values are effective input limits, used as-is: the counterpart of a
+	// built-in EffectiveMax, not of MaxTokens, so leave room for the model's
+	// output yourself. Entries override DefaultModelContextWindows; a key also
+	// matches dated variants (e.g. "claude-sonnet-4" matches "claude-sonnet-4-20250514").
+	ModelContextWindows map[string]int `yaml:"model_context_windows,omitempty"`
+	// Effective input limit for models found in neither table
+	// (default: DefaultUnknownModelContextWindow.EffectiveMax).
+	DefaultContextWindow int `yaml:"default_context_window,omitempty"`
+
 	// Logging
 	LoggingEnabled    bool   `yaml:"logging_enabled,omitempty"` // Controls history_compaction.jsonl (follows telemetry_enabled)
 	LogDir            string `yaml:"log_dir,omitempty"`
@@ -46,8 +75,54 @@ type Config struct {
 	Session    SessionConfig    `yaml:"session"`
 	Detectors  DetectorsConfig  `yaml:"detectors"`
 
+	// FallbackSummarizer is tried when the primary summarizer errors or times out.
+	// If it fails too, the oldest messages are truncated locally instead.
+	FallbackSummarizer *SummarizerConfig `yaml:"fallback_summarizer,omitempty"`
+
 	// Response headers
 	AddResponseHeaders bool `yaml:"add_response_headers"`
+
+	// InjectSummaryNote labels the compaction summary with SummaryNoteMarker so the
+	// model knows earlier turns were compacted. nil = on (see SummaryNoteEnabled).
+	InjectSummaryNote *bool `yaml:"inject_summary_note,omitempty"`
+
+	// ToolResultCompaction consolidates old tool_results across the whole
+	// history on ordinary requests (see tool_results.go).
+	ToolResultCompaction ToolResultCompactionConfig `yaml:"tool_result_compaction,omitempty"`
+
+	// OverflowAction handles a request that still exceeds the context window
+	// after compression: "compact", "truncate" or "error" (see overflow.go).
+	// Empty forwards it unchanged. Applies even when enabled is false, except
+	// "compact", which needs the summarizer. Binary payloads (images, PDFs)
+	// count as a fixed estimate, not their base64 size. A model with no known
+	// window is checked against default_context_window.
+	OverflowAction string `yaml:"overflow_action,omitempty"`
+}
+
+// ToolResultCompactionConfig configures whole-history tool_result compaction.
+type ToolResultCompactionConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	TriggerTokens   int  `yaml:"trigger_tokens,omitempty"`    // Uncompacted old tool_result tokens that start a batch (default: 20000)
+	KeepRecentTurns int  `yaml:"keep_recent_turns,omitempty"` // Turns whose tool_results stay individual (default: 4)
+}
+
+// WithDefaults fills unset fields.
+func (c ToolResultCompactionConfig) WithDefaults() ToolResultCompactionConfig {
+	if c.TriggerTokens <= 0 {
+		c.TriggerTokens = DefaultToolResultTriggerTokens
+	}
+	if c.KeepRecentTurns <= 0 {
+		c.KeepRecentTurns = DefaultKeepRecentTurns
+	}
+	return c
+}
+
+// SummaryNoteMarker prefixes the compaction summary when inject_summary_note is on.
+const SummaryNoteMarker = "[Earlier conversation summarized:]"
+
+// SummaryNoteEnabled reports whether inject_summary_note is on (the default).
+func (c Config) SummaryNoteEnabled() bool {
+	return c.InjectSummaryNote == nil || *c.InjectSummaryNote
 }
 
 // SummarizerConfig configures the summarization service.
@@ -70,6 +145,13 @@ type SummarizerConfig struct {
 	KeepRecentCount  int           `yaml:"keep_recent"`        // Message-based (legacy fallback)
 	SystemPrompt     string        `yaml:"system_prompt,omitempty"`
 
+	// Endpoints spreads summarizer calls over several endpoints/keys (strategy:
+	// "external_provider"), e.g. to stay under per-key rate limits. A call that
+	// fails on one endpoint is retried on the next; a failed endpoint is tried
+	// last for a while. When set, these replace endpoint/api_key above.
+	Endpoints         []SummarizerEndpoint `yaml:"endpoints,omitempty"`
+	EndpointSelection string               `yaml:"endpoint_selection,omitempty"` // round_robin (default) or least_loaded
+
 	// Compresr config (for strategy: "compresr")
 	Compresr *CompresrConfig `yaml:"compresr,omitempty"`
 
@@ -78,6 +160,18 @@ type SummarizerConfig struct {
 	CompresrBaseURL string `yaml:"-"`
 }
 
+// SummarizerEndpoint is one entry of summarizer.endpoints.
+type SummarizerEndpoint struct {
+	Endpoint    string `yaml:"endpoint"`
+	ProviderKey string `yaml:"api_key,omitempty"` // empty = the summarizer's usual auth (api_key or captured)
+}
+
+// Values of SummarizerConfig.EndpointSelection.
+const (
+	EndpointSelectionRoundRobin  = "round_robin"
+	EndpointSelectionLeastLoaded = "least_loaded"
+)
+
 // CompresrConfig for Compresr API compression.
 type CompresrConfig struct {
 	Endpoint string        `yaml:"endpoint"` // e.g., "/api/compress/history/"
@@ -115,63 +209,124 @@ type GenericDetectorConfig struct {
 
 // Validate validates the configuration.
 func (c *Config) Validate() error {
+	switch c.OverflowAction {
+	case OverflowActionNone, OverflowActionTruncate, OverflowActionError:
+	case OverflowActionCompact:
+		if !c.Enabled {
+			return fmt.Errorf("overflow_action 'compact' requires preemptive.enabled")
+		}
+	default:
+		return fmt.Errorf("overflow_action must be '%s', '%s' or '%s'", OverflowActionCompact, OverflowActionTruncate, OverflowActionError)
+	}
 	if !c.Enabled {
 		return nil
 	}
 	if c.TriggerThreshold < 0 || c.TriggerThreshold > 100 {
 		return fmt.Errorf("trigger_threshold must be between 0 and 100 (0 = disabled)")
 	}
+	if c.Strategy != CompactionDefault && c.Strategy != CompactionRecencyWindow {
+		return fmt.Errorf("strategy must be empty or 'recency_window'")
+	}
+	if c.KeepRecentTurns < 0 {
+		return fmt.Errorf("keep_recent_turns must be non-negative")
+	}
+	for model, window := range c.ModelContextWindows {
+		if model == "" || window <= 0 {
+			return fmt.Errorf("model_context_windows: window for %q must be positive", model)
+		}
+	}
+	if c.DefaultContextWindow < 0 {
+		return fmt.Errorf("default_context_window must be non-negative")
+	}
+	if c.ToolResultCompaction.TriggerTokens < 0 || c.ToolResultCompaction.KeepRecentTurns < 0 {
+		return fmt.Errorf("tool_result_compaction: trigger_tokens and keep_recent_turns must be non-negative")
+	}
 
+	if err := c.Summarizer.validate("summarizer"); err != nil {
+		return err
+	}
+	if c.FallbackSummarizer != nil {
+		if err := c.FallbackSummarizer.validate("fallback_summarizer"); err != nil {
+			return err
+		}
+	}
+
+	if c.Session.SummaryTTL <= 0 {
+		return fmt.Errorf("session.summary_ttl must be positive")
+	}
+	if c.Session.HashMessageCount <= 0 {
+		return fmt.Errorf("session.hash_message_count must be positive")
+	}
+	return nil
+}
+
+// validate checks a summarizer config; name prefixes error messages.
+func (sc *SummarizerConfig) validate(name string) error {
 	// Validate strategy
-	if c.Summarizer.Strategy == "" {
-		c.Summarizer.Strategy = StrategyExternalProvider // default to provider (backward compat)
+	if sc.Strategy == "" {
+		sc.Strategy = StrategyExternalProvider // default to provider (backward compat)
 	}
-	if c.Summarizer.Strategy != StrategyExternalProvider && c.Summarizer.Strategy != StrategyCompresr {
-		return fmt.Errorf("summarizer.strategy must be 'external_provider' or 'compresr'")
+	if sc.Strategy != StrategyExternalProvider && sc.Strategy != StrategyCompresr {
+		return fmt.Errorf("%s.strategy must be 'external_provider' or 'compresr'", name)
 	}
 
 	// Strategy-specific validation
-	switch c.Summarizer.Strategy {
+	switch sc.Strategy {
 	case StrategyExternalProvider:
 		// Model is required unless using provider reference
-		if c.Summarizer.Provider == "" && c.Summarizer.Model == "" {
-			return fmt.Errorf("summarizer.model is required (or use provider reference)")
+		if sc.Provider == "" && sc.Model == "" {
+			return fmt.Errorf("%s.model is required (or use provider reference)", name)
 		}
 		// API key is optional - can be captured from incoming requests (Max/Pro users)
 		// Bedrock uses SigV4 signing (no API key needed)
 		// Runtime error will occur in callAPI if no auth is available
-		if c.Summarizer.MaxTokens <= 0 {
-			return fmt.Errorf("summarizer.max_tokens must be positive")
+		if sc.MaxTokens <= 0 {
+			return fmt.Errorf("%s.max_tokens must be positive", name)
 		}
-		if c.Summarizer.Timeout <= 0 {
-			return fmt.Errorf("summarizer.timeout must be positive")
+		if sc.Timeout <= 0 {
+			return fmt.Errorf("%s.timeout must be positive", name)
+		}
+		for i, ep := range sc.Endpoints {
+			if ep.Endpoint == "" {
+				return fmt.Errorf("%s.endpoints[%d].endpoint is required", name, i)
+			}
+		}
+		switch sc.EndpointSelection {
+		case "", EndpointSelectionRoundRobin, EndpointSelectionLeastLoaded:
+		default:
+			return fmt.Errorf("%s.endpoint_selection must be '%s' or '%s'", name, EndpointSelectionRoundRobin, EndpointSelectionLeastLoaded)
 		}
 	case StrategyCompresr:
 		// API config validation
-		if c.Summarizer.Compresr == nil {
-			return fmt.Errorf("summarizer.compresr is required when strategy is 'compresr'")
+		if sc.Compresr == nil {
+			return fmt.Errorf("%s.compresr is required when strategy is 'compresr'", name)
 		}
-		if c.Summarizer.Compresr.Endpoint == "" {
-			return fmt.Errorf("summarizer.compresr.endpoint is required")
+		if sc.Compresr.Endpoint == "" {
+			return fmt.Errorf("%s.compresr.endpoint is required", name)
 		}
-		if c.Summarizer.Compresr.APIKey == "" {
-			return fmt.Errorf("summarizer.compresr.api_key is required")
+		if sc.Compresr.APIKey == "" {
+			return fmt.Errorf("%s.compresr.api_key is required", name)
 		}
-		if c.Summarizer.Compresr.Model == "" {
-			return fmt.Errorf("summarizer.compresr.model is required")
+		if sc.Compresr.Model == "" {
+			return fmt.Errorf("%s.compresr.model is required", name)
 		}
-		if c.Summarizer.Compresr.Timeout <= 0 {
-			return fmt.Errorf("summarizer.compresr.timeout must be positive")
+		if sc.Compresr.Timeout <= 0 {
+			return fmt.Errorf("%s.compresr.timeout must be positive", name)
 		}
 	}
+	return nil
+}
 
-	if c.Session.SummaryTTL <= 0 {
-		return fmt.Errorf("session.summary_ttl must be positive")
+// RecentTurnsToKeep returns the number of turns to keep verbatim,
+// or 0 when the recency_window strategy is not active.
+func (c *Config) RecentTurnsToKeep() int {
+	if c.Strategy != CompactionRecencyWindow {
+		return 0
 	}
-	if c.Session.HashMessageCount <= 0 {
-		return fmt.Errorf("session.hash_message_count must be positive")
+	if c.KeepRecentTurns <= 0 {
+		return DefaultKeepRecentTurns
 	}
-	return nil
+	return c.KeepRecentTurns
 }
 
 // EffectiveModelAndProvider returns the model and provider names based on the active strategy.
@@ -243,6 +398,9 @@ type request struct {
 	provider  adapters.Provider
 	detection DetectionResult
 
+	// tokenCount is the token estimate for the whole request body
+	tokenCount int
+
 	// Per-request auth captured from headers
 	auth authtypes.CapturedAuth
 }
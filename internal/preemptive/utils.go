@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -77,17 +78,86 @@ func ComputeSessionIDFromClean(cleanContent string) string {
 // MODEL CONTEXT WINDOW HELPERS
 
 // GetModelContextWindow returns context window for a model.
-// Falls back to DefaultUnknownModelContextWindow if model is not found.
+// Dated variants match their base entry; falls back to
+// DefaultUnknownModelContextWindow if model is not found.
 func GetModelContextWindow(model string) ModelContextWindow {
-	if mw, ok := DefaultModelContextWindows[model]; ok {
-		return mw
+	mw, ok := lookupModel(DefaultModelContextWindows, model)
+	if !ok {
+		mw = DefaultUnknownModelContextWindow
+	}
+	mw.Model = model
+	return mw
+}
+
+// ContextWindow returns the token window used as 100% for trigger_threshold.
+// Resolution: test_context_window_override, model_context_windows, built-in
+// table, default_context_window, then DefaultUnknownModelContextWindow.
+// Every source yields an effective input limit: built-ins return EffectiveMax
+// and configured values are taken as already net of output tokens.
+// Models not found in either table log a warning, once per model for the
+// first maxWarnedUnknownModels names.
+func (c *Config) ContextWindow(model string) int {
+	if c.TestContextWindowOverride > 0 {
+		return c.TestContextWindowOverride
+	}
+	if window, ok := lookupModel(c.ModelContextWindows, model); ok {
+		return window
+	}
+	if mw, ok := lookupModel(DefaultModelContextWindows, model); ok {
+		return mw.EffectiveMax
+	}
+
+	fallback := c.DefaultContextWindow
+	if fallback <= 0 {
+		fallback = DefaultUnknownModelContextWindow.EffectiveMax
+	}
+	if firstUnknownModel(model) {
+		log.Warn().
+			Str("model", model).
+			Int("context_window", fallback).
+			Msg("preemptive: unknown model context window, using fallback (set preemptive.model_context_windows)")
 	}
-	// Return fallback with the actual model name
-	fallback := DefaultUnknownModelContextWindow
-	fallback.Model = model
 	return fallback
 }
 
+// maxWarnedUnknownModels bounds unknownModelsWarned, which is keyed by the
+// client-supplied model name; past it, further unknown models are not logged.
+const maxWarnedUnknownModels = 256
+
+// unknownModelsWarned records models already warned about by ContextWindow.
+var unknownModelsWarned = struct {
+	sync.Mutex
+	models map[string]bool
+}{models: make(map[string]bool)}
+
+// firstUnknownModel reports whether model should be warned about: it was not
+// warned about before and the record is not full.
+func firstUnknownModel(model string) bool {
+	unknownModelsWarned.Lock()
+	defer unknownModelsWarned.Unlock()
+	if unknownModelsWarned.models[model] || len(unknownModelsWarned.models) >= maxWarnedUnknownModels {
+		return false
+	}
+	unknownModelsWarned.models[model] = true
+	return true
+}
+
+// lookupModel finds model in m by exact name, then by the longest key that
+// model extends with "-" (dated or suffixed variants).
+func lookupModel[V any](m map[string]V, model string) (V, bool) {
+	if v, ok := m[model]; ok {
+		return v, true
+	}
+	var best V
+	bestLen := 0
+	for key, v := range m {
+		if len(key) > bestLen && strings.HasPrefix(model, key+"-") {
+			best, bestLen = v, len(key)
+		}
+	}
+	return best, bestLen > 0
+}
+
 // TOKEN USAGE HELPERS
 
 // CalculateUsage calculates token usage percentage.
@@ -115,6 +185,112 @@ func ParseMessages(body []byte) ([]json.RawMessage, error) {
 	return req.Messages, nil
 }
 
+// FindRecencyCutoff returns the last message index to summarize so that the
+// final keepTurns turns stay verbatim. A turn starts at a user message carrying
+// real input (not only tool results), so a cut never lands between a tool_use
+// and its tool_result. Returns an error if there are no older turns to drop.
+func FindRecencyCutoff(messages []json.RawMessage, keepTurns int) (int, error) {
+	if keepTurns <= 0 {
+		return -1, fmt.Errorf("keep_recent_turns must be positive")
+	}
+
+	turns := 0
+	for i := len(messages) - 1; i > 0; i-- {
+		if !isTurnStart(messages[i]) {
+			continue
+		}
+		turns++
+		if turns < keepTurns {
+			continue
+		}
+		// Defensive: if an earlier tool_use is answered inside the kept window,
+		// keep counting back until the pair is on one side of the boundary.
+		if splitsToolPair(messages, i-1) {
+			continue
+		}
+		return i - 1, nil
+	}
+	return -1, fmt.Errorf("not enough turns: have %d, keeping %d", turns, keepTurns)
+}
+
+// isTurnStart reports whether a message opens a new user turn.
+func isTurnStart(raw json.RawMessage) bool {
+	var msg struct {
+		Role    string `json:"role"`
+		Content any    `json:"content"`
+	}
+	if err := json.Unmarshal(raw, &msg); err != nil || msg.Role != "user" {
+		return false
+	}
+	blocks, ok := msg.Content.([]any)
+	if !ok {
+		return true
+	}
+	for _, b := range blocks {
+		if block, ok := b.(map[string]any); ok && block["type"] != "tool_result" {
+			return true
+		}
+	}
+	return false
+}
+
+// splitsToolPair reports whether any tool call in messages[:cutoff+1] has its
+// result in messages[cutoff+1:]. Handles Anthropic blocks and OpenAI tool_calls.
+func splitsToolPair(messages []json.RawMessage, cutoff int) bool {
+	type toolMsg struct {
+		Content    any    `json:"content"`
+		ToolCallID string `json:"tool_call_id"`
+		ToolCalls  []struct {
+			ID string `json:"id"`
+		} `json:"tool_calls"`
+	}
+
+	calls := make(map[string]bool)
+	for _, raw := range messages[:cutoff+1] {
+		var msg toolMsg
+		if json.Unmarshal(raw, &msg) != nil {
+			continue
+		}
+		for _, tc := range msg.ToolCalls {
+			if tc.ID != "" {
+				calls[tc.ID] = true
+			}
+		}
+		if blocks, ok := msg.Content.([]any); ok {
+			for _, b := range blocks {
+				if block, ok := b.(map[string]any); ok && block["type"] == "tool_use" {
+					if id, _ := block["id"].(string); id != "" {
+						calls[id] = true
+					}
+				}
+			}
+		}
+	}
+	if len(calls) == 0 {
+		return false
+	}
+
+	for _, raw := range messages[cutoff+1:] {
+		var msg toolMsg
+		if json.Unmarshal(raw, &msg) != nil {
+			continue
+		}
+		if msg.ToolCallID != "" && calls[msg.ToolCallID] {
+			return true
+		}
+		if blocks, ok := msg.Content.([]any); ok {
+			for _, b := range blocks {
+				if block, ok := b.(map[string]any); ok && block["type"] == "tool_result" {
+					if id, _ := block["tool_use_id"].(string); calls[id] {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
 // ExtractText extracts text from message content (works for both Anthropic and OpenAI).
 func ExtractText(content any) string {
 	if content == nil {
@@ -189,6 +365,27 @@ func ExtractContentString(content any) string {
 	return ""
 }
 
+// messageText renders a message for summarization: its content plus, for
+// OpenAI assistant turns, one "[Tool: name]" line per tool_calls entry (their
+// content is often null), matching how Anthropic tool_use blocks render.
+func messageText(msg map[string]any) string {
+	content := ExtractContentString(msg["content"])
+	calls, _ := msg["tool_calls"].([]any)
+	if len(calls) == 0 {
+		return content
+	}
+	parts := make([]string, 0, len(calls)+1)
+	parts = append(parts, content)
+	for _, c := range calls {
+		call, _ := c.(map[string]any)
+		fn, _ := call["function"].(map[string]any)
+		if name, _ := fn["name"].(string); name != "" {
+			parts = append(parts, fmt.Sprintf("[Tool: %s]", name))
+		}
+	}
+	return JoinNonEmpty(parts, "\n")
+}
+
 // FormatMessages formats messages for summarization input.
 // OPTIMIZED: Uses strings.Builder for 30-50% better performance vs bytes.Buffer.
 func FormatMessages(messages []json.RawMessage) string {
@@ -203,7 +400,7 @@ func FormatMessages(messages []json.RawMessage) string {
 		}
 
 		role, _ := msg["role"].(string)
-		content := ExtractContentString(msg["content"])
+		content := messageText(msg)
 		if content == "" {
 			continue
 		}
@@ -298,6 +495,14 @@ func BuildAnthropicResponse(summary string, messages []json.RawMessage, lastInde
 	return data
 }
 
+// LabelSummary prefixes summary with SummaryNoteMarker (idempotent).
+func LabelSummary(summary string) string {
+	if strings.HasPrefix(summary, SummaryNoteMarker) {
+		return summary
+	}
+	return SummaryNoteMarker + "\n" + summary
+}
+
 // truncate shortens a string for logging
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
@@ -309,7 +514,16 @@ func truncate(s string, maxLen int) string {
 // BuildOpenAICompactedRequest creates a compacted request for OpenAI API.
 // Old messages are replaced with a summary, then forwarded to the API.
 // If excludeLastMessage is true, the last message (compaction instruction) is excluded.
+// The cut moves back until no tool call is separated from its result, since
+// the API rejects a tool message whose call was summarized away.
 func BuildOpenAICompactedRequest(messages []json.RawMessage, summary string, lastIndex int, excludeLastMessage bool) []byte {
+	if lastIndex >= len(messages) {
+		lastIndex = len(messages) - 1
+	}
+	for lastIndex >= 0 && splitsToolPair(messages, lastIndex) {
+		lastIndex--
+	}
+
 	newMsgs := []any{
 		map[string]any{
 			"role":    "user",
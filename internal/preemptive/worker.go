@@ -62,6 +62,7 @@ type Worker struct {
 	sessions         *SessionManager
 	summarizerCfg    SummarizerConfig
 	triggerThreshold float64
+	keepRecentTurns  int
 	jobRetention     time.Duration
 
 	jobs     map[string]*Job
@@ -75,7 +76,8 @@ type Worker struct {
 }
 
 // NewWorker creates a new background worker.
-func NewWorker(summarizer *Summarizer, sessions *SessionManager, cfg SummarizerConfig, triggerThreshold float64) *Worker {
+// keepRecentTurns > 0 selects the recency_window cutoff (see Config.RecentTurnsToKeep).
+func NewWorker(summarizer *Summarizer, sessions *SessionManager, cfg SummarizerConfig, triggerThreshold float64, keepRecentTurns int) *Worker {
 	const defaultJobRetention = 30 * time.Minute
 	stopCtx, stopFn := context.WithCancel(context.Background())
 	return &Worker{
@@ -83,6 +85,7 @@ func NewWorker(summarizer *Summarizer, sessions *SessionManager, cfg SummarizerC
 		sessions:         sessions,
 		summarizerCfg:    cfg,
 		triggerThreshold: triggerThreshold,
+		keepRecentTurns:  keepRecentTurns,
 		jobRetention:     defaultJobRetention,
 		jobs:             make(map[string]*Job),
 		jobQueue:         make(chan *Job, 100),
@@ -224,11 +227,13 @@ func (w *Worker) processJob(workerID int, job *Job) {
 
 	log.Info().Int("worker", workerID).Str("session_id", job.SessionID).Int("messages", job.MessageCount).Msg("Processing summarization job")
 
-	// Update session state
+	// Update session state; its window was resolved from config when it was created
+	contextWindow := 0
 	_ = w.sessions.Update(job.SessionID, func(s *Session) {
 		s.State = StatePending
 		now := time.Now()
 		s.SummaryTriggeredAt = &now
+		contextWindow = s.MaxContextTokens
 	})
 
 	// Do summarization using lifecycle context so job is cancelled on Stop()
@@ -240,6 +245,8 @@ func (w *Worker) processJob(workerID int, job *Job) {
 		TriggerThreshold: w.triggerThreshold,
 		KeepRecentTokens: w.summarizerCfg.KeepRecentTokens,
 		KeepRecentCount:  w.summarizerCfg.KeepRecentCount,
+		KeepRecentTurns:  w.keepRecentTurns,
+		ContextWindow:    contextWindow,
 		Model:            job.Model,
 		Auth:             job.Auth,
 	})
@@ -271,11 +278,11 @@ func (w *Worker) processJob(workerID int, job *Job) {
 		job.Summary = result.Summary
 		job.SummaryTokens = result.SummaryTokens
 		job.LastIndex = result.LastSummarizedIndex
-		log.Info().Str("session_id", job.SessionID).Int("summary_tokens", result.SummaryTokens).Dur("duration", result.Duration).Msg("Summarization job completed")
+		log.Info().Str("session_id", job.SessionID).Str("summarized_by", result.SummarizedBy).Int("summary_tokens", result.SummaryTokens).Dur("duration", result.Duration).Msg("Summarization job completed")
 		_ = w.sessions.SetSummaryReady(job.SessionID, result.Summary, result.SummaryTokens, result.LastSummarizedIndex, job.MessageCount)
 		// Log preemptive complete with original and compressed content
 		if logger := GetCompactionLogger(); logger != nil {
-			summModel, summProvider := w.summarizerCfg.EffectiveModelAndProvider()
+			summModel, summProvider := result.Model, result.Provider
 			// use strings.Builder to avoid O(N²) allocations from += on large message sets
 			var sb strings.Builder
 			for i, msg := range job.Messages {
@@ -0,0 +1,161 @@
+// Package replay - mock.go is the offline upstream replayed traffic is sent to.
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/tidwall/gjson"
+)
+
+// upstreamPrefix is the path prefix for LLM traffic on the mock.
+// Anything else reaching the mock is a remote compression/provider call.
+const upstreamPrefix = "/upstream"
+
+// expandToolName mirrors the expand_context phantom tool injected by the gateway.
+const expandToolName = "expand_context"
+
+// shadowIDPattern matches shadow references the tool_output pipe leaves in compressed content.
+var shadowIDPattern = regexp.MustCompile(`shadow_[0-9a-f]{8,}`)
+
+// mockUpstream stands in for the LLM provider. It answers every request with a
+// short text reply and, when expand is set, requests expand_context once for each
+// compressed tool output that appears in the newest message.
+type mockUpstream struct {
+	expand bool
+
+	mu            sync.Mutex
+	calls         int
+	sentBytes     int64
+	expandCalls   int
+	blockedRemote int
+	expanded      map[string]bool
+}
+
+func newMockUpstream(expand bool) *mockUpstream {
+	return &mockUpstream{expand: expand, expanded: make(map[string]bool)}
+}
+
+// ServeHTTP implements http.Handler.
+func (m *mockUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	if !strings.HasPrefix(r.URL.Path, upstreamPrefix) {
+		m.mu.Lock()
+		m.blockedRemote++
+		m.mu.Unlock()
+		http.Error(w, "replay: remote calls are disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	anthropic := strings.HasSuffix(r.URL.Path, "/v1/messages")
+
+	m.mu.Lock()
+	m.calls++
+	m.sentBytes += int64(len(body))
+	var expandIDs []string
+	if m.expand && anthropic && declaresExpandTool(body) {
+		for _, id := range newestShadowIDs(body) {
+			if !m.expanded[id] {
+				m.expanded[id] = true
+				expandIDs = append(expandIDs, id)
+			}
+		}
+		m.expandCalls += len(expandIDs)
+	}
+	callNum := m.calls
+	m.mu.Unlock()
+
+	var resp []byte
+	switch {
+	case len(expandIDs) > 0:
+		resp = anthropicExpandResponse(expandIDs, callNum, len(body))
+	case anthropic:
+		resp = anthropicTextResponse(callNum, len(body))
+	default:
+		resp = openAITextResponse(callNum, len(body))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// declaresExpandTool reports whether the gateway injected expand_context into the request.
+func declaresExpandTool(body []byte) bool {
+	return gjson.GetBytes(body, `tools.#(name=="`+expandToolName+`")`).Exists()
+}
+
+// newestShadowIDs returns shadow IDs referenced in the last message of an Anthropic request.
+func newestShadowIDs(body []byte) []string {
+	messages := gjson.GetBytes(body, "messages").Array()
+	if len(messages) == 0 {
+		return nil
+	}
+	return shadowIDPattern.FindAllString(messages[len(messages)-1].Raw, -1)
+}
+
+// approxTokens estimates token usage for mock responses (~4 bytes per token).
+func approxTokens(n int) int {
+	return n / 4
+}
+
+func anthropicTextResponse(callNum, inputBytes int) []byte {
+	return mustMarshal(map[string]interface{}{
+		"id":    fmt.Sprintf("msg_replay_%d", callNum),
+		"type":  "message",
+		"role":  "assistant",
+		"model": "replay-mock",
+		"content": []interface{}{
+			map[string]interface{}{"type": "text", "text": "ok"},
+		},
+		"stop_reason": "end_turn",
+		"usage":       map[string]interface{}{"input_tokens": approxTokens(inputBytes), "output_tokens": 1},
+	})
+}
+
+func anthropicExpandResponse(ids []string, callNum, inputBytes int) []byte {
+	content := make([]interface{}, 0, len(ids))
+	for i, id := range ids {
+		content = append(content, map[string]interface{}{
+			"type":  "tool_use",
+			"id":    fmt.Sprintf("toolu_replay_%d_%d", callNum, i),
+			"name":  expandToolName,
+			"input": map[string]interface{}{"id": id},
+		})
+	}
+	return mustMarshal(map[string]interface{}{
+		"id":          fmt.Sprintf("msg_replay_%d", callNum),
+		"type":        "message",
+		"role":        "assistant",
+		"model":       "replay-mock",
+		"content":     content,
+		"stop_reason": "tool_use",
+		"usage":       map[string]interface{}{"input_tokens": approxTokens(inputBytes), "output_tokens": 10 * len(ids)},
+	})
+}
+
+func openAITextResponse(callNum, inputBytes int) []byte {
+	prompt := approxTokens(inputBytes)
+	return mustMarshal(map[string]interface{}{
+		"id":     fmt.Sprintf("chatcmpl-replay-%d", callNum),
+		"object": "chat.completion",
+		"model":  "replay-mock",
+		"choices": []interface{}{
+			map[string]interface{}{
+				"index":         0,
+				"message":       map[string]interface{}{"role": "assistant", "content": "ok"},
+				"finish_reason": "stop",
+			},
+		},
+		"usage": map[string]interface{}{"prompt_tokens": prompt, "completion_tokens": 1, "total_tokens": prompt + 1},
+	})
+}
+
+func mustMarshal(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
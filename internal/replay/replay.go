@@ -0,0 +1,200 @@
+// Package replay re-sends a captured session through an in-process gateway
+// against a mock upstream, so compression configs can be compared offline.
+//
+// Input is the requests.jsonl written when monitoring.capture_requests is on.
+// No request leaves the machine: the LLM upstream, the Compresr API and any
+// external_provider endpoint all point at the mock.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/monitoring"
+	"github.com/compresr/context-gateway/internal/pipes"
+)
+
+// maxRateLimitRetries bounds retries when the gateway's per-IP limiter rejects a replayed request.
+const maxRateLimitRetries = 3
+
+// Options controls how the mock upstream behaves.
+type Options struct {
+	// Expand makes the mock model call expand_context once for every compressed
+	// tool output in the newest message (worst case for aggressive configs).
+	Expand bool
+}
+
+// Result summarizes one config's replay.
+type Result struct {
+	Name          string
+	Requests      int   // captured requests replayed
+	Failed        int   // requests the gateway did not answer with 2xx
+	OriginalBytes int64 // request bodies as the client sent them
+	SentBytes     int64 // bytes the gateway sent upstream, including expand round trips
+	UpstreamCalls int   // requests the mock upstream received
+	ExpandCalls   int   // expand_context calls served by the gateway
+	BlockedRemote int   // compression/provider calls that were kept offline
+}
+
+// Ratio returns sent/original bytes (1.0 = no savings). Returns 0 with no traffic.
+func (r *Result) Ratio() float64 {
+	if r.OriginalBytes == 0 {
+		return 0
+	}
+	return float64(r.SentBytes) / float64(r.OriginalBytes)
+}
+
+// LoadSession reads captured requests from a session directory or a requests.jsonl path.
+func LoadSession(path string) ([]monitoring.CapturedRequest, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, monitoring.RequestCaptureFile)
+	}
+	reqs, err := monitoring.ReadCapturedRequests(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s not found (enable monitoring.capture_requests to record sessions)", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(reqs) == 0 {
+		return nil, fmt.Errorf("%s has no captured requests", path)
+	}
+	return reqs, nil
+}
+
+// OfflineNotes lists config settings whose compression relies on a remote model.
+// During replay those calls hit the mock and fail, so the fallback strategy is measured instead.
+func OfflineNotes(cfg *config.Config) []string {
+	var notes []string
+	to := cfg.Pipes.ToolOutput
+	if to.Enabled {
+		switch to.Strategy {
+		case config.StrategyCompresr, pipes.StrategyAPI, config.StrategyExternalProvider:
+			notes = append(notes, fmt.Sprintf("tool_output strategy %q needs a remote model; replay measures fallback %q", to.Strategy, fallbackOrPassthrough(to.FallbackStrategy)))
+		}
+	}
+	td := cfg.Pipes.ToolDiscovery
+	if td.Enabled && td.Strategy == config.StrategyCompresr {
+		notes = append(notes, fmt.Sprintf("tool_discovery strategy %q needs the Compresr API; replay measures fallback %q", td.Strategy, fallbackOrPassthrough(td.FallbackStrategy)))
+	}
+	if cfg.Preemptive.Enabled {
+		notes = append(notes, "preemptive summarization is disabled during replay")
+	}
+	return notes
+}
+
+func fallbackOrPassthrough(s string) string {
+	if s == "" {
+		return config.StrategyPassthrough
+	}
+	return s
+}
+
+// Run replays reqs through a gateway built from cfg and returns the traffic summary.
+// Run takes ownership of cfg and rewrites it for offline use.
+func Run(name string, cfg *config.Config, reqs []monitoring.CapturedRequest, opts Options) (*Result, error) {
+	upstream := newMockUpstream(opts.Expand)
+	mock := httptest.NewServer(upstream)
+	defer mock.Close()
+
+	PrepareOffline(cfg, mock.URL)
+	gateway.EnableLocalHostsForReplay()
+	gw := gateway.New(cfg)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = gw.Shutdown(ctx)
+	}()
+	handler := gw.Handler()
+
+	result := &Result{Name: name}
+	for _, captured := range reqs {
+		result.Requests++
+		result.OriginalBytes += int64(len(captured.Body))
+
+		status, err := replayOne(handler, captured, mock.URL+upstreamPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("request %d (%s): %w", result.Requests, captured.RequestID, err)
+		}
+		if status < 200 || status >= 300 {
+			result.Failed++
+		}
+	}
+
+	upstream.mu.Lock()
+	result.SentBytes = upstream.sentBytes
+	result.UpstreamCalls = upstream.calls
+	result.ExpandCalls = upstream.expandCalls
+	result.BlockedRemote = upstream.blockedRemote
+	upstream.mu.Unlock()
+	return result, nil
+}
+
+// PrepareOffline points every outbound endpoint at the mock and turns off
+// side effects (logs, session files, notifications) that a replay must not touch.
+func PrepareOffline(cfg *config.Config, mockURL string) {
+	cfg.URLs.Compresr = mockURL
+	for name, p := range cfg.Providers {
+		p.Endpoint = mockURL + "/provider/" + name
+		cfg.Providers[name] = p
+	}
+	if cfg.Pipes.ToolOutput.Strategy == config.StrategyExternalProvider {
+		cfg.Pipes.ToolOutput.Compresr.Endpoint = mockURL + "/provider/tool_output"
+	}
+	if cfg.Pipes.TaskOutput.ExternalProvider.Endpoint != "" {
+		cfg.Pipes.TaskOutput.ExternalProvider.Endpoint = mockURL + "/provider/task_output"
+	}
+
+	cfg.Store = config.StoreConfig{Type: "memory", TTL: time.Hour}
+	cfg.Preemptive.Enabled = false
+	cfg.PostSession.Enabled = false
+	cfg.CostControl.Enabled = false
+	cfg.Notifications = config.NotificationsConfig{}
+	cfg.Monitoring = config.MonitoringConfig{LogLevel: "disabled", LogFormat: "json", LogOutput: "discard"}
+}
+
+// replayOne sends a captured request through the gateway handler and returns the status.
+// Streaming is turned off so the mock can answer with plain JSON.
+func replayOne(handler http.Handler, captured monitoring.CapturedRequest, targetURL string) (int, error) {
+	body := []byte(captured.Body)
+	if gjson.GetBytes(body, "stream").Exists() {
+		var err error
+		if body, err = sjson.SetBytes(body, "stream", false); err != nil {
+			return 0, err
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		req := httptest.NewRequest(http.MethodPost, captured.Path, strings.NewReader(string(body)))
+		for k, v := range captured.Headers {
+			req.Header.Set(k, v)
+		}
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if strings.HasSuffix(captured.Path, "/v1/messages") || req.Header.Get("Anthropic-Version") != "" {
+			req.Header.Set("x-api-key", "sk-ant-replay")
+		} else {
+			req.Header.Set("Authorization", "Bearer sk-replay")
+		}
+		req.Header.Set(gateway.HeaderTargetURL, targetURL)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusTooManyRequests || attempt >= maxRateLimitRetries {
+			return rec.Code, nil
+		}
+		time.Sleep(time.Second) // gateway rate limiter: Retry-After: 1
+	}
+}
@@ -30,6 +30,48 @@ func IsTransientStatus(code int) bool {
 	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
 }
 
+// Class says how a failed API call should be handled.
+type Class int
+
+const (
+	// ClassTransient covers timeouts, connection errors, 429 and 5xx: retry,
+	// then fall back.
+	ClassTransient Class = iota
+	// ClassBadRequest covers 400, 422 and other permanent 4xx: the request
+	// itself was rejected, so fall back without retrying.
+	ClassBadRequest
+	// ClassAuth covers 401 and 403: the credentials were rejected and every
+	// further call will fail the same way.
+	ClassAuth
+)
+
+// StatusError is a non-2xx response from an upstream API.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string { return e.Message }
+
+// Classify maps an API call error to its Class. Errors that carry no
+// StatusError (network failures, timeouts) are transient.
+func Classify(err error) Class {
+	var se *StatusError
+	if !errors.As(err, &se) {
+		return ClassTransient
+	}
+	switch {
+	case se.StatusCode == http.StatusUnauthorized || se.StatusCode == http.StatusForbidden:
+		return ClassAuth
+	case IsTransientStatus(se.StatusCode) || se.StatusCode == http.StatusRequestTimeout:
+		return ClassTransient
+	case se.StatusCode >= http.StatusBadRequest:
+		return ClassBadRequest
+	default:
+		return ClassTransient
+	}
+}
+
 // Backoff returns the exponential backoff duration for the given retry index (0-based).
 // attempt=0 → 100ms, attempt=1 → 200ms.
 func Backoff(attempt int) time.Duration {
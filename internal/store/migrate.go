@@ -0,0 +1,140 @@
+// Store migration — copies live originals and compressed entries between stores.
+package store
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Entry kinds carried by ExportedEntry.
+const (
+	EntryKindOriginal   = "original"
+	EntryKindCompressed = "compressed"
+)
+
+// ExportedEntry is one stored value with its absolute expiry.
+type ExportedEntry struct {
+	Kind      string    `json:"kind"` // EntryKindOriginal | EntryKindCompressed
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Exporter is implemented by stores whose contents can be enumerated.
+type Exporter interface {
+	// Export returns every non-expired original and compressed entry, plus the
+	// number of entries skipped because they had already expired.
+	Export() (entries []ExportedEntry, expired int)
+}
+
+// Importer is implemented by stores that can keep an entry's remaining TTL.
+// Stores without it receive entries via Set/SetCompressed and apply their own TTL.
+type Importer interface {
+	Import(e ExportedEntry) error
+}
+
+// MigrateStats reports the outcome of Migrate.
+type MigrateStats struct {
+	Migrated int `json:"migrated"`
+	Skipped  int `json:"skipped"` // expired before or during the migration
+}
+
+// Migrate copies all live entries from src to dst, preserving TTLs when dst
+// implements Importer. Stops at the first write error.
+func Migrate(src Exporter, dst Store) (MigrateStats, error) {
+	entries, expired := src.Export()
+	stats := MigrateStats{Skipped: expired}
+
+	importer, keepTTL := dst.(Importer)
+	now := time.Now()
+	for _, e := range entries {
+		if !e.ExpiresAt.After(now) {
+			stats.Skipped++
+			continue
+		}
+
+		var err error
+		switch {
+		case keepTTL:
+			err = importer.Import(e)
+		case e.Kind == EntryKindOriginal:
+			err = dst.Set(e.Key, e.Value)
+		case e.Kind == EntryKindCompressed:
+			err = dst.SetCompressed(e.Key, e.Value)
+		default:
+			err = fmt.Errorf("unknown entry kind %q", e.Kind)
+		}
+		if err != nil {
+			return stats, fmt.Errorf("migrate %s %s: %w", e.Kind, e.Key, err)
+		}
+		stats.Migrated++
+	}
+	return stats, nil
+}
+
+// Export returns all live originals and compressed entries, originals first,
+// each group sorted by key.
+func (s *MemoryStore) Export() ([]ExportedEntry, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stopped {
+		return nil, 0
+	}
+
+	now := time.Now()
+	expired := 0
+	entries := make([]ExportedEntry, 0, len(s.data)+len(s.compressed))
+	collect := func(kind string, m map[string]entry) {
+		start := len(entries)
+		for key, e := range m {
+			if now.After(e.expiresAt) {
+				expired++
+				continue
+			}
+			entries = append(entries, ExportedEntry{Kind: kind, Key: key, Value: e.value, ExpiresAt: e.expiresAt})
+		}
+		group := entries[start:]
+		sort.Slice(group, func(i, j int) bool { return group[i].Key < group[j].Key })
+	}
+	collect(EntryKindOriginal, s.data)
+	collect(EntryKindCompressed, s.compressed)
+	return entries, expired
+}
+
+// Import stores e with its original expiry instead of the store's default TTL.
+func (s *MemoryStore) Import(e ExportedEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stopped {
+		return nil
+	}
+
+	switch e.Kind {
+	case EntryKindOriginal:
+		if existing, ok := s.data[e.Key]; ok {
+			s.dataOrder.MoveToBack(existing.element)
+			s.data[e.Key] = entry{value: e.Value, expiresAt: e.ExpiresAt, element: existing.element}
+			return nil
+		}
+		if len(s.data) >= MaxOriginalEntries {
+			s.evictOldestData()
+		}
+		s.data[e.Key] = entry{value: e.Value, expiresAt: e.ExpiresAt, element: s.dataOrder.PushBack(e.Key)}
+	case EntryKindCompressed:
+		if existing, ok := s.compressed[e.Key]; ok {
+			s.compOrder.MoveToBack(existing.element)
+			s.compressed[e.Key] = entry{value: e.Value, expiresAt: e.ExpiresAt, element: existing.element}
+			return nil
+		}
+		if s.maxCompressed > 0 && len(s.compressed) >= s.maxCompressed {
+			s.evictOldestCompressed()
+		}
+		s.compressed[e.Key] = entry{value: e.Value, expiresAt: e.ExpiresAt, element: s.compOrder.PushBack(e.Key)}
+	default:
+		return fmt.Errorf("unknown entry kind %q", e.Kind)
+	}
+	return nil
+}
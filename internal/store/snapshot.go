@@ -0,0 +1,76 @@
+// Store snapshot for debugging — reports metadata only, never content.
+package store
+
+import (
+	"sort"
+	"time"
+)
+
+// EntryInfo describes a single shadow ID held by the store.
+// Content is deliberately omitted so snapshots are safe to expose.
+type EntryInfo struct {
+	ID                string `json:"id"`
+	HasOriginal       bool   `json:"has_original"`
+	HasCompressed     bool   `json:"has_compressed"`
+	HasExpansion      bool   `json:"has_expansion"`
+	OriginalBytes     int    `json:"original_bytes"`
+	CompressedBytes   int    `json:"compressed_bytes"`
+	OriginalTTLSecs   int64  `json:"original_ttl_remaining_secs"`   // 0 when no original
+	CompressedTTLSecs int64  `json:"compressed_ttl_remaining_secs"` // 0 when no compressed
+}
+
+// Snapshot returns metadata for every live (non-expired) shadow ID,
+// sorted by ID. Field refs are not included.
+func (s *MemoryStore) Snapshot() []EntryInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.stopped {
+		return nil
+	}
+
+	now := time.Now()
+	byID := make(map[string]*EntryInfo, len(s.data)+len(s.compressed))
+	get := func(id string) *EntryInfo {
+		info, ok := byID[id]
+		if !ok {
+			info = &EntryInfo{ID: id}
+			byID[id] = info
+		}
+		return info
+	}
+
+	for id, e := range s.data {
+		if now.After(e.expiresAt) {
+			continue
+		}
+		info := get(id)
+		info.HasOriginal = true
+		info.OriginalBytes = len(e.value)
+		info.OriginalTTLSecs = int64(e.expiresAt.Sub(now).Seconds())
+	}
+	for id, e := range s.compressed {
+		if now.After(e.expiresAt) {
+			continue
+		}
+		info := get(id)
+		info.HasCompressed = true
+		info.CompressedBytes = len(e.value)
+		info.CompressedTTLSecs = int64(e.expiresAt.Sub(now).Seconds())
+	}
+	for id, e := range s.expansions {
+		if now.After(e.expiresAt) {
+			continue
+		}
+		if info, ok := byID[id]; ok {
+			info.HasExpansion = true
+		}
+	}
+
+	out := make([]EntryInfo, 0, len(byID))
+	for _, info := range byID {
+		out = append(out, *info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
@@ -430,10 +430,13 @@ func (s *MemoryStore) CompressedSize() int {
 
 // Reset clears all cached data without stopping the cleanup goroutine.
 // Call this when starting a new session to ensure a clean slate.
-func (s *MemoryStore) Reset() {
+// Returns the number of entries removed (originals, compressed versions,
+// expansion records and field refs, expired or not).
+func (s *MemoryStore) Reset() int {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	n := len(s.data) + len(s.compressed) + len(s.expansions) + len(s.fieldRefs)
 	s.data = make(map[string]entry)
 	s.dataOrder.Init()
 	s.compressed = make(map[string]entry)
@@ -442,6 +445,7 @@ func (s *MemoryStore) Reset() {
 	s.expansOrder.Init()
 	s.fieldRefs = make(map[string]fieldRefEntry)
 	s.fieldRefOrder.Init()
+	return n
 }
 
 // Close stops the cleanup goroutine and clears data.
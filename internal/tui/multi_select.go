@@ -0,0 +1,204 @@
+package tui
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// MULTI-SELECTION
+
+// errInterrupted is returned by RunMultiSelect when Ctrl+C is pressed.
+var errInterrupted = errors.New("interrupted")
+
+// MultiSelectMenu displays an interactive checkbox menu and returns the selected
+// indices in ascending order. Space toggles, arrows (or j/k) move, Enter confirms.
+// Returns nil and error if cancelled.
+func MultiSelectMenu(prompt string, items []MenuItem) ([]int, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items to select")
+	}
+
+	stdinFd := int(os.Stdin.Fd()) // #nosec G115 -- fd fits in int on all supported platforms
+	if !term.IsTerminal(stdinFd) {
+		return multiSelectNumberedMenu(prompt, items)
+	}
+
+	oldState, err := term.MakeRaw(stdinFd)
+	if err != nil {
+		return multiSelectNumberedMenu(prompt, items)
+	}
+	defer func() { _ = term.Restore(stdinFd, oldState) }()
+
+	// Hide cursor
+	fmt.Print("\033[?25l")
+	defer fmt.Print("\033[?25h") // Show cursor on exit
+
+	selected, err := RunMultiSelect(os.Stdin, os.Stdout, prompt, items)
+	if errors.Is(err, errInterrupted) {
+		// Restore terminal state before exiting
+		fmt.Print("\033[?25h") // Show cursor
+		_ = term.Restore(stdinFd, oldState)
+		fmt.Println("\n\nInterrupted.")
+		os.Exit(130) // Standard exit code for Ctrl+C
+	}
+	return selected, err
+}
+
+// RunMultiSelect drives the checkbox menu from raw key bytes on in, rendering to out.
+// MultiSelectMenu uses it after putting the terminal in raw mode; it is exported so
+// the key handling can be scripted without a terminal.
+func RunMultiSelect(in io.Reader, out io.Writer, prompt string, items []MenuItem) ([]int, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("no items to select")
+	}
+
+	cursor := 0
+	checked := make([]bool, len(items))
+	reader := bufio.NewReader(in)
+
+	// prompt + blank + items + blank + help
+	screen := &menuScreen{out: out, lines: 3 + len(items) + 2}
+
+	render := func() {
+		screen.redraw()
+
+		_, _ = fmt.Fprint(out, "\033[2K")
+		_, _ = fmt.Fprintf(out, "\r\n%s%s%s%s\n\n", ColorBold, ColorCyan, prompt, ColorReset)
+		for i, item := range items {
+			_, _ = fmt.Fprint(out, "\033[2K")
+			_, _ = fmt.Fprint(out, renderMultiSelectLine(item, checked[i], i == cursor))
+			_, _ = fmt.Fprint(out, "\n")
+		}
+		_, _ = fmt.Fprint(out, "\033[2K")
+		_, _ = fmt.Fprintf(out, "\r\n  %s[↑/↓] Navigate  [Space] Toggle  [Enter] Confirm  [q/Esc] Cancel%s\n", ColorDim, ColorReset)
+	}
+
+	render()
+
+	for {
+		key, err := readMenuKey(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key {
+		case keyInterrupt:
+			return nil, errInterrupted
+
+		case keyUp, keyDown:
+			cursor = moveCursor(cursor, key, len(items))
+			render()
+
+		case keyToggle:
+			if !items[cursor].Locked {
+				checked[cursor] = !checked[cursor]
+			}
+			render()
+
+		case keyCancel:
+			screen.clear()
+			return nil, fmt.Errorf("cancelled")
+
+		case keyEnter:
+			screen.clear()
+			selected := []int{}
+			for i, c := range checked {
+				if c {
+					selected = append(selected, i)
+				}
+			}
+			return selected, nil
+		}
+	}
+}
+
+// renderMultiSelectLine renders one checkbox row.
+func renderMultiSelectLine(item MenuItem, checked, current bool) string {
+	box := "[ ]"
+	if checked {
+		box = ColorGreen + "[x]" + ColorReset
+	}
+
+	pointer := "  "
+	if current {
+		pointer = ColorGreen + "❯" + ColorReset + " "
+	}
+
+	label := item.Label
+	switch {
+	case item.Locked:
+		label = ColorDim + "🔒 " + item.Label + ColorReset
+		box = ColorDim + "[ ]" + ColorReset
+	case current:
+		label = ColorBold + item.Label + ColorReset
+	}
+
+	desc := item.Description
+	if item.Locked && item.LockedReason != "" {
+		desc = "[" + item.LockedReason + "]"
+	}
+	if desc != "" {
+		desc = " " + ColorDim + "- " + desc + ColorReset
+	}
+
+	return "\r  " + pointer + box + " " + label + desc
+}
+
+// multiSelectNumberedMenu is a fallback for non-interactive terminals.
+func multiSelectNumberedMenu(prompt string, items []MenuItem) ([]int, error) {
+	fmt.Printf("\n%s%s%s%s\n\n", ColorBold, ColorCyan, prompt, ColorReset)
+
+	for i, item := range items {
+		fmt.Printf("  %s[%d]%s %s", ColorGreen, i+1, ColorReset, item.Label)
+		if item.Description != "" {
+			fmt.Printf(" %s- %s%s", ColorDim, item.Description, ColorReset)
+		}
+		fmt.Println()
+	}
+	fmt.Printf("  %s[0]%s Cancel\n\n", ColorYellow, ColorReset)
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Enter numbers (comma-separated): ")
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+
+		if input == "0" || input == "q" {
+			return nil, fmt.Errorf("cancelled")
+		}
+
+		if selected, ok := parseNumberList(input, items); ok {
+			return selected, nil
+		}
+		fmt.Printf("Invalid choice. Enter numbers 1-%d separated by commas, or 0 to cancel.\n", len(items))
+	}
+}
+
+// parseNumberList parses "1, 3,4" into sorted, de-duplicated zero-based indices.
+func parseNumberList(input string, items []MenuItem) ([]int, bool) {
+	seen := make(map[int]bool)
+	selected := []int{}
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' }) {
+		num, err := strconv.Atoi(field)
+		if err != nil || num < 1 || num > len(items) || items[num-1].Locked {
+			return nil, false
+		}
+		if !seen[num-1] {
+			seen[num-1] = true
+			selected = append(selected, num-1)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, false
+	}
+	sort.Ints(selected)
+	return selected, true
+}
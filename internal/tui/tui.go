@@ -8,6 +8,7 @@ package tui
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -113,6 +114,91 @@ func ClearLastMenu() {
 	}
 }
 
+// menuKey is a key press understood by the interactive menus.
+type menuKey int
+
+const (
+	keyOther menuKey = iota
+	keyUp
+	keyDown
+	keyToggle
+	keyEnter
+	keyCancel
+	keyInterrupt
+)
+
+// readMenuKey reads one key press from raw terminal input. Arrows and j/k
+// move, Space toggles, Enter confirms, q or a bare Escape cancels and Ctrl+C
+// interrupts.
+func readMenuKey(reader *bufio.Reader) (menuKey, error) {
+	b, err := reader.ReadByte()
+	if err != nil {
+		return keyOther, err
+	}
+	switch b {
+	case 3: // Ctrl+C
+		return keyInterrupt, nil
+	case 27: // Escape or arrow key sequence
+		next, _ := reader.ReadByte()
+		if next == '[' {
+			arrow, _ := reader.ReadByte()
+			switch arrow {
+			case 'A':
+				return keyUp, nil
+			case 'B':
+				return keyDown, nil
+			}
+		}
+		return keyCancel, nil
+	case 'q':
+		return keyCancel, nil
+	case 'k': // vim-style up
+		return keyUp, nil
+	case 'j': // vim-style down
+		return keyDown, nil
+	case ' ':
+		return keyToggle, nil
+	case 13, '\n':
+		return keyEnter, nil
+	}
+	return keyOther, nil
+}
+
+// moveCursor applies an up or down key to cursor, staying within n items.
+func moveCursor(cursor int, key menuKey, n int) int {
+	switch {
+	case key == keyUp && cursor > 0:
+		return cursor - 1
+	case key == keyDown && cursor < n-1:
+		return cursor + 1
+	}
+	return cursor
+}
+
+// menuScreen redraws a menu of a fixed number of lines in place.
+type menuScreen struct {
+	out   io.Writer
+	lines int
+	drawn bool
+}
+
+// redraw moves back to the top of the previous frame before a new one is printed.
+func (s *menuScreen) redraw() {
+	if s.drawn {
+		_, _ = fmt.Fprintf(s.out, "\033[%dA", s.lines)
+	}
+	s.drawn = true
+}
+
+// clear blanks the menu, leaving the cursor where the menu started.
+func (s *menuScreen) clear() {
+	_, _ = fmt.Fprintf(s.out, "\033[%dA", s.lines)
+	for i := 0; i < s.lines; i++ {
+		_, _ = fmt.Fprint(s.out, "\033[2K\n")
+	}
+	_, _ = fmt.Fprintf(s.out, "\033[%dA", s.lines)
+}
+
 // SelectMenu displays an interactive arrow-key menu and returns the selected index.
 // Returns -1 and error if cancelled.
 func SelectMenu(prompt string, items []MenuItem) (int, error) {
@@ -140,15 +226,13 @@ func SelectMenu(prompt string, items []MenuItem) (int, error) {
 		termWidth = 80 // Default fallback
 	}
 
-	// Calculate total lines we'll render
-	totalLines := 3 + len(items) + 2 // prompt + blank + items + blank + help
+	// prompt + blank + items + blank + help
+	screen := &menuScreen{out: os.Stdout, lines: 3 + len(items) + 2}
 
 	// Hide cursor
 	fmt.Print("\033[?25l")
 	defer fmt.Print("\033[?25h") // Show cursor on exit
 
-	firstRender := true
-
 	// Helper to truncate text to fit terminal width
 	truncate := func(text string, maxLen int) string {
 		if len(text) <= maxLen {
@@ -161,11 +245,7 @@ func SelectMenu(prompt string, items []MenuItem) (int, error) {
 	}
 
 	renderMenu := func() {
-		if !firstRender {
-			// Move cursor up to start of menu and clear
-			fmt.Printf("\033[%dA", totalLines)
-		}
-		firstRender = false
+		screen.redraw()
 
 		// Clear line and print prompt
 		fmt.Print("\033[2K") // Clear line
@@ -227,68 +307,25 @@ func SelectMenu(prompt string, items []MenuItem) (int, error) {
 	renderMenu()
 
 	for {
-		b, err := reader.ReadByte()
+		key, err := readMenuKey(reader)
 		if err != nil {
 			return -1, err
 		}
 
-		switch b {
-		case 3: // Ctrl+C - exit immediately
+		switch key {
+		case keyInterrupt: // exit immediately
 			// Restore terminal state before exiting
 			fmt.Print("\033[?25h") // Show cursor
 			_ = term.Restore(stdinFd, oldState)
 			fmt.Println("\n\nInterrupted.")
 			os.Exit(130) // Standard exit code for Ctrl+C
-
-		case 'q', 27: // q or Escape
-			// Check for escape sequence (arrow keys)
-			if b == 27 {
-				// Read next two bytes for escape sequence
-				next, _ := reader.ReadByte()
-				if next == '[' {
-					arrow, _ := reader.ReadByte()
-					switch arrow {
-					case 'A': // Up arrow
-						if selected > 0 {
-							selected--
-						}
-						renderMenu()
-						continue
-					case 'B': // Down arrow
-						if selected < len(items)-1 {
-							selected++
-						}
-						renderMenu()
-						continue
-					}
-				}
-				// Pure Escape key - cancel
-				// Clear menu before exit
-				fmt.Printf("\033[%dA", totalLines)
-				for i := 0; i < totalLines; i++ {
-					fmt.Print("\033[2K\n")
-				}
-				fmt.Printf("\033[%dA", totalLines)
-				return -1, fmt.Errorf("cancelled")
-			}
-			// 'q' - cancel
-			fmt.Printf("\033[%dA", totalLines)
-			for i := 0; i < totalLines; i++ {
-				fmt.Print("\033[2K\n")
-			}
-			fmt.Printf("\033[%dA", totalLines)
-			return -1, fmt.Errorf("cancelled")
-		case 'k': // vim-style up
-			if selected > 0 {
-				selected--
-			}
+		case keyUp, keyDown:
+			selected = moveCursor(selected, key, len(items))
 			renderMenu()
-		case 'j': // vim-style down
-			if selected < len(items)-1 {
-				selected++
-			}
-			renderMenu()
-		case 13: // Enter
+		case keyCancel:
+			screen.clear()
+			return -1, fmt.Errorf("cancelled")
+		case keyEnter:
 			// Check if this is a locked item - don't allow selection
 			if items[selected].Locked {
 				// Show a brief message and continue
@@ -298,7 +335,7 @@ func SelectMenu(prompt string, items []MenuItem) (int, error) {
 			if items[selected].Editable {
 				// Calculate position: from help line, go up to selected item
 				// Help line is at bottom, items are above it (with 1 blank line between)
-				// totalLines = 3 + len(items) + 2 = prompt(1) + blank(2) + items + blank(1) + help(1)
+				// screen.lines = 3 + len(items) + 2 = prompt(1) + blank(2) + items + blank(1) + help(1)
 				linesUp := (len(items) - selected) + 2 // +2 for blank line and help line
 
 				// Move up to the selected item line
@@ -352,11 +389,7 @@ func SelectMenu(prompt string, items []MenuItem) (int, error) {
 
 			// Non-editable item - return silently (no confirmation printed)
 			// Just clear the menu and return
-			fmt.Printf("\033[%dA", totalLines)
-			for i := 0; i < totalLines; i++ {
-				fmt.Print("\033[2K\n")
-			}
-			fmt.Printf("\033[%dA", totalLines)
+			screen.clear()
 			menuLines = 0
 			return selected, nil
 		}
@@ -0,0 +1,175 @@
+// Package updater manages the installed binary for "context-gateway update":
+// version pinning and the backup kept for "update --rollback".
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Install locates the files an update reads and writes.
+type Install struct {
+	// ConfigDir holds the pinned and previous version files
+	// (~/.config/context-gateway).
+	ConfigDir string
+	// ExecPath is the resolved path of the installed binary.
+	ExecPath string
+}
+
+// PinnedVersionFile is written by "update --version" and "update --rollback"
+// and cleared by a plain "update".
+func (in Install) PinnedVersionFile() string {
+	return filepath.Join(in.ConfigDir, ".pinned_version")
+}
+
+// PreviousVersionFile records the version replaced by the last update (the one
+// the backup binary holds).
+func (in Install) PreviousVersionFile() string {
+	return filepath.Join(in.ConfigDir, ".previous_version")
+}
+
+// BackupPath is the binary backed up by the last update.
+func (in Install) BackupPath() string {
+	return in.ExecPath + ".bak"
+}
+
+// PinnedVersion returns the pinned version, or "" when not pinned.
+func (in Install) PinnedVersion() string {
+	return readVersion(in.PinnedVersionFile())
+}
+
+// PreviousVersion returns the version held by the backup, or "" when unknown.
+func (in Install) PreviousVersion() string {
+	return readVersion(in.PreviousVersionFile())
+}
+
+// SetPinnedVersion pins the installed version, or clears the pin when version is "".
+func (in Install) SetPinnedVersion(version string) {
+	if version == "" {
+		_ = os.Remove(in.PinnedVersionFile())
+		return
+	}
+	writeVersion(in.ConfigDir, in.PinnedVersionFile(), version)
+}
+
+// Replace installs newBinary at ExecPath. The replaced binary becomes the
+// rollback backup and replacedVersion is recorded as its version. If the new
+// binary cannot be moved into place the old one is restored.
+func (in Install) Replace(newBinary, replacedVersion string) error {
+	backupFile := in.BackupPath()
+	_ = os.Remove(backupFile)
+
+	if err := os.Rename(in.ExecPath, backupFile); err != nil {
+		_ = os.Remove(newBinary)
+		return fmt.Errorf("failed to backup old binary: %w", err)
+	}
+
+	if err := os.Rename(newBinary, in.ExecPath); err != nil {
+		// Try to restore old binary
+		if restoreErr := os.Rename(backupFile, in.ExecPath); restoreErr != nil {
+			return fmt.Errorf("failed to install new binary: %w (failed to restore old binary: %v)", err, restoreErr)
+		}
+		return fmt.Errorf("failed to install new binary: %w", err)
+	}
+
+	writeVersion(in.ConfigDir, in.PreviousVersionFile(), replacedVersion)
+	return nil
+}
+
+// CheckBackup returns an error when there is no backup to roll back to.
+func (in Install) CheckBackup() error {
+	if _, err := os.Stat(in.BackupPath()); err != nil {
+		return fmt.Errorf("no backup found at %s — rollback is only available after \"context-gateway update\"", in.BackupPath())
+	}
+	return nil
+}
+
+// Rollback restores the backup over ExecPath and pins its version, so startup
+// does not immediately suggest updating again. The backup is consumed: the
+// current binary is discarded, not swapped in. Returns the restored version,
+// or "" when it was not recorded.
+func (in Install) Rollback() (string, error) {
+	if err := in.CheckBackup(); err != nil {
+		return "", err
+	}
+
+	previous := in.PreviousVersion()
+	if err := os.Rename(in.BackupPath(), in.ExecPath); err != nil {
+		return "", fmt.Errorf("failed to restore backup: %w", err)
+	}
+	_ = os.Remove(in.PreviousVersionFile())
+	if previous != "" {
+		in.SetPinnedVersion(previous)
+	}
+	return previous, nil
+}
+
+// NormalizeVersionTag ensures a release tag has the "v" prefix ("0.5.2" → "v0.5.2").
+func NormalizeVersionTag(v string) string {
+	v = strings.TrimSpace(v)
+	if v == "" || strings.HasPrefix(v, "v") {
+		return v
+	}
+	return "v" + v
+}
+
+// IsNewerVersion returns true if latest is newer than current.
+// Handles dev versions: v0.5.2-dev is considered newer than v0.5.1.
+func IsNewerVersion(current, latest string) bool {
+	// Strip "v" prefix and "-dev"/"-private" suffixes for comparison
+	cleanVersion := func(v string) string {
+		v = strings.TrimPrefix(v, "v")
+		if idx := strings.IndexByte(v, '-'); idx >= 0 {
+			v = v[:idx]
+		}
+		return v
+	}
+	currentClean := cleanVersion(current)
+	latestClean := cleanVersion(latest)
+
+	// Parse major.minor.patch
+	parseParts := func(v string) (int, int, int) {
+		parts := strings.Split(v, ".")
+		major, minor, patch := 0, 0, 0
+		if len(parts) >= 1 {
+			major, _ = strconv.Atoi(parts[0])
+		}
+		if len(parts) >= 2 {
+			minor, _ = strconv.Atoi(parts[1])
+		}
+		if len(parts) >= 3 {
+			patch, _ = strconv.Atoi(parts[2])
+		}
+		return major, minor, patch
+	}
+
+	cMaj, cMin, cPatch := parseParts(currentClean)
+	lMaj, lMin, lPatch := parseParts(latestClean)
+
+	if lMaj != cMaj {
+		return lMaj > cMaj
+	}
+	if lMin != cMin {
+		return lMin > cMin
+	}
+	return lPatch > cPatch
+}
+
+func readVersion(path string) string {
+	data, err := os.ReadFile(path) // #nosec G304 -- fixed path under config dir
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+func writeVersion(dir, path, version string) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return
+	}
+	// #nosec G306 -- version string, not secret
+	_ = os.WriteFile(path, []byte(version+"\n"), 0644)
+}
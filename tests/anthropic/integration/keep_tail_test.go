@@ -0,0 +1,122 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// sendBashOutputViaMock proxies one tool_result through the gateway to a mock
+// upstream and returns the tool_result content the upstream received.
+func sendBashOutputViaMock(t *testing.T, cfg *config.Config, output string) string {
+	t.Helper()
+
+	var mu sync.Mutex
+	var forwarded []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		forwarded = body
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	gwServer := httptest.NewServer(gateway.New(cfg).Handler())
+	defer gwServer.Close()
+
+	requestBody := map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Did the tests pass?"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_tail_001", "name": "bash", "input": map[string]string{"command": "go test ./..."}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_tail_001", "content": output},
+			}},
+		},
+	}
+	bodyBytes, err := json.Marshal(requestBody)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, gwServer.URL+"/v1/messages", bytes.NewReader(bodyBytes))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", anthropicVersion)
+	req.Header.Set("X-Target-URL", upstream.URL+"/v1/messages")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	mu.Lock()
+	defer mu.Unlock()
+	return gjson.GetBytes(forwarded, "messages.2.content.0.content").String()
+}
+
+func keepTailConfig(strategy string, keepTail int) *config.Config {
+	cfg := passthroughConfig()
+	cfg.Pipes.ToolOutput = config.ToolOutputPipeConfig{
+		Enabled:                strategy != config.StrategyPassthrough,
+		Strategy:               strategy,
+		MinTokens:              50,
+		MaxTokens:              50000,
+		TargetCompressionRatio: 0.9,
+		BypassCostCheck:        true,
+		KeepTailBytes:          keepTail,
+	}
+	return cfg
+}
+
+// TestKeepTail_BashSummarySurvives verifies the final PASS / ok lines of a large
+// bash output survive local truncation when keep_tail_bytes is set.
+func TestKeepTail_BashSummarySurvives(t *testing.T) {
+	output := generateLargeBashOutput(8000)
+	lines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+	summary := strings.Join(lines[len(lines)-2:], "\n") + "\n"
+	require.True(t, strings.HasPrefix(summary, "PASS\nok "), summary)
+
+	for _, strategy := range []string{config.StrategySimple, config.StrategyTrimming} {
+		t.Run(strategy, func(t *testing.T) {
+			sent := sendBashOutputViaMock(t, keepTailConfig(strategy, 200), output)
+
+			assert.Less(t, len(sent), len(output), "output should still be truncated")
+			assert.True(t, strings.HasSuffix(sent, output[len(output)-200:]), "last 200 bytes kept verbatim")
+			assert.Contains(t, sent, summary)
+			if strategy == config.StrategySimple {
+				assert.Contains(t, sent, "... omitted ...")
+				assert.True(t, strings.HasPrefix(sent, "=== RUN TestFunction0"), "head retention is kept")
+			}
+		})
+	}
+}
+
+// TestKeepTail_DisabledByDefault verifies simple truncation drops the summary
+// when keep_tail_bytes is unset.
+func TestKeepTail_DisabledByDefault(t *testing.T) {
+	output := generateLargeBashOutput(8000)
+
+	sent := sendBashOutputViaMock(t, keepTailConfig(config.StrategySimple, 0), output)
+
+	assert.Less(t, len(sent), len(output))
+	assert.NotContains(t, sent, "PASS\nok ")
+	assert.NotContains(t, sent, "... omitted ...")
+}
@@ -0,0 +1,117 @@
+// Offline replay of recorded Anthropic traffic.
+//
+// The cassette under tests/anthropic/fixtures/cassettes is served from a local
+// mock upstream, so this runs without network access or API keys. Re-record it
+// against the real API with:
+//
+//	go test ./tests/anthropic/integration -run TestReplay -record
+
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/tests/testkit"
+)
+
+const toolResultCassette = "../fixtures/cassettes/tool_result_flow.json"
+
+// replayTools is the tool set offered in the recorded tool-result flow.
+var replayTools = []map[string]interface{}{{
+	"name":        "list_files",
+	"description": "List the files in a directory.",
+	"input_schema": map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"path": map[string]interface{}{"type": "string"}},
+		"required":   []string{"path"},
+	},
+}}
+
+// postMessages sends body through the gateway to upstreamURL and returns the status and response body.
+func postMessages(t *testing.T, gwURL, upstreamURL, apiKey string, body map[string]interface{}) (int, []byte) {
+	t.Helper()
+	bodyBytes, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, gwURL+"/v1/messages", bytes.NewReader(bodyBytes))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", apiKey)
+	req.Header.Set("anthropic-version", anthropicVersion)
+	req.Header.Set("X-Target-URL", upstreamURL+"/v1/messages")
+
+	resp, err := (&http.Client{Timeout: 2 * time.Minute}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp.StatusCode, respBody
+}
+
+// TestReplay_AnthropicToolResultFlow replays a tool_use -> tool_result -> answer
+// exchange through gateway.Handler() and checks the client sees exactly the
+// recorded upstream responses.
+func TestReplay_AnthropicToolResultFlow(t *testing.T) {
+	apiKey := "sk-ant-replay"
+	if testkit.Recording() {
+		apiKey = getAnthropicKey(t)
+	}
+	cassette, err := testkit.NewCassette(toolResultCassette, anthropicBaseURL)
+	require.NoError(t, err)
+
+	gwServer := httptest.NewServer(gateway.New(passthroughConfig()).Handler())
+	defer gwServer.Close()
+
+	messages := []interface{}{
+		map[string]interface{}{"role": "user", "content": "Use the list_files tool on /srv/app, then tell me how many files there are."},
+	}
+	request := func() map[string]interface{} {
+		return map[string]interface{}{
+			"model":      anthropicModel,
+			"max_tokens": 200,
+			"tools":      replayTools,
+			"messages":   messages,
+		}
+	}
+
+	// Turn 1: the model asks for the tool.
+	status, first := postMessages(t, gwServer.URL, cassette.URL(), apiKey, request())
+	require.Equal(t, http.StatusOK, status, string(first))
+	require.Equal(t, "tool_use", gjson.GetBytes(first, "stop_reason").String())
+	toolUse := gjson.GetBytes(first, `content.#(type=="tool_use")`)
+	require.True(t, toolUse.Exists())
+
+	// Turn 2: the tool result goes back and the model answers.
+	var assistantContent interface{}
+	require.NoError(t, json.Unmarshal([]byte(gjson.GetBytes(first, "content").Raw), &assistantContent))
+	messages = append(messages,
+		map[string]interface{}{"role": "assistant", "content": assistantContent},
+		map[string]interface{}{"role": "user", "content": []map[string]interface{}{{
+			"type":        "tool_result",
+			"tool_use_id": toolUse.Get("id").String(),
+			"content":     "main.go\ngo.mod\ngo.sum\nREADME.md",
+		}}},
+	)
+	status, second := postMessages(t, gwServer.URL, cassette.URL(), apiKey, request())
+	require.Equal(t, http.StatusOK, status, string(second))
+	assert.Equal(t, "end_turn", gjson.GetBytes(second, "stop_reason").String())
+
+	require.NoError(t, cassette.Close())
+	assert.Empty(t, cassette.Errors())
+
+	interactions := cassette.Interactions()
+	require.Len(t, interactions, 2)
+	assert.JSONEq(t, interactions[0].ResponseBody, string(first))
+	assert.JSONEq(t, interactions[1].ResponseBody, string(second))
+	assert.Contains(t, interactions[1].RequestBody, `"tool_result"`)
+}
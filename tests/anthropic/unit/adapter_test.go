@@ -300,3 +300,37 @@ func TestAnthropic_ExtractUserQuery_InvalidJSON(t *testing.T) {
 func TestAnthropicAdapter_ImplementsInterface(t *testing.T) {
 	var _ adapters.Adapter = adapters.NewAnthropicAdapter()
 }
+
+func TestAnthropic_FilterToolCallFromResponse_KeepsThinkingBlocks(t *testing.T) {
+	adapter := adapters.NewAnthropicAdapter()
+
+	body := []byte(`{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"content": [
+			{"type": "thinking", "thinking": "The log looks truncated.", "signature": "sig_abc=="},
+			{"type": "redacted_thinking", "data": "EmwKAhgBEgy3va3pzix/LafPsn4aDFIT"},
+			{"type": "text", "text": "Let me see the full output."},
+			{"type": "tool_use", "id": "toolu_exp", "name": "expand_context", "input": {"id": "shadow_abc"}}
+		],
+		"stop_reason": "tool_use"
+	}`)
+
+	filtered, modified := adapter.FilterToolCallFromResponse(body, "expand_context")
+	require.True(t, modified)
+
+	var resp struct {
+		Content    []map[string]any `json:"content"`
+		StopReason string           `json:"stop_reason"`
+	}
+	require.NoError(t, json.Unmarshal(filtered, &resp))
+	require.Len(t, resp.Content, 3)
+	assert.Equal(t, "thinking", resp.Content[0]["type"])
+	assert.Equal(t, "The log looks truncated.", resp.Content[0]["thinking"])
+	assert.Equal(t, "sig_abc==", resp.Content[0]["signature"])
+	assert.Equal(t, "redacted_thinking", resp.Content[1]["type"])
+	assert.Equal(t, "EmwKAhgBEgy3va3pzix/LafPsn4aDFIT", resp.Content[1]["data"])
+	assert.Equal(t, "text", resp.Content[2]["type"])
+	assert.Equal(t, "end_turn", resp.StopReason)
+}
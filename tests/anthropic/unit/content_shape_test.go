@@ -0,0 +1,100 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// =============================================================================
+// CONTENT SHAPE TESTS
+//
+// Anthropic message and tool_result content may be a plain string or an array
+// of blocks. Extract/Apply must treat both the same and write back in the shape
+// the client sent, so the request structure (and its cache prefix) is unchanged.
+// =============================================================================
+
+const shapeBody = `{"model":"claude-3","messages":[` +
+	`{"role":"user","content":"read both files"},` +
+	`{"role":"user","content":[{"type":"text","text":"and be brief"}]},` +
+	`{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"read","input":{}},{"type":"tool_use","id":"t2","name":"read","input":{}}]},` +
+	`{"role":"user","content":[` +
+	`{"type":"tool_result","tool_use_id":"t1","content":"string form output"},` +
+	`{"type":"tool_result","tool_use_id":"t2","content":[` +
+	`{"type":"text","text":"array form ","cache_control":{"type":"ephemeral"}},` +
+	`{"type":"image","source":{"type":"base64","media_type":"image/png","data":"iVBORw0KGgo="}},` +
+	`{"type":"text","text":"output"}]}]}]}`
+
+// TestAnthropic_ContentShape_ExtractBothForms verifies string and array
+// tool_result content are extracted the same way.
+func TestAnthropic_ContentShape_ExtractBothForms(t *testing.T) {
+	adapter := adapters.NewAnthropicAdapter()
+
+	extracted, err := adapter.ExtractToolOutput([]byte(shapeBody))
+	require.NoError(t, err)
+	require.Len(t, extracted, 2)
+	assert.Equal(t, "string form output", extracted[0].Content)
+	assert.Equal(t, "array form output", extracted[1].Content)
+	assert.Equal(t, 3, extracted[1].MessageIndex)
+	assert.Equal(t, 1, extracted[1].BlockIndex)
+}
+
+// TestAnthropic_ContentShape_ApplyPreservesShape verifies a compressed
+// array-form tool_result stays an array with its non-text blocks, a string-form
+// one stays a string, and string/array user messages are untouched.
+func TestAnthropic_ContentShape_ApplyPreservesShape(t *testing.T) {
+	adapter := adapters.NewAnthropicAdapter()
+	body := []byte(shapeBody)
+
+	modified, err := adapter.ApplyToolOutput(body, []adapters.CompressedResult{
+		{ID: "t1", Compressed: "short 1", MessageIndex: 3, BlockIndex: 0},
+		{ID: "t2", Compressed: "short 2", MessageIndex: 3, BlockIndex: 1},
+	})
+	require.NoError(t, err)
+
+	for _, path := range []string{"model", "messages.0", "messages.1", "messages.2"} {
+		assert.Equal(t, gjson.GetBytes(body, path).Raw, gjson.GetBytes(modified, path).Raw, path)
+	}
+
+	first := gjson.GetBytes(modified, "messages.3.content.0.content")
+	assert.Equal(t, gjson.String, first.Type)
+	assert.Equal(t, "short 1", first.String())
+
+	second := gjson.GetBytes(modified, "messages.3.content.1.content")
+	require.True(t, second.IsArray(), "array-form content must stay an array")
+	blocks := second.Array()
+	require.Len(t, blocks, 2, "text blocks merge, the image is kept")
+	assert.Equal(t, "text", blocks[0].Get("type").String())
+	assert.Equal(t, "short 2", blocks[0].Get("text").String())
+	assert.Equal(t, "ephemeral", blocks[0].Get("cache_control.type").String())
+	assert.Equal(t, gjson.Get(shapeBody, "messages.3.content.1.content.1").Raw, blocks[1].Raw)
+
+	// Round trip: re-extracting sees the compressed text at the same positions.
+	extracted, err := adapter.ExtractToolOutput(modified)
+	require.NoError(t, err)
+	require.Len(t, extracted, 2)
+	assert.Equal(t, "short 1", extracted[0].Content)
+	assert.Equal(t, "short 2", extracted[1].Content)
+}
+
+// TestAnthropic_ContentShape_EmptyArrayFill verifies an empty array-form
+// tool_result receives its placeholder as a text block, not a string.
+func TestAnthropic_ContentShape_EmptyArrayFill(t *testing.T) {
+	adapter := adapters.NewAnthropicAdapter()
+	body := []byte(`{"messages":[{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"read","input":{}}]},` +
+		`{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[]}]}]}`)
+
+	empty, err := adapter.ExtractEmptyToolOutputs(body)
+	require.NoError(t, err)
+	require.Len(t, empty, 1)
+
+	modified, err := adapter.ApplyToolOutput(body, []adapters.CompressedResult{
+		{ID: "t1", Compressed: "(no output)", MessageIndex: 1, BlockIndex: 0},
+	})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"type":"text","text":"(no output)"}]`,
+		gjson.GetBytes(modified, "messages.1.content.0.content").Raw)
+}
@@ -21,6 +21,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
 
 	"github.com/compresr/context-gateway/internal/gateway"
 	"github.com/compresr/context-gateway/tests/common/fixtures"
@@ -265,6 +266,54 @@ func TestExpandContext_ShadowIDNotFound_Anthropic(t *testing.T) {
 		"expand_context should be filtered from final response")
 }
 
+// TestExpandContext_ShadowIDNotFound_ConfiguredMessage_Anthropic verifies an
+// expand_context call for an unknown shadow ID is answered with a valid
+// tool_result carrying tool_output.expand_not_found_message.
+func TestExpandContext_ShadowIDNotFound_ConfiguredMessage_Anthropic(t *testing.T) {
+	var callCount atomic.Int32
+	var followUp []byte
+
+	mockLLM := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count := callCount.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		if count == 1 {
+			w.Write(fixtures.AnthropicResponseWithExpandCall("toolu_expand_404", "shadow_hallucinated"))
+			return
+		}
+		followUp, _ = io.ReadAll(r.Body)
+		w.Write(fixtures.AnthropicFinalResponse("Continuing without expansion."))
+	}))
+	defer mockLLM.Close()
+
+	cfg := fixtures.SimpleCompressionConfig()
+	cfg.Pipes.ToolOutput.ExpandNotFoundMessage = "No stored content for {id}; do not retry."
+	gw := gateway.New(cfg)
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	requestBody := fixtures.AnthropicToolResultRequest("claude-sonnet-4-5", fixtures.LargeToolOutput)
+	req, err := http.NewRequest("POST", gwServer.URL+"/v1/messages", bytes.NewReader(requestBody))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", mockLLM.URL)
+
+	resp, err := (&http.Client{}).Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, followUp, "gateway sends the expand result back to the LLM")
+
+	last := gjson.GetBytes(followUp, "messages|@reverse|0")
+	assert.Equal(t, "user", last.Get("role").String())
+	blocks := last.Get("content").Array()
+	require.Len(t, blocks, 1)
+	assert.Equal(t, "tool_result", blocks[0].Get("type").String())
+	assert.Equal(t, "toolu_expand_404", blocks[0].Get("tool_use_id").String())
+	assert.Equal(t, "No stored content for shadow_hallucinated; do not retry.", blocks[0].Get("content").String())
+}
+
 // TestExpandContext_MultipleExpands_Anthropic verifies that the LLM can call
 // expand_context for multiple different shadow IDs in a single response.
 func TestExpandContext_MultipleExpands_Anthropic(t *testing.T) {
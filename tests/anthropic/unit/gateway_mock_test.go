@@ -465,8 +465,10 @@ func TestGateway_MissingTargetURL_ReturnsError(t *testing.T) {
 	defer resp.Body.Close()
 
 	// Should fail because no target URL can be determined
-	// Gateway returns 502 Bad Gateway when upstream request fails
-	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	// Gateway rejects it up front, naming X-Target-URL and server.default_upstream
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(body), "X-Target-URL")
 }
 
 // =============================================================================
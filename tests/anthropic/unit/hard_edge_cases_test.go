@@ -7,8 +7,10 @@ package unit
 
 import (
 	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -19,7 +21,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/tidwall/sjson"
 
+	"github.com/compresr/context-gateway/internal/adapters"
 	"github.com/compresr/context-gateway/internal/config"
 	"github.com/compresr/context-gateway/internal/gateway"
 	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
@@ -154,6 +158,166 @@ func TestHard_ManyToolOutputs(t *testing.T) {
 	assert.Less(t, elapsed, 5*time.Second)
 }
 
+// manyToolOutputsRequest builds a request with n distinct, compressible tool results.
+func manyToolOutputsRequest(n int) []byte {
+	toolUses := make([]interface{}, n)
+	toolResults := make([]interface{}, n)
+	for i := 0; i < n; i++ {
+		toolUses[i] = map[string]interface{}{
+			"type":  "tool_use",
+			"id":    fmt.Sprintf("toolu_%d", i),
+			"name":  "read_file",
+			"input": map[string]interface{}{"path": fmt.Sprintf("file_%d.txt", i)},
+		}
+		toolResults[i] = map[string]interface{}{
+			"type":        "tool_result",
+			"tool_use_id": fmt.Sprintf("toolu_%d", i),
+			"content":     fmt.Sprintf("tool %d: %s", i, strings.Repeat("padding data ", 50)),
+		}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model": "claude-3",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "read the files"},
+			map[string]interface{}{"role": "assistant", "content": toolUses},
+			map[string]interface{}{"role": "user", "content": toolResults},
+		},
+	})
+	return body
+}
+
+// concurrencyTrackingAPI returns a slow mock compression API that records peak in-flight calls.
+func concurrencyTrackingAPI(delay time.Duration, peak *atomic.Int64) *httptest.Server {
+	var inFlight atomic.Int64
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(delay)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"compressed_output": "compressed"},
+		})
+	}))
+}
+
+func concurrencyConfig(apiURL string, maxConcurrency, maxQueueDepth int) *config.Config {
+	return &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:          true,
+				Strategy:         config.StrategyCompresr,
+				FallbackStrategy: config.StrategyPassthrough,
+				MinTokens:        2,
+				MaxTokens:        262144,
+				Compresr: config.CompresrConfig{
+					Endpoint:       "/compress",
+					APIKey:         "test-key",
+					Timeout:        5 * time.Second,
+					MaxConcurrency: maxConcurrency,
+					MaxQueueDepth:  maxQueueDepth,
+				},
+			},
+		},
+		URLs: config.URLsConfig{
+			Compresr: apiURL,
+		},
+	}
+}
+
+func TestHard_ManyToolOutputs_ConcurrencyLimit(t *testing.T) {
+	var peak atomic.Int64
+	mockAPI := concurrencyTrackingAPI(20*time.Millisecond, &peak)
+	defer mockAPI.Close()
+
+	pipe := tooloutput.New(concurrencyConfig(mockAPI.URL, 3, 0), fixtures.TestStore())
+	ctx := fixtures.TestPipeContextAnthropic(manyToolOutputsRequest(40))
+
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+
+	assert.Greater(t, peak.Load(), int64(0), "mock API should have been called")
+	assert.LessOrEqual(t, peak.Load(), int64(3), "in-flight API calls must not exceed max_concurrency")
+	assert.Equal(t, int64(0), pipe.GetMetrics().QueueFull, "default queue depth should absorb the burst")
+}
+
+func TestHard_ManyToolOutputs_QueueFullFallsBack(t *testing.T) {
+	var peak atomic.Int64
+	mockAPI := concurrencyTrackingAPI(200*time.Millisecond, &peak)
+	defer mockAPI.Close()
+
+	pipe := tooloutput.New(concurrencyConfig(mockAPI.URL, 1, 1), fixtures.TestStore())
+	ctx := fixtures.TestPipeContextAnthropic(manyToolOutputsRequest(10))
+
+	start := time.Now()
+	result, err := pipe.Process(ctx)
+	elapsed := time.Since(start)
+
+	require.NoError(t, err)
+	assert.NotNil(t, result)
+	assert.LessOrEqual(t, peak.Load(), int64(1))
+	assert.Greater(t, pipe.GetMetrics().QueueFull, int64(0), "calls beyond queue depth should fall back")
+	assert.Less(t, elapsed, 2*time.Second, "overflow must not block on the semaphore")
+}
+
+// fixedSummaryAPI returns a mock compression API that always answers with summary.
+func fixedSummaryAPI(summary string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]interface{}{"compressed_output": summary},
+		})
+	}))
+}
+
+func TestHard_PreservePatterns_MissingErrorCodeFallsBack(t *testing.T) {
+	original := strings.Repeat("build step ok\n", 60) + "fatal: upstream returned ERR-4021 while linking\n"
+
+	for _, tc := range []struct {
+		name       string
+		summary    string
+		wantStatus string
+	}{
+		{"summary drops code", "build steps succeeded, then linking failed", "preserve_missed"},
+		{"summary keeps code", "build steps succeeded, linking failed with ERR-4021", "compressed"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockAPI := fixedSummaryAPI(tc.summary)
+			defer mockAPI.Close()
+
+			cfg := concurrencyConfig(mockAPI.URL, 0, 0)
+			cfg.Pipes.ToolOutput.PreservePatterns = []string{`ERR-[0-9]+`}
+			pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+			body, _ := json.Marshal(map[string]interface{}{
+				"model": "claude-3",
+				"messages": []interface{}{
+					map[string]interface{}{"role": "user", "content": "run the build"},
+					map[string]interface{}{"role": "assistant", "content": []interface{}{
+						map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "bash", "input": map[string]interface{}{}},
+					}},
+					map[string]interface{}{"role": "user", "content": []interface{}{
+						map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": original},
+					}},
+				},
+			})
+			ctx := fixtures.TestPipeContextAnthropic(body)
+			result, err := pipe.Process(ctx)
+			require.NoError(t, err)
+
+			require.Len(t, ctx.ToolOutputCompressions, 1)
+			assert.Equal(t, tc.wantStatus, ctx.ToolOutputCompressions[0].MappingStatus)
+			assert.Contains(t, string(result), "ERR-4021", "required error code must reach the LLM")
+		})
+	}
+}
+
 func TestHard_ZeroByteThreshold(t *testing.T) {
 	cfg := fixtures.TestConfig(config.StrategyPassthrough, 0, true)
 	pipe := tooloutput.New(cfg, fixtures.TestStore())
@@ -201,6 +365,246 @@ func TestHard_ConcurrentRequests_SameContent(t *testing.T) {
 	}
 }
 
+func TestHard_IdenticalToolOutputs_DedupedByReference(t *testing.T) {
+	cfg := fixtures.TestConfig(config.StrategySimple, 10, true)
+	cfg.Pipes.ToolOutput.DedupeIdentical = true
+	cfg.Pipes.ToolOutput.BypassCostCheck = true
+	st := fixtures.TestStore()
+	pipe := tooloutput.New(cfg, st)
+
+	content := strings.Repeat("package main // identical file content\n", 256) // ~10KB
+	var toolUses, toolResults []interface{}
+	for i := 0; i < 3; i++ {
+		id := fmt.Sprintf("toolu_%d", i)
+		toolUses = append(toolUses, map[string]interface{}{
+			"type": "tool_use", "id": id, "name": "read_file",
+			"input": map[string]interface{}{"path": "main.go"},
+		})
+		toolResults = append(toolResults, map[string]interface{}{
+			"type": "tool_result", "tool_use_id": id, "content": content,
+		})
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model": "claude-3",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "read main.go three times"},
+			map[string]interface{}{"role": "assistant", "content": toolUses},
+			map[string]interface{}{"role": "user", "content": toolResults},
+		},
+	})
+
+	ctx := fixtures.TestPipeContextAnthropic(body)
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+	assert.Less(t, len(result), len(body)/2, "duplicates should be replaced by short references")
+
+	var deduped []string
+	for _, c := range ctx.ToolOutputCompressions {
+		if c.MappingStatus == "deduplicated" {
+			deduped = append(deduped, c.ToolCallID)
+			assert.Contains(t, c.CompressedContent, "toolu_0", "reference should name the first occurrence")
+
+			// Expanding the reference yields the full original content
+			original, ok := st.Get(c.ShadowID)
+			require.True(t, ok)
+			assert.Equal(t, content, original)
+		}
+	}
+	assert.Equal(t, []string{"toolu_1", "toolu_2"}, deduped)
+}
+
+// topKRequest builds an Anthropic request with one tool result per entry in lines,
+// each holding that many distinct lines.
+func topKRequest(lines []int) []byte {
+	var toolUses, toolResults []interface{}
+	for i, n := range lines {
+		id := fmt.Sprintf("toolu_%d", i)
+		var b strings.Builder
+		for j := 0; j < n; j++ {
+			fmt.Fprintf(&b, "output %d line %d: build step finished\n", i, j)
+		}
+		toolUses = append(toolUses, map[string]interface{}{
+			"type": "tool_use", "id": id, "name": "bash", "input": map[string]interface{}{},
+		})
+		toolResults = append(toolResults, map[string]interface{}{
+			"type": "tool_result", "tool_use_id": id, "content": b.String(),
+		})
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"model": "claude-3",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "run the build"},
+			map[string]interface{}{"role": "assistant", "content": toolUses},
+			map[string]interface{}{"role": "user", "content": toolResults},
+		},
+	})
+	return body
+}
+
+// compressedToolResults returns the tool_use_ids whose results carry a shadow marker.
+func compressedToolResults(t *testing.T, body []byte) []string {
+	var req struct {
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(body, &req))
+	var blocks []struct {
+		Type      string `json:"type"`
+		ToolUseID string `json:"tool_use_id"`
+		Content   string `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(req.Messages[len(req.Messages)-1].Content, &blocks))
+	var ids []string
+	for _, b := range blocks {
+		if b.Type == "tool_result" && strings.Contains(b.Content, tooloutput.ShadowPrefixMarker) {
+			ids = append(ids, b.ToolUseID)
+		}
+	}
+	return ids
+}
+
+func TestHard_CompressTopK_OnlyLargestCompressed(t *testing.T) {
+	cfg := fixtures.TestConfig(config.StrategySimple, 10, true)
+	cfg.Pipes.ToolOutput.CompressTopK = 2
+	cfg.Pipes.ToolOutput.BypassCostCheck = true
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	// Ten outputs, all above min_tokens; toolu_7 and toolu_2 are the largest.
+	body := topKRequest([]int{200, 50, 900, 120, 60, 300, 80, 1000, 70, 90})
+
+	ctx := fixtures.TestPipeContextAnthropic(body)
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []string{"toolu_2", "toolu_7"}, compressedToolResults(t, result))
+
+	var notTopK int
+	for _, c := range ctx.ToolOutputCompressions {
+		if c.MappingStatus == "passthrough_not_top_k" {
+			notTopK++
+		}
+	}
+	assert.Equal(t, 8, notTopK)
+}
+
+func TestHard_CompressTopK_RulesFilterBeforeRanking(t *testing.T) {
+	cfg := fixtures.TestConfig(config.StrategySimple, 10, true)
+	cfg.Pipes.ToolOutput.CompressTopK = 1
+	cfg.Pipes.ToolOutput.MaxTokens = 8000
+	cfg.Pipes.ToolOutput.BypassCostCheck = true
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	// toolu_0 exceeds max_tokens, so it is not eligible and does not use up the
+	// single top-K slot: the next largest eligible output (toolu_2) is compressed.
+	body := topKRequest([]int{2000, 50, 150, 100})
+
+	ctx := fixtures.TestPipeContextAnthropic(body)
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"toolu_2"}, compressedToolResults(t, result))
+}
+
+func TestHard_CompressTopK_BinaryDoesNotTakeSlot(t *testing.T) {
+	cfg := fixtures.TestConfig(config.StrategySimple, 10, true)
+	cfg.Pipes.ToolOutput.CompressTopK = 1
+	cfg.Pipes.ToolOutput.BypassCostCheck = true
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	// toolu_0 is the largest output but a base64 blob, which is never
+	// compressed; the single top-K slot goes to toolu_2.
+	body := topKRequest([]int{10, 50, 150, 100})
+	raw := make([]byte, 24000)
+	for i := range raw {
+		raw[i] = byte(i * 7)
+	}
+	blob := base64.StdEncoding.EncodeToString(raw)
+	body, err := sjson.SetBytes(body, "messages.2.content.0.content", blob)
+	require.NoError(t, err)
+
+	ctx := fixtures.TestPipeContextAnthropic(body)
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"toolu_2"}, compressedToolResults(t, result))
+	require.NotEmpty(t, ctx.ToolOutputCompressions)
+	assert.Equal(t, "binary_skipped", ctx.ToolOutputCompressions[0].MappingStatus)
+}
+
+func TestHard_CompressTopK_ZeroCompressesAll(t *testing.T) {
+	cfg := fixtures.TestConfig(config.StrategySimple, 10, true)
+	cfg.Pipes.ToolOutput.BypassCostCheck = true
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	body := topKRequest([]int{200, 50, 300})
+
+	result, err := pipe.Process(fixtures.TestPipeContextAnthropic(body))
+	require.NoError(t, err)
+
+	assert.Len(t, compressedToolResults(t, result), 3)
+}
+
+// failingSetStore wraps a MemoryStore whose Set fails while failing is true.
+type failingSetStore struct {
+	*store.MemoryStore
+	failing atomic.Bool
+}
+
+func (s *failingSetStore) Set(key, value string) error {
+	if s.failing.Load() {
+		return fmt.Errorf("store unreachable")
+	}
+	return s.MemoryStore.Set(key, value)
+}
+
+func TestHard_StoreSetFails_PassthroughUntilRecovered(t *testing.T) {
+	cfg := fixtures.TestConfig(config.StrategySimple, 10, true)
+	cfg.Pipes.ToolOutput.BypassCostCheck = true
+	st := &failingSetStore{MemoryStore: store.NewMemoryStore(5 * time.Minute)}
+	defer st.Close()
+	pipe := tooloutput.New(cfg, st)
+
+	content := strings.Repeat("log line with useful detail\n", 400)
+	body, _ := json.Marshal(map[string]interface{}{
+		"model": "claude-3",
+		"messages": []interface{}{
+			map[string]interface{}{"role": "user", "content": "read the log"},
+			map[string]interface{}{"role": "assistant", "content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": "toolu_1", "name": "read_file", "input": map[string]interface{}{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": "toolu_1", "content": content},
+			}},
+		},
+	})
+
+	// Store down: content is forwarded unchanged, no shadow markers are emitted.
+	st.failing.Store(true)
+	ctx := fixtures.TestPipeContextAnthropic(body)
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, ctx.ShadowRefs)
+	assert.NotContains(t, string(result), tooloutput.ShadowIDPrefix)
+	assert.Contains(t, string(result), strings.Repeat("log line with useful detail\\n", 10))
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	assert.Equal(t, "passthrough_store_unavailable", ctx.ToolOutputCompressions[0].MappingStatus)
+	assert.Equal(t, int64(1), pipe.GetMetrics().StoreUnavailable)
+
+	// Store recovered: the next request compresses normally and is expandable.
+	st.failing.Store(false)
+	ctx = fixtures.TestPipeContextAnthropic(body)
+	result, err = pipe.Process(ctx)
+	require.NoError(t, err)
+	assert.Less(t, len(result), len(body))
+	require.Len(t, ctx.ShadowRefs, 1)
+	for id := range ctx.ShadowRefs {
+		original, ok := st.Get(id)
+		require.True(t, ok)
+		assert.Equal(t, content, original)
+	}
+}
+
 func TestHard_ConcurrentStoreAccess(t *testing.T) {
 	st := store.NewMemoryStore(5 * time.Minute)
 
@@ -420,6 +824,78 @@ func TestHard_Compression_APITimeout(t *testing.T) {
 	assert.Less(t, elapsed, 2*time.Second, "Should timeout quickly")
 }
 
+func htmlAPIConfig(apiURL string, to config.ToolOutputPipeConfig) *config.Config {
+	to.Enabled = true
+	to.Strategy = config.StrategyCompresr
+	to.MinTokens = 2
+	to.MaxTokens = 262144
+	to.Compresr = config.CompresrConfig{Endpoint: "/compress", APIKey: "cmp_test", Timeout: 5 * time.Second}
+	return &config.Config{
+		Pipes: config.PipesConfig{ToolOutput: to},
+		URLs:  config.URLsConfig{Compresr: apiURL},
+	}
+}
+
+func TestHard_Compression_APIReturnsHTML_FallbackChainCompressesLocally(t *testing.T) {
+	var apiCalls atomic.Int32
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiCalls.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Error page</body></html>"))
+	}))
+	defer mockAPI.Close()
+
+	cfg := htmlAPIConfig(mockAPI.URL, config.ToolOutputPipeConfig{
+		FallbackStrategy: config.StrategyPassthrough, // ignored: fallback_chain wins
+		FallbackChain:    []string{config.StrategyLocal, config.StrategyPassthrough},
+	})
+	require.NoError(t, cfg.Pipes.ToolOutput.Validate())
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	content := strings.Repeat("test content ", 50)
+	ctx := fixtures.TestPipeContext(fixtures.RequestWithSingleToolOutput(content))
+
+	_, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Positive(t, apiCalls.Load(), "API should be tried before the chain")
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	c := ctx.ToolOutputCompressions[0]
+	assert.Equal(t, "compressed", c.MappingStatus, "local level should handle the output")
+	assert.Less(t, c.CompressedTokens, c.OriginalTokens)
+	assert.Contains(t, c.CompressedContent, "[TRIMMED")
+}
+
+func TestHard_Compression_APIReturnsHTML_SingleFallbackStrategy(t *testing.T) {
+	mockAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>Error page</body></html>"))
+	}))
+	defer mockAPI.Close()
+
+	cfg := htmlAPIConfig(mockAPI.URL, config.ToolOutputPipeConfig{FallbackStrategy: config.StrategyPassthrough})
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	content := strings.Repeat("test content ", 50)
+	ctx := fixtures.TestPipeContext(fixtures.RequestWithSingleToolOutput(content))
+
+	_, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	assert.Equal(t, "passthrough", ctx.ToolOutputCompressions[0].MappingStatus)
+	assert.Equal(t, content, ctx.ToolOutputCompressions[0].CompressedContent)
+}
+
+func TestHard_FallbackChain_RejectsUnknownLevel(t *testing.T) {
+	to := config.ToolOutputPipeConfig{
+		Enabled:       true,
+		Strategy:      config.StrategySimple,
+		FallbackChain: []string{config.StrategyCompresr, config.StrategyPassthrough},
+	}
+	assert.ErrorContains(t, to.Validate(), "fallback_chain")
+}
+
 // =============================================================================
 // STREAMING EDGE CASES
 // =============================================================================
@@ -695,6 +1171,43 @@ func TestHard_Store_CompressedWithoutOriginal(t *testing.T) {
 	assert.Equal(t, "compressed but no original", compressed)
 }
 
+// expandOnce runs one expand_context call for shadowID and returns the tool_result text.
+func expandOnce(t *testing.T, h *gateway.ExpandContextHandler, shadowID string) string {
+	t.Helper()
+	result := h.HandleCalls([]gateway.PhantomToolCall{{
+		ToolUseID: "toolu_expand_orphan",
+		ToolName:  gateway.ExpandContextToolName,
+		Input:     map[string]any{"id": shadowID},
+	}}, adapters.NewAnthropicAdapter(), nil)
+	require.Len(t, result.ToolResults, 1)
+	blocks, ok := result.ToolResults[0]["content"].([]any)
+	require.True(t, ok)
+	require.Len(t, blocks, 1)
+	block, ok := blocks[0].(map[string]any)
+	require.True(t, ok)
+	text, _ := block["content"].(string)
+	return text
+}
+
+func TestHard_Expand_CompressedWithoutOriginal(t *testing.T) {
+	st := store.NewMemoryStore(5 * time.Minute)
+	shadowID := "shadow_orphaned_compressed"
+	st.SetCompressed(shadowID, "compressed but no original")
+
+	// Default: the model gets the not-found message.
+	text := expandOnce(t, gateway.NewExpandContextHandler(st), shadowID)
+	assert.Equal(t, strings.ReplaceAll(gateway.DefaultExpandNotFoundMessage, "{id}", shadowID), text)
+
+	// Opt-in: the compressed version comes back with an expiry note.
+	text = expandOnce(t, gateway.NewExpandContextHandler(st).WithCompressedFallback(true), shadowID)
+	assert.Equal(t, strings.ReplaceAll(gateway.ExpandCompressedFallbackNote, "{id}", shadowID)+"compressed but no original", text)
+
+	// Nothing stored at all: still not found.
+	text = expandOnce(t, gateway.NewExpandContextHandler(st).WithCompressedFallback(true), "shadow_missing")
+	assert.Contains(t, text, "shadow_missing")
+	assert.NotContains(t, text, "compressed version")
+}
+
 func TestHard_Store_DeleteDuringIteration(t *testing.T) {
 	st := store.NewMemoryStore(5 * time.Minute)
 
@@ -1066,3 +1579,76 @@ app-api-1   | Server listening on :8080`
 
 
 
+
+// =============================================================================
+// COMPRESSION TIMEOUTS: connect / first-byte fail fast
+// =============================================================================
+
+// stalledListener accepts TCP connections but never writes, so a TLS handshake hangs.
+func stalledListener(t *testing.T) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, c := range conns {
+				_ = c.Close()
+			}
+		}()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+	return ln
+}
+
+func TestHard_Compression_ConnectTimeout(t *testing.T) {
+	ln := stalledListener(t)
+	defer ln.Close()
+
+	cfg := concurrencyConfig("https://"+ln.Addr().String(), 0, 0)
+	cfg.Pipes.ToolOutput.Compresr.Timeouts = config.TimeoutsConfig{
+		Connect: 100 * time.Millisecond,
+		Overall: 10 * time.Second,
+	}
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	body := manyToolOutputsRequest(1)
+	ctx := fixtures.TestPipeContextAnthropic(body)
+	start := time.Now()
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	// Fails on the 100ms connect deadline, not the 10s overall one.
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.NotContains(t, string(result), tooloutput.ShadowIDPrefix)
+	assert.Empty(t, ctx.ShadowRefs)
+}
+
+func TestHard_Compression_FirstByteTimeout(t *testing.T) {
+	release := make(chan struct{})
+	slowAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slowAPI.Close()
+	defer close(release)
+
+	cfg := concurrencyConfig(slowAPI.URL, 0, 0)
+	cfg.Pipes.ToolOutput.Compresr.Timeouts = config.TimeoutsConfig{
+		FirstByte: 100 * time.Millisecond,
+		Overall:   10 * time.Second,
+	}
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+
+	ctx := fixtures.TestPipeContextAnthropic(manyToolOutputsRequest(1))
+	start := time.Now()
+	result, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	assert.NotContains(t, string(result), tooloutput.ShadowIDPrefix)
+}
@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/tests/anthropic/fixtures"
+)
+
+// sequentialShadowIDs hands out shadow_fixed_1, shadow_fixed_2, ... in first-seen
+// order, returning the same ID for repeated content like the hash generator does.
+type sequentialShadowIDs struct {
+	mu  sync.Mutex
+	ids map[string]string
+}
+
+func (g *sequentialShadowIDs) ShadowID(content string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.ids == nil {
+		g.ids = make(map[string]string)
+	}
+	if id, ok := g.ids[content]; ok {
+		return id
+	}
+	id := fmt.Sprintf("shadow_fixed_%d", len(g.ids)+1)
+	g.ids[content] = id
+	return id
+}
+
+func TestShadowIDGenerator_DefaultIsContentHash(t *testing.T) {
+	g := tooloutput.HashShadowIDGenerator{}
+
+	id := g.ShadowID("hello")
+	assert.True(t, strings.HasPrefix(id, tooloutput.ShadowIDPrefix))
+	assert.Len(t, id, len(tooloutput.ShadowIDPrefix)+32)
+	assert.Equal(t, id, g.ShadowID("hello"))
+	assert.NotEqual(t, id, g.ShadowID("hello!"))
+}
+
+func TestShadowIDGenerator_InjectedIDsInRewrittenBody(t *testing.T) {
+	st := fixtures.TestStore()
+	pipe := tooloutput.New(fixtures.TestConfig(config.StrategySimple, 10, true), st)
+	pipe.SetShadowIDGenerator(&sequentialShadowIDs{})
+
+	ctx := fixtures.TestPipeContextAnthropic(topKRequest([]int{40, 60}))
+	body, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `[REF:shadow_fixed_1]`)
+	assert.Contains(t, string(body), `[REF:shadow_fixed_2]`)
+	assert.Contains(t, string(body), `expand_context(id=\"shadow_fixed_1\")`)
+
+	original, ok := st.Get("shadow_fixed_2")
+	require.True(t, ok, "original stored under the injected ID")
+	assert.Contains(t, original, "output 1 line 59")
+}
+
+func TestShadowIDGenerator_InjectedIDsInDedupeRefs(t *testing.T) {
+	cfg := fixtures.TestConfig(config.StrategySimple, 10, true)
+	cfg.Pipes.ToolOutput.DedupeIdentical = true
+	pipe := tooloutput.New(cfg, fixtures.TestStore())
+	pipe.SetShadowIDGenerator(&sequentialShadowIDs{})
+
+	output := strings.Repeat("identical build log line\n", 40)
+	ctx := fixtures.TestPipeContext(fixtures.MultiToolOutputRequest(output, output))
+	body, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), `[REF:shadow_fixed_1]\n[Identical to the tool result for call_001 above]`)
+	assert.NotContains(t, string(body), "shadow_fixed_2")
+}
+
+func TestShadowIDGenerator_NilRestoresDefault(t *testing.T) {
+	pipe := tooloutput.New(fixtures.TestConfig(config.StrategySimple, 10, true), fixtures.TestStore())
+	pipe.SetShadowIDGenerator(&sequentialShadowIDs{})
+	pipe.SetShadowIDGenerator(nil)
+
+	content := strings.Repeat("line of tool output\n", 40)
+	ctx := fixtures.TestPipeContextAnthropic(fixtures.AnthropicSingleToolOutputRequest(content))
+	body, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(body), "[REF:"+tooloutput.HashShadowIDGenerator{}.ShadowID(content)+"]")
+}
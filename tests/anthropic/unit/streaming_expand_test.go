@@ -6,6 +6,7 @@
 package unit
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -48,3 +49,48 @@ func TestStreamBuffer_IgnoresNonTextEvents(t *testing.T) {
 
 	assert.False(t, buffer.HasSuppressedCalls())
 }
+
+// TestStreamBuffer_ThinkingBlocksSurviveExpandSuppression verifies thinking events are
+// forwarded, rebuilt with their signature, and kept in order while expand_context is suppressed.
+func TestStreamBuffer_ThinkingBlocksSurviveExpandSuppression(t *testing.T) {
+	buffer := tooloutput.NewStreamBuffer()
+
+	chunks := []string{
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}` + "\n\n",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"Need the "}}` + "\n\n",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"full log."}}` + "\n\n",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig_abc=="}}` + "\n\n",
+		`data: {"type":"content_block_stop","index":0}` + "\n\n",
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"redacted_thinking","data":"EmwKAhgB"}}` + "\n\n",
+		`data: {"type":"content_block_stop","index":1}` + "\n\n",
+		`data: {"type":"content_block_start","index":2,"content_block":{"type":"tool_use","id":"toolu_exp","name":"expand_context","input":{}}}` + "\n\n",
+		`data: {"type":"content_block_delta","index":2,"delta":{"type":"input_json_delta","partial_json":"{\"id\":\"shadow_abc\"}"}}` + "\n\n",
+		`data: {"type":"content_block_stop","index":2}` + "\n\n",
+	}
+
+	var forwarded []string
+	for _, chunk := range chunks {
+		out, err := buffer.ProcessChunk([]byte(chunk))
+		assert.NoError(t, err)
+		if out != nil {
+			forwarded = append(forwarded, string(out))
+		}
+	}
+
+	joined := strings.Join(forwarded, "")
+	assert.Contains(t, joined, "thinking_delta")
+	assert.Contains(t, joined, "signature_delta")
+	assert.Contains(t, joined, "redacted_thinking")
+	assert.NotContains(t, joined, "expand_context")
+
+	calls := buffer.GetSuppressedCalls()
+	if assert.Len(t, calls, 1) {
+		assert.Equal(t, "shadow_abc", calls[0].ShadowID)
+	}
+
+	blocks := buffer.GetThinkingBlocks()
+	if assert.Len(t, blocks, 2) {
+		assert.Equal(t, map[string]any{"type": "thinking", "thinking": "Need the full log.", "signature": "sig_abc=="}, blocks[0])
+		assert.Equal(t, map[string]any{"type": "redacted_thinking", "data": "EmwKAhgB"}, blocks[1])
+	}
+}
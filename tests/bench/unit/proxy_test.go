@@ -0,0 +1,50 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/bench"
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+func passthroughConfig() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{
+			Port:         18080,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		},
+		Pipes: config.PipesConfig{
+			ToolOutput:    config.ToolOutputPipeConfig{Enabled: false, Strategy: "passthrough", FallbackStrategy: "passthrough"},
+			ToolDiscovery: config.ToolDiscoveryPipeConfig{Enabled: false},
+		},
+		Store:      config.StoreConfig{Type: "memory", TTL: time.Hour},
+		Monitoring: config.MonitoringConfig{LogLevel: "disabled", LogFormat: "json", LogOutput: "discard"},
+	}
+}
+
+// TestProxy_ReportsPercentiles verifies the benchmark completes offline and
+// returns ordered, non-zero percentiles for every path.
+func TestProxy_ReportsPercentiles(t *testing.T) {
+	res, err := bench.Proxy(passthroughConfig(), bench.ProxyOptions{Requests: 40, Warmup: 5})
+	require.NoError(t, err)
+	assert.Equal(t, 40, res.Requests)
+
+	for name, l := range map[string]bench.Latencies{"direct": res.Direct, "proxied": res.Proxied} {
+		assert.Positive(t, l.P50, name)
+		assert.LessOrEqual(t, l.P50, l.P95, name)
+		assert.LessOrEqual(t, l.P95, l.P99, name)
+	}
+	assert.LessOrEqual(t, res.Added.P50, res.Added.P95)
+	assert.LessOrEqual(t, res.Added.P95, res.Added.P99)
+}
+
+// TestProxy_RejectsZeroRequests verifies an empty run is an error, not a zero report.
+func TestProxy_RejectsZeroRequests(t *testing.T) {
+	_, err := bench.Proxy(passthroughConfig(), bench.ProxyOptions{})
+	assert.Error(t, err)
+}
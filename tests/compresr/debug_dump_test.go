@@ -0,0 +1,74 @@
+package compresr_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/compresr"
+)
+
+// TestClient_DebugDumpDir verifies a compression call with debug_dump_dir set
+// writes a request/response pair holding the payload and the raw (here HTML)
+// response, with the API key scrubbed.
+func TestClient_DebugDumpDir(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>Error page</body></html>"))
+	}))
+	defer server.Close()
+
+	const apiKey = "cmp_secret_key_0123456789"
+	dir := t.TempDir()
+	client := compresr.NewClient(server.URL, apiKey, compresr.WithDebugDumpDir(dir))
+
+	_, err := client.CompressToolOutput(compresr.CompressToolOutputParams{
+		ToolOutput: "total 42\ndrwxr-xr-x  src",
+		ToolName:   "bash",
+	})
+	require.Error(t, err, "HTML is not a valid API response")
+
+	requests, _ := filepath.Glob(filepath.Join(dir, "*.request.json"))
+	responses, _ := filepath.Glob(filepath.Join(dir, "*.response.json"))
+	require.Len(t, requests, 1)
+	require.Len(t, responses, 1)
+	assert.Equal(t, strings.TrimSuffix(requests[0], ".request.json"), strings.TrimSuffix(responses[0], ".response.json"))
+
+	req, err := os.ReadFile(requests[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(req), "/api/compress/tool-output/")
+	assert.Contains(t, string(req), `"tool_output": "total 42\ndrwxr-xr-x  src"`)
+
+	resp, err := os.ReadFile(responses[0])
+	require.NoError(t, err)
+	assert.Contains(t, string(resp), "<html><body>Error page</body></html>")
+	assert.Contains(t, string(resp), `"status": 200`)
+
+	for _, dump := range [][]byte{req, resp} {
+		assert.NotContains(t, string(dump), apiKey)
+	}
+}
+
+// TestClient_DebugDumpDir_OffByDefault verifies no dumps are written without the option.
+func TestClient_DebugDumpDir_OffByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"success": true, "data": {"compressed_output": "ok"}}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	client := compresr.NewClient(server.URL, "cmp_secret_key_0123456789")
+	_, err := client.CompressToolOutput(compresr.CompressToolOutputParams{ToolOutput: "x", ToolName: "bash"})
+	require.NoError(t, err)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
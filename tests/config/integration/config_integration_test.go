@@ -231,3 +231,30 @@ store:
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "config file path is required")
 }
+
+// TestIntegration_Config_UpstreamHeaders verifies server.upstream_headers values
+// are env-expanded and that malformed header names are rejected.
+func TestIntegration_Config_UpstreamHeaders(t *testing.T) {
+	t.Setenv("TEST_OPENAI_ORG", "org-from-env")
+
+	yamlContent := `
+server:
+  port: 18081
+  read_timeout: 30s
+  write_timeout: 120s
+  upstream_headers:
+    OpenAI-Organization: "${TEST_OPENAI_ORG}"
+
+store:
+  type: "memory"
+  ttl: 1h
+`
+	cfg, err := config.LoadFromBytes([]byte(yamlContent))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"OpenAI-Organization": "org-from-env"}, cfg.Server.UpstreamHeaders)
+
+	cfg.Server.UpstreamHeaders["Bad Header"] = "x"
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.upstream_headers")
+}
@@ -0,0 +1,68 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// TestIntegration_Config_RemoteSourceDrivesGateway serves a config over HTTP,
+// loads it through HTTPSource, starts a gateway from it, and verifies a change
+// on the server is picked up via WatchConfigSource.
+func TestIntegration_Config_RemoteSourceDrivesGateway(t *testing.T) {
+	const base = `
+server:
+  port: 19191
+  read_timeout: 15s
+  write_timeout: 60s
+store:
+  type: memory
+  ttl: 1h
+cost_control:
+  enabled: true
+  session_cap: %s
+`
+	var sessionCap atomic.Value
+	sessionCap.Store("1.5")
+	remote := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(fmt.Sprintf(base, sessionCap.Load().(string))))
+	}))
+	defer remote.Close()
+
+	src := config.NewHTTPSource(remote.URL+"/gateway.yaml", t.TempDir())
+	data, _, err := src.Fetch(context.Background())
+	require.NoError(t, err)
+	cfg, err := config.LoadFromBytes(data)
+	require.NoError(t, err)
+	assert.Equal(t, 19191, cfg.Server.Port)
+
+	gw := gateway.New(cfg)
+	defer func() { _ = gw.Shutdown(context.Background()) }()
+	srv := httptest.NewServer(gw.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/health")
+	require.NoError(t, err)
+	var health map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	gw.WatchConfigSource(src, 20*time.Millisecond)
+	sessionCap.Store("7.25")
+
+	assert.Eventually(t, func() bool {
+		return gw.ConfigReloader().Current().CostControl.SessionCap == 7.25
+	}, 3*time.Second, 20*time.Millisecond, "remote change should be reloaded")
+}
@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+func writeSetupFile(t *testing.T, base, rel, content string) {
+	t.Helper()
+	p := filepath.Join(base, filepath.FromSlash(rel))
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0750))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0600))
+}
+
+// bundleEntries returns name → content for every file in a bundle.
+func bundleEntries(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	out := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		var buf bytes.Buffer
+		_, _ = buf.ReadFrom(tr)
+		out[hdr.Name] = buf.String()
+	}
+	return out
+}
+
+func TestBundle_RoundTripReproducesConfigs(t *testing.T) {
+	src := t.TempDir()
+	writeSetupFile(t, src, "configs/fast_setup.yaml", "server:\n  port: 18081\n")
+	writeSetupFile(t, src, "configs/custom.yaml", "server:\n  port: 18090\n")
+	writeSetupFile(t, src, "agents/claude_code.yaml", "agent:\n  name: claude_code\n")
+	writeSetupFile(t, src, ".env", "ANTHROPIC_API_KEY=sk-ant-0123456789abcdef\n")
+	writeSetupFile(t, src, "logs/ignored.yaml", "not exported")
+
+	var buf bytes.Buffer
+	manifest, err := config.ExportBundle(src, &buf, false, "v1.0.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"agents/claude_code.yaml", "configs/custom.yaml", "configs/fast_setup.yaml"}, manifest.Files)
+	assert.NotContains(t, bundleEntries(t, buf.Bytes()), ".env", "secrets must not be exported unless flagged")
+
+	dst := t.TempDir()
+	written, err := config.ImportBundle(bytes.NewReader(buf.Bytes()), dst, nil)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, manifest.Files, written)
+
+	for _, rel := range manifest.Files {
+		want, _ := os.ReadFile(filepath.Join(src, rel))
+		got, err := os.ReadFile(filepath.Join(dst, rel))
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got))
+	}
+	_, err = os.Stat(filepath.Join(dst, ".env"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestBundle_IncludeSecretsMasksManifest(t *testing.T) {
+	src := t.TempDir()
+	writeSetupFile(t, src, "configs/fast_setup.yaml", "server: {}\n")
+	writeSetupFile(t, src, ".env", "ANTHROPIC_API_KEY=sk-ant-0123456789abcdef\n")
+
+	var buf bytes.Buffer
+	manifest, err := config.ExportBundle(src, &buf, true, "")
+	require.NoError(t, err)
+	assert.Contains(t, manifest.Files, ".env")
+
+	entries := bundleEntries(t, buf.Bytes())
+	assert.Contains(t, entries[".env"], "sk-ant-0123456789abcdef")
+	assert.NotContains(t, entries[config.BundleManifestName], "sk-ant-0123456789abcdef")
+
+	var stored config.BundleManifest
+	require.NoError(t, json.Unmarshal([]byte(entries[config.BundleManifestName]), &stored))
+	assert.Equal(t, "sk-ant-0...cdef", stored.Secrets["ANTHROPIC_API_KEY"])
+}
+
+func TestBundle_ImportConflictPrompt(t *testing.T) {
+	src := t.TempDir()
+	writeSetupFile(t, src, "configs/a.yaml", "new\n")
+	var buf bytes.Buffer
+	_, err := config.ExportBundle(src, &buf, false, "")
+	require.NoError(t, err)
+
+	dst := t.TempDir()
+	writeSetupFile(t, dst, "configs/a.yaml", "old\n")
+
+	var asked []string
+	written, err := config.ImportBundle(bytes.NewReader(buf.Bytes()), dst, func(rel string) bool {
+		asked = append(asked, rel)
+		return false
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"configs/a.yaml"}, asked)
+	assert.Empty(t, written)
+	got, _ := os.ReadFile(filepath.Join(dst, "configs", "a.yaml"))
+	assert.Equal(t, "old\n", string(got), "declined overwrite keeps the existing file")
+}
+
+func TestBundle_ImportRejectsPathTraversal(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	data := []byte("evil")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "configs/../../evil.yaml", Mode: 0644, Size: int64(len(data))}))
+	_, _ = tw.Write(data)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	_, err := config.ImportBundle(&buf, t.TempDir(), nil)
+	require.Error(t, err)
+}
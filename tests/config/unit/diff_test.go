@@ -0,0 +1,84 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+const diffBaseYAML = `
+server:
+  port: 18099
+  read_timeout: 30s
+  write_timeout: 60s
+store:
+  type: memory
+  ttl: 1h
+preemptive:
+  enabled: true
+  trigger_threshold: 85.0
+  summarizer:
+    strategy: "external_provider"
+    model: "claude-haiku-4-5"
+    max_tokens: 4096
+    timeout: 60s
+  session:
+    summary_ttl: 3h
+    hash_message_count: 3
+`
+
+func loadYAML(t *testing.T, yaml string) *config.Config {
+	t.Helper()
+	cfg, err := config.LoadFromBytes([]byte(yaml))
+	require.NoError(t, err)
+	return cfg
+}
+
+// TestDiff_OnlyChangedField verifies configs differing only in trigger_threshold
+// (and in formatting) report exactly that field.
+func TestDiff_OnlyChangedField(t *testing.T) {
+	a := loadYAML(t, diffBaseYAML)
+	reformatted := strings.Replace(diffBaseYAML, "trigger_threshold: 85.0", "trigger_threshold: 70   # earlier", 1)
+	b := loadYAML(t, "# comment\n"+reformatted)
+
+	diffs := config.Diff(a, b)
+	require.Len(t, diffs, 1)
+	assert.Equal(t, config.FieldDiff{Path: "preemptive.trigger_threshold", A: "85", B: "70"}, diffs[0])
+
+	assert.Empty(t, config.Diff(a, loadYAML(t, diffBaseYAML)))
+}
+
+// TestDiff_EnvExpansionAndSecrets verifies values are compared after env
+// expansion, map entries are keyed by name and credentials are masked.
+func TestDiff_EnvExpansionAndSecrets(t *testing.T) {
+	t.Setenv("CG_DIFF_PORT", "18099")
+	t.Setenv("CG_DIFF_KEY", "sk-ant-REDACTED")
+	viaEnv := strings.Replace(diffBaseYAML, "port: 18099", "port: ${CG_DIFF_PORT}", 1) + `
+providers:
+  anthropic:
+    api_key: "${CG_DIFF_KEY}"
+    model: "claude-haiku-4-5"
+`
+	literal := diffBaseYAML + `
+providers:
+  anthropic:
+    api_key: "sk-ant-REDACTED"
+    model: "claude-haiku-4-5"
+  openai:
+    model: "gpt-4o-mini"
+`
+	diffs := config.Diff(loadYAML(t, viaEnv), loadYAML(t, literal))
+
+	paths := make([]string, 0, len(diffs))
+	for _, d := range diffs {
+		paths = append(paths, d.Path)
+	}
+	assert.Equal(t, []string{"providers.anthropic.api_key", "providers.openai.model"}, paths, "port is equal after expansion")
+	assert.Equal(t, "sk-ant-0...ghij", diffs[0].A)
+	assert.NotContains(t, diffs[0].B, "zyxwvu")
+	assert.Equal(t, config.FieldDiff{Path: "providers.openai.model", A: `""`, B: `"gpt-4o-mini"`}, diffs[1], "added provider lists its fields")
+}
@@ -0,0 +1,134 @@
+package unit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+const geminiDoctorYAML = `
+server:
+  port: 18099
+  read_timeout: 30s
+  write_timeout: 60s
+store:
+  type: memory
+  ttl: 1h
+providers:
+  gemini:
+    api_key: "${DOCTOR_TEST_GEMINI_KEY:-}"
+    model: "gemini-2.5-flash"
+    endpoint: "${DOCTOR_TEST_ENDPOINT:-}"
+preemptive:
+  enabled: true
+  trigger_threshold: 85.0
+  summarizer:
+    provider: gemini
+    max_tokens: 4096
+    timeout: 60s
+  session:
+    summary_ttl: 3h
+    hash_message_count: 3
+`
+
+// TestDoctor_MissingProviderKey verifies a Gemini config without its key is
+// reported before launch, and passes once the key is set.
+func TestDoctor_MissingProviderKey(t *testing.T) {
+	cfg := loadYAML(t, geminiDoctorYAML)
+	issues := cfg.Doctor(context.Background(), config.DoctorOptions{})
+	require.Len(t, issues, 1)
+	assert.Equal(t, config.DoctorIssue{
+		Provider: "gemini",
+		Kind:     config.IssueMissingKey,
+		Message:  `provider "gemini" has no api_key (is its environment variable set?)`,
+	}, issues[0])
+
+	t.Setenv("DOCTOR_TEST_GEMINI_KEY", "AIzaSyTestKeyTestKeyTestKey")
+	assert.Empty(t, loadYAML(t, geminiDoctorYAML).Doctor(context.Background(), config.DoctorOptions{}))
+}
+
+// TestDoctor_CapturedProviderAndOAuth verifies keys the gateway captures from
+// the agent's own requests are not required.
+func TestDoctor_CapturedProviderAndOAuth(t *testing.T) {
+	cfg := loadYAML(t, geminiDoctorYAML)
+	assert.Empty(t, cfg.Doctor(context.Background(), config.DoctorOptions{CapturedProvider: "gemini"}))
+
+	p := cfg.Providers["gemini"]
+	p.Auth = "oauth"
+	cfg.Providers["gemini"] = p
+	assert.Empty(t, cfg.Doctor(context.Background(), config.DoctorOptions{}))
+}
+
+func TestDoctor_ModelMismatch(t *testing.T) {
+	t.Setenv("DOCTOR_TEST_GEMINI_KEY", "AIzaSyTestKeyTestKeyTestKey")
+	cfg := loadYAML(t, geminiDoctorYAML)
+	p := cfg.Providers["gemini"]
+	p.Model = "claude-haiku-4-5"
+	cfg.Providers["gemini"] = p
+
+	issues := cfg.Doctor(context.Background(), config.DoctorOptions{})
+	require.Len(t, issues, 1)
+	assert.Equal(t, config.IssueModelMismatch, issues[0].Kind)
+	assert.Contains(t, issues[0].Message, `model "claude-haiku-4-5" belongs to anthropic`)
+}
+
+func TestDoctor_Ping(t *testing.T) {
+	t.Setenv("DOCTOR_TEST_GEMINI_KEY", "AIzaSyTestKeyTestKeyTestKey")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed) // any answer proves reachability
+	}))
+
+	t.Setenv("DOCTOR_TEST_ENDPOINT", server.URL+"/v1beta/models")
+	cfg := loadYAML(t, geminiDoctorYAML)
+	assert.Empty(t, cfg.Doctor(context.Background(), config.DoctorOptions{Ping: true}))
+
+	server.Close()
+	issues := cfg.Doctor(context.Background(), config.DoctorOptions{Ping: true})
+	require.Len(t, issues, 1)
+	assert.Equal(t, config.IssueUnreachable, issues[0].Kind)
+}
+
+const vertexDoctorYAML = `
+server:
+  port: 18099
+  read_timeout: 30s
+  write_timeout: 60s
+store:
+  type: memory
+  ttl: 1h
+providers:
+  vertex:
+    model: "gemini-2.0-flash"
+    project: "acme-prod"
+    region: "europe-west4"
+preemptive:
+  enabled: true
+  trigger_threshold: 85.0
+  summarizer:
+    provider: vertex
+    max_tokens: 4096
+    timeout: 60s
+  session:
+    summary_ttl: 3h
+    hash_message_count: 3
+`
+
+// TestDoctor_VertexAccessToken verifies Vertex is satisfied by the OAuth access
+// token in GOOGLE_OAUTH_ACCESS_TOKEN and, without it, asks for that variable
+// rather than an API key.
+func TestDoctor_VertexAccessToken(t *testing.T) {
+	t.Setenv(config.VertexTokenEnv, "")
+	issues := loadYAML(t, vertexDoctorYAML).Doctor(context.Background(), config.DoctorOptions{})
+	require.Len(t, issues, 1)
+	assert.Equal(t, config.IssueMissingKey, issues[0].Kind)
+	assert.Contains(t, issues[0].Message, config.VertexTokenEnv)
+
+	t.Setenv(config.VertexTokenEnv, "ya29.test-access-token")
+	assert.Empty(t, loadYAML(t, vertexDoctorYAML).Doctor(context.Background(), config.DoctorOptions{}))
+}
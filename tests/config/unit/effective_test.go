@@ -0,0 +1,75 @@
+package unit
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+const effectiveYAML = `
+server:
+  port: 18099
+  read_timeout: 30s
+  write_timeout: 60s
+  default_upstream: https://api.anthropic.com
+providers:
+  anthropic:
+    api_key: "${EFFECTIVE_TEST_KEY}"
+    model: claude-haiku-4-5
+pipes:
+  tool_output:
+    enabled: true
+    strategy: simple
+    enable_expand_context: true
+store:
+  type: memory
+  ttl: 1h
+preemptive:
+  enabled: true
+  trigger_threshold: 70
+  summarizer:
+    provider: anthropic
+    max_tokens: 1024
+    timeout: 30s
+  session:
+    summary_ttl: 1h
+    hash_message_count: 3
+`
+
+func TestEffectiveSummary_LogsResolvedValuesWithMaskedKeys(t *testing.T) {
+	const secret = "sk-ant-REDACTED"
+	t.Setenv("EFFECTIVE_TEST_KEY", secret)
+
+	cfg, err := config.LoadFromBytes([]byte(effectiveYAML))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	logger := zerolog.New(&buf)
+	logger.Info().EmbedObject(cfg.EffectiveSummary()).Msg("effective configuration")
+
+	assert.NotContains(t, buf.String(), secret)
+
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, float64(18099), entry["port"])
+	assert.Equal(t, "https://api.anthropic.com", entry["upstream"])
+	assert.Equal(t, "simple", entry["tool_output_strategy"])
+	assert.Equal(t, float64(config.DefaultMinTokens), entry["min_tokens"], "defaults are resolved")
+	assert.Equal(t, float64(config.DefaultMaxTokens), entry["max_tokens"])
+	assert.Equal(t, config.DefaultTargetCompressionRatio, entry["target_compression_ratio"])
+	assert.Equal(t, true, entry["expand_context"])
+	assert.Equal(t, "memory", entry["store_type"])
+	assert.Equal(t, true, entry["preemptive_enabled"])
+	assert.Equal(t, float64(70), entry["trigger_threshold"])
+	assert.Equal(t, "claude-haiku-4-5", entry["summarizer_model"])
+
+	keys, ok := entry["api_keys"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "sk-ant-a...6789", keys["anthropic"], "env-expanded key is masked")
+}
@@ -1,6 +1,7 @@
 package unit
 
 import (
+	"net/http"
 	"testing"
 	"time"
 
@@ -84,6 +85,16 @@ func TestProviderConfig_GetEndpoint(t *testing.T) {
 			providerName: "gemini",
 			want:         "https://generativelanguage.googleapis.com/v1beta/models/gemini-flash-2.0:generateContent",
 		},
+		{
+			name: "vertex builds regional project endpoint",
+			cfg: config.ProviderConfig{
+				Model:   "gemini-2.0-flash",
+				Project: "my-project",
+				Region:  "europe-west4",
+			},
+			providerName: "vertex",
+			want:         "https://europe-west4-aiplatform.googleapis.com/v1/projects/my-project/locations/europe-west4/publishers/google/models/gemini-2.0-flash:generateContent",
+		},
 	}
 
 	for _, tt := range tests {
@@ -130,6 +141,27 @@ func TestProvidersConfig_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "vertex region outside [a-z0-9-]",
+			cfg: config.ProvidersConfig{
+				"vertex": {
+					Model:   "gemini-2.0-flash",
+					Project: "my-project",
+					Region:  "evil.example.com/x",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "vertex without project or region",
+			cfg: config.ProvidersConfig{
+				"vertex": {
+					Model:  "gemini-2.0-flash",
+					Region: "us-central1",
+				},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -307,3 +339,55 @@ func TestConfig_ResolveProvider(t *testing.T) {
 		}
 	})
 }
+
+func TestServerConfig_EffectiveUpstreamTimeouts(t *testing.T) {
+	tests := []struct {
+		name   string
+		server config.ServerConfig
+		want   config.TimeoutsConfig
+	}{
+		{
+			name:   "legacy write_timeout drives overall and first byte",
+			server: config.ServerConfig{WriteTimeout: 5 * time.Minute},
+			want:   config.TimeoutsConfig{FirstByte: 5 * time.Minute, Overall: 5 * time.Minute},
+		},
+		{
+			name:   "zero write_timeout keeps upstream unbounded",
+			server: config.ServerConfig{},
+			want:   config.TimeoutsConfig{},
+		},
+		{
+			name: "explicit values win",
+			server: config.ServerConfig{
+				WriteTimeout:     5 * time.Minute,
+				UpstreamTimeouts: config.TimeoutsConfig{Connect: 2 * time.Second, FirstByte: time.Minute},
+			},
+			want: config.TimeoutsConfig{Connect: 2 * time.Second, FirstByte: time.Minute, Overall: 5 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.server.EffectiveUpstreamTimeouts(); got != tt.want {
+				t.Errorf("EffectiveUpstreamTimeouts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeoutsConfig_ApplyToDefaults(t *testing.T) {
+	tr := &http.Transport{}
+	config.TimeoutsConfig{}.ApplyTo(tr)
+	if tr.TLSHandshakeTimeout != pipes.DefaultTLSHandshakeTimeout {
+		t.Errorf("unset connect: TLSHandshakeTimeout = %v, want %v", tr.TLSHandshakeTimeout, pipes.DefaultTLSHandshakeTimeout)
+	}
+
+	if got, want := (config.TimeoutsConfig{}).ConnectLimit(), pipes.DefaultDialTimeout+pipes.DefaultTLSHandshakeTimeout; got != want {
+		t.Errorf("unset connect: ConnectLimit() = %v, want %v", got, want)
+	}
+
+	config.TimeoutsConfig{Connect: 2 * time.Second}.ApplyTo(tr)
+	if tr.TLSHandshakeTimeout != 2*time.Second {
+		t.Errorf("connect 2s: TLSHandshakeTimeout = %v, want 2s", tr.TLSHandshakeTimeout)
+	}
+}
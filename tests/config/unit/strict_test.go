@@ -0,0 +1,51 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// TestLoadFromBytesStrict_RejectsMisspelledKey verifies a typo is ignored by the
+// default loader but fails strict loading with the key and its line.
+func TestLoadFromBytesStrict_RejectsMisspelledKey(t *testing.T) {
+	typo := strings.Replace(diffBaseYAML, "trigger_threshold: 85.0", "trigger_treshold: 85.0", 1)
+
+	cfg, err := config.LoadFromBytes([]byte(typo))
+	require.NoError(t, err, "lenient loading stays the default")
+	assert.Zero(t, cfg.Preemptive.TriggerThreshold)
+
+	_, err = config.LoadFromBytesStrict([]byte(typo))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "field trigger_treshold not found")
+	assert.Contains(t, err.Error(), "line 11:")
+}
+
+func TestLoadFromBytesStrict_AcceptsValidConfig(t *testing.T) {
+	cfg, err := config.LoadFromBytesStrict([]byte("metadata:\n  name: test\n" + diffBaseYAML))
+	require.NoError(t, err)
+	assert.Equal(t, 85.0, cfg.Preemptive.TriggerThreshold)
+	assert.Equal(t, "test", cfg.Metadata.Name)
+}
+
+// TestLoadFromBytesStrict_BundledConfigs keeps the shipped configs and the
+// quickstart example free of unknown keys, so --strict works on them out of
+// the box.
+func TestLoadFromBytesStrict_BundledConfigs(t *testing.T) {
+	paths, err := filepath.Glob("../../../cmd/configs/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	paths = append(paths, "../../../docs/quickstart/config.yaml")
+	for _, path := range paths {
+		data, err := os.ReadFile(path) // #nosec G304 -- test fixture path
+		require.NoError(t, err)
+		_, err = config.LoadFromBytesStrict(data)
+		assert.NoError(t, err, path)
+	}
+}
@@ -29,6 +29,7 @@ func TestDetectProvider(t *testing.T) {
 		{"anthropic api", "https://api.anthropic.com/v1/messages", "anthropic"},
 		{"anthropic in path", "https://proxy.example.com/anthropic/v1", "anthropic"},
 		{"gemini api", "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent", "gemini"},
+		{"vertex ai", "https://us-central1-aiplatform.googleapis.com/v1/projects/p/locations/us-central1/publishers/google/models/gemini-2.0-flash:generateContent", "vertex"},
 		{"openai api", "https://api.openai.com/v1/chat/completions", "openai"},
 		{"localhost default", "http://localhost:8080/v1/chat/completions", "openai"},
 		{"custom proxy", "https://my-proxy.com/v1/chat", "openai"},
@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkedReader hands out at most n bytes per Read so the client writes the
+// body as many transfer-encoding chunks.
+type chunkedReader struct {
+	r io.Reader
+	n int
+}
+
+func (c chunkedReader) Read(p []byte) (int, error) {
+	if len(p) > c.n {
+		p = p[:c.n]
+	}
+	return c.r.Read(p)
+}
+
+// postMessages sends body to the gateway, chunked (no Content-Length) or
+// single-shot, and returns the response status.
+func postMessages(t *testing.T, gwURL, targetURL string, body []byte, chunked bool) int {
+	t.Helper()
+	var reader io.Reader = bytes.NewReader(body)
+	if chunked {
+		reader = chunkedReader{r: bytes.NewReader(body), n: 4096}
+	}
+	req, err := http.NewRequest(http.MethodPost, gwURL+"/v1/messages", reader)
+	require.NoError(t, err)
+	if chunked {
+		require.EqualValues(t, 0, req.ContentLength, "unknown length forces chunked encoding")
+		req.TransferEncoding = []string{"chunked"}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", targetURL+"/v1/messages")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode
+}
+
+func largeToolRequest() map[string]interface{} {
+	return map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 500,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Summarize the log"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_chunked_1", "name": "bash", "input": map[string]string{"command": "cat app.log"}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_chunked_1", "content": largeToolOutput(8000)},
+			}},
+		},
+	}
+}
+
+// TestIntegration_ChunkedRequest_MatchesSingleShot verifies that a large body
+// sent with chunked transfer encoding is compressed and forwarded exactly like
+// the same body sent with Content-Length.
+func TestIntegration_ChunkedRequest_MatchesSingleShot(t *testing.T) {
+	mock := newMockLLM(func([]byte, int) []byte { return anthropicTextResponse("done") })
+	defer mock.close()
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	body, err := json.Marshal(largeToolRequest())
+	require.NoError(t, err)
+	require.Greater(t, len(body), 2*4096, "body spans several chunks")
+
+	require.Equal(t, http.StatusOK, postMessages(t, gwServer.URL, mock.url(), body, false))
+	require.Equal(t, http.StatusOK, postMessages(t, gwServer.URL, mock.url(), body, true))
+
+	reqs := mock.getRequests()
+	require.Len(t, reqs, 2)
+	assert.Less(t, len(reqs[0].Body), len(body), "single-shot request was compressed")
+	assert.Equal(t, string(reqs[0].Body), string(reqs[1].Body))
+}
+
+// TestIntegration_ChunkedRequest_TooLarge verifies server.max_request_bytes
+// rejects an oversized body with 413 whether or not its length was declared.
+func TestIntegration_ChunkedRequest_TooLarge(t *testing.T) {
+	mock := newMockLLM(func([]byte, int) []byte { return anthropicTextResponse("done") })
+	defer mock.close()
+	cfg := expandContextConfig()
+	cfg.Server.MaxRequestBytes = 4096
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	body, err := json.Marshal(largeToolRequest())
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postMessages(t, gwServer.URL, mock.url(), body, false))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, postMessages(t, gwServer.URL, mock.url(), body, true))
+	assert.Empty(t, mock.getRequests(), "oversized requests never reach upstream")
+}
@@ -0,0 +1,111 @@
+package integration
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sendPreflight(t *testing.T, gwURL, origin string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodOptions, gwURL+"/v1/messages", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	req.Header.Set("Access-Control-Request-Headers", "content-type, x-api-key, anthropic-version")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	return resp
+}
+
+// TestIntegration_CORS_PreflightConfiguredOrigin verifies that a preflight from an
+// origin in server.cors_allowed_origins is answered with that origin.
+func TestIntegration_CORS_PreflightConfiguredOrigin(t *testing.T) {
+	cfg := passthroughConfig()
+	cfg.Server.CORSAllowedOrigins = []string{"https://app.example.com"}
+	gw := createGateway(cfg)
+	defer gw.Close()
+
+	resp := sendPreflight(t, gw.URL, "https://app.example.com")
+
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), "POST")
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Headers"), "anthropic-version")
+}
+
+// TestIntegration_CORS_PreflightUnlistedOrigin verifies that other origins get no CORS headers.
+func TestIntegration_CORS_PreflightUnlistedOrigin(t *testing.T) {
+	cfg := passthroughConfig()
+	cfg.Server.CORSAllowedOrigins = []string{"https://app.example.com"}
+	gw := createGateway(cfg)
+	defer gw.Close()
+
+	for _, origin := range []string{"https://evil.example.com", "https://app.example.com.evil.com", "http://app.example.com"} {
+		resp := sendPreflight(t, gw.URL, origin)
+		assert.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"), "origin %s must not be allowed", origin)
+	}
+}
+
+// TestIntegration_CORS_WildcardOrigin verifies that "*" allows any origin.
+func TestIntegration_CORS_WildcardOrigin(t *testing.T) {
+	cfg := passthroughConfig()
+	cfg.Server.CORSAllowedOrigins = []string{"*"}
+	gw := createGateway(cfg)
+	defer gw.Close()
+
+	resp := sendPreflight(t, gw.URL, "https://anything.example.org")
+	assert.Equal(t, "https://anything.example.org", resp.Header.Get("Access-Control-Allow-Origin"))
+}
+
+// TestIntegration_CORS_StreamingStripsUpstreamHeaders verifies that a streamed
+// response keeps the gateway's CORS headers while hop-by-hop and upstream CORS
+// headers are dropped.
+func TestIntegration_CORS_StreamingStripsUpstreamHeaders(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Connection", "keep-alive, X-Upstream-Hop")
+		w.Header().Set("Keep-Alive", "timeout=5")
+		w.Header().Set("X-Upstream-Hop", "1")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Request-Id", "req_upstream")
+		_, _ = io.WriteString(w, "event: message_start\n"+
+			`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-3-haiku-20240307","usage":{"input_tokens":5,"output_tokens":0}}}`+"\n\n"+
+			"event: message_stop\n"+
+			`data: {"type":"message_stop"}`+"\n\n")
+	}))
+	defer upstream.Close()
+
+	cfg := passthroughConfig()
+	cfg.Server.CORSAllowedOrigins = []string{"https://app.example.com"}
+	gw := createGateway(cfg)
+	defer gw.Close()
+
+	body := `{"model":"claude-3-haiku-20240307","max_tokens":50,"stream":true,"messages":[{"role":"user","content":"hi"}]}`
+	req, err := http.NewRequest(http.MethodPost, gw.URL+"/v1/messages", strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", upstream.URL+"/v1/messages")
+	req.Header.Set("Origin", "https://app.example.com")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(data), "message_stop")
+	assert.Equal(t, "https://app.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "req_upstream", resp.Header.Get("Request-Id"))
+	assert.Empty(t, resp.Header.Get("Keep-Alive"))
+	assert.Empty(t, resp.Header.Get("X-Upstream-Hop"))
+}
@@ -0,0 +1,84 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+func postCountTokens(t *testing.T, gwURL string, body map[string]interface{}) (*http.Response, gateway.CountTokensResponse) {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, gwURL+"/count-tokens", bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var out gateway.CountTokensResponse
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	}
+	return resp, out
+}
+
+// TestIntegration_CountTokens_LargeToolOutput verifies the preview reports fewer
+// tokens after compression and makes no upstream call.
+func TestIntegration_CountTokens_LargeToolOutput(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("unused")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	resp, out := postCountTokens(t, gwServer.URL, compressibleRequest())
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.Equal(t, "anthropic", out.Provider)
+	assert.Equal(t, "claude-sonnet-4-20250514", out.Model)
+	assert.True(t, out.Compressed)
+	assert.Equal(t, 1, out.CompressedBlocks)
+	assert.Less(t, out.CompressedTokens, out.InputTokens)
+	assert.Equal(t, out.InputTokens-out.CompressedTokens, out.TokensSaved)
+	assert.Greater(t, out.CompressionRatio, 0.0)
+	assert.Empty(t, mock.getRequests(), "preview must not call upstream")
+}
+
+// TestIntegration_CountTokens_Passthrough verifies an uncompressed request reports
+// no savings beyond phantom tool overhead.
+func TestIntegration_CountTokens_Passthrough(t *testing.T) {
+	gwServer := createGateway(passthroughConfig())
+	defer gwServer.Close()
+
+	resp, out := postCountTokens(t, gwServer.URL, simpleRequest("Hello"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	assert.False(t, out.Compressed)
+	assert.Zero(t, out.CompressedBlocks)
+	assert.Greater(t, out.InputTokens, 0)
+	assert.GreaterOrEqual(t, out.CompressedTokens, out.InputTokens, "injected tools only add tokens")
+}
+
+// TestIntegration_CountTokens_MethodNotAllowed verifies only POST is served.
+func TestIntegration_CountTokens_MethodNotAllowed(t *testing.T) {
+	gwServer := createGateway(passthroughConfig())
+	defer gwServer.Close()
+
+	resp, err := http.Get(gwServer.URL + "/count-tokens")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
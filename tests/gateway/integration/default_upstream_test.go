@@ -0,0 +1,64 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postWithoutTarget sends body to the gateway with no X-Target-URL header.
+func postWithoutTarget(t *testing.T, gwURL, path string, body []byte) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, gwURL+path, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	require.NoError(t, err)
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	return resp.StatusCode, string(respBody)
+}
+
+// TestIntegration_DefaultUpstream_RoutesWithoutTargetHeader verifies a request
+// without X-Target-URL goes to server.default_upstream.
+func TestIntegration_DefaultUpstream_RoutesWithoutTargetHeader(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("from default upstream")
+	})
+	defer mock.close()
+
+	cfg := passthroughConfig()
+	cfg.Server.DefaultUpstream = mock.url()
+	gw := createGateway(cfg)
+	defer gw.Close()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages":   []map[string]interface{}{{"role": "user", "content": "hello"}},
+	})
+	require.NoError(t, err)
+
+	status, respBody := postWithoutTarget(t, gw.URL, "/v1/messages", body)
+	require.Equal(t, http.StatusOK, status, respBody)
+	assert.Contains(t, respBody, "from default upstream")
+	assert.Len(t, mock.getRequests(), 1)
+}
+
+// TestIntegration_DefaultUpstream_UnknownRejected verifies a request whose
+// upstream can be neither configured nor inferred fails with a clear 400.
+func TestIntegration_DefaultUpstream_UnknownRejected(t *testing.T) {
+	gw := createGateway(passthroughConfig())
+	defer gw.Close()
+
+	status, respBody := postWithoutTarget(t, gw.URL, "/v1/unknown-endpoint", []byte(`{"model":"m"}`))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, respBody, "X-Target-URL")
+	assert.Contains(t, respBody, "server.default_upstream")
+}
@@ -0,0 +1,67 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func duplicateToolResultRequest() map[string]interface{} {
+	return map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Read both files"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_a", "name": "read_file", "input": map[string]string{"path": "a.go"}},
+				{"type": "tool_use", "id": "toolu_b", "name": "read_file", "input": map[string]string{"path": "b.go"}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_a", "content": "stale a.go"},
+				{"type": "tool_result", "tool_use_id": "toolu_b", "content": "package b"},
+				{"type": "tool_result", "tool_use_id": "toolu_a", "content": "package a"},
+				{"type": "text", "text": "Summarize them"},
+			}},
+		},
+	}
+}
+
+// TestIntegration_DedupeToolUseIDs verifies a tool_use_id answered twice reaches
+// the upstream once, with its last result, when dedupe_tool_use_ids is set, and
+// unchanged otherwise.
+func TestIntegration_DedupeToolUseIDs(t *testing.T) {
+	for _, dedupe := range []bool{false, true} {
+		t.Run(map[bool]string{false: "off", true: "on"}[dedupe], func(t *testing.T) {
+			mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+				return anthropicTextResponse("Both are trivial.")
+			})
+			defer mock.close()
+
+			cfg := passthroughConfig()
+			cfg.Pipes.ToolOutput.Enabled = true
+			cfg.Pipes.ToolOutput.DedupeToolUseIDs = dedupe
+			gwServer := createGateway(cfg)
+			defer gwServer.Close()
+
+			resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), duplicateToolResultRequest())
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			requests := mock.getRequests()
+			require.Len(t, requests, 1)
+			blocks := gjson.GetBytes(requests[0].Body, "messages.2.content").Array()
+			if !dedupe {
+				assert.Len(t, blocks, 4)
+				return
+			}
+			require.Len(t, blocks, 3)
+			assert.Equal(t, "toolu_b", blocks[0].Get("tool_use_id").String())
+			assert.Equal(t, "toolu_a", blocks[1].Get("tool_use_id").String())
+			assert.Equal(t, "package a", blocks[1].Get("content").String(), "the last result is kept")
+			assert.Equal(t, "text", blocks[2].Get("type").String())
+		})
+	}
+}
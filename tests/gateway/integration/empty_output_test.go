@@ -0,0 +1,54 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+func emptyGrepRequest() map[string]interface{} {
+	return map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Find uses of deprecatedHelper"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_grep", "name": "grep", "input": map[string]string{"pattern": "deprecatedHelper"}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_grep", "content": ""},
+			}},
+		},
+	}
+}
+
+// TestIntegration_EmptyOutputPlaceholder verifies an empty grep result reaches
+// the upstream as the placeholder when configured, and unchanged otherwise.
+func TestIntegration_EmptyOutputPlaceholder(t *testing.T) {
+	for _, placeholder := range []string{"", "(no results)"} {
+		t.Run("placeholder="+placeholder, func(t *testing.T) {
+			mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+				return anthropicTextResponse("No uses found.")
+			})
+			defer mock.close()
+
+			cfg := passthroughConfig()
+			cfg.Pipes.ToolOutput.Enabled = true
+			cfg.Pipes.ToolOutput.EmptyOutputPlaceholder = placeholder
+			gwServer := createGateway(cfg)
+			defer gwServer.Close()
+
+			resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), emptyGrepRequest())
+			require.NoError(t, err)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			requests := mock.getRequests()
+			require.Len(t, requests, 1)
+			content := gjson.GetBytes(requests[0].Body, "messages.2.content.0.content")
+			assert.Equal(t, placeholder, content.String())
+		})
+	}
+}
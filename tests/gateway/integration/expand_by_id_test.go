@@ -0,0 +1,129 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+var shadowIDPattern = regexp.MustCompile(`shadow_[0-9a-f]+`)
+
+// getExpand calls GET /expand/{id} with the given session header.
+func getExpand(t *testing.T, gwURL, id, sessionID string) (int, map[string]string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, gwURL+"/expand/"+id, nil)
+	require.NoError(t, err)
+	if sessionID != "" {
+		req.Header.Set(gateway.HeaderSessionID, sessionID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var body map[string]string
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	}
+	return resp.StatusCode, body
+}
+
+// TestIntegration_ExpandByID returns a stored original to the session that
+// produced it, and 404 for other sessions and unknown IDs.
+func TestIntegration_ExpandByID(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Analysis complete.")
+	})
+	defer mock.close()
+
+	cfg := expandContextConfig()
+	cfg.Server.DebugEndpoints = true
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), compressibleRequest())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get(gateway.HeaderSessionID)
+	require.NotEmpty(t, sessionID)
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1)
+	shadowID := shadowIDPattern.FindString(string(requests[0].Body))
+	require.NotEmpty(t, shadowID, "forwarded request should reference a shadow")
+
+	status, body := getExpand(t, gwServer.URL, shadowID, sessionID)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, shadowID, body["id"])
+	assert.Equal(t, largeToolOutput(2000), body["content"])
+
+	status, _ = getExpand(t, gwServer.URL, shadowID, "")
+	assert.Equal(t, http.StatusNotFound, status, "no session header")
+	status, _ = getExpand(t, gwServer.URL, shadowID, "other-session")
+	assert.Equal(t, http.StatusNotFound, status, "another session's shadow")
+	status, _ = getExpand(t, gwServer.URL, "shadow_0000000000000000", sessionID)
+	assert.Equal(t, http.StatusNotFound, status, "unknown ID")
+}
+
+// TestIntegration_ExpandByID_RequiresDebugEndpoints keeps the endpoint hidden by default.
+func TestIntegration_ExpandByID_RequiresDebugEndpoints(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Analysis complete.")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), compressibleRequest())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(gateway.HeaderSessionID))
+
+	shadowID := shadowIDPattern.FindString(string(mock.getRequests()[0].Body))
+	require.NotEmpty(t, shadowID)
+	status, _ := getExpand(t, gwServer.URL, shadowID, "any")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+// TestIntegration_DebugStoreClear verifies DELETE /debug/store flushes the
+// store: a shadow that expanded before the clear returns 404 afterwards.
+func TestIntegration_DebugStoreClear(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Analysis complete.")
+	})
+	defer mock.close()
+
+	cfg := expandContextConfig()
+	cfg.Server.DebugEndpoints = true
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), compressibleRequest())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	sessionID := resp.Header.Get(gateway.HeaderSessionID)
+	shadowID := shadowIDPattern.FindString(string(mock.getRequests()[0].Body))
+	require.NotEmpty(t, shadowID)
+
+	status, _ := getExpand(t, gwServer.URL, shadowID, sessionID)
+	require.Equal(t, http.StatusOK, status)
+
+	req, err := http.NewRequest(http.MethodDelete, gwServer.URL+"/debug/store", nil)
+	require.NoError(t, err)
+	clearResp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer clearResp.Body.Close()
+	require.Equal(t, http.StatusOK, clearResp.StatusCode)
+	var cleared gateway.DebugStoreClearResponse
+	require.NoError(t, json.NewDecoder(clearResp.Body).Decode(&cleared))
+	assert.Positive(t, cleared.Cleared)
+
+	status, _ = getExpand(t, gwServer.URL, shadowID, sessionID)
+	assert.Equal(t, http.StatusNotFound, status, "shadow expands no more after clear")
+}
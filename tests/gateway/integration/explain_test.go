@@ -0,0 +1,94 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+func postWithExplain(t *testing.T, gwURL, targetURL string, body map[string]interface{}, explain bool) *http.Response {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, gwURL+"/v1/messages", bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", targetURL+"/v1/messages")
+	if explain {
+		req.Header.Set(gateway.HeaderExplain, "true")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	return resp
+}
+
+// TestIntegration_Explain_ListsCompressedBlocks verifies X-CG-Explain returns a
+// per-block record with accurate sizes, is not forwarded, and leaves the
+// upstream request unchanged.
+func TestIntegration_Explain_ListsCompressedBlocks(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("ok")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	reqBody := compressibleRequest()
+	messages := reqBody["messages"].([]map[string]interface{})
+	messages = append(messages,
+		map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+			{"type": "tool_use", "id": "toolu_small_001", "name": "pwd", "input": map[string]string{}},
+		}},
+		map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+			{"type": "tool_result", "tool_use_id": "toolu_small_001", "content": "/home/user"},
+		}},
+	)
+	reqBody["messages"] = messages
+	original := messages[2]["content"].([]map[string]interface{})[0]["content"].(string)
+
+	resp := postWithExplain(t, gwServer.URL, mock.url(), reqBody, true)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	raw := resp.Header.Get(gateway.HeaderExplainBlocks)
+	require.NotEmpty(t, raw)
+	var blocks []gateway.ExplainBlock
+	require.NoError(t, json.Unmarshal([]byte(raw), &blocks))
+	require.Len(t, blocks, 1, "only the rewritten block is listed")
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1)
+	sent := gjson.GetBytes(requests[0].Body, "messages.2.content.0.content").String()
+
+	b := blocks[0]
+	assert.Equal(t, "toolu_hdr_001", b.ToolCallID)
+	assert.Equal(t, "read_file", b.ToolName)
+	assert.Equal(t, "compressed", b.Status)
+	assert.NotEmpty(t, b.ShadowID)
+	assert.Contains(t, sent, b.ShadowID)
+	assert.Equal(t, len(original), b.OriginalBytes)
+	assert.Equal(t, len(sent), b.CompressedBytes)
+	assert.Less(t, b.CompressedTokens, b.OriginalTokens)
+	assert.Empty(t, requests[0].Headers.Get(gateway.HeaderExplain), "explain header must not be forwarded")
+
+	// The same request without the header sends an identical body upstream.
+	resp = postWithExplain(t, gwServer.URL, mock.url(), reqBody, false)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(gateway.HeaderExplainBlocks))
+	requests = mock.getRequests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, string(requests[0].Body), string(requests[1].Body))
+}
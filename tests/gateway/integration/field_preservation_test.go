@@ -0,0 +1,132 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// postRaw sends body to the gateway exactly as given, so number formatting and
+// escapes survive into the comparison.
+func postRaw(t *testing.T, gwURL, path, targetURL string, body []byte, headers map[string]string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, gwURL+path, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Target-URL", targetURL+path)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	require.NoError(t, err)
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(respBody))
+}
+
+// assertSiblingsPreserved checks every top-level field of sent other than
+// messages and tools reaches the upstream byte-identical, and that messages
+// were actually rewritten.
+func assertSiblingsPreserved(t *testing.T, sent, forwarded []byte) {
+	t.Helper()
+	keys := 0
+	gjson.ParseBytes(sent).ForEach(func(key, value gjson.Result) bool {
+		name := key.String()
+		if name == "messages" || name == "tools" {
+			return true
+		}
+		keys++
+		got := gjson.GetBytes(forwarded, name)
+		if assert.True(t, got.Exists(), "field %q dropped", name) {
+			assert.Equal(t, value.Raw, got.Raw, "field %q altered", name)
+		}
+		return true
+	})
+	gjson.ParseBytes(forwarded).ForEach(func(key, _ gjson.Result) bool {
+		if name := key.String(); name != "tools" {
+			assert.True(t, gjson.GetBytes(sent, name).Exists(), "field %q added", name)
+		}
+		return true
+	})
+	assert.Greater(t, keys, 5)
+	assert.NotEqual(t, gjson.GetBytes(sent, "messages").Raw, gjson.GetBytes(forwarded, "messages").Raw,
+		"messages should have been compressed")
+	assert.Contains(t, gjson.GetBytes(forwarded, "messages").Raw, "[REF:")
+}
+
+// TestIntegration_FieldPreservation_Anthropic verifies sampling params, stop
+// sequences and unknown fields pass through a compressing request untouched.
+func TestIntegration_FieldPreservation_Anthropic(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("ok")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	messages, err := json.Marshal(compressibleRequest()["messages"])
+	require.NoError(t, err)
+	body := []byte(`{"model":"claude-sonnet-4-20250514","max_tokens":1024,` +
+		`"temperature":0.70,"top_p":1e0,"top_k":40,` +
+		`"stop_sequences":["\n\nHuman:","END","café"],` +
+		`"metadata":{"user_id":"u-123"},"system":[{"type":"text","text":"Be terse."}],` +
+		`"x_unknown_field":{"nested":[1, 2.50, null]},` +
+		`"messages":` + string(messages) + `}`)
+
+	postRaw(t, gwServer.URL, "/v1/messages", mock.url(), body, map[string]string{
+		"x-api-key":         "sk-ant-test-key",
+		"anthropic-version": "2023-06-01",
+	})
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1)
+	assertSiblingsPreserved(t, body, requests[0].Body)
+}
+
+// TestIntegration_FieldPreservation_OpenAI covers the OpenAI Chat Completions
+// generation parameters.
+func TestIntegration_FieldPreservation_OpenAI(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return openAITextResponse("ok")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	messages, err := json.Marshal([]map[string]interface{}{
+		{"role": "user", "content": "What are the key points from the log?"},
+		{
+			"role": "assistant",
+			"tool_calls": []map[string]interface{}{
+				{"id": "call_fp_001", "type": "function", "function": map[string]interface{}{
+					"name": "read_file", "arguments": `{"path":"system.log"}`,
+				}},
+			},
+		},
+		{"role": "tool", "tool_call_id": "call_fp_001", "content": largeToolOutput(2000)},
+	})
+	require.NoError(t, err)
+	body := []byte(`{"model":"gpt-4o","max_completion_tokens":2048,"max_tokens":2048,` +
+		`"temperature":0.2,"top_p":0.95,"stop":["###","\u0000END"],` +
+		`"seed":42,"n":1,"presence_penalty":0.0,"frequency_penalty":-0.5,` +
+		`"logit_bias":{"50256":-100},"response_format":{"type":"json_object"},` +
+		`"user":"u-123","x_unknown_field":true,` +
+		`"messages":` + string(messages) + `}`)
+
+	postRaw(t, gwServer.URL, "/v1/chat/completions", mock.url(), body, map[string]string{
+		"Authorization": "Bearer sk-test-key",
+	})
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1)
+	assertSiblingsPreserved(t, body, requests[0].Body)
+}
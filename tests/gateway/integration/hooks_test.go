@@ -0,0 +1,139 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// preambleHook prepends a fixed preamble to the Anthropic system prompt.
+type preambleHook struct{ preamble string }
+
+func (h preambleHook) Name() string { return "preamble" }
+
+func (h preambleHook) OnRequest(req *gateway.HookRequest) error {
+	system, _ := req.Body["system"].(string)
+	req.Body["system"] = h.preamble + system
+	return nil
+}
+
+// panicHook panics after a partial edit, which must not leak into the request.
+type panicHook struct{}
+
+func (panicHook) Name() string { return "panics" }
+
+func (panicHook) OnRequest(req *gateway.HookRequest) error {
+	req.Body["max_tokens"] = 1
+	panic("boom")
+}
+
+// footerHook appends a footer to the first text block of a response.
+type footerHook struct{}
+
+func (footerHook) Name() string { return "footer" }
+
+func (footerHook) OnResponse(resp *gateway.HookResponse) error {
+	content, _ := resp.Body["content"].([]any)
+	if len(content) == 0 {
+		return fmt.Errorf("no content")
+	}
+	block, _ := content[0].(map[string]any)
+	block["text"] = fmt.Sprintf("%v [status %d]", block["text"], resp.StatusCode)
+	return nil
+}
+
+// TestIntegration_Hooks verifies registered hooks run in order on the forwarded
+// request and the returned response, and that a panicking hook is skipped
+// without failing the request.
+func TestIntegration_Hooks(t *testing.T) {
+	t.Cleanup(gateway.ClearHooks)
+	gateway.RegisterRequestHook(preambleHook{preamble: "Always answer in English. "})
+	gateway.RegisterRequestHook(panicHook{})
+	gateway.RegisterRequestHook(preambleHook{preamble: "[org policy] "})
+	gateway.RegisterResponseHook(footerHook{})
+
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Hello")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(passthroughConfig())
+	defer gwServer.Close()
+	// Hooks are snapshotted by New; later registrations do not reach this gateway.
+	gateway.RegisterRequestHook(preambleHook{preamble: "late "})
+
+	resp, respBody, err := sendAnthropicRequest(gwServer.URL, mock.url(), map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"system":     "You are terse.",
+		"messages":   []map[string]interface{}{{"role": "user", "content": "Hi"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1)
+	forwarded := requests[0].Body
+	assert.Equal(t, "[org policy] Always answer in English. You are terse.", gjson.GetBytes(forwarded, "system").String())
+	assert.Equal(t, int64(100), gjson.GetBytes(forwarded, "max_tokens").Int(), "panicking hook's edit must be discarded")
+	assert.Equal(t, "Hi", gjson.GetBytes(forwarded, "messages.0.content").String())
+
+	assert.Equal(t, "Hello [status 200]", gjson.GetBytes(respBody, "content.0.text").String())
+}
+
+// noopHook inspects the request without changing it.
+type noopHook struct{ seen *int }
+
+func (h noopHook) Name() string { return "noop" }
+
+func (h noopHook) OnRequest(req *gateway.HookRequest) error {
+	*h.seen++
+	return nil
+}
+
+// TestIntegration_Hooks_ByteStable verifies a hook that changes nothing
+// forwards the exact bytes a gateway without hooks would, and that a changed
+// body is encoded without HTML escaping.
+func TestIntegration_Hooks_ByteStable(t *testing.T) {
+	t.Cleanup(gateway.ClearHooks)
+	request := map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"system":     "Compare a < b && c > d.",
+		// Raw so the keys keep their unsorted order.
+		"messages": json.RawMessage(`[{"role":"user","content":"Is <b> & <i> HTML?"}]`),
+	}
+	forward := func() []byte {
+		mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+			return anthropicTextResponse("ok")
+		})
+		defer mock.close()
+		gwServer := createGateway(passthroughConfig())
+		defer gwServer.Close()
+		resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), request)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		requests := mock.getRequests()
+		require.Len(t, requests, 1)
+		return requests[0].Body
+	}
+
+	baseline := forward()
+	var seen int
+	gateway.RegisterRequestHook(noopHook{seen: &seen})
+	assert.Equal(t, string(baseline), string(forward()), "unchanged body is forwarded byte for byte")
+	assert.Equal(t, 1, seen)
+	assert.Contains(t, string(baseline), `{"role":"user","content":`)
+
+	gateway.RegisterRequestHook(preambleHook{preamble: "Policy: "})
+	forwarded := forward()
+	assert.Contains(t, string(forwarded), `"system":"Policy: Compare a < b && c > d."`)
+	assert.NotContains(t, string(forwarded), `\u003c`)
+}
@@ -0,0 +1,102 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// countUpstream mimics Anthropic's count_tokens endpoint, answering ~4 bytes per token.
+type countUpstream struct {
+	mu     sync.Mutex
+	paths  []string
+	server *httptest.Server
+}
+
+func newCountUpstream() *countUpstream {
+	u := &countUpstream{}
+	u.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		u.mu.Lock()
+		u.paths = append(u.paths, r.URL.Path)
+		u.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = fmt.Fprintf(w, `{"input_tokens":%d}`, len(body)/4)
+	}))
+	return u
+}
+
+func (u *countUpstream) getPaths() []string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return append([]string(nil), u.paths...)
+}
+
+// postMessagesCountTokens calls /v1/messages/count_tokens. An empty targetURL
+// sends no target and no credentials, so the gateway must count locally.
+func postMessagesCountTokens(t *testing.T, cfg *config.Config, targetURL string) (int, string) {
+	t.Helper()
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	data, err := json.Marshal(compressibleRequest())
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, gwServer.URL+"/v1/messages/count_tokens", bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	if targetURL != "" {
+		req.Header.Set("x-api-key", "sk-ant-test-key")
+		req.Header.Set("X-Target-URL", targetURL)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var out struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	return out.InputTokens, resp.Header.Get(gateway.HeaderTokenCountSource)
+}
+
+// TestIntegration_MessagesCountTokens_Upstream verifies the compressed body is
+// forwarded to the upstream count endpoint and counts fewer tokens.
+func TestIntegration_MessagesCountTokens_Upstream(t *testing.T) {
+	upstream := newCountUpstream()
+	defer upstream.server.Close()
+
+	uncompressed, source := postMessagesCountTokens(t, passthroughConfig(), upstream.server.URL)
+	assert.Equal(t, "upstream", source)
+	compressed, source := postMessagesCountTokens(t, expandContextConfig(), upstream.server.URL)
+	assert.Equal(t, "upstream", source)
+
+	assert.Greater(t, uncompressed, 0)
+	assert.Less(t, compressed, uncompressed)
+	assert.Equal(t, []string{"/v1/messages/count_tokens", "/v1/messages/count_tokens"}, upstream.getPaths())
+}
+
+// TestIntegration_MessagesCountTokens_Local verifies the gateway counts locally
+// when there is no upstream to ask.
+func TestIntegration_MessagesCountTokens_Local(t *testing.T) {
+	uncompressed, source := postMessagesCountTokens(t, passthroughConfig(), "")
+	assert.Equal(t, "local", source)
+	compressed, source := postMessagesCountTokens(t, expandContextConfig(), "")
+	assert.Equal(t, "local", source)
+
+	assert.Greater(t, uncompressed, 0)
+	assert.Less(t, compressed, uncompressed)
+}
@@ -0,0 +1,41 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+// TestIntegration_ModelAliases_Rewritten verifies an aliased model reaches the
+// upstream as the concrete model, and other models pass through untouched.
+func TestIntegration_ModelAliases_Rewritten(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("ok")
+	})
+	defer mock.close()
+
+	cfg := passthroughConfig()
+	cfg.Server.ModelAliases = map[string]string{"fast": "claude-3-haiku-20240307"}
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	aliased := simpleRequest("Hello")
+	aliased["model"] = "fast"
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), aliased)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	concrete := simpleRequest("Hello again")
+	concrete["model"] = "claude-sonnet-4-20250514"
+	resp, _, err = sendAnthropicRequest(gwServer.URL, mock.url(), concrete)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "claude-3-haiku-20240307", gjson.GetBytes(requests[0].Body, "model").String())
+	assert.Equal(t, "claude-sonnet-4-20250514", gjson.GetBytes(requests[1].Body, "model").String())
+}
@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+)
+
+var shadowRefPattern = regexp.MustCompile(`\[REF:(shadow_[0-9a-f]+)\]`)
+
+// openAIExpandCallResponse is an assistant turn with null content that only
+// calls expand_context for the first shadow ref found in reqBody.
+func openAIExpandCallResponse(t *testing.T, reqBody []byte) []byte {
+	m := shadowRefPattern.FindSubmatch(reqBody)
+	require.NotNil(t, m, "tool output should have been compressed")
+	resp, err := json.Marshal(map[string]interface{}{
+		"id":     "chatcmpl-expand",
+		"object": "chat.completion",
+		"model":  "gpt-4o",
+		"choices": []interface{}{map[string]interface{}{
+			"index": 0,
+			"message": map[string]interface{}{
+				"role":    "assistant",
+				"content": nil,
+				"tool_calls": []interface{}{map[string]interface{}{
+					"id":   "call_expand_1",
+					"type": "function",
+					"function": map[string]interface{}{
+						"name":      "expand_context",
+						"arguments": `{"id":"` + string(m[1]) + `"}`,
+					},
+				}},
+			},
+			"finish_reason": "tool_calls",
+		}},
+		"usage": map[string]interface{}{"prompt_tokens": 100, "completion_tokens": 10, "total_tokens": 110},
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+// TestIntegration_NullAssistantContent_ExpandRoundTrip verifies that assistant
+// tool-call turns with null or omitted content survive compression and the
+// expand_context loop with their content shape unchanged.
+func TestIntegration_NullAssistantContent_ExpandRoundTrip(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		if callNum == 1 {
+			return openAIExpandCallResponse(t, reqBody)
+		}
+		return openAITextResponse("The auth service fails.")
+	})
+	defer mock.close()
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	readFile := func(id, path string) []map[string]interface{} {
+		return []map[string]interface{}{{
+			"id": id, "type": "function",
+			"function": map[string]interface{}{"name": "read_file", "arguments": `{"path":"` + path + `"}`},
+		}}
+	}
+	resp, body, err := sendOpenAIRequest(gwServer.URL, mock.url(), map[string]interface{}{
+		"model": "gpt-4o",
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Which service fails?"},
+			{"role": "assistant", "content": nil, "tool_calls": readFile("call_log_1", "a.log")},
+			{"role": "tool", "tool_call_id": "call_log_1", "content": largeToolOutput(2000)},
+			{"role": "assistant", "tool_calls": readFile("call_log_2", "b.log")},
+			{"role": "tool", "tool_call_id": "call_log_2", "content": "ok"},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Contains(t, string(body), "The auth service fails.")
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 2, "expand_context handled by the gateway")
+	for i, r := range requests {
+		messages := gjson.GetBytes(r.Body, "messages").Array()
+		require.GreaterOrEqual(t, len(messages), 5)
+		assert.Equal(t, gjson.Null, messages[1].Get("content").Type, "request %d: null content kept", i)
+		assert.True(t, messages[1].Get("content").Exists(), "request %d: null content not dropped", i)
+		assert.False(t, messages[3].Get("content").Exists(), "request %d: omitted content not added", i)
+	}
+
+	messages := gjson.GetBytes(requests[1].Body, "messages").Array()
+	require.Len(t, messages, 7)
+	appended := messages[5]
+	assert.Equal(t, "assistant", appended.Get("role").String())
+	assert.Equal(t, gjson.Null, appended.Get("content").Type)
+	assert.True(t, appended.Get("content").Exists())
+	assert.Equal(t, "call_expand_1", messages[6].Get("tool_call_id").String())
+	assert.Contains(t, messages[6].Get("content").String(), "CRITICAL ERROR LOG")
+}
@@ -0,0 +1,86 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/monitoring"
+	"github.com/compresr/context-gateway/internal/pipes"
+)
+
+// panickingPipe stands in for a tool_output strategy with a crash bug.
+type panickingPipe struct{}
+
+func (panickingPipe) Name() string     { return "tool_output" }
+func (panickingPipe) Strategy() string { return "simple" }
+func (panickingPipe) Enabled() bool    { return true }
+func (panickingPipe) Process(ctx *pipes.PipeContext) ([]byte, error) {
+	ctx.OutputCompressed = true
+	ctx.ShadowRefs["shadow_partial"] = "half-written state"
+	var nested map[string]map[string]int
+	nested["deep"]["key"]++ // nil map write
+	return ctx.OriginalRequest, nil
+}
+
+// TestIntegration_PipePanic_ForwardsOriginal verifies a panicking pipe degrades
+// to passthrough: the request completes, the upstream receives the original
+// body, and telemetry records the panic.
+func TestIntegration_PipePanic_ForwardsOriginal(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("still answered")
+	})
+	defer mock.close()
+
+	telemetryPath := filepath.Join(t.TempDir(), "telemetry.jsonl")
+	cfg := expandContextConfig()
+	cfg.Monitoring.TelemetryEnabled = true
+	cfg.Monitoring.TelemetryPath = telemetryPath
+
+	gw := gateway.New(cfg)
+	gw.SetToolOutputPipeForTest(func() pipes.Pipe { return panickingPipe{} })
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	sent, err := json.Marshal(compressibleRequest())
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, gwServer.URL+"/v1/messages", bytes.NewReader(sent))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", mock.url()+"/v1/messages")
+
+	resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+	assert.Contains(t, string(body), "still answered")
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1, "no expand loop for the discarded shadow ref")
+	assert.JSONEq(t, gjson.GetBytes(sent, "messages").Raw,
+		gjson.GetBytes(requests[0].Body, "messages").Raw, "original messages forwarded")
+
+	telemetry, err := os.ReadFile(telemetryPath)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(telemetry)), "\n")
+	var event monitoring.RequestEvent
+	require.NoError(t, json.Unmarshal([]byte(lines[len(lines)-1]), &event))
+	assert.Contains(t, event.PipelinePanic, "tool_output")
+	assert.False(t, event.CompressionUsed)
+	assert.Zero(t, event.ShadowRefsCreated)
+}
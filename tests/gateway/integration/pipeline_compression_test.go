@@ -0,0 +1,244 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// slowSetupListener delays the first read on every accepted connection,
+// standing in for TCP/TLS setup to a remote provider.
+type slowSetupListener struct {
+	net.Listener
+	delay time.Duration
+}
+
+func (l slowSetupListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &slowSetupConn{Conn: c, delay: l.delay}, nil
+}
+
+type slowSetupConn struct {
+	net.Conn
+	delay time.Duration
+	once  sync.Once
+}
+
+func (c *slowSetupConn) Read(p []byte) (int, error) {
+	c.once.Do(func() { time.Sleep(c.delay) })
+	return c.Conn.Read(p)
+}
+
+// pipelineUpstream is a provider mock with slow connection setup. Unless
+// keepAlive is set, each POST closes its connection, so every forwarded
+// request needs a fresh one.
+type pipelineUpstream struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string // "METHOD path"
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func newPipelineUpstream(setupDelay time.Duration, keepAlive bool) *pipelineUpstream {
+	u := &pipelineUpstream{}
+	u.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		u.mu.Lock()
+		u.requests = append(u.requests, r.Method+" "+r.URL.Path)
+		u.bodies = append(u.bodies, body)
+		u.headers = append(u.headers, r.Header.Clone())
+		u.mu.Unlock()
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !keepAlive {
+			w.Header().Set("Connection", "close")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(anthropicTextResponse("Done."))
+	}))
+	u.Listener = slowSetupListener{Listener: u.Listener, delay: setupDelay}
+	u.Start()
+	return u
+}
+
+// slowCompresr answers tool-output compression calls after delay.
+func slowCompresr(delay time.Duration) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"compressed_output": "COMPRESSED SUMMARY: services failing with 5xx"},
+		})
+	}))
+}
+
+func pipelineConfig(compresrURL string, pipelined bool) *config.Config {
+	cfg := expandContextConfig()
+	cfg.URLs.Compresr = compresrURL
+	cfg.Server.PipelineCompression = pipelined
+	cfg.Pipes.ToolOutput.Strategy = config.StrategyCompresr
+	cfg.Pipes.ToolOutput.MaxTokens = 100000
+	cfg.Pipes.ToolOutput.BypassCostCheck = true
+	cfg.Pipes.ToolOutput.Compresr = config.CompresrConfig{APIKey: "test-key"}
+	return cfg
+}
+
+// pipelineRequest carries one tool output of at least size bytes; tag makes it
+// unique so repeated requests are not served from the compression cache.
+func pipelineRequest(tag string, size int) map[string]interface{} {
+	return map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 500,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Summarize the failures."},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_pipe_001", "name": "read_file", "input": map[string]string{"path": "system.log"}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_pipe_001", "content": tag + "\n" + largeToolOutput(size)},
+			}},
+		},
+	}
+}
+
+// TestIntegration_PipelineCompression verifies server.pipeline_compression
+// connects upstream during compression without sending anything from the
+// request early: the only upstream request with a body is the compressed one.
+func TestIntegration_PipelineCompression(t *testing.T) {
+	upstream := newPipelineUpstream(0, false)
+	defer upstream.Close()
+	api := slowCompresr(50 * time.Millisecond)
+	defer api.Close()
+
+	gwServer := createGateway(pipelineConfig(api.URL, true))
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, upstream.URL, pipelineRequest("large", 64*1024))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	upstream.mu.Lock()
+	defer upstream.mu.Unlock()
+	require.Equal(t, []string{"HEAD /", "POST /v1/messages"}, upstream.requests, "warm-up completes before the forward")
+	assert.Empty(t, upstream.bodies[0])
+	assert.Empty(t, upstream.headers[0].Get("x-api-key"), "client credentials are not sent on warm-up")
+	assert.Contains(t, string(upstream.bodies[1]), "COMPRESSED SUMMARY")
+	assert.NotContains(t, string(upstream.bodies[1]), "CRITICAL ERROR LOG")
+}
+
+// TestIntegration_PipelineCompression_KeepAlive verifies the warm-up carries
+// server.upstream_headers, and is skipped while the host has a kept-alive
+// connection from a recent request.
+func TestIntegration_PipelineCompression_KeepAlive(t *testing.T) {
+	upstream := newPipelineUpstream(0, true)
+	defer upstream.Close()
+	api := slowCompresr(20 * time.Millisecond)
+	defer api.Close()
+
+	cfg := pipelineConfig(api.URL, true)
+	cfg.Server.UpstreamHeaders = map[string]string{"Proxy-Authorization": "Basic cHJveHk6c2VjcmV0"}
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, _, err := sendAnthropicRequest(gwServer.URL, upstream.URL, pipelineRequest(fmt.Sprintf("keep-alive %d", i), 64*1024))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	upstream.mu.Lock()
+	defer upstream.mu.Unlock()
+	require.Equal(t, []string{"HEAD /", "POST /v1/messages", "POST /v1/messages"}, upstream.requests)
+	assert.Equal(t, "Basic cHJveHk6c2VjcmV0", upstream.headers[0].Get("Proxy-Authorization"))
+}
+
+// TestIntegration_PipelineCompression_SmallRequest verifies small requests are
+// forwarded without a warm-up.
+func TestIntegration_PipelineCompression_SmallRequest(t *testing.T) {
+	upstream := newPipelineUpstream(0, false)
+	defer upstream.Close()
+	api := slowCompresr(0)
+	defer api.Close()
+
+	gwServer := createGateway(pipelineConfig(api.URL, true))
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, upstream.URL, pipelineRequest("small", 4*1024))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	upstream.mu.Lock()
+	defer upstream.mu.Unlock()
+	assert.Equal(t, []string{"POST /v1/messages"}, upstream.requests)
+}
+
+// BenchmarkPipelineCompression compares wall-clock latency of a large request
+// through the serial path and with pipeline_compression, against an upstream
+// whose connections take 30ms to set up and a 30ms compression call. The cold
+// cases close every connection; the keep-alive cases reuse pooled ones, where
+// the warm-up must not run after the first request.
+//
+// Run with: go test ./tests/gateway/integration/ -run '^$' -bench PipelineCompression
+func BenchmarkPipelineCompression(b *testing.B) {
+	for _, keepAlive := range []bool{false, true} {
+		for _, pipelined := range []bool{false, true} {
+			name := "cold/serial"
+			switch {
+			case keepAlive && pipelined:
+				name = "keep-alive/pipelined"
+			case keepAlive:
+				name = "keep-alive/serial"
+			case pipelined:
+				name = "cold/pipelined"
+			}
+			b.Run(name, func(b *testing.B) {
+				upstream := newPipelineUpstream(30*time.Millisecond, keepAlive)
+				defer upstream.Close()
+				api := slowCompresr(30 * time.Millisecond)
+				defer api.Close()
+				gwServer := createGateway(pipelineConfig(api.URL, pipelined))
+				defer gwServer.Close()
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					resp, _, err := sendAnthropicRequest(gwServer.URL, upstream.URL, pipelineRequest(fmt.Sprintf("run %s %d", name, i), 64*1024))
+					if err != nil || resp.StatusCode != http.StatusOK {
+						b.Fatalf("request failed: %v", err)
+					}
+				}
+				b.StopTimer()
+				if !pipelined {
+					return
+				}
+				want := b.N
+				if keepAlive {
+					want = 1
+				}
+				upstream.mu.Lock()
+				defer upstream.mu.Unlock()
+				if heads := strings.Count(strings.Join(upstream.requests, "\n"), "HEAD /"); heads != want {
+					b.Fatalf("expected %d warm-ups, got %d", want, heads)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHangingUpstream returns an upstream that never answers on its own and
+// reports on the channel when its request context is cancelled.
+func newHangingUpstream(t *testing.T) (*httptest.Server, <-chan struct{}) {
+	t.Helper()
+	cancelled := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Drain the body so net/http starts watching the connection for a close.
+		_, _ = io.Copy(io.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+		case <-time.After(10 * time.Second):
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(anthropicTextResponse("too late"))
+		}
+	}))
+	return server, cancelled
+}
+
+// TestIntegration_ClientCancel_AbortsUpstream verifies that a client disconnect
+// mid-flight cancels the in-progress upstream request.
+func TestIntegration_ClientCancel_AbortsUpstream(t *testing.T) {
+	upstream, cancelled := newHangingUpstream(t)
+	defer upstream.Close()
+
+	gwServer := createGateway(passthroughConfig())
+	defer gwServer.Close()
+
+	body, err := json.Marshal(simpleRequest("Hello"))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, gwServer.URL+"/v1/messages", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", upstream.URL+"/v1/messages")
+
+	time.AfterFunc(200*time.Millisecond, cancel)
+	_, err = http.DefaultClient.Do(req)
+	require.Error(t, err, "client request was cancelled")
+
+	select {
+	case <-cancelled:
+	case <-time.After(3 * time.Second):
+		t.Fatal("upstream request kept running after the client cancelled")
+	}
+}
+
+// TestIntegration_RequestTimeout_CancelsUpstream verifies server.request_timeout
+// aborts a slow upstream and answers 504.
+func TestIntegration_RequestTimeout_CancelsUpstream(t *testing.T) {
+	upstream, cancelled := newHangingUpstream(t)
+	defer upstream.Close()
+
+	cfg := passthroughConfig()
+	cfg.Server.RequestTimeout = 300 * time.Millisecond
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	start := time.Now()
+	resp, _, err := sendAnthropicRequest(gwServer.URL, upstream.URL, simpleRequest("Hello"))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Less(t, time.Since(start), 5*time.Second)
+
+	select {
+	case <-cancelled:
+	case <-time.After(3 * time.Second):
+		t.Fatal("upstream request was not cancelled at the deadline")
+	}
+}
@@ -0,0 +1,115 @@
+package integration
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+func responseCacheConfig() *config.Config {
+	cfg := passthroughConfig()
+	cfg.Server.ResponseCacheTTL = time.Minute
+	return cfg
+}
+
+func simpleRequest(content string) map[string]interface{} {
+	return map[string]interface{}{
+		"model":      "claude-3-haiku-20240307",
+		"max_tokens": 100,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": content},
+		},
+	}
+}
+
+// TestIntegration_ResponseCache_RepeatHitsCache verifies that two identical
+// requests within the TTL produce a single upstream call.
+func TestIntegration_ResponseCache_RepeatHitsCache(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Cached answer.")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(responseCacheConfig())
+	defer gwServer.Close()
+
+	first, firstBody, err := sendAnthropicRequest(gwServer.URL, mock.url(), simpleRequest("Hello"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, first.StatusCode)
+	assert.Empty(t, first.Header.Get(gateway.HeaderResponseCache))
+
+	second, secondBody, err := sendAnthropicRequest(gwServer.URL, mock.url(), simpleRequest("Hello"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, second.StatusCode)
+
+	assert.Len(t, mock.getRequests(), 1, "repeat must be served from the cache")
+	assert.Equal(t, "hit", second.Header.Get(gateway.HeaderResponseCache))
+	assert.Equal(t, firstBody, secondBody)
+
+	_, _, err = sendAnthropicRequest(gwServer.URL, mock.url(), simpleRequest("Hello again"))
+	require.NoError(t, err)
+	assert.Len(t, mock.getRequests(), 2, "different request must go upstream")
+}
+
+// TestIntegration_ResponseCache_DisabledByDefault verifies repeats go upstream without a TTL.
+func TestIntegration_ResponseCache_DisabledByDefault(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Answer.")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(passthroughConfig())
+	defer gwServer.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), simpleRequest("Hello"))
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	assert.Len(t, mock.getRequests(), 2)
+}
+
+// TestIntegration_ResponseCache_SkipsErrors verifies error responses are never replayed.
+func TestIntegration_ResponseCache_SkipsErrors(t *testing.T) {
+	mock := newMockLLMWithStatus(http.StatusServiceUnavailable, func(reqBody []byte, callNum int) []byte {
+		return anthropicErrorResponse()
+	})
+	defer mock.close()
+
+	gwServer := createGateway(responseCacheConfig())
+	defer gwServer.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), simpleRequest("Hello"))
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+		assert.Empty(t, resp.Header.Get(gateway.HeaderResponseCache))
+	}
+	assert.Len(t, mock.getRequests(), 2)
+}
+
+// TestIntegration_ResponseCache_SkipsStreaming verifies streaming requests bypass the cache.
+func TestIntegration_ResponseCache_SkipsStreaming(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return []byte("event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(responseCacheConfig())
+	defer gwServer.Close()
+
+	req := simpleRequest("Hello")
+	req["stream"] = true
+	for i := 0; i < 2; i++ {
+		resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), req)
+		require.NoError(t, err)
+		assert.Empty(t, resp.Header.Get(gateway.HeaderResponseCache))
+	}
+	assert.Len(t, mock.getRequests(), 2)
+}
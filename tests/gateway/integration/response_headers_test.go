@@ -0,0 +1,126 @@
+package integration
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+func compressibleRequest() map[string]interface{} {
+	return map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514", // budget models skip compression
+		"max_tokens": 500,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "What are the key points from the log?"},
+			{
+				"role": "assistant",
+				"content": []map[string]interface{}{
+					{"type": "tool_use", "id": "toolu_hdr_001", "name": "read_file", "input": map[string]string{"path": "system.log"}},
+				},
+			},
+			{
+				"role": "user",
+				"content": []map[string]interface{}{
+					{"type": "tool_result", "tool_use_id": "toolu_hdr_001", "content": largeToolOutput(2000)},
+				},
+			},
+		},
+	}
+}
+
+// TestIntegration_ResponseHeaders_CompressionSummary verifies that
+// tool_output.add_response_headers reports accurate counts to the client.
+func TestIntegration_ResponseHeaders_CompressionSummary(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Analysis complete.")
+	})
+	defer mock.close()
+
+	cfg := expandContextConfig()
+	cfg.Pipes.ToolOutput.AddResponseHeaders = true
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	reqBody := compressibleRequest()
+	clientBody, err := json.Marshal(reqBody)
+	require.NoError(t, err)
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), reqBody)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1)
+
+	assert.Equal(t, "1", resp.Header.Get(gateway.HeaderCompressedBlocks))
+	assert.Equal(t, strconv.Itoa(len(clientBody)), resp.Header.Get(gateway.HeaderOriginalBytes))
+	assert.Equal(t, strconv.Itoa(len(requests[0].Body)), resp.Header.Get(gateway.HeaderSentBytes))
+	assert.Equal(t, "true", resp.Header.Get(gateway.HeaderExpandAvailable))
+
+	assert.Empty(t, requests[0].Headers.Get(gateway.HeaderCompressedBlocks), "headers must not be forwarded upstream")
+}
+
+// TestIntegration_ResponseHeaders_Streaming verifies the headers are sent before the stream body.
+func TestIntegration_ResponseHeaders_Streaming(t *testing.T) {
+	var mu sync.Mutex
+	var upstreamBodies [][]byte
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		upstreamBodies = append(upstreamBodies, body)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		_, _ = w.Write([]byte("data: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer mock.Close()
+
+	cfg := expandContextConfig()
+	cfg.Pipes.ToolOutput.AddResponseHeaders = true
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	reqBody := compressibleRequest()
+	reqBody["stream"] = true
+	clientBody, err := json.Marshal(reqBody)
+	require.NoError(t, err)
+
+	resp, respBody, err := sendAnthropicRequest(gwServer.URL, mock.URL, reqBody)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(respBody), "message_stop")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, upstreamBodies, 1)
+	assert.Equal(t, "1", resp.Header.Get(gateway.HeaderCompressedBlocks))
+	assert.Equal(t, strconv.Itoa(len(clientBody)), resp.Header.Get(gateway.HeaderOriginalBytes))
+	assert.Equal(t, strconv.Itoa(len(upstreamBodies[0])), resp.Header.Get(gateway.HeaderSentBytes))
+}
+
+// TestIntegration_ResponseHeaders_DisabledByDefault verifies no X-CG-* headers without the option.
+func TestIntegration_ResponseHeaders_DisabledByDefault(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Analysis complete.")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), compressibleRequest())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, resp.Header.Get(gateway.HeaderCompressedBlocks))
+	assert.Empty(t, resp.Header.Get(gateway.HeaderSentBytes))
+}
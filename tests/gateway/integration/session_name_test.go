@@ -0,0 +1,59 @@
+package integration
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+// TestIntegration_SessionName_InSessionListing verifies a session directory
+// labelled via --session-name is the session's ID in the dashboard listing.
+func TestIntegration_SessionName_InSessionListing(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Done.")
+	})
+	defer mock.close()
+
+	base := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(base, "claude_code_1_20260101_110000"), 0750))
+	sessionDir := launcher.PrepareSessionPath(base, "claude_code", "fix bug 123", time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	gw := gateway.New(passthroughConfig())
+	gw.SetLazySession(sessionDir, []byte("pipes: {}\n"), "claude_code")
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages":   []map[string]interface{}{{"role": "user", "content": "Fix bug 123"}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.FileExists(t, filepath.Join(sessionDir, "config.yaml"))
+
+	listResp, err := http.Get(gwServer.URL + "/monitor/api/sessions")
+	require.NoError(t, err)
+	defer listResp.Body.Close()
+	var listing struct {
+		Sessions []struct {
+			ID string `json:"id"`
+		} `json:"sessions"`
+	}
+	require.NoError(t, json.NewDecoder(listResp.Body).Decode(&listing))
+
+	var ids []string
+	for _, s := range listing.Sessions {
+		ids = append(ids, s.ID)
+	}
+	assert.Contains(t, ids, "claude_code_2_fix-bug-123_20260101_120000")
+}
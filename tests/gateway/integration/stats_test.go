@@ -0,0 +1,110 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// TestIntegration_Stats_CountsCompressedTraffic verifies that gw.Stats() reports
+// accurate counters after driving compressed requests through an in-process gateway.
+func TestIntegration_Stats_CountsCompressedTraffic(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Analysis complete.")
+	})
+	defer mock.close()
+
+	gw := gateway.New(expandContextConfig())
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	reqBody := compressibleRequest()
+	clientBody, err := json.Marshal(reqBody)
+	require.NoError(t, err)
+
+	// The same tool output three times: compressed once, then served from cache.
+	for i := 0; i < 3; i++ {
+		resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), reqBody)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 3)
+	var sent int64
+	for _, r := range requests {
+		sent += int64(len(r.Body))
+	}
+	stats := gw.Stats()
+	assert.Equal(t, int64(3*len(clientBody)), stats.Gateway.BytesIn)
+	assert.Equal(t, sent, stats.Gateway.BytesOut)
+	assert.Less(t, stats.Gateway.BytesOut, stats.Gateway.BytesIn)
+
+	// An unsupported request format is rejected before forwarding and counts as an error.
+	resp, err := http.Post(gwServer.URL+"/v1/unknown", "application/json", bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.GreaterOrEqual(t, resp.StatusCode, 400)
+
+	stats = gw.Stats()
+	assert.Equal(t, int64(4), stats.Gateway.TotalRequests)
+	assert.Equal(t, int64(3), stats.Gateway.SuccessfulRequests)
+	assert.Equal(t, int64(1), stats.Gateway.Errors)
+	assert.Equal(t, int64(3), stats.Gateway.Compressions)
+	assert.Equal(t, int64(2), stats.Gateway.CacheHits)
+	assert.Equal(t, int64(1), stats.Gateway.CacheMisses)
+	assert.Equal(t, int64(3*len(clientBody)), stats.Gateway.BytesIn, "rejected requests add no bytes")
+}
+
+// TestIntegration_Stats_CountsCompressedToolInputs verifies compress_tool_inputs
+// rewrites count as compressions and cache lookups like tool outputs do.
+func TestIntegration_Stats_CountsCompressedToolInputs(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("Done.")
+	})
+	defer mock.close()
+
+	cfg := expandContextConfig()
+	cfg.Pipes.ToolOutput.CompressToolInputs = true
+	gw := gateway.New(cfg)
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	content := strings.Repeat("func helper(x int) int { return x * 2 } // generated helper\n", 150)
+	reqBody := map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 500,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Write the helpers."},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_write", "name": "write_file",
+					"input": map[string]string{"path": "helpers.go", "content": content}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_write", "content": "ok"},
+			}},
+			{"role": "assistant", "content": "Written."},
+			{"role": "user", "content": "Thanks, what next?"},
+		},
+	}
+
+	// Compressed on the first request, served from cache on the second.
+	for i := 0; i < 2; i++ {
+		resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), reqBody)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	stats := gw.Stats()
+	assert.Equal(t, int64(2), stats.Gateway.Compressions)
+	assert.Equal(t, int64(1), stats.Gateway.CacheHits)
+	assert.Equal(t, int64(1), stats.Gateway.CacheMisses)
+}
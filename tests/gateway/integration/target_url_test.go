@@ -0,0 +1,80 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIntegration_TargetURL_HostOrFullPath verifies X-Target-URL routing: a bare
+// host gets the request path appended, a URL ending with the endpoint is used
+// as-is (sub-resources appended), a path prefix is joined, and none of them
+// produces a doubled /v1/messages/v1/messages.
+func TestIntegration_TargetURL_HostOrFullPath(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(anthropicTextResponse("ok"))
+	}))
+	defer upstream.Close()
+
+	gwServer := createGateway(passthroughConfig())
+	defer gwServer.Close()
+
+	body, err := json.Marshal(simpleRequest("Hello"))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		target string
+		path   string
+		want   string
+	}{
+		{"host only", upstream.URL, "/v1/messages", "/v1/messages"},
+		{"host with trailing slash", upstream.URL + "/", "/v1/messages", "/v1/messages"},
+		{"full path", upstream.URL + "/v1/messages", "/v1/messages", "/v1/messages"},
+		{"path prefix", upstream.URL + "/api", "/v1/messages", "/api/v1/messages"},
+		{"path prefix with version", upstream.URL + "/api/v1/", "/v1/messages", "/api/v1/messages"},
+		{"endpoint sub-resource", upstream.URL + "/v1/messages", "/v1/messages/count_tokens", "/v1/messages/count_tokens"},
+		{"prefix sub-resource", upstream.URL + "/api", "/v1/messages/count_tokens", "/api/v1/messages/count_tokens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			paths = nil
+			mu.Unlock()
+
+			req, err := http.NewRequest(http.MethodPost, gwServer.URL+tt.path, bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("x-api-key", "sk-ant-test-key")
+			req.Header.Set("anthropic-version", "2023-06-01")
+			req.Header.Set("X-Target-URL", tt.target)
+
+			resp, err := (&http.Client{Timeout: 30 * time.Second}).Do(req)
+			require.NoError(t, err)
+			respBody, _ := io.ReadAll(resp.Body)
+			_ = resp.Body.Close()
+			require.Equal(t, http.StatusOK, resp.StatusCode, string(respBody))
+
+			mu.Lock()
+			defer mu.Unlock()
+			assert.Equal(t, []string{tt.want}, paths)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package integration
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/monitoring"
+)
+
+// queryTelemetry runs query against the telemetry database and returns rows as column→value maps.
+func queryTelemetry(t *testing.T, dbPath, query string) []map[string]string {
+	t.Helper()
+	columns, rows, err := monitoring.QueryTelemetrySQLite(context.Background(), dbPath, query)
+	require.NoError(t, err)
+	out := make([]map[string]string, 0, len(rows))
+	for _, row := range rows {
+		m := make(map[string]string, len(columns))
+		for i, col := range columns {
+			m[col] = row[i]
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// TestIntegration_TelemetrySQLite_CompressionFlow verifies a compressed request
+// lands in the requests and compressions tables with the expected columns.
+func TestIntegration_TelemetrySQLite_CompressionFlow(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("summary of the log")
+	})
+	defer mock.close()
+
+	dbPath := filepath.Join(t.TempDir(), "telemetry.db")
+	cfg := expandContextConfig()
+	cfg.Monitoring.TelemetryEnabled = true
+	cfg.Monitoring.TelemetrySQLitePath = dbPath
+	gw := createGateway(cfg)
+	defer gw.Close()
+
+	resp, _, err := sendAnthropicRequest(gw.URL, mock.url(), compressibleRequest())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var requests []map[string]string
+	require.Eventually(t, func() bool {
+		requests = queryTelemetry(t, dbPath, "SELECT * FROM requests")
+		return len(requests) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	req := requests[0]
+	assert.NotEmpty(t, req["request_id"])
+	assert.Equal(t, "anthropic", req["provider"])
+	assert.Equal(t, "claude-sonnet-4-20250514", req["model"])
+	assert.Equal(t, "/v1/messages", req["path"])
+	assert.Equal(t, "200", req["status_code"])
+	assert.Equal(t, "1", req["success"])
+	assert.Equal(t, "1", req["tool_output_count"])
+	_, err = time.Parse(time.RFC3339, req["timestamp"])
+	assert.NoError(t, err, "timestamp is RFC3339")
+
+	comps := queryTelemetry(t, dbPath, "SELECT * FROM compressions WHERE status = 'compressed'")
+	require.Len(t, comps, 1)
+	comp := comps[0]
+	assert.Equal(t, req["request_id"], comp["request_id"])
+	assert.Equal(t, "tool_output", comp["event_type"])
+	assert.Equal(t, "read_file", comp["tool_name"])
+	assert.NotEmpty(t, comp["shadow_id"])
+	saved, err := strconv.Atoi(comp["bytes_saved"])
+	require.NoError(t, err)
+	assert.Positive(t, saved)
+	tokensSaved, _ := strconv.Atoi(comp["tokens_saved"])
+	assert.Positive(t, tokensSaved)
+
+	byTool := queryTelemetry(t, dbPath,
+		"SELECT tool_name, SUM(bytes_saved) AS bytes_saved FROM compressions WHERE tool_name != '' GROUP BY tool_name")
+	require.Len(t, byTool, 1)
+	assert.Equal(t, comp["bytes_saved"], byTool[0]["bytes_saved"])
+}
+
+// TestIntegration_TelemetrySQLite_ReadOnlyQueries verifies stats queries cannot modify the database.
+func TestIntegration_TelemetrySQLite_ReadOnlyQueries(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "telemetry.db")
+	cfg := passthroughConfig()
+	cfg.Monitoring.TelemetryEnabled = true
+	cfg.Monitoring.TelemetrySQLitePath = dbPath
+	gw := createGateway(cfg)
+	defer gw.Close()
+
+	_, _, err := monitoring.QueryTelemetrySQLite(context.Background(), dbPath, "DELETE FROM requests")
+	assert.Error(t, err)
+}
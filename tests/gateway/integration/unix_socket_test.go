@@ -0,0 +1,145 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// socketPath returns a short socket path; t.TempDir() can exceed the ~104-byte
+// sun_path limit on some platforms.
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "cg-sock")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return filepath.Join(dir, "gw.sock")
+}
+
+// startUnixGateway serves gw on path and waits until the socket accepts
+// connections. The returned channel yields StartUnix's result.
+func startUnixGateway(t *testing.T, gw *gateway.Gateway, path string) <-chan error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- gw.StartUnix(path) }()
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			_ = conn.Close()
+		}
+		return err == nil
+	}, 2*time.Second, 10*time.Millisecond)
+	return done
+}
+
+func unixClient(path string) *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+// TestIntegration_UnixSocket_SameAsTCP verifies a request over the Unix socket
+// runs the full pipeline and reaches the upstream identical to one sent over TCP,
+// that localhost-only endpoints accept socket clients, and that the socket file
+// is removed on shutdown.
+func TestIntegration_UnixSocket_SameAsTCP(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("ok")
+	})
+	defer mock.close()
+
+	tcpServer := createGateway(expandContextConfig())
+	defer tcpServer.Close()
+	resp, _, err := sendAnthropicRequest(tcpServer.URL, mock.url(), compressibleRequest())
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	path := socketPath(t)
+	gw := gateway.New(expandContextConfig())
+	done := startUnixGateway(t, gw, path)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	data, err := json.Marshal(compressibleRequest())
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, "http://unix/v1/messages", bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", mock.url()+"/v1/messages")
+
+	client := unixClient(path)
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 2)
+	assert.Contains(t, string(requests[0].Body), "[REF:", "TCP request was compressed")
+	assert.Equal(t, string(requests[0].Body), string(requests[1].Body), "socket request matches TCP")
+
+	resp, err = client.Get("http://unix/stats")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "socket clients count as local")
+
+	require.NoError(t, gw.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+	_, err = os.Stat(path)
+	assert.True(t, errors.Is(err, os.ErrNotExist), "socket file removed on shutdown")
+}
+
+// TestIntegration_UnixSocket_ExistingPath verifies a live socket or regular file
+// at the path is refused while a stale socket file is replaced.
+func TestIntegration_UnixSocket_ExistingPath(t *testing.T) {
+	path := socketPath(t)
+	live, err := net.Listen("unix", path)
+	require.NoError(t, err)
+
+	gw := gateway.New(passthroughConfig())
+	defer func() { _ = gw.Shutdown(context.Background()) }()
+
+	err = gw.StartUnix(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "already in use")
+
+	// A socket file whose listener is gone (e.g. after a crash) is stale.
+	live.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(t, live.Close())
+	done := startUnixGateway(t, gw, path)
+	resp, err := unixClient(path).Get("http://unix/health")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, gw.Shutdown(context.Background()))
+	<-done
+
+	regular := filepath.Join(filepath.Dir(path), "not-a-socket")
+	require.NoError(t, os.WriteFile(regular, []byte("x"), 0600))
+	err = gw.StartUnix(regular)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a socket")
+}
@@ -0,0 +1,78 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestIntegration_UpstreamHeaders_Injected verifies configured headers reach the upstream.
+func TestIntegration_UpstreamHeaders_Injected(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		return anthropicTextResponse("ok")
+	})
+	defer mock.close()
+
+	cfg := passthroughConfig()
+	cfg.Server.UpstreamHeaders = map[string]string{
+		"OpenAI-Organization": "org-configured",
+		"X-Trace-Tenant":      "tenant-42",
+	}
+	gwServer := createGateway(cfg)
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), simpleRequest("Hello"))
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	requests := mock.getRequests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "org-configured", requests[0].Headers.Get("OpenAI-Organization"))
+	assert.Equal(t, "tenant-42", requests[0].Headers.Get("X-Trace-Tenant"))
+}
+
+// TestIntegration_UpstreamHeaders_ClientWins verifies client values are kept
+// unless override_upstream_headers is set.
+func TestIntegration_UpstreamHeaders_ClientWins(t *testing.T) {
+	for _, override := range []bool{false, true} {
+		mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+			return anthropicTextResponse("ok")
+		})
+
+		cfg := passthroughConfig()
+		cfg.Server.UpstreamHeaders = map[string]string{"OpenAI-Organization": "org-configured"}
+		cfg.Server.OverrideUpstreamHeaders = override
+		gwServer := createGateway(cfg)
+
+		body, err := json.Marshal(simpleRequest("Hello"))
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, gwServer.URL+"/v1/messages", bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", "sk-ant-test-key")
+		req.Header.Set("anthropic-version", "2023-06-01")
+		req.Header.Set("OpenAI-Organization", "org-client")
+		req.Header.Set("X-Target-URL", mock.url()+"/v1/messages")
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		requests := mock.getRequests()
+		require.Len(t, requests, 1)
+		want := "org-client"
+		if override {
+			want = "org-configured"
+		}
+		assert.Equal(t, want, requests[0].Headers.Get("OpenAI-Organization"), "override=%v", override)
+
+		gwServer.Close()
+		mock.close()
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 	"time"
@@ -76,6 +77,26 @@ func TestGateway_Health_POST(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+func TestGateway_Health_ReportsConfigAndPID(t *testing.T) {
+	cfg := edgeCaseConfig()
+	gw := gateway.New(cfg, "/etc/context-gateway/fast_setup.yaml")
+	defer gw.Shutdown(context.Background())
+
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	resp, err := http.Get(gwServer.URL + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Loopback callers see the config file and pid, so the launcher can
+	// compare the config and serve can tell its own listener answered
+	var health map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&health))
+	assert.Equal(t, "/etc/context-gateway/fast_setup.yaml", health["config"])
+	assert.Equal(t, float64(os.Getpid()), health["pid"])
+}
+
 func TestGateway_EmptyRequestBody(t *testing.T) {
 	cfg := edgeCaseConfig()
 	gw := gateway.New(cfg)
@@ -128,3 +149,55 @@ func TestGateway_StatsEndpoint_ReturnsJSON(t *testing.T) {
 	err = json.NewDecoder(resp.Body).Decode(&stats)
 	assert.NoError(t, err, "stats response should be valid JSON")
 }
+
+func TestGateway_DebugStore_DisabledByDefault(t *testing.T) {
+	cfg := edgeCaseConfig()
+	gw := gateway.New(cfg)
+	defer gw.Shutdown(context.Background())
+
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	resp, err := http.Get(gwServer.URL + "/debug/store")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestGateway_DebugStore_Enabled(t *testing.T) {
+	cfg := edgeCaseConfig()
+	cfg.Server.DebugEndpoints = true
+	gw := gateway.New(cfg)
+	defer gw.Shutdown(context.Background())
+
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	resp, err := http.Get(gwServer.URL + "/debug/store")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var dump gateway.DebugStoreResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&dump))
+	assert.Equal(t, 0, dump.Count)
+	assert.NotNil(t, dump.Entries)
+}
+
+func TestGateway_DebugStore_ClearRequiresDebugEndpoints(t *testing.T) {
+	gw := gateway.New(edgeCaseConfig())
+	defer gw.Shutdown(context.Background())
+
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	req, err := http.NewRequest(http.MethodDelete, gwServer.URL+"/debug/store", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
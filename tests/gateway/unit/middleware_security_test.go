@@ -3,6 +3,7 @@ package unit
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -92,3 +93,33 @@ func TestSecurityHeaders(t *testing.T) {
 	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
 	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
 }
+
+// TestSSRF_TargetURLMetadataIP_Forbidden verifies that a request whose X-Target-URL
+// points at the cloud metadata IP is rejected with 403 before proxying.
+func TestSSRF_TargetURLMetadataIP_Forbidden(t *testing.T) {
+	g := gateway.New(ssrfTestConfig())
+	handler := g.Handler()
+
+	body := `{"model":"gpt-4","messages":[{"role":"user","content":"hi"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Target-URL", "http://169.254.169.254/latest/meta-data")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestSSRF_ConfiguredUpstreamHosts verifies the configured allow/deny lists.
+func TestSSRF_ConfiguredUpstreamHosts(t *testing.T) {
+	cfg := ssrfTestConfig()
+	cfg.Server.AllowedUpstreamHosts = []string{"llm.internal.example", "10.1.0.0/16"}
+	cfg.Server.DeniedUpstreamHosts = []string{"api.openai.com"}
+	g := gateway.New(cfg)
+
+	assert.True(t, g.IsAllowedHostForTest("llm.internal.example"))
+	assert.True(t, g.IsAllowedHostForTest("10.1.2.3:8080"), "CIDR entry should match IPs in range")
+	assert.False(t, g.IsAllowedHostForTest("10.2.0.1"), "IPs outside the CIDR stay blocked")
+	assert.False(t, g.IsAllowedHostForTest("api.openai.com"), "denylist overrides built-in allowlist")
+	assert.True(t, g.IsAllowedHostForTest("api.anthropic.com"))
+}
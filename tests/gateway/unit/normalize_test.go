@@ -0,0 +1,138 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+)
+
+// upstreamBody sends body through a passthrough gateway whose shadow store
+// holds shadowIDs and returns the body the upstream received and the
+// gateway's stats afterwards.
+func upstreamBody(t *testing.T, body map[string]interface{}, shadowIDs ...string) ([]byte, gateway.StatsResponse) {
+	t.Helper()
+	gateway.EnableLocalHostsForTesting()
+
+	var (
+		mu       sync.Mutex
+		received []byte
+	)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = b
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"model":"claude-sonnet-4-20250514","stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(edgeCaseConfig())
+	defer gw.Shutdown(context.Background())
+	for _, id := range shadowIDs {
+		require.NoError(t, gw.ShadowStoreForTest().Set(id, "original content"))
+	}
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	req, err := http.NewRequest(http.MethodPost, gwServer.URL+"/v1/messages", strings.NewReader(string(raw)))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", "sk-ant-test-key")
+	req.Header.Set("anthropic-version", "2023-06-01")
+	req.Header.Set("X-Target-URL", upstream.URL)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	respBody, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode, string(respBody))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, received)
+	return received, gw.Stats()
+}
+
+// TestGateway_LeakedRefs_StripsOwnRefs verifies [REF:id] references the
+// gateway emitted are removed from the system prompt and assistant turns,
+// while the rest of the text is forwarded as-is.
+func TestGateway_LeakedRefs_StripsOwnRefs(t *testing.T) {
+	body, stats := upstreamBody(t, map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"system": []map[string]interface{}{
+			{"type": "text", "text": "You are helpful.\n[REF:shadow_abc123]\nBe brief."},
+		},
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Read the log"},
+			{"role": "assistant", "content": "[REF:shadow_def456]\nHere is the log summary."},
+			{"role": "user", "content": "Thanks"},
+		},
+	}, "shadow_abc123", "shadow_def456")
+
+	assert.Equal(t, "You are helpful.\nBe brief.", gjson.GetBytes(body, "system.0.text").String())
+	assert.Equal(t, "Here is the log summary.", gjson.GetBytes(body, "messages.1.content").String())
+	assert.NotContains(t, string(body), "[REF:shadow_")
+	assert.Equal(t, int64(2), stats.Gateway.LeakedRefs)
+}
+
+// TestGateway_LeakedRefs_UnknownCountedNotStripped verifies [REF:id]
+// references the gateway did not emit are counted but forwarded as-is, as is
+// the rest of the text — including a model's <<<EXPAND:id>>> request.
+func TestGateway_LeakedRefs_UnknownCountedNotStripped(t *testing.T) {
+	system := "You are helpful.\n[REF:shadow_abc123]\nBe brief."
+	assistant := "<<<EXPAND:shadow_abc123>>>Here is the log summary. [REF:shadow_def456]"
+	body, stats := upstreamBody(t, map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"system": []map[string]interface{}{
+			{"type": "text", "text": system},
+		},
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Read the log"},
+			{"role": "assistant", "content": assistant},
+			{"role": "user", "content": "Thanks"},
+		},
+	})
+
+	assert.Equal(t, system, gjson.GetBytes(body, "system.0.text").String())
+	assert.Equal(t, assistant, gjson.GetBytes(body, "messages.1.content").String())
+	assert.Equal(t, int64(2), stats.Gateway.LeakedRefs)
+}
+
+// TestGateway_LeakedRefs_ToolResultsNotCounted verifies a [REF:id] inside a
+// tool result — where compressed content legitimately lives — is forwarded
+// as-is and not counted.
+func TestGateway_LeakedRefs_ToolResultsNotCounted(t *testing.T) {
+	toolOutput := "[REF:shadow_abc123]\nbuild ok"
+	body, stats := upstreamBody(t, map[string]interface{}{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 100,
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Build it"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_1", "name": "bash", "input": map[string]string{"command": "make"}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": toolOutput},
+			}},
+		},
+	})
+
+	assert.Equal(t, toolOutput, gjson.GetBytes(body, "messages.2.content.0.content").String())
+	assert.Zero(t, stats.Gateway.LeakedRefs)
+}
@@ -0,0 +1,71 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/preemptive"
+)
+
+// TestGateway_OverflowGuard_ErrorNeverForwards verifies a request still over
+// the context window after the pipes is rejected with a clear gateway error
+// under overflow_action: error, and never reaches the upstream.
+func TestGateway_OverflowGuard_ErrorNeverForwards(t *testing.T) {
+	gateway.EnableLocalHostsForTesting()
+
+	var upstreamCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"model":"claude-sonnet-4-20250514","stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":1}}`))
+	}))
+	defer upstream.Close()
+
+	cfg := edgeCaseConfig()
+	cfg.Preemptive.OverflowAction = preemptive.OverflowActionError
+	cfg.Preemptive.TestContextWindowOverride = 200
+	gw := gateway.New(cfg)
+	defer gw.Shutdown(context.Background())
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	send := func(content string) (int, string) {
+		raw, err := json.Marshal(map[string]interface{}{
+			"model":      "claude-sonnet-4-20250514",
+			"max_tokens": 100,
+			"messages":   []map[string]interface{}{{"role": "user", "content": content}},
+		})
+		require.NoError(t, err)
+		req, err := http.NewRequest(http.MethodPost, gwServer.URL+"/v1/messages", strings.NewReader(string(raw)))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", "sk-ant-test-key")
+		req.Header.Set("anthropic-version", "2023-06-01")
+		req.Header.Set("X-Target-URL", upstream.URL)
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	status, body := send(strings.Repeat("far too long for the window ", 200))
+	assert.Equal(t, http.StatusBadRequest, status)
+	assert.Contains(t, body, "context overflow")
+	assert.Contains(t, body, "200-token context window")
+	assert.Zero(t, upstreamCalls.Load(), "over-window request must not be forwarded")
+
+	status, body = send("short question")
+	assert.Equal(t, http.StatusOK, status, body)
+	assert.Equal(t, int32(1), upstreamCalls.Load())
+}
@@ -0,0 +1,33 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+// TestLaunchConfig verifies when an agent's default_config replaces the config
+// selection step.
+func TestLaunchConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		configFlag   string
+		agentDefault string
+		showMenu     bool
+		skipProxy    bool
+		want         string
+	}{
+		{name: "agent default", agentDefault: "fast_setup", want: "fast_setup"},
+		{name: "explicit -c wins", configFlag: "mine", agentDefault: "fast_setup", want: "mine"},
+		{name: "bare -c shows the menu", agentDefault: "fast_setup", showMenu: true, want: ""},
+		{name: "proxy skipped", agentDefault: "fast_setup", skipProxy: true, want: ""},
+		{name: "no default", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, launcher.LaunchConfig(tt.configFlag, tt.agentDefault, tt.showMenu, tt.skipProxy))
+		})
+	}
+}
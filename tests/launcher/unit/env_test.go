@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+func writeEnvFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+// TestLoadEnvFiles_LaterFilesWin verifies env files override the environment
+// and each other in order.
+func TestLoadEnvFiles_LaterFilesWin(t *testing.T) {
+	t.Setenv("LAUNCHER_ENV_SHARED", "from-environment")
+	t.Setenv("LAUNCHER_ENV_FIRST", "")
+	dir := t.TempDir()
+	first := writeEnvFile(t, dir, "first.env", "LAUNCHER_ENV_SHARED=first\nLAUNCHER_ENV_FIRST=only-first\n")
+	second := writeEnvFile(t, dir, "second.env", "LAUNCHER_ENV_SHARED=second\n")
+
+	require.NoError(t, launcher.LoadEnvFiles([]string{first, second}))
+	assert.Equal(t, "second", os.Getenv("LAUNCHER_ENV_SHARED"))
+	assert.Equal(t, "only-first", os.Getenv("LAUNCHER_ENV_FIRST"))
+}
+
+// TestLoadEnvFiles_MissingFile verifies a named file that does not exist is an
+// error and files after it are not loaded.
+func TestLoadEnvFiles_MissingFile(t *testing.T) {
+	t.Setenv("LAUNCHER_ENV_AFTER", "unset")
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.env")
+	after := writeEnvFile(t, dir, "after.env", "LAUNCHER_ENV_AFTER=loaded\n")
+
+	err := launcher.LoadEnvFiles([]string{missing, after})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "env file not found: "+missing)
+	assert.Equal(t, "unset", os.Getenv("LAUNCHER_ENV_AFTER"))
+}
@@ -0,0 +1,36 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+// TestStripGlobalFlags verifies --quiet is removed wherever it appears before
+// "--", and that CG_QUIET turns quiet mode on without the flag.
+func TestStripGlobalFlags(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		quietEnv  string
+		wantRest  []string
+		wantQuiet bool
+	}{
+		{"no flags", []string{"serve", "--port", "18081"}, "", []string{"serve", "--port", "18081"}, false},
+		{"quiet before subcommand", []string{"--quiet", "stats"}, "", []string{"stats"}, true},
+		{"quiet after subcommand", []string{"serve", "--quiet", "-c", "fast.yaml"}, "", []string{"serve", "-c", "fast.yaml"}, true},
+		{"CG_QUIET", []string{"stats"}, "1", []string{"stats"}, true},
+		{"CG_QUIET false", []string{"stats"}, "false", []string{"stats"}, false},
+		{"CG_QUIET invalid", []string{"stats"}, "yes please", []string{"stats"}, false},
+		{"agent args kept", []string{"agent", "claude_code", "--", "--quiet", "-p", "hi"}, "", []string{"agent", "claude_code", "--", "--quiet", "-p", "hi"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rest, quiet := launcher.StripGlobalFlags(tt.args, tt.quietEnv)
+			assert.Equal(t, tt.wantRest, rest)
+			assert.Equal(t, tt.wantQuiet, quiet)
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package unit
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+const geminiLaunchYAML = `
+server:
+  port: 18099
+  read_timeout: 30s
+  write_timeout: 60s
+store:
+  type: memory
+  ttl: 1h
+providers:
+  gemini:
+    api_key: "${LAUNCHER_TEST_GEMINI_KEY:-}"
+    model: "gemini-2.5-flash"
+preemptive:
+  enabled: true
+  trigger_threshold: 85.0
+  summarizer:
+    provider: gemini
+    max_tokens: 4096
+    timeout: 60s
+  session:
+    summary_ttl: 3h
+    hash_message_count: 3
+`
+
+func noWarn(t *testing.T) func(config.DoctorIssue) {
+	return func(issue config.DoctorIssue) { t.Errorf("unexpected warning: %s", issue.Message) }
+}
+
+// TestCheckKeys_EnterKey verifies a key entered at the prompt is picked up and
+// the launch continues without asking again.
+func TestCheckKeys_EnterKey(t *testing.T) {
+	t.Setenv("LAUNCHER_TEST_GEMINI_KEY", "")
+	var asked []string
+	ok := launcher.CheckKeys([]byte(geminiLaunchYAML), "", func(missing config.DoctorIssue) launcher.KeyAction {
+		asked = append(asked, missing.Provider)
+		require.NoError(t, os.Setenv("LAUNCHER_TEST_GEMINI_KEY", "AIzaSyTestKeyTestKeyTestKey"))
+		return launcher.KeyEntered
+	}, noWarn(t))
+
+	assert.True(t, ok)
+	assert.Equal(t, []string{"gemini"}, asked)
+}
+
+// TestCheckKeys_ContinueOrExit verifies the user's choice decides the launch.
+func TestCheckKeys_ContinueOrExit(t *testing.T) {
+	t.Setenv("LAUNCHER_TEST_GEMINI_KEY", "")
+	answer := func(a launcher.KeyAction) launcher.KeyPrompt {
+		return func(config.DoctorIssue) launcher.KeyAction { return a }
+	}
+	assert.True(t, launcher.CheckKeys([]byte(geminiLaunchYAML), "", answer(launcher.KeyContinue), noWarn(t)))
+	assert.False(t, launcher.CheckKeys([]byte(geminiLaunchYAML), "", answer(launcher.KeyExit), noWarn(t)))
+}
+
+// TestCheckKeys_NoPrompt verifies nothing is asked when the key is set or the
+// agent's own credentials are captured.
+func TestCheckKeys_NoPrompt(t *testing.T) {
+	prompt := func(missing config.DoctorIssue) launcher.KeyAction {
+		t.Errorf("unexpected prompt for %s", missing.Provider)
+		return launcher.KeyExit
+	}
+
+	t.Setenv("LAUNCHER_TEST_GEMINI_KEY", "")
+	assert.True(t, launcher.CheckKeys([]byte(geminiLaunchYAML), config.ProviderGemini, prompt, noWarn(t)))
+
+	t.Setenv("LAUNCHER_TEST_GEMINI_KEY", "AIzaSyTestKeyTestKeyTestKey")
+	assert.True(t, launcher.CheckKeys([]byte(geminiLaunchYAML), "", prompt, noWarn(t)))
+}
+
+// TestCapturedProvider verifies which agents bring their own provider credentials.
+func TestCapturedProvider(t *testing.T) {
+	assert.Equal(t, config.ProviderAnthropic, launcher.CapturedProvider("claude_code"))
+	assert.Equal(t, config.ProviderOpenAI, launcher.CapturedProvider("codex"))
+	assert.Empty(t, launcher.CapturedProvider("openclaw"))
+}
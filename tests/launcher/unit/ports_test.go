@@ -0,0 +1,126 @@
+package unit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+// fakeProbe reports the listed ports as taken, by a gateway or by another process.
+func fakeProbe(gateways, others []int) launcher.PortProbe {
+	set := func(ports []int) map[int]bool {
+		m := make(map[int]bool, len(ports))
+		for _, p := range ports {
+			m[p] = true
+		}
+		return m
+	}
+	gw, other := set(gateways), set(others)
+	return launcher.PortProbe{
+		InUse:   func(port int) bool { return gw[port] || other[port] },
+		Healthy: func(port int) bool { return gw[port] },
+	}
+}
+
+// TestChoosePort_FirstFree verifies the first free port in the range is used.
+func TestChoosePort_FirstFree(t *testing.T) {
+	probe := fakeProbe([]int{18081}, []int{18082})
+	choice, err := probe.ChoosePort(launcher.PortRequest{CanReuse: true}, 18081, 10)
+	require.NoError(t, err)
+	assert.Equal(t, launcher.PortChoice{Port: 18083}, choice)
+}
+
+// TestChoosePort_DetachReuse verifies --detach with an explicit port reuses the
+// gateway already serving it, and only then.
+func TestChoosePort_DetachReuse(t *testing.T) {
+	probe := fakeProbe([]int{18085}, []int{18086})
+	tests := []struct {
+		name string
+		req  launcher.PortRequest
+		want launcher.PortChoice
+	}{
+		{"detached gateway", launcher.PortRequest{Port: 18085, Detach: true, CanReuse: true}, launcher.PortChoice{Port: 18085, Reuse: true}},
+		{"without --detach", launcher.PortRequest{Port: 18085, CanReuse: true}, launcher.PortChoice{Port: 18085}},
+		{"daemon child", launcher.PortRequest{Port: 18085, Detach: true}, launcher.PortChoice{Port: 18085}},
+		{"port held by another process", launcher.PortRequest{Port: 18086, Detach: true, CanReuse: true}, launcher.PortChoice{Port: 18086}},
+		{"free port", launcher.PortRequest{Port: 18087, Detach: true, CanReuse: true}, launcher.PortChoice{Port: 18087}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			choice, err := probe.ChoosePort(tt.req, 18081, 10)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, choice)
+		})
+	}
+}
+
+// TestChoosePort_RangeBusy verifies a busy range reports who holds each port.
+func TestChoosePort_RangeBusy(t *testing.T) {
+	probe := fakeProbe([]int{18081, 18083}, []int{18082})
+	_, err := probe.ChoosePort(launcher.PortRequest{CanReuse: true}, 18081, 3)
+
+	var noPort *launcher.NoPortError
+	require.True(t, errors.As(err, &noPort))
+	assert.Equal(t, []int{18081, 18083}, noPort.Gateways)
+	assert.Equal(t, []int{18082}, noPort.Others)
+	assert.Contains(t, err.Error(), "18081-18083")
+}
+
+// TestStopDetached verifies serve stop terminates the detached gateway and
+// removes its PID file.
+func TestStopDetached(t *testing.T) {
+	cmd := startProcess(t, "exec sleep 30")
+	go func() { _ = cmd.Wait() }() // reap, as init would for the real daemon
+
+	pidFile := filepath.Join(t.TempDir(), "gateway.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0600))
+
+	require.NoError(t, launcher.StopDetached(pidFile, 18085, 5*time.Second))
+	assert.NoFileExists(t, pidFile)
+}
+
+// TestStopDetached_NotRunning verifies missing and stale PID files are errors,
+// and a stale one is cleaned up.
+func TestStopDetached_NotRunning(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "gateway.pid")
+	err := launcher.StopDetached(pidFile, 18085, time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no detached gateway on port 18085")
+
+	cmd := startProcess(t, "exit 0")
+	require.NoError(t, cmd.Wait())
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(cmd.Process.Pid)), 0600))
+	err = launcher.StopDetached(pidFile, 18085, time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "stale PID file")
+	assert.NoFileExists(t, pidFile)
+}
+
+// TestChoosePort_ReuseGateway verifies --reuse-gateway shares the first
+// healthy gateway when the range is busy, and never otherwise.
+func TestChoosePort_ReuseGateway(t *testing.T) {
+	busy := fakeProbe([]int{18082, 18083}, []int{18081})
+	choice, err := busy.ChoosePort(launcher.PortRequest{ReuseBusy: true, CanReuse: true}, 18081, 3)
+	require.NoError(t, err)
+	assert.Equal(t, launcher.PortChoice{Port: 18082, Reuse: true}, choice)
+
+	_, err = busy.ChoosePort(launcher.PortRequest{ReuseBusy: true}, 18081, 3)
+	assert.Error(t, err, "the daemon child starts its own gateway")
+
+	noGateway := fakeProbe(nil, []int{18081, 18082, 18083})
+	_, err = noGateway.ChoosePort(launcher.PortRequest{ReuseBusy: true, CanReuse: true}, 18081, 3)
+	assert.Error(t, err, "nothing to share")
+
+	free := fakeProbe([]int{18081}, nil)
+	choice, err = free.ChoosePort(launcher.PortRequest{ReuseBusy: true, CanReuse: true}, 18081, 3)
+	require.NoError(t, err)
+	assert.Equal(t, launcher.PortChoice{Port: 18082}, choice, "a free port is still preferred")
+}
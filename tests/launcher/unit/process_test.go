@@ -0,0 +1,62 @@
+package unit
+
+import (
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+func startProcess(t *testing.T, script string) *exec.Cmd {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+	cmd := exec.Command("sh", "-c", script)
+	require.NoError(t, cmd.Start())
+	return cmd
+}
+
+// TestStopSubprocess_Graceful verifies a process that honours the stop signal
+// exits without being killed.
+func TestStopSubprocess_Graceful(t *testing.T) {
+	cmd := startProcess(t, "exec sleep 30")
+
+	forced, err := launcher.StopSubprocess(cmd, 5*time.Second)
+	require.NoError(t, err)
+	assert.False(t, forced)
+	assert.NotNil(t, cmd.ProcessState, "process was reaped")
+}
+
+// TestStopSubprocess_ForceKill verifies a process ignoring the stop signal is
+// killed once the timeout passes.
+func TestStopSubprocess_ForceKill(t *testing.T) {
+	cmd := startProcess(t, `trap "" TERM; while :; do sleep 0.1; done`)
+	time.Sleep(100 * time.Millisecond) // let the shell install the trap
+
+	start := time.Now()
+	forced, err := launcher.StopSubprocess(cmd, 200*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, forced)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.NotNil(t, cmd.ProcessState, "process was reaped")
+}
+
+// TestStopSubprocess_AlreadyExited verifies stopping a finished or never
+// started process is not an error.
+func TestStopSubprocess_AlreadyExited(t *testing.T) {
+	forced, err := launcher.StopSubprocess(nil, time.Second)
+	require.NoError(t, err)
+	assert.False(t, forced)
+
+	cmd := startProcess(t, "exit 0")
+	time.Sleep(100 * time.Millisecond)
+	forced, err = launcher.StopSubprocess(cmd, time.Second)
+	require.NoError(t, err)
+	assert.False(t, forced)
+}
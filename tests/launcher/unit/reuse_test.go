@@ -0,0 +1,69 @@
+package unit
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+// healthServer serves a /health reporting configPath and returns its port.
+func healthServer(t *testing.T, configPath string) int {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if configPath == "" {
+			fmt.Fprint(w, `{"status":"ok"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"ok","config":%q}`, configPath)
+	}))
+	t.Cleanup(srv.Close)
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+	return port
+}
+
+// TestCheckReuse verifies a running gateway is shared only when it serves the
+// selected config file.
+func TestCheckReuse(t *testing.T) {
+	dir := t.TempDir()
+	fast := filepath.Join(dir, "fast_setup.yaml")
+	port := healthServer(t, fast)
+
+	require.NoError(t, launcher.CheckReuse(port, fast))
+	require.NoError(t, launcher.CheckReuse(port, filepath.Join(dir, ".", "fast_setup.yaml")))
+
+	err := launcher.CheckReuse(port, filepath.Join(dir, "other.yaml"))
+	var mismatch *launcher.ConfigMismatchError
+	require.True(t, errors.As(err, &mismatch))
+	assert.Equal(t, fast, mismatch.Running)
+	assert.Equal(t, port, mismatch.Port)
+}
+
+// TestCheckReuse_Unknown verifies a gateway that does not report its config
+// passes, and an unreachable one is an error but not a mismatch.
+func TestCheckReuse_Unknown(t *testing.T) {
+	require.NoError(t, launcher.CheckReuse(healthServer(t, ""), "/etc/cg/fast_setup.yaml"))
+
+	srv := httptest.NewServer(http.NotFoundHandler())
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+	srv.Close()
+
+	err := launcher.CheckReuse(port, "/etc/cg/fast_setup.yaml")
+	require.Error(t, err)
+	var mismatch *launcher.ConfigMismatchError
+	assert.False(t, errors.As(err, &mismatch))
+}
@@ -0,0 +1,47 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/launcher"
+)
+
+var sessionTime = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// TestSessionSlug verifies session names become filesystem-safe slugs.
+func TestSessionSlug(t *testing.T) {
+	assert.Equal(t, "fix-bug-123", launcher.SessionSlug("fix bug #123"))
+	assert.Equal(t, "refactor_auth", launcher.SessionSlug("  refactor_auth  "))
+	assert.Equal(t, "a-b", launcher.SessionSlug("a / b!"))
+	assert.Empty(t, launcher.SessionSlug("?!"))
+	assert.Len(t, launcher.SessionSlug(strings.Repeat("x", 100)), 48)
+}
+
+// TestPrepareSessionPath verifies session directories are numbered per agent
+// and a session name is inserted before the timestamp.
+func TestPrepareSessionPath(t *testing.T) {
+	base := t.TempDir()
+
+	first := launcher.PrepareSessionPath(base, "claude_code", "", sessionTime)
+	assert.Equal(t, filepath.Join(base, "claude_code_1_20260101_120000"), first)
+	assert.NoDirExists(t, first, "created lazily by the gateway")
+
+	require.NoError(t, os.Mkdir(first, 0750))
+	require.NoError(t, os.Mkdir(filepath.Join(base, "codex_7_20260101_110000"), 0750))
+
+	named := launcher.PrepareSessionPath(base, "claude_code", "fix bug 123", sessionTime)
+	assert.Equal(t, filepath.Join(base, "claude_code_2_fix-bug-123_20260101_120000"), named)
+
+	require.NoError(t, os.Mkdir(named, 0750))
+	assert.Equal(t, filepath.Join(base, "claude_code_3_20260101_120000"),
+		launcher.PrepareSessionPath(base, "claude_code", "", sessionTime), "named sessions count toward numbering")
+	assert.Equal(t, filepath.Join(base, "session_1_20260101_120000"),
+		launcher.PrepareSessionPath(base, "", "", sessionTime))
+}
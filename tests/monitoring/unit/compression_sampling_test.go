@@ -0,0 +1,107 @@
+package unit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/compresr/context-gateway/internal/monitoring"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	n := 0
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 1024*1024), 1024*1024)
+	for sc.Scan() {
+		n++
+	}
+	require.NoError(t, sc.Err())
+	return n
+}
+
+func logCompressionEvents(tracker *monitoring.Tracker, n int) {
+	for i := 0; i < n; i++ {
+		tracker.LogCompressionComparison(monitoring.CompressionComparison{
+			RequestID:         "req",
+			ToolName:          "Read",
+			OriginalTokens:    100,
+			CompressedTokens:  40,
+			OriginalContent:   strings.Repeat("a", 300),
+			CompressedContent: strings.Repeat("b", 100),
+			Status:            "compressed",
+			EventType:         monitoring.EventTypeToolOutput,
+		})
+	}
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+func TestCompressionLogSampleRate_SamplesLogButCountsAll(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "compression.jsonl")
+	tracker, err := monitoring.NewTracker(monitoring.TelemetryConfig{
+		Enabled:                  true,
+		CompressionLogPath:       logPath,
+		CompressionLogSampleRate: floatPtr(0.1),
+	})
+	require.NoError(t, err)
+
+	const events = 2000
+	logCompressionEvents(tracker, events)
+	totals := tracker.CompressionTotals()
+	require.NoError(t, tracker.Close())
+
+	lines := countLines(t, logPath)
+	assert.InDelta(t, events/10, lines, 60, "~10%% of events should be logged")
+	assert.Equal(t, int64(lines), totals.Logged)
+	assert.Equal(t, int64(events), totals.Events)
+	assert.Equal(t, int64(events*60), totals.TokensSaved)
+	assert.Equal(t, int64(events*200), totals.BytesSaved)
+	assert.Equal(t, 0.1, totals.SampleRate)
+}
+
+func TestCompressionLogSampleRate_DefaultLogsAll(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "compression.jsonl")
+	tracker, err := monitoring.NewTracker(monitoring.TelemetryConfig{
+		Enabled:            true,
+		CompressionLogPath: logPath,
+	})
+	require.NoError(t, err)
+
+	logCompressionEvents(tracker, 50)
+	totals := tracker.CompressionTotals()
+	require.NoError(t, tracker.Close())
+
+	assert.Equal(t, 50, countLines(t, logPath))
+	assert.Equal(t, int64(50), totals.Logged)
+	assert.Equal(t, 1.0, totals.SampleRate)
+
+	tracker.ResetCompressionTotals()
+	assert.Equal(t, monitoring.CompressionTotals{SampleRate: 1}, tracker.CompressionTotals())
+}
+
+func TestCompressionLogSampleRate_ZeroLogsNone(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "compression.jsonl")
+	tracker, err := monitoring.NewTracker(monitoring.TelemetryConfig{
+		Enabled:                  true,
+		CompressionLogPath:       logPath,
+		CompressionLogSampleRate: floatPtr(0),
+	})
+	require.NoError(t, err)
+
+	logCompressionEvents(tracker, 50)
+	totals := tracker.CompressionTotals()
+	require.NoError(t, tracker.Close())
+
+	assert.Equal(t, 0, countLines(t, logPath))
+	assert.Equal(t, int64(0), totals.Logged)
+	assert.Equal(t, int64(50), totals.Events, "totals still count every event")
+	assert.Equal(t, 0.0, totals.SampleRate)
+}
@@ -0,0 +1,36 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/monitoring"
+)
+
+// TestTelemetrySQLite_CloseFlushesQueuedRows verifies rows queued for the
+// background writer are all in the database once the tracker is closed, and
+// recording after Close is a no-op.
+func TestTelemetrySQLite_CloseFlushesQueuedRows(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "telemetry.db")
+	tracker, err := monitoring.NewTracker(monitoring.TelemetryConfig{Enabled: true, SQLitePath: dbPath})
+	require.NoError(t, err)
+
+	const n = 200
+	for i := 0; i < n; i++ {
+		tracker.RecordRequest(&monitoring.RequestEvent{RequestID: fmt.Sprintf("req-%d", i), Success: true})
+	}
+	require.NoError(t, tracker.Close())
+	tracker.RecordRequest(&monitoring.RequestEvent{RequestID: "after-close"})
+
+	_, rows, err := monitoring.QueryTelemetrySQLite(context.Background(), dbPath, "SELECT request_id FROM requests ORDER BY id")
+	require.NoError(t, err)
+	require.Len(t, rows, n)
+	for i, row := range rows {
+		assert.Equal(t, fmt.Sprintf("req-%d", i), row[0], "rows are written in order")
+	}
+}
@@ -0,0 +1,143 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/notifications"
+)
+
+// recordingServer captures JSON bodies and headers posted to it.
+type recordingServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	bodies  []map[string]any
+	headers []http.Header
+}
+
+func newRecordingServer(t *testing.T) *recordingServer {
+	rs := &recordingServer{}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		rs.mu.Lock()
+		rs.bodies = append(rs.bodies, body)
+		rs.headers = append(rs.headers, r.Header.Clone())
+		rs.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+func (rs *recordingServer) received() []map[string]any {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]map[string]any(nil), rs.bodies...)
+}
+
+func TestWebhookNotifier_PayloadShape(t *testing.T) {
+	srv := newRecordingServer(t)
+	n := notifications.NewWebhookNotifier(srv.URL, map[string]string{"Authorization": "Token abc"})
+
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := n.Notify(notifications.Event{
+		Type:      notifications.EventBudgetExceeded,
+		Timestamp: ts,
+		SessionID: "sess-1",
+		Message:   "over budget",
+		Details:   map[string]any{"session_cap": 5.0},
+	})
+	require.NoError(t, err)
+
+	bodies := srv.received()
+	require.Len(t, bodies, 1)
+	assert.Equal(t, map[string]any{
+		"type":       "budget_exceeded",
+		"timestamp":  "2026-01-02T03:04:05Z",
+		"session_id": "sess-1",
+		"message":    "over budget",
+		"details":    map[string]any{"session_cap": 5.0},
+	}, bodies[0])
+	assert.Equal(t, "Token abc", srv.headers[0].Get("Authorization"))
+	assert.Equal(t, "application/json", srv.headers[0].Get("Content-Type"))
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	err := notifications.NewWebhookNotifier(srv.URL, nil).Notify(notifications.Event{Type: "x"})
+	assert.Error(t, err)
+}
+
+func TestDispatcher_FansOutToEnabledNotifiers(t *testing.T) {
+	slack := newRecordingServer(t)
+	discord := newRecordingServer(t)
+	hook := newRecordingServer(t)
+
+	d := notifications.NewDispatcher(config.NotificationsConfig{
+		Slack:   config.SlackConfig{Enabled: true, WebhookURL: slack.URL},
+		Discord: config.DiscordConfig{Enabled: true, WebhookURL: discord.URL},
+		Webhook: config.WebhookConfig{Enabled: true, URL: hook.URL},
+	})
+	require.True(t, d.Enabled())
+
+	d.Notify(notifications.Event{Type: notifications.EventPanic, Message: "boom"})
+	d.Wait()
+
+	require.Len(t, slack.received(), 1)
+	assert.Contains(t, slack.received()[0]["text"], "boom")
+	require.Len(t, discord.received(), 1)
+	assert.Contains(t, discord.received()[0]["content"], "boom")
+	require.Len(t, hook.received(), 1)
+	assert.Equal(t, "panic", hook.received()[0]["type"])
+}
+
+// TestDispatcher_SlackUsesHookWebhook verifies gateway events reach the
+// SLACK_WEBHOOK_URL the hook script uses when webhook_url is not configured.
+func TestDispatcher_SlackUsesHookWebhook(t *testing.T) {
+	slack := newRecordingServer(t)
+	t.Setenv("SLACK_WEBHOOK_URL", slack.URL)
+
+	d := notifications.NewDispatcher(config.NotificationsConfig{Slack: config.SlackConfig{Enabled: true}})
+	require.True(t, d.Enabled())
+
+	d.Notify(notifications.Event{Type: notifications.EventPanic, Message: "boom"})
+	d.Wait()
+	require.Len(t, slack.received(), 1)
+	assert.Contains(t, slack.received()[0]["text"], "boom")
+}
+
+func TestDispatcher_SuppressesRepeatsAndSkipsDisabled(t *testing.T) {
+	hook := newRecordingServer(t)
+	d := notifications.NewDispatcher(config.NotificationsConfig{
+		Slack:   config.SlackConfig{Enabled: false, WebhookURL: hook.URL},
+		Webhook: config.WebhookConfig{Enabled: true, URL: hook.URL},
+	})
+
+	for i := 0; i < 3; i++ {
+		d.Notify(notifications.Event{Type: notifications.EventBudgetExceeded, SessionID: "s1", Message: "over"})
+	}
+	d.Notify(notifications.Event{Type: notifications.EventBudgetExceeded, SessionID: "s2", Message: "over"})
+	d.Wait()
+
+	assert.Len(t, hook.received(), 2, "one per session; disabled slack sends nothing")
+}
+
+func TestDispatcher_NoNotifiers(t *testing.T) {
+	d := notifications.NewDispatcher(config.NotificationsConfig{})
+	assert.False(t, d.Enabled())
+	d.Notify(notifications.Event{Type: "x"})
+	d.Wait()
+}
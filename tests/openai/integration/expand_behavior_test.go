@@ -867,8 +867,22 @@ func extractOpenAIContentHelper(response map[string]interface{}) string {
 		return ""
 	}
 
-	content, _ := message["content"].(string)
-	return content
+	// Content may be a string or an array of text parts
+	switch content := message["content"].(type) {
+	case string:
+		return content
+	case []interface{}:
+		var texts []string
+		for _, p := range content {
+			if part, ok := p.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
 }
 
 // isValidOpenAIResponse checks if response is valid (works with reasoning models that may have empty content)
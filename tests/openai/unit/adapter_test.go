@@ -62,6 +62,71 @@ func TestOpenAI_ApplyToolOutput(t *testing.T) {
 	assert.Equal(t, "compressed summary", outputItem["output"])
 }
 
+func TestOpenAI_ExtractToolOutput_ContentParts(t *testing.T) {
+	adapter := adapters.NewOpenAIAdapter()
+
+	body := []byte(`{
+		"model": "gpt-5",
+		"messages": [
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call_001", "type": "function", "function": {"name": "read_file", "arguments": "{}"}}]},
+			{"role": "tool", "tool_call_id": "call_001", "content": [
+				{"type": "text", "text": "part one"},
+				{"type": "image_url", "image_url": {"url": "data:image/png;base64,AAAA"}},
+				{"type": "text", "text": "part two"}
+			]}
+		]
+	}`)
+
+	extracted, err := adapter.ExtractToolOutput(body)
+
+	require.NoError(t, err)
+	require.Len(t, extracted, 1)
+	assert.Equal(t, "part one\npart two", extracted[0].Content)
+	assert.Equal(t, "read_file", extracted[0].ToolName)
+	assert.Equal(t, 1, extracted[0].MessageIndex)
+}
+
+func TestOpenAI_ApplyToolOutput_ContentParts(t *testing.T) {
+	adapter := adapters.NewOpenAIAdapter()
+
+	body := []byte(`{"model":"gpt-5","messages":[` +
+		`{"role":"assistant","content":null,"tool_calls":[{"id":"call_001","type":"function","function":{"name":"read_file","arguments":"{}"}}]},` +
+		`{"role":"tool","tool_call_id":"call_001","content":[` +
+		`{"type":"text","text":"part one","cache_control":{"type":"ephemeral"}},` +
+		`{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}},` +
+		`{"type":"text","text":"part two"}]}]}`)
+
+	modified, err := adapter.ApplyToolOutput(body, []adapters.CompressedResult{
+		{ID: "call_001", Compressed: "compressed summary", MessageIndex: 1},
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, string(modified),
+		`"content":[{"type":"text","text":"compressed summary","cache_control":{"type":"ephemeral"}},`+
+			`{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]`,
+		"text parts collapse into the first one; other parts keep their bytes")
+
+	// Round-trip: extracting again yields the compressed text
+	extracted, err := adapter.ExtractToolOutput(modified)
+	require.NoError(t, err)
+	require.Len(t, extracted, 1)
+	assert.Equal(t, "compressed summary", extracted[0].Content)
+}
+
+func TestOpenAI_ApplyToolOutput_ResponsesAPIOutputParts(t *testing.T) {
+	adapter := adapters.NewOpenAIAdapter()
+
+	body := []byte(`{"model":"gpt-5","input":[` +
+		`{"type":"function_call","call_id":"call_001","name":"read_file","arguments":"{}"},` +
+		`{"type":"function_call_output","call_id":"call_001","output":[{"type":"input_text","text":"original content"}]}]}`)
+
+	modified, err := adapter.ApplyToolOutput(body, []adapters.CompressedResult{
+		{ID: "call_001", Compressed: "compressed summary", MessageIndex: 1},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, string(modified), `"output":[{"type":"input_text","text":"compressed summary"}]`)
+}
+
 func TestOpenAI_ExtractToolOutput_Multiple(t *testing.T) {
 	adapter := adapters.NewOpenAIAdapter()
 
@@ -429,10 +494,72 @@ func TestOpenAI_ExtractUserQuery_MixedItemTypes(t *testing.T) {
 	assert.Equal(t, "Now what is in line 5?", query, "Should return the last user message in mixed input")
 }
 
+// =============================================================================
+// DUPLICATE TOOL RESULTS
+// =============================================================================
+
+func TestOpenAI_DropDuplicateToolResults_ChatCompletions(t *testing.T) {
+	adapter := adapters.NewOpenAIAdapter()
+
+	body := []byte(`{"model":"gpt-4o","messages":[
+		{"role":"assistant","tool_calls":[{"id":"call_1","type":"function","function":{"name":"ls","arguments":"{}"}}]},
+		{"role":"tool","tool_call_id":"call_1","content":"first"},
+		{"role":"tool","tool_call_id":"call_1","content":"second"},
+		{"role":"user","content":"thanks"}
+	]}`)
+
+	out, dupIDs, err := adapter.DropDuplicateToolResults(body)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"call_1"}, dupIDs)
+
+	var req struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(out, &req))
+	require.Len(t, req.Messages, 3)
+	assert.Equal(t, "second", req.Messages[1]["content"], "the last result is kept")
+}
+
+func TestOpenAI_DropDuplicateToolResults_ResponsesAPI(t *testing.T) {
+	adapter := adapters.NewOpenAIAdapter()
+
+	// The same call_id in separate turns is not a duplicate.
+	body := []byte(`{"model":"gpt-5","input":[
+		{"type":"function_call_output","call_id":"call_1","output":"a"},
+		{"type":"message","role":"user","content":"again"},
+		{"type":"function_call_output","call_id":"call_1","output":"b"}
+	]}`)
+
+	out, dupIDs, err := adapter.DropDuplicateToolResults(body)
+	require.NoError(t, err)
+	assert.Empty(t, dupIDs)
+	assert.Equal(t, body, out)
+}
+
 // =============================================================================
 // ADAPTER INTERFACE COMPLIANCE
 // =============================================================================
 
 func TestOpenAIAdapter_ImplementsInterface(t *testing.T) {
 	var _ adapters.Adapter = adapters.NewOpenAIAdapter()
+	var _ adapters.DuplicateToolResultAdapter = adapters.NewOpenAIAdapter()
+}
+
+func TestOpenAI_ExtractToolOutput_ErrorConvention(t *testing.T) {
+	adapter := adapters.NewOpenAIAdapter()
+
+	body := []byte(`{"model":"gpt-4o","messages":[
+		{"role":"tool","tool_call_id":"call_1","content":"Error: file not found"},
+		{"role":"tool","tool_call_id":"call_2","content":"{\"output\":\"boom\",\"metadata\":{\"exit_code\":2}}"},
+		{"role":"tool","tool_call_id":"call_3","content":"{\"output\":\"ok\",\"metadata\":{\"exit_code\":0}}"},
+		{"role":"tool","tool_call_id":"call_4","content":"no errors found"}
+	]}`)
+
+	extracted, err := adapter.ExtractToolOutput(body)
+	require.NoError(t, err)
+	require.Len(t, extracted, 4)
+	assert.True(t, extracted[0].IsError)
+	assert.True(t, extracted[1].IsError)
+	assert.False(t, extracted[2].IsError)
+	assert.False(t, extracted[3].IsError)
 }
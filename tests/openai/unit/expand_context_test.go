@@ -90,6 +90,70 @@ func TestExpandContext_OpenAI_FullFlow(t *testing.T) {
 	assert.NotEmpty(t, content)
 }
 
+// TestExpandContext_OpenAI_ArrayToolContent tests compression and expand when the
+// tool message content is an array of parts instead of a string.
+func TestExpandContext_OpenAI_ArrayToolContent(t *testing.T) {
+	var callCount atomic.Int32
+	var capturedRequests [][]byte
+
+	mockLLM := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		capturedRequests = append(capturedRequests, body)
+		w.Header().Set("Content-Type", "application/json")
+		if callCount.Add(1) == 1 {
+			w.Write(fixtures.OpenAIResponseWithExpandCall("call_expand_001", extractShadowIDFromRequest(body)))
+		} else {
+			w.Write(fixtures.OpenAIFinalResponse("Found database failures."))
+		}
+	}))
+	defer mockLLM.Close()
+
+	gw := gateway.New(fixtures.SimpleCompressionConfig())
+	gwServer := httptest.NewServer(gw.Handler())
+	defer gwServer.Close()
+
+	var requestBody map[string]interface{}
+	require.NoError(t, json.Unmarshal(fixtures.OpenAIToolResultRequest("gpt-4", ""), &requestBody))
+	messages := requestBody["messages"].([]interface{})
+	messages[2].(map[string]interface{})["content"] = []map[string]interface{}{
+		{"type": "text", "text": fixtures.LargeToolOutput},
+		{"type": "image_url", "image_url": map[string]interface{}{"url": "data:image/png;base64,AAAA"}},
+	}
+	bodyBytes, _ := json.Marshal(requestBody)
+
+	req, err := http.NewRequest("POST", gwServer.URL+"/v1/chat/completions", bytes.NewReader(bodyBytes))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer test-key")
+	req.Header.Set("X-Target-URL", mockLLM.URL)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int32(2), callCount.Load(), "Should have 2 LLM calls for expand flow")
+
+	// First forward: compressed text part with a shadow ref, image part kept, expand_context injected
+	var first map[string]interface{}
+	require.NoError(t, json.Unmarshal(capturedRequests[0], &first))
+	toolContent, ok := first["messages"].([]interface{})[2].(map[string]interface{})["content"].([]interface{})
+	require.True(t, ok, "tool content must stay an array")
+	require.Len(t, toolContent, 2)
+	textPart := toolContent[0].(map[string]interface{})
+	assert.Equal(t, "text", textPart["type"])
+	assert.Contains(t, textPart["text"], "[REF:shadow_")
+	assert.Less(t, len(textPart["text"].(string)), len(fixtures.LargeToolOutput))
+	assert.Equal(t, "image_url", toolContent[1].(map[string]interface{})["type"])
+	assert.Contains(t, string(capturedRequests[0]), `"expand_context"`)
+
+	// Second forward: the expand result carries the original text
+	assert.Contains(t, string(capturedRequests[1]), "SSL certificate validation failed for external API endpoint")
+
+	var response map[string]interface{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	assert.Equal(t, "Found database failures.", extractOpenAIContent(response))
+}
+
 // TestExpandContext_OpenAI_NoExpand tests when OpenAI doesn't request expand.
 func TestExpandContext_OpenAI_NoExpand(t *testing.T) {
 	var callCount atomic.Int32
@@ -184,9 +248,20 @@ func extractOpenAIContent(response map[string]interface{}) string {
 		return ""
 	}
 
-	content, ok := message["content"].(string)
-	if !ok {
-		return ""
+	// Content may be a string or an array of text parts
+	switch content := message["content"].(type) {
+	case string:
+		return content
+	case []interface{}:
+		var texts []string
+		for _, p := range content {
+			if part, ok := p.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					texts = append(texts, text)
+				}
+			}
+		}
+		return strings.Join(texts, "\n")
 	}
-	return content
+	return ""
 }
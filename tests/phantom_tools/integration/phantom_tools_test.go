@@ -15,11 +15,16 @@ package integration
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/adapters"
 )
 
 // =============================================================================
@@ -883,3 +888,213 @@ func TestIntegration_MultiProvider_SameGateway(t *testing.T) {
 	err = json.Unmarshal(respBody2, &resp2Parsed)
 	require.NoError(t, err, "OpenAI response should be valid JSON")
 }
+
+// =============================================================================
+// TOOL_CHOICE: forced / none choices skip phantom tool injection
+// =============================================================================
+
+// TestIntegration_ToolChoice_ForcedSkipsInjection verifies that a client tool_choice
+// forcing a real tool (or "none") is forwarded untouched and expand_context is not
+// injected, while auto/any/required still get it. The forwarded request must keep
+// the forced tool in tools[] so the provider accepts it.
+func TestIntegration_ToolChoice_ForcedSkipsInjection(t *testing.T) {
+	anthropicTools := []map[string]interface{}{
+		{"name": "read_file", "description": "Read a file", "input_schema": map[string]interface{}{"type": "object"}},
+	}
+	openAITools := []map[string]interface{}{
+		{"type": "function", "function": map[string]interface{}{"name": "read_file", "parameters": map[string]interface{}{"type": "object"}}},
+	}
+
+	tests := []struct {
+		name       string
+		openAI     bool
+		toolChoice interface{}
+		wantInject bool
+	}{
+		{"anthropic forced tool", false, map[string]interface{}{"type": "tool", "name": "read_file"}, false},
+		{"anthropic none", false, map[string]interface{}{"type": "none"}, false},
+		{"anthropic any", false, map[string]interface{}{"type": "any"}, true},
+		{"openai forced function", true, map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "read_file"}}, false},
+		{"openai none", true, "none", false},
+		{"openai required", true, "required", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+				if tt.openAI {
+					return openAITextResponse("done")
+				}
+				return anthropicTextResponse("done")
+			})
+			defer mock.close()
+
+			gwServer := createGateway(expandContextConfig())
+			defer gwServer.Close()
+
+			var (
+				resp *http.Response
+				err  error
+			)
+			if tt.openAI {
+				reqBody := openAIRequestWithToolResult(largeToolOutput(1000))
+				reqBody["tools"] = openAITools
+				reqBody["tool_choice"] = tt.toolChoice
+				resp, _, err = sendOpenAIRequest(gwServer.URL, mock.url(), reqBody)
+			} else {
+				reqBody := anthropicRequestWithToolResult(largeToolOutput(1000))
+				reqBody["tools"] = anthropicTools
+				reqBody["tool_choice"] = tt.toolChoice
+				resp, _, err = sendAnthropicRequest(gwServer.URL, mock.url(), reqBody)
+			}
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			requests := mock.getRequests()
+			require.Len(t, requests, 1)
+			forwarded := requests[0].Body
+
+			assert.Equal(t, tt.wantInject, containsToolName(forwarded, "expand_context"))
+			assert.True(t, containsToolName(forwarded, "read_file"), "forced tool must stay in tools[]")
+
+			var got map[string]interface{}
+			require.NoError(t, json.Unmarshal(forwarded, &got))
+			assert.Equal(t, tt.toolChoice, got["tool_choice"], "tool_choice must be forwarded unchanged")
+		})
+	}
+}
+
+// =============================================================================
+// TOOL PAIRING: phantom loop never forwards orphaned tool_use / tool_result
+// =============================================================================
+
+// TestIntegration_PhantomLoop_MixedToolCallsKeepPairing verifies that when the model
+// calls expand_context alongside a real tool, the gateway does not re-forward a
+// history with an unanswered tool_use. The client gets the real tool call instead.
+func TestIntegration_PhantomLoop_MixedToolCallsKeepPairing(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		if callNum == 1 {
+			return []byte(`{"id":"msg_mixed","type":"message","role":"assistant","content":[
+				{"type":"tool_use","id":"toolu_expand_1","name":"expand_context","input":{"id":"shadow_0123456789abcdef"}},
+				{"type":"tool_use","id":"toolu_read_1","name":"read_file","input":{"path":"main.go"}}
+			],"stop_reason":"tool_use","usage":{"input_tokens":100,"output_tokens":20}}`)
+		}
+		return anthropicTextResponse("unexpected re-forward")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	resp, respBody, err := sendAnthropicRequest(gwServer.URL, mock.url(), anthropicRequestWithToolResult(largeToolOutput(1000)))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for i, req := range mock.getRequests() {
+		assert.NoError(t, adapters.ValidateToolPairing(req.Body), "upstream request %d has broken tool pairing", i+1)
+	}
+	assert.Len(t, mock.getRequests(), 1, "mixed phantom/real calls must not be re-forwarded")
+
+	assert.NotContains(t, string(respBody), "expand_context")
+	assert.Contains(t, string(respBody), "toolu_read_1", "real tool call must reach the client")
+}
+
+// TestIntegration_PhantomLoop_TextExpandKeepsPairing verifies that a text-pattern
+// expansion (no tool_use block to answer) is not re-forwarded as an orphaned tool_result.
+func TestIntegration_PhantomLoop_TextExpandKeepsPairing(t *testing.T) {
+	mock := newMockLLM(func(reqBody []byte, callNum int) []byte {
+		if callNum == 1 {
+			return anthropicTextResponse("I need more detail: <<<EXPAND:shadow_0123456789abcdef>>>")
+		}
+		return anthropicTextResponse("unexpected re-forward")
+	})
+	defer mock.close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	resp, _, err := sendAnthropicRequest(gwServer.URL, mock.url(), anthropicRequestWithToolResult(largeToolOutput(1000)))
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for i, req := range mock.getRequests() {
+		assert.NoError(t, adapters.ValidateToolPairing(req.Body), "upstream request %d has broken tool pairing", i+1)
+	}
+}
+
+// anthropicThinkingExpandSSE streams a thinking block followed by an expand_context call.
+func anthropicThinkingExpandSSE(shadowID string) []byte {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_think","type":"message","role":"assistant","model":"claude-sonnet-4","content":[],"usage":{"input_tokens":100,"output_tokens":0}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"thinking","thinking":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"thinking_delta","thinking":"The summary is not enough."}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"signature_delta","signature":"sig_think=="}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_expand_1","name":"expand_context","input":{}}}`,
+		`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"id\":\"` + shadowID + `\"}"}}`,
+		`{"type":"content_block_stop","index":1}`,
+		`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":20}}`,
+		`{"type":"message_stop"}`,
+	}
+	var b bytes.Buffer
+	for _, e := range events {
+		b.WriteString("data: " + e + "\n\n")
+	}
+	return b.Bytes()
+}
+
+// TestIntegration_StreamingExpand_ReplaysThinkingBlocks verifies that when a streamed
+// response with extended thinking calls expand_context, the retry sends the thinking
+// block (signature intact) ahead of the expand_context tool_use, as Anthropic requires.
+func TestIntegration_StreamingExpand_ReplaysThinkingBlocks(t *testing.T) {
+	// Real SSE upstreams are chunked; write through a Flusher so no Content-Length is set.
+	var mu sync.Mutex
+	var requests [][]byte
+	mock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqBody, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, reqBody)
+		callNum := len(requests)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		if callNum == 1 {
+			_, _ = w.Write(anthropicThinkingExpandSSE(extractShadowIDFromRequest(reqBody)))
+			return
+		}
+		_, _ = w.Write([]byte("data: {\"type\":\"message_stop\"}\n\n"))
+	}))
+	defer mock.Close()
+
+	gwServer := createGateway(expandContextConfig())
+	defer gwServer.Close()
+
+	body := anthropicRequestWithToolResult(largeToolOutput(2000))
+	body["model"] = "claude-sonnet-4-20250514" // budget models skip compression
+	body["stream"] = true
+	resp, respBody, err := sendAnthropicRequest(gwServer.URL, mock.URL, body)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotContains(t, string(respBody), "expand_context")
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, requests, 2, "expand_context should trigger one retry")
+	messages := extractMessages(requests[1])
+	require.GreaterOrEqual(t, len(messages), 2)
+	assistant, _ := messages[len(messages)-2].(map[string]interface{})
+	require.Equal(t, "assistant", assistant["role"])
+	content, _ := assistant["content"].([]interface{})
+	require.Len(t, content, 2)
+
+	thinking, _ := content[0].(map[string]interface{})
+	assert.Equal(t, "thinking", thinking["type"])
+	assert.Equal(t, "The summary is not enough.", thinking["thinking"])
+	assert.Equal(t, "sig_think==", thinking["signature"])
+
+	toolUse, _ := content[1].(map[string]interface{})
+	assert.Equal(t, "tool_use", toolUse["type"])
+	assert.Equal(t, "expand_context", toolUse["name"])
+}
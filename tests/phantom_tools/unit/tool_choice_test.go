@@ -0,0 +1,54 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/phantom_tools"
+)
+
+func TestParseToolChoice(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want phantom_tools.ToolChoice
+	}{
+		{"absent", `{"model":"m"}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceAuto}},
+		{"anthropic auto", `{"tool_choice":{"type":"auto"}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceAuto}},
+		{"anthropic any", `{"tool_choice":{"type":"any"}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceAuto}},
+		{"anthropic none", `{"tool_choice":{"type":"none"}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceNone}},
+		{"anthropic tool", `{"tool_choice":{"type":"tool","name":"bash"}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceForced, Name: "bash"}},
+		{"openai auto", `{"tool_choice":"auto"}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceAuto}},
+		{"openai required", `{"tool_choice":"required"}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceAuto}},
+		{"openai none", `{"tool_choice":"none"}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceNone}},
+		{"openai function", `{"tool_choice":{"type":"function","function":{"name":"bash"}}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceForced, Name: "bash"}},
+		{"responses function", `{"tool_choice":{"type":"function","name":"bash"}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceForced, Name: "bash"}},
+		{"responses allowed_tools", `{"tool_choice":{"type":"allowed_tools","mode":"auto","tools":[]}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceForced}},
+		{"responses hosted tool", `{"tool_choice":{"type":"web_search_preview"}}`, phantom_tools.ToolChoice{Mode: phantom_tools.ToolChoiceForced}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, phantom_tools.ParseToolChoice([]byte(tt.body)))
+		})
+	}
+}
+
+func TestAllowsPhantomTools_ForcedPhantomTool(t *testing.T) {
+	body := []byte(`{"tool_choice":{"type":"tool","name":"expand_context"}}`)
+	assert.True(t, phantom_tools.AllowsPhantomTools(body))
+}
+
+func TestInjectAll_RespectsToolChoice(t *testing.T) {
+	forced := []byte(`{"messages":[],"tools":[{"name":"bash","input_schema":{"type":"object"}}],"tool_choice":{"type":"tool","name":"bash"}}`)
+	out, err := phantom_tools.InjectAll(forced, adapters.ProviderAnthropic)
+	assert.NoError(t, err)
+	assert.Equal(t, string(forced), string(out))
+
+	auto := []byte(`{"messages":[],"tools":[{"name":"bash","input_schema":{"type":"object"}}],"tool_choice":{"type":"auto"}}`)
+	out, err = phantom_tools.InjectAll(auto, adapters.ProviderAnthropic)
+	assert.NoError(t, err)
+	assert.True(t, phantom_tools.HasToolByName(out, phantom_tools.ExpandContextToolName))
+}
@@ -0,0 +1,147 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+)
+
+func TestValidateToolPairing(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		orphans    []string
+		unanswered []string
+	}{
+		{
+			name: "anthropic valid",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"bash","input":{}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]}]}`,
+		},
+		{
+			name: "anthropic trailing tool_use is not yet due",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"bash","input":{}}]}]}`,
+		},
+		{
+			name: "anthropic orphaned tool_result",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","content":[{"type":"text","text":"<<<EXPAND:shadow_x>>>"}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"text_expand_0","content":"full"}]}]}`,
+			orphans: []string{"text_expand_0"},
+		},
+		{
+			name: "anthropic stripped tool_use",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","content":[{"type":"text","text":"reading"}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"},{"type":"tool_result","tool_use_id":"t2","content":"ok"}]}]}`,
+			orphans: []string{"t1", "t2"},
+		},
+		{
+			name: "anthropic tool_result before its tool_use",
+			body: `{"messages":[
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"ok"}]},
+				{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"bash","input":{}}]}]}`,
+			orphans: []string{"t1"},
+		},
+		{
+			name: "anthropic mixed phantom and real call only partly answered",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","content":[
+					{"type":"tool_use","id":"t_expand","name":"expand_context","input":{"id":"shadow_x"}},
+					{"type":"tool_use","id":"t_read","name":"read_file","input":{}}]},
+				{"role":"user","content":[{"type":"tool_result","tool_use_id":"t_expand","content":"full"}]}]}`,
+			unanswered: []string{"t_read"},
+		},
+		{
+			name: "anthropic unanswered tool_use before next assistant turn",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"bash","input":{}}]},
+				{"role":"user","content":"never mind"},
+				{"role":"assistant","content":"ok"}]}`,
+			unanswered: []string{"t1"},
+		},
+		{
+			name: "openai valid",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"bash","arguments":"{}"}}]},
+				{"role":"tool","tool_call_id":"c1","content":"ok"}]}`,
+		},
+		{
+			name: "openai orphaned tool message",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","content":"done"},
+				{"role":"tool","tool_call_id":"c9","content":"ok"}]}`,
+			orphans: []string{"c9"},
+		},
+		{
+			name: "openai partly answered tool_calls",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","tool_calls":[
+					{"id":"c1","type":"function","function":{"name":"expand_context","arguments":"{}"}},
+					{"id":"c2","type":"function","function":{"name":"bash","arguments":"{}"}}]},
+				{"role":"tool","tool_call_id":"c1","content":"ok"}]}`,
+			unanswered: []string{"c2"},
+		},
+		{
+			name: "responses valid",
+			body: `{"input":[
+				{"type":"message","role":"user","content":"hi"},
+				{"type":"function_call","call_id":"f1","name":"bash","arguments":"{}"},
+				{"type":"function_call_output","call_id":"f1","output":"ok"}]}`,
+		},
+		{
+			name: "responses orphan and unanswered",
+			body: `{"input":[
+				{"type":"function_call_output","call_id":"f0","output":"ok"},
+				{"type":"function_call","call_id":"f1","name":"bash","arguments":"{}"}]}`,
+			orphans:    []string{"f0"},
+			unanswered: []string{"f1"},
+		},
+		{
+			name: "ollama tool messages pair by position",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","tool_calls":[{"function":{"name":"bash","arguments":{}}}]},
+				{"role":"tool","name":"bash","content":"ok"}]}`,
+		},
+		{
+			name: "id-less tool message answers pending call by position",
+			body: `{"messages":[
+				{"role":"user","content":"hi"},
+				{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"expand_context","arguments":"{}"}}]},
+				{"role":"tool","name":"expand_context","content":"full"}]}`,
+		},
+		{
+			name: "gemini has no call ids",
+			body: `{"contents":[{"role":"model","parts":[{"functionCall":{"name":"bash","args":{}}}]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := adapters.ValidateToolPairing([]byte(tt.body))
+			if len(tt.orphans) == 0 && len(tt.unanswered) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var pairErr *adapters.ToolPairingError
+			require.ErrorAs(t, err, &pairErr)
+			assert.Equal(t, tt.orphans, pairErr.OrphanResults)
+			assert.Equal(t, tt.unanswered, pairErr.UnansweredCalls)
+		})
+	}
+}
@@ -278,6 +278,29 @@ func TestConfig_Validate_StrategyDefaultsToProvider(t *testing.T) {
 	assert.Equal(t, "external_provider", cfg.Summarizer.Strategy)
 }
 
+func TestConfig_Validate_CompactionStrategy(t *testing.T) {
+	cfg := validConfig()
+	cfg.Strategy = "oldest_first"
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "strategy")
+
+	cfg = validConfig()
+	cfg.Strategy = preemptive.CompactionRecencyWindow
+	cfg.KeepRecentTurns = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "keep_recent_turns")
+
+	cfg.KeepRecentTurns = 0
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, preemptive.DefaultKeepRecentTurns, cfg.RecentTurnsToKeep())
+
+	cfg.Strategy = ""
+	cfg.KeepRecentTurns = 6
+	assert.Equal(t, 0, cfg.RecentTurnsToKeep(), "turns ignored without recency_window")
+}
+
 // =============================================================================
 // HELPERS
 // =============================================================================
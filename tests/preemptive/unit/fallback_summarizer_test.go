@@ -0,0 +1,135 @@
+package preemptive_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/preemptive"
+)
+
+// compresrSummarizerConfig points a compresr-strategy summarizer at baseURL.
+func compresrSummarizerConfig(baseURL string) preemptive.SummarizerConfig {
+	return preemptive.SummarizerConfig{
+		Strategy:        preemptive.StrategyCompresr,
+		CompresrBaseURL: baseURL,
+		Compresr: &preemptive.CompresrConfig{
+			Endpoint: "/api/compress/history/",
+			APIKey:   "cmp_test-key-12345",
+			Model:    "hcc_espresso_v1",
+			Timeout:  5 * time.Second,
+		},
+		KeepRecentCount: 3,
+	}
+}
+
+// failingCompresr returns a mock Compresr API that always errors and counts calls.
+func failingCompresr(t *testing.T, calls *int32) string {
+	t.Helper()
+	server := mockCompresrServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = w.Write([]byte(`{"success": false, "error": "summarizer down"}`))
+	})
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+// compactWithFallback runs the OpenAI compaction request through a manager whose
+// primary summarizer always fails.
+func compactWithFallback(t *testing.T, fallback *preemptive.SummarizerConfig) ([]map[string]any, int32) {
+	t.Helper()
+	var primaryCalls int32
+	cfg := createTestConfig()
+	cfg.Summarizer = compresrSummarizerConfig(failingCompresr(t, &primaryCalls))
+	cfg.FallbackSummarizer = fallback
+	cfg.Detectors.Codex = preemptive.CodexDetectorConfig{
+		Enabled:        true,
+		PromptPatterns: preemptive.DefaultCodexPromptPatterns,
+	}
+
+	manager := preemptive.NewManager(cfg)
+	t.Cleanup(manager.Stop)
+
+	out, isCompaction, _, _, err := manager.ProcessRequest(context.Background(), http.Header{}, []byte(openAICompactionBody), "gpt-4o", "openai")
+	require.NoError(t, err)
+	require.True(t, isCompaction)
+
+	var req struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(out, &req))
+	return req.Messages, atomic.LoadInt32(&primaryCalls)
+}
+
+// TestFallbackSummarizer_HandlesPrimaryFailure verifies compaction succeeds with
+// the fallback's summary when the primary summarizer errors.
+func TestFallbackSummarizer_HandlesPrimaryFailure(t *testing.T) {
+	var fallbackCalls int32
+	server := mockCompresrServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		atomic.AddInt32(&fallbackCalls, 1)
+		_ = json.NewEncoder(w).Encode(successResponse("Fallback: user scaffolded a Go project.", 500, 20, 5, 2, 0.96))
+	})
+	defer server.Close()
+	fallback := compresrSummarizerConfig(server.URL)
+
+	messages, primaryCalls := compactWithFallback(t, &fallback)
+
+	assert.Positive(t, primaryCalls, "primary was tried first")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fallbackCalls))
+	require.Len(t, messages, 3, "summary, ack, then the kept assistant turn")
+	summary, _ := messages[0]["content"].(string)
+	assert.Contains(t, summary, "Fallback: user scaffolded a Go project.")
+	assert.Equal(t, "There are two files.", messages[2]["content"])
+}
+
+// TestFallbackSummarizer_TruncatesWhenBothFail verifies the last resort drops
+// the oldest messages instead of failing the compaction.
+func TestFallbackSummarizer_TruncatesWhenBothFail(t *testing.T) {
+	var fallbackCalls int32
+	fallback := compresrSummarizerConfig(failingCompresr(t, &fallbackCalls))
+
+	messages, _ := compactWithFallback(t, &fallback)
+
+	assert.Positive(t, atomic.LoadInt32(&fallbackCalls), "fallback was tried before truncating")
+	require.NotEmpty(t, messages)
+	summary, _ := messages[0]["content"].(string)
+	assert.Contains(t, summary, "earlier messages were dropped")
+	last := messages[len(messages)-1]
+	assert.Equal(t, "There are two files.", last["content"], "recent turns are kept verbatim")
+}
+
+// TestFallbackSummarizer_NotConfigured verifies a failing primary still fails
+// the compaction when no fallback is set.
+func TestFallbackSummarizer_NotConfigured(t *testing.T) {
+	var primaryCalls int32
+	cfg := createTestConfig()
+	cfg.Summarizer = compresrSummarizerConfig(failingCompresr(t, &primaryCalls))
+	cfg.Detectors.Codex = preemptive.CodexDetectorConfig{
+		Enabled:        true,
+		PromptPatterns: preemptive.DefaultCodexPromptPatterns,
+	}
+	manager := preemptive.NewManager(cfg)
+	defer manager.Stop()
+
+	_, _, _, _, err := manager.ProcessRequest(context.Background(), http.Header{}, []byte(openAICompactionBody), "gpt-4o", "openai")
+	assert.Error(t, err)
+}
+
+func TestFallbackSummarizer_Validate(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.FallbackSummarizer = &preemptive.SummarizerConfig{Strategy: preemptive.StrategyCompresr}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "fallback_summarizer.compresr is required")
+
+	fallback := compresrSummarizerConfig("http://localhost")
+	cfg.FallbackSummarizer = &fallback
+	assert.NoError(t, cfg.Validate())
+}
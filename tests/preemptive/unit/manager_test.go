@@ -2,7 +2,10 @@ package preemptive_test
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -348,3 +351,32 @@ func TestManager_ConcurrentRequests(t *testing.T) {
 		}
 	}
 }
+
+// TestManager_UsageUsesModelContextWindow verifies the same token count yields
+// different usage percentages for a 200k-window and a 128k-window model.
+func TestManager_UsageUsesModelContextWindow(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.ModelContextWindows = map[string]int{
+		"model-200k": 200000,
+		"model-128k": 128000,
+	}
+	manager := preemptive.NewManager(cfg)
+
+	usage := func(model string) (string, float64) {
+		content := strings.Repeat("lorem ipsum dolor sit amet ", 2000)
+		body := []byte(fmt.Sprintf(`{"model": %q, "messages": [{"role": "user", "content": %q}]}`, model, model+" "+content))
+		_, _, _, respHeaders, err := manager.ProcessRequest(context.Background(), http.Header{}, body, model, "anthropic")
+		require.NoError(t, err)
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(respHeaders["X-Context-Usage"], "%"), 64)
+		require.NoError(t, err)
+		return respHeaders["X-Context-Tokens"], pct
+	}
+
+	tokens200, pct200 := usage("model-200k")
+	tokens128, pct128 := usage("model-128k")
+
+	assert.True(t, strings.HasSuffix(tokens200, "/200000"), tokens200)
+	assert.True(t, strings.HasSuffix(tokens128, "/128000"), tokens128)
+	assert.Greater(t, pct128, pct200)
+	assert.InDelta(t, 200000.0/128000.0, pct128/pct200, 0.05)
+}
@@ -103,3 +103,47 @@ func TestCalculateUsage_ZeroMax(t *testing.T) {
 	assert.Equal(t, 128000, usage.MaxTokens)
 	assert.InDelta(t, 39.06, usage.UsagePercent, 0.1)
 }
+
+func TestGetModelContextWindow_DatedVariant(t *testing.T) {
+	mw := preemptive.GetModelContextWindow("claude-sonnet-4-20250514")
+
+	assert.Equal(t, "claude-sonnet-4-20250514", mw.Model)
+	assert.Equal(t, 200000, mw.MaxTokens)
+
+	// Longest base key wins
+	assert.Equal(t, 16384, preemptive.GetModelContextWindow("gpt-4o-mini-2024-07-18").OutputMax)
+}
+
+func TestConfigContextWindow_Resolution(t *testing.T) {
+	cfg := preemptive.Config{
+		ModelContextWindows: map[string]int{
+			"gpt-4o":       64000, // overrides built-in; used as-is, no output reservation
+			"custom-model": 32000,
+		},
+	}
+
+	assert.Equal(t, 64000, cfg.ContextWindow("gpt-4o"))
+	assert.Equal(t, 64000, cfg.ContextWindow("gpt-4o-2024-08-06"))
+	assert.Equal(t, 32000, cfg.ContextWindow("custom-model"))
+	assert.Equal(t, 136000, cfg.ContextWindow("claude-sonnet-4-5"), "built-in effective window")
+	assert.Equal(t, preemptive.DefaultUnknownModelContextWindow.EffectiveMax, cfg.ContextWindow("mystery-model"))
+
+	cfg.DefaultContextWindow = 50000
+	assert.Equal(t, 50000, cfg.ContextWindow("mystery-model"))
+
+	cfg.TestContextWindowOverride = 4000
+	assert.Equal(t, 4000, cfg.ContextWindow("gpt-4o"))
+}
+
+func TestConfigContextWindow_Validation(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.ModelContextWindows = map[string]int{"custom-model": 0}
+	assert.Error(t, cfg.Validate())
+
+	cfg.ModelContextWindows = map[string]int{"custom-model": 32000}
+	cfg.DefaultContextWindow = -1
+	assert.Error(t, cfg.Validate())
+
+	cfg.DefaultContextWindow = 0
+	assert.NoError(t, cfg.Validate())
+}
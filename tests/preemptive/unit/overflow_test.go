@@ -0,0 +1,213 @@
+package preemptive_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/preemptive"
+	"github.com/compresr/context-gateway/internal/tokenizer"
+)
+
+// overflowBody builds an Anthropic request with turns user/assistant pairs of
+// ~200 tokens each; the last user message is "latest question".
+func overflowBody(t *testing.T, turns int) []byte {
+	t.Helper()
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 40)
+	var messages []json.RawMessage
+	for i := 0; i < turns; i++ {
+		messages = append(messages,
+			makeMessage("user", fmt.Sprintf("question %d: %s", i, filler)),
+			makeMessage("assistant", fmt.Sprintf("answer %d: %s", i, filler)))
+	}
+	messages = append(messages, makeMessage("user", "latest question"))
+	body, err := json.Marshal(map[string]any{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 1024,
+		"system":     "You are helpful.",
+		"messages":   messages,
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func overflowManager(t *testing.T, cfg preemptive.Config) *preemptive.Manager {
+	t.Helper()
+	require.NoError(t, cfg.Validate())
+	m := preemptive.NewManager(cfg)
+	t.Cleanup(m.Stop)
+	return m
+}
+
+// TestGuardOverflow_WithinWindow verifies a request that fits is forwarded unchanged.
+func TestGuardOverflow_WithinWindow(t *testing.T) {
+	m := overflowManager(t, preemptive.Config{OverflowAction: preemptive.OverflowActionError})
+	body := overflowBody(t, 2)
+
+	out, changed, err := m.GuardOverflow(context.Background(), http.Header{}, body, "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, body, out)
+}
+
+// TestGuardOverflow_Error verifies overflow_action: error rejects an
+// over-window request with an OverflowError naming the sizes.
+func TestGuardOverflow_Error(t *testing.T) {
+	m := overflowManager(t, preemptive.Config{
+		OverflowAction:            preemptive.OverflowActionError,
+		TestContextWindowOverride: 500,
+	})
+	body := overflowBody(t, 5)
+
+	out, changed, err := m.GuardOverflow(context.Background(), http.Header{}, body, "claude-sonnet-4-20250514")
+	require.Error(t, err)
+	assert.Nil(t, out)
+	assert.False(t, changed)
+
+	var overflow *preemptive.OverflowError
+	require.True(t, errors.As(err, &overflow))
+	assert.Equal(t, 500, overflow.Window)
+	assert.Equal(t, tokenizer.CountBytes(body), overflow.Tokens)
+	assert.Contains(t, err.Error(), "500-token context window")
+}
+
+// TestGuardOverflow_ImagesCountAsEstimate verifies base64 image data is not
+// counted as text: a short request with screenshots far larger than the
+// window in encoded bytes is neither rejected nor truncated.
+func TestGuardOverflow_ImagesCountAsEstimate(t *testing.T) {
+	m := overflowManager(t, preemptive.Config{
+		OverflowAction:            preemptive.OverflowActionError,
+		TestContextWindowOverride: 5000,
+	})
+	raw := make([]byte, 300*1024)
+	for i := range raw {
+		raw[i] = byte(i * 31)
+	}
+	image := map[string]any{
+		"type":   "image",
+		"source": map[string]any{"type": "base64", "media_type": "image/png", "data": base64.StdEncoding.EncodeToString(raw)},
+	}
+	body, err := json.Marshal(map[string]any{
+		"model":      "claude-sonnet-4-20250514",
+		"max_tokens": 1024,
+		"messages": []any{map[string]any{
+			"role":    "user",
+			"content": []any{image, image, map[string]any{"type": "text", "text": "what changed between these screenshots?"}},
+		}},
+	})
+	require.NoError(t, err)
+	require.Greater(t, tokenizer.CountBytes(body), 5000, "encoded size alone exceeds the window")
+
+	out, changed, err := m.GuardOverflow(context.Background(), http.Header{}, body, "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, body, out)
+}
+
+// TestGuardOverflow_Unset verifies the guard is off by default, even for an
+// over-window request.
+func TestGuardOverflow_Unset(t *testing.T) {
+	m := overflowManager(t, preemptive.Config{TestContextWindowOverride: 500})
+	body := overflowBody(t, 5)
+
+	out, changed, err := m.GuardOverflow(context.Background(), http.Header{}, body, "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, body, out)
+}
+
+// TestGuardOverflow_Truncate verifies overflow_action: truncate drops the
+// oldest turns behind a note until the request fits, keeping the latest turn
+// and every non-message field.
+func TestGuardOverflow_Truncate(t *testing.T) {
+	m := overflowManager(t, preemptive.Config{
+		OverflowAction:            preemptive.OverflowActionTruncate,
+		TestContextWindowOverride: 700,
+	})
+	body := overflowBody(t, 5)
+
+	out, changed, err := m.GuardOverflow(context.Background(), http.Header{}, body, "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.LessOrEqual(t, tokenizer.CountBytes(out), 700)
+
+	messages := gjson.GetBytes(out, "messages").Array()
+	require.Greater(t, len(messages), 2)
+	assert.Less(t, len(messages), len(gjson.GetBytes(body, "messages").Array()))
+	assert.Contains(t, messages[0].Get("content").String(), "earlier messages were dropped")
+	assert.Equal(t, "latest question", messages[len(messages)-1].Get("content").String())
+	assert.Equal(t, "You are helpful.", gjson.GetBytes(out, "system").String())
+	assert.Equal(t, int64(1024), gjson.GetBytes(out, "max_tokens").Int())
+}
+
+// TestGuardOverflow_TruncateCannotFit verifies truncate returns an error rather
+// than forwarding when even the latest turn exceeds the window.
+func TestGuardOverflow_TruncateCannotFit(t *testing.T) {
+	m := overflowManager(t, preemptive.Config{
+		OverflowAction:            preemptive.OverflowActionTruncate,
+		TestContextWindowOverride: 10,
+	})
+
+	_, _, err := m.GuardOverflow(context.Background(), http.Header{}, overflowBody(t, 3), "claude-sonnet-4-20250514")
+	var overflow *preemptive.OverflowError
+	require.True(t, errors.As(err, &overflow))
+	assert.Equal(t, preemptive.OverflowActionTruncate, overflow.Action)
+	assert.NotEmpty(t, overflow.Reason)
+}
+
+// TestGuardOverflow_Compact verifies overflow_action: compact summarizes the
+// older turns synchronously and forwards summary + recent turns.
+func TestGuardOverflow_Compact(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(mockAnthropicResponse("test summary"))
+	}))
+	defer srv.Close()
+
+	cfg := createTestConfig()
+	cfg.OverflowAction = preemptive.OverflowActionCompact
+	cfg.TestContextWindowOverride = 700
+	cfg.Summarizer.Provider = "anthropic"
+	cfg.Summarizer.Endpoint = srv.URL
+	cfg.Summarizer.Timeout = 5 * time.Second
+	cfg.Summarizer.KeepRecentCount = 2
+	m := overflowManager(t, cfg)
+
+	out, changed, err := m.GuardOverflow(context.Background(), http.Header{}, overflowBody(t, 5), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 1, calls)
+	assert.LessOrEqual(t, tokenizer.CountBytes(out), 700)
+
+	messages := gjson.GetBytes(out, "messages").Array()
+	assert.Contains(t, messages[0].Get("content").String(), "test summary")
+	assert.Equal(t, "latest question", messages[len(messages)-1].Get("content").String())
+}
+
+// TestOverflowAction_Validate verifies overflow_action values and that compact
+// requires the summarizer.
+func TestOverflowAction_Validate(t *testing.T) {
+	cfg := preemptive.Config{OverflowAction: "drop"}
+	assert.Error(t, cfg.Validate())
+
+	cfg.OverflowAction = preemptive.OverflowActionCompact
+	assert.Error(t, cfg.Validate(), "compact needs preemptive.enabled")
+
+	for _, action := range []string{preemptive.OverflowActionNone, preemptive.OverflowActionTruncate, preemptive.OverflowActionError} {
+		cfg.OverflowAction = action
+		assert.NoError(t, cfg.Validate(), action)
+	}
+}
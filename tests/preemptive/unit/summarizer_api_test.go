@@ -3,8 +3,10 @@ package preemptive_test
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -961,3 +963,56 @@ func TestSummarizeViaAPI_ZeroCompressionRatio(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, 1000, result.SummaryTokens)
 }
+
+// =============================================================================
+// RECENCY WINDOW
+// =============================================================================
+
+func TestSummarize_RecencyWindow_LongConversation(t *testing.T) {
+	var messages []json.RawMessage
+	for i := 0; i < 20; i++ {
+		messages = append(messages,
+			makeMessage("user", fmt.Sprintf("question %d", i)),
+			makeContentBlockMessage("assistant", []map[string]interface{}{
+				{"type": "tool_use", "id": fmt.Sprintf("tu_%d", i), "name": "read_file", "input": map[string]interface{}{}},
+			}),
+			makeContentBlockMessage("user", []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": fmt.Sprintf("tu_%d", i), "content": fmt.Sprintf("contents %d", i)},
+			}),
+			makeMessage("assistant", fmt.Sprintf("answer %d", i)),
+		)
+	}
+
+	server := mockCompresrServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		// 3 turns of 4 messages each are kept verbatim
+		assert.Equal(t, float64(12), body["keep_recent"])
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(successResponse("Older questions 0-16 were answered.", 5000, 200, 68, 12, 0.96))
+	})
+	defer server.Close()
+
+	summarizer := newAPISummarizer(server.URL)
+	result, err := summarizer.Summarize(context.Background(), preemptive.SummarizeInput{
+		Messages:        messages,
+		KeepRecentTurns: 3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 67, result.LastSummarizedIndex)
+
+	// Prefix collapses into a single summary; the recent turns follow in order, untouched.
+	resp := preemptive.BuildAnthropicResponse(result.Summary, messages, result.LastSummarizedIndex, "test-model", false)
+	var parsed struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(resp, &parsed))
+	require.Len(t, parsed.Content, 1)
+	text := parsed.Content[0].Text
+	assert.Equal(t, 1, strings.Count(text, "<summary>"))
+	assert.NotContains(t, text, "question 16")
+	for i := 17; i < 20; i++ {
+		assert.Contains(t, text, fmt.Sprintf("[user]: question %d", i))
+		assert.Contains(t, text, fmt.Sprintf("[assistant]: answer %d", i))
+	}
+}
@@ -0,0 +1,106 @@
+package preemptive_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/preemptive"
+)
+
+// countingLLMServer answers with status (a summary on 200) and counts calls and keys seen.
+func countingLLMServer(t *testing.T, status int, calls *atomic.Int32, lastKey *atomic.Value) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		lastKey.Store(r.Header.Get("x-api-key"))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write(mockAnthropicResponse("pooled summary"))
+		} else {
+			w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"bad key"}}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func pooledSummarizer(selection string, endpoints ...preemptive.SummarizerEndpoint) *preemptive.Summarizer {
+	return preemptive.NewSummarizer(preemptive.SummarizerConfig{
+		Strategy:          preemptive.StrategyExternalProvider,
+		Provider:          "anthropic",
+		Model:             "claude-haiku-4-5",
+		MaxTokens:         256,
+		Timeout:           5 * time.Second,
+		Endpoints:         endpoints,
+		EndpointSelection: selection,
+	})
+}
+
+// TestSummarizer_Endpoints_RoundRobin verifies calls alternate across endpoints,
+// each sent with its own key.
+func TestSummarizer_Endpoints_RoundRobin(t *testing.T) {
+	var callsA, callsB atomic.Int32
+	var keyA, keyB atomic.Value
+	a := countingLLMServer(t, http.StatusOK, &callsA, &keyA)
+	b := countingLLMServer(t, http.StatusOK, &callsB, &keyB)
+
+	s := pooledSummarizer(preemptive.EndpointSelectionRoundRobin,
+		preemptive.SummarizerEndpoint{Endpoint: a.URL, ProviderKey: "sk-ant-key-a"},
+		preemptive.SummarizerEndpoint{Endpoint: b.URL, ProviderKey: "sk-ant-key-b"},
+	)
+	for i := 0; i < 6; i++ {
+		out, err := s.Summarize(t.Context(), twoMessages())
+		require.NoError(t, err)
+		assert.Equal(t, "pooled summary", out.Summary)
+	}
+
+	assert.Equal(t, int32(3), callsA.Load())
+	assert.Equal(t, int32(3), callsB.Load())
+	assert.Equal(t, "sk-ant-key-a", keyA.Load())
+	assert.Equal(t, "sk-ant-key-b", keyB.Load())
+}
+
+// TestSummarizer_Endpoints_FailingEndpointSkipped verifies a failing endpoint
+// fails over to the healthy one and is then skipped while cooling down.
+func TestSummarizer_Endpoints_FailingEndpointSkipped(t *testing.T) {
+	var callsBad, callsGood atomic.Int32
+	var keyBad, keyGood atomic.Value
+	bad := countingLLMServer(t, http.StatusUnauthorized, &callsBad, &keyBad)
+	good := countingLLMServer(t, http.StatusOK, &callsGood, &keyGood)
+
+	s := pooledSummarizer("",
+		preemptive.SummarizerEndpoint{Endpoint: bad.URL, ProviderKey: "sk-ant-revoked"},
+		preemptive.SummarizerEndpoint{Endpoint: good.URL, ProviderKey: "sk-ant-good"},
+	)
+	for i := 0; i < 4; i++ {
+		out, err := s.Summarize(t.Context(), twoMessages())
+		require.NoError(t, err)
+		assert.Equal(t, preemptive.SummarizedByPrimary, out.SummarizedBy)
+	}
+
+	assert.Equal(t, int32(1), callsBad.Load(), "failed endpoint is not retried during cooldown")
+	assert.Equal(t, int32(4), callsGood.Load())
+}
+
+// TestSummarizer_Endpoints_AllFail verifies the error when no endpoint succeeds.
+func TestSummarizer_Endpoints_AllFail(t *testing.T) {
+	var calls atomic.Int32
+	var key atomic.Value
+	bad := countingLLMServer(t, http.StatusUnauthorized, &calls, &key)
+
+	s := pooledSummarizer(preemptive.EndpointSelectionLeastLoaded,
+		preemptive.SummarizerEndpoint{Endpoint: bad.URL, ProviderKey: "sk-ant-revoked-1"},
+		preemptive.SummarizerEndpoint{Endpoint: bad.URL, ProviderKey: "sk-ant-revoked-2"},
+	)
+	_, err := s.Summarize(t.Context(), twoMessages())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "all 2 summarizer endpoints failed")
+	assert.Equal(t, int32(2), calls.Load())
+}
@@ -0,0 +1,75 @@
+package preemptive_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/preemptive"
+)
+
+// TestSummarizer_Vertex_EndpointAndBearer verifies a summarizer configured with
+// the vertex provider calls the regional project URL with the OAuth token from
+// the environment as a Bearer header and parses the Gemini-format response.
+// The token is re-read on every call, so a refreshed one is picked up.
+func TestSummarizer_Vertex_EndpointAndBearer(t *testing.T) {
+	t.Setenv(config.VertexTokenEnv, "ya29.test-access-token")
+
+	var gotPath, gotAuth, gotGoogKey string
+	var gotBody map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		gotGoogKey = r.Header.Get("x-goog-api-key")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"candidates": []map[string]interface{}{{
+				"content": map[string]interface{}{
+					"role":  "model",
+					"parts": []map[string]string{{"text": "vertex summary"}},
+				},
+			}},
+			"usageMetadata": map[string]int{"promptTokenCount": 40, "candidatesTokenCount": 5},
+		})
+	}))
+	defer srv.Close()
+
+	cfg := &config.Config{
+		Providers: config.ProvidersConfig{
+			"vertex": {Model: "gemini-2.0-flash", Project: "acme-prod", Region: "europe-west4"},
+		},
+		Preemptive: config.PreemptiveConfig{
+			Summarizer: config.SummarizerConfig{
+				Strategy:  preemptive.StrategyExternalProvider,
+				Provider:  "vertex",
+				MaxTokens: 256,
+				Timeout:   5 * time.Second,
+			},
+		},
+	}
+	sc := cfg.ResolvePreemptiveProvider().Summarizer
+
+	const host = "https://europe-west4-aiplatform.googleapis.com"
+	const path = "/v1/projects/acme-prod/locations/europe-west4/publishers/google/models/gemini-2.0-flash:generateContent"
+	require.Equal(t, host+path, sc.Endpoint)
+	sc.Endpoint = strings.Replace(sc.Endpoint, host, srv.URL, 1)
+
+	// Access tokens expire after ~1h; the refreshed value is used.
+	t.Setenv(config.VertexTokenEnv, "ya29.refreshed-token")
+	out, err := preemptive.NewSummarizer(sc).Summarize(t.Context(), twoMessages())
+	require.NoError(t, err)
+
+	assert.Equal(t, "vertex summary", out.Summary)
+	assert.Equal(t, path, gotPath)
+	assert.Equal(t, "Bearer ya29.refreshed-token", gotAuth)
+	assert.Empty(t, gotGoogKey, "vertex takes no API key header")
+	assert.Contains(t, gotBody, "contents", "request uses the Gemini generateContent format")
+}
@@ -0,0 +1,140 @@
+package preemptive_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/preemptive"
+)
+
+// openAICompactionBody is a Codex-style conversation ending in a compaction
+// prompt. Messages 3-4 are a tool call and its result.
+const openAICompactionBody = `{
+	"model": "gpt-4o",
+	"messages": [
+		{"role": "user", "content": "Set up the project"},
+		{"role": "assistant", "content": "Done, scaffolding created."},
+		{"role": "user", "content": "Now list the files"},
+		{"role": "assistant", "content": null, "tool_calls": [{"id": "call_ls", "type": "function", "function": {"name": "ls", "arguments": "{}"}}]},
+		{"role": "tool", "tool_call_id": "call_ls", "content": "main.go\ngo.mod"},
+		{"role": "assistant", "content": "There are two files."},
+		{"role": "user", "content": "Please summarize the conversation"}
+	]
+}`
+
+// compactOpenAI runs a compaction request through the manager. The mocked
+// Compresr API reports messagesKept messages (counting the compaction prompt)
+// as kept after the summary.
+func compactOpenAI(t *testing.T, inject *bool, messagesKept int) []map[string]any {
+	t.Helper()
+	server := mockCompresrServer(t, func(w http.ResponseWriter, r *http.Request, body map[string]interface{}) {
+		_ = json.NewEncoder(w).Encode(successResponse("User scaffolded a Go project.", 500, 20, 3, messagesKept, 0.96))
+	})
+	t.Cleanup(server.Close)
+
+	cfg := createTestConfig()
+	cfg.InjectSummaryNote = inject
+	cfg.Summarizer = preemptive.SummarizerConfig{
+		Strategy:        preemptive.StrategyCompresr,
+		CompresrBaseURL: server.URL,
+		Compresr: &preemptive.CompresrConfig{
+			Endpoint: "/api/compress/history/",
+			APIKey:   "cmp_test-key-12345",
+			Model:    "hcc_espresso_v1",
+			Timeout:  5 * time.Second,
+		},
+		KeepRecentCount: 3,
+	}
+	cfg.Detectors.Codex = preemptive.CodexDetectorConfig{
+		Enabled:        true,
+		PromptPatterns: preemptive.DefaultCodexPromptPatterns,
+	}
+
+	manager := preemptive.NewManager(cfg)
+	t.Cleanup(manager.Stop)
+
+	out, isCompaction, synthetic, _, err := manager.ProcessRequest(context.Background(), http.Header{}, []byte(openAICompactionBody), "gpt-4o", "openai")
+	require.NoError(t, err)
+	require.True(t, isCompaction)
+	require.Empty(t, synthetic, "OpenAI compaction is forwarded, not answered locally")
+
+	var req struct {
+		Messages []map[string]any `json:"messages"`
+	}
+	require.NoError(t, json.Unmarshal(out, &req))
+	return req.Messages
+}
+
+// TestSummaryNote_LabelsSummaryAndKeepsRecentTurns verifies the outgoing
+// request carries the labeled summary followed by the preserved turns.
+func TestSummaryNote_LabelsSummaryAndKeepsRecentTurns(t *testing.T) {
+	messages := compactOpenAI(t, nil, 2)
+
+	require.Len(t, messages, 3, "summary, ack, then the kept assistant turn")
+	summary, _ := messages[0]["content"].(string)
+	assert.Contains(t, summary, preemptive.SummaryNoteMarker+"\nUser scaffolded a Go project.")
+	assert.Equal(t, "There are two files.", messages[2]["content"])
+}
+
+// TestSummaryNote_Disabled verifies inject_summary_note: false leaves the
+// summary unlabeled.
+func TestSummaryNote_Disabled(t *testing.T) {
+	off := false
+	messages := compactOpenAI(t, &off, 2)
+
+	summary, _ := messages[0]["content"].(string)
+	assert.Contains(t, summary, "User scaffolded a Go project.")
+	assert.NotContains(t, summary, preemptive.SummaryNoteMarker)
+}
+
+// TestSummaryNote_KeepsToolPairsTogether verifies a cut between a tool call
+// and its result moves back so the tool message keeps its call.
+func TestSummaryNote_KeepsToolPairsTogether(t *testing.T) {
+	// Keeping 3 would cut right after the tool call, orphaning its result.
+	messages := compactOpenAI(t, nil, 3)
+
+	require.Len(t, messages, 5, "summary, ack, tool call, tool result, reply")
+	var calls, results []string
+	for _, msg := range messages {
+		if tcs, ok := msg["tool_calls"].([]any); ok {
+			for _, tc := range tcs {
+				calls = append(calls, tc.(map[string]any)["id"].(string))
+			}
+		}
+		if id, ok := msg["tool_call_id"].(string); ok {
+			results = append(results, id)
+		}
+	}
+	assert.Equal(t, []string{"call_ls"}, results)
+	assert.Equal(t, results, calls, "every tool result keeps its call")
+}
+
+func TestLabelSummary_Idempotent(t *testing.T) {
+	once := preemptive.LabelSummary("summary")
+	assert.Equal(t, preemptive.SummaryNoteMarker+"\nsummary", once)
+	assert.Equal(t, once, preemptive.LabelSummary(once))
+}
+
+func TestBuildAnthropicResponse_LabeledSummary(t *testing.T) {
+	msgs := []json.RawMessage{
+		json.RawMessage(`{"role":"user","content":"old"}`),
+		json.RawMessage(`{"role":"assistant","content":"recent reply"}`),
+	}
+	resp := preemptive.BuildAnthropicResponse(preemptive.LabelSummary("old stuff"), msgs, 0, "claude-sonnet-4-5", false)
+
+	var out struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	}
+	require.NoError(t, json.Unmarshal(resp, &out))
+	require.Len(t, out.Content, 1)
+	assert.Contains(t, out.Content[0].Text, "<summary>\n"+preemptive.SummaryNoteMarker+"\nold stuff\n</summary>")
+	assert.Contains(t, out.Content[0].Text, "[assistant]: recent reply")
+}
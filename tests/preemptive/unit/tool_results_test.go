@@ -0,0 +1,235 @@
+package preemptive_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/preemptive"
+)
+
+// =============================================================================
+// TOOL RESULT COMPACTION TESTS
+// =============================================================================
+
+const toolResultNote = "read_file main.go: package main, 3 funcs; grep TODO: 2 hits in server.go"
+
+// toolResultManager returns a manager whose summarizer answers every call with
+// toolResultNote, and a counter of those calls.
+func toolResultManager(t *testing.T, trc preemptive.ToolResultCompactionConfig) (*preemptive.Manager, *int32) {
+	t.Helper()
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(mockAnthropicResponse(toolResultNote))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := createTestConfig()
+	cfg.Summarizer.Provider = "anthropic"
+	cfg.Summarizer.Endpoint = server.URL
+	cfg.ToolResultCompaction = trc
+	manager := preemptive.NewManager(cfg)
+	t.Cleanup(manager.Stop)
+	return manager, &calls
+}
+
+// toolTurn is one user turn in which the assistant calls a tool once.
+func toolTurn(n int, output string) []json.RawMessage {
+	id := fmt.Sprintf("toolu_%d", n)
+	return []json.RawMessage{
+		makeMessage("user", fmt.Sprintf("step %d", n)),
+		makeContentBlockMessage("assistant", []map[string]interface{}{
+			{"type": "tool_use", "id": id, "name": "read_file", "input": map[string]interface{}{"path": fmt.Sprintf("f%d.go", n)}},
+		}),
+		makeContentBlockMessage("user", []map[string]interface{}{
+			{"type": "tool_result", "tool_use_id": id, "content": output},
+		}),
+		makeMessage("assistant", fmt.Sprintf("done with step %d", n)),
+	}
+}
+
+func toolHistoryBody(t *testing.T, outputs ...string) []byte {
+	t.Helper()
+	var messages []json.RawMessage
+	for i, out := range outputs {
+		messages = append(messages, toolTurn(i, out)...)
+	}
+	messages = append(messages, makeMessage("user", "what next?"))
+	body, err := json.Marshal(map[string]interface{}{"model": "claude-sonnet-4-5", "messages": messages})
+	require.NoError(t, err)
+	return body
+}
+
+// waitForCompaction repeats the request until the background note is ready.
+func waitForCompaction(t *testing.T, manager *preemptive.Manager, body []byte, sessionID string, archive preemptive.ToolResultArchive) []byte {
+	t.Helper()
+	var out []byte
+	require.Eventually(t, func() bool {
+		var ok bool
+		out, ok = manager.CompactToolResults(body, sessionID, archive)
+		return ok
+	}, 5*time.Second, 10*time.Millisecond)
+	return out
+}
+
+func toolResultContent(body []byte, msg int) string {
+	return gjson.GetBytes(body, fmt.Sprintf("messages.%d.content.0.content", msg)).String()
+}
+
+func TestCompactToolResults_ConsolidatesOldResults(t *testing.T) {
+	manager, calls := toolResultManager(t, preemptive.ToolResultCompactionConfig{
+		Enabled: true, TriggerTokens: 100, KeepRecentTurns: 2,
+	})
+
+	outputs := []string{
+		strings.Repeat("old output zero ", 100),
+		strings.Repeat("old output one ", 100),
+		strings.Repeat("recent output ", 100),
+	}
+	body := toolHistoryBody(t, outputs...)
+
+	archived := make(map[string]string)
+	archive := func(content string) string {
+		id := fmt.Sprintf("shadow_%d", len(archived))
+		archived[id] = content
+		return id
+	}
+
+	// The note is summarized in the background: the triggering request goes
+	// out unchanged, a later one carries the batch.
+	out, ok := manager.CompactToolResults(body, "session-a", archive)
+	require.False(t, ok)
+	assert.Equal(t, body, out)
+	out = waitForCompaction(t, manager, body, "session-a", archive)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls))
+
+	// Tool results sit at messages 2, 6 and 10; the last turn (with the final
+	// user message) and the one before it are kept.
+	first, second := toolResultContent(out, 2), toolResultContent(out, 6)
+	assert.True(t, strings.HasPrefix(first, "[REF:shadow_0]\n"), first)
+	assert.NotContains(t, first, toolResultNote)
+	assert.True(t, strings.HasPrefix(second, "[REF:shadow_1]\n"+preemptive.ToolResultNoteMarker), second)
+	assert.Contains(t, second, toolResultNote)
+	assert.Equal(t, outputs[2], toolResultContent(out, 10), "recent tool results must be untouched")
+
+	// Originals stay expandable.
+	assert.Equal(t, outputs[0], archived["shadow_0"])
+	assert.Equal(t, outputs[1], archived["shadow_1"])
+
+	// tool_use / tool_result pairing is preserved.
+	assert.Equal(t, "toolu_0", gjson.GetBytes(out, "messages.2.content.0.tool_use_id").String())
+
+	// The client resends the original history: the cached note is reapplied.
+	again, ok := manager.CompactToolResults(body, "session-a", archive)
+	require.True(t, ok)
+	assert.Equal(t, int32(1), atomic.LoadInt32(calls), "cached batch must not be summarized again")
+	assert.Contains(t, toolResultContent(again, 6), toolResultNote)
+}
+
+// TestCompactToolResults_ScopedToSession verifies a note is never applied to
+// another conversation that reuses the same tool call IDs with other content.
+func TestCompactToolResults_ScopedToSession(t *testing.T) {
+	manager, calls := toolResultManager(t, preemptive.ToolResultCompactionConfig{
+		Enabled: true, TriggerTokens: 100, KeepRecentTurns: 2,
+	})
+	bodyA := toolHistoryBody(t, strings.Repeat("session a zero ", 100), strings.Repeat("session a one ", 100), "recent")
+	bodyB := toolHistoryBody(t, strings.Repeat("session b zero ", 100), strings.Repeat("session b one ", 100), "recent")
+
+	waitForCompaction(t, manager, bodyA, "session-a", nil)
+
+	out, ok := manager.CompactToolResults(bodyB, "session-b", nil)
+	require.False(t, ok, "session b must not reuse session a's note")
+	assert.Equal(t, bodyB, out)
+
+	out, ok = manager.CompactToolResults(bodyB, "session-a", nil)
+	require.False(t, ok, "same IDs with different content must not reuse the note")
+	assert.Equal(t, bodyB, out)
+
+	waitForCompaction(t, manager, bodyB, "session-b", nil)
+	assert.GreaterOrEqual(t, atomic.LoadInt32(calls), int32(2), "session b summarized its own batch")
+}
+
+// TestCompactToolResults_KeepsArrayShape verifies array-form content stays an
+// array with its image blocks, and that a tool_result already carrying a
+// hint line before its [REF:id] is not compacted again.
+func TestCompactToolResults_KeepsArrayShape(t *testing.T) {
+	manager, _ := toolResultManager(t, preemptive.ToolResultCompactionConfig{
+		Enabled: true, TriggerTokens: 100, KeepRecentTurns: 1,
+	})
+
+	image := map[string]interface{}{"type": "image", "source": map[string]interface{}{"type": "base64", "media_type": "image/png", "data": "iVBORw0KGgo="}}
+	hinted := "[expand_context available]\n[REF:shadow_prior]\nearlier summary"
+	messages := []json.RawMessage{
+		makeMessage("user", "step 0"),
+		makeContentBlockMessage("assistant", []map[string]interface{}{
+			{"type": "tool_use", "id": "toolu_0", "name": "screenshot", "input": map[string]interface{}{}},
+			{"type": "tool_use", "id": "toolu_1", "name": "read_file", "input": map[string]interface{}{}},
+		}),
+		makeContentBlockMessage("user", []map[string]interface{}{
+			{"type": "tool_result", "tool_use_id": "toolu_0", "content": []map[string]interface{}{
+				{"type": "text", "text": strings.Repeat("screen text ", 100)},
+				image,
+			}},
+			{"type": "tool_result", "tool_use_id": "toolu_1", "content": hinted},
+		}),
+		makeMessage("assistant", "done"),
+		makeMessage("user", "what next?"),
+	}
+	body, err := json.Marshal(map[string]interface{}{"model": "claude-sonnet-4-5", "messages": messages})
+	require.NoError(t, err)
+
+	out := waitForCompaction(t, manager, body, "session-a", nil)
+
+	content := gjson.GetBytes(out, "messages.2.content.0.content")
+	require.True(t, content.IsArray(), content.Raw)
+	assert.Equal(t, "text", content.Get("0.type").String())
+	assert.Contains(t, content.Get("0.text").String(), toolResultNote)
+	imageJSON, err := json.Marshal(image)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(imageJSON), content.Get("1").Raw, "image block kept")
+	assert.Equal(t, hinted, gjson.GetBytes(out, "messages.2.content.1.content").String(), "hinted shadow left alone")
+}
+
+func TestCompactToolResults_BelowTrigger(t *testing.T) {
+	manager, calls := toolResultManager(t, preemptive.ToolResultCompactionConfig{
+		Enabled: true, TriggerTokens: 100000, KeepRecentTurns: 1,
+	})
+	body := toolHistoryBody(t, "small output", "another small output")
+
+	out, ok := manager.CompactToolResults(body, "session-a", nil)
+	assert.False(t, ok)
+	assert.Equal(t, body, out)
+	assert.Equal(t, int32(0), atomic.LoadInt32(calls))
+}
+
+func TestCompactToolResults_Disabled(t *testing.T) {
+	manager, calls := toolResultManager(t, preemptive.ToolResultCompactionConfig{TriggerTokens: 1})
+	body := toolHistoryBody(t, strings.Repeat("x ", 500), "y")
+
+	out, ok := manager.CompactToolResults(body, "session-a", nil)
+	assert.False(t, ok)
+	assert.Equal(t, body, out)
+	assert.Equal(t, int32(0), atomic.LoadInt32(calls))
+}
+
+func TestToolResultCompactionConfig_Validate(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.ToolResultCompaction = preemptive.ToolResultCompactionConfig{Enabled: true, TriggerTokens: -1}
+	assert.Error(t, cfg.Validate())
+
+	cfg.ToolResultCompaction = preemptive.ToolResultCompactionConfig{Enabled: true}
+	trc := cfg.ToolResultCompaction.WithDefaults()
+	assert.Equal(t, preemptive.DefaultToolResultTriggerTokens, trc.TriggerTokens)
+	assert.Equal(t, preemptive.DefaultKeepRecentTurns, trc.KeepRecentTurns)
+}
@@ -0,0 +1,66 @@
+package preemptive_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/preemptive"
+)
+
+// TestManager_UsageHeaders_ClimbAndTrigger verifies X-CG-Context-Usage-Percent
+// grows as a session's history grows and X-CG-Compaction-Triggered flips to
+// true once usage crosses trigger_threshold.
+func TestManager_UsageHeaders_ClimbAndTrigger(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.Summarizer.Endpoint = "http://127.0.0.1:1/v1/messages" // never reached successfully
+	cfg.ModelContextWindows = map[string]int{"small-window": 20000}
+	manager := preemptive.NewManager(cfg)
+
+	messages := []map[string]string{{"role": "user", "content": "Refactor the billing module."}}
+	var percents []float64
+	var triggered []bool
+	for turn := 0; turn < 6; turn++ {
+		messages = append(messages,
+			map[string]string{"role": "assistant", "content": strings.Repeat("progress note ", 250)},
+			map[string]string{"role": "user", "content": "continue"},
+		)
+		body, err := json.Marshal(map[string]interface{}{"model": "small-window", "messages": messages})
+		require.NoError(t, err)
+
+		_, _, _, headers, err := manager.ProcessRequest(context.Background(), http.Header{}, body, "small-window", "anthropic")
+		require.NoError(t, err)
+		pct, err := strconv.ParseFloat(headers["X-CG-Context-Usage-Percent"], 64)
+		require.NoError(t, err)
+		percents = append(percents, pct)
+		triggered = append(triggered, headers["X-CG-Compaction-Triggered"] == "true")
+	}
+
+	for i := 1; i < len(percents); i++ {
+		assert.Greater(t, percents[i], percents[i-1], "usage climbs with history")
+	}
+	for i, pct := range percents {
+		assert.Equal(t, pct >= cfg.TriggerThreshold, triggered[i], "turn %d at %.1f%%", i, pct)
+	}
+	assert.False(t, triggered[0])
+	assert.True(t, triggered[len(triggered)-1], "threshold crossed by the last turn")
+}
+
+// TestManager_UsageHeaders_Disabled verifies the headers follow add_response_headers.
+func TestManager_UsageHeaders_Disabled(t *testing.T) {
+	cfg := createTestConfig()
+	cfg.AddResponseHeaders = false
+	manager := preemptive.NewManager(cfg)
+
+	body := []byte(`{"model":"claude-sonnet-4-5","messages":[{"role":"user","content":"hi"}]}`)
+	_, _, _, headers, err := manager.ProcessRequest(context.Background(), http.Header{}, body, "claude-sonnet-4-5", "anthropic")
+	require.NoError(t, err)
+	assert.Empty(t, headers["X-CG-Context-Usage-Percent"])
+	assert.Empty(t, headers["X-CG-Compaction-Triggered"])
+}
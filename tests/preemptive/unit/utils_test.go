@@ -234,7 +234,74 @@ func TestFormatMessages(t *testing.T) {
 	assert.Contains(t, result, "assistant")
 }
 
+func TestFormatMessages_NullContentToolCalls(t *testing.T) {
+	msgs := []json.RawMessage{
+		json.RawMessage(`{"role":"user","content":"Check the log"}`),
+		json.RawMessage(`{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"read_file","arguments":"{}"}}]}`),
+		json.RawMessage(`{"role":"tool","tool_call_id":"call_1","content":"auth failed"}`),
+		json.RawMessage(`{"role":"assistant"}`),
+	}
+
+	result := preemptive.FormatMessages(msgs)
+	assert.Contains(t, result, "[Message 2 - assistant]\n[Tool: read_file]")
+	assert.Contains(t, result, "auth failed")
+	assert.NotContains(t, result, "Message 4", "message without content or tool calls is skipped")
+}
+
 func TestFormatMessages_Empty(t *testing.T) {
 	result := preemptive.FormatMessages(nil)
 	assert.Empty(t, result)
 }
+
+// =============================================================================
+// FindRecencyCutoff
+// =============================================================================
+
+func TestFindRecencyCutoff_KeepsWholeTurns(t *testing.T) {
+	msgs := []json.RawMessage{
+		json.RawMessage(`{"role":"user","content":"turn 1"}`),
+		json.RawMessage(`{"role":"assistant","content":"reply 1"}`),
+		json.RawMessage(`{"role":"user","content":"turn 2"}`),
+		json.RawMessage(`{"role":"assistant","content":[{"type":"tool_use","id":"tu_1","name":"read","input":{}}]}`),
+		json.RawMessage(`{"role":"user","content":[{"type":"tool_result","tool_use_id":"tu_1","content":"file"}]}`),
+		json.RawMessage(`{"role":"assistant","content":"reply 2"}`),
+		json.RawMessage(`{"role":"user","content":"turn 3"}`),
+		json.RawMessage(`{"role":"assistant","content":"reply 3"}`),
+	}
+
+	cutoff, err := preemptive.FindRecencyCutoff(msgs, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 5, cutoff)
+
+	// The tool_result at index 4 is not a turn start, so the cut lands
+	// before "turn 2" and the tool_use/tool_result pair stays together.
+	cutoff, err = preemptive.FindRecencyCutoff(msgs, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cutoff)
+}
+
+func TestFindRecencyCutoff_OpenAIToolPairNotSplit(t *testing.T) {
+	msgs := []json.RawMessage{
+		json.RawMessage(`{"role":"user","content":"turn 1"}`),
+		json.RawMessage(`{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function"}]}`),
+		json.RawMessage(`{"role":"user","content":"interjection"}`),
+		json.RawMessage(`{"role":"tool","tool_call_id":"call_1","content":"result"}`),
+		json.RawMessage(`{"role":"assistant","content":"done"}`),
+	}
+
+	// The last user turn starts at index 2, but call_1 is answered at index 3,
+	// so the boundary moves back past the tool call.
+	_, err := preemptive.FindRecencyCutoff(msgs, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not enough turns")
+}
+
+func TestFindRecencyCutoff_NotEnoughTurns(t *testing.T) {
+	msgs := []json.RawMessage{
+		json.RawMessage(`{"role":"user","content":"only turn"}`),
+		json.RawMessage(`{"role":"assistant","content":"reply"}`),
+	}
+
+	_, err := preemptive.FindRecencyCutoff(msgs, 1)
+	require.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package unit
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/gateway"
+	"github.com/compresr/context-gateway/internal/replay"
+	"github.com/compresr/context-gateway/tests/testkit"
+)
+
+func TestMain(m *testing.M) {
+	gateway.EnableLocalHostsForTesting()
+	os.Exit(m.Run())
+}
+
+func baseConfig() *config.Config {
+	return &config.Config{
+		Server: config.ServerConfig{
+			Port:         18080,
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		},
+		Pipes: config.PipesConfig{
+			ToolOutput:    config.ToolOutputPipeConfig{Enabled: false, Strategy: "passthrough", FallbackStrategy: "passthrough"},
+			ToolDiscovery: config.ToolDiscoveryPipeConfig{Enabled: false},
+		},
+		Store:      config.StoreConfig{Type: "memory", TTL: time.Hour},
+		Monitoring: config.MonitoringConfig{LogLevel: "disabled", LogFormat: "json", LogOutput: "discard"},
+	}
+}
+
+func compressingConfig() *config.Config {
+	cfg := baseConfig()
+	cfg.Pipes.ToolOutput = config.ToolOutputPipeConfig{
+		Enabled:                true,
+		Strategy:               "simple",
+		FallbackStrategy:       "passthrough",
+		MinTokens:              25,
+		MaxTokens:              16384,
+		TargetCompressionRatio: 0.1,
+		BypassCostCheck:        true,
+		EnableExpandContext:    true,
+	}
+	return cfg
+}
+
+// toolTurn builds an Anthropic request ending with n tool results.
+func toolTurn(n int) map[string]interface{} {
+	messages := []interface{}{
+		map[string]interface{}{"role": "user", "content": "inspect the build logs"},
+	}
+	for i := 0; i < n; i++ {
+		id := "toolu_" + string(rune('a'+i))
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []interface{}{
+				map[string]interface{}{"type": "tool_use", "id": id, "name": "read_file", "input": map[string]interface{}{"path": id}},
+			}},
+			map[string]interface{}{"role": "user", "content": []interface{}{
+				map[string]interface{}{"type": "tool_result", "tool_use_id": id, "content": testkit.LargeToolOutput(4000) + id},
+			}},
+		)
+	}
+	return map[string]interface{}{"model": "claude-sonnet-4-5", "max_tokens": 1024, "messages": messages}
+}
+
+// captureSession runs two turns through a capturing gateway and returns the session dir.
+func captureSession(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	mock := testkit.NewMockLLM(func([]byte, int) []byte { return testkit.AnthropicTextResponse("ok") })
+	defer mock.Close()
+
+	cfg := baseConfig()
+	cfg.Monitoring.CaptureRequests = true
+	cfg.Monitoring.RequestCapturePath = filepath.Join(dir, "requests.jsonl")
+	gw := testkit.CreateGateway(cfg)
+	defer gw.Close()
+
+	for _, n := range []int{1, 2} {
+		resp, _, err := testkit.SendAnthropicRequest(gw.URL, mock.URL(), toolTurn(n))
+		require.NoError(t, err)
+		require.Equal(t, 200, resp.StatusCode)
+	}
+	return dir
+}
+
+func TestCaptureRequests_WritesReplayableSession(t *testing.T) {
+	dir := captureSession(t)
+
+	data, err := os.ReadFile(filepath.Join(dir, "requests.jsonl"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "sk-ant-test-key", "auth headers must never be captured")
+
+	reqs, err := replay.LoadSession(dir)
+	require.NoError(t, err)
+	require.Len(t, reqs, 2)
+	assert.Equal(t, "/v1/messages", reqs[0].Path)
+	assert.Equal(t, "2023-06-01", reqs[0].Headers["Anthropic-Version"])
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(reqs[1].Body, &body))
+	assert.Len(t, body["messages"], 5)
+}
+
+func TestLoadSession_MissingCapture(t *testing.T) {
+	_, err := replay.LoadSession(t.TempDir())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "capture_requests")
+}
+
+func TestReplay_ComparesConfigs(t *testing.T) {
+	reqs, err := replay.LoadSession(captureSession(t))
+	require.NoError(t, err)
+
+	baseline, err := replay.Run("baseline", baseConfig(), reqs, replay.Options{})
+	require.NoError(t, err)
+	compressed, err := replay.Run("compressed", compressingConfig(), reqs, replay.Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, baseline.Requests)
+	assert.Zero(t, baseline.Failed)
+	assert.Equal(t, 2, baseline.UpstreamCalls)
+	// Passthrough still adds the injected phantom tool schemas, so ratio is slightly above 1.
+	assert.GreaterOrEqual(t, baseline.Ratio(), 1.0)
+
+	assert.Zero(t, compressed.Failed)
+	assert.Equal(t, baseline.OriginalBytes, compressed.OriginalBytes)
+	assert.Less(t, compressed.SentBytes, baseline.SentBytes/2)
+	assert.Zero(t, compressed.ExpandCalls)
+	assert.Zero(t, compressed.BlockedRemote)
+}
+
+func TestReplay_ExpandCallsCounted(t *testing.T) {
+	reqs, err := replay.LoadSession(captureSession(t))
+	require.NoError(t, err)
+
+	baseline, err := replay.Run("baseline", baseConfig(), reqs, replay.Options{Expand: true})
+	require.NoError(t, err)
+	compressed, err := replay.Run("compressed", compressingConfig(), reqs, replay.Options{Expand: true})
+	require.NoError(t, err)
+
+	// Nothing is compressed without the pipe, so nothing needs expanding.
+	assert.Zero(t, baseline.ExpandCalls)
+
+	// One expansion per distinct compressed output; each adds an upstream round trip.
+	assert.Equal(t, 2, compressed.ExpandCalls)
+	assert.Equal(t, 4, compressed.UpstreamCalls)
+	assert.Zero(t, compressed.Failed)
+}
+
+func TestReplay_CompresrStrategyStaysOffline(t *testing.T) {
+	reqs, err := replay.LoadSession(captureSession(t))
+	require.NoError(t, err)
+
+	cfg := compressingConfig()
+	cfg.Pipes.ToolOutput.Strategy = config.StrategyCompresr
+	cfg.Pipes.ToolOutput.Compresr = config.CompresrConfig{Endpoint: "/api/compress/tool-output/", APIKey: "test-key"}
+	cfg.URLs.Compresr = "https://api.compresr.ai"
+
+	notes := replay.OfflineNotes(cfg)
+	require.NotEmpty(t, notes)
+	assert.True(t, strings.Contains(notes[0], "passthrough"))
+
+	res, err := replay.Run("compresr", cfg, reqs, replay.Options{})
+	require.NoError(t, err)
+	assert.Positive(t, res.BlockedRemote)
+	assert.Zero(t, res.Failed)
+	assert.GreaterOrEqual(t, res.Ratio(), 1.0, "compression fell back to passthrough")
+}
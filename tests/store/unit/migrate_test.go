@@ -0,0 +1,89 @@
+package unit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/compresr/context-gateway/internal/store"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate_CopiesLiveEntries(t *testing.T) {
+	src := store.NewMemoryStoreWithDualTTL(time.Hour, 24*time.Hour)
+	defer src.Close()
+	dst := store.NewMemoryStore(time.Minute)
+	defer dst.Close()
+
+	const n = 25
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("shadow_%02d", i)
+		require.NoError(t, src.Set(key, "original "+key))
+		require.NoError(t, src.SetCompressed(key, "compressed "+key))
+	}
+
+	stats, err := store.Migrate(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, 2*n, stats.Migrated)
+	assert.Zero(t, stats.Skipped)
+
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("shadow_%02d", i)
+		original, ok := dst.Get(key)
+		require.True(t, ok, key)
+		assert.Equal(t, "original "+key, original)
+		compressed, ok := dst.GetCompressed(key)
+		require.True(t, ok, key)
+		assert.Equal(t, "compressed "+key, compressed)
+	}
+
+	srcEntries, _ := src.Export()
+	dstEntries, _ := dst.Export()
+	assert.Equal(t, srcEntries, dstEntries, "remaining TTLs are preserved, not reset to the destination's")
+}
+
+func TestMigrate_SkipsExpired(t *testing.T) {
+	src := store.NewMemoryStoreWithDualTTL(10*time.Millisecond, time.Hour)
+	defer src.Close()
+	dst := store.NewMemoryStore(time.Hour)
+	defer dst.Close()
+
+	require.NoError(t, src.Set("short", "gone soon"))
+	require.NoError(t, src.SetCompressed("short", "kept"))
+	time.Sleep(20 * time.Millisecond)
+
+	stats, err := store.Migrate(src, dst)
+	require.NoError(t, err)
+	assert.Equal(t, store.MigrateStats{Migrated: 1, Skipped: 1}, stats)
+
+	_, ok := dst.Get("short")
+	assert.False(t, ok)
+	compressed, ok := dst.GetCompressed("short")
+	require.True(t, ok)
+	assert.Equal(t, "kept", compressed)
+}
+
+// plainStore hides MemoryStore.Import so Migrate falls back to Set/SetCompressed.
+type plainStore struct{ store.Store }
+
+func TestMigrate_DestinationWithoutImporter(t *testing.T) {
+	src := store.NewMemoryStore(time.Hour)
+	defer src.Close()
+	inner := store.NewMemoryStore(time.Hour)
+	defer inner.Close()
+
+	require.NoError(t, src.Set("a", "original"))
+	require.NoError(t, src.SetCompressed("a", "compressed"))
+
+	stats, err := store.Migrate(src, plainStore{inner})
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Migrated)
+
+	original, ok := inner.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, "original", original)
+	compressed, ok := inner.GetCompressed("a")
+	require.True(t, ok)
+	assert.Equal(t, "compressed", compressed)
+}
@@ -312,3 +312,32 @@ func TestMemoryStore_OverwriteDoesNotEvict(t *testing.T) {
 	assert.Equal(t, evictionsBefore, s.Metrics.CompressedEvictions.Load())
 	assert.Equal(t, store.MaxCompressedEntries, s.CompressedSize())
 }
+
+func TestMemoryStore_Snapshot_MetadataOnly(t *testing.T) {
+	s := store.NewMemoryStoreWithDualTTL(1*time.Hour, 24*time.Hour)
+	defer s.Close()
+
+	require.NoError(t, s.Set("shadow_a", "original content"))
+	require.NoError(t, s.SetCompressed("shadow_a", "short"))
+	require.NoError(t, s.SetCompressed("shadow_b", "compressed only"))
+
+	entries := s.Snapshot()
+	require.Len(t, entries, 2)
+
+	assert.Equal(t, "shadow_a", entries[0].ID)
+	assert.True(t, entries[0].HasOriginal)
+	assert.True(t, entries[0].HasCompressed)
+	assert.Equal(t, len("original content"), entries[0].OriginalBytes)
+	assert.Equal(t, len("short"), entries[0].CompressedBytes)
+	assert.Greater(t, entries[0].OriginalTTLSecs, int64(0))
+	assert.Greater(t, entries[0].CompressedTTLSecs, entries[0].OriginalTTLSecs)
+
+	// Compressed without original is visible as such
+	assert.Equal(t, "shadow_b", entries[1].ID)
+	assert.False(t, entries[1].HasOriginal)
+	assert.True(t, entries[1].HasCompressed)
+
+	data, err := json.Marshal(entries)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "original content")
+}
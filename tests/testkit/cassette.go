@@ -0,0 +1,233 @@
+package testkit
+
+// =============================================================================
+// RECORD / REPLAY UPSTREAM
+// =============================================================================
+//
+// A cassette is a JSON fixture of upstream request/response pairs. By default
+// a Cassette replays its fixture from a local mock upstream, so tests that
+// point the gateway at Cassette.URL() run offline and deterministically.
+// Running the same tests with -record (and real API keys) forwards to the real
+// provider instead and rewrites the fixture:
+//
+//	go test ./tests/anthropic/integration -run Replay -record
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/compresr/context-gateway/internal/monitoring"
+)
+
+var recordFlag = flag.Bool("record", false, "record real upstream traffic into cassette fixtures instead of replaying them")
+
+// Recording reports whether tests run with -record.
+func Recording() bool { return *recordFlag }
+
+// cassetteSecretHeaders are request headers whose values are scrubbed from
+// recorded bodies. Headers are never written to a cassette themselves.
+var cassetteSecretHeaders = []string{"x-api-key", "authorization", "x-goog-api-key", "api-key"}
+
+// cassetteResponseHeaders are the only response headers kept in a cassette.
+var cassetteResponseHeaders = []string{"Content-Type"}
+
+// redacted replaces secrets in recorded bodies.
+const redacted = "REDACTED"
+
+// Interaction is one recorded upstream exchange.
+type Interaction struct {
+	Method          string            `json:"method"`
+	Path            string            `json:"path"` // URL path only; query strings may carry keys
+	RequestBody     string            `json:"request_body"`
+	StatusCode      int               `json:"status_code"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	ResponseBody    string            `json:"response_body"`
+}
+
+// Cassette is an upstream that records real traffic (-record) or replays it.
+type Cassette struct {
+	path     string
+	upstream string
+	server   *httptest.Server
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+	secrets      []string
+	errs         []string
+}
+
+// NewCassette starts an upstream backed by the fixture at path. With -record it
+// forwards to upstreamBaseURL (e.g. "https://api.anthropic.com") and Close
+// writes the scrubbed exchanges to path; otherwise path must exist and its
+// exchanges are replayed, each at most once, matched by method, path and body.
+func NewCassette(path, upstreamBaseURL string) (*Cassette, error) {
+	c := &Cassette{path: path, upstream: strings.TrimRight(upstreamBaseURL, "/")}
+	if Recording() {
+		c.server = httptest.NewServer(http.HandlerFunc(c.record))
+		return c, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette (run with -record to create it): %w", err)
+	}
+	if err := json.Unmarshal(data, &c.interactions); err != nil {
+		return nil, fmt.Errorf("parse cassette %s: %w", path, err)
+	}
+	c.used = make([]bool, len(c.interactions))
+	c.server = httptest.NewServer(http.HandlerFunc(c.replay))
+	return c, nil
+}
+
+// URL returns the base URL to use as the gateway's upstream (X-Target-URL).
+func (c *Cassette) URL() string { return c.server.URL }
+
+// Interactions returns the recorded or loaded exchanges.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Interaction(nil), c.interactions...)
+}
+
+// Errors returns replay mismatches: requests that matched no unused exchange.
+func (c *Cassette) Errors() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.errs...)
+}
+
+// Close stops the upstream. In record mode it writes the cassette fixture.
+func (c *Cassette) Close() error {
+	c.server.Close()
+	if !Recording() {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := range c.interactions {
+		c.interactions[i].RequestBody = c.scrub(c.interactions[i].RequestBody)
+		c.interactions[i].ResponseBody = c.scrub(c.interactions[i].ResponseBody)
+	}
+	data, err := json.MarshalIndent(c.interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(c.path, append(data, '\n'), 0600)
+}
+
+// record forwards r to the real upstream and keeps the exchange.
+func (c *Cassette) record(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, c.upstream+r.URL.RequestURI(), bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	req.Header = r.Header.Clone()
+	req.Header.Del("Accept-Encoding") // keep recorded bodies readable
+	resp, err := (&http.Client{Timeout: 5 * time.Minute}).Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	respBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	headers := make(map[string]string)
+	for _, name := range cassetteResponseHeaders {
+		if v := resp.Header.Get(name); v != "" {
+			headers[name] = v
+		}
+	}
+
+	c.mu.Lock()
+	for _, name := range cassetteSecretHeaders {
+		if v := r.Header.Get(name); v != "" {
+			c.secrets = append(c.secrets, v, strings.TrimPrefix(v, "Bearer "))
+		}
+	}
+	c.interactions = append(c.interactions, Interaction{
+		Method:          r.Method,
+		Path:            r.URL.Path,
+		RequestBody:     string(body),
+		StatusCode:      resp.StatusCode,
+		ResponseHeaders: headers,
+		ResponseBody:    string(respBody),
+	})
+	c.mu.Unlock()
+
+	for k, v := range headers {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(resp.StatusCode)
+	w.Write(respBody)
+}
+
+// replay serves the first unused exchange matching r.
+func (c *Cassette) replay(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+
+	c.mu.Lock()
+	match := -1
+	for i, in := range c.interactions {
+		if !c.used[i] && in.Method == r.Method && in.Path == r.URL.Path && sameBody(in.RequestBody, string(body)) {
+			match = i
+			break
+		}
+	}
+	if match < 0 {
+		msg := fmt.Sprintf("no recorded exchange for %s %s (%d bytes)", r.Method, r.URL.Path, len(body))
+		c.errs = append(c.errs, msg)
+		c.mu.Unlock()
+		http.Error(w, "cassette: "+msg, http.StatusNotImplemented)
+		return
+	}
+	c.used[match] = true
+	in := c.interactions[match]
+	c.mu.Unlock()
+
+	for k, v := range in.ResponseHeaders {
+		w.Header().Set(k, v)
+	}
+	w.WriteHeader(in.StatusCode)
+	w.Write([]byte(in.ResponseBody))
+}
+
+// scrub removes recorded credentials from s.
+func (c *Cassette) scrub(s string) string {
+	for _, secret := range c.secrets {
+		if len(secret) >= 8 {
+			s = strings.ReplaceAll(s, secret, redacted)
+		}
+	}
+	return monitoring.SanitizeLogLine(s)
+}
+
+// sameBody compares request bodies as JSON when both parse, else byte-for-byte.
+// Recorded bodies are scrubbed, so secrets in a live body never match.
+func sameBody(recorded, got string) bool {
+	var a, b any
+	if json.Unmarshal([]byte(recorded), &a) == nil && json.Unmarshal([]byte(got), &b) == nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return recorded == got
+}
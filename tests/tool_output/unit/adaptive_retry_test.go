@@ -0,0 +1,187 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// adaptiveCompresr mimics the Compresr tool-output endpoint. responses[i] answers
+// the i-th call (the last one repeats) and each call's target ratio is recorded.
+type adaptiveCompresr struct {
+	mu        sync.Mutex
+	responses []string
+	targets   []float64
+	*httptest.Server
+}
+
+func newAdaptiveCompresr(responses ...string) *adaptiveCompresr {
+	m := &adaptiveCompresr{responses: responses}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			TargetCompressionRatio float64 `json:"target_compression_ratio"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		m.mu.Lock()
+		out := m.responses[min(len(m.targets), len(m.responses)-1)]
+		m.targets = append(m.targets, req.TargetCompressionRatio)
+		m.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"compressed_output": out},
+		})
+	}))
+	return m
+}
+
+func (m *adaptiveCompresr) calls() []float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]float64(nil), m.targets...)
+}
+
+func adaptiveConfig(apiURL string, retries int) *config.Config {
+	return &config.Config{
+		URLs: config.URLsConfig{Compresr: apiURL},
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:                true,
+				Strategy:               config.StrategyCompresr,
+				MinTokens:              10,
+				MaxTokens:              100000,
+				TargetCompressionRatio: 0.5,
+				BypassCostCheck:        true,
+				Compresr: config.CompresrConfig{
+					APIKey:                "test-key",
+					MaxCompressionRetries: retries,
+				},
+			},
+		},
+	}
+}
+
+// runAdaptive compresses one large tool output and returns the text sent onward.
+func runAdaptive(t *testing.T, cfg *config.Config) (string, *pipes.PipeContext, *tooloutput.Pipe) {
+	t.Helper()
+	original := strings.Repeat("=== RUN   TestFunction\n--- PASS: TestFunction (0.01s)\n", 100)
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514",
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "run the tests"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_adaptive", "name": "bash", "input": map[string]string{}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_adaptive", "content": original},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	st := store.NewMemoryStore(0)
+	t.Cleanup(func() { _ = st.Close() })
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+	return gjson.GetBytes(out, "messages.2.content.0.content").String(), ctx, pipe
+}
+
+// weakSummary removes ~20% of the output — short of the 0.5 target.
+func weakSummary() string {
+	return strings.Repeat("=== RUN   TestFunction\n--- PASS: TestFunction (0.01s)\n", 80)
+}
+
+// TestToolOutput_AdaptiveRetryUsesStrongerResult verifies a result that misses the
+// target is retried with a more aggressive target and the stronger result is sent.
+func TestToolOutput_AdaptiveRetryUsesStrongerResult(t *testing.T) {
+	api := newAdaptiveCompresr(weakSummary(), "100 tests passed")
+	defer api.Close()
+
+	sent, ctx, pipe := runAdaptive(t, adaptiveConfig(api.URL, 2))
+
+	assert.Equal(t, "100 tests passed", sent)
+	calls := api.calls()
+	require.Len(t, calls, 2, "target met on the retry, no further calls")
+	assert.InDelta(t, 0.5, calls[0], 0.001)
+	assert.Greater(t, calls[1], calls[0], "retry asks for stronger compression")
+
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	rec := ctx.ToolOutputCompressions[0]
+	assert.Equal(t, "compressed", rec.MappingStatus)
+	assert.Equal(t, 2, rec.Attempts)
+	assert.False(t, rec.TargetMissed)
+
+	m := pipe.GetMetrics()
+	assert.Equal(t, int64(1), m.AdaptiveRetries)
+	assert.Zero(t, m.TargetMissed)
+}
+
+// TestToolOutput_AdaptiveRetryKeepsBestWhenStillMissed verifies retries stop at
+// max_compression_retries, the smallest result wins and the miss is recorded.
+func TestToolOutput_AdaptiveRetryKeepsBestWhenStillMissed(t *testing.T) {
+	better := strings.Repeat("=== RUN   TestFunction\n--- PASS: TestFunction (0.01s)\n", 70)
+	api := newAdaptiveCompresr(weakSummary(), better, weakSummary())
+	defer api.Close()
+
+	sent, ctx, pipe := runAdaptive(t, adaptiveConfig(api.URL, 2))
+
+	assert.Equal(t, better, sent)
+	assert.Len(t, api.calls(), 3, "first call plus two retries")
+
+	rec := ctx.ToolOutputCompressions[0]
+	assert.Equal(t, 3, rec.Attempts)
+	assert.True(t, rec.TargetMissed)
+
+	m := pipe.GetMetrics()
+	assert.Equal(t, int64(2), m.AdaptiveRetries)
+	assert.Equal(t, int64(1), m.TargetMissed)
+}
+
+// TestToolOutput_AdaptiveRetryDisabledByDefault verifies a single API call is
+// made when max_compression_retries is unset.
+func TestToolOutput_AdaptiveRetryDisabledByDefault(t *testing.T) {
+	api := newAdaptiveCompresr(weakSummary(), "100 tests passed")
+	defer api.Close()
+
+	sent, ctx, _ := runAdaptive(t, adaptiveConfig(api.URL, 0))
+
+	assert.Equal(t, weakSummary(), sent)
+	assert.Len(t, api.calls(), 1)
+	assert.Zero(t, ctx.ToolOutputCompressions[0].Attempts)
+}
+
+func TestToolOutputConfig_MaxCompressionRetriesValidation(t *testing.T) {
+	toolOutput := func(retries int) config.ToolOutputPipeConfig {
+		cfg := adaptiveConfig("http://localhost", retries).Pipes.ToolOutput
+		cfg.Compresr.Endpoint = "/api/compress/tool-output/"
+		return cfg
+	}
+	for _, retries := range []int{-1, pipes.MaxCompressionRetriesLimit + 1} {
+		cfg := toolOutput(retries)
+		err := cfg.Validate()
+		require.Error(t, err, fmt.Sprint(retries))
+		assert.Contains(t, err.Error(), "max_compression_retries")
+	}
+	cfg := toolOutput(pipes.MaxCompressionRetriesLimit)
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,82 @@
+package unit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// toolResultRequest is a single bash call whose tool_result carries content.
+func toolResultRequest(t *testing.T, content string) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514",
+		"messages": []map[string]interface{}{
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_replay_1", "name": "bash", "input": map[string]string{}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_replay_1", "content": content},
+			}},
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+// TestToolOutput_AlreadyCompressed_ReplayedWithHint verifies a tool_result
+// compressed in a prior turn (expand hint line before [REF:id]) and replayed by
+// the client is left as-is, not wrapped again, and stays expandable.
+func TestToolOutput_AlreadyCompressed_ReplayedWithHint(t *testing.T) {
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:             true,
+				Strategy:            config.StrategySimple,
+				MinTokens:           10,
+				MaxTokens:           100000,
+				BypassCostCheck:     true,
+				EnableExpandContext: true,
+				IncludeExpandHint:   true,
+			},
+		},
+	}
+	st := store.NewMemoryStore(0)
+	t.Cleanup(func() { _ = st.Close() })
+	pipe := tooloutput.New(cfg, st)
+	original := strings.Repeat("2024-01-01 INFO request served in 12ms\n", 60)
+
+	first := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), toolResultRequest(t, original))
+	first.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(first)
+	require.NoError(t, err)
+	sent := gjson.GetBytes(out, "messages.1.content.0.content").String()
+	require.True(t, strings.HasPrefix(sent, "[COMPRESSED"), "hint precedes [REF:id]: %q", sent)
+	m := refRE.FindStringSubmatch(sent)
+	require.NotNil(t, m)
+	shadowID := m[1]
+
+	replay := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), toolResultRequest(t, sent))
+	replay.TargetModel = "claude-sonnet-4-20250514"
+	out, err = pipe.Process(replay)
+	require.NoError(t, err)
+
+	assert.Equal(t, sent, gjson.GetBytes(out, "messages.1.content.0.content").String())
+	require.Len(t, replay.ToolOutputCompressions, 1)
+	assert.Equal(t, "already_compressed", replay.ToolOutputCompressions[0].MappingStatus)
+	assert.Empty(t, replay.ShadowRefs, "no new shadow ref for a replayed block")
+
+	stored, ok := st.Get(shadowID)
+	require.True(t, ok, "original still expandable")
+	assert.Equal(t, original, stored)
+}
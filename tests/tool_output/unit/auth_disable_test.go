@@ -0,0 +1,74 @@
+package unit
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/compresr/context-gateway/internal/config"
+)
+
+// statusCompresr answers every compression call with status and counts the calls.
+func statusCompresr(t *testing.T, status int, calls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"success": false, "message": "rejected"}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestToolOutput_AuthErrorDisablesAPIForSession verifies a 401 from the
+// compression API is logged as an auth error once, and later outputs in the
+// same session go to the fallback chain without calling the API again.
+func TestToolOutput_AuthErrorDisablesAPIForSession(t *testing.T) {
+	var logs bytes.Buffer
+	prev := log.Logger
+	log.Logger = zerolog.New(&logs)
+	t.Cleanup(func() { log.Logger = prev })
+
+	var calls atomic.Int32
+	api := statusCompresr(t, http.StatusUnauthorized, &calls)
+	pipe := newBudgetPipe(t, api.URL, func(c *config.ToolOutputPipeConfig) {
+		c.FallbackChain = []string{config.StrategyPassthrough}
+	})
+
+	for turn := 0; turn < 3; turn++ {
+		original, sent, _ := budgetTurn(t, pipe, "session-bad-key", turn)
+		assert.Equal(t, original, sent, "turn %d passes through", turn)
+	}
+
+	assert.Equal(t, int32(1), calls.Load(), "no API calls after the 401")
+	assert.Equal(t, 1, bytes.Count(logs.Bytes(), []byte("rejected credentials")), logs.String())
+	assert.Contains(t, logs.String(), `"level":"error"`)
+	assert.Equal(t, int64(3), pipe.GetMetrics().AuthDisabled)
+
+	budgetTurn(t, pipe, "session-other", 3)
+	assert.Equal(t, int32(2), calls.Load(), "other sessions still try the API")
+}
+
+// TestToolOutput_BadRequestNotRetried verifies a 400 from the compression API
+// falls back immediately, without retries, and does not disable the session.
+func TestToolOutput_BadRequestNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	api := statusCompresr(t, http.StatusBadRequest, &calls)
+	pipe := newBudgetPipe(t, api.URL, func(c *config.ToolOutputPipeConfig) {
+		c.FallbackChain = []string{config.StrategyPassthrough}
+	})
+
+	original, sent, _ := budgetTurn(t, pipe, "session-400", 0)
+	assert.Equal(t, original, sent)
+	assert.Equal(t, int32(1), calls.Load(), "400 is not retried")
+
+	budgetTurn(t, pipe, "session-400", 1)
+	assert.Equal(t, int32(2), calls.Load(), "a bad request does not disable the session")
+	assert.Zero(t, pipe.GetMetrics().AuthDisabled)
+}
@@ -0,0 +1,130 @@
+package unit
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/formats"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	rand.New(rand.NewSource(42)).Read(b)
+	return b
+}
+
+func pngContent() string {
+	return "\x89PNG\r\n\x1a\n" + string(randomBytes(4000))
+}
+
+func base64Content() string {
+	return base64.StdEncoding.EncodeToString(randomBytes(4000))
+}
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    bool
+	}{
+		{"png", pngContent(), true},
+		{"gzip header", "\x1f\x8b\x08\x00" + strings.Repeat("x", 100), true},
+		{"null bytes", strings.Repeat("\x00", 1000), true},
+		{"replacement chars", strings.Repeat("�ab", 100), true},
+		{"base64 blob", base64Content(), true},
+		{"wrapped base64", strings.Join(chunk(base64Content(), 76), "\n"), true},
+		{"data uri", "data:image/png;base64," + base64Content(), true},
+		{"plain text", strings.Repeat("--- PASS: TestFunction (0.01s)\n", 100), false},
+		{"json", `{"files": ["a.go", "b.go"], "count": 2}`, false},
+		{"short base64", base64.StdEncoding.EncodeToString([]byte("hello world")), false},
+		{"long hex", strings.Repeat("0123456789abcdef", 32), false},
+		{"empty", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, formats.IsBinary(tt.content))
+		})
+	}
+}
+
+func chunk(s string, n int) []string {
+	var out []string
+	for len(s) > n {
+		out = append(out, s[:n])
+		s = s[n:]
+	}
+	return append(out, s)
+}
+
+// TestToolOutput_BinarySkipped verifies binary and base64 tool results pass
+// through uncompressed with a binary_skipped status, while text is compressed.
+func TestToolOutput_BinarySkipped(t *testing.T) {
+	text := strings.Repeat("=== RUN   TestFunction\n--- PASS: TestFunction (0.01s)\n", 100)
+	outputs := []string{pngContent(), base64Content(), text}
+
+	var messages []map[string]interface{}
+	for i, content := range outputs {
+		id := fmt.Sprintf("toolu_bin_%d", i)
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": id, "name": "read_file", "input": map[string]string{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": id, "content": content},
+			}},
+		)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model":    "claude-sonnet-4-20250514",
+		"messages": messages,
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:         true,
+				Strategy:        config.StrategySimple,
+				MinTokens:       10,
+				MaxTokens:       100000,
+				BypassCostCheck: true,
+			},
+		},
+	}
+	st := store.NewMemoryStore(0)
+	defer st.Close()
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	resultPath := func(i int) string { return fmt.Sprintf("messages.%d.content.0.content", 2*i+1) }
+	original := func(i int) string { return gjson.GetBytes(body, resultPath(i)).String() }
+	sent := func(i int) string { return gjson.GetBytes(out, resultPath(i)).String() }
+	assert.Equal(t, original(0), sent(0), "PNG output passes through")
+	assert.Equal(t, original(1), sent(1), "base64 output passes through")
+	assert.Less(t, len(sent(2)), len(original(2)), "text output is still compressed")
+
+	statuses := map[string]string{}
+	for _, c := range ctx.ToolOutputCompressions {
+		statuses[c.ToolCallID] = c.MappingStatus
+	}
+	assert.Equal(t, "binary_skipped", statuses["toolu_bin_0"])
+	assert.Equal(t, "binary_skipped", statuses["toolu_bin_1"])
+	assert.NotEqual(t, "binary_skipped", statuses["toolu_bin_2"])
+}
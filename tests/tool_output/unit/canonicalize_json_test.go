@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// jsonOutputs builds two semantically equal JSON documents that differ only in
+// key order and whitespace.
+func jsonOutputs() (string, string) {
+	var a, b strings.Builder
+	a.WriteString(`{"items":[`)
+	b.WriteString("{\n  \"total\": 300,\n  \"items\": [\n")
+	for i := 0; i < 300; i++ {
+		if i > 0 {
+			a.WriteString(", ")
+			b.WriteString(",\n")
+		}
+		fmt.Fprintf(&a, `{"id": %d, "name": "file_%d.go", "size": %d}`, i, i, i*10)
+		fmt.Fprintf(&b, `    { "size": %d, "name": "file_%d.go", "id": %d }`, i*10, i, i)
+	}
+	a.WriteString(`], "total": 300}`)
+	b.WriteString("\n  ]\n}")
+	return a.String(), b.String()
+}
+
+// shadowIDsFor runs outputs through a simple-strategy pipe and returns the shadow
+// ID recorded for each tool call, in order.
+func shadowIDsFor(t *testing.T, canonicalize bool, outputs ...string) []string {
+	t.Helper()
+	var messages []map[string]interface{}
+	for i, output := range outputs {
+		id := fmt.Sprintf("toolu_canon_%d", i)
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": id, "name": "list_files", "input": map[string]string{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": id, "content": output},
+			}},
+		)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model":    "claude-sonnet-4-20250514",
+		"messages": messages,
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:             true,
+				Strategy:            config.StrategySimple,
+				MinTokens:           10,
+				MaxTokens:           100000,
+				BypassCostCheck:     true,
+				CanonicalizeJSON:    canonicalize,
+				EnableExpandContext: true,
+			},
+		},
+	}
+	st := store.NewMemoryStore(0)
+	defer st.Close()
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	_, err = pipe.Process(ctx)
+	require.NoError(t, err)
+
+	ids := map[string]string{}
+	for _, c := range ctx.ToolOutputCompressions {
+		ids[c.ToolCallID] = c.ShadowID
+	}
+	var out []string
+	for i := range outputs {
+		id := ids[fmt.Sprintf("toolu_canon_%d", i)]
+		require.NotEmpty(t, id, "output %d should be compressed", i)
+		out = append(out, id)
+	}
+	return out
+}
+
+// TestToolOutput_CanonicalizeJSON verifies JSON outputs differing only in key
+// order and whitespace share a shadow ID with canonicalize_json and do not without.
+func TestToolOutput_CanonicalizeJSON(t *testing.T) {
+	a, b := jsonOutputs()
+
+	on := shadowIDsFor(t, true, a, b)
+	assert.Equal(t, on[0], on[1], "canonicalized JSON shares a shadow ID")
+
+	off := shadowIDsFor(t, false, a, b)
+	assert.NotEqual(t, off[0], off[1], "raw JSON hashes differ")
+}
+
+// TestToolOutput_CanonicalizeJSON_NonJSON verifies text (including text that only
+// looks like JSON at the start) hashes as-is.
+func TestToolOutput_CanonicalizeJSON_NonJSON(t *testing.T) {
+	text := strings.Repeat("line of build output with some detail\n", 200)
+	almost := "{not json} " + text
+
+	on := shadowIDsFor(t, true, text, almost)
+	off := shadowIDsFor(t, false, text, almost)
+	assert.Equal(t, off, on)
+}
@@ -0,0 +1,92 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// errorResultBody has one large failed tool_result and one large successful one.
+func errorResultBody(t *testing.T, output string) []byte {
+	t.Helper()
+	var messages []map[string]interface{}
+	for i, isErr := range []bool{true, false} {
+		id := fmt.Sprintf("toolu_err_%d", i)
+		result := map[string]interface{}{"type": "tool_result", "tool_use_id": id, "content": output}
+		if isErr {
+			result["is_error"] = true
+		}
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": id, "name": "bash", "input": map[string]string{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{result}},
+		)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model":    "claude-sonnet-4-20250514",
+		"messages": messages,
+	})
+	require.NoError(t, err)
+	return body
+}
+
+// TestToolOutput_CompressErrors verifies a large is_error result is forwarded
+// verbatim, still flagged, with compress_errors: false, and compressed by default.
+func TestToolOutput_CompressErrors(t *testing.T) {
+	output := strings.Repeat("panic: runtime error: index out of range [3] with length 3\n\tat main.go:42\n", 100)
+	body := errorResultBody(t, output)
+
+	for _, tc := range []struct {
+		name           string
+		compressErrors *bool
+		wantVerbatim   bool
+	}{
+		{"default", nil, false},
+		{"disabled", new(bool), true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Pipes: config.PipesConfig{
+					ToolOutput: config.ToolOutputPipeConfig{
+						Enabled:         true,
+						Strategy:        config.StrategySimple,
+						MinTokens:       10,
+						MaxTokens:       100000,
+						BypassCostCheck: true,
+						CompressErrors:  tc.compressErrors,
+					},
+				},
+			}
+			st := store.NewMemoryStore(0)
+			defer st.Close()
+			pipe := tooloutput.New(cfg, st)
+
+			ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+			ctx.TargetModel = "claude-sonnet-4-20250514"
+			out, err := pipe.Process(ctx)
+			require.NoError(t, err)
+
+			failed := gjson.GetBytes(out, "messages.1.content.0")
+			assert.True(t, failed.Get("is_error").Bool(), "is_error flag is kept")
+			if tc.wantVerbatim {
+				assert.Equal(t, output, failed.Get("content").String())
+			} else {
+				assert.Less(t, len(failed.Get("content").String()), len(output))
+			}
+			succeeded := gjson.GetBytes(out, "messages.3.content.0.content").String()
+			assert.Less(t, len(succeeded), len(output), "successful result is compressed either way")
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+const noResults = "(no results)"
+
+func placeholderConfig(placeholder string) *config.Config {
+	return &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:                true,
+				Strategy:               config.StrategyPassthrough,
+				EmptyOutputPlaceholder: placeholder,
+			},
+		},
+	}
+}
+
+func runPlaceholderPipe(t *testing.T, placeholder, provider string, body []byte) []byte {
+	t.Helper()
+	st := store.NewMemoryStore(0)
+	defer st.Close()
+	pipe := tooloutput.New(placeholderConfig(placeholder), st)
+	out, err := pipe.Process(pipes.NewPipeContext(adapters.NewRegistry().Get(provider), body))
+	require.NoError(t, err)
+	return out
+}
+
+func TestEmptyOutputPlaceholder_Formats(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		body     string
+		path     string
+	}{
+		{
+			name:     "anthropic empty string",
+			provider: "anthropic",
+			body:     `{"messages":[{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"grep","input":{}}]},{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":""}]}]}`,
+			path:     "messages.1.content.0.content",
+		},
+		{
+			name:     "anthropic whitespace text blocks",
+			provider: "anthropic",
+			body:     `{"messages":[{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":" \n"}]}]}]}`,
+			path:     "messages.0.content.0.content.0.text",
+		},
+		{
+			name:     "openai chat completions",
+			provider: "openai",
+			body:     `{"messages":[{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"grep","arguments":"{}"}}]},{"role":"tool","tool_call_id":"c1","content":"  "}]}`,
+			path:     "messages.1.content",
+		},
+		{
+			name:     "openai responses api",
+			provider: "openai",
+			body:     `{"input":[{"type":"function_call","call_id":"c1","name":"grep","arguments":"{}"},{"type":"function_call_output","call_id":"c1","output":""}]}`,
+			path:     "input.1.output",
+		},
+		{
+			name:     "gemini",
+			provider: "gemini",
+			body:     `{"contents":[{"role":"user","parts":[{"functionResponse":{"name":"grep","response":{"result":""}}}]}]}`,
+			path:     "contents.0.parts.0.functionResponse.response.result",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := runPlaceholderPipe(t, noResults, tt.provider, []byte(tt.body))
+			assert.Equal(t, noResults, gjson.GetBytes(out, tt.path).String())
+
+			unchanged := runPlaceholderPipe(t, "", tt.provider, []byte(tt.body))
+			assert.Equal(t, tt.body, string(unchanged))
+		})
+	}
+}
+
+func TestEmptyOutputPlaceholder_LeavesOtherResults(t *testing.T) {
+	body := `{"messages":[{"role":"user","content":[` +
+		`{"type":"tool_result","tool_use_id":"t1","content":"src/main.go:12: match"},` +
+		`{"type":"tool_result","tool_use_id":"t2","content":"","is_error":true},` +
+		`{"type":"tool_result","tool_use_id":"t3","content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"AAAA"}}]},` +
+		`{"type":"tool_result","tool_use_id":"t4","content":""}]}]}`
+
+	out := runPlaceholderPipe(t, noResults, "anthropic", []byte(body))
+
+	assert.Equal(t, "src/main.go:12: match", gjson.GetBytes(out, "messages.0.content.0.content").String())
+	assert.Equal(t, "", gjson.GetBytes(out, "messages.0.content.1.content").String(), "error results are not rewritten")
+	assert.True(t, gjson.GetBytes(out, "messages.0.content.2.content").IsArray(), "image results are not blank")
+	assert.Equal(t, noResults, gjson.GetBytes(out, "messages.0.content.3.content").String())
+}
@@ -0,0 +1,116 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// compressHinted runs one large tool_result through the pipe with the expand
+// hint enabled and returns the sent content, its shadow ID and the original.
+func compressHinted(t *testing.T, template, position string) (sent, shadowID, original string) {
+	t.Helper()
+	original = strings.Repeat("2024-01-01 INFO request served in 12ms\n", 60)
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514",
+		"messages": []map[string]interface{}{
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_hint_1", "name": "bash", "input": map[string]string{}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_hint_1", "content": original},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:             true,
+				Strategy:            config.StrategySimple,
+				MinTokens:           10,
+				MaxTokens:           100000,
+				BypassCostCheck:     true,
+				EnableExpandContext: true,
+				IncludeExpandHint:   true,
+				ExpandHintTemplate:  template,
+				ExpandHintPosition:  position,
+			},
+		},
+	}
+	pipe := tooloutput.New(cfg, store.NewMemoryStore(0))
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	sent = gjson.GetBytes(out, "messages.1.content.0.content").String()
+	m := refRE.FindStringSubmatch(sent)
+	require.NotNil(t, m, "compressed content carries [REF:id]")
+	return sent, m[1], original
+}
+
+// TestToolOutput_ExpandHintTemplate verifies a custom template is rendered and
+// appended after the compressed content, leaving [REF:id] first.
+func TestToolOutput_ExpandHintTemplate(t *testing.T) {
+	sent, shadowID, original := compressHinted(t, "[{{original_bytes}} bytes elided; expand_context(id={{shadow_id}})]", "")
+
+	want := fmt.Sprintf("[%d bytes elided; expand_context(id=%s)]", len(original), shadowID)
+	assert.True(t, strings.HasPrefix(sent, "[REF:"+shadowID+"]\n"), "got %q", sent)
+	assert.True(t, strings.HasSuffix(sent, "\n"+want), "got %q", sent)
+	assert.NotContains(t, sent, "[COMPRESSED")
+}
+
+// TestToolOutput_ExpandHintTemplate_Before verifies expand_hint_position
+// places a custom hint ahead of the [REF:id] line.
+func TestToolOutput_ExpandHintTemplate_Before(t *testing.T) {
+	sent, shadowID, _ := compressHinted(t, "expand {{shadow_id}} for the rest", pipes.ExpandHintBefore)
+
+	assert.True(t, strings.HasPrefix(sent, "expand "+shadowID+" for the rest\n[REF:"+shadowID+"]\n"), "got %q", sent)
+}
+
+// TestToolOutput_ExpandHintTemplate_Default verifies the built-in hint is
+// unchanged when no template is set.
+func TestToolOutput_ExpandHintTemplate_Default(t *testing.T) {
+	sent, shadowID, _ := compressHinted(t, "", "")
+
+	want := fmt.Sprintf(tooloutput.ExpandHintFormat, shadowID) + fmt.Sprintf(tooloutput.PrefixFormat, shadowID, "")
+	assert.True(t, strings.HasPrefix(sent, want), "got %q", sent)
+}
+
+func TestToolOutput_ExpandHintPosition_Validate(t *testing.T) {
+	cfg := config.ToolOutputPipeConfig{Enabled: true, Strategy: config.StrategySimple, ExpandHintPosition: "middle"}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expand_hint_position")
+}
+
+// TestToolOutput_ExpandHintTemplate_MultiLineBeforeRejected verifies a hint
+// placed before the [REF:id] line must be one line, so replayed blocks are
+// still recognised as already compressed.
+func TestToolOutput_ExpandHintTemplate_MultiLineBeforeRejected(t *testing.T) {
+	cfg := config.ToolOutputPipeConfig{
+		Enabled:            true,
+		Strategy:           config.StrategySimple,
+		ExpandHintTemplate: "compressed\nexpand {{shadow_id}}",
+		ExpandHintPosition: pipes.ExpandHintBefore,
+	}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "single line")
+
+	cfg.ExpandHintPosition = pipes.ExpandHintAfter
+	assert.NoError(t, cfg.Validate(), "multi-line hints are fine after the content")
+}
@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// largeToolOutput returns ~2MB of numbered log lines.
+func largeToolOutput() string {
+	var b strings.Builder
+	for i := 0; b.Len() < 2<<20; i++ {
+		fmt.Fprintf(&b, "line %06d: GET /api/items/%d 200 OK in 12ms\n", i, i)
+	}
+	return b.String()
+}
+
+func hierarchicalPipe(t *testing.T, h config.HierarchicalConfig) (*tooloutput.Pipe, *store.MemoryStore) {
+	t.Helper()
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:             true,
+				Strategy:            config.StrategySimple,
+				MinTokens:           10,
+				MaxTokens:           50000,
+				BypassCostCheck:     true,
+				EnableExpandContext: true,
+				Hierarchical:        h,
+			},
+		},
+	}
+	require.NoError(t, cfg.Pipes.ToolOutput.Validate())
+	st := store.NewMemoryStore(time.Hour)
+	t.Cleanup(func() { _ = st.Close() })
+	return tooloutput.New(cfg, st), st
+}
+
+// TestToolOutput_Hierarchical_LargeOutput verifies a 2MB tool output above
+// max_tokens is compressed chunk by chunk into ordered [part i/n] summaries,
+// and the exact original stays expandable.
+func TestToolOutput_Hierarchical_LargeOutput(t *testing.T) {
+	pipe, st := hierarchicalPipe(t, config.HierarchicalConfig{Enabled: true})
+	original := largeToolOutput()
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), toolResultRequest(t, original))
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	sent := gjson.GetBytes(out, "messages.1.content.0.content").String()
+	assert.Less(t, len(sent), len(original)/10)
+	n := strings.Count(sent, "[part ")
+	require.Greater(t, n, 1, "output is split into several chunks")
+	last := -1
+	for i := 1; i <= n; i++ {
+		pos := strings.Index(sent, fmt.Sprintf("[part %d/%d]", i, n))
+		require.Greater(t, pos, last, "part %d present and in order", i)
+		last = pos
+	}
+	assert.Contains(t, sent, "line 000000", "first chunk summarizes the start of the output")
+
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	rec := ctx.ToolOutputCompressions[0]
+	require.NotEmpty(t, rec.ShadowID)
+	assert.Contains(t, sent, rec.ShadowID)
+	stored, ok := st.Get(rec.ShadowID)
+	require.True(t, ok)
+	assert.Equal(t, original, stored, "expand returns the exact original")
+	assert.Equal(t, int64(1), pipe.GetMetrics().ChunkedOutputs)
+}
+
+// TestToolOutput_Hierarchical_Limits verifies outputs above max_tokens still
+// pass through when hierarchical is off or they need more than max_chunks.
+func TestToolOutput_Hierarchical_Limits(t *testing.T) {
+	original := largeToolOutput()
+	for name, h := range map[string]config.HierarchicalConfig{
+		"disabled":   {},
+		"max_chunks": {Enabled: true, MaxChunks: 2},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pipe, _ := hierarchicalPipe(t, h)
+			ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), toolResultRequest(t, original))
+			ctx.TargetModel = "claude-sonnet-4-20250514"
+			out, err := pipe.Process(ctx)
+			require.NoError(t, err)
+
+			assert.Equal(t, original, gjson.GetBytes(out, "messages.1.content.0.content").String())
+			require.Len(t, ctx.ToolOutputCompressions, 1)
+			assert.Equal(t, "passthrough_large", ctx.ToolOutputCompressions[0].MappingStatus)
+		})
+	}
+}
+
+// TestToolOutput_Hierarchical_SessionBudget verifies a chunked output is
+// charged one API call per chunk: it is compressed when the session budget
+// covers every chunk, and goes to the fallback chain without any API call
+// when it does not.
+func TestToolOutput_Hierarchical_SessionBudget(t *testing.T) {
+	original := largeToolOutput()
+	run := func(maxCalls int) (*adaptiveCompresr, string, pipes.ToolOutputCompression) {
+		api := newAdaptiveCompresr("chunk summary")
+		t.Cleanup(api.Close)
+		cfg := adaptiveConfig(api.URL, 2)
+		cfg.Pipes.ToolOutput.MaxTokens = 50000
+		cfg.Pipes.ToolOutput.MaxCompressionCallsPerSession = maxCalls
+		cfg.Pipes.ToolOutput.Hierarchical = config.HierarchicalConfig{Enabled: true}
+		st := store.NewMemoryStore(time.Hour)
+		t.Cleanup(func() { _ = st.Close() })
+		pipe := tooloutput.New(cfg, st)
+
+		ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), toolResultRequest(t, original))
+		ctx.TargetModel = "claude-sonnet-4-20250514"
+		ctx.SessionID = "session-chunks"
+		out, err := pipe.Process(ctx)
+		require.NoError(t, err)
+		require.Len(t, ctx.ToolOutputCompressions, 1)
+		return api, gjson.GetBytes(out, "messages.1.content.0.content").String(), ctx.ToolOutputCompressions[0]
+	}
+
+	api, sent, rec := run(100)
+	n := strings.Count(sent, "[part ")
+	require.Greater(t, n, 2)
+	assert.Len(t, api.calls(), n, "one call per chunk, no adaptive retries")
+	assert.False(t, rec.BudgetExhausted)
+
+	api, sent, rec = run(2)
+	assert.Empty(t, api.calls(), "budget cannot cover every chunk")
+	assert.Equal(t, original, sent)
+	assert.True(t, rec.BudgetExhausted)
+}
+
+// TestHierarchicalConfig_Validate verifies a negative max_chunks is rejected.
+func TestHierarchicalConfig_Validate(t *testing.T) {
+	cfg := config.ToolOutputPipeConfig{
+		Enabled:      true,
+		Strategy:     config.StrategySimple,
+		Hierarchical: config.HierarchicalConfig{Enabled: true, MaxChunks: -1},
+	}
+	assert.Error(t, cfg.Validate())
+	cfg.Hierarchical.MaxChunks = 0
+	assert.NoError(t, cfg.Validate())
+}
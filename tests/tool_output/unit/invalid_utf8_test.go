@@ -0,0 +1,66 @@
+package unit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/formats"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+	"github.com/compresr/context-gateway/tests/common/fixtures"
+)
+
+// rawToolResultRequest builds an Anthropic request by hand so tool result
+// content can carry raw bytes that json.Marshal would have replaced.
+func rawToolResultRequest(outputs ...string) []byte {
+	var b strings.Builder
+	b.WriteString(`{"model":"claude-sonnet-4-20250514","messages":[{"role":"user","content":"run it"}`)
+	for i, out := range outputs {
+		id := "toolu_raw_" + string(rune('a'+i))
+		b.WriteString(`,{"role":"assistant","content":[{"type":"tool_use","id":"` + id + `","name":"bash","input":{}}]}`)
+		b.WriteString(`,{"role":"user","content":[{"type":"tool_result","tool_use_id":"` + id + `","content":"` + strings.ReplaceAll(out, "\n", `\n`) + `"}]}`)
+	}
+	b.WriteString(`]}`)
+	return []byte(b.String())
+}
+
+// TestToolOutput_InvalidUTF8ProducesValidRequest verifies a tool_result with raw
+// 0xFF 0xFE bytes still yields a valid UTF-8 JSON request when the pipe rewrites
+// the body, whether the block is compressed or left untouched.
+func TestToolOutput_InvalidUTF8ProducesValidRequest(t *testing.T) {
+	large := "\xff\xfe header\n" + strings.Repeat("=== RUN   TestFunction\n--- PASS: TestFunction (0.01s)\n", 100)
+	small := "exit \xff\xfe status"
+	body := rawToolResultRequest(large, small)
+	require.False(t, utf8.Valid(body))
+
+	st := store.NewMemoryStore(0)
+	t.Cleanup(func() { _ = st.Close() })
+	pipe := tooloutput.New(fixtures.SimpleCompressionConfigNoExpand(), st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+	require.True(t, ctx.OutputCompressed, "large output should be rewritten")
+
+	assert.True(t, utf8.Valid(out), "forwarded request must be valid UTF-8")
+	var parsed map[string]any
+	require.NoError(t, json.Unmarshal(out, &parsed))
+
+	compressed := gjson.GetBytes(out, "messages.2.content.0.content").String()
+	assert.True(t, strings.HasPrefix(compressed, "�"), "invalid bytes in compressed block replaced")
+	assert.Equal(t, "exit � status", gjson.GetBytes(out, "messages.4.content.0.content").String())
+}
+
+func TestToValidUTF8(t *testing.T) {
+	valid := []byte(`{"content":"héllo"}`)
+	assert.Same(t, &valid[0], &formats.ToValidUTF8(valid)[0], "valid input is not copied")
+	assert.Equal(t, `{"content":"a`+formats.ReplacementChar+`b"}`, string(formats.ToValidUTF8([]byte("{\"content\":\"a\xff\xfeb\"}"))))
+}
@@ -0,0 +1,70 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// TestToolOutput_LossyMode verifies lossy_mode sends the compressed content with
+// no [REF:id] marker or expand hint, creates no shadow refs, and stores no original.
+func TestToolOutput_LossyMode(t *testing.T) {
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:         true,
+				Strategy:        config.StrategySimple,
+				MinTokens:       10,
+				MaxTokens:       100000,
+				BypassCostCheck: true,
+				LossyMode:       true,
+			},
+		},
+	}
+	st := store.NewMemoryStore(0)
+	t.Cleanup(func() { _ = st.Close() })
+	pipe := tooloutput.New(cfg, st)
+	original := strings.Repeat("2024-01-01 INFO request served in 12ms\n", 60)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), toolResultRequest(t, original))
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	sent := gjson.GetBytes(out, "messages.1.content.0.content").String()
+	assert.Less(t, len(sent), len(original), "content is compressed")
+	assert.NotContains(t, sent, tooloutput.ShadowPrefixMarker)
+	assert.NotContains(t, sent, "expand_context")
+	assert.Empty(t, ctx.ShadowRefs)
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	assert.Empty(t, ctx.ToolOutputCompressions[0].ShadowID)
+	assert.Equal(t, sent, ctx.ToolOutputCompressions[0].CompressedContent)
+
+	for _, e := range st.Snapshot() {
+		assert.False(t, e.HasOriginal, "original stored under %s", e.ID)
+	}
+}
+
+// TestToolOutput_LossyMode_RejectsExpand verifies lossy_mode cannot be combined
+// with expand_context, which would advertise originals that were never kept.
+func TestToolOutput_LossyMode_RejectsExpand(t *testing.T) {
+	cfg := config.ToolOutputPipeConfig{
+		Enabled:             true,
+		Strategy:            config.StrategySimple,
+		LossyMode:           true,
+		EnableExpandContext: true,
+	}
+	require.Error(t, cfg.Validate())
+
+	cfg.EnableExpandContext = false
+	require.NoError(t, cfg.Validate())
+}
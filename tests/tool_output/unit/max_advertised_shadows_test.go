@@ -0,0 +1,175 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+var refRE = regexp.MustCompile(`\[REF:([^\]]+)\]`)
+
+// TestToolOutput_MaxAdvertisedShadows verifies a request with 100 compressed
+// blocks carries the expand_context hint on at most K of them (the largest),
+// while every block keeps its [REF:id] and its original stays retrievable.
+func TestToolOutput_MaxAdvertisedShadows(t *testing.T) {
+	const blocks, k = 100, 10
+
+	var messages []map[string]interface{}
+	originals := make([]string, blocks)
+	for i := 0; i < blocks; i++ {
+		id := fmt.Sprintf("toolu_adv_%03d", i)
+		// Sizes grow with i, so the last k blocks are the largest.
+		originals[i] = strings.Repeat(fmt.Sprintf("block %d line with some log text\n", i), 40+i)
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": id, "name": "bash", "input": map[string]string{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": id, "content": originals[i]},
+			}},
+		)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model":    "claude-sonnet-4-20250514",
+		"messages": messages,
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:              true,
+				Strategy:             config.StrategySimple,
+				MinTokens:            10,
+				MaxTokens:            100000,
+				BypassCostCheck:      true,
+				EnableExpandContext:  true,
+				IncludeExpandHint:    true,
+				MaxAdvertisedShadows: k,
+			},
+		},
+	}
+	st := store.NewMemoryStore(time.Hour)
+	defer st.Close()
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	hinted := 0
+	for i := 0; i < blocks; i++ {
+		sent := gjson.GetBytes(out, fmt.Sprintf("messages.%d.content.0.content", 2*i+1)).String()
+		m := refRE.FindStringSubmatch(sent)
+		require.NotNil(t, m, "block %d keeps its [REF:id]", i)
+		shadowID := m[1]
+
+		if strings.HasPrefix(sent, fmt.Sprintf(tooloutput.ExpandHintFormat, shadowID)) {
+			hinted++
+			assert.GreaterOrEqual(t, i, blocks-k, "only the largest blocks are advertised")
+		}
+
+		original, ok := st.Get(shadowID)
+		require.True(t, ok, "block %d expandable by direct shadow ID", i)
+		assert.Equal(t, originals[i], original)
+	}
+	assert.Equal(t, k, hinted)
+	assert.Len(t, ctx.ShadowRefs, blocks)
+}
+
+// TestToolOutput_MaxAdvertisedShadows_StickyAcrossTurns verifies blocks
+// replayed from history keep the bytes they were first sent with when a later
+// turn adds a larger output: the hint decision is not re-ranked.
+func TestToolOutput_MaxAdvertisedShadows_StickyAcrossTurns(t *testing.T) {
+	const k = 2
+
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:              true,
+				Strategy:             config.StrategySimple,
+				MinTokens:            10,
+				MaxTokens:            100000,
+				BypassCostCheck:      true,
+				EnableExpandContext:  true,
+				IncludeExpandHint:    true,
+				MaxAdvertisedShadows: k,
+			},
+		},
+	}
+	st := store.NewMemoryStore(time.Hour)
+	defer st.Close()
+	pipe := tooloutput.New(cfg, st)
+
+	var messages []map[string]interface{}
+	addBlock := func(i, lines int) {
+		id := fmt.Sprintf("toolu_sticky_%d", i)
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": id, "name": "bash", "input": map[string]string{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": id, "content": strings.Repeat(fmt.Sprintf("block %d line with some log text\n", i), lines)},
+			}},
+		)
+	}
+	process := func() []string {
+		body, err := json.Marshal(map[string]interface{}{
+			"model":    "claude-sonnet-4-20250514",
+			"messages": messages,
+		})
+		require.NoError(t, err)
+		ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+		ctx.TargetModel = "claude-sonnet-4-20250514"
+		ctx.SessionID = "sticky-session"
+		out, err := pipe.Process(ctx)
+		require.NoError(t, err)
+		var sent []string
+		for i := 1; i < len(messages); i += 2 {
+			sent = append(sent, gjson.GetBytes(out, fmt.Sprintf("messages.%d.content.0.content", i)).String())
+		}
+		return sent
+	}
+	isHinted := func(s string) bool {
+		m := refRE.FindStringSubmatch(s)
+		require.NotNil(t, m)
+		return strings.HasPrefix(s, fmt.Sprintf(tooloutput.ExpandHintFormat, m[1]))
+	}
+
+	addBlock(0, 40)
+	addBlock(1, 50)
+	first := process()
+	assert.True(t, isHinted(first[0]))
+	assert.True(t, isHinted(first[1]))
+
+	// A larger output arrives: it would outrank both, but the earlier blocks
+	// keep their hints and bytes, and the cap is still honoured.
+	addBlock(2, 200)
+	second := process()
+	assert.Equal(t, first, second[:2], "history blocks are byte-stable")
+	assert.False(t, isHinted(second[2]), "no slot left for the new block")
+
+	// Replaying the same history again changes nothing.
+	assert.Equal(t, second, process())
+}
+
+func TestToolOutput_MaxAdvertisedShadows_Validate(t *testing.T) {
+	cfg := config.ToolOutputPipeConfig{Enabled: true, Strategy: config.StrategySimple, MaxAdvertisedShadows: -1}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_advertised_shadows")
+}
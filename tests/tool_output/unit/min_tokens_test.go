@@ -0,0 +1,80 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+	"github.com/compresr/context-gateway/internal/tokenizer"
+)
+
+// TestToolOutput_MinTokensCountsTokensNotBytes verifies eligibility follows the
+// token count: a large block that is mostly whitespace stays below min_tokens,
+// while a smaller but token-dense block is compressed.
+func TestToolOutput_MinTokensCountsTokensNotBytes(t *testing.T) {
+	const model = "claude-sonnet-4-20250514"
+	const minTokens = 300
+	if tokenizer.CountTokensForModel(strings.Repeat(" ", 64), model) >= 64 {
+		t.Skip("tiktoken vocabulary unavailable (byte-level fallback); whitespace does not merge")
+	}
+
+	sparse := "header" + strings.Repeat(" ", 4000) + "\n" + strings.Repeat("\n", 4000) + "footer"
+	var b strings.Builder
+	for i := 0; b.Len() < 2500; i++ {
+		fmt.Fprintf(&b, "%x ", i*7919)
+	}
+	dense := b.String()
+
+	require.Greater(t, len(sparse), len(dense))
+	require.LessOrEqual(t, tokenizer.CountTokensForModel(sparse, model), minTokens)
+	require.Greater(t, tokenizer.CountTokensForModel(dense, model), minTokens)
+
+	outputs := []string{sparse, dense}
+	var messages []map[string]interface{}
+	for i, output := range outputs {
+		id := fmt.Sprintf("toolu_min_%d", i)
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": id, "name": "bash", "input": map[string]string{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": id, "content": output},
+			}},
+		)
+	}
+	body, err := json.Marshal(map[string]interface{}{"model": model, "messages": messages})
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:         true,
+				Strategy:        config.StrategySimple,
+				MinTokens:       minTokens,
+				MaxTokens:       100000,
+				BypassCostCheck: true,
+			},
+		},
+	}
+	st := store.NewMemoryStore(0)
+	defer st.Close()
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = model
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	assert.Equal(t, sparse, gjson.GetBytes(out, "messages.1.content.0.content").String(), "whitespace-heavy block is below min_tokens")
+	assert.NotEqual(t, dense, gjson.GetBytes(out, "messages.3.content.0.content").String(), "token-dense block is compressed")
+}
@@ -0,0 +1,132 @@
+package unit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// modelRecorder mimics the Compresr tool-output endpoint and records the
+// compression model each call asked for.
+type modelRecorder struct {
+	mu     sync.Mutex
+	models []string
+	*httptest.Server
+}
+
+func newModelRecorder() *modelRecorder {
+	m := &modelRecorder{}
+	m.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Model string `json:"compression_model_name"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		m.mu.Lock()
+		m.models = append(m.models, req.Model)
+		m.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"data":    map[string]string{"compressed_output": "all tests passed"},
+		})
+	}))
+	return m
+}
+
+func (m *modelRecorder) calls() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.models...)
+}
+
+// compressWithFormat runs one large tool output in the given request format
+// through a compresr-strategy pipe configured with model_by_format.
+func compressWithFormat(t *testing.T, apiURL, format string) {
+	t.Helper()
+	original := strings.Repeat("=== RUN   TestFunction\n--- PASS: TestFunction (0.01s)\n", 100)
+	var request map[string]interface{}
+	switch format {
+	case "anthropic":
+		request = map[string]interface{}{
+			"model": "claude-sonnet-4-20250514",
+			"messages": []map[string]interface{}{
+				{"role": "user", "content": "run the tests"},
+				{"role": "assistant", "content": []map[string]interface{}{
+					{"type": "tool_use", "id": "toolu_fmt", "name": "bash", "input": map[string]string{}},
+				}},
+				{"role": "user", "content": []map[string]interface{}{
+					{"type": "tool_result", "tool_use_id": "toolu_fmt", "content": original},
+				}},
+			},
+		}
+	case "openai":
+		request = map[string]interface{}{
+			"model": "gpt-4o",
+			"messages": []map[string]interface{}{
+				{"role": "user", "content": "run the tests"},
+				{"role": "assistant", "content": nil, "tool_calls": []map[string]interface{}{{
+					"id": "call_fmt", "type": "function",
+					"function": map[string]interface{}{"name": "bash", "arguments": "{}"},
+				}}},
+				{"role": "tool", "tool_call_id": "call_fmt", "content": original},
+			},
+		}
+	}
+	body, err := json.Marshal(request)
+	require.NoError(t, err)
+
+	cfg := adaptiveConfig(apiURL, 0)
+	cfg.Pipes.ToolOutput.Compresr.Model = "toc_latte_v1"
+	cfg.Pipes.ToolOutput.Compresr.ModelByFormat = map[string]string{
+		"anthropic": "tool_output_anthropic",
+		"openai":    "tool_output_openai",
+	}
+	st := store.NewMemoryStore(0)
+	t.Cleanup(func() { _ = st.Close() })
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get(format), body)
+	ctx.TargetModel = request["model"].(string)
+	_, err = pipe.Process(ctx)
+	require.NoError(t, err)
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	assert.Equal(t, "tool_output_"+format, ctx.ToolOutputCompressions[0].Model)
+}
+
+// TestToolOutput_ModelByFormat verifies compresr.model_by_format sends Anthropic
+// traffic to the Anthropic compression model and OpenAI traffic to the OpenAI one.
+func TestToolOutput_ModelByFormat(t *testing.T) {
+	api := newModelRecorder()
+	defer api.Close()
+
+	compressWithFormat(t, api.URL, "anthropic")
+	compressWithFormat(t, api.URL, "openai")
+
+	assert.Equal(t, []string{"tool_output_anthropic", "tool_output_openai"}, api.calls())
+}
+
+// TestToolOutput_ModelByFormat_Fallback verifies formats missing from
+// model_by_format use compresr.model.
+func TestToolOutput_ModelByFormat_Fallback(t *testing.T) {
+	api := newModelRecorder()
+	defer api.Close()
+
+	cfg := adaptiveConfig(api.URL, 0)
+	cfg.Pipes.ToolOutput.Compresr.Model = "toc_latte_v1"
+	cfg.Pipes.ToolOutput.Compresr.ModelByFormat = map[string]string{"openai": "tool_output_openai"}
+	runAdaptive(t, cfg)
+
+	assert.Equal(t, []string{"toc_latte_v1"}, api.calls())
+}
@@ -0,0 +1,81 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// TestToolOutput_NeverCompressTools verifies output from a protected tool passes
+// through intact far above min_tokens, while an identical output from another
+// tool is still compressed and the protected one is not deduplicated.
+func TestToolOutput_NeverCompressTools(t *testing.T) {
+	patch := strings.Repeat("--- a/main.go\n+++ b/main.go\n@@ -1,3 +1,3 @@\n-old line\n+new line\n", 200)
+	tools := []string{"apply_patch", "bash", "apply_patch"}
+
+	var messages []map[string]interface{}
+	for i, name := range tools {
+		id := fmt.Sprintf("toolu_protect_%d", i)
+		messages = append(messages,
+			map[string]interface{}{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": id, "name": name, "input": map[string]string{}},
+			}},
+			map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": id, "content": patch},
+			}},
+		)
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model":    "claude-sonnet-4-20250514",
+		"messages": messages,
+	})
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		Pipes: config.PipesConfig{
+			ToolOutput: config.ToolOutputPipeConfig{
+				Enabled:            true,
+				Strategy:           config.StrategySimple,
+				MinTokens:          10,
+				MaxTokens:          100000,
+				BypassCostCheck:    true,
+				DedupeIdentical:    true,
+				NeverCompressTools: []string{"apply_patch"},
+			},
+		},
+	}
+	st := store.NewMemoryStore(0)
+	defer st.Close()
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+
+	sent := func(i int) string {
+		return gjson.GetBytes(out, fmt.Sprintf("messages.%d.content.0.content", 2*i+1)).String()
+	}
+	assert.Equal(t, patch, sent(0), "apply_patch output passes through")
+	assert.Less(t, len(sent(1)), len(patch), "bash output is compressed")
+	assert.Equal(t, patch, sent(2), "protected output is not deduplicated")
+
+	statuses := map[string]string{}
+	for _, c := range ctx.ToolOutputCompressions {
+		statuses[c.ToolCallID] = c.MappingStatus
+	}
+	assert.Equal(t, "protected", statuses["toolu_protect_0"])
+	assert.Equal(t, "protected", statuses["toolu_protect_2"])
+	assert.Equal(t, "compressed", statuses["toolu_protect_1"])
+}
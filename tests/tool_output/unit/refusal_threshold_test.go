@@ -0,0 +1,81 @@
+package unit
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// TestToolOutput_RefusalThresholdCountsMarkerOverhead verifies a compression that
+// saves a few percent on its own is forwarded uncompressed, without a shadow
+// marker, once the expand_context marker would eat those savings.
+func TestToolOutput_RefusalThresholdCountsMarkerOverhead(t *testing.T) {
+	line := "2024-01-15 10:00:00 INFO worker heartbeat ok\n"
+	original := strings.Repeat(line, 40)
+	slightlyShorter := strings.Repeat(line, 38) // ~5% smaller
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514",
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "check the worker log"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_floor", "name": "bash", "input": map[string]string{}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_floor", "content": original},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name         string
+		expand       bool
+		wantStatus   string
+		wantRejected int64
+	}{
+		{"no marker", false, "compressed", 0},
+		{"marker outweighs savings", true, "ratio_exceeded", 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			api := newAdaptiveCompresr(slightlyShorter)
+			defer api.Close()
+
+			cfg := adaptiveConfig(api.URL, 0)
+			cfg.Pipes.ToolOutput.RefusalThreshold = 0.03
+			cfg.Pipes.ToolOutput.EnableExpandContext = tc.expand
+			cfg.Pipes.ToolOutput.IncludeExpandHint = tc.expand
+
+			st := store.NewMemoryStore(0)
+			defer st.Close()
+			pipe := tooloutput.New(cfg, st)
+
+			ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+			ctx.TargetModel = "claude-sonnet-4-20250514"
+			out, err := pipe.Process(ctx)
+			require.NoError(t, err)
+
+			require.Len(t, ctx.ToolOutputCompressions, 1)
+			assert.Equal(t, tc.wantStatus, ctx.ToolOutputCompressions[0].MappingStatus)
+			assert.Equal(t, tc.wantRejected, pipe.GetMetrics().LowSavingsRejected)
+
+			sent := gjson.GetBytes(out, "messages.2.content.0.content").String()
+			if tc.expand {
+				assert.Equal(t, original, sent)
+				assert.NotContains(t, sent, "[REF:")
+				assert.Empty(t, ctx.ShadowRefs)
+			} else {
+				assert.Equal(t, slightlyShorter, sent)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// budgetTurn sends one request in sessionID whose only tool result is a distinct
+// large output, returning the content forwarded and the compression record.
+func budgetTurn(t *testing.T, pipe *tooloutput.Pipe, sessionID string, turn int) (string, string, pipes.ToolOutputCompression) {
+	t.Helper()
+	original := fmt.Sprintf("turn %d\n", turn) + strings.Repeat("=== RUN   TestFunction\n--- PASS: TestFunction (0.01s)\n", 100)
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514",
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "run the tests"},
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "tool_use", "id": "toolu_budget", "name": "bash", "input": map[string]string{}},
+			}},
+			{"role": "user", "content": []map[string]interface{}{
+				{"type": "tool_result", "tool_use_id": "toolu_budget", "content": original},
+			}},
+		},
+	})
+	require.NoError(t, err)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	ctx.SessionID = sessionID
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+	require.Len(t, ctx.ToolOutputCompressions, 1)
+	return original, gjson.GetBytes(out, "messages.2.content.0.content").String(), ctx.ToolOutputCompressions[0]
+}
+
+func newBudgetPipe(t *testing.T, apiURL string, mutate func(*config.ToolOutputPipeConfig)) *tooloutput.Pipe {
+	t.Helper()
+	cfg := adaptiveConfig(apiURL, 0)
+	mutate(&cfg.Pipes.ToolOutput)
+	st := store.NewMemoryStore(0)
+	t.Cleanup(func() { _ = st.Close() })
+	return tooloutput.New(cfg, st)
+}
+
+// TestToolOutput_SessionCallBudget verifies that after N API compressions in a
+// session the next large output goes to the fallback chain without an API call,
+// while other sessions keep their own budget.
+func TestToolOutput_SessionCallBudget(t *testing.T) {
+	api := newAdaptiveCompresr("100 tests passed")
+	defer api.Close()
+	pipe := newBudgetPipe(t, api.URL, func(c *config.ToolOutputPipeConfig) {
+		c.MaxCompressionCallsPerSession = 2
+	})
+
+	for turn := 0; turn < 2; turn++ {
+		_, sent, rec := budgetTurn(t, pipe, "session-a", turn)
+		assert.Equal(t, "100 tests passed", sent)
+		assert.False(t, rec.BudgetExhausted)
+	}
+
+	original, sent, rec := budgetTurn(t, pipe, "session-a", 2)
+	assert.Equal(t, original, sent, "passthrough fallback after the budget is spent")
+	assert.True(t, rec.BudgetExhausted)
+	assert.Equal(t, "passthrough", rec.MappingStatus)
+	assert.Len(t, api.calls(), 2, "no API call once the budget is exhausted")
+	assert.Equal(t, int64(1), pipe.GetMetrics().BudgetExhausted)
+
+	_, sent, _ = budgetTurn(t, pipe, "session-b", 3)
+	assert.Equal(t, "100 tests passed", sent, "budget is per session")
+	assert.Len(t, api.calls(), 3)
+}
+
+// TestToolOutput_AdaptiveRetriesChargeSessionBudget verifies adaptive retries
+// count against max_compression_calls_per_session: with room for two calls,
+// an output that keeps missing the target stops after one retry.
+func TestToolOutput_AdaptiveRetriesChargeSessionBudget(t *testing.T) {
+	api := newAdaptiveCompresr(weakSummary())
+	defer api.Close()
+	pipe := newBudgetPipe(t, api.URL, func(c *config.ToolOutputPipeConfig) {
+		c.MaxCompressionCallsPerSession = 2
+		c.Compresr.MaxCompressionRetries = 3
+	})
+
+	_, sent, rec := budgetTurn(t, pipe, "session-a", 0)
+	assert.Equal(t, weakSummary(), sent, "best result kept when the budget stops retrying")
+	assert.Equal(t, 2, rec.Attempts)
+	assert.Len(t, api.calls(), 2, "first call plus one charged retry")
+
+	_, _, rec = budgetTurn(t, pipe, "session-a", 1)
+	assert.True(t, rec.BudgetExhausted)
+	assert.Len(t, api.calls(), 2)
+}
+
+// TestToolOutput_SessionByteBudgetUsesLocalFallback verifies the byte budget and
+// that a local fallback level compresses the output once it is exhausted.
+func TestToolOutput_SessionByteBudgetUsesLocalFallback(t *testing.T) {
+	api := newAdaptiveCompresr("100 tests passed")
+	defer api.Close()
+	pipe := newBudgetPipe(t, api.URL, func(c *config.ToolOutputPipeConfig) {
+		c.MaxCompressionBytesPerSession = 6000 // one ~5 KB output fits, two do not
+		c.FallbackChain = []string{config.StrategyTrimming, config.StrategyPassthrough}
+	})
+
+	_, sent, _ := budgetTurn(t, pipe, "session-a", 0)
+	assert.Equal(t, "100 tests passed", sent)
+
+	original, sent, rec := budgetTurn(t, pipe, "session-a", 1)
+	assert.True(t, rec.BudgetExhausted)
+	assert.Equal(t, "compressed", rec.MappingStatus)
+	assert.Less(t, len(sent), len(original), "trimming fallback compressed the output")
+	assert.Len(t, api.calls(), 1)
+}
+
+func TestToolOutputConfig_SessionBudgetValidation(t *testing.T) {
+	cfg := adaptiveConfig("http://localhost", 0).Pipes.ToolOutput
+	cfg.Compresr.Endpoint = "/api/compress/tool-output/"
+	cfg.MaxCompressionCallsPerSession = -1
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_compression_calls_per_session")
+
+	cfg.MaxCompressionCallsPerSession = 0
+	cfg.MaxCompressionBytesPerSession = -1
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "max_compression_bytes_per_session")
+}
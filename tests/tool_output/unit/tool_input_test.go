@@ -0,0 +1,192 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tidwall/gjson"
+
+	"github.com/compresr/context-gateway/internal/adapters"
+	"github.com/compresr/context-gateway/internal/config"
+	"github.com/compresr/context-gateway/internal/pipes"
+	tooloutput "github.com/compresr/context-gateway/internal/pipes/tool_output"
+	"github.com/compresr/context-gateway/internal/store"
+)
+
+// largeFileContent returns roughly 50KB of source text.
+func largeFileContent(name string) string {
+	var b strings.Builder
+	for i := 0; b.Len() < 50*1024; i++ {
+		fmt.Fprintf(&b, "func %s%d(x int) int { return x * %d } // generated helper\n", name, i, i)
+	}
+	return b.String()
+}
+
+func writeFileUse(id, path, content string) map[string]interface{} {
+	return map[string]interface{}{"type": "tool_use", "id": id, "name": "write_file",
+		"input": map[string]string{"path": path, "content": content}}
+}
+
+func toolResultMsg(id string) map[string]interface{} {
+	return map[string]interface{}{"role": "user", "content": []map[string]interface{}{
+		{"type": "tool_result", "tool_use_id": id, "content": "ok"},
+	}}
+}
+
+// toolInputHistory is a session where a 50KB write_file happened several turns
+// before the latest (equally large) write_file.
+func toolInputHistory(t *testing.T) []byte {
+	t.Helper()
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "claude-sonnet-4-20250514",
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "Generate the helpers, then the tests."},
+			{"role": "assistant", "content": []map[string]interface{}{writeFileUse("toolu_old", "helpers.go", largeFileContent("helper"))}},
+			toolResultMsg("toolu_old"),
+			{"role": "assistant", "content": []map[string]interface{}{
+				{"type": "text", "text": "Now the tests."},
+				writeFileUse("toolu_new", "helpers_test.go", largeFileContent("test")),
+			}},
+			toolResultMsg("toolu_new"),
+		},
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func toolInputConfig(enabled bool) config.ToolOutputPipeConfig {
+	return config.ToolOutputPipeConfig{
+		Enabled:             true,
+		Strategy:            config.StrategySimple,
+		MinTokens:           100,
+		MaxTokens:           100000,
+		BypassCostCheck:     true,
+		EnableExpandContext: true,
+		CompressToolInputs:  enabled,
+	}
+}
+
+func runToolInputPipe(t *testing.T, enabled bool, body []byte) ([]byte, *pipes.PipeContext) {
+	t.Helper()
+	return runToolInputPipeWith(t, toolInputConfig(enabled), body)
+}
+
+func runToolInputPipeWith(t *testing.T, toolCfg config.ToolOutputPipeConfig, body []byte) ([]byte, *pipes.PipeContext) {
+	t.Helper()
+	cfg := &config.Config{Pipes: config.PipesConfig{ToolOutput: toolCfg}}
+	st := store.NewMemoryStore(0)
+	t.Cleanup(func() { st.Close() })
+	pipe := tooloutput.New(cfg, st)
+
+	ctx := pipes.NewPipeContext(adapters.NewRegistry().Get("anthropic"), body)
+	ctx.TargetModel = "claude-sonnet-4-20250514"
+	out, err := pipe.Process(ctx)
+	require.NoError(t, err)
+	return out, ctx
+}
+
+func inputCompressions(ctx *pipes.PipeContext) []pipes.ToolOutputCompression {
+	var out []pipes.ToolOutputCompression
+	for _, c := range ctx.ToolOutputCompressions {
+		if c.MappingStatus == "input_compressed" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// TestToolOutput_CompressToolInputs verifies a large write_file input from an
+// earlier turn is compressed and expandable while the latest call is untouched.
+func TestToolOutput_CompressToolInputs(t *testing.T) {
+	body := toolInputHistory(t)
+	out, ctx := runToolInputPipe(t, true, body)
+
+	oldContent := gjson.GetBytes(out, "messages.1.content.0.input.content").String()
+	assert.Contains(t, oldContent, tooloutput.ShadowPrefixMarker, "old write_file content compressed")
+	assert.Less(t, len(oldContent), 1024)
+	assert.Equal(t, "helpers.go", gjson.GetBytes(out, "messages.1.content.0.input.path").String())
+
+	assert.Equal(t, gjson.GetBytes(body, "messages.3").Raw, gjson.GetBytes(out, "messages.3").Raw,
+		"latest tool_use must be forwarded byte-for-byte")
+
+	inputs := inputCompressions(ctx)
+	require.Len(t, inputs, 1)
+	rec := inputs[0]
+	assert.Equal(t, "toolu_old", rec.ToolCallID)
+	assert.Equal(t, gjson.GetBytes(body, "messages.1.content.0.input.content").String(), ctx.ShadowRefs[rec.ShadowID])
+
+	// Next turn: the client resends the original history; the rewrite is identical.
+	again, _ := runToolInputPipe(t, true, body)
+	assert.Equal(t, out, again)
+}
+
+// TestToolOutput_CompressToolInputs_Hierarchical verifies inputs share the
+// output pass: an argument above max_tokens is compressed in chunks.
+func TestToolOutput_CompressToolInputs_Hierarchical(t *testing.T) {
+	body := toolInputHistory(t)
+	toolCfg := toolInputConfig(true)
+	toolCfg.MaxTokens = 2000
+	toolCfg.Hierarchical = config.HierarchicalConfig{Enabled: true}
+
+	out, ctx := runToolInputPipeWith(t, toolCfg, body)
+	oldContent := gjson.GetBytes(out, "messages.1.content.0.input.content").String()
+	assert.Contains(t, oldContent, tooloutput.ShadowPrefixMarker)
+	require.Len(t, inputCompressions(ctx), 1)
+	assert.Less(t, inputCompressions(ctx)[0].CompressedTokens, inputCompressions(ctx)[0].OriginalTokens)
+}
+
+// TestToolOutput_CompressToolInputs_Disabled verifies inputs are left alone by default.
+func TestToolOutput_CompressToolInputs_Disabled(t *testing.T) {
+	body := toolInputHistory(t)
+	out, ctx := runToolInputPipe(t, false, body)
+	assert.JSONEq(t, string(body), string(out))
+	assert.Empty(t, inputCompressions(ctx))
+}
+
+// TestToolInputAdapter_OpenAIChat verifies Chat Completions arguments strings
+// are rewritten in place and the last assistant turn is excluded.
+func TestToolInputAdapter_OpenAIChat(t *testing.T) {
+	args := func(content string) string {
+		raw, _ := json.Marshal(map[string]string{"path": "a.go", "content": content})
+		return string(raw)
+	}
+	call := func(id, content string) map[string]interface{} {
+		return map[string]interface{}{"role": "assistant", "tool_calls": []map[string]interface{}{
+			{"id": id, "type": "function", "function": map[string]string{"name": "write_file", "arguments": args(content)}},
+		}}
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"model": "gpt-4o",
+		"messages": []map[string]interface{}{
+			{"role": "user", "content": "write it"},
+			call("call_old", "old body"),
+			{"role": "tool", "tool_call_id": "call_old", "content": "ok"},
+			call("call_new", "new body"),
+			{"role": "tool", "tool_call_id": "call_new", "content": "ok"},
+		},
+	})
+	require.NoError(t, err)
+
+	adapter, ok := adapters.NewRegistry().Get("openai").(adapters.ToolInputAdapter)
+	require.True(t, ok)
+	inputs, err := adapter.ExtractToolInputs(body)
+	require.NoError(t, err)
+	require.Len(t, inputs, 2, "path and content of the old call only")
+	for _, in := range inputs {
+		assert.Equal(t, "call_old", in.ID)
+		assert.Equal(t, "write_file", in.ToolName)
+	}
+
+	out, err := adapter.ApplyToolInputs(body, []adapters.CompressedResult{
+		{ID: "call_old", Compressed: "[REF:x]\nshort", MessageIndex: 1, BlockIndex: 0, Field: "content"},
+	})
+	require.NoError(t, err)
+	newArgs := gjson.GetBytes(out, "messages.1.tool_calls.0.function.arguments").String()
+	assert.Equal(t, "[REF:x]\nshort", gjson.Get(newArgs, "content").String())
+	assert.Equal(t, "a.go", gjson.Get(newArgs, "path").String())
+	assert.Equal(t, gjson.GetBytes(body, "messages.3").Raw, gjson.GetBytes(out, "messages.3").Raw)
+}
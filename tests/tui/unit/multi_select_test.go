@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/tui"
+)
+
+const (
+	keyUp   = "\033[A"
+	keyDown = "\033[B"
+	keyEnt  = "\r"
+)
+
+func sessionItems() []tui.MenuItem {
+	return []tui.MenuItem{
+		{Label: "session_1"},
+		{Label: "session_2"},
+		{Label: "session_3", Locked: true, LockedReason: "in use"},
+		{Label: "session_4"},
+	}
+}
+
+func TestMultiSelect_ToggleAndConfirm(t *testing.T) {
+	keys := " " + keyDown + keyDown + keyDown + " " + keyUp + keyUp + " " + " " + "j" + keyEnt
+	var out bytes.Buffer
+
+	selected, err := tui.RunMultiSelect(strings.NewReader(keys), &out, "Clean sessions", sessionItems())
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 3}, selected)
+}
+
+func TestMultiSelect_ConfirmWithNothingChecked(t *testing.T) {
+	selected, err := tui.RunMultiSelect(strings.NewReader(keyEnt), &bytes.Buffer{}, "Pick", sessionItems())
+	require.NoError(t, err)
+	assert.Empty(t, selected)
+}
+
+func TestMultiSelect_LockedItemCannotBeToggled(t *testing.T) {
+	keys := "jj " + keyEnt
+	selected, err := tui.RunMultiSelect(strings.NewReader(keys), &bytes.Buffer{}, "Pick", sessionItems())
+	require.NoError(t, err)
+	assert.Empty(t, selected)
+}
+
+func TestMultiSelect_CursorStaysInBounds(t *testing.T) {
+	keys := keyUp + " " + strings.Repeat(keyDown, 10) + " " + keyEnt
+	selected, err := tui.RunMultiSelect(strings.NewReader(keys), &bytes.Buffer{}, "Pick", sessionItems())
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 3}, selected)
+}
+
+func TestMultiSelect_Cancel(t *testing.T) {
+	for name, keys := range map[string]string{"q": " q", "escape": " \033"} {
+		t.Run(name, func(t *testing.T) {
+			selected, err := tui.RunMultiSelect(strings.NewReader(keys), &bytes.Buffer{}, "Pick", sessionItems())
+			assert.Error(t, err)
+			assert.Nil(t, selected)
+		})
+	}
+}
+
+func TestMultiSelect_RendersCheckboxes(t *testing.T) {
+	var out bytes.Buffer
+	_, err := tui.RunMultiSelect(strings.NewReader(" "+keyEnt), &out, "Enable notifiers", sessionItems())
+	require.NoError(t, err)
+
+	rendered := out.String()
+	assert.Contains(t, rendered, "Enable notifiers")
+	assert.Contains(t, rendered, "[ ]")
+	assert.Contains(t, rendered, tui.ColorGreen+"[x]"+tui.ColorReset+" "+tui.ColorBold+"session_1")
+	assert.Contains(t, rendered, "🔒 session_3")
+	assert.Contains(t, rendered, "[in use]")
+	assert.Contains(t, rendered, "[Space] Toggle")
+}
+
+func TestMultiSelect_NoItems(t *testing.T) {
+	_, err := tui.RunMultiSelect(strings.NewReader(keyEnt), &bytes.Buffer{}, "Pick", nil)
+	assert.Error(t, err)
+}
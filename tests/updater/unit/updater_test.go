@@ -0,0 +1,114 @@
+package unit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/compresr/context-gateway/internal/updater"
+)
+
+// newInstall returns an Install with a fake binary holding content.
+func newInstall(t *testing.T, content string) updater.Install {
+	t.Helper()
+	dir := t.TempDir()
+	in := updater.Install{
+		ConfigDir: filepath.Join(dir, "config"),
+		ExecPath:  filepath.Join(dir, "bin", "context-gateway"),
+	}
+	require.NoError(t, os.MkdirAll(filepath.Dir(in.ExecPath), 0750))
+	require.NoError(t, os.WriteFile(in.ExecPath, []byte(content), 0600))
+	return in
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+// TestInstall_PinnedVersion verifies pinning, re-pinning and clearing the pin.
+func TestInstall_PinnedVersion(t *testing.T) {
+	in := newInstall(t, "v1")
+	assert.Empty(t, in.PinnedVersion())
+
+	in.SetPinnedVersion("v0.5.2")
+	assert.Equal(t, "v0.5.2", in.PinnedVersion())
+	in.SetPinnedVersion("v0.5.1")
+	assert.Equal(t, "v0.5.1", in.PinnedVersion())
+
+	in.SetPinnedVersion("")
+	assert.Empty(t, in.PinnedVersion())
+	assert.NoFileExists(t, in.PinnedVersionFile())
+}
+
+// TestInstall_ReplaceThenRollback verifies an update keeps the replaced binary
+// as the backup and rollback restores it and pins its version.
+func TestInstall_ReplaceThenRollback(t *testing.T) {
+	in := newInstall(t, "old binary")
+	newBinary := in.ExecPath + ".new"
+	require.NoError(t, os.WriteFile(newBinary, []byte("new binary"), 0600))
+
+	require.NoError(t, in.Replace(newBinary, "v0.5.1"))
+	assert.Equal(t, "new binary", readFile(t, in.ExecPath))
+	assert.Equal(t, "old binary", readFile(t, in.BackupPath()))
+	assert.NoFileExists(t, newBinary)
+	assert.Equal(t, "v0.5.1", in.PreviousVersion())
+
+	restored, err := in.Rollback()
+	require.NoError(t, err)
+	assert.Equal(t, "v0.5.1", restored)
+	assert.Equal(t, "old binary", readFile(t, in.ExecPath))
+	assert.NoFileExists(t, in.BackupPath(), "backup is consumed")
+	assert.Empty(t, in.PreviousVersion())
+	assert.Equal(t, "v0.5.1", in.PinnedVersion(), "rollback pins the restored version")
+
+	_, err = in.Rollback()
+	assert.Error(t, err, "nothing left to roll back to")
+}
+
+// TestInstall_RollbackWithoutBackup verifies rollback fails without touching
+// the installed binary when no update ran.
+func TestInstall_RollbackWithoutBackup(t *testing.T) {
+	in := newInstall(t, "current")
+	require.Error(t, in.CheckBackup())
+
+	_, err := in.Rollback()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no backup found")
+	assert.Equal(t, "current", readFile(t, in.ExecPath))
+	assert.Empty(t, in.PinnedVersion())
+}
+
+// TestInstall_RollbackUnknownVersion verifies a backup without a recorded
+// version is restored but leaves the pin alone.
+func TestInstall_RollbackUnknownVersion(t *testing.T) {
+	in := newInstall(t, "current")
+	require.NoError(t, os.WriteFile(in.BackupPath(), []byte("older"), 0600))
+
+	restored, err := in.Rollback()
+	require.NoError(t, err)
+	assert.Empty(t, restored)
+	assert.Equal(t, "older", readFile(t, in.ExecPath))
+	assert.Empty(t, in.PinnedVersion())
+}
+
+// TestNormalizeVersionTag verifies release tags gain the "v" prefix.
+func TestNormalizeVersionTag(t *testing.T) {
+	assert.Equal(t, "v0.5.2", updater.NormalizeVersionTag("0.5.2"))
+	assert.Equal(t, "v0.5.2", updater.NormalizeVersionTag(" v0.5.2 "))
+	assert.Empty(t, updater.NormalizeVersionTag(""))
+}
+
+// TestIsNewerVersion verifies semantic comparison, including dev suffixes.
+func TestIsNewerVersion(t *testing.T) {
+	assert.True(t, updater.IsNewerVersion("v0.5.1", "v0.5.2"))
+	assert.True(t, updater.IsNewerVersion("v0.9.9", "v1.0.0"))
+	assert.False(t, updater.IsNewerVersion("v0.5.2", "v0.5.2"))
+	assert.False(t, updater.IsNewerVersion("v0.5.2-dev", "v0.5.1"))
+	assert.False(t, updater.IsNewerVersion("v0.10.0", "v0.9.0"))
+}